@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// The user (or service) operations run for, to check field access against. Put it in the context of a request's
+// collections, e.g.
+//
+//	ctx := bongo.PrincipalContextKey.WithValue(r.Context(), user)
+//	err := conn.Collection("users").WithContext(ctx).FindByID(id, found)
+type Principal interface {
+	HasRole(role string) bool
+}
+
+var PrincipalContextKey = NewContextKey[Principal]("principal")
+
+// Roles that may read and write a field, from its access tag, e.g.
+//
+//	Salary int `bson:"salary" access:"read=hr|admin,write=admin"`
+//
+// A nil list allows everyone, an empty one (access:"write=") nobody
+type accessField struct {
+	goPath string
+	path   string
+	read   []string
+	write  []string
+}
+
+var accessFieldsCache sync.Map
+
+// The fields of a type with an access tag, cached per type
+func accessFields(t reflect.Type) []*accessField {
+	if fields, ok := accessFieldsCache.Load(t); ok {
+		return fields.([]*accessField)
+	}
+
+	fields := make([]*accessField, 0)
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		tag, ok := field.Tag.Lookup("access")
+		if !ok {
+			return true
+		}
+		access := &accessField{goPath: goPath, path: path}
+		for _, part := range strings.Split(tag, ",") {
+			name, roles, _ := strings.Cut(part, "=")
+			list := make([]string, 0)
+			if len(roles) > 0 {
+				list = strings.Split(roles, "|")
+			}
+			switch strings.TrimSpace(name) {
+			case "read":
+				access.read = list
+			case "write":
+				access.write = list
+			}
+		}
+		fields = append(fields, access)
+		return false
+	})
+
+	accessFieldsCache.Store(t, fields)
+	return fields
+}
+
+func principalHasRole(principal Principal, roles []string) bool {
+	if roles == nil {
+		return true
+	}
+	for _, role := range roles {
+		if principal.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *accessField) canRead(principal Principal) bool {
+	return principalHasRole(principal, f.read)
+}
+
+// Fields that can't be read can't be written either, or saving a document loaded without them would clear them
+func (f *accessField) canWrite(principal Principal) bool {
+	return f.canRead(principal) && principalHasRole(principal, f.write)
+}
+
+// The principal in the collection's context, or nil if the operations aren't restricted
+func (c *Collection) principal() Principal {
+	if c == nil {
+		return nil
+	}
+	principal, _ := PrincipalContextKey.Get(c.Context)
+	return principal
+}
+
+// The struct value of a document and its access tagged fields, or nil if there are none or no principal to check
+func (c *Collection) accessFields(doc interface{}) (Principal, reflect.Value, []*accessField) {
+	principal := c.principal()
+	if principal == nil {
+		return nil, reflect.Value{}, nil
+	}
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, reflect.Value{}, nil
+	}
+	v = v.Elem()
+	fields := accessFields(v.Type())
+	if len(fields) == 0 {
+		return nil, reflect.Value{}, nil
+	}
+	return principal, v, fields
+}
+
+// Clears the fields of a loaded document the principal may not read
+func (c *Collection) stripUnreadable(doc interface{}) {
+	principal, v, fields := c.accessFields(doc)
+	for _, field := range fields {
+		if field.canRead(principal) {
+			continue
+		}
+		if value := fieldByGoPath(v, field.goPath); value.IsValid() && value.CanSet() {
+			value.Set(reflect.Zero(value.Type()))
+		}
+	}
+}
+
+// Makes sure a document about to be saved only changes fields the principal may write. Fields it may not read keep
+// their stored value. Changes to fields it may read but not write fail the save with a *FieldNotAllowedError, or
+// are dropped if the collection's FieldAccessConfig says so
+func (c *Collection) enforceFieldAccess(doc Document, isNew bool) error {
+	principal, v, fields := c.accessFields(doc)
+	denied := make([]*accessField, 0)
+	for _, field := range fields {
+		if !field.canWrite(principal) {
+			denied = append(denied, field)
+		}
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+
+	// What the document holds in the database, zero for new documents
+	stored := reflect.New(v.Type()).Elem()
+	if !isNew {
+		if err := c.loadStoredFields(doc, denied, stored.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range denied {
+		current := fieldByGoPath(v, field.goPath)
+		previous := fieldByGoPath(stored, field.goPath)
+		if !current.IsValid() || !current.CanSet() {
+			continue
+		}
+		if !previous.IsValid() {
+			previous = reflect.Zero(current.Type())
+		}
+		if reflect.DeepEqual(current.Interface(), previous.Interface()) {
+			continue
+		}
+		if field.canRead(principal) && !c.fieldAccess().DropUnwritable {
+			return &FieldNotAllowedError{Collection: c.Name, Field: field.path, Use: "write"}
+		}
+		current.Set(previous)
+	}
+	return nil
+}
+
+// Decodes the stored values of the fields of an existing document into target
+func (c *Collection) loadStoredFields(doc Document, fields []*accessField, target interface{}) error {
+	projection := bson.M{}
+	for _, field := range fields {
+		projection[field.path] = 1
+	}
+	id := doc.GetID()
+
+	err := c.runOperation("findById", func(ctx context.Context) error {
+		filter, err := c.guardFilter(ctx, bson.D{{"_id", id}})
+		if err != nil {
+			return err
+		}
+		opts := options.FindOne().SetProjection(projection)
+		commentOn(ctx, c, opts.SetComment)
+		return c.Collection().FindOne(ctx, filter, opts).Decode(target)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	return err
+}
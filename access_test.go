@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"reflect"
+	"testing"
+)
+
+type rolePrincipal []string
+
+func (p rolePrincipal) HasRole(role string) bool {
+	return stringInSlice(role, p)
+}
+
+type accessDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string `bson:"name"`
+	Salary       int    `bson:"salary" access:"read=hr|admin,write=admin"`
+	Notes        string `bson:"notes" access:"read=admin"`
+	Email        string `bson:"email" access:"write="`
+}
+
+func TestFieldAccessControl(t *testing.T) {
+	Convey("Field access control", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest", FieldAccess: map[string]*FieldAccessConfig{}}}
+		col := func(roles ...string) *Collection {
+			ctx := PrincipalContextKey.WithValue(context.Background(), rolePrincipal(roles))
+			return conn.Collection("staff").WithContext(ctx)
+		}
+
+		Convey("should parse the access tags", func() {
+			fields := accessFields(reflect.TypeOf(accessDocument{}))
+			So(len(fields), ShouldEqual, 3)
+			So(fields[0].path, ShouldEqual, "salary")
+			So(fields[0].read, ShouldResemble, []string{"hr", "admin"})
+			So(fields[0].write, ShouldResemble, []string{"admin"})
+			So(fields[1].write, ShouldBeNil)
+			So(fields[2].read, ShouldBeNil)
+			So(fields[2].write, ShouldResemble, []string{})
+		})
+
+		Convey("should strip the fields the principal may not read", func() {
+			doc := &accessDocument{Name: "Ann", Salary: 100, Notes: "secret"}
+			col("hr").stripUnreadable(doc)
+			So(doc.Salary, ShouldEqual, 100)
+			So(doc.Notes, ShouldEqual, "")
+			So(doc.Name, ShouldEqual, "Ann")
+
+			col().stripUnreadable(doc)
+			So(doc.Salary, ShouldEqual, 0)
+		})
+
+		Convey("should not restrict operations without a principal", func() {
+			doc := &accessDocument{Salary: 100, Notes: "secret", Email: "ann@example.com"}
+			conn.Collection("staff").stripUnreadable(doc)
+			So(doc.Notes, ShouldEqual, "secret")
+			So(conn.Collection("staff").enforceFieldAccess(doc, true), ShouldBeNil)
+		})
+
+		Convey("should reject writes to fields the principal may not write", func() {
+			doc := &accessDocument{Name: "Ann", Salary: 100}
+			err := col("hr").enforceFieldAccess(doc, true)
+			So(err, ShouldResemble, &FieldNotAllowedError{Collection: "staff", Field: "salary", Use: "write"})
+
+			So(col("admin").enforceFieldAccess(doc, true), ShouldBeNil)
+
+			doc = &accessDocument{Email: "ann@example.com"}
+			So(col("admin").enforceFieldAccess(doc, true), ShouldHaveSameTypeAs, &FieldNotAllowedError{})
+		})
+
+		Convey("should drop writes if the collection says so", func() {
+			conn.Config.FieldAccess["staff"] = &FieldAccessConfig{DropUnwritable: true}
+			doc := &accessDocument{Name: "Ann", Salary: 100}
+			So(col("hr").enforceFieldAccess(doc, true), ShouldBeNil)
+			So(doc.Salary, ShouldEqual, 0)
+			So(doc.Name, ShouldEqual, "Ann")
+		})
+
+		Convey("should silently keep the stored value of unreadable fields", func() {
+			doc := &accessDocument{Name: "Ann", Notes: "made up"}
+			So(col("hr").enforceFieldAccess(doc, true), ShouldBeNil)
+			So(doc.Notes, ShouldEqual, "")
+		})
+	})
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+)
+
+// Anonymization strategies for the anonymize tag
+const (
+	// Replaces the value with a (salted) SHA-256 hash, so equal values still match after anonymization
+	ANONYMIZE_HASH = "hash"
+
+	// Replaces the value with an email address derived from its hash
+	ANONYMIZE_FAKE_EMAIL = "fake-email"
+
+	// Removes the field
+	ANONYMIZE_DROP = "drop"
+
+	// Replaces the value with RedactedValue
+	ANONYMIZE_MASK = "mask"
+)
+
+// Rewrites documents according to the anonymize tags of a document type, e.g.
+//
+//	Email string `bson:"email" anonymize:"fake-email"`
+//	Phone string `bson:"phone" anonymize:"drop"`
+//
+// Tags on fields of nested structs apply to their dotted paths. Array values are anonymized element by element
+type Anonymizer struct {
+	// Mixed into hashes, so hashed values can't be looked up in precomputed tables
+	Salt string
+
+	rules map[string]string
+}
+
+// Creates an anonymizer from the anonymize tags of a document type. Fields tagged for redaction (see
+// MarshalRedactedJSON) are dropped or masked as well, unless they have an anonymize tag
+func NewAnonymizer(doc interface{}) (*Anonymizer, error) {
+	return newAnonymizer(doc, true)
+}
+
+// Creates an anonymizer that only drops or masks the fields tagged for redaction, e.g. for exports
+func NewRedactor(doc interface{}) (*Anonymizer, error) {
+	return newAnonymizer(doc, false)
+}
+
+func newAnonymizer(doc interface{}, anonymize bool) (*Anonymizer, error) {
+	a := &Anonymizer{rules: make(map[string]string)}
+
+	var err error
+	var collect func(field reflect.StructField, path string) bool
+	collect = func(field reflect.StructField, path string) bool {
+		strategy, ok := "", false
+		if anonymize {
+			strategy, ok = field.Tag.Lookup("anonymize")
+		}
+		if !ok {
+			var redactErr error
+			if strategy, ok, redactErr = redactStrategy(field); redactErr != nil {
+				err = redactErr
+			}
+		}
+		if !ok {
+			// Documents in arrays are anonymized like nested documents
+			if t := field.Type; t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+				walkBsonFields(t.Elem(), path, collect)
+			}
+			return true
+		}
+		if strategy != ANONYMIZE_HASH && strategy != ANONYMIZE_FAKE_EMAIL && strategy != ANONYMIZE_DROP && strategy != ANONYMIZE_MASK {
+			err = errors.New("unknown anonymize strategy " + strategy + " on field " + field.Name)
+		}
+		a.rules[path] = strategy
+		return false
+	}
+	walkBsonFields(reflect.TypeOf(doc), "", collect)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Maps a redact tag to the anonymization strategy with the same effect. Unknown values are an error, so a typo
+// doesn't leak the field
+func redactStrategy(field reflect.StructField) (string, bool, error) {
+	value, ok := field.Tag.Lookup("redact")
+	if !ok {
+		return "", false, nil
+	}
+	switch value {
+	case "true", REDACT_OMIT:
+		return ANONYMIZE_DROP, true, nil
+	case REDACT_MASK:
+		return ANONYMIZE_MASK, true, nil
+	}
+	return "", false, errors.New("unknown redact value " + value + " on field " + field.Name)
+}
+
+// Returns an anonymized copy of a document
+func (a *Anonymizer) Anonymize(doc bson.Raw) (bson.Raw, error) {
+	if len(a.rules) == 0 {
+		return doc, nil
+	}
+
+	var d bson.D
+	if err := bson.Unmarshal(doc, &d); err != nil {
+		return nil, err
+	}
+
+	return bson.Marshal(a.anonymizeDocument(d, ""))
+}
+
+func (a *Anonymizer) anonymizeDocument(d bson.D, prefix string) bson.D {
+	out := make(bson.D, 0, len(d))
+
+	for _, e := range d {
+		path := e.Key
+		if len(prefix) > 0 {
+			path = prefix + "." + e.Key
+		}
+
+		strategy, ok := a.rules[path]
+		if !ok {
+			out = append(out, bson.E{Key: e.Key, Value: a.descend(e.Value, path)})
+			continue
+		}
+		if strategy == ANONYMIZE_DROP {
+			continue
+		}
+		out = append(out, bson.E{Key: e.Key, Value: a.apply(strategy, e.Value)})
+	}
+
+	return out
+}
+
+// Anonymizes the tagged fields of nested documents, including documents in arrays
+func (a *Anonymizer) descend(value interface{}, path string) interface{} {
+	switch v := value.(type) {
+	case bson.D:
+		return a.anonymizeDocument(v, path)
+	case bson.A:
+		out := make(bson.A, len(v))
+		for i, item := range v {
+			out[i] = a.descend(item, path)
+		}
+		return out
+	}
+	return value
+}
+
+func (a *Anonymizer) apply(strategy string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if len(v) == 0 {
+			return v
+		}
+	case bson.A:
+		out := make(bson.A, len(v))
+		for i, item := range v {
+			out[i] = a.apply(strategy, item)
+		}
+		return out
+	}
+
+	if strategy == ANONYMIZE_MASK {
+		return RedactedValue
+	}
+
+	hash := a.hash(value)
+	if strategy == ANONYMIZE_FAKE_EMAIL {
+		return "user_" + hash[:12] + "@example.com"
+	}
+	return hash
+}
+
+func (a *Anonymizer) hash(value interface{}) string {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case primitive.ObjectID:
+		data = []byte(v.Hex())
+	default:
+		// Anything else is hashed in its canonical extended JSON form
+		data, _ = bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, true, false)
+	}
+
+	sum := sha256.Sum256(append([]byte(a.Salt), data...))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type anonymizedContact struct {
+	Phone string `bson:"phone" anonymize:"drop"`
+	City  string `bson:"city"`
+}
+
+type anonymizedDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string              `bson:"name" anonymize:"hash"`
+	Email        string              `bson:"email" anonymize:"fake-email"`
+	Aliases      []string            `bson:"aliases" anonymize:"hash"`
+	Contact      anonymizedContact   `bson:"contact"`
+	Contacts     []anonymizedContact `bson:"contacts"`
+	Age          int                 `bson:"age"`
+}
+
+type badAnonymizedDocument struct {
+	Name string `anonymize:"scramble"`
+}
+
+func TestAnonymizer(t *testing.T) {
+	Convey("Anonymizer", t, func() {
+		anonymizer, err := NewAnonymizer(&anonymizedDocument{})
+		So(err, ShouldEqual, nil)
+
+		raw, _ := bson.Marshal(bson.D{
+			{Key: "name", Value: "Jane"},
+			{Key: "email", Value: "jane@example.org"},
+			{Key: "aliases", Value: bson.A{"J", ""}},
+			{Key: "contact", Value: bson.D{{Key: "phone", Value: "123"}, {Key: "city", Value: "Berlin"}}},
+			{Key: "contacts", Value: bson.A{bson.D{{Key: "phone", Value: "456"}, {Key: "city", Value: "Paris"}}}},
+			{Key: "age", Value: 42},
+		})
+
+		Convey("should apply the strategies of the tags", func() {
+			out, err := anonymizer.Anonymize(raw)
+			So(err, ShouldEqual, nil)
+
+			name := out.Lookup("name").StringValue()
+			So(len(name), ShouldEqual, 64)
+			So(name, ShouldNotContainSubstring, "Jane")
+
+			email := out.Lookup("email").StringValue()
+			So(email, ShouldStartWith, "user_")
+			So(email, ShouldEndWith, "@example.com")
+
+			aliases, _ := out.Lookup("aliases").Array().Values()
+			So(len(aliases[0].StringValue()), ShouldEqual, 64)
+			So(aliases[1].StringValue(), ShouldEqual, "")
+
+			_, err = out.LookupErr("contact", "phone")
+			So(err, ShouldNotBeNil)
+			So(out.Lookup("contact", "city").StringValue(), ShouldEqual, "Berlin")
+
+			contacts, _ := out.Lookup("contacts").Array().Values()
+			_, err = contacts[0].Document().LookupErr("phone")
+			So(err, ShouldNotBeNil)
+
+			So(out.Lookup("age").Int32(), ShouldEqual, 42)
+		})
+
+		Convey("should hash deterministically per salt", func() {
+			first, _ := anonymizer.Anonymize(raw)
+			second, _ := anonymizer.Anonymize(raw)
+			So(first.Lookup("name").StringValue(), ShouldEqual, second.Lookup("name").StringValue())
+
+			salted, _ := NewAnonymizer(&anonymizedDocument{})
+			salted.Salt = "pepper"
+			third, _ := salted.Anonymize(raw)
+			So(third.Lookup("name").StringValue(), ShouldNotEqual, first.Lookup("name").StringValue())
+		})
+
+		Convey("should anonymize exports", func() {
+			out := &bytes.Buffer{}
+			_, err := resultSetFromDocuments(raw).Export(out, EXPORT_NDJSON, &ExportOptions{Anonymizer: anonymizer})
+			So(err, ShouldEqual, nil)
+			So(out.String(), ShouldNotContainSubstring, "jane@example.org")
+			So(out.String(), ShouldNotContainSubstring, "123")
+		})
+
+		Convey("should reject unknown strategies", func() {
+			_, err := NewAnonymizer(&badAnonymizedDocument{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"time"
+)
+
+// The collection that maps archived documents to the archive they were moved to
+const ArchiveMapCollection = "bongo_archive_map"
+
+// Which documents of a collection Archive moves away, and where to
+type ArchivalPolicy struct {
+	// Documents to archive once they are old enough. Nil archives every document that is old enough
+	Filter interface{}
+
+	// How old documents must be to be archived
+	Age time.Duration
+
+	// The time field the age is measured by. Defaults to "created_at"
+	AgeField string
+
+	// Where documents are archived to. Default to the database of the connection and the collection's name plus
+	// "_archive"
+	ArchiveDatabase   string
+	ArchiveCollection string
+
+	// Documents moved per transaction. Defaults to 100
+	BatchSize int
+}
+
+func (p *ArchivalPolicy) withDefaults(collection string, database string) *ArchivalPolicy {
+	policy := *p
+	if len(policy.AgeField) == 0 {
+		policy.AgeField = "created_at"
+	}
+	if len(policy.ArchiveDatabase) == 0 {
+		policy.ArchiveDatabase = database
+	}
+	if len(policy.ArchiveCollection) == 0 {
+		policy.ArchiveCollection = collection + "_archive"
+	}
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = 100
+	}
+	return &policy
+}
+
+// Where an archived document went
+type archiveMapping struct {
+	Key        archiveKey `bson:"_id"`
+	Database   string     `bson:"database"`
+	Archive    string     `bson:"archive"`
+	ArchivedAt time.Time  `bson:"archived_at"`
+}
+
+type archiveKey struct {
+	Collection string      `bson:"collection"`
+	ID         interface{} `bson:"id"`
+}
+
+// Moves the documents matching the ArchivalPolicies of the config to their archives, batch by batch, each batch in
+// a transaction (which needs a replica set). Every archived document is recorded in the ArchiveMapCollection, so
+// FindArchived can find it. Returns the number of documents archived per collection
+func (m *Connection) Archive(ctx context.Context) (map[string]int, error) {
+	names := make([]string, 0, len(m.Config.ArchivalPolicies))
+	for name := range m.Config.ArchivalPolicies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	archived := map[string]int{}
+	for _, name := range names {
+		n, err := m.Collection(name).archive(ctx, m.Config.ArchivalPolicies[name])
+		if n > 0 {
+			archived[name] = n
+		}
+		if err != nil {
+			return archived, err
+		}
+	}
+	return archived, nil
+}
+
+func (c *Collection) archive(ctx context.Context, policy *ArchivalPolicy) (int, error) {
+	policy = policy.withDefaults(c.Name, c.Database)
+	archive := c.Connection.CollectionFromDatabase(policy.ArchiveCollection, policy.ArchiveDatabase)
+	mappings := c.Connection.CollectionFromDatabase(ArchiveMapCollection, c.Database)
+	filter := AndFilter(policy.Filter, bson.M{policy.AgeField: bson.M{"$lt": time.Now().Add(-policy.Age)}})
+
+	archived := 0
+	for {
+		var ids []interface{}
+		err := c.runOperationContext(ctx, "archive", func(ctx context.Context) error {
+			ids = nil
+			guarded, err := c.guardFilter(ctx, filter)
+			if err != nil {
+				return err
+			}
+			opts := options.Find().SetProjection(bson.M{"_id": 1}).SetSort(bson.D{{"_id", 1}}).SetLimit(int64(policy.BatchSize))
+			cursor, err := c.Collection().Find(ctx, guarded, opts)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+			for cursor.Next(ctx) {
+				ids = append(ids, cursor.Current.Lookup("_id"))
+			}
+			return cursor.Err()
+		})
+		if err != nil || len(ids) == 0 {
+			return archived, err
+		}
+
+		moved := 0
+		err = c.Connection.inTransaction(ctx, func(ctx context.Context) error {
+			moved = 0
+			// Documents that changed since they were picked may not match any more
+			batch, err := c.guardFilter(ctx, AndFilter(filter, bson.M{"_id": bson.M{"$in": ids}}))
+			if err != nil {
+				return err
+			}
+			cursor, err := c.Collection().Find(ctx, batch)
+			if err != nil {
+				return err
+			}
+			var copies, maps []mongo.WriteModel
+			var movedIDs bson.A
+			now := time.Now()
+			for cursor.Next(ctx) {
+				id := cursor.Current.Lookup("_id")
+				doc := bson.Raw(append([]byte{}, cursor.Current...))
+				copies = append(copies, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": id}).SetReplacement(doc).SetUpsert(true))
+				mapping := &archiveMapping{
+					Key:        archiveKey{Collection: c.Name, ID: id},
+					Database:   policy.ArchiveDatabase,
+					Archive:    policy.ArchiveCollection,
+					ArchivedAt: now,
+				}
+				maps = append(maps, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": mapping.Key}).SetReplacement(mapping).SetUpsert(true))
+				movedIDs = append(movedIDs, id)
+			}
+			err = cursor.Err()
+			cursor.Close(ctx)
+			if err != nil || len(movedIDs) == 0 {
+				return err
+			}
+
+			if _, err := archive.Collection().BulkWrite(ctx, copies); err != nil {
+				return err
+			}
+			if _, err := mappings.Collection().BulkWrite(ctx, maps); err != nil {
+				return err
+			}
+			if _, err := c.Collection().DeleteMany(ctx, bson.M{"_id": bson.M{"$in": movedIDs}}); err != nil {
+				return err
+			}
+			moved = len(movedIDs)
+			return nil
+		})
+		if err != nil {
+			return archived, err
+		}
+		archived += moved
+		if len(ids) < policy.BatchSize {
+			return archived, nil
+		}
+	}
+}
+
+// Finds a document Archive moved out of the collection, or returns a *DocumentNotFoundError if it wasn't archived
+func (c *Collection) FindArchived(ctx context.Context, id primitive.ObjectID, doc interface{}) error {
+	mapping := &archiveMapping{}
+	mappings := c.Connection.CollectionFromDatabase(ArchiveMapCollection, c.Database)
+	err := mappings.runOperationContext(ctx, "findArchived", func(ctx context.Context) error {
+		return mappings.Collection().FindOne(ctx, bson.M{"_id": archiveKey{Collection: c.Name, ID: id}}).Decode(mapping)
+	})
+	if err == mongo.ErrNoDocuments {
+		return &DocumentNotFoundError{}
+	}
+	if err != nil {
+		return err
+	}
+
+	archive := c.Connection.CollectionFromDatabase(mapping.Archive, mapping.Database)
+	var raw bson.Raw
+	err = archive.runOperationContext(ctx, "findArchived", func(ctx context.Context) error {
+		var err error
+		raw, err = archive.Collection().FindOne(ctx, bson.M{"_id": id}).Raw()
+		return err
+	})
+	if err == mongo.ErrNoDocuments {
+		return &DocumentNotFoundError{}
+	}
+	if err != nil {
+		return err
+	}
+	return c.decodeFound(raw, doc)
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+func TestArchivalPolicy(t *testing.T) {
+	Convey("Archival policies", t, func() {
+		Convey("should apply the defaults", func() {
+			policy := (&ArchivalPolicy{Age: time.Hour}).withDefaults("orders", "bongotest")
+			So(policy.AgeField, ShouldEqual, "created_at")
+			So(policy.ArchiveDatabase, ShouldEqual, "bongotest")
+			So(policy.ArchiveCollection, ShouldEqual, "orders_archive")
+			So(policy.BatchSize, ShouldEqual, 100)
+		})
+
+		Convey("should keep what is set", func() {
+			policy := &ArchivalPolicy{AgeField: "closed_at", ArchiveDatabase: "cold", ArchiveCollection: "old_orders", BatchSize: 10}
+			defaulted := policy.withDefaults("orders", "bongotest")
+			So(defaulted, ShouldResemble, policy)
+			So(defaulted, ShouldNotPointTo, policy)
+		})
+	})
+}
+
+func TestArchive(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Archive", t, func() {
+		ctx := context.Background()
+		conn.Config.ArchivalPolicies = map[string]*ArchivalPolicy{
+			"tests": {Filter: bson.M{"name": "old"}, Age: time.Hour, BatchSize: 1},
+		}
+		tests := conn.Collection("tests")
+
+		old := &noHookDocument{Name: "old"}
+		So(tests.Save(old), ShouldBeNil)
+		kept := &noHookDocument{Name: "kept"}
+		So(tests.Save(kept), ShouldBeNil)
+		_, err := tests.Collection().UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"created_at": time.Now().Add(-2 * time.Hour)}})
+		So(err, ShouldBeNil)
+
+		Convey("should move matching documents to the archive", func() {
+			archived, err := conn.Archive(ctx)
+			So(err, ShouldBeNil)
+			So(archived["tests"], ShouldEqual, 1)
+
+			So(tests.FindByID(old.ID, &noHookDocument{}), ShouldHaveSameTypeAs, &DocumentNotFoundError{})
+			So(tests.FindByID(kept.ID, &noHookDocument{}), ShouldBeNil)
+
+			found := &noHookDocument{}
+			So(tests.FindArchived(ctx, old.ID, found), ShouldBeNil)
+			So(found.Name, ShouldEqual, "old")
+			So(tests.FindArchived(ctx, kept.ID, found), ShouldHaveSameTypeAs, &DocumentNotFoundError{})
+		})
+
+		Reset(func() {
+			conn.Config.ArchivalPolicies = nil
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+)
+
+// What AutoMigrate did
+const (
+	AUTOMIGRATE_CREATE_COLLECTION     = "create_collection"
+	AUTOMIGRATE_CREATE_INDEXES        = "create_indexes"
+	AUTOMIGRATE_CREATE_VECTOR_INDEXES = "create_vector_indexes"
+	AUTOMIGRATE_SET_VALIDATOR         = "set_validator"
+)
+
+// A step AutoMigrate took for a model
+type AutoMigrateAction struct {
+	Collection string
+
+	// One of the AUTOMIGRATE_ constants
+	Action string
+
+	// The names of the created indexes, if any
+	Indexes []string
+}
+
+func (a *AutoMigrateAction) String() string {
+	if len(a.Indexes) > 0 {
+		return a.Collection + ": " + a.Action + " " + strings.Join(a.Indexes, ", ")
+	}
+	return a.Collection + ": " + a.Action
+}
+
+// Bootstraps the collections of models, e.g. for a new service or a test database: creates each collection if it
+// doesn't exist, creates its declared indexes (and vector search indexes, if any) and sets the model's JSONSchema as
+// its validator. Without models, all registered models are migrated:
+//
+//	actions, err := connection.AutoMigrate()
+//
+// The validator uses the "moderate" validation level, so updates to documents that were invalid before keep
+// working. Every step can run again, and the actions that were taken are logged and returned. Stops at the first
+// error, returning the actions taken so far
+func (m *Connection) AutoMigrate(models ...*Model) ([]*AutoMigrateAction, error) {
+	if len(models) == 0 {
+		models = Models()
+	}
+
+	actions := make([]*AutoMigrateAction, 0)
+	record := func(action *AutoMigrateAction) {
+		actions = append(actions, action)
+		m.logf(LOG_DEBUG, "auto migrate: %s", action)
+	}
+
+	for _, model := range models {
+		if err := m.autoMigrate(model, record); err != nil {
+			return actions, err
+		}
+	}
+	return actions, nil
+}
+
+func (m *Connection) autoMigrate(model *Model, record func(*AutoMigrateAction)) error {
+	collection := m.ModelCollection(model)
+	db := collection.Collection().Database()
+	ctx, cancel := collection.operationContext()
+	defer cancel()
+
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": collection.Name})
+	if err != nil {
+		return err
+	}
+	schema := model.JSONSchema()
+	if len(names) == 0 {
+		opts := options.CreateCollection().SetValidator(bson.M{"$jsonSchema": schema}).SetValidationLevel("moderate")
+		if err := db.CreateCollection(ctx, collection.Name, opts); err != nil {
+			return err
+		}
+		record(&AutoMigrateAction{Collection: collection.Name, Action: AUTOMIGRATE_CREATE_COLLECTION})
+	} else {
+		if err := db.RunCommand(ctx, validatorCommand(collection.Name, schema)).Err(); err != nil {
+			return err
+		}
+	}
+	record(&AutoMigrateAction{Collection: collection.Name, Action: AUTOMIGRATE_SET_VALIDATOR})
+
+	indexes, err := m.SyncIndexes(model)
+	if err != nil {
+		return err
+	}
+	if len(indexes) > 0 {
+		record(&AutoMigrateAction{Collection: collection.Name, Action: AUTOMIGRATE_CREATE_INDEXES, Indexes: indexes})
+	}
+
+	// Vector search indexes need Atlas, so they are only synced for models that declare some
+	if specs, err := model.VectorIndexes(); err != nil || len(specs) > 0 {
+		created, err := m.SyncVectorIndexes(model)
+		if err != nil {
+			return err
+		}
+		if len(created) > 0 {
+			record(&AutoMigrateAction{Collection: collection.Name, Action: AUTOMIGRATE_CREATE_VECTOR_INDEXES, Indexes: created})
+		}
+	}
+	return nil
+}
+
+// The collMod command that sets the $jsonSchema validator of an existing collection
+func validatorCommand(collection string, schema bson.M) bson.D {
+	return bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: bson.M{"$jsonSchema": schema}},
+		{Key: "validationLevel", Value: "moderate"},
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+type autoMigrateDocument struct {
+	DocumentBase `bson:",inline"`
+	Email        string `bson:"email" index:",unique"`
+	Name         string `bson:"name"`
+}
+
+func TestAutoMigrateCommands(t *testing.T) {
+	Convey("AutoMigrate", t, func() {
+		Convey("should set the validator of existing collections", func() {
+			schema := bson.M{"bsonType": "object"}
+			So(validatorCommand("people", schema), ShouldResemble, bson.D{
+				{Key: "collMod", Value: "people"},
+				{Key: "validator", Value: bson.M{"$jsonSchema": schema}},
+				{Key: "validationLevel", Value: "moderate"},
+			})
+		})
+
+		Convey("should describe its actions", func() {
+			action := &AutoMigrateAction{Collection: "people", Action: AUTOMIGRATE_CREATE_INDEXES, Indexes: []string{"email_1"}}
+			So(action.String(), ShouldEqual, "people: create_indexes email_1")
+		})
+	})
+}
+
+func TestAutoMigrate(t *testing.T) {
+	conn := getConnection()
+
+	Convey("AutoMigrate", t, func() {
+		model := &Model{Collection: "automigrated", Type: reflect.TypeOf(autoMigrateDocument{})}
+
+		Convey("should create the collection, its indexes and its validator", func() {
+			actions, err := conn.AutoMigrate(model)
+			So(err, ShouldBeNil)
+			So(len(actions), ShouldEqual, 3)
+			So(actions[0].Action, ShouldEqual, AUTOMIGRATE_CREATE_COLLECTION)
+			So(actions[1].Action, ShouldEqual, AUTOMIGRATE_SET_VALIDATOR)
+			So(actions[2].Indexes, ShouldContain, "email_1")
+
+			_, err = conn.Collection("automigrated").Collection().InsertOne(context.Background(), bson.M{"email": 1})
+			So(err, ShouldNotBeNil)
+
+			actions, err = conn.AutoMigrate(model)
+			So(err, ShouldBeNil)
+			So(actions[0].Action, ShouldEqual, AUTOMIGRATE_SET_VALIDATOR)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
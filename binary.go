@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strconv"
+)
+
+// Bytes that are stored as BSON binary of the given subtype (bsontype.BinaryGeneric, bsontype.BinaryUUID,
+// bsontype.BinaryMD5, ...) and written to JSON as a base64 string. Arrays of numbers that were stored by mistake are
+// read as well, so they are converted the next time the document is saved
+type Binary struct {
+	Data    []byte
+	Subtype byte
+}
+
+// The number of bytes
+func (b Binary) Len() int {
+	return len(b.Data)
+}
+
+// Checks the size of the bytes for a Validate hook. Returns a *FieldError with the code "min_size" or "max_size"
+// if there are fewer than min or more than max bytes. A max of 0 means no maximum
+func (b Binary) ValidateSize(field string, min int, max int) error {
+	if len(b.Data) < min {
+		return &FieldError{Field: field, Code: "min_size", Params: map[string]interface{}{"min": min},
+			Message: field + " must have at least " + strconv.Itoa(min) + " bytes"}
+	}
+	if max > 0 && len(b.Data) > max {
+		return &FieldError{Field: field, Code: "max_size", Params: map[string]interface{}{"max": max},
+			Message: field + " must have at most " + strconv.Itoa(max) + " bytes"}
+	}
+	return nil
+}
+
+func (b Binary) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if b.Data == nil {
+		return bsontype.Null, nil, nil
+	}
+	return bson.MarshalValue(primitive.Binary{Subtype: b.Subtype, Data: b.Data})
+}
+
+func (b *Binary) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	*b = Binary{}
+	raw := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		return nil
+	case bsontype.Array:
+		numbers := []int64{}
+		if err := raw.Unmarshal(&numbers); err != nil {
+			return err
+		}
+		b.Data = make([]byte, len(numbers))
+		for i, n := range numbers {
+			b.Data[i] = byte(n)
+		}
+		return nil
+	}
+	binary := primitive.Binary{}
+	if err := raw.Unmarshal(&binary); err != nil {
+		return err
+	}
+	b.Data, b.Subtype = binary.Data, binary.Subtype
+	return nil
+}
+
+func (b Binary) MarshalJSON() ([]byte, error) {
+	if b.Data == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(b.Data))
+}
+
+// Reads a base64 string. The subtype is left as it is
+func (b *Binary) UnmarshalJSON(data []byte) error {
+	var encoded *string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	if encoded == nil {
+		b.Data = nil
+		return nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(*encoded)
+	if err != nil {
+		return err
+	}
+	b.Data = decoded
+	return nil
+}
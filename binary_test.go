@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"testing"
+)
+
+type binaryDocument struct {
+	DocumentBase `bson:",inline"`
+	Checksum     Binary `bson:"checksum" json:"checksum"`
+}
+
+func TestBinary(t *testing.T) {
+	Convey("Binary", t, func() {
+		Convey("should be stored as BSON binary with its subtype", func() {
+			raw, err := bson.Marshal(&binaryDocument{Checksum: Binary{Data: []byte{1, 2, 3}, Subtype: bsontype.BinaryMD5}})
+			So(err, ShouldBeNil)
+			subtype, data := bson.Raw(raw).Lookup("checksum").Binary()
+			So(subtype, ShouldEqual, bsontype.BinaryMD5)
+			So(data, ShouldResemble, []byte{1, 2, 3})
+
+			found := &binaryDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Checksum, ShouldResemble, Binary{Data: []byte{1, 2, 3}, Subtype: bsontype.BinaryMD5})
+		})
+
+		Convey("should read arrays of numbers", func() {
+			raw, err := bson.Marshal(bson.M{"checksum": bson.A{int32(1), int64(2), 3.0}})
+			So(err, ShouldBeNil)
+
+			found := &binaryDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Checksum.Data, ShouldResemble, []byte{1, 2, 3})
+		})
+
+		Convey("should be null without data", func() {
+			raw, err := bson.Marshal(&binaryDocument{})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("checksum").Type, ShouldEqual, bson.TypeNull)
+		})
+
+		Convey("should be base64 in JSON", func() {
+			data, err := json.Marshal(Binary{Data: []byte("foo")})
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `"Zm9v"`)
+
+			b := Binary{}
+			So(json.Unmarshal(data, &b), ShouldBeNil)
+			So(string(b.Data), ShouldEqual, "foo")
+
+			data, err = json.Marshal(Binary{})
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "null")
+		})
+
+		Convey("should validate its size", func() {
+			b := Binary{Data: []byte("foo")}
+			So(b.ValidateSize("checksum", 1, 3), ShouldBeNil)
+			So(b.ValidateSize("checksum", 4, 0).(*FieldError).Code, ShouldEqual, "min_size")
+			So(b.ValidateSize("checksum", 0, 2).(*FieldError).Code, ShouldEqual, "max_size")
+		})
+	})
+}
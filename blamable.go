@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+// Where CreatedByTracker documents store who created them, as in Blamable
+const createdByField = "created_by"
+
+// Implemented by principals that can be recorded as the creator or last editor of documents, see Blamable
+type IdentifiedPrincipal interface {
+	Principal
+	PrincipalID() string
+}
+
+type CreatedByTracker interface {
+	GetCreatedBy() string
+	SetCreatedBy(string)
+}
+
+type UpdatedByTracker interface {
+	GetUpdatedBy() string
+	SetUpdatedBy(string)
+}
+
+// Records who created and last saved a document, from the principal in the context of the collection it is saved
+// with (see PrincipalContextKey), if that is an IdentifiedPrincipal:
+//
+//	type Order struct {
+//		bongo.DocumentBase `bson:",inline"`
+//		bongo.Blamable     `bson:",inline"`
+//	}
+//
+//	ctx := bongo.PrincipalContextKey.WithValue(r.Context(), user)
+//	err := conn.Collection("orders").WithContext(ctx).Save(order) // order.UpdatedBy == user.PrincipalID()
+//
+// Saves without such a principal, e.g. from jobs, leave both fields as they are
+type Blamable struct {
+	CreatedBy string `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+}
+
+// Sets who created the document
+func (b *Blamable) SetCreatedBy(id string) {
+	b.CreatedBy = id
+}
+
+// Get who created the document
+func (b *Blamable) GetCreatedBy() string {
+	return b.CreatedBy
+}
+
+// Sets who last saved the document
+func (b *Blamable) SetUpdatedBy(id string) {
+	b.UpdatedBy = id
+}
+
+// Get who last saved the document
+func (b *Blamable) GetUpdatedBy() string {
+	return b.UpdatedBy
+}
+
+// Records the principal of the collection's context as the creator (if the document is new) and last editor of a
+// document
+func (c *Collection) blame(doc Document, isNew bool) {
+	principal, ok := c.principal().(IdentifiedPrincipal)
+	if !ok {
+		return
+	}
+	id := principal.PrincipalID()
+	if len(id) == 0 {
+		return
+	}
+
+	if tracker, ok := doc.(CreatedByTracker); ok && isNew {
+		tracker.SetCreatedBy(id)
+	}
+	if tracker, ok := doc.(UpdatedByTracker); ok {
+		tracker.SetUpdatedBy(id)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+type idPrincipal string
+
+func (p idPrincipal) HasRole(role string) bool {
+	return false
+}
+
+func (p idPrincipal) PrincipalID() string {
+	return string(p)
+}
+
+type blamableDocument struct {
+	DocumentBase `bson:",inline"`
+	Blamable     `bson:",inline"`
+	Name         string `bson:"name"`
+}
+
+func TestBlamable(t *testing.T) {
+	Convey("Blamable documents", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+		orders := conn.Collection("orders")
+		doc := &blamableDocument{Name: "Foo"}
+
+		Convey("should record who created and updated them", func() {
+			alice := orders.WithContext(PrincipalContextKey.WithValue(context.Background(), idPrincipal("alice")))
+			alice.blame(doc, true)
+			So(doc.CreatedBy, ShouldEqual, "alice")
+			So(doc.UpdatedBy, ShouldEqual, "alice")
+
+			orders.WithContextValue(PrincipalContextKey, Principal(idPrincipal("bob"))).blame(doc, false)
+			So(doc.CreatedBy, ShouldEqual, "alice")
+			So(doc.UpdatedBy, ShouldEqual, "bob")
+		})
+
+		Convey("should be left alone without an identified principal", func() {
+			orders.blame(doc, true)
+			orders.WithContextValue(PrincipalContextKey, Principal(rolePrincipal{"admin"})).blame(doc, true)
+			orders.WithContextValue(PrincipalContextKey, Principal(idPrincipal(""))).blame(doc, true)
+			So(doc.CreatedBy, ShouldEqual, "")
+			So(doc.UpdatedBy, ShouldEqual, "")
+		})
+
+		Convey("should only set the creator on insert when upserted", func() {
+			doc.CreatedBy, doc.UpdatedBy = "alice", "alice"
+			update, err := upsertUpdate(doc, primitive.NewObjectID(), time.Now(), nil)
+			So(err, ShouldBeNil)
+			So(update["$setOnInsert"].(bson.M)["created_by"], ShouldEqual, "alice")
+			So(update["$set"].(bson.M)["updated_by"], ShouldEqual, "alice")
+			_, set := update["$set"].(bson.M)["created_by"]
+			So(set, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,539 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// What a cached collection does with the cached copy of a document when it is saved
+const (
+	// Drop the cached copy, so the next read loads it again
+	CACHE_WRITE_INVALIDATE = ""
+	// Write the document to the database, then cache it
+	CACHE_WRITE_THROUGH = "write_through"
+	// Cache the document and queue the write, see CacheConfig.FlushInterval
+	CACHE_WRITE_BEHIND = "write_behind"
+)
+
+// Defaults of CacheConfig and NewMemoryCache
+const (
+	CACHE_FLUSH_INTERVAL = time.Second
+	CACHE_FLUSH_SIZE     = 1000
+	CACHE_MAX_ENTRIES    = 10000
+)
+
+// Where cached collections keep their documents, as the BSON they are stored as, e.g. a MemoryCache or a client of
+// a shared cache like Redis. Must be safe for concurrent use
+type Cache interface {
+	// The value of a key, and whether it was found (and hasn't expired)
+	Get(key string) ([]byte, bool)
+
+	// Stores a value, for ttl or forever if it is zero
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+}
+
+// How a collection caches its documents by _id. Only FindByID and the writes of single documents (Save,
+// DeleteDocument and the writes that run their hooks, like SaveMany and UnitOfWork) use the cache; updates and
+// deletes by query bypass it, so TTL bounds how stale cached copies can get
+type CacheConfig struct {
+	// Defaults to a MemoryCache shared by the collections of the connection
+	Cache Cache
+
+	// Load the documents FindByID doesn't find in the cache from the database and cache them. Finds with Select,
+	// Exclude or Project always go to the database
+	ReadThrough bool
+
+	// One of the CACHE_WRITE_ constants. Write behind also reads through, since the database may not have the
+	// latest version of a document yet
+	Write string
+
+	// How long documents stay cached. Zero means until they are written or evicted
+	TTL time.Duration
+
+	// Adds up to this much to TTL at random, so documents cached together don't expire together
+	Jitter time.Duration
+
+	// How often queued writes are flushed, with write behind. Defaults to CACHE_FLUSH_INTERVAL
+	FlushInterval time.Duration
+
+	// Flush as soon as this many documents are queued, with write behind. Defaults to CACHE_FLUSH_SIZE
+	FlushSize int
+}
+
+// A Cache in memory, dropping the least recently used values when it is full
+type MemoryCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+type memoryCacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// Creates a memory cache for up to maxEntries values. Zero or less means CACHE_MAX_ENTRIES
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = CACHE_MAX_ENTRIES
+	}
+	return &MemoryCache{maxEntries: maxEntries, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		m.order.Remove(element)
+		delete(m.entries, key)
+		return nil, false
+	}
+	m.order.MoveToFront(element)
+	return append([]byte{}, entry.value...), true
+}
+
+func (m *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	entry := &memoryCacheEntry{key: key, value: append([]byte{}, value...)}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		m.order.Remove(element)
+	}
+	m.entries[key] = m.order.PushFront(entry)
+	for m.order.Len() > m.maxEntries {
+		oldest := m.order.Remove(m.order.Back()).(*memoryCacheEntry)
+		delete(m.entries, oldest.key)
+	}
+}
+
+func (m *MemoryCache) Delete(key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		m.order.Remove(element)
+		delete(m.entries, key)
+	}
+}
+
+// Changes how many values the cache holds, dropping the least recently used ones over it. Zero or less means
+// CACHE_MAX_ENTRIES
+func (m *MemoryCache) Resize(maxEntries int) {
+	if maxEntries <= 0 {
+		maxEntries = CACHE_MAX_ENTRIES
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.maxEntries = maxEntries
+	for m.order.Len() > m.maxEntries {
+		oldest := m.order.Remove(m.order.Back()).(*memoryCacheEntry)
+		delete(m.entries, oldest.key)
+	}
+}
+
+// The number of values in the cache, including expired ones that weren't read since
+func (m *MemoryCache) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.order.Len()
+}
+
+// The shared memory cache and the write behind queues of a connection
+type connectionCaches struct {
+	mutex  sync.Mutex
+	memory *MemoryCache
+	queues map[string]*writeBehindQueue
+}
+
+// The writes of a collection waiting to be flushed, by _id, so only the last save of a document is written
+type writeBehindQueue struct {
+	// A plain copy of the collection that queued the first write, without the context (and session) of its request,
+	// which may be over by the time the writes are flushed
+	collection *Collection
+	mutex      sync.Mutex
+	pending    map[primitive.ObjectID]mongo.WriteModel
+	timer      *time.Timer
+
+	// One flush at a time, so two flushes never write versions of a document out of order
+	flushing sync.Mutex
+	// The documents of the flush being written. Direct writes and deletes of them wait for flushed
+	inflight map[primitive.ObjectID]bool
+	flushed  *sync.Cond
+}
+
+// The cache config of the collection, or nil if it isn't cached. Collections with a query guard aren't cached,
+// since cached reads would skip it
+func (c *Collection) cacheConfig() *CacheConfig {
+	if c.Connection == nil || c.Connection.config() == nil || c.queryGuard() != nil {
+		return nil
+	}
+	return c.Connection.config().Caches[c.Name]
+}
+
+func (c *Collection) cache(config *CacheConfig) Cache {
+	if config.Cache != nil {
+		return config.Cache
+	}
+	caches := &c.Connection.caches
+	caches.mutex.Lock()
+	defer caches.mutex.Unlock()
+	if caches.memory == nil {
+		caches.memory = NewMemoryCache(c.Connection.config().CacheMaxEntries)
+	}
+	return caches.memory
+}
+
+// Resizes the shared memory cache, if one was made
+func (caches *connectionCaches) resize(maxEntries int) {
+	caches.mutex.Lock()
+	defer caches.mutex.Unlock()
+	if caches.memory != nil {
+		caches.memory.Resize(maxEntries)
+	}
+}
+
+func (c *Collection) cacheKey(id primitive.ObjectID) string {
+	return c.Database + "." + c.Name + " " + id.Hex()
+}
+
+func (config *CacheConfig) ttl() time.Duration {
+	if config.TTL <= 0 {
+		return 0
+	}
+	if config.Jitter <= 0 {
+		return config.TTL
+	}
+	return config.TTL + time.Duration(rand.Int63n(int64(config.Jitter)))
+}
+
+func (config *CacheConfig) readsThrough() bool {
+	return config.ReadThrough || config.Write == CACHE_WRITE_BEHIND
+}
+
+// Whether the documents found with these options can be cached, i.e. they are complete
+func (o *findOptions) cacheable() bool {
+	return o.projection == nil && len(o.view) == 0
+}
+
+// Decodes the cached copy of a document into doc and completes it like a found document. Returns false if the
+// collection doesn't read through its cache or the document isn't cached
+func (c *Collection) findCached(ctx context.Context, id primitive.ObjectID, doc interface{}, o *findOptions) (bool, error) {
+	config := c.cacheConfig()
+	if config == nil || !config.readsThrough() || !o.cacheable() {
+		return false, nil
+	}
+	raw, ok := c.cache(config).Get(c.cacheKey(id))
+	if !ok {
+		return false, nil
+	}
+	if err := bson.UnmarshalWithRegistry(Registry, raw, doc); err != nil {
+		// Cached by a different version of the model, so load it again
+		c.cache(config).Delete(c.cacheKey(id))
+		return false, nil
+	}
+	return true, c.finishFind(ctx, doc)
+}
+
+// Caches a document found in the database
+func (c *Collection) cacheFound(id primitive.ObjectID, o *findOptions, raw bson.Raw) {
+	config := c.cacheConfig()
+	if config == nil || !config.readsThrough() || !o.cacheable() {
+		return
+	}
+	c.cache(config).Set(c.cacheKey(id), raw, config.ttl())
+}
+
+// Updates the cached copy of a saved document: caches it with write through or behind, or drops it. A direct write
+// of a document also drops its queued write, so the queue doesn't overwrite it with an older version
+func (c *Collection) cacheSaved(doc Document, queued bool) {
+	config := c.cacheConfig()
+	if config == nil {
+		return
+	}
+	if !queued {
+		c.dropQueuedWrite(doc.GetID())
+	}
+
+	key := c.cacheKey(doc.GetID())
+	if config.Write == CACHE_WRITE_THROUGH || config.Write == CACHE_WRITE_BEHIND {
+		if raw, err := bson.MarshalWithRegistry(Registry, doc); err == nil {
+			c.cache(config).Set(key, raw, config.ttl())
+			return
+		}
+	}
+	c.cache(config).Delete(key)
+}
+
+// Drops the cached copy and the queued write of a deleted document
+func (c *Collection) uncache(id primitive.ObjectID) {
+	config := c.cacheConfig()
+	if config == nil {
+		return
+	}
+	c.dropQueuedWrite(id)
+	c.cache(config).Delete(c.cacheKey(id))
+}
+
+// Whether saves on the collection are queued instead of written. Writes in transactions are never queued
+func (c *Collection) writesBehind(ctx context.Context) bool {
+	config := c.cacheConfig()
+	return config != nil && config.Write == CACHE_WRITE_BEHIND && mongo.SessionFromContext(ctx) == nil
+}
+
+// Queues the upsert of a document, to be flushed after FlushInterval or once FlushSize documents are queued
+func (c *Collection) queueWrite(id primitive.ObjectID, doc Document) error {
+	config := c.cacheConfig()
+	raw, err := bson.MarshalWithRegistry(Registry, doc)
+	if err != nil {
+		return err
+	}
+	model := mongo.NewReplaceOneModel().SetFilter(documentFilter(id, doc)).SetReplacement(raw).SetUpsert(true)
+
+	queue := c.Connection.writeBehindQueue(c)
+	queue.mutex.Lock()
+	queue.pending[id] = model
+	size := len(queue.pending)
+	queue.schedule(config)
+	queue.mutex.Unlock()
+
+	flushSize := config.FlushSize
+	if flushSize <= 0 {
+		flushSize = CACHE_FLUSH_SIZE
+	}
+	if size >= flushSize {
+		go queue.flush()
+	}
+	return nil
+}
+
+// Drops the queued write of a document. If a flush is writing it, waits until it is written, so a direct write or
+// delete that follows lands after it
+func (c *Collection) dropQueuedWrite(id primitive.ObjectID) {
+	if c.cacheConfig() == nil {
+		return
+	}
+	caches := &c.Connection.caches
+	caches.mutex.Lock()
+	queue := caches.queues[c.Database+"."+c.Name]
+	caches.mutex.Unlock()
+	if queue == nil {
+		return
+	}
+	queue.mutex.Lock()
+	delete(queue.pending, id)
+	for queue.inflight[id] {
+		queue.flushed.Wait()
+	}
+	// A flush that failed may have queued it again
+	delete(queue.pending, id)
+	queue.mutex.Unlock()
+}
+
+func (m *Connection) writeBehindQueue(c *Collection) *writeBehindQueue {
+	caches := &m.caches
+	caches.mutex.Lock()
+	defer caches.mutex.Unlock()
+
+	if caches.queues == nil {
+		caches.queues = make(map[string]*writeBehindQueue)
+	}
+	name := c.Database + "." + c.Name
+	queue, ok := caches.queues[name]
+	if !ok {
+		queue = newWriteBehindQueue(m.CollectionFromDatabase(c.Name, c.Database))
+		caches.queues[name] = queue
+	}
+	return queue
+}
+
+func newWriteBehindQueue(collection *Collection) *writeBehindQueue {
+	queue := &writeBehindQueue{
+		collection: collection,
+		pending:    make(map[primitive.ObjectID]mongo.WriteModel),
+		inflight:   make(map[primitive.ObjectID]bool),
+	}
+	queue.flushed = sync.NewCond(&queue.mutex)
+	return queue
+}
+
+// Starts the flush timer if it isn't running. Must be called with the queue mutex held
+func (q *writeBehindQueue) schedule(config *CacheConfig) {
+	if q.timer != nil || len(q.pending) == 0 {
+		return
+	}
+	interval := CACHE_FLUSH_INTERVAL
+	if config != nil && config.FlushInterval > 0 {
+		interval = config.FlushInterval
+	}
+	q.timer = time.AfterFunc(interval, func() {
+		if err := q.flush(); err != nil {
+			q.collection.Connection.logf(LOG_ERROR, "flushing writes to %s failed: %s", q.collection.Name, err)
+		}
+	})
+}
+
+// Writes the queued documents with one BulkWrite, within the operation timeout. When the database is unavailable,
+// the documents that weren't saved again meanwhile are queued again. Documents that fail otherwise (validation,
+// duplicate keys, ...) would fail again, so they are dropped and logged
+func (q *writeBehindQueue) flush() error {
+	q.flushing.Lock()
+	defer q.flushing.Unlock()
+
+	q.mutex.Lock()
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	pending := q.pending
+	q.pending = make(map[primitive.ObjectID]mongo.WriteModel)
+	for id := range pending {
+		q.inflight[id] = true
+	}
+	q.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	defer q.settle()
+
+	ids := make([]primitive.ObjectID, 0, len(pending))
+	models := make([]mongo.WriteModel, 0, len(pending))
+	for id, model := range pending {
+		ids = append(ids, id)
+		models = append(models, model)
+	}
+
+	err := q.collection.runOperation("flushWrites", func(ctx context.Context) error {
+		guarded, err := q.collection.guardModels(ctx, models)
+		if err != nil {
+			return err
+		}
+		_, err = q.collection.Collection().BulkWrite(ctx, guarded, options.BulkWrite().SetOrdered(false))
+		return err
+	})
+	if err == nil {
+		return nil
+	}
+
+	requeue, dropped := flushFailures(err, len(models))
+	for i, dropErr := range dropped {
+		q.collection.Connection.logf(LOG_ERROR, "dropping the queued write of %s to %s: %s", ids[i].Hex(), q.collection.Name, dropErr)
+	}
+	if len(requeue) > 0 {
+		q.mutex.Lock()
+		for _, i := range requeue {
+			if _, ok := q.pending[ids[i]]; !ok {
+				q.pending[ids[i]] = models[i]
+			}
+		}
+		q.schedule(q.collection.cacheConfig())
+		q.mutex.Unlock()
+	}
+	return err
+}
+
+// Ends a flush: wakes the writes waiting for its documents
+func (q *writeBehindQueue) settle() {
+	q.mutex.Lock()
+	q.inflight = make(map[primitive.ObjectID]bool)
+	q.flushed.Broadcast()
+	q.mutex.Unlock()
+}
+
+// Splits the documents of a failed flush by their index in the bulk write: those to queue again because the
+// database was unavailable, and those that failed for good, with their errors
+func flushFailures(err error, count int) ([]int, map[int]error) {
+	requeue := make([]int, 0)
+	dropped := make(map[int]error)
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, writeErr := range bulkErr.WriteErrors {
+			dropped[writeErr.Index] = writeErr
+		}
+		// The others were written, unless the write concern wasn't met
+		if bulkErr.WriteConcernError != nil {
+			for i := 0; i < count; i++ {
+				if _, ok := dropped[i]; !ok {
+					requeue = append(requeue, i)
+				}
+			}
+		}
+		return requeue, dropped
+	}
+
+	var open *CircuitOpenError
+	var limited *RateLimitError
+	unavailable := IsTransientError(err) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &open) || errors.As(err, &limited) || errors.Is(err, mongo.ErrClientDisconnected)
+	for i := 0; i < count; i++ {
+		if unavailable {
+			requeue = append(requeue, i)
+		} else {
+			dropped[i] = err
+		}
+	}
+	return requeue, dropped
+}
+
+// Writes the queued writes of the collections with write behind caches now, e.g. before shutting down. Disconnect
+// flushes them too. Returns the first error; writes that failed because the database is unavailable stay queued
+func (m *Connection) FlushWrites() error {
+	m.caches.mutex.Lock()
+	queues := make([]*writeBehindQueue, 0, len(m.caches.queues))
+	for _, queue := range m.caches.queues {
+		queues = append(queues, queue)
+	}
+	m.caches.mutex.Unlock()
+
+	var first error
+	for _, queue := range queues {
+		if err := queue.flush(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Stops the flush timers, so writes that failed to flush on Disconnect aren't retried on a closed client. They stay
+// queued for FlushWrites
+func (m *Connection) stopFlushing() {
+	m.caches.mutex.Lock()
+	defer m.caches.mutex.Unlock()
+	for _, queue := range m.caches.queues {
+		queue.mutex.Lock()
+		if queue.timer != nil {
+			queue.timer.Stop()
+			queue.timer = nil
+		}
+		queue.mutex.Unlock()
+	}
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	Convey("Memory cache", t, func() {
+		cache := NewMemoryCache(2)
+
+		Convey("should drop the least recently used values", func() {
+			cache.Set("a", []byte("1"), 0)
+			cache.Set("b", []byte("2"), 0)
+			_, ok := cache.Get("a")
+			So(ok, ShouldBeTrue)
+			cache.Set("c", []byte("3"), 0)
+
+			_, ok = cache.Get("b")
+			So(ok, ShouldBeFalse)
+			value, ok := cache.Get("a")
+			So(ok, ShouldBeTrue)
+			So(string(value), ShouldEqual, "1")
+			So(cache.Len(), ShouldEqual, 2)
+
+			cache.Delete("a")
+			_, ok = cache.Get("a")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("should drop the least recently used values when it shrinks", func() {
+			cache.Set("a", []byte("1"), 0)
+			cache.Set("b", []byte("2"), 0)
+			cache.Resize(1)
+			So(cache.Len(), ShouldEqual, 1)
+			_, ok := cache.Get("b")
+			So(ok, ShouldBeTrue)
+
+			cache.Resize(0)
+			So(cache.maxEntries, ShouldEqual, CACHE_MAX_ENTRIES)
+		})
+
+		Convey("should expire values", func() {
+			cache.Set("a", []byte("1"), time.Nanosecond)
+			time.Sleep(time.Millisecond)
+			_, ok := cache.Get("a")
+			So(ok, ShouldBeFalse)
+			So(cache.Len(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestCollectionCache(t *testing.T) {
+	Convey("Collection cache", t, func() {
+		config := &CacheConfig{ReadThrough: true}
+		conn := &Connection{Config: &Config{Database: "bongotest", Caches: map[string]*CacheConfig{"tests": config}}, Context: &Context{}}
+		tests := conn.Collection("tests")
+
+		doc := &noHookDocument{Name: "cached"}
+		doc.ID = primitive.NewObjectID()
+		raw, err := bson.Marshal(doc)
+		So(err, ShouldBeNil)
+
+		Convey("should find cached documents without querying", func() {
+			tests.cache(config).Set(tests.cacheKey(doc.ID), raw, 0)
+
+			found := &noHookDocument{}
+			So(tests.FindByID(doc.ID, found), ShouldBeNil)
+			So(found.Name, ShouldEqual, "cached")
+			So(found.IsNew(), ShouldBeFalse)
+
+			ok, err := tests.findCached(context.Background(), doc.ID, &noHookDocument{}, newFindOptions([]FindOption{Select("name")}))
+			So(ok, ShouldBeFalse)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("should only cache complete documents", func() {
+			tests.cacheFound(doc.ID, newFindOptions([]FindOption{Exclude("name")}), raw)
+			_, ok := tests.cache(config).Get(tests.cacheKey(doc.ID))
+			So(ok, ShouldBeFalse)
+
+			tests.cacheFound(doc.ID, newFindOptions(nil), raw)
+			_, ok = tests.cache(config).Get(tests.cacheKey(doc.ID))
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("should drop saved documents by default", func() {
+			tests.cacheFound(doc.ID, newFindOptions(nil), raw)
+			So(tests.finishSave(doc, newWriteOptions(nil)), ShouldBeNil)
+			_, ok := tests.cache(config).Get(tests.cacheKey(doc.ID))
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("should cache saved documents with write through", func() {
+			config.Write = CACHE_WRITE_THROUGH
+			doc.Name = "saved"
+			So(tests.finishSave(doc, newWriteOptions(nil)), ShouldBeNil)
+
+			found := &noHookDocument{}
+			So(tests.FindByID(doc.ID, found), ShouldBeNil)
+			So(found.Name, ShouldEqual, "saved")
+
+			So(tests.finishDelete(doc, newWriteOptions([]WriteOption{SkipCascade()})), ShouldBeNil)
+			_, ok := tests.cache(config).Get(tests.cacheKey(doc.ID))
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("should queue the last save of documents with write behind", func() {
+			config.Write = CACHE_WRITE_BEHIND
+			config.FlushInterval = time.Hour
+			So(tests.writesBehind(context.Background()), ShouldBeTrue)
+
+			So(tests.queueWrite(doc.ID, doc), ShouldBeNil)
+			So(tests.queueWrite(doc.ID, doc), ShouldBeNil)
+			queue := conn.writeBehindQueue(tests)
+			So(len(queue.pending), ShouldEqual, 1)
+			So(queue.timer, ShouldNotBeNil)
+
+			Convey("and drop it when the document is written directly", func() {
+				So(tests.finishSave(doc, newWriteOptions(nil)), ShouldBeNil)
+				So(len(queue.pending), ShouldEqual, 0)
+			})
+
+			Convey("and keep it when the save was queued", func() {
+				So(tests.finishSave(doc, &writeOptions{queued: true}), ShouldBeNil)
+				So(len(queue.pending), ShouldEqual, 1)
+			})
+
+			Convey("and drop it when the document is deleted", func() {
+				tests.uncache(doc.ID)
+				So(len(queue.pending), ShouldEqual, 0)
+			})
+
+			Convey("and make a delete wait for the flush writing it", func() {
+				queue.mutex.Lock()
+				queue.inflight[doc.ID] = true
+				queue.mutex.Unlock()
+
+				deleted := make(chan struct{})
+				go func() {
+					tests.uncache(doc.ID)
+					close(deleted)
+				}()
+
+				select {
+				case <-deleted:
+					t.Error("the delete didn't wait for the flush")
+				case <-time.After(20 * time.Millisecond):
+				}
+				queue.settle()
+				<-deleted
+				So(len(queue.pending), ShouldEqual, 0)
+			})
+
+			Reset(func() {
+				conn.stopFlushing()
+			})
+		})
+
+		Convey("should flush without the context of the request that queued the write", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			request := tests.WithContext(ctx)
+			queue := conn.writeBehindQueue(request)
+			So(queue.collection, ShouldNotEqual, request)
+			So(queue.collection.baseContext().Err(), ShouldBeNil)
+			So(queue.collection.Name, ShouldEqual, "tests")
+		})
+
+		Convey("should drop the writes that can't succeed", func() {
+			bulkErr := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}}}}
+			requeue, dropped := flushFailures(bulkErr, 3)
+			So(requeue, ShouldBeEmpty)
+			So(dropped, ShouldContainKey, 1)
+			So(len(dropped), ShouldEqual, 1)
+
+			bulkErr.WriteConcernError = &mongo.WriteConcernError{Message: "waiting for replication timed out"}
+			requeue, _ = flushFailures(bulkErr, 3)
+			So(requeue, ShouldResemble, []int{0, 2})
+
+			requeue, dropped = flushFailures(mongo.BulkWriteException{WriteConcernError: &mongo.WriteConcernError{Code: 64}}, 2)
+			So(requeue, ShouldResemble, []int{0, 1})
+			So(dropped, ShouldBeEmpty)
+
+			requeue, dropped = flushFailures(fmt.Errorf("flushing: %w", mongo.BulkWriteException{WriteConcernError: &mongo.WriteConcernError{Code: 64}}), 2)
+			So(requeue, ShouldResemble, []int{0, 1})
+			So(dropped, ShouldBeEmpty)
+
+			requeue, dropped = flushFailures(&CircuitOpenError{}, 2)
+			So(requeue, ShouldResemble, []int{0, 1})
+			So(dropped, ShouldBeEmpty)
+
+			requeue, dropped = flushFailures(errors.New("document failed validation"), 2)
+			So(requeue, ShouldBeEmpty)
+			So(len(dropped), ShouldEqual, 2)
+		})
+
+		Convey("should add jitter to the TTL", func() {
+			config.TTL = time.Minute
+			config.Jitter = time.Second
+			for i := 0; i < 10; i++ {
+				ttl := config.ttl()
+				So(ttl, ShouldBeGreaterThanOrEqualTo, time.Minute)
+				So(ttl, ShouldBeLessThan, time.Minute+time.Second)
+			}
+		})
+
+		Convey("should skip collections with a query guard", func() {
+			conn.Config.QueryGuards = map[string]QueryGuard{"tests": func(ctx context.Context, filter interface{}) (interface{}, error) {
+				return filter, nil
+			}}
+			So(tests.cacheConfig(), ShouldBeNil)
+		})
+	})
+}
+
+func TestWriteBehind(t *testing.T) {
+	Convey("Write behind", t, func() {
+		conn := getConnection()
+		conn.Config.Caches = map[string]*CacheConfig{"tests": {Write: CACHE_WRITE_BEHIND, FlushInterval: time.Hour}}
+		tests := conn.Collection("tests")
+
+		doc := &noHookDocument{Name: "queued"}
+		So(tests.Save(doc), ShouldBeNil)
+
+		count, err := tests.Collection().CountDocuments(context.Background(), bson.M{"_id": doc.ID})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+
+		found := &noHookDocument{}
+		So(tests.FindByID(doc.ID, found), ShouldBeNil)
+		So(found.Name, ShouldEqual, "queued")
+
+		So(conn.FlushWrites(), ShouldBeNil)
+		count, err = tests.Collection().CountDocuments(context.Background(), bson.M{"_id": doc.ID})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+
+		Convey("should not bring back a document deleted during a flush", func() {
+			doc.Name = "requeued"
+			So(tests.Save(doc), ShouldBeNil)
+			So(blockCommand(conn, "update", 200*time.Millisecond), ShouldBeNil)
+
+			flushed := make(chan error)
+			go func() {
+				flushed <- conn.FlushWrites()
+			}()
+			queue := conn.writeBehindQueue(tests)
+			for {
+				queue.mutex.Lock()
+				writing := queue.inflight[doc.ID]
+				queue.mutex.Unlock()
+				if writing {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			_, err := tests.DeleteDocument(doc)
+			So(err, ShouldBeNil)
+			So(<-flushed, ShouldBeNil)
+
+			count, err := tests.Collection().CountDocuments(context.Background(), bson.M{"_id": doc.ID})
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 0)
+		})
+
+		Reset(func() {
+			conn.Session.Database("admin").RunCommand(context.Background(), bson.D{{"configureFailPoint", "failCommand"}, {"mode", "off"}})
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
+
+// Makes the next run of a command wait before it runs, like a slow write. Needs a server with test commands enabled
+func blockCommand(conn *Connection, command string, wait time.Duration) error {
+	return conn.Session.Database("admin").RunCommand(context.Background(), bson.D{
+		{"configureFailPoint", "failCommand"},
+		{"mode", bson.M{"times": 1}},
+		{"data", bson.M{
+			"failCommands":    bson.A{command},
+			"blockConnection": true,
+			"blockTimeMS":     wait.Milliseconds(),
+		}},
+	}).Err()
+}
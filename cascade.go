@@ -7,11 +7,13 @@ package bongo
 import (
 	"context"
 	"errors"
-	"github.com/go-bongo/go-dotaccess"
 	"github.com/oleiade/reflections"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
 	"strings"
+	"sync"
 )
 
 // Relation types (one-to-many or one-to-one)
@@ -61,219 +63,431 @@ type CascadeConfig struct {
 
 	// If this is provided, use this field instead of _id for determining "sameness". This must also be a bson.ObjectId field
 	ReferenceQuery []*ReferenceField
+
+	// Load the related documents after the cascade (as Instance, or the model registered for the collection) and
+	// call their AfterCascadedInto hook, e.g. to invalidate caches keyed on them
+	Notify bool
+
+	// The document being cascaded
+	source Document
+}
+
+// Implemented by documents that want to know when a cascade changed their copy of another document
+type AfterCascadedIntoHook interface {
+	AfterCascadedInto(collection *Collection, source Document) error
 }
 
 type CascadeFilter func(data map[string]interface{})
 
+// The cascades running in the background, limited by Config.CascadeConcurrency
+type cascadeLimiter struct {
+	mutex   sync.Mutex
+	running int
+	freed   *sync.Cond
+}
+
+// Runs a cascade on a goroutine of its own, once fewer than Config.CascadeConcurrency cascades are running. The
+// limit is read again whenever a cascade finishes or Reconfigure changes it
+func (m *Connection) goCascade(cascade func()) {
+	if m == nil {
+		go cascade()
+		return
+	}
+	go func() {
+		limiter := &m.cascades
+		limiter.mutex.Lock()
+		if limiter.freed == nil {
+			limiter.freed = sync.NewCond(&limiter.mutex)
+		}
+		for m.cascadeConcurrency() > 0 && limiter.running >= m.cascadeConcurrency() {
+			limiter.freed.Wait()
+		}
+		limiter.running++
+		limiter.mutex.Unlock()
+
+		defer func() {
+			limiter.mutex.Lock()
+			limiter.running--
+			limiter.freed.Broadcast()
+			limiter.mutex.Unlock()
+		}()
+		cascade()
+	}()
+}
+
+func (m *Connection) cascadeConcurrency() int {
+	if config := m.config(); config != nil {
+		return config.CascadeConcurrency
+	}
+	return 0
+}
+
+// Wakes the cascades waiting for the limit, after it changed
+func (limiter *cascadeLimiter) wake() {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if limiter.freed != nil {
+		limiter.freed.Broadcast()
+	}
+}
+
 // Cascades a document's properties to related documents, after it has been prepared
 // for db insertion (encrypted, etc)
 func CascadeSave(collection *Collection, doc Document) error {
-	// Find out which properties to cascade
-	if conv, ok := doc.(CascadingDocument); ok {
-		toCascade := conv.GetCascade(collection)
-		for _, conf := range toCascade {
-			if len(conf.ReferenceQuery) == 0 {
-				conf.ReferenceQuery = []*ReferenceField{{"_id", doc.GetID()}}
+	return runCascadeSave(cascadeConfigs(collection, doc))
+}
+
+// Gets the cascade configs of documents that are being saved. Configs that would write the same data with the
+// same query as for the original values of a document's DiffTracker are left out, so saves that only touch
+// unrelated fields don't rewrite the related documents
+func savedCascadeConfigs(collection *Collection, docs []Document) []*CascadeConfig {
+	toCascade := make([]*CascadeConfig, 0)
+	for _, doc := range docs {
+		configs := cascadeConfigs(collection, doc)
+
+		original := originalDocument(doc)
+		if original == nil {
+			toCascade = append(toCascade, configs...)
+			continue
+		}
+
+		originalConfigs := cascadeConfigs(collection, original)
+		for i, conf := range configs {
+			if len(conf.OldQuery) > 0 || len(originalConfigs) != len(configs) || !sameCascade(conf, originalConfigs[i]) {
+				toCascade = append(toCascade, conf)
 			}
-			_, err := cascadeSaveWithConfig(conf, doc)
+		}
+	}
+	return toCascade
+}
+
+// A copy of the original values of a document with a DiffTracker, or nil if it has none
+func originalDocument(doc Document) Document {
+	tracked, ok := doc.(Trackable)
+	if !ok {
+		return nil
+	}
+	tracker := tracked.GetDiffTracker()
+	if tracker == nil || tracker.original == nil {
+		return nil
+	}
+
+	original := reflect.ValueOf(tracker.original)
+	if reflect.TypeOf(doc).Kind() != reflect.Ptr || reflect.TypeOf(doc).Elem() != original.Type() {
+		return nil
+	}
+	copied := reflect.New(original.Type())
+	copied.Elem().Set(original)
+	document, _ := copied.Interface().(Document)
+	return document
+}
+
+// Whether two cascade configs write the same data to the same documents
+func sameCascade(a *CascadeConfig, b *CascadeConfig) bool {
+	if a.RelType != b.RelType || a.ThroughProp != b.ThroughProp || a.RemoveOnly != b.RemoveOnly || a.Nest != b.Nest {
+		return false
+	}
+
+	x, err := bson.Marshal(bson.M{"query": a.Query, "data": a.Data})
+	if err != nil {
+		return false
+	}
+	y, err := bson.Marshal(bson.M{"query": b.Query, "data": b.Data})
+	if err != nil {
+		return false
+	}
+	_, contained := rawContains(x, y)
+	_, contains := rawContains(y, x)
+	return contained && contains
+}
+
+// Runs the cascade configs of saved documents. The updates of all configs are combined into one ordered BulkWrite
+// per target collection
+func runCascadeSave(toCascade []*CascadeConfig) error {
+	err := runCascadeWrites(toCascade, cascadeSaveModels)
+	if err != nil {
+		return err
+	}
+
+	for _, conf := range toCascade {
+		if conf.Nest && !conf.RemoveOnly {
+			results, err := conf.Collection.Find(conf.Query)
 			if err != nil {
 				return err
 			}
-			if conf.Nest {
-				results, err := conf.Collection.Find(conf.Query)
+			for results.Next(conf.Instance) {
+				err = CascadeSave(conf.Collection, conf.Instance)
 				if err != nil {
 					return err
 				}
-				for results.Next(conf.Instance) {
-					err = CascadeSave(conf.Collection, conf.Instance)
-					if err != nil {
-						return err
-					}
-				}
-
 			}
+
 		}
 	}
-	return nil
+	return notifyCascadeTargets(toCascade)
+}
+
+// The cascade configs of a document, from GetCascade or else the relations of the model
+func cascadeConfigs(collection *Collection, doc Document) []*CascadeConfig {
+	toCascade := relationCascades(collection, doc)
+	if conv, ok := doc.(CascadingDocument); ok {
+		toCascade = conv.GetCascade(collection)
+	}
+	for _, conf := range toCascade {
+		if len(conf.ReferenceQuery) == 0 {
+			conf.ReferenceQuery = []*ReferenceField{{"_id", doc.GetID()}}
+		}
+		conf.source = doc
+	}
+	return toCascade
 }
 
 // Deletes references to a document from its related documents
 func CascadeDelete(collection *Collection, doc interface{}) {
-	// Find out which properties to cascade
+	// Find out which properties to cascade, from GetCascade or else the relations of the model
+	var toCascade []*CascadeConfig
+	if document, ok := doc.(Document); ok {
+		toCascade = relationCascades(collection, document)
+	}
 	if conv, ok := doc.(interface {
 		GetCascade(*Collection) []*CascadeConfig
 	}); ok {
-		toCascade := conv.GetCascade(collection)
+		toCascade = conv.GetCascade(collection)
+	}
+
+	for _, conf := range toCascade {
+		if len(conf.ReferenceQuery) == 0 {
+			id, err := reflections.GetField(doc, "Id")
+			if err != nil {
+				panic(err)
+			}
+			conf.ReferenceQuery = []*ReferenceField{{"_id", id}}
+		}
+		conf.source, _ = doc.(Document)
+	}
 
-		// Get the ID
+	if runCascadeWrites(toCascade, cascadeDeleteModels) == nil {
+		notifyCascadeTargets(toCascade)
+	}
+}
 
-		for _, conf := range toCascade {
-			if len(conf.ReferenceQuery) == 0 {
-				id, err := reflections.GetField(doc, "Id")
-				if err != nil {
-					panic(err)
-				}
-				conf.ReferenceQuery = []*ReferenceField{{"_id", id}}
+// Loads the related documents of configs with Notify (those matching the query or the old query) and calls their
+// AfterCascadedInto hook, once per source and related document
+func notifyCascadeTargets(toCascade []*CascadeConfig) error {
+	notified := make(map[string]bool)
+	for _, conf := range toCascade {
+		if !conf.Notify || conf.source == nil {
+			continue
+		}
+		newTarget := cascadeTargetFactory(conf)
+		if newTarget == nil {
+			continue
+		}
+
+		query := conf.Query
+		if len(conf.OldQuery) > 0 {
+			query = bson.M{"$or": bson.A{conf.Query, conf.OldQuery}}
+		}
+		results, err := conf.Collection.Find(query)
+		if err != nil {
+			return err
+		}
+
+		for {
+			target := newTarget()
+			if !results.Next(target) {
+				break
 			}
 
-			cascadeDeleteWithConfig(conf)
+			key := conf.source.GetID().Hex() + " " + conf.Collection.Name + " " + target.GetID().Hex()
+			if notified[key] {
+				continue
+			}
+			notified[key] = true
 
+			if hook, ok := target.(AfterCascadedIntoHook); ok {
+				if err := hook.AfterCascadedInto(conf.Collection, conf.source); err != nil {
+					results.Free()
+					return err
+				}
+			}
 		}
+		if results.Error != nil {
+			return results.Error
+		}
+	}
+	return nil
+}
 
+// Creates instances of the related documents of a config, or nil if their type is unknown
+func cascadeTargetFactory(conf *CascadeConfig) func() Document {
+	if conf.Instance != nil {
+		t := reflect.TypeOf(conf.Instance)
+		if t.Kind() == reflect.Ptr {
+			return func() Document {
+				return reflect.New(t.Elem()).Interface().(Document)
+			}
+		}
 	}
+	if model := GetModel(conf.Collection.Name); model != nil {
+		return model.New
+	}
+	return nil
 }
 
-// Runs a cascaded delete operation with one configuration
-func cascadeDeleteWithConfig(conf *CascadeConfig) (*mongo.UpdateResult, error) {
+// The updates of cascade configs against one collection
+type cascadeWrite struct {
+	collection *Collection
+	models     []mongo.WriteModel
+}
 
-	switch conf.RelType {
-	case REL_ONE:
-		update := map[string]map[string]interface{}{
-			"$set": {},
-		}
+// Writes the updates of cascade configs, with one ordered BulkWrite per target collection
+func runCascadeWrites(toCascade []*CascadeConfig, modelsFor func(conf *CascadeConfig) ([]mongo.WriteModel, error)) error {
+	writes, err := cascadeWrites(toCascade, modelsFor)
+	if err != nil {
+		return err
+	}
 
-		if len(conf.ThroughProp) > 0 {
-			update["$set"][conf.ThroughProp] = nil
-		} else {
-			for _, p := range conf.Properties {
-				update["$set"][p] = nil
-			}
+	for _, write := range writes {
+		err := write.collection.runOperation("cascade", func(ctx context.Context) error {
+			_, err := write.collection.Collection().BulkWrite(ctx, write.models, options.BulkWrite().SetOrdered(true))
+			return err
+		})
+		if err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		return conf.Collection.Collection().UpdateMany(context.Background(), conf.Query, update)
-	case REL_MANY:
-		update := map[string]map[string]interface{}{
-			"$pull": {},
+// Groups the updates of cascade configs by target collection, in the order the collections first appear
+func cascadeWrites(toCascade []*CascadeConfig, modelsFor func(conf *CascadeConfig) ([]mongo.WriteModel, error)) ([]*cascadeWrite, error) {
+	writes := make([]*cascadeWrite, 0)
+	byTarget := make(map[string]*cascadeWrite)
+
+	for _, conf := range toCascade {
+		models, err := modelsFor(conf)
+		if err != nil {
+			return nil, err
+		}
+		if len(models) == 0 {
+			continue
 		}
 
-		q := bson.M{}
-		for _, f := range conf.ReferenceQuery {
-			q[f.BsonName] = f.Value
+		key := conf.Collection.Database + "." + conf.Collection.Name
+		write, ok := byTarget[key]
+		if !ok {
+			write = &cascadeWrite{collection: conf.Collection}
+			byTarget[key] = write
+			writes = append(writes, write)
 		}
-		update["$pull"][conf.ThroughProp] = q
-		return conf.Collection.Collection().UpdateMany(context.Background(), conf.Query, update)
+		write.models = append(write.models, models...)
 	}
+	return writes, nil
+}
 
-	return &mongo.UpdateResult{}, errors.New("invalid relation type")
+// The $pull of a document from the related documents of a REL_MANY config
+func cascadePull(conf *CascadeConfig) bson.M {
+	q := bson.M{}
+	for _, f := range conf.ReferenceQuery {
+		q[f.BsonName] = f.Value
+	}
+	return bson.M{"$pull": bson.M{conf.ThroughProp: q}}
 }
 
-// Runs a cascaded save operation with one configuration
-func cascadeSaveWithConfig(conf *CascadeConfig, doc Document) (*mongo.UpdateResult, error) {
-	// Create a new map with just the props to cascade
-	data := conf.Data
+// The $set that nullifies a document in the related documents of a REL_ONE config
+func cascadeUnset(conf *CascadeConfig) bson.M {
+	set := bson.M{}
+	if len(conf.ThroughProp) > 0 {
+		set[conf.ThroughProp] = nil
+	} else {
+		for _, p := range conf.Properties {
+			set[p] = nil
+		}
+	}
+	return bson.M{"$set": set}
+}
 
+// The updates of a cascaded delete with one configuration
+func cascadeDeleteModels(conf *CascadeConfig) ([]mongo.WriteModel, error) {
 	switch conf.RelType {
 	case REL_ONE:
-		if len(conf.OldQuery) > 0 {
-
-			update1 := map[string]map[string]interface{}{
-				"$set": {},
-			}
+		return []mongo.WriteModel{mongo.NewUpdateManyModel().SetFilter(conf.Query).SetUpdate(cascadeUnset(conf))}, nil
+	case REL_MANY:
+		return []mongo.WriteModel{mongo.NewUpdateManyModel().SetFilter(conf.Query).SetUpdate(cascadePull(conf))}, nil
+	}
 
-			if len(conf.ThroughProp) > 0 {
-				update1["$set"][conf.ThroughProp] = nil
-			} else {
-				for _, p := range conf.Properties {
-					update1["$set"][p] = nil
-				}
-			}
+	return nil, errors.New("invalid relation type")
+}
 
-			ret, err := conf.Collection.Collection().UpdateMany(context.Background(), conf.OldQuery, update1)
+// The updates of a cascaded save with one configuration: removing the document from its previous relations (if
+// there is an OldQuery) and writing its current data
+func cascadeSaveModels(conf *CascadeConfig) ([]mongo.WriteModel, error) {
+	models := make([]mongo.WriteModel, 0, 3)
 
+	switch conf.RelType {
+	case REL_ONE:
+		if len(conf.OldQuery) > 0 {
+			models = append(models, mongo.NewUpdateManyModel().SetFilter(conf.OldQuery).SetUpdate(cascadeUnset(conf)))
 			if conf.RemoveOnly {
-				return ret, err
+				return models, nil
 			}
 		}
 
-		update := make(map[string]interface{})
-
+		update := bson.M{"$set": conf.Data}
 		if len(conf.ThroughProp) > 0 {
-			m := bson.M{}
-			m[conf.ThroughProp] = data
-			update["$set"] = m
-		} else {
-			update["$set"] = data
+			update = bson.M{"$set": bson.M{conf.ThroughProp: conf.Data}}
 		}
-
-		// Just update
-		return conf.Collection.Collection().UpdateMany(context.Background(), conf.Query, update)
+		return append(models, mongo.NewUpdateManyModel().SetFilter(conf.Query).SetUpdate(update)), nil
 	case REL_MANY:
-
-		update1 := map[string]map[string]interface{}{
-			"$pull": {},
-		}
-
-		q := bson.M{}
-		for _, f := range conf.ReferenceQuery {
-			q[f.BsonName] = f.Value
-		}
-		update1["$pull"][conf.ThroughProp] = q
-
 		if len(conf.OldQuery) > 0 {
-			ret, err := conf.Collection.Collection().UpdateMany(context.Background(), conf.OldQuery, update1)
+			models = append(models, mongo.NewUpdateManyModel().SetFilter(conf.OldQuery).SetUpdate(cascadePull(conf)))
 			if conf.RemoveOnly {
-				return ret, err
+				return models, nil
 			}
 		}
 
 		// Remove self from current relations, so we can replace it
-		conf.Collection.Collection().UpdateMany(context.Background(), conf.Query, update1)
-
-		update2 := map[string]map[string]interface{}{
-			"$push": {},
-		}
-
-		update2["$push"][conf.ThroughProp] = data
-		return conf.Collection.Collection().UpdateMany(context.Background(), conf.Query, update2)
-
+		return append(models,
+			mongo.NewUpdateManyModel().SetFilter(conf.Query).SetUpdate(cascadePull(conf)),
+			mongo.NewUpdateManyModel().SetFilter(conf.Query).SetUpdate(bson.M{"$push": bson.M{conf.ThroughProp: conf.Data}}),
+		), nil
 	}
 
-	return &mongo.UpdateResult{}, errors.New("invalid relation type")
-
+	return nil, errors.New("invalid relation type")
 }
 
 // If you need to, you can use this to construct the i18n map that will be cascaded down to
-// related documents. Doing this is not recommended unless the cascaded fields are dynamic.
+// related documents. Doing this is not recommended unless the cascaded fields are dynamic. Properties are bson
+// paths or Go paths of the document, and keep their names in the map. Values are converted with ToMap, and
+// transient and unknown fields are left out.
 func MapFromCascadeProperties(properties []string, doc Document) map[string]interface{} {
 	data := make(map[string]interface{})
+	stored, err := ToMap(doc)
+	if err != nil {
+		stored = map[string]interface{}{}
+	}
 
 	for _, prop := range properties {
-		split := strings.Split(prop, ".")
+		path, ok := bsonPathOf(reflect.TypeOf(doc), prop)
+		if !ok {
+			continue
+		}
+		val, _ := mapValue(stored, path)
 
-		if len(split) == 1 {
-			data[prop], _ = dotaccess.Get(doc, prop)
-		} else {
-			actualProp := split[len(split)-1]
-			split := append([]string{}, split[:len(split)-1]...)
-			curData := data
-
-			for _, s := range split {
-				if _, ok := curData[s]; ok {
-					if mapped, ok := curData[s].(map[string]interface{}); ok {
-						curData = mapped
-					} else {
-						panic("Cannot access non-map property via dot notation")
-					}
-
-				} else {
-					curData[s] = make(map[string]interface{})
-					if mapped, ok := curData[s].(map[string]interface{}); ok {
-						curData = mapped
-					} else {
-						panic("Cannot access non-map property via dot notation")
-					}
-				}
+		split := strings.Split(prop, ".")
+		curData := data
+		for _, s := range split[:len(split)-1] {
+			if _, ok := curData[s]; !ok {
+				curData[s] = make(map[string]interface{})
 			}
-
-			val, _ := dotaccess.Get(doc, prop)
-			// if bsonId, ok := val.(bson.ObjectId); ok {
-			// 	if !bsonId.Valid() {
-			// 		curData[actualProp] = ""
-			// 		continue
-			// 	}
-			// }
-			curData[actualProp] = val
+			mapped, ok := curData[s].(map[string]interface{})
+			if !ok {
+				panic("Cannot access non-map property via dot notation")
+			}
+			curData = mapped
 		}
+		curData[split[len(split)-1]] = val
 	}
 
 	return data
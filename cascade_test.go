@@ -9,6 +9,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"reflect"
 	"testing"
 	"time"
@@ -258,6 +259,49 @@ func TestCascade(t *testing.T) {
 
 	})
 
+	Convey("Cascade Save - skipped", t, func() {
+		_ = connection.Session.Database("bongotest").Drop(context.Background())
+		collection := connection.Collection("parents")
+		childCollection := connection.Collection("children")
+
+		parent := &Parent{Bar: "Testy McGee"}
+		So(collection.Save(parent), ShouldEqual, nil)
+
+		child := &Child{ParentID: parent.ID, Name: "Foo McGoo"}
+		So(childCollection.Save(child, SkipCascade()), ShouldEqual, nil)
+
+		time.Sleep(100 * time.Millisecond)
+
+		newParent := &Parent{}
+		_ = collection.FindByID(parent.ID, newParent)
+		So(newParent.Child.Name, ShouldEqual, "")
+
+		// An explicit resync cascades it after all
+		So(CascadeSave(childCollection, child), ShouldEqual, nil)
+		_ = collection.FindByID(parent.ID, newParent)
+		So(newParent.Child.Name, ShouldEqual, "Foo McGoo")
+	})
+
+	Convey("Cascade Save - many", t, func() {
+		_ = connection.Session.Database("bongotest").Drop(context.Background())
+		collection := connection.Collection("parents")
+		childCollection := connection.Collection("children")
+
+		parent := &Parent{Bar: "Testy McGee"}
+		So(collection.Save(parent), ShouldEqual, nil)
+
+		children := []Document{&Child{ParentID: parent.ID, Name: "Foo"}, &Child{ParentID: parent.ID, Name: "Bar"}}
+		So(childCollection.SaveMany(children), ShouldEqual, nil)
+		So(children[0].(*Child).IsNew(), ShouldBeFalse)
+
+		time.Sleep(100 * time.Millisecond)
+
+		newParent := &Parent{}
+		_ = collection.FindByID(parent.ID, newParent)
+		So(len(newParent.Children), ShouldEqual, 2)
+		So(newParent.Child.Name, ShouldEqual, "Bar")
+	})
+
 	Convey("MapFromCascadeProperties", t, func() {
 		parent := &Parent{
 			Bar: "bar",
@@ -283,3 +327,130 @@ func TestCascade(t *testing.T) {
 	})
 
 }
+
+func TestCascadeWrites(t *testing.T) {
+	Convey("Cascade writes", t, func() {
+		connection := &Connection{Config: &Config{Database: "bongotest"}}
+		child := &Child{ParentID: primitive.NewObjectID(), Name: "Foo"}
+		child.SetID(primitive.NewObjectID())
+		toCascade := cascadeConfigs(connection.Collection("children"), child)
+
+		Convey("should combine the updates per target collection", func() {
+			writes, err := cascadeWrites(toCascade, cascadeSaveModels)
+			So(err, ShouldEqual, nil)
+			So(len(writes), ShouldEqual, 1)
+			So(writes[0].collection.Name, ShouldEqual, "parents")
+			// $set child, $pull + $push children, $set childProp
+			So(len(writes[0].models), ShouldEqual, 4)
+
+			writes, err = cascadeWrites(toCascade, cascadeDeleteModels)
+			So(err, ShouldEqual, nil)
+			So(len(writes[0].models), ShouldEqual, 3)
+		})
+
+		Convey("should remove the document from its previous relations first", func() {
+			toCascade[1].OldQuery = bson.M{"_ID": primitive.NewObjectID()}
+			models, err := cascadeSaveModels(toCascade[1])
+			So(err, ShouldEqual, nil)
+			So(len(models), ShouldEqual, 3)
+			So(models[0].(*mongo.UpdateManyModel).Filter, ShouldResemble, toCascade[1].OldQuery)
+			So(models[0].(*mongo.UpdateManyModel).Update, ShouldResemble, bson.M{"$pull": bson.M{"children": bson.M{"_id": child.ID}}})
+
+			toCascade[1].RemoveOnly = true
+			models, err = cascadeSaveModels(toCascade[1])
+			So(err, ShouldEqual, nil)
+			So(len(models), ShouldEqual, 1)
+		})
+
+		Convey("should skip configs whose data didn't change", func() {
+			registerRelModels()
+			books := connection.Collection("rel_books")
+			book := &relBook{Title: "Dune", AuthorID: primitive.NewObjectID()}
+			book.SetID(primitive.NewObjectID())
+			So(len(savedCascadeConfigs(books, []Document{book})), ShouldEqual, 1)
+
+			book.GetDiffTracker().Reset()
+			book.Pages = 412
+			So(len(savedCascadeConfigs(books, []Document{book})), ShouldEqual, 0)
+
+			book.Title = "Dune Messiah"
+			So(len(savedCascadeConfigs(books, []Document{book})), ShouldEqual, 1)
+		})
+
+		Convey("should reject invalid relation types", func() {
+			_, err := cascadeWrites([]*CascadeConfig{{Collection: connection.Collection("parents"), RelType: 5}}, cascadeSaveModels)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+type notifyTarget struct {
+	DocumentBase `bson:",inline"`
+	Sources      []bson.M `bson:"sources"`
+}
+
+var cascadedInto = make(chan primitive.ObjectID, 10)
+
+func (n *notifyTarget) AfterCascadedInto(collection *Collection, source Document) error {
+	cascadedInto <- n.ID
+	return nil
+}
+
+type notifySource struct {
+	DocumentBase `bson:",inline"`
+	TargetID     primitive.ObjectID `bson:"targetId"`
+	Name         string             `bson:"name"`
+}
+
+func (n *notifySource) GetCascade(collection *Collection) []*CascadeConfig {
+	data := bson.M{"_id": n.ID, "name": n.Name}
+	query := bson.M{"_id": n.TargetID}
+	return []*CascadeConfig{
+		{Collection: collection.Connection.Collection("notify_targets"), RelType: REL_MANY, ThroughProp: "sources", Query: query, Data: data, Notify: true},
+		{Collection: collection.Connection.Collection("notify_targets"), RelType: REL_ONE, ThroughProp: "source", Query: query, Data: data, Notify: true},
+	}
+}
+
+func TestCascadeTargets(t *testing.T) {
+	Convey("Cascade targets", t, func() {
+		Convey("should be created from the instance or the registered model", func() {
+			So(cascadeTargetFactory(&CascadeConfig{Collection: &Collection{Name: "unregistered"}}), ShouldBeNil)
+			So(cascadeTargetFactory(&CascadeConfig{Collection: &Collection{Name: "unregistered"}, Instance: &Child{}})(), ShouldHaveSameTypeAs, &Child{})
+
+			RegisterModel("notify_targets", &notifyTarget{})
+			So(cascadeTargetFactory(&CascadeConfig{Collection: &Collection{Name: "notify_targets"}})(), ShouldHaveSameTypeAs, &notifyTarget{})
+		})
+
+		Convey("should be notified for relations that ask for it", func() {
+			RegisterModel("rel_books", &relBook{}).BelongsTo("Author", "rel_authors", "authorId").CascadeTo("books", REL_MANY, "title").NotifyTargets()
+			book := &relBook{AuthorID: primitive.NewObjectID()}
+			configs := relationCascades(&Collection{Name: "rel_books", Connection: &Connection{Config: &Config{}}}, book)
+			So(configs[0].Notify, ShouldBeTrue)
+			registerRelModels()
+		})
+	})
+}
+
+func TestCascadeNotify(t *testing.T) {
+	connection := getConnection()
+
+	Convey("Cascade Save - notify", t, func() {
+		_ = connection.Session.Database("bongotest").Drop(context.Background())
+		RegisterModel("notify_targets", &notifyTarget{})
+
+		target := &notifyTarget{}
+		So(connection.Collection("notify_targets").Save(target), ShouldEqual, nil)
+		So(connection.Collection("notify_sources").Save(&notifySource{TargetID: target.ID, Name: "a"}), ShouldEqual, nil)
+
+		select {
+		case id := <-cascadedInto:
+			So(id, ShouldEqual, target.ID)
+		case <-time.After(time.Second):
+			So("no AfterCascadedInto call", ShouldBeEmpty)
+		}
+
+		// Once per source and target, not per config
+		time.Sleep(100 * time.Millisecond)
+		So(len(cascadedInto), ShouldEqual, 0)
+	})
+}
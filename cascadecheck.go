@@ -0,0 +1,276 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+)
+
+// Kinds of inconsistencies found by VerifyCascades
+const (
+	// A related document has no copy of the source document
+	CASCADE_MISSING = "missing"
+
+	// The copy differs from what the source document cascades today
+	CASCADE_STALE = "stale"
+
+	// A document that is no longer related still holds a copy
+	CASCADE_ORPHANED = "orphaned"
+)
+
+// An inconsistency between a source document and a cascaded copy
+type CascadeMismatch struct {
+	Kind string
+
+	// The source document
+	SourceID interface{}
+
+	// The document holding (or missing) the copy, and where: the through prop, or the first differing property for
+	// configs without one
+	Collection string
+	TargetID   interface{}
+	Field      string
+}
+
+type VerifyCascadesOptions struct {
+	// How many source documents are read per query. Defaults to 500
+	BatchSize int
+
+	// Resume after the document with this _id
+	After interface{}
+}
+
+// What VerifyCascades found
+type CascadeReport struct {
+	// Source documents checked, and the _id of the last one
+	Checked int64
+	LastID  interface{}
+
+	Mismatches []*CascadeMismatch
+}
+
+// Compares the documents of the collection matching the filter against their cascaded copies, per cascade config,
+// and reports missing, stale and orphaned copies without modifying anything. It is the read-only companion of
+// ResyncCascades:
+//
+//	report, err := bongo.VerifyCascades(ctx, conn.Collection("children"), nil, nil)
+//	if len(report.Mismatches) > 0 {
+//		_, err = bongo.ResyncCascades(ctx, conn.Collection("children"), nil, nil)
+//	}
+//
+// Copies of deleted source documents can't be found this way, since there is no document left to read the config
+// from
+func VerifyCascades(ctx context.Context, collection *Collection, filter interface{}, opts *VerifyCascadesOptions) (*CascadeReport, error) {
+	if opts == nil {
+		opts = &VerifyCascadesOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultResyncBatchSize
+	}
+
+	model := GetModel(collection.Name)
+	if model == nil {
+		return nil, errors.New("no model registered for collection " + collection.Name)
+	}
+
+	report := &CascadeReport{LastID: opts.After, Mismatches: make([]*CascadeMismatch, 0)}
+	err := readBatches(ctx, collection, model, filter, opts.After, batchSize, func(docs []Document) error {
+		for _, doc := range docs {
+			for _, conf := range cascadeConfigs(collection, doc) {
+				if conf.RemoveOnly {
+					continue
+				}
+				mismatches, err := verifyCascade(ctx, conf, doc)
+				if err != nil {
+					return err
+				}
+				report.Mismatches = append(report.Mismatches, mismatches...)
+			}
+			report.Checked++
+			report.LastID = doc.GetID()
+		}
+		return nil
+	})
+	return report, err
+}
+
+// Checks the copies of one cascade config of a document
+func verifyCascade(ctx context.Context, conf *CascadeConfig, doc Document) ([]*CascadeMismatch, error) {
+	expected, err := bson.MarshalWithRegistry(Registry, conf.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches := make([]*CascadeMismatch, 0)
+	mismatch := func(kind string, target bson.Raw, field string) {
+		var targetID interface{}
+		target.Lookup("_id").Unmarshal(&targetID)
+		mismatches = append(mismatches, &CascadeMismatch{
+			Kind:       kind,
+			SourceID:   doc.GetID(),
+			Collection: conf.Collection.Name,
+			TargetID:   targetID,
+			Field:      field,
+		})
+	}
+
+	targets, err := findRaw(ctx, conf.Collection, conf.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		if len(conf.ThroughProp) == 0 {
+			if field, ok := rawContains(expected, target); !ok {
+				mismatch(CASCADE_STALE, target, field)
+			}
+			continue
+		}
+
+		embedded, found := cascadedCopy(conf, target)
+		if !found {
+			mismatch(CASCADE_MISSING, target, conf.ThroughProp)
+		} else if _, ok := rawContains(expected, embedded); !ok {
+			mismatch(CASCADE_STALE, target, conf.ThroughProp)
+		}
+	}
+
+	// Documents that hold a copy without matching the query any more
+	if orphans := orphanFilter(conf); orphans != nil {
+		targets, err := findRaw(ctx, conf.Collection, orphans)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range targets {
+			mismatch(CASCADE_ORPHANED, target, conf.ThroughProp)
+		}
+	}
+
+	return mismatches, nil
+}
+
+// Finds the copy of the source document in a related document
+func cascadedCopy(conf *CascadeConfig, target bson.Raw) (bson.Raw, bool) {
+	value, err := target.LookupErr(strings.Split(conf.ThroughProp, ".")...)
+	if err != nil {
+		return nil, false
+	}
+
+	if conf.RelType == REL_ONE {
+		embedded, ok := value.DocumentOK()
+		return embedded, ok && referencesMatch(conf, embedded)
+	}
+
+	array, ok := value.ArrayOK()
+	if !ok {
+		return nil, false
+	}
+	values, _ := array.Values()
+	for _, element := range values {
+		if embedded, ok := element.DocumentOK(); ok && referencesMatch(conf, embedded) {
+			return embedded, true
+		}
+	}
+	return nil, false
+}
+
+// Whether a copy holds the reference fields of the source document
+func referencesMatch(conf *CascadeConfig, embedded bson.Raw) bool {
+	for _, ref := range conf.ReferenceQuery {
+		actual, err := embedded.LookupErr(strings.Split(ref.BsonName, ".")...)
+		if err != nil {
+			return false
+		}
+		t, data, err := bson.MarshalValue(ref.Value)
+		if err != nil || !rawValuesEqual(bson.RawValue{Type: t, Value: data}, actual) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches the documents that hold a copy of the source document but don't match the query, or nil when copies
+// can't be told apart (configs without a through prop)
+func orphanFilter(conf *CascadeConfig) bson.M {
+	if len(conf.ThroughProp) == 0 || len(conf.ReferenceQuery) == 0 {
+		return nil
+	}
+
+	reference := bson.M{}
+	for _, ref := range conf.ReferenceQuery {
+		reference[ref.BsonName] = ref.Value
+	}
+
+	var holds bson.M
+	if conf.RelType == REL_MANY {
+		holds = bson.M{conf.ThroughProp: bson.M{"$elemMatch": reference}}
+	} else {
+		holds = bson.M{}
+		for field, value := range reference {
+			holds[conf.ThroughProp+"."+field] = value
+		}
+	}
+	return bson.M{"$and": bson.A{holds, bson.M{"$nor": bson.A{conf.Query}}}}
+}
+
+// Whether every field of expected is in actual with an equal value (comparing embedded documents the same way), and
+// if not, the first field that differs
+func rawContains(expected bson.Raw, actual bson.Raw) (string, bool) {
+	elements, err := expected.Elements()
+	if err != nil {
+		return "", false
+	}
+
+	for _, element := range elements {
+		key := element.Key()
+		value, err := actual.LookupErr(key)
+		if err != nil {
+			return key, false
+		}
+
+		want := element.Value()
+		if wantDoc, ok := want.DocumentOK(); ok {
+			gotDoc, ok := value.DocumentOK()
+			if !ok {
+				return key, false
+			}
+			if field, ok := rawContains(wantDoc, gotDoc); !ok {
+				return key + "." + field, false
+			}
+			continue
+		}
+		if !rawValuesEqual(want, value) {
+			return key, false
+		}
+	}
+	return "", true
+}
+
+// Finds the raw documents matching a query
+func findRaw(ctx context.Context, collection *Collection, query interface{}) ([]bson.Raw, error) {
+	docs := make([]bson.Raw, 0)
+	err := collection.runOperationContext(ctx, "findRaw", func(ctx context.Context) error {
+		docs = docs[:0]
+		guarded, err := collection.guardFilter(ctx, query)
+		if err != nil {
+			return err
+		}
+		cursor, err := collection.Collection().Find(ctx, guarded)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			docs = append(docs, append(bson.Raw{}, cursor.Current...))
+		}
+		return cursor.Err()
+	})
+	return docs, err
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestCascadeCopies(t *testing.T) {
+	Convey("Cascade verification", t, func() {
+		raw := func(doc interface{}) bson.Raw {
+			data, _ := bson.Marshal(doc)
+			return data
+		}
+		id := primitive.NewObjectID()
+
+		Convey("should compare the cascaded fields only", func() {
+			expected := raw(bson.M{"name": "a", "address": bson.M{"city": "x"}, "count": int32(3)})
+			_, ok := rawContains(expected, raw(bson.M{"count": int64(3), "address": bson.M{"city": "x", "zip": 1}, "name": "a", "other": true}))
+			So(ok, ShouldBeTrue)
+
+			field, ok := rawContains(expected, raw(bson.M{"count": 3, "address": bson.M{"city": "y"}, "name": "a"}))
+			So(ok, ShouldBeFalse)
+			So(field, ShouldEqual, "address.city")
+
+			field, ok = rawContains(expected, raw(bson.M{"address": bson.M{"city": "x"}, "count": 3}))
+			So(ok, ShouldBeFalse)
+			So(field, ShouldEqual, "name")
+		})
+
+		Convey("should find the copy by its reference", func() {
+			conf := &CascadeConfig{RelType: REL_MANY, ThroughProp: "children", ReferenceQuery: []*ReferenceField{{"_id", id}}}
+			target := raw(bson.M{"children": bson.A{bson.M{"_id": primitive.NewObjectID()}, bson.M{"_id": id, "name": "b"}}})
+			embedded, found := cascadedCopy(conf, target)
+			So(found, ShouldBeTrue)
+			So(embedded.Lookup("name").StringValue(), ShouldEqual, "b")
+
+			conf.RelType = REL_ONE
+			conf.ThroughProp = "child"
+			_, found = cascadedCopy(conf, raw(bson.M{"child": bson.M{"_id": primitive.NewObjectID()}}))
+			So(found, ShouldBeFalse)
+		})
+
+		Convey("should look for orphaned copies outside the query", func() {
+			conf := &CascadeConfig{RelType: REL_MANY, ThroughProp: "children", Query: bson.M{"_id": 1}, ReferenceQuery: []*ReferenceField{{"_id", id}}}
+			So(orphanFilter(conf), ShouldResemble, bson.M{"$and": bson.A{
+				bson.M{"children": bson.M{"$elemMatch": bson.M{"_id": id}}},
+				bson.M{"$nor": bson.A{bson.M{"_id": 1}}},
+			}})
+
+			conf.RelType = REL_ONE
+			conf.ThroughProp = "child"
+			So(orphanFilter(conf)["$and"].(bson.A)[0], ShouldResemble, bson.M{"child._id": id})
+
+			conf.ThroughProp = ""
+			So(orphanFilter(conf), ShouldBeNil)
+		})
+	})
+}
+
+func TestVerifyCascades(t *testing.T) {
+	conn := getConnection()
+
+	Convey("VerifyCascades", t, func() {
+		registerRelModels()
+		authors, books := conn.Collection("rel_authors"), conn.Collection("rel_books")
+
+		frank, brian := &relAuthor{Name: "Frank"}, &relAuthor{Name: "Brian"}
+		So(authors.Save(frank), ShouldEqual, nil)
+		So(authors.Save(brian), ShouldEqual, nil)
+		dune := &relBook{Title: "Dune", AuthorID: frank.ID}
+		So(books.Save(dune), ShouldEqual, nil)
+		time.Sleep(100 * time.Millisecond)
+
+		Convey("should report nothing for consistent copies", func() {
+			report, err := VerifyCascades(context.Background(), books, nil, nil)
+			So(err, ShouldEqual, nil)
+			So(report.Checked, ShouldEqual, 1)
+			So(len(report.Mismatches), ShouldEqual, 0)
+		})
+
+		Convey("should report stale, missing and orphaned copies", func() {
+			dune.Title = "Dune Messiah"
+			So(books.Save(dune, SkipCascade()), ShouldEqual, nil)
+
+			report, err := VerifyCascades(context.Background(), books, nil, nil)
+			So(err, ShouldEqual, nil)
+			So(report.Mismatches, ShouldResemble, []*CascadeMismatch{
+				{Kind: CASCADE_STALE, SourceID: dune.ID, Collection: "rel_authors", TargetID: frank.ID, Field: "books"},
+			})
+
+			dune.AuthorID = brian.ID
+			So(books.Save(dune, SkipCascade()), ShouldEqual, nil)
+
+			report, err = VerifyCascades(context.Background(), books, nil, nil)
+			So(err, ShouldEqual, nil)
+			So(report.Mismatches, ShouldResemble, []*CascadeMismatch{
+				{Kind: CASCADE_MISSING, SourceID: dune.ID, Collection: "rel_authors", TargetID: brian.ID, Field: "books"},
+				{Kind: CASCADE_ORPHANED, SourceID: dune.ID, Collection: "rel_authors", TargetID: frank.ID, Field: "books"},
+			})
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
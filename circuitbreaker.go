@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	CIRCUIT_CLOSED = iota
+	CIRCUIT_OPEN
+	CIRCUIT_HALF_OPEN
+)
+
+// Configuration for the optional circuit breaker wrapped around every operation
+type CircuitBreakerConfig struct {
+	// Error rate (0-1) within Window at which the circuit opens
+	FailureThreshold float64
+
+	// Minimum number of operations within Window before the error rate is considered
+	MinRequests int
+
+	// Length of the window over which the error rate is measured
+	Window time.Duration
+
+	// How long the circuit stays open before letting trial operations through (half-open)
+	OpenTimeout time.Duration
+
+	// Number of successful trial operations needed in the half-open state to close the circuit again
+	HalfOpenRequests int
+
+	// Keep a separate circuit per collection instead of one for the whole connection
+	PerCollection bool
+
+	// Decides whether an error counts as a failure. Defaults to transient and timeout errors, so
+	// application-level errors like duplicate keys don't trip the circuit
+	IsFailure func(error) bool
+}
+
+// Returned instead of running an operation while the circuit is open
+type CircuitOpenError struct {
+	Name string
+}
+
+func (e *CircuitOpenError) Error() string {
+	if len(e.Name) > 0 {
+		return "Circuit breaker is open for " + e.Name
+	}
+	return "Circuit breaker is open"
+}
+
+type CircuitBreaker struct {
+	Name   string
+	config *CircuitBreakerConfig
+
+	mutex       sync.Mutex
+	state       int
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	trials      int
+	successes   int
+}
+
+func NewCircuitBreaker(name string, config *CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		Name:   name,
+		config: config,
+		state:  CIRCUIT_CLOSED,
+	}
+}
+
+// The current state (CIRCUIT_CLOSED, CIRCUIT_OPEN or CIRCUIT_HALF_OPEN)
+func (b *CircuitBreaker) State() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.advance(time.Now())
+	return b.state
+}
+
+// Moves an open circuit to half-open once the open timeout has elapsed
+func (b *CircuitBreaker) advance(now time.Time) {
+	if b.state == CIRCUIT_OPEN && now.Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.state = CIRCUIT_HALF_OPEN
+		b.trials = 0
+		b.successes = 0
+	}
+}
+
+// Reports whether an operation may run. Returns a *CircuitOpenError if not
+func (b *CircuitBreaker) Allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.advance(time.Now())
+
+	switch b.state {
+	case CIRCUIT_OPEN:
+		return &CircuitOpenError{b.Name}
+	case CIRCUIT_HALF_OPEN:
+		if b.trials >= b.halfOpenRequests() {
+			return &CircuitOpenError{b.Name}
+		}
+		b.trials++
+	}
+
+	return nil
+}
+
+// Records the outcome of an operation that was allowed through. An operation its caller canceled tells nothing
+// about the cluster, so it is released instead
+func (b *CircuitBreaker) Record(err error) {
+	if errors.Is(err, context.Canceled) {
+		b.Release()
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	failed := err != nil && b.isFailure(err)
+
+	switch b.state {
+	case CIRCUIT_HALF_OPEN:
+		if failed {
+			b.open(now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.halfOpenRequests() {
+			b.state = CIRCUIT_CLOSED
+			b.resetWindow(now)
+		}
+	case CIRCUIT_CLOSED:
+		if now.Sub(b.windowStart) > b.config.Window {
+			b.resetWindow(now)
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.config.MinRequests && float64(b.failures)/float64(b.requests) >= b.config.FailureThreshold {
+			b.open(now)
+		}
+	}
+}
+
+// Gives back the trial slot of an operation that was allowed through without recording its outcome, e.g. because
+// its caller gave up on it
+func (b *CircuitBreaker) Release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == CIRCUIT_HALF_OPEN && b.trials > b.successes {
+		b.trials--
+	}
+}
+
+func (b *CircuitBreaker) open(now time.Time) {
+	b.state = CIRCUIT_OPEN
+	b.openedAt = now
+	b.resetWindow(now)
+}
+
+func (b *CircuitBreaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
+
+func (b *CircuitBreaker) halfOpenRequests() int {
+	if b.config.HalfOpenRequests < 1 {
+		return 1
+	}
+	return b.config.HalfOpenRequests
+}
+
+func (b *CircuitBreaker) isFailure(err error) bool {
+	if b.config.IsFailure != nil {
+		return b.config.IsFailure(err)
+	}
+	return IsTransientError(err) || mongo.IsTimeout(err)
+}
+
+// Get the circuit breaker guarding operations on a collection, or nil if no circuit breaker is configured (or an
+// empty config turned it off)
+func (m *Connection) CircuitBreaker(collection *Collection) *CircuitBreaker {
+	settings := m.config()
+	if settings == nil || settings.CircuitBreaker == nil || reflect.ValueOf(*settings.CircuitBreaker).IsZero() {
+		return nil
+	}
+	config := settings.CircuitBreaker
+
+	name := ""
+	if config.PerCollection {
+		name = collection.Database + "." + collection.Name
+	}
+
+	m.breakerMutex.Lock()
+	defer m.breakerMutex.Unlock()
+
+	if m.breakers == nil {
+		m.breakers = make(map[string]*CircuitBreaker)
+	}
+
+	breaker, ok := m.breakers[name]
+	if !ok {
+		breaker = NewCircuitBreaker(name, config)
+		m.breakers[name] = breaker
+	}
+	return breaker
+}
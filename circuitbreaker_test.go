@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("CircuitBreaker", t, func() {
+		failure := mongo.CommandError{Code: 189, Message: "primary stepped down"}
+		config := &CircuitBreakerConfig{
+			FailureThreshold: 0.5,
+			MinRequests:      4,
+			Window:           time.Minute,
+			OpenTimeout:      20 * time.Millisecond,
+			HalfOpenRequests: 1,
+		}
+
+		Convey("should open once the error rate is reached", func() {
+			b := NewCircuitBreaker("test", config)
+			for i := 0; i < 2; i++ {
+				So(b.Allow(), ShouldBeNil)
+				b.Record(nil)
+			}
+			So(b.Allow(), ShouldBeNil)
+			b.Record(failure)
+			So(b.State(), ShouldEqual, CIRCUIT_CLOSED)
+			So(b.Allow(), ShouldBeNil)
+			b.Record(failure)
+			So(b.State(), ShouldEqual, CIRCUIT_OPEN)
+
+			err := b.Allow()
+			_, ok := err.(*CircuitOpenError)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("should ignore application errors", func() {
+			b := NewCircuitBreaker("test", config)
+			for i := 0; i < 10; i++ {
+				b.Record(errors.New("validation failed"))
+			}
+			So(b.State(), ShouldEqual, CIRCUIT_CLOSED)
+		})
+
+		Convey("should go half-open after the timeout and close on success", func() {
+			b := NewCircuitBreaker("test", config)
+			for i := 0; i < 4; i++ {
+				b.Record(failure)
+			}
+			So(b.State(), ShouldEqual, CIRCUIT_OPEN)
+			time.Sleep(30 * time.Millisecond)
+			So(b.State(), ShouldEqual, CIRCUIT_HALF_OPEN)
+
+			So(b.Allow(), ShouldBeNil)
+			So(b.Allow(), ShouldNotBeNil)
+			b.Record(nil)
+			So(b.State(), ShouldEqual, CIRCUIT_CLOSED)
+		})
+
+		Convey("should re-open when a trial fails", func() {
+			b := NewCircuitBreaker("test", config)
+			for i := 0; i < 4; i++ {
+				b.Record(failure)
+			}
+			time.Sleep(30 * time.Millisecond)
+			So(b.Allow(), ShouldBeNil)
+			b.Record(failure)
+			So(b.State(), ShouldEqual, CIRCUIT_OPEN)
+		})
+
+		Convey("should not decide on trials their callers canceled", func() {
+			b := NewCircuitBreaker("test", config)
+			for i := 0; i < 4; i++ {
+				b.Record(failure)
+			}
+			time.Sleep(30 * time.Millisecond)
+			So(b.Allow(), ShouldBeNil)
+			b.Record(context.Canceled)
+			So(b.State(), ShouldEqual, CIRCUIT_HALF_OPEN)
+
+			conn := &Connection{Config: &Config{CircuitBreaker: config}}
+			conn.breakers = map[string]*CircuitBreaker{"": b}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			err := conn.Collection("tests").runOperationContext(ctx, "test", func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+			So(err, ShouldEqual, context.DeadlineExceeded)
+			So(b.State(), ShouldEqual, CIRCUIT_HALF_OPEN)
+
+			So(b.Allow(), ShouldBeNil)
+			b.Record(nil)
+			So(b.State(), ShouldEqual, CIRCUIT_CLOSED)
+		})
+
+		Convey("should guard collection operations", func() {
+			conn := &Connection{Config: &Config{CircuitBreaker: config}}
+			col := conn.Collection("tests")
+
+			for i := 0; i < 4; i++ {
+				col.runOperation("test", func(ctx context.Context) error {
+					return failure
+				})
+			}
+
+			calls := 0
+			err := col.runOperation("test", func(ctx context.Context) error {
+				calls++
+				return nil
+			})
+			_, ok := err.(*CircuitOpenError)
+			So(ok, ShouldBeTrue)
+			So(calls, ShouldEqual, 0)
+		})
+
+		Convey("should keep separate circuits per collection", func() {
+			perCollection := *config
+			perCollection.PerCollection = true
+			conn := &Connection{Config: &Config{CircuitBreaker: &perCollection}}
+
+			So(conn.CircuitBreaker(conn.Collection("a")), ShouldNotEqual, conn.CircuitBreaker(conn.Collection("b")))
+			So(conn.CircuitBreaker(conn.Collection("a")), ShouldEqual, conn.CircuitBreaker(conn.Collection("a")))
+		})
+	})
+}
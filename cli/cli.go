@@ -0,0 +1,350 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+// Package cli implements the bongo command line tool. Models and migrations live in application code, so
+// applications build their own binary that imports their models for registration and calls Run:
+//
+//	package main
+//
+//	import (
+//		"os"
+//
+//		"github.com/go-bongo/bongo/cli"
+//		_ "example.com/app/models"
+//	)
+//
+//	func main() {
+//		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+//	}
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/go-bongo/bongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+const usage = `Usage: bongo [flags] <command>
+
+Commands:
+  indexes sync        create the indexes declared on all registered models
+  indexes diff        report indexes that are missing, extra or changed compared to the registered models
+  indexes reindex <collection>
+                      rebuild the indexes of a registered model that are missing or changed, swapping them in
+  migrate up          run all pending migrations
+  migrate down [n]    revert the last n migrations (default 1)
+  migrate status      list registered migrations and whether they have been applied
+  validate-schema     count documents that don't match their model's schema
+  automigrate         create the collections, indexes and schema validators of all registered models
+  cascades resync <collection> [after-id]
+                      re-apply the cascades of a collection's documents, optionally resuming after an id
+  cascades verify <collection>
+                      report cascaded copies that are missing, stale or orphaned, without changing them
+
+Flags:
+`
+
+// Connection settings, read from a JSON file, the environment (BONGO_URI, BONGO_DATABASE) or flags
+type Config struct {
+	ConnectionString string `json:"connectionString"`
+	Database         string `json:"database"`
+}
+
+// Runs the command line tool and returns the exit code. The output of commands goes to out, usage and errors go
+// to errOut
+func Run(args []string, out io.Writer, errOut io.Writer) int {
+	flags := flag.NewFlagSet("bongo", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+
+	configFile := flags.String("config", "", "path to a JSON config file with connectionString and database")
+	uri := flags.String("uri", "", "connection string (overrides config and BONGO_URI)")
+	database := flags.String("db", "", "database name (overrides config and BONGO_DATABASE)")
+
+	flags.Usage = func() {
+		fmt.Fprint(errOut, usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	config, err := LoadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(errOut, err.Error())
+		return 1
+	}
+	if len(*uri) > 0 {
+		config.ConnectionString = *uri
+	}
+	if len(*database) > 0 {
+		config.Database = *database
+	}
+
+	command := flags.Args()
+	run, ok := commandFor(command)
+	if !ok {
+		flags.Usage()
+		return 2
+	}
+
+	conn, err := bongo.Connect(&bongo.Config{
+		ConnectionString: config.ConnectionString,
+		Database:         config.Database,
+	})
+	if err != nil {
+		fmt.Fprintln(errOut, err.Error())
+		return 1
+	}
+
+	if err := run(conn, command, out); err != nil {
+		fmt.Fprintln(errOut, err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// Reads the config file (if any) and applies the environment on top of it
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{
+		ConnectionString: "mongodb://localhost:27017",
+	}
+
+	if len(path) > 0 {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, err
+		}
+	}
+
+	if uri := os.Getenv("BONGO_URI"); len(uri) > 0 {
+		config.ConnectionString = uri
+	}
+	if database := os.Getenv("BONGO_DATABASE"); len(database) > 0 {
+		config.Database = database
+	}
+
+	return config, nil
+}
+
+type command func(conn *bongo.Connection, args []string, out io.Writer) error
+
+func commandFor(args []string) (command, bool) {
+	switch {
+	case args[0] == "indexes" && len(args) == 2 && args[1] == "sync":
+		return syncIndexes, true
+	case args[0] == "indexes" && len(args) == 2 && args[1] == "diff":
+		return diffIndexes, true
+	case args[0] == "indexes" && len(args) == 3 && args[1] == "reindex":
+		return reindex, true
+	case args[0] == "migrate" && len(args) == 2 && args[1] == "up":
+		return migrateUp, true
+	case args[0] == "migrate" && len(args) >= 2 && len(args) <= 3 && args[1] == "down":
+		return migrateDown, true
+	case args[0] == "migrate" && len(args) == 2 && args[1] == "status":
+		return migrateStatus, true
+	case args[0] == "validate-schema" && len(args) == 1:
+		return validateSchema, true
+	case args[0] == "automigrate" && len(args) == 1:
+		return autoMigrate, true
+	case args[0] == "cascades" && len(args) >= 3 && len(args) <= 4 && args[1] == "resync":
+		return resyncCascades, true
+	case args[0] == "cascades" && len(args) == 3 && args[1] == "verify":
+		return verifyCascades, true
+	}
+	return nil, false
+}
+
+func syncIndexes(conn *bongo.Connection, args []string, out io.Writer) error {
+	for _, model := range bongo.Models() {
+		names, err := conn.SyncIndexes(model)
+		if err != nil {
+			return errors.New(model.Collection + ": " + err.Error())
+		}
+		for _, name := range names {
+			fmt.Fprintf(out, "%s: %s\n", model.Collection, name)
+		}
+	}
+	return nil
+}
+
+func autoMigrate(conn *bongo.Connection, args []string, out io.Writer) error {
+	actions, err := conn.AutoMigrate()
+	for _, action := range actions {
+		fmt.Fprintln(out, action)
+	}
+	return err
+}
+
+func diffIndexes(conn *bongo.Connection, args []string, out io.Writer) error {
+	drifted := false
+	for _, model := range bongo.Models() {
+		drift, err := conn.ModelCollection(model).IndexDiff(model)
+		if err != nil {
+			return errors.New(model.Collection + ": " + err.Error())
+		}
+		for _, spec := range drift.Missing {
+			fmt.Fprintf(out, "missing    %s: %s\n", model.Collection, spec.Name)
+		}
+		for _, index := range drift.Extra {
+			fmt.Fprintf(out, "extra      %s: %s\n", model.Collection, index.Name)
+		}
+		for _, mismatch := range drift.Mismatched {
+			fmt.Fprintf(out, "mismatched %s: %s\n", model.Collection, mismatch.Declared.Name)
+		}
+		if !drift.InSync() {
+			drifted = true
+		}
+	}
+
+	if drifted {
+		return errors.New("indexes differ from the models")
+	}
+	return nil
+}
+
+func reindex(conn *bongo.Connection, args []string, out io.Writer) error {
+	model := bongo.GetModel(args[2])
+	if model == nil {
+		return errors.New("no model registered for collection " + args[2])
+	}
+	specs, err := model.Indexes()
+	if err != nil {
+		return err
+	}
+
+	opts := &bongo.ReindexOptions{
+		Swap: true,
+		Progress: func(progress *bongo.ReindexProgress) {
+			if len(progress.Index) > 0 {
+				fmt.Fprintf(out, "%s: building %s, %d/%d\n", args[2], progress.Index, progress.Done, progress.Total)
+			}
+		},
+	}
+	progress, err := conn.Collection(args[2]).Reindex(context.Background(), specs, opts)
+	if progress != nil {
+		for _, name := range progress.Created {
+			fmt.Fprintf(out, "%s: built %s\n", args[2], name)
+		}
+		for _, name := range progress.Unchanged {
+			fmt.Fprintf(out, "%s: %s is up to date\n", args[2], name)
+		}
+	}
+	return err
+}
+
+func migrateUp(conn *bongo.Connection, args []string, out io.Writer) error {
+	ran, err := conn.MigrateUp()
+	for _, id := range ran {
+		fmt.Fprintf(out, "applied %s\n", id)
+	}
+	return err
+}
+
+func migrateDown(conn *bongo.Connection, args []string, out io.Writer) error {
+	n := 1
+	if len(args) == 3 {
+		var err error
+		n, err = strconv.Atoi(args[2])
+		if err != nil || n < 1 {
+			return errors.New("invalid number of migrations: " + args[2])
+		}
+	}
+
+	reverted, err := conn.MigrateDown(n)
+	for _, id := range reverted {
+		fmt.Fprintf(out, "reverted %s\n", id)
+	}
+	return err
+}
+
+func migrateStatus(conn *bongo.Connection, args []string, out io.Writer) error {
+	applied, err := conn.AppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range bongo.Migrations() {
+		status := "pending"
+		for _, id := range applied {
+			if id == migration.ID {
+				status = "applied"
+				break
+			}
+		}
+		fmt.Fprintf(out, "%-8s %s\n", status, migration.ID)
+	}
+	return nil
+}
+
+func validateSchema(conn *bongo.Connection, args []string, out io.Writer) error {
+	failed := false
+	for _, model := range bongo.Models() {
+		invalid, err := conn.ModelCollection(model).ValidateSchema(model.JSONSchema())
+		if err != nil {
+			return errors.New(model.Collection + ": " + err.Error())
+		}
+		fmt.Fprintf(out, "%s: %d invalid documents\n", model.Collection, invalid)
+		if invalid > 0 {
+			failed = true
+		}
+	}
+
+	if failed {
+		return errors.New("schema validation failed")
+	}
+	return nil
+}
+
+func resyncCascades(conn *bongo.Connection, args []string, out io.Writer) error {
+	opts := &bongo.ResyncOptions{
+		Progress: func(progress *bongo.ResyncProgress) {
+			fmt.Fprintf(out, "%s: %d resynced, last %v\n", args[2], progress.Resynced, progress.LastID)
+		},
+	}
+	if len(args) == 4 {
+		after, err := primitive.ObjectIDFromHex(args[3])
+		if err != nil {
+			return errors.New("invalid id: " + args[3])
+		}
+		opts.After = after
+	}
+
+	_, err := bongo.ResyncCascades(context.Background(), conn.Collection(args[2]), nil, opts)
+	return err
+}
+
+func verifyCascades(conn *bongo.Connection, args []string, out io.Writer) error {
+	report, err := bongo.VerifyCascades(context.Background(), conn.Collection(args[2]), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, mismatch := range report.Mismatches {
+		fmt.Fprintf(out, "%-8s %s %v: %s %v %s\n", mismatch.Kind, args[2], mismatch.SourceID, mismatch.Collection, mismatch.TargetID, mismatch.Field)
+	}
+	fmt.Fprintf(out, "%s: %d documents checked, %d mismatches\n", args[2], report.Checked, len(report.Mismatches))
+
+	if len(report.Mismatches) > 0 {
+		return errors.New("cascade verification failed")
+	}
+	return nil
+}
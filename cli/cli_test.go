@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package cli
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCLI(t *testing.T) {
+	Convey("CLI", t, func() {
+		Convey("should print usage for unknown commands", func() {
+			out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+			So(Run([]string{"nope"}, out, errOut), ShouldEqual, 2)
+			So(errOut.String(), ShouldContainSubstring, "Usage: bongo")
+
+			errOut.Reset()
+			So(Run([]string{}, out, errOut), ShouldEqual, 2)
+			So(errOut.String(), ShouldContainSubstring, "indexes sync")
+			So(out.Len(), ShouldEqual, 0)
+		})
+
+		Convey("should print errors to errOut", func() {
+			out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+			So(Run([]string{"-config", "/nonexistent/bongo.json", "migrate", "status"}, out, errOut), ShouldEqual, 1)
+			So(errOut.String(), ShouldContainSubstring, "/nonexistent/bongo.json")
+			So(out.Len(), ShouldEqual, 0)
+		})
+
+		Convey("should diff indexes", func() {
+			_, ok := commandFor([]string{"indexes", "diff"})
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("should take a collection to reindex", func() {
+			_, ok := commandFor([]string{"indexes", "reindex", "users"})
+			So(ok, ShouldBeTrue)
+			_, ok = commandFor([]string{"indexes", "reindex"})
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("should load config from a file and the environment", func() {
+			file, _ := ioutil.TempFile("", "bongo")
+			defer os.Remove(file.Name())
+			file.WriteString(`{"connectionString": "mongodb://db:27017", "database": "app"}`)
+			file.Close()
+
+			config, err := LoadConfig(file.Name())
+			So(err, ShouldEqual, nil)
+			So(config.ConnectionString, ShouldEqual, "mongodb://db:27017")
+			So(config.Database, ShouldEqual, "app")
+
+			os.Setenv("BONGO_DATABASE", "other")
+			defer os.Unsetenv("BONGO_DATABASE")
+			config, err = LoadConfig(file.Name())
+			So(err, ShouldEqual, nil)
+			So(config.Database, ShouldEqual, "other")
+		})
+	})
+}
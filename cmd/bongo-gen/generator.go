@@ -0,0 +1,354 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// What to generate for one model type
+type Options struct {
+	Type        string
+	Collection  string
+	Projections []*Projection
+}
+
+// A named subset of a model's fields, e.g. UserSummary=Email,FirstName
+type Projection struct {
+	Name   string
+	Fields []string
+}
+
+type field struct {
+	Name     string
+	Param    string
+	Type     string
+	BsonName string
+	Tag      string
+	FindBy   bool
+	FindAll  bool
+}
+
+type projection struct {
+	Name   string
+	Fields []*field
+	HasID  bool
+}
+
+type templateData struct {
+	Package     string
+	Type        string
+	Collection  string
+	Imports     []string
+	Finders     []*field
+	Projections []*projection
+}
+
+// Parses a projection flag value of the form Name=Field1,Field2
+func ParseProjection(value string) (*Projection, error) {
+	split := strings.SplitN(value, "=", 2)
+	if len(split) != 2 || len(split[0]) == 0 || len(split[1]) == 0 {
+		return nil, errors.New("invalid projection " + value + ", expected Name=Field1,Field2")
+	}
+	return &Projection{Name: split[0], Fields: strings.Split(split[1], ",")}, nil
+}
+
+// Generates the repository source for a model type declared in one of the files of a package
+func Generate(pkg string, files []*ast.File, opts *Options) ([]byte, error) {
+	spec, file := findType(files, opts.Type)
+	if spec == nil {
+		return nil, errors.New("type " + opts.Type + " not found")
+	}
+
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil, errors.New(opts.Type + " is not a struct")
+	}
+
+	fields := make(map[string]*field)
+	ordered := make([]*field, 0)
+	for _, f := range st.Fields.List {
+		// Embedded structs (like DocumentBase) are not exposed through finders or projections
+		if len(f.Names) == 0 {
+			continue
+		}
+		tag := ""
+		if f.Tag != nil {
+			tag, _ = strconv.Unquote(f.Tag.Value)
+		}
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			fd := newField(name.Name, types.ExprString(f.Type), tag)
+			fields[fd.Name] = fd
+			ordered = append(ordered, fd)
+		}
+	}
+
+	data := &templateData{
+		Package:    pkg,
+		Type:       opts.Type,
+		Collection: opts.Collection,
+	}
+
+	used := make(map[string]bool)
+	for _, f := range ordered {
+		if f.FindBy || f.FindAll {
+			data.Finders = append(data.Finders, f)
+			selectors(f.Type, used)
+		}
+	}
+
+	for _, p := range opts.Projections {
+		proj := &projection{Name: p.Name}
+		for _, name := range p.Fields {
+			f, ok := fields[name]
+			if !ok {
+				return nil, errors.New("projection " + p.Name + ": " + opts.Type + " has no field " + name)
+			}
+			if f.BsonName == "_id" {
+				proj.HasID = true
+			}
+			proj.Fields = append(proj.Fields, f)
+			selectors(f.Type, used)
+		}
+		data.Projections = append(data.Projections, proj)
+	}
+
+	imports, err := resolveImports(file, used)
+	if err != nil {
+		return nil, err
+	}
+	data.Imports = imports
+
+	buf := &bytes.Buffer{}
+	if err := repositoryTemplate.Execute(buf, data); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code: %s\n%s", err.Error(), buf.String())
+	}
+	return src, nil
+}
+
+func findType(files []*ast.File, name string) (*ast.TypeSpec, *ast.File) {
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == name {
+					return ts, file
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func newField(name string, typ string, tag string) *field {
+	st := reflect.StructTag(tag)
+
+	bsonName := strings.Split(st.Get("bson"), ",")[0]
+	if len(bsonName) == 0 {
+		bsonName = strings.ToLower(name)
+	}
+
+	param := string(unicode.ToLower(rune(name[0]))) + name[1:]
+	if token.IsKeyword(param) {
+		param += "Value"
+	}
+
+	f := &field{
+		Name:     name,
+		Param:    param,
+		Type:     typ,
+		BsonName: bsonName,
+		Tag:      tag,
+	}
+
+	for _, opt := range strings.Split(st.Get("repo"), ",") {
+		switch opt {
+		case "findBy":
+			f.FindBy = true
+		case "findAllBy":
+			f.FindAll = true
+		}
+	}
+
+	return f
+}
+
+// Collects the package names referenced by a type expression (e.g. "time" in "*time.Time")
+func selectors(typ string, used map[string]bool) {
+	for _, part := range strings.FieldsFunc(typ, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.')
+	}) {
+		if i := strings.Index(part, "."); i > 0 {
+			used[part[:i]] = true
+		}
+	}
+}
+
+// Maps the package names used by copied field types to the import paths of the model's file
+func resolveImports(file *ast.File, used map[string]bool) ([]string, error) {
+	imports := make([]string, 0)
+	for name := range used {
+		found := false
+		for _, imp := range file.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			local := path[strings.LastIndex(path, "/")+1:]
+			if imp.Name != nil {
+				local = imp.Name.Name
+			}
+			if local == name {
+				if imp.Name != nil {
+					imports = append(imports, imp.Name.Name+" "+strconv.Quote(path))
+				} else {
+					imports = append(imports, strconv.Quote(path))
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("could not resolve import for package " + name)
+		}
+	}
+	sort.Strings(imports)
+	return imports, nil
+}
+
+var repositoryTemplate = template.Must(template.New("repository").Parse(`// Code generated by bongo-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/go-bongo/bongo"
+{{- if .Finders}}
+	"go.mongodb.org/mongo-driver/bson"
+{{- end}}
+	"go.mongodb.org/mongo-driver/bson/primitive"
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+
+// {{.Type}}Repository gives typed access to the {{.Type}} documents in the "{{.Collection}}" collection
+type {{.Type}}Repository struct {
+	Collection *bongo.Collection
+}
+
+// New{{.Type}}Repository creates a repository on the given connection
+func New{{.Type}}Repository(conn *bongo.Connection) *{{.Type}}Repository {
+	return &{{.Type}}Repository{Collection: conn.Collection("{{.Collection}}")}
+}
+
+// Save saves a {{.Type}}, running its hooks unless bongo.SkipHooks() is passed
+func (r *{{.Type}}Repository) Save(doc *{{.Type}}, opts ...bongo.WriteOption) error {
+	return r.Collection.Save(doc, opts...)
+}
+
+// Delete deletes a {{.Type}}, running its hooks unless bongo.SkipHooks() is passed
+func (r *{{.Type}}Repository) Delete(doc *{{.Type}}, opts ...bongo.WriteOption) error {
+	_, err := r.Collection.DeleteDocument(doc, opts...)
+	return err
+}
+
+// FindByID finds the {{.Type}} with the given ID
+func (r *{{.Type}}Repository) FindByID(id primitive.ObjectID, opts ...bongo.FindOption) (*{{.Type}}, error) {
+	doc := &{{.Type}}{}
+	if err := r.Collection.FindByID(id, doc, opts...); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// FindOne finds the first {{.Type}} matching the query
+func (r *{{.Type}}Repository) FindOne(query interface{}, opts ...bongo.FindOption) (*{{.Type}}, error) {
+	doc := &{{.Type}}{}
+	if err := r.Collection.FindOne(query, doc, opts...); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Find finds all {{.Type}} documents matching the query
+func (r *{{.Type}}Repository) Find(query interface{}, opts ...bongo.FindOption) ([]*{{.Type}}, error) {
+	results, err := r.Collection.Find(query, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Free()
+
+	docs := make([]*{{.Type}}, 0)
+	for {
+		doc := &{{.Type}}{}
+		if !results.Next(doc) {
+			break
+		}
+		docs = append(docs, doc)
+	}
+	return docs, results.Error
+}
+{{range .Finders}}{{if .FindBy}}
+// FindBy{{.Name}} finds the first {{$.Type}} with the given {{.BsonName}}
+func (r *{{$.Type}}Repository) FindBy{{.Name}}({{.Param}} {{.Type}}) (*{{$.Type}}, error) {
+	return r.FindOne(bson.M{"{{.BsonName}}": {{.Param}}})
+}
+{{end}}{{if .FindAll}}
+// FindAllBy{{.Name}} finds all {{$.Type}} documents with the given {{.BsonName}}
+func (r *{{$.Type}}Repository) FindAllBy{{.Name}}({{.Param}} {{.Type}}) ([]*{{$.Type}}, error) {
+	return r.Find(bson.M{"{{.BsonName}}": {{.Param}}})
+}
+{{end}}{{end}}
+{{- range .Projections}}
+// {{.Name}} is a projection of {{$.Type}}
+type {{.Name}} struct {
+{{- if not .HasID}}
+	ID primitive.ObjectID ` + "`" + `bson:"_id" json:"id"` + "`" + `
+{{- end}}
+{{- range .Fields}}
+	{{.Name}} {{.Type}}{{if .Tag}} ` + "`" + `{{.Tag}}` + "`" + `{{end}}
+{{- end}}
+}
+
+// Find{{.Name}} finds all {{$.Type}} documents matching the query, fetching only the fields of {{.Name}}
+func (r *{{$.Type}}Repository) Find{{.Name}}(query interface{}, opts ...bongo.FindOption) ([]*{{.Name}}, error) {
+	opts = append(opts, bongo.Select({{range $i, $field := .Fields}}{{if $i}}, {{end}}"{{$field.BsonName}}"{{end}}))
+	results, err := r.Collection.Find(query, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Free()
+
+	docs := make([]*{{.Name}}, 0)
+	for {
+		doc := &{{.Name}}{}
+		if !results.Next(doc) {
+			break
+		}
+		docs = append(docs, doc)
+	}
+	return docs, results.Error
+}
+{{end}}`))
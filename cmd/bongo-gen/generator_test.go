@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package main
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+const modelSource = `package models
+
+import (
+	"github.com/go-bongo/bongo"
+	"time"
+)
+
+type User struct {
+	bongo.DocumentBase ` + "`bson:\",inline\"`" + `
+	Email     string    ` + "`bson:\"email\" repo:\"findBy\"`" + `
+	FirstName string    ` + "`bson:\"first_name\"`" + `
+	Status    string    ` + "`repo:\"findAllBy\"`" + `
+	Birthday  time.Time ` + "`bson:\"birthday\"`" + `
+	Type      string    ` + "`bson:\"type\" repo:\"findBy\"`" + `
+}
+`
+
+func parseSource(src string) []*ast.File {
+	file, err := parser.ParseFile(token.NewFileSet(), "user.go", src, 0)
+	if err != nil {
+		panic(err)
+	}
+	return []*ast.File{file}
+}
+
+func TestGenerate(t *testing.T) {
+	Convey("Generate", t, func() {
+		files := parseSource(modelSource)
+
+		Convey("should generate a typed repository with finders", func() {
+			src, err := Generate("models", files, &Options{Type: "User", Collection: "users"})
+			So(err, ShouldEqual, nil)
+
+			code := string(src)
+			So(code, ShouldContainSubstring, "type UserRepository struct")
+			So(code, ShouldContainSubstring, `conn.Collection("users")`)
+			So(code, ShouldContainSubstring, "func (r *UserRepository) FindByEmail(email string) (*User, error)")
+			So(code, ShouldContainSubstring, `bson.M{"email": email}`)
+			So(code, ShouldContainSubstring, "func (r *UserRepository) FindAllByStatus(status string) ([]*User, error)")
+			So(code, ShouldContainSubstring, `bson.M{"status": status}`)
+			So(code, ShouldContainSubstring, "func (r *UserRepository) FindByType(typeValue string) (*User, error)")
+			So(code, ShouldNotContainSubstring, `"time"`)
+		})
+
+		Convey("should generate projection structs", func() {
+			projection, err := ParseProjection("UserSummary=Email,Birthday")
+			So(err, ShouldEqual, nil)
+
+			src, err := Generate("models", files, &Options{Type: "User", Collection: "users", Projections: []*Projection{projection}})
+			So(err, ShouldEqual, nil)
+
+			code := string(src)
+			So(code, ShouldContainSubstring, "type UserSummary struct")
+			So(code, ShouldContainSubstring, "time.Time          `bson:\"birthday\"`")
+			So(code, ShouldContainSubstring, `"time"`)
+			So(code, ShouldContainSubstring, `bongo.Select("email", "birthday")`)
+			So(code, ShouldContainSubstring, "results.Next(doc)")
+			So(code, ShouldNotContainSubstring, "r.Collection.Collection()")
+			So(code, ShouldContainSubstring, "func (r *UserRepository) FindUserSummary(query interface{}, opts ...bongo.FindOption) ([]*UserSummary, error)")
+		})
+
+		Convey("should fail for unknown types and fields", func() {
+			_, err := Generate("models", files, &Options{Type: "Nope"})
+			So(err, ShouldNotBeNil)
+
+			_, err = Generate("models", files, &Options{Type: "User", Projections: []*Projection{{Name: "X", Fields: []string{"Nope"}}}})
+			So(err, ShouldNotBeNil)
+
+			_, err = ParseProjection("nope")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+// Command bongo-gen generates typed repositories for bongo models. Use it from go:generate:
+//
+//	//go:generate bongo-gen -type=User -collection=users -projection=UserSummary=Email,FirstName
+//	type User struct {
+//		bongo.DocumentBase `bson:",inline"`
+//		Email     string `bson:"email" repo:"findBy"`
+//		FirstName string `bson:"first_name"`
+//		Status    string `bson:"status" repo:"findAllBy"`
+//	}
+//
+// This writes user_repository.go with a UserRepository exposing Save, Delete, FindByID, FindOne and Find for
+// *User, plus FindByEmail, FindAllByStatus and FindUserSummary.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type projectionFlags []*Projection
+
+func (p *projectionFlags) String() string {
+	return ""
+}
+
+func (p *projectionFlags) Set(value string) error {
+	projection, err := ParseProjection(value)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, projection)
+	return nil
+}
+
+func main() {
+	typeName := flag.String("type", "", "name of the model struct (required)")
+	collection := flag.String("collection", "", "collection name (defaults to the lower cased type name + s)")
+	output := flag.String("output", "", "output file (defaults to <type>_repository.go)")
+	dir := flag.String("dir", ".", "directory of the package containing the model")
+	var projections projectionFlags
+	flag.Var(&projections, "projection", "projection struct to generate, as Name=Field1,Field2 (repeatable)")
+	flag.Parse()
+
+	if len(*typeName) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if len(*collection) == 0 {
+		*collection = strings.ToLower(*typeName) + "s"
+	}
+	if len(*output) == 0 {
+		*output = filepath.Join(*dir, strings.ToLower(*typeName)+"_repository.go")
+	}
+
+	pkg, files, err := parsePackage(*dir, *output)
+	if err != nil {
+		fail(err)
+	}
+
+	src, err := Generate(pkg, files, &Options{
+		Type:        *typeName,
+		Collection:  *collection,
+		Projections: projections,
+	})
+	if err != nil {
+		fail(err)
+	}
+
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		fail(err)
+	}
+}
+
+// Parses the non-test Go files of a directory, skipping a previously generated output file
+func parsePackage(dir string, output string) (string, []*ast.File, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", nil, err
+	}
+
+	fset := token.NewFileSet()
+	pkg := ""
+	files := make([]*ast.File, 0)
+
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") || filepath.Clean(path) == filepath.Clean(output) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return "", nil, err
+		}
+		pkg = file.Name.Name
+		files = append(files, file)
+	}
+
+	return pkg, files, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "bongo-gen: "+err.Error())
+	os.Exit(1)
+}
@@ -0,0 +1,17 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+// Command bongo runs migrations, index sync and schema validation. This binary only knows the models and
+// migrations registered by the packages it imports, so applications usually copy this file into their own
+// cmd/bongo and add a blank import of their models package.
+package main
+
+import (
+	"github.com/go-bongo/bongo/cli"
+	"os"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
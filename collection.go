@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
 	"strings"
 	"time"
 )
@@ -76,6 +77,15 @@ type Collection struct {
 	Database   string
 	Context    *Context
 	Connection *Connection
+
+	// Overrides Config.OperationTimeout for the operations on this collection
+	Timeout time.Duration
+
+	// Overrides Config.CollectionDefaults for this collection, see WithDefaults
+	Defaults *CollectionDefaults
+
+	// The session all operations on this collection run on, see WithSession
+	Session mongo.Session
 }
 
 type NewTracker interface {
@@ -91,22 +101,20 @@ func (d DocumentNotFoundError) Error() string {
 
 // Collection ...
 func (c *Collection) Collection() *mongo.Collection {
-	return c.Connection.Session.Database(c.Database).Collection(c.Name)
+	return c.collectionOnSession(c.Connection.Client())
 }
 
 // CollectionOnSession ...
 func (c *Collection) collectionOnSession(sess *mongo.Client) *mongo.Collection {
+	if opts := c.driverOptions(); opts != nil {
+		return sess.Database(c.Database).Collection(c.Name, opts)
+	}
 	return sess.Database(c.Database).Collection(c.Name)
 }
 
 func (c *Collection) PreSave(doc Document) error {
-	// Validate?
-	if validator, ok := doc.(ValidateHook); ok {
-		errs := validator.Validate(c)
-
-		if len(errs) > 0 {
-			return &ValidationError{errs}
-		}
+	if err := c.validateDocument(doc); err != nil {
+		return err
 	}
 
 	if hook, ok := doc.(BeforeSaveHook); ok {
@@ -119,21 +127,119 @@ func (c *Collection) PreSave(doc Document) error {
 	return nil
 }
 
-func (c *Collection) Save(doc Document) error {
+// Checks the Enum fields of the document and runs its validation hooks, then its database checks
+func (c *Collection) validateDocument(doc Document) error {
+	errs := validateEnums(doc)
+	if validator, ok := doc.(ValidateHook); ok {
+		errs = append(errs, validator.Validate(c)...)
+	}
 
-	var err error
+	if validator, ok := doc.(DBValidateHook); ok {
+		checks := newDBChecks(doc.GetID())
+		validator.ValidateDB(c, checks)
+		dbErrs, err := checks.Run(c)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, dbErrs...)
+	}
 
-	err = c.PreSave(doc)
+	if len(errs) > 0 {
+		return &ValidationError{errs}
+	}
+	return nil
+}
+
+func (c *Collection) Save(doc Document, opts ...WriteOption) error {
+	return c.save(c.baseContext(), doc, newWriteOptions(opts))
+}
+
+func (c *Collection) save(ctx context.Context, doc Document, o *writeOptions) error {
+	id, err := c.prepareSave(doc, o)
 	if err != nil {
 		return err
 	}
-	// If the model implements the NewTracker interface, we'll use that to determine newness. Otherwise always assume it's new
 
+	if c.writesBehind(ctx) {
+		err = c.queueWrite(id, doc)
+		queued := *o
+		queued.queued = true
+		o = &queued
+	} else {
+		err = c.upsertID(ctx, id, doc)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !o.skipCascade {
+		toCascade := savedCascadeConfigs(c, []Document{doc})
+		c.Connection.goCascade(func() { runCascadeSave(toCascade) })
+	}
+
+	return c.finishSave(doc, o)
+}
+
+// Saves documents like Save, but with one BulkWrite, and cascades them together with one BulkWrite per related
+// collection. Nothing is written if a document fails validation or a before save hook. If the write fails, the
+// documents before the failing one have been saved
+func (c *Collection) SaveMany(docs []Document, opts ...WriteOption) error {
+	o := newWriteOptions(opts)
+	if len(docs) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, len(docs))
+	for i, doc := range docs {
+		id, err := c.prepareSave(doc, o)
+		if err != nil {
+			return err
+		}
+		models[i] = mongo.NewReplaceOneModel().SetFilter(documentFilter(id, doc)).SetReplacement(doc).SetUpsert(true)
+	}
+
+	saved := docs
+	err := c.runOperation("saveMany", func(ctx context.Context) error {
+		guarded, err := c.guardModels(ctx, models)
+		if err != nil {
+			return err
+		}
+		bulkOpts := options.BulkWrite().SetOrdered(true)
+		commentOn(ctx, c, bulkOpts.SetComment)
+		_, err = c.Collection().BulkWrite(ctx, guarded, bulkOpts)
+		return err
+	})
+	if err != nil {
+		saved = docs[:appliedWrites(err)]
+	}
+
+	if !o.skipCascade && len(saved) > 0 {
+		toCascade := savedCascadeConfigs(c, saved)
+		c.Connection.goCascade(func() { runCascadeSave(toCascade) })
+	}
+
+	for _, doc := range saved {
+		if finishErr := c.finishSave(doc, o); finishErr != nil && err == nil {
+			err = finishErr
+		}
+	}
+	return err
+}
+
+// Runs the validation and before save hooks, checks the references if they are enforced, sets the timestamps and
+// makes sure the document has an Id. Returns the Id to save the document under. Cascading is up to the caller, once
+// the document is written
+func (c *Collection) prepareSave(doc Document, o *writeOptions) (primitive.ObjectID, error) {
+	// If the model implements the NewTracker interface, we'll use that to determine newness. Otherwise always assume it's new
 	isNew := true
 	if newt, ok := doc.(NewTracker); ok {
 		isNew = newt.IsNew()
 	}
 
+	if err := c.validateSave(doc, isNew, o); err != nil {
+		return primitive.NilObjectID, err
+	}
+
 	// Add created/modified time. Also set on the model itself if it has those fields.
 	now := time.Now()
 
@@ -144,13 +250,12 @@ func (c *Collection) Save(doc Document) error {
 	if tt, ok := doc.(TimeModifiedTracker); ok {
 		tt.SetUpdatedAt(now)
 	}
-
-	go CascadeSave(c, doc)
+	c.blame(doc, isNew)
 
 	id := doc.GetID()
 
 	if !isNew && id.IsZero() {
-		return errors.New("new tracker says this document isn't new but there is no valid Id field")
+		return primitive.NilObjectID, errors.New("new tracker says this document isn't new but there is no valid Id field")
 	}
 
 	if isNew && id.IsZero() {
@@ -159,13 +264,83 @@ func (c *Collection) Save(doc Document) error {
 		doc.SetID(id)
 	}
 
-	err = c.UpsertID(id, doc)
+	if err := c.encodeSave(doc, isNew); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	// A queued write of an older version must not land after this one
+	c.dropQueuedWrite(id)
+	return id, nil
+}
+
+// The checks of every write of a whole document: that it wasn't found with a projection, field access, validation
+// and the before save hooks (or only validation, without hooks) and references if they are enforced. Secrets are
+// hashed afterwards
+func (c *Collection) validateSave(doc Document, isNew bool, o *writeOptions) error {
+	err := c.checkProjected(doc)
+	if err != nil {
+		return err
+	}
+
+	err = c.enforceFieldAccess(doc, isNew)
+	if err != nil {
+		return err
+	}
+
+	if o.skipHooks {
+		err = c.validateDocument(doc)
+	} else {
+		err = c.PreSave(doc)
+	}
 	if err != nil {
 		return err
 	}
 
-	if hook, ok := doc.(AfterSaveHook); ok {
-		err = hook.AfterSave(c)
+	if !o.skipReferences {
+		if err = c.enforceReferences(doc); err != nil {
+			return err
+		}
+	}
+
+	// Validation sees the plaintext of secrets, the database only the hashes
+	return hashFields(doc)
+}
+
+// Gets a validated document into the shape it is stored in: checks its shard key, converts its zoned times, uploads
+// its overflowed fields and streams, and checks its size
+func (c *Collection) encodeSave(doc Document, isNew bool) error {
+	if err := c.checkShardKey(doc, isNew); err != nil {
+		return err
+	}
+
+	storeZones(doc)
+
+	// Overflowed fields don't count towards the size of the document
+	if err := c.storeOverflow(c.baseContext(), doc); err != nil {
+		return err
+	}
+	if err := c.storeStreams(c.baseContext(), doc); err != nil {
+		return err
+	}
+
+	return c.checkDocumentSize(doc)
+}
+
+// Runs the after save hook once a document is written
+func (c *Collection) finishSave(doc Document, o *writeOptions) error {
+	if o.identityMap != nil {
+		o.identityMap.Evict(c, doc.GetID())
+	}
+	c.forgetDegraded(doc.GetID())
+	c.cacheSaved(doc, o.queued)
+
+	// Saving may have restored stored values the principal can't read
+	c.stripUnreadable(doc)
+	c.dropOverflow(doc, false)
+	c.dropStreams(doc, false)
+
+	if hook, ok := doc.(AfterSaveHook); ok && !o.skipHooks {
+		err := hook.AfterSave(c)
 		if err != nil {
 			return err
 		}
@@ -179,15 +354,58 @@ func (c *Collection) Save(doc Document) error {
 	return nil
 }
 
-func (c *Collection) FindByID(id primitive.ObjectID, doc interface{}) error {
+// Loads the overflowed fields of a decoded document, attaches its streams and moves its times into their zones
+func (c *Collection) completeFound(ctx context.Context, doc interface{}) error {
+	if err := c.loadOverflow(ctx, doc); err != nil {
+		return err
+	}
+	c.attachStreams(doc)
+	applyZones(doc)
+	return nil
+}
 
-	filter := bson.D{{"_id", id}}
+func (c *Collection) FindByID(id primitive.ObjectID, doc interface{}, opts ...FindOption) error {
+	return c.findByID(c.baseContext(), id, doc, opts...)
+}
 
-	err := c.Collection().FindOne(context.Background(), filter).Decode(&doc)
+func (c *Collection) findByID(parent context.Context, id primitive.ObjectID, doc interface{}, opts ...FindOption) error {
+	// Documents with (part of) their shard key set are only looked for on their shard
+	filter := documentFilter(id, doc)
+
+	o, err := c.findOptions(opts)
+	if err != nil {
+		return err
+	}
+	if found, err := c.findCached(parent, id, doc, o); found || err != nil {
+		return err
+	}
+	findOpts := options.FindOne()
+	projection := o.viewProjection(reflect.TypeOf(doc))
+	if projection != nil {
+		findOpts.SetProjection(projection)
+	}
+	if o.maxTime > 0 {
+		findOpts.SetMaxTime(o.maxTime)
+	}
+
+	var result *mongo.SingleResult
+	err = c.runOperationContext(parent, "findById", func(ctx context.Context) error {
+		guarded, err := c.guardFilter(ctx, filter)
+		if err != nil {
+			return err
+		}
+		commentOn(ctx, c, findOpts.SetComment)
+		result = c.Collection().FindOne(ctx, guarded, findOpts)
+		return result.Err()
+	})
 
 	// Handle errors coming from mgo - we want to convert it to a DocumentNotFoundError so people can figure out
 	// what the error type is without looking at the text
 	if err != nil {
+		if c.serveDegraded(id, opts, doc, err) {
+			markProjected(doc, projection != nil)
+			return nil
+		}
 		if err == mongo.ErrNoDocuments {
 			return &DocumentNotFoundError{}
 		} else {
@@ -195,60 +413,111 @@ func (c *Collection) FindByID(id primitive.ObjectID, doc interface{}) error {
 		}
 	}
 
+	if err = result.Decode(doc); err != nil {
+		raw, _ := result.Raw()
+		return newDecodeError(c, raw, doc, err)
+	}
+	if err = c.finishFind(parent, doc); err != nil {
+		return err
+	}
+	markProjected(doc, projection != nil)
+	if raw, err := result.Raw(); err == nil {
+		c.keepDegraded(id, opts, raw)
+		c.cacheFound(id, o, raw)
+	}
+	return nil
+}
+
+// Completes a document found by _id and runs the after find hook
+func (c *Collection) finishFind(ctx context.Context, doc interface{}) error {
+	if err := c.completeFound(ctx, doc); err != nil {
+		return err
+	}
+
 	if hook, ok := doc.(AfterFindHook); ok {
-		err = hook.AfterFind(c)
-		if err != nil {
+		if err := hook.AfterFind(c); err != nil {
 			return err
 		}
 	}
+	c.stripUnreadable(doc)
 
 	// We retrieved it, so set new to false
 	if newt, ok := doc.(NewTracker); ok {
 		newt.SetIsNew(false)
 	}
+	markFresh(doc)
+	markProjected(doc, false)
 	return nil
 }
 
 // This doesn't actually do any DB interaction, it just creates the result set so we can
-// start looping through on the iterator
-func (c *Collection) Find(query interface{}) (*ResultSet, error) {
-	col := c.Collection()
-
-	// Count for testing
-	cursor, err := col.Find(context.Background(), query)
+// start looping through on the iterator. The query runs on the first call to ResultSet.Next
+func (c *Collection) Find(query interface{}, opts ...FindOption) (*ResultSet, error) {
 	resultset := new(ResultSet)
 
-	opts := &options.FindOptions{}
+	o, err := c.findOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	findOpts := &options.FindOptions{}
+	if o.projection != nil {
+		findOpts.SetProjection(o.projection)
+	}
+	if o.sort != nil {
+		findOpts.SetSort(o.sort)
+	} else if sort := c.defaults().Sort; sort != nil {
+		findOpts.SetSort(sort)
+	}
+	if o.maxTime > 0 {
+		findOpts.SetMaxTime(o.maxTime)
+	}
 
-	resultset.Query = opts
-	resultset.Cursor = cursor
+	resultset.Query = findOpts
 	resultset.Params = query
 	resultset.Collection = c
+	resultset.view = o
 
-	return resultset, err
+	return resultset, nil
 }
 
 func (c *Collection) UpsertID(id primitive.ObjectID, doc interface{}) error {
+	return c.upsertID(c.baseContext(), id, doc)
+}
+
+func (c *Collection) upsertID(parent context.Context, id primitive.ObjectID, doc interface{}) error {
 	upsertopts := &options.ReplaceOptions{}
 	upsertopts.SetUpsert(true)
-	_, err := c.Collection().ReplaceOne(context.Background(), bson.D{{"_id", id}}, doc, upsertopts)
+	err := c.runOperationContext(parent, "upsert", func(ctx context.Context) error {
+		filter, err := c.guardFilter(ctx, documentFilter(id, doc))
+		if err != nil {
+			return err
+		}
+		commentOn(ctx, c, upsertopts.SetComment)
+		_, err = c.Collection().ReplaceOne(ctx, filter, doc, upsertopts)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Collection) FindOne(query interface{}, doc interface{}) error {
+func (c *Collection) FindOne(query interface{}, doc interface{}, opts ...FindOption) error {
 	// Now run a find
-	results, err := c.Find(query)
+	results, err := c.Find(query, opts...)
 	if err != nil {
 		return err
 	}
+	defer results.Free()
 	results.Query.SetLimit(1)
 	hasNext := results.Next(doc)
 	if !hasNext {
 		// There could have been an error fetching the next one, which would set the Error property on the resultset
 		if results.Error != nil {
+			if c.serveDegraded(query, opts, doc, results.Error) {
+				markProjected(doc, results.Query.Projection != nil)
+				return nil
+			}
 			return results.Error
 		} else {
 			return &DocumentNotFoundError{}
@@ -258,49 +527,117 @@ func (c *Collection) FindOne(query interface{}, doc interface{}) error {
 	if newt, ok := doc.(NewTracker); ok {
 		newt.SetIsNew(false)
 	}
+	markFresh(doc)
+	c.keepDegraded(query, opts, results.Cursor.Current)
 
 	return nil
 }
 
-func (c *Collection) DeleteDocument(doc Document) (*mongo.DeleteResult, error) {
+func (c *Collection) DeleteDocument(doc Document, opts ...WriteOption) (*mongo.DeleteResult, error) {
+	return c.deleteDocument(c.baseContext(), doc, newWriteOptions(opts))
+}
+
+func (c *Collection) deleteDocument(parent context.Context, doc Document, o *writeOptions) (*mongo.DeleteResult, error) {
 	var err error
 	// Create a new session per mgo's suggestion to avoid blocking
 	col := c.Collection()
 
-	if hook, ok := doc.(BeforeDeleteHook); ok {
-		err := hook.BeforeDelete(c)
-		if err != nil {
-			return nil, err
-		}
+	if err = c.prepareDelete(doc, o); err != nil {
+		return nil, err
 	}
 
-	res, err := col.DeleteOne(context.Background(), bson.M{"_id": doc.GetID()})
+	var res *mongo.DeleteResult
+	err = c.runOperationContext(parent, "deleteDocument", func(ctx context.Context) error {
+		filter, err := c.guardFilter(ctx, bson.M{"_id": doc.GetID()})
+		if err != nil {
+			return err
+		}
+		deleteOpts := options.Delete()
+		commentOn(ctx, c, deleteOpts.SetComment)
+		res, err = col.DeleteOne(ctx, filter, deleteOpts)
+		return err
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	go CascadeDelete(c, doc)
+	if err = c.finishDelete(doc, o); err != nil {
+		return nil, err
+	}
+
+	return res, nil
 
-	if hook, ok := doc.(AfterDeleteHook); ok {
-		err = hook.AfterDelete(c)
+}
+
+// Runs the before delete hook and makes sure the document may be deleted
+func (c *Collection) prepareDelete(doc Document, o *writeOptions) error {
+	if hook, ok := doc.(BeforeDeleteHook); ok && !o.skipHooks {
+		err := hook.BeforeDelete(c)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	return res, nil
+	if err := c.restrictDelete(doc.GetID()); err != nil {
+		return err
+	}
 
+	// A queued write must not bring the document back
+	c.uncache(doc.GetID())
+	return nil
+}
+
+// Cascades the delete of a document and runs the after delete hook
+func (c *Collection) finishDelete(doc Document, o *writeOptions) error {
+	if o.identityMap != nil {
+		o.identityMap.Evict(c, doc.GetID())
+	}
+	c.forgetDegraded(doc.GetID())
+	c.uncache(doc.GetID())
+
+	if !o.skipCascade {
+		c.Connection.goCascade(func() { CascadeDelete(c, doc) })
+	}
+	c.dropOverflow(doc, true)
+	c.dropStreams(doc, true)
+
+	if hook, ok := doc.(AfterDeleteHook); ok && !o.skipHooks {
+		return hook.AfterDelete(c)
+	}
+	return nil
 }
 
 // Convenience method which just delegates to mgo. Note that hooks are NOT run
 func (c *Collection) Delete(query bson.D) (*mongo.DeleteResult, error) {
 	col := c.Collection()
-	return col.DeleteMany(context.Background(), query)
+	var res *mongo.DeleteResult
+	err := c.runOperation("delete", func(ctx context.Context) error {
+		filter, err := c.guardFilter(ctx, query)
+		if err != nil {
+			return err
+		}
+		deleteOpts := options.Delete()
+		commentOn(ctx, c, deleteOpts.SetComment)
+		res, err = col.DeleteMany(ctx, filter, deleteOpts)
+		return err
+	})
+	return res, err
 }
 
 // Convenience method which just delegates to mgo. Note that hooks are NOT run
 func (c *Collection) DeleteOne(query bson.D) (*mongo.DeleteResult, error) {
 	col := c.Collection()
-	return col.DeleteOne(context.Background(), query)
+	var res *mongo.DeleteResult
+	err := c.runOperation("deleteOne", func(ctx context.Context) error {
+		filter, err := c.guardFilter(ctx, query)
+		if err != nil {
+			return err
+		}
+		deleteOpts := options.Delete()
+		commentOn(ctx, c, deleteOpts.SetComment)
+		res, err = col.DeleteOne(ctx, filter, deleteOpts)
+		return err
+	})
+	return res, err
 }
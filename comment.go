@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+)
+
+// The id of the request an operation runs for, e.g. from an X-Request-Id header
+var RequestIDContextKey = NewContextKey[string]("request_id")
+
+// The name of the code an operation runs for, e.g. the handler or job
+var CallerContextKey = NewContextKey[string]("caller")
+
+// The $comment of the queries of an operation, so slow queries in the server log and the profiler can be traced back
+// to the request that ran them, e.g. "request_id=4f2a caller=orders.List client=5d0f4f5fa6d8ba0001a1b2c3". Built
+// from RequestIDContextKey and CallerContextKey in the operation's context or else the collection's Context, with
+// whitespace replaced by underscores. The client token tells the connection's operations from those of other
+// clients, see CurrentOps. Empty if neither the request id nor the caller is set
+func (c *Collection) queryComment(ctx context.Context) string {
+	parts := make([]string, 0, 3)
+	if id := commentValue(ctx, c, RequestIDContextKey); len(id) > 0 {
+		parts = append(parts, "request_id="+id)
+	}
+	if caller := commentValue(ctx, c, CallerContextKey); len(caller) > 0 {
+		parts = append(parts, "caller="+caller)
+	}
+	if len(parts) > 0 && c.Connection != nil {
+		parts = append(parts, "client="+c.Connection.clientToken())
+	}
+	return strings.Join(parts, " ")
+}
+
+func commentValue(ctx context.Context, c *Collection, key ContextKey[string]) string {
+	value, ok := key.From(ctx)
+	if !ok {
+		value, _ = key.Get(c.Context)
+	}
+	// Values come from headers and such, and must not split into more parts of the comment
+	return strings.Join(strings.Fields(value), "_")
+}
+
+// A random token that is part of the comments of the connection's operations, made on first use
+func (m *Connection) clientToken() string {
+	m.tokenOnce.Do(func() {
+		m.token = primitive.NewObjectID().Hex()
+	})
+	return m.token
+}
+
+// Sets the $comment of the options of a query, if there is one, e.g. commentOn(ctx, c, opts.SetComment). Takes the
+// setter, since the driver's options take comments as strings or as any value
+func commentOn[T any, O any](ctx context.Context, c *Collection, set func(T) O) {
+	if comment := c.queryComment(ctx); len(comment) > 0 {
+		set(any(comment).(T))
+	}
+}
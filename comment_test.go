@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"testing"
+)
+
+func TestQueryComment(t *testing.T) {
+	Convey("Query comments", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+		orders := conn.Collection("orders")
+		ctx := context.Background()
+
+		Convey("should be empty without a request id or caller", func() {
+			So(orders.queryComment(ctx), ShouldEqual, "")
+
+			opts := options.Find()
+			commentOn(ctx, orders, opts.SetComment)
+			So(opts.Comment, ShouldBeNil)
+		})
+
+		Convey("should hold the request id and caller of the context", func() {
+			ctx = RequestIDContextKey.WithValue(ctx, "4f2a")
+			ctx = CallerContextKey.WithValue(ctx, "orders.List")
+			So(orders.queryComment(ctx), ShouldEqual, "request_id=4f2a caller=orders.List client="+conn.clientToken())
+			So(orders.WithContext(ctx).queryComment(context.Background()), ShouldEqual, "request_id=4f2a caller=orders.List client="+conn.clientToken())
+		})
+
+		Convey("should fall back to the collection's Context", func() {
+			tagged := orders.WithContextValue(CallerContextKey, "billing")
+			So(tagged.queryComment(ctx), ShouldEqual, "caller=billing client="+conn.clientToken())
+			So(tagged.queryComment(CallerContextKey.WithValue(ctx, "orders.List")), ShouldEqual, "caller=orders.List client="+conn.clientToken())
+		})
+
+		Convey("should identify the connection", func() {
+			ctx = CallerContextKey.WithValue(ctx, "orders.List")
+			other := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+			So(conn.clientToken(), ShouldHaveLength, 24)
+			So(conn.clientToken(), ShouldEqual, conn.clientToken())
+			So(orders.queryComment(ctx), ShouldNotEqual, other.Collection("orders").queryComment(ctx))
+		})
+
+		Convey("should not let values split into more parts", func() {
+			ctx = RequestIDContextKey.WithValue(ctx, " 4f2a caller=admin\t")
+			So(orders.queryComment(ctx), ShouldEqual, "request_id=4f2a_caller=admin client="+conn.clientToken())
+		})
+
+		Convey("should be set on string and any comment options", func() {
+			ctx = RequestIDContextKey.WithValue(ctx, "4f2a")
+
+			find := options.Find()
+			commentOn(ctx, orders, find.SetComment)
+			So(*find.Comment, ShouldEqual, "request_id=4f2a client="+conn.clientToken())
+
+			update := options.Update()
+			commentOn(ctx, orders, update.SetComment)
+			So(update.Comment, ShouldEqual, "request_id=4f2a client="+conn.clientToken())
+		})
+	})
+}
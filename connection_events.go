@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	"sync"
+	"time"
+)
+
+// Connection event types
+const (
+	EVENT_CONNECTED = iota
+	EVENT_DISCONNECTED
+	EVENT_PRIMARY_CHANGED
+)
+
+// Number of events buffered on the Events() channel. If the consumer falls behind, further events are dropped
+const connectionEventBuffer = 32
+
+type ConnectionEvent struct {
+	Type int
+	// Address of the new primary for EVENT_PRIMARY_CHANGED (empty if there is no primary)
+	Address string
+	Time    time.Time
+}
+
+type connectionEvents struct {
+	once      sync.Once
+	ch        chan ConnectionEvent
+	mutex     sync.Mutex
+	connected bool
+	primary   string
+}
+
+// Returns a channel emitting connected/disconnected/primary-changed events. The events are derived from the
+// topology description, which the driver updates on every server heartbeat
+func (m *Connection) Events() <-chan ConnectionEvent {
+	m.events.once.Do(func() {
+		m.events.ch = make(chan ConnectionEvent, connectionEventBuffer)
+		m.OnTopologyChanged(m.handleTopologyChanged)
+	})
+	return m.events.ch
+}
+
+// Whether any server of a topology is known, and the address of its primary (if any)
+func topologyState(e *event.TopologyDescriptionChangedEvent) (bool, string) {
+	connected := false
+	primary := ""
+	for _, server := range e.NewDescription.Servers {
+		if server.Kind != description.Unknown {
+			connected = true
+		}
+		if server.Kind == description.RSPrimary {
+			primary = server.Addr.String()
+		}
+	}
+	return connected, primary
+}
+
+func (m *Connection) handleTopologyChanged(e *event.TopologyDescriptionChangedEvent) {
+	connected, primary := topologyState(e)
+	now := time.Now()
+
+	m.events.mutex.Lock()
+	defer m.events.mutex.Unlock()
+
+	if connected != m.events.connected {
+		m.events.connected = connected
+		if connected {
+			m.emit(ConnectionEvent{Type: EVENT_CONNECTED, Time: now})
+		} else {
+			m.emit(ConnectionEvent{Type: EVENT_DISCONNECTED, Time: now})
+		}
+	}
+
+	if primary != m.events.primary {
+		m.events.primary = primary
+		m.emit(ConnectionEvent{Type: EVENT_PRIMARY_CHANGED, Address: primary, Time: now})
+	}
+}
+
+// Sends without blocking, since this runs on the driver's monitoring goroutines
+func (m *Connection) emit(e ConnectionEvent) {
+	select {
+	case m.events.ch <- e:
+	default:
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/address"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	"testing"
+)
+
+func topologyChange(servers ...description.Server) *event.TopologyDescriptionChangedEvent {
+	return &event.TopologyDescriptionChangedEvent{
+		NewDescription: description.Topology{Servers: servers},
+	}
+}
+
+func TestConnectionEvents(t *testing.T) {
+	Convey("Connection events", t, func() {
+		conn := &Connection{Config: &Config{}}
+		events := conn.Events()
+		monitor := conn.monitorOptions(0).ServerMonitor
+
+		Convey("should emit connected, primary changed and disconnected events", func() {
+			monitor.TopologyDescriptionChanged(topologyChange(
+				description.Server{Addr: address.Address("a:27017"), Kind: description.RSPrimary},
+				description.Server{Addr: address.Address("b:27017"), Kind: description.RSSecondary},
+			))
+
+			e := <-events
+			So(e.Type, ShouldEqual, EVENT_CONNECTED)
+			e = <-events
+			So(e.Type, ShouldEqual, EVENT_PRIMARY_CHANGED)
+			So(e.Address, ShouldEqual, "a:27017")
+
+			monitor.TopologyDescriptionChanged(topologyChange(
+				description.Server{Addr: address.Address("a:27017"), Kind: description.RSSecondary},
+				description.Server{Addr: address.Address("b:27017"), Kind: description.RSPrimary},
+			))
+			e = <-events
+			So(e.Type, ShouldEqual, EVENT_PRIMARY_CHANGED)
+			So(e.Address, ShouldEqual, "b:27017")
+
+			monitor.TopologyDescriptionChanged(topologyChange(
+				description.Server{Addr: address.Address("a:27017"), Kind: description.Unknown},
+				description.Server{Addr: address.Address("b:27017"), Kind: description.Unknown},
+			))
+			e = <-events
+			So(e.Type, ShouldEqual, EVENT_DISCONNECTED)
+			e = <-events
+			So(e.Type, ShouldEqual, EVENT_PRIMARY_CHANGED)
+			So(e.Address, ShouldEqual, "")
+		})
+
+		Convey("should not emit anything when nothing changed", func() {
+			monitor.TopologyDescriptionChanged(topologyChange())
+			So(len(events), ShouldEqual, 0)
+		})
+	})
+}
@@ -4,31 +4,143 @@
 
 package bongo
 
-// Context struct
+import (
+	"context"
+	"sync"
+)
+
+// Values for hooks and operations, like the current user or the locale. A derived Context (see Derive and
+// WithContext) reads through to the Context it was derived from, while its own values and deletions stay on it.
+// Every collection gets a Context derived from the connection's, so values set on one collection (or one request's
+// copy of it, see Collection.WithContextValue) don't leak into the others. Safe for concurrent use
 type Context struct {
-	set map[string]interface{}
+	mutex sync.RWMutex
+	set   map[interface{}]interface{}
+	ctx   context.Context
+	base  *Context
 }
 
+// Marks a key deleted on a derived Context, hiding the value of its base
+type deletedContextValue struct{}
+
 // Get ...
 func (c *Context) Get(key string) interface{} {
-	if value, ok := c.set[key]; ok {
+	return c.Value(key)
+}
+
+// The value under a key: set on this Context, in its context.Context or in the Context it was derived from
+func (c *Context) Value(key interface{}) interface{} {
+	if c == nil {
+		return nil
+	}
+	c.mutex.RLock()
+	value, ok := c.set[key]
+	c.mutex.RUnlock()
+	if ok {
+		if _, deleted := value.(deletedContextValue); deleted {
+			return nil
+		}
 		return value
 	}
-	return nil
+	if c.ctx != nil {
+		if value := c.ctx.Value(key); value != nil {
+			return value
+		}
+	}
+	return c.base.Value(key)
 }
 
 func (c *Context) Delete(key string) bool {
-	if _, ok := c.set[key]; ok {
+	if c.Value(key) == nil {
+		return false
+	}
+
+	if c.base != nil || c.ctx != nil {
+		c.setValue(key, deletedContextValue{})
+	} else {
+		c.mutex.Lock()
 		delete(c.set, key)
-		return true
+		c.mutex.Unlock()
 	}
-	return false
+	return true
 }
 
 // Set ...
 func (c *Context) Set(key string, value interface{}) {
+	c.setValue(key, value)
+}
+
+func (c *Context) setValue(key interface{}, value interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	if c.set == nil {
-		c.set = make(map[string]interface{})
+		c.set = make(map[interface{}]interface{})
 	}
 	c.set[key] = value
 }
+
+// Derives a Context that reads through to this one. Values set on or deleted from it don't change this one
+func (c *Context) Derive() *Context {
+	return &Context{base: c}
+}
+
+// Derives a Context that reads values from ctx before this one
+func (c *Context) WithContext(ctx context.Context) *Context {
+	return &Context{ctx: ctx, base: c}
+}
+
+// The context.Context this Context was derived from, or context.Background()
+func (c *Context) Parent() context.Context {
+	for ; c != nil; c = c.base {
+		if c.ctx != nil {
+			return c.ctx
+		}
+	}
+	return context.Background()
+}
+
+// A typed key for values in a Context or a context.Context, e.g.
+//
+//	var CurrentUser = bongo.NewContextKey[*User]("user")
+//
+//	ctx = CurrentUser.WithValue(r.Context(), user)
+//	err := conn.Collection("orders").WithContext(ctx).Save(order)
+//
+//	func (o *Order) BeforeSave(c *bongo.Collection) error {
+//		user, _ := CurrentUser.Get(c.Context)
+//		...
+//	}
+type ContextKey[T any] struct {
+	name string
+}
+
+// Creates a key. Keys with the same name and type are the same key
+func NewContextKey[T any](name string) ContextKey[T] {
+	return ContextKey[T]{name: name}
+}
+
+func (k ContextKey[T]) String() string {
+	return "bongo.ContextKey(" + k.name + ")"
+}
+
+// The value of the key in a Context (or the context.Context it was derived from)
+func (k ContextKey[T]) Get(c *Context) (T, bool) {
+	value, ok := c.Value(k).(T)
+	return value, ok
+}
+
+// Sets the value of the key in a Context
+func (k ContextKey[T]) Set(c *Context, value T) {
+	c.setValue(k, value)
+}
+
+// The value of the key in a context.Context
+func (k ContextKey[T]) From(ctx context.Context) (T, bool) {
+	value, ok := ctx.Value(k).(T)
+	return value, ok
+}
+
+// Derives a context.Context with the value of the key
+func (k ContextKey[T]) WithValue(ctx context.Context, value T) context.Context {
+	return context.WithValue(ctx, k, value)
+}
@@ -5,6 +5,7 @@
 package bongo
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -26,6 +27,102 @@ func Test_Context(t *testing.T) {
 			So(c.Delete("foo"), ShouldBeFalse)
 		})
 
+		Convey("Typed keys", func() {
+			count := NewContextKey[int]("count")
+			c := &Context{}
+			_, ok := count.Get(c)
+			So(ok, ShouldBeFalse)
+
+			count.Set(c, 3)
+			value, ok := count.Get(c)
+			So(ok, ShouldBeTrue)
+			So(value, ShouldEqual, 3)
+
+			// Keys of other types don't collide
+			_, ok = NewContextKey[string]("count").Get(c)
+			So(ok, ShouldBeFalse)
+			So(c.Get("count"), ShouldBeNil)
+		})
+
+		Convey("Reading through to a context.Context", func() {
+			user := NewContextKey[string]("user")
+			base := &Context{}
+			base.Set("foo", "bar")
+			user.Set(base, "base")
+
+			ctx := user.WithValue(context.Background(), "request")
+			value, _ := user.From(ctx)
+			So(value, ShouldEqual, "request")
+
+			derived := base.WithContext(ctx)
+			value, _ = user.Get(derived)
+			So(value, ShouldEqual, "request")
+			So(derived.Get("foo"), ShouldEqual, "bar")
+			So(derived.Parent(), ShouldEqual, ctx)
+			So(base.Parent(), ShouldEqual, context.Background())
+
+			derived.Set("foo", "baz")
+			So(derived.Get("foo"), ShouldEqual, "baz")
+			So(base.Get("foo"), ShouldEqual, "bar")
+		})
+
+		Convey("Isolating collections", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+			conn.Context.Set("locale", "en")
+			first, second := conn.Collection("tests"), conn.Collection("tests")
+
+			first.Context.Set("user", "ann")
+			So(first.Context.Get("user"), ShouldEqual, "ann")
+			So(second.Context.Get("user"), ShouldBeNil)
+			So(conn.Context.Get("user"), ShouldBeNil)
+
+			So(first.Context.Delete("locale"), ShouldBeTrue)
+			So(first.Context.Get("locale"), ShouldBeNil)
+			So(second.Context.Get("locale"), ShouldEqual, "en")
+			So(first.Context.Delete("locale"), ShouldBeFalse)
+
+			german := second.WithContextValue(LocaleContextKey, "de")
+			So(german.Locale(), ShouldEqual, "de")
+			So(second.Locale(), ShouldEqual, "en")
+
+			conn.Context.Set("locale", "fr")
+			So(second.Locale(), ShouldEqual, "fr")
+		})
+
+		Convey("Concurrent use", func() {
+			c := (&Context{}).Derive()
+			done := make(chan bool)
+			for i := 0; i < 10; i++ {
+				go func(i int) {
+					c.Set("foo", i)
+					c.Get("foo")
+					done <- true
+				}(i)
+			}
+			for i := 0; i < 10; i++ {
+				<-done
+			}
+			So(c.Get("foo"), ShouldNotBeNil)
+		})
+
+		Convey("Collections for a request", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+			locale := NewContextKey[string]("locale")
+			ctx, cancel := context.WithCancel(locale.WithValue(context.Background(), "de"))
+			defer cancel()
+
+			col := conn.Collection("tests").WithContext(ctx)
+			value, _ := locale.Get(col.Context)
+			So(value, ShouldEqual, "de")
+			So(col.baseContext(), ShouldEqual, ctx)
+			So(conn.Collection("tests").baseContext(), ShouldEqual, context.Background())
+
+			cancel()
+			So(col.runOperation("test", func(ctx context.Context) error {
+				return ctx.Err()
+			}), ShouldEqual, context.Canceled)
+		})
+
 	})
 
 }
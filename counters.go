@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Named counters in a collection, updated atomically with $inc, e.g. for sequences, stats and quotas:
+//
+//	counters := bongo.Counters(conn, "counters")
+//	invoiceNumber, err := counters.Incr("invoices", 1)
+//	err = counters.IncrWindowed("api_calls:"+user, time.Now(), 1)
+//	today, err := counters.Day("api_calls:"+user, time.Now())
+type CounterSet struct {
+	Collection *Collection
+}
+
+type counterRecord struct {
+	Value int64 `bson:"value"`
+}
+
+// One day of a windowed counter, with the hours of the day (UTC) by their two digit number
+type windowRecord struct {
+	Total int64            `bson:"total"`
+	Hours map[string]int64 `bson:"hours"`
+}
+
+// The counters in the named collection
+func Counters(conn *Connection, collection string) *CounterSet {
+	return &CounterSet{Collection: conn.Collection(collection)}
+}
+
+// Adds n to the counter (which starts at 0) and returns its new value. Not retried by the RetryPolicy, since the
+// increment may have been applied when an error is returned
+func (s *CounterSet) Incr(name string, n int64) (int64, error) {
+	record := &counterRecord{}
+	err := s.Collection.runOperationOnce(s.Collection.baseContext(), "incr", func(ctx context.Context) error {
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+		return s.Collection.Collection().FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$inc": bson.M{"value": n}}, opts).Decode(record)
+	})
+	return record.Value, err
+}
+
+// The value of the counter, 0 if it was never incremented
+func (s *CounterSet) Get(name string) (int64, error) {
+	record := &counterRecord{}
+	err := s.find(name, record)
+	return record.Value, err
+}
+
+// Adds n to the day and the hour of at of a windowed counter. Every day is a document of its own. Not retried, like
+// Incr
+func (s *CounterSet) IncrWindowed(name string, at time.Time, n int64) error {
+	at = at.UTC()
+	update := bson.M{"$inc": bson.M{"total": n, "hours." + fmt.Sprintf("%02d", at.Hour()): n}}
+	return s.Collection.runOperationOnce(s.Collection.baseContext(), "incr", func(ctx context.Context) error {
+		_, err := s.Collection.Collection().UpdateOne(ctx, bson.M{"_id": windowKey(name, at)}, update, options.Update().SetUpsert(true))
+		return err
+	})
+}
+
+// The count of a windowed counter on the day (UTC) of day
+func (s *CounterSet) Day(name string, day time.Time) (int64, error) {
+	record := &windowRecord{}
+	err := s.find(windowKey(name, day.UTC()), record)
+	return record.Total, err
+}
+
+// The count of a windowed counter in the hour (UTC) of at
+func (s *CounterSet) Hour(name string, at time.Time) (int64, error) {
+	hours, err := s.Hours(name, at)
+	return hours[at.UTC().Hour()], err
+}
+
+// The counts of a windowed counter in each hour (UTC) of the day of day
+func (s *CounterSet) Hours(name string, day time.Time) ([24]int64, error) {
+	var hours [24]int64
+	record := &windowRecord{}
+	if err := s.find(windowKey(name, day.UTC()), record); err != nil {
+		return hours, err
+	}
+	for hour := range hours {
+		hours[hour] = record.Hours[fmt.Sprintf("%02d", hour)]
+	}
+	return hours, nil
+}
+
+// Decodes a counter document, leaving record alone if there is none
+func (s *CounterSet) find(id string, record interface{}) error {
+	err := s.Collection.runOperation("get", func(ctx context.Context) error {
+		return s.Collection.Collection().FindOne(ctx, bson.M{"_id": id}).Decode(record)
+	})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	return err
+}
+
+// The id of the document of a windowed counter for a day
+func windowKey(name string, day time.Time) string {
+	return name + "@" + day.Format("2006-01-02")
+}
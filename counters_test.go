@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestWindowKey(t *testing.T) {
+	Convey("Windowed counter keys", t, func() {
+		day := time.Date(2019, 6, 23, 23, 30, 0, 0, time.UTC)
+		So(windowKey("api_calls", day), ShouldEqual, "api_calls@2019-06-23")
+		So(windowKey("api_calls", day.Add(time.Hour)), ShouldEqual, "api_calls@2019-06-24")
+	})
+}
+
+func TestCounters(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Counters", t, func() {
+		counters := Counters(conn, "counters")
+
+		Convey("should count atomically", func() {
+			value, err := counters.Get("invoices")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 0)
+
+			value, err = counters.Incr("invoices", 1)
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 1)
+			value, _ = counters.Incr("invoices", 5)
+			So(value, ShouldEqual, 6)
+
+			value, _ = counters.Get("invoices")
+			So(value, ShouldEqual, 6)
+		})
+
+		Convey("should count per day and hour", func() {
+			at := time.Date(2019, 6, 23, 13, 5, 0, 0, time.UTC)
+			So(counters.IncrWindowed("calls", at, 2), ShouldBeNil)
+			So(counters.IncrWindowed("calls", at.Add(time.Minute), 1), ShouldBeNil)
+			So(counters.IncrWindowed("calls", at.Add(2*time.Hour), 4), ShouldBeNil)
+
+			day, _ := counters.Day("calls", at)
+			So(day, ShouldEqual, 7)
+			hour, _ := counters.Hour("calls", at)
+			So(hour, ShouldEqual, 3)
+			hours, _ := counters.Hours("calls", at)
+			So(hours[15], ShouldEqual, 4)
+			So(hours[0], ShouldEqual, 0)
+
+			day, _ = counters.Day("calls", at.AddDate(0, 0, 1))
+			So(day, ShouldEqual, 0)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
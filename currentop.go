@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+	"time"
+)
+
+// The $comment of the $currentOp aggregation of CurrentOps, so it doesn't list itself
+const CURRENT_OPS_COMMENT = "bongo.currentOps"
+
+// An operation in progress on the server, from $currentOp
+type CurrentOp struct {
+	// The id to pass to KillOp. A number, or a string like "shard01:1234" on mongos
+	OpID        interface{} `bson:"opid" json:"opid"`
+	Type        string      `bson:"type" json:"type"`
+	Op          string      `bson:"op" json:"op"`
+	Namespace   string      `bson:"ns" json:"ns"`
+	Active      bool        `bson:"active" json:"active"`
+	Command     bson.M      `bson:"command" json:"command"`
+	PlanSummary string      `bson:"planSummary" json:"planSummary"`
+	Client      string      `bson:"client" json:"client"`
+	AppName     string      `bson:"appName" json:"appName"`
+	Desc        string      `bson:"desc" json:"desc"`
+	WaitingLock bool        `bson:"waitingForLock" json:"waitingForLock"`
+	Microsecs   int64       `bson:"microsecs_running" json:"microsecs_running"`
+
+	// How long the operation has been running
+	Running time.Duration `bson:"-" json:"-"`
+
+	// The $comment of the operation (or of the query whose cursor it reads), and the request id and caller bongo
+	// put in it, see RequestIDContextKey
+	Comment   string `bson:"-" json:"comment"`
+	RequestID string `bson:"-" json:"requestId"`
+	Caller    string `bson:"-" json:"caller"`
+
+	// The bongo collection the operation runs against
+	Collection *Collection `bson:"-" json:"-"`
+}
+
+// Lists the operations in progress that were started by this client: those of its application name
+// (ClientOptions.SetAppName or appName in the connection string) and those with a $comment from this connection,
+// which carries a token of the connection, so the operations of other bongo clients aren't listed. The filter,
+// if any, further matches the output of $currentOp, e.g. bson.M{"secs_running": bson.M{"$gte": 30}}. Only the
+// operations of the connection's user are listed, unless it has the inprog privilege
+func (m *Connection) CurrentOps(filter interface{}) ([]*CurrentOp, error) {
+	ctx, cancel := m.operationContext()
+	defer cancel()
+
+	opts := options.Aggregate().SetComment(CURRENT_OPS_COMMENT)
+	cursor, err := m.Client().Database("admin").Aggregate(ctx, currentOpsPipeline(m.appName(), m.clientToken(), filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ops := make([]*CurrentOp, 0)
+	for cursor.Next(ctx) {
+		op := &CurrentOp{}
+		if err := cursor.Decode(op); err != nil {
+			return ops, err
+		}
+		op.complete(m, cursor.Current)
+		ops = append(ops, op)
+	}
+	return ops, cursor.Err()
+}
+
+// Kills an operation listed by CurrentOps, e.g. a runaway query. The server stops it at its next interrupt point
+func (m *Connection) KillOp(opID interface{}) error {
+	ctx, cancel := m.operationContext()
+	defer cancel()
+
+	cmd := bson.D{{Key: "killOp", Value: 1}, {Key: "op", Value: opID}}
+	return m.Client().Database("admin").RunCommand(ctx, cmd).Err()
+}
+
+// A context with the operation timeout of the connection, if any
+func (m *Connection) operationContext() (context.Context, context.CancelFunc) {
+	if config := m.config(); config != nil && config.OperationTimeout > 0 {
+		return context.WithTimeout(context.Background(), config.OperationTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// The application name the client reports to the server, if any
+func (m *Connection) appName() string {
+	if m.Config == nil {
+		return ""
+	}
+	opts := options.Client()
+	if len(m.Config.ConnectionString) > 0 {
+		opts.ApplyURI(m.Config.ConnectionString)
+	}
+	if m.Config.ClientOptions != nil && m.Config.ClientOptions.AppName != nil {
+		opts.SetAppName(*m.Config.ClientOptions.AppName)
+	}
+	if opts.AppName == nil {
+		return ""
+	}
+	return *opts.AppName
+}
+
+func currentOpsPipeline(appName string, token string, filter interface{}) bson.A {
+	comment := bson.M{"$regex": "(^| )client=" + token + "( |$)"}
+	client := bson.A{
+		bson.M{"command.comment": comment},
+		bson.M{"cursor.originatingCommand.comment": comment},
+	}
+	if len(appName) > 0 {
+		client = append(client, bson.M{"appName": appName})
+	}
+
+	match := bson.A{
+		bson.M{"$or": client},
+		bson.M{"command.comment": bson.M{"$ne": CURRENT_OPS_COMMENT}},
+	}
+	if filter != nil {
+		match = append(match, filter)
+	}
+	return bson.A{
+		bson.M{"$currentOp": bson.M{}},
+		bson.M{"$match": bson.M{"$and": match}},
+	}
+}
+
+// Fills in the fields that aren't decoded from the raw output of $currentOp
+func (op *CurrentOp) complete(m *Connection, raw bson.Raw) {
+	op.Running = time.Duration(op.Microsecs) * time.Microsecond
+
+	if comment, ok := op.Command["comment"].(string); ok {
+		op.Comment = comment
+	} else if comment, ok := raw.Lookup("cursor", "originatingCommand", "comment").StringValueOK(); ok {
+		op.Comment = comment
+	}
+	for _, part := range strings.Fields(op.Comment) {
+		if value, ok := strings.CutPrefix(part, "request_id="); ok {
+			op.RequestID = value
+		} else if value, ok := strings.CutPrefix(part, "caller="); ok {
+			op.Caller = value
+		}
+	}
+
+	split := strings.SplitN(op.Namespace, ".", 2)
+	if len(split) == 2 {
+		op.Collection = m.CollectionFromDatabase(split[1], split[0])
+	}
+}
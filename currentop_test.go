@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"testing"
+	"time"
+)
+
+func TestCurrentOpDecoding(t *testing.T) {
+	Convey("Current operations", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest", ConnectionString: "mongodb://localhost:27017/?appName=billing"}, Context: &Context{}}
+
+		Convey("should know the application name of the client", func() {
+			So(conn.appName(), ShouldEqual, "billing")
+			conn.Config.ClientOptions = options.Client().SetAppName("reports")
+			So(conn.appName(), ShouldEqual, "reports")
+			conn.Config = &Config{ConnectionString: "mongodb://localhost:27017"}
+			So(conn.appName(), ShouldEqual, "")
+		})
+
+		Convey("should match the operations of the client", func() {
+			pipeline := currentOpsPipeline("billing", conn.clientToken(), bson.M{"secs_running": bson.M{"$gte": 30}})
+			match := pipeline[1].(bson.M)["$match"].(bson.M)["$and"].(bson.A)
+			So(len(match), ShouldEqual, 3)
+			So(len(match[0].(bson.M)["$or"].(bson.A)), ShouldEqual, 3)
+			So(match[0].(bson.M)["$or"].(bson.A)[0], ShouldResemble, bson.M{"command.comment": bson.M{"$regex": "(^| )client=" + conn.clientToken() + "( |$)"}})
+			So(match[1], ShouldResemble, bson.M{"command.comment": bson.M{"$ne": CURRENT_OPS_COMMENT}})
+			So(match[2], ShouldResemble, bson.M{"secs_running": bson.M{"$gte": 30}})
+
+			pipeline = currentOpsPipeline("", conn.clientToken(), nil)
+			match = pipeline[1].(bson.M)["$match"].(bson.M)["$and"].(bson.A)
+			So(len(match), ShouldEqual, 2)
+			So(len(match[0].(bson.M)["$or"].(bson.A)), ShouldEqual, 2)
+		})
+
+		Convey("should read the request id and caller from comments", func() {
+			raw, err := bson.Marshal(bson.M{
+				"opid":              int32(42),
+				"ns":                "bongotest.orders",
+				"microsecs_running": int64(1500000),
+				"command":           bson.M{"find": "orders", "comment": "request_id=4f2a caller=orders.List"},
+			})
+			So(err, ShouldBeNil)
+			op := &CurrentOp{}
+			So(bson.Unmarshal(raw, op), ShouldBeNil)
+			op.complete(conn, raw)
+
+			So(op.OpID, ShouldEqual, int32(42))
+			So(op.Running, ShouldEqual, 1500*time.Millisecond)
+			So(op.RequestID, ShouldEqual, "4f2a")
+			So(op.Caller, ShouldEqual, "orders.List")
+			So(op.Collection.Name, ShouldEqual, "orders")
+			So(op.Collection.Database, ShouldEqual, "bongotest")
+		})
+
+		Convey("should read the comment of the query of a getMore", func() {
+			raw, err := bson.Marshal(bson.M{
+				"opid":    "shard01:7",
+				"command": bson.M{"getMore": int64(1)},
+				"cursor":  bson.M{"originatingCommand": bson.M{"comment": "caller=export"}},
+			})
+			So(err, ShouldBeNil)
+			op := &CurrentOp{}
+			So(bson.Unmarshal(raw, op), ShouldBeNil)
+			op.complete(conn, raw)
+
+			So(op.OpID, ShouldEqual, "shard01:7")
+			So(op.Comment, ShouldEqual, "caller=export")
+			So(op.Caller, ShouldEqual, "export")
+			So(op.RequestID, ShouldEqual, "")
+		})
+	})
+}
+
+func TestCurrentOps(t *testing.T) {
+	Convey("should list the operations of the client without itself", t, func() {
+		conn := getConnection()
+		ops, err := conn.CurrentOps(nil)
+		So(err, ShouldBeNil)
+		for _, op := range ops {
+			So(op.Comment, ShouldNotEqual, CURRENT_OPS_COMMENT)
+		}
+	})
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	DATE_FORMAT        = "2006-01-02"
+	TIME_OF_DAY_FORMAT = "15:04:05"
+)
+
+// A calendar date without a time or time zone, like a birthday. Stored as midnight UTC of the date, so dates compare
+// and sort correctly in queries, and written to JSON as "2006-01-02". The zero value is no date and stored as null
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// A date, normalizing overflowing months and days like time.Date, e.g. January 32 is February 1
+func NewDate(year int, month time.Month, day int) Date {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// The date of t in its location
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// Parses a date like "2006-01-02"
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DATE_FORMAT, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// Midnight of the date in a location
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// Adds years, months and days like time.AddDate
+func (d Date) AddDate(years int, months int, days int) Date {
+	return DateOf(d.In(time.UTC).AddDate(years, months, days))
+}
+
+func (d Date) Before(o Date) bool {
+	return d.In(time.UTC).Before(o.In(time.UTC))
+}
+
+func (d Date) After(o Date) bool {
+	return o.Before(d)
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.In(time.UTC).Format(DATE_FORMAT)
+}
+
+func (d Date) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if d.IsZero() {
+		return bsontype.Null, nil, nil
+	}
+	return bson.MarshalValue(primitive.NewDateTimeFromTime(d.In(time.UTC)))
+}
+
+// Reads dates, which are taken in UTC, and strings in DATE_FORMAT
+func (d *Date) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.DateTime:
+		*d = DateOf(raw.Time().UTC())
+	case bsontype.String:
+		parsed, err := ParseDate(raw.StringValue())
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	case bsontype.Null, bsontype.Undefined:
+		*d = Date{}
+	default:
+		return errors.New("can't decode " + t.String() + " into a date")
+	}
+	return nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == nil || len(*s) == 0 {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(*s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// A filter for the dates at path from from (inclusive) until until (exclusive). Zero bounds are left out, so
+// without any the filter matches everything
+func DateRange(path string, from Date, until Date) bson.M {
+	condition := bson.M{}
+	if !from.IsZero() {
+		condition["$gte"] = from
+	}
+	if !until.IsZero() {
+		condition["$lt"] = until
+	}
+	if len(condition) == 0 {
+		return bson.M{}
+	}
+	return bson.M{path: condition}
+}
+
+// A time of day without a date or time zone, like an opening hour. Stored as the seconds since midnight, so times
+// compare and sort correctly in queries, and written to JSON as "15:04:05". The zero value is midnight
+type TimeOfDay struct {
+	Hour   int
+	Minute int
+	Second int
+}
+
+// A time of day, wrapping around midnight, e.g. 25:00 is 01:00
+func NewTimeOfDay(hour int, minute int, second int) TimeOfDay {
+	return timeOfDayFromSeconds(hour*3600 + minute*60 + second)
+}
+
+// The time of day of t in its location
+func TimeOfDayOf(t time.Time) TimeOfDay {
+	return TimeOfDay{Hour: t.Hour(), Minute: t.Minute(), Second: t.Second()}
+}
+
+// Parses a time of day like "15:04:05" or "15:04"
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	t, err := time.Parse(TIME_OF_DAY_FORMAT, s)
+	if err != nil {
+		var shortErr error
+		if t, shortErr = time.Parse("15:04", s); shortErr != nil {
+			return TimeOfDay{}, err
+		}
+	}
+	return TimeOfDayOf(t), nil
+}
+
+func timeOfDayFromSeconds(seconds int) TimeOfDay {
+	seconds %= 24 * 3600
+	if seconds < 0 {
+		seconds += 24 * 3600
+	}
+	return TimeOfDay{Hour: seconds / 3600, Minute: seconds / 60 % 60, Second: seconds % 60}
+}
+
+// The seconds since midnight
+func (t TimeOfDay) Seconds() int {
+	return t.Hour*3600 + t.Minute*60 + t.Second
+}
+
+// Adds a duration, wrapping around midnight. Fractions of seconds are dropped
+func (t TimeOfDay) Add(d time.Duration) TimeOfDay {
+	return timeOfDayFromSeconds(t.Seconds() + int(d/time.Second))
+}
+
+// The time of day on a date in a location
+func (t TimeOfDay) On(d Date, loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, t.Hour, t.Minute, t.Second, 0, loc)
+}
+
+func (t TimeOfDay) Before(o TimeOfDay) bool {
+	return t.Seconds() < o.Seconds()
+}
+
+func (t TimeOfDay) After(o TimeOfDay) bool {
+	return t.Seconds() > o.Seconds()
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+}
+
+func (t TimeOfDay) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(int32(t.Seconds()))
+}
+
+// Reads seconds since midnight and strings in TIME_OF_DAY_FORMAT
+func (t *TimeOfDay) UnmarshalBSONValue(bt bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: bt, Value: data}
+	if seconds, ok := raw.AsInt64OK(); ok {
+		*t = timeOfDayFromSeconds(int(seconds))
+		return nil
+	}
+	switch bt {
+	case bsontype.String:
+		parsed, err := ParseTimeOfDay(raw.StringValue())
+		if err != nil {
+			return err
+		}
+		*t = parsed
+	case bsontype.Null, bsontype.Undefined:
+		*t = TimeOfDay{}
+	default:
+		return errors.New("can't decode " + bt.String() + " into a time of day")
+	}
+	return nil
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *TimeOfDay) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseTimeOfDay(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// A filter for the times of day at path from from (inclusive) until until (exclusive). Ranges that wrap around
+// midnight, like 22:00 until 06:00, match the times after from or before until
+func TimeOfDayRange(path string, from TimeOfDay, until TimeOfDay) bson.M {
+	if from.After(until) {
+		return bson.M{"$or": bson.A{
+			bson.M{path: bson.M{"$gte": from}},
+			bson.M{path: bson.M{"$lt": until}},
+		}}
+	}
+	return bson.M{path: bson.M{"$gte": from, "$lt": until}}
+}
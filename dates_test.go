@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+type datesDocument struct {
+	DocumentBase `bson:",inline"`
+	Birthday     Date      `bson:"birthday" json:"birthday"`
+	Opens        TimeOfDay `bson:"opens" json:"opens"`
+}
+
+func TestDate(t *testing.T) {
+	Convey("Date", t, func() {
+		Convey("should be the date of a time in its location", func() {
+			zurich, _ := time.LoadLocation("Europe/Zurich")
+			So(DateOf(time.Date(2020, 3, 1, 0, 30, 0, 0, zurich)), ShouldResemble, Date{Year: 2020, Month: 3, Day: 1})
+			So(NewDate(2020, 1, 32), ShouldResemble, Date{Year: 2020, Month: 2, Day: 1})
+			So(NewDate(2020, 2, 28).AddDate(0, 0, 1).String(), ShouldEqual, "2020-02-29")
+			So(NewDate(2020, 2, 28).Before(NewDate(2020, 3, 1)), ShouldBeTrue)
+		})
+
+		Convey("should be stored as midnight UTC", func() {
+			raw, err := bson.Marshal(&datesDocument{Birthday: NewDate(1990, 5, 17)})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("birthday").Time().UTC(), ShouldEqual, time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC))
+
+			found := &datesDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Birthday, ShouldResemble, NewDate(1990, 5, 17))
+		})
+
+		Convey("should be null when zero", func() {
+			raw, err := bson.Marshal(&datesDocument{})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("birthday").Type, ShouldEqual, bson.TypeNull)
+		})
+
+		Convey("should be a date string in JSON", func() {
+			data, err := json.Marshal(NewDate(1990, 5, 17))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `"1990-05-17"`)
+
+			d := Date{}
+			So(json.Unmarshal([]byte(`"2000-01-02"`), &d), ShouldBeNil)
+			So(d, ShouldResemble, NewDate(2000, 1, 2))
+			So(json.Unmarshal([]byte(`"2000-13-02"`), &d), ShouldNotBeNil)
+		})
+
+		Convey("should build range filters", func() {
+			from, until := NewDate(2020, 1, 1), NewDate(2021, 1, 1)
+			So(DateRange("birthday", from, until), ShouldResemble, bson.M{"birthday": bson.M{"$gte": from, "$lt": until}})
+			So(DateRange("birthday", Date{}, Date{}), ShouldResemble, bson.M{})
+		})
+	})
+}
+
+func TestTimeOfDay(t *testing.T) {
+	Convey("TimeOfDay", t, func() {
+		Convey("should wrap around midnight", func() {
+			So(NewTimeOfDay(25, 0, 0).String(), ShouldEqual, "01:00:00")
+			So(NewTimeOfDay(23, 30, 0).Add(time.Hour).String(), ShouldEqual, "00:30:00")
+			So(NewTimeOfDay(0, 30, 0).Add(-time.Hour).String(), ShouldEqual, "23:30:00")
+		})
+
+		Convey("should parse times with and without seconds", func() {
+			parsed, err := ParseTimeOfDay("08:30")
+			So(err, ShouldBeNil)
+			So(parsed, ShouldResemble, NewTimeOfDay(8, 30, 0))
+			_, err = ParseTimeOfDay("25:00")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should be stored as seconds since midnight", func() {
+			raw, err := bson.Marshal(&datesDocument{Opens: NewTimeOfDay(8, 30, 0)})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("opens").Int32(), ShouldEqual, 8*3600+30*60)
+
+			found := &datesDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Opens, ShouldResemble, NewTimeOfDay(8, 30, 0))
+		})
+
+		Convey("should be a time string in JSON", func() {
+			data, err := json.Marshal(NewTimeOfDay(8, 30, 0))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `"08:30:00"`)
+		})
+
+		Convey("should be placed on a date", func() {
+			So(NewTimeOfDay(8, 30, 0).On(NewDate(2020, 5, 1), time.UTC), ShouldEqual, time.Date(2020, 5, 1, 8, 30, 0, 0, time.UTC))
+		})
+
+		Convey("should build range filters that wrap around midnight", func() {
+			from, until := NewTimeOfDay(22, 0, 0), NewTimeOfDay(6, 0, 0)
+			So(TimeOfDayRange("opens", until, from), ShouldResemble, bson.M{"opens": bson.M{"$gte": until, "$lt": from}})
+			So(TimeOfDayRange("opens", from, until), ShouldResemble, bson.M{"$or": bson.A{
+				bson.M{"opens": bson.M{"$gte": from}},
+				bson.M{"opens": bson.M{"$lt": until}},
+			}})
+		})
+	})
+}
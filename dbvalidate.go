@@ -0,0 +1,248 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Codes of the FieldErrors returned by database checks
+const (
+	VALIDATION_UNIQUE        = "unique"
+	VALIDATION_REF_NOT_FOUND = "ref_not_found"
+)
+
+// Documents can implement this hook to declare validations that need the database, like uniqueness. The checks
+// are only collected here. They run after Validate, batched into one $in query per collection and field, and
+// failures are added to the *ValidationError:
+//
+//	func (u *User) ValidateDB(c *bongo.Collection, checks *bongo.DBChecks) {
+//		checks.Unique("email", u.Email)
+//		checks.Exists("teamId", "teams", "_id", u.TeamID)
+//	}
+type DBValidateHook interface {
+	ValidateDB(*Collection, *DBChecks)
+}
+
+const (
+	dbCheckUnique = iota
+	dbCheckExists
+)
+
+// The database checks of a document, see DBValidateHook
+type DBChecks struct {
+	// The _id of the document, which unique checks don't count as a duplicate
+	id     primitive.ObjectID
+	checks []*dbCheck
+}
+
+type dbCheck struct {
+	kind int
+
+	// Where to look for the value
+	collection string
+	field      string
+
+	// The field of the validated document, for the error
+	name  string
+	value interface{}
+}
+
+func newDBChecks(id primitive.ObjectID) *DBChecks {
+	return &DBChecks{id: id}
+}
+
+// Checks that no other document of the collection has the value in field. Zero values aren't checked
+func (d *DBChecks) Unique(field string, value interface{}) {
+	if isZeroValue(value) {
+		return
+	}
+	d.checks = append(d.checks, &dbCheck{kind: dbCheckUnique, field: field, name: field, value: value})
+}
+
+// Checks that a document with the value in field (usually _id) exists in another collection of the same database.
+// name is the field of the validated document holding the reference. Zero values aren't checked
+func (d *DBChecks) Exists(name string, collection string, field string, value interface{}) {
+	if isZeroValue(value) {
+		return
+	}
+	d.checks = append(d.checks, &dbCheck{kind: dbCheckExists, collection: collection, field: field, name: name, value: value})
+}
+
+// Runs the checks with one query per kind of check, collection and field. Returns the failed checks as
+// FieldErrors, and an error if a query fails
+func (d *DBChecks) Run(c *Collection) ([]error, error) {
+	groups := make(map[string][]*dbCheck)
+	keys := make([]string, 0)
+	for _, check := range d.checks {
+		key := fmt.Sprint(check.kind, "\x00", check.collection, "\x00", check.field)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], check)
+	}
+	sort.Strings(keys)
+
+	errs := make([]error, 0)
+	for _, key := range keys {
+		group := groups[key]
+		found, err := d.lookup(c, group)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, check := range group {
+			switch {
+			case check.kind == dbCheckUnique && found[i]:
+				errs = append(errs, &FieldError{
+					Field:   check.name,
+					Code:    VALIDATION_UNIQUE,
+					Params:  map[string]interface{}{"value": check.value},
+					Message: fmt.Sprintf("%s %v is already taken", check.name, check.value),
+				})
+			case check.kind == dbCheckExists && !found[i]:
+				errs = append(errs, &FieldError{
+					Field:   check.name,
+					Code:    VALIDATION_REF_NOT_FOUND,
+					Params:  map[string]interface{}{"value": check.value, "collection": check.collection},
+					Message: fmt.Sprintf("%s %v does not exist in %s", check.name, check.value, check.collection),
+				})
+			}
+		}
+	}
+	return errs, nil
+}
+
+// Finds which values of a group of checks on the same collection and field are stored
+func (d *DBChecks) lookup(c *Collection, group []*dbCheck) ([]bool, error) {
+	first := group[0]
+	target := c
+	if first.kind == dbCheckExists {
+		if c.Connection == nil {
+			return nil, errors.New("database checks need a connection")
+		}
+		target = c.Connection.CollectionFromDatabase(first.collection, c.Database)
+	}
+
+	values := make(bson.A, len(group))
+	raws := make([]bson.RawValue, len(group))
+	for i, check := range group {
+		t, data, err := bson.MarshalValue(check.value)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = check.value
+		raws[i] = bson.RawValue{Type: t, Value: data}
+	}
+
+	filter := bson.M{first.field: bson.M{"$in": values}}
+	if first.kind == dbCheckUnique && !d.id.IsZero() {
+		filter["_id"] = bson.M{"$ne": d.id}
+	}
+	opts := options.Find().SetProjection(bson.M{first.field: 1})
+	path := strings.Split(first.field, ".")
+
+	stored := make([]bson.RawValue, 0)
+	err := target.runOperation("validate", func(ctx context.Context) error {
+		stored = stored[:0]
+		guarded, err := target.guardFilter(ctx, filter)
+		if err != nil {
+			return err
+		}
+		cursor, err := target.Collection().Find(ctx, guarded, opts)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			stored = append(stored, valuesAtPath(bson.RawValue{Type: bsontype.EmbeddedDocument, Value: cursor.Current}, path)...)
+		}
+		return cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]bool, len(group))
+	for i, raw := range raws {
+		for _, value := range stored {
+			if rawValuesEqual(raw, value) {
+				found[i] = true
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+// The values a query on a dotted path matches in a stored value. Like the server, the path goes through arrays:
+// "members.email" finds the email of every member, and "tags.0" the first tag. Arrays at the end of the path add
+// their elements
+func valuesAtPath(value bson.RawValue, path []string) []bson.RawValue {
+	if len(path) == 0 {
+		return rawValues(value)
+	}
+	switch value.Type {
+	case bsontype.EmbeddedDocument:
+		field, err := value.Document().LookupErr(path[0])
+		if err != nil {
+			return nil
+		}
+		return valuesAtPath(field, path[1:])
+	case bsontype.Array:
+		values := make([]bson.RawValue, 0)
+		if element, err := value.Array().LookupErr(path[0]); err == nil {
+			values = append(values, valuesAtPath(element, path[1:])...)
+		}
+		elements, _ := value.Array().Values()
+		for _, element := range elements {
+			if element.Type == bsontype.EmbeddedDocument {
+				values = append(values, valuesAtPath(element, path)...)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Compares stored values, treating numbers of different types as equal if their values are
+func rawValuesEqual(a bson.RawValue, b bson.RawValue) bool {
+	if a.Type == b.Type {
+		return bytes.Equal(a.Value, b.Value)
+	}
+	x, ok := rawNumber(a)
+	if !ok {
+		return false
+	}
+	y, ok := rawNumber(b)
+	return ok && x == y
+}
+
+func rawNumber(v bson.RawValue) (float64, bool) {
+	switch v.Type {
+	case bsontype.Int32:
+		return float64(v.Int32()), true
+	case bsontype.Int64:
+		return float64(v.Int64()), true
+	case bsontype.Double:
+		return v.Double(), true
+	}
+	return 0, false
+}
+
+func isZeroValue(value interface{}) bool {
+	return value == nil || reflect.ValueOf(value).IsZero()
+}
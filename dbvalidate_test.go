@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+type dbValidatedDocument struct {
+	DocumentBase `bson:",inline"`
+	Email        string             `bson:"email"`
+	Handle       string             `bson:"handle"`
+	TeamID       primitive.ObjectID `bson:"teamId,omitempty"`
+}
+
+func (d *dbValidatedDocument) ValidateDB(c *Collection, checks *DBChecks) {
+	checks.Unique("email", d.Email)
+	checks.Unique("handle", d.Handle)
+	checks.Exists("teamId", "teams", "_id", d.TeamID)
+}
+
+func TestDBChecks(t *testing.T) {
+	Convey("Database checks", t, func() {
+		Convey("should skip zero values", func() {
+			checks := newDBChecks(primitive.NilObjectID)
+			(&dbValidatedDocument{}).ValidateDB(&Collection{}, checks)
+			So(len(checks.checks), ShouldEqual, 0)
+
+			errs, err := checks.Run(&Collection{})
+			So(err, ShouldEqual, nil)
+			So(len(errs), ShouldEqual, 0)
+		})
+
+		Convey("should find the stored values of paths through arrays", func() {
+			raw, _ := bson.Marshal(bson.M{
+				"owner":   bson.M{"email": "a@example.com"},
+				"members": bson.A{bson.M{"email": "b@example.com"}, bson.M{"emails": bson.A{"c@example.com", "d@example.com"}}, "e"},
+				"tags":    bson.A{"x", "y"},
+			})
+			doc := bson.RawValue{Type: bsontype.EmbeddedDocument, Value: raw}
+			texts := func(values []bson.RawValue) []string {
+				found := make([]string, 0)
+				for _, value := range values {
+					found = append(found, value.StringValue())
+				}
+				return found
+			}
+			So(texts(valuesAtPath(doc, []string{"owner", "email"})), ShouldResemble, []string{"a@example.com"})
+			So(texts(valuesAtPath(doc, []string{"members", "email"})), ShouldResemble, []string{"b@example.com"})
+			So(texts(valuesAtPath(doc, []string{"members", "emails"})), ShouldResemble, []string{"c@example.com", "d@example.com"})
+			So(texts(valuesAtPath(doc, []string{"tags"})), ShouldResemble, []string{"x", "y"})
+			So(texts(valuesAtPath(doc, []string{"tags", "1"})), ShouldResemble, []string{"y"})
+			So(valuesAtPath(doc, []string{"owner", "name"}), ShouldBeEmpty)
+		})
+
+		Convey("should compare stored numbers regardless of their type", func() {
+			raw := func(v interface{}) bson.RawValue {
+				t, data, _ := bson.MarshalValue(v)
+				return bson.RawValue{Type: t, Value: data}
+			}
+			So(rawValuesEqual(raw(int32(3)), raw(int64(3))), ShouldBeTrue)
+			So(rawValuesEqual(raw(3.0), raw(int32(3))), ShouldBeTrue)
+			So(rawValuesEqual(raw(int32(3)), raw(int32(4))), ShouldBeFalse)
+			So(rawValuesEqual(raw("3"), raw(int32(3))), ShouldBeFalse)
+			So(rawValuesEqual(raw("a"), raw("a")), ShouldBeTrue)
+		})
+	})
+}
+
+func TestDBValidation(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Database validation", t, func() {
+		col := conn.Collection("db_validated")
+		team := &noHookDocument{Name: "team"}
+		So(conn.Collection("teams").Save(team), ShouldEqual, nil)
+
+		existing := &dbValidatedDocument{Email: "a@example.com", Handle: "a", TeamID: team.ID}
+		So(col.Save(existing), ShouldEqual, nil)
+
+		Convey("should not count the document itself as a duplicate", func() {
+			So(col.Save(existing), ShouldEqual, nil)
+		})
+
+		Convey("should report duplicates and missing references together", func() {
+			err := col.Save(&dbValidatedDocument{Email: "a@example.com", Handle: "b", TeamID: primitive.NewObjectID()})
+			So(err, ShouldHaveSameTypeAs, &ValidationError{})
+
+			errs := err.(*ValidationError).Errors
+			So(len(errs), ShouldEqual, 2)
+			codes := []string{errs[0].(*FieldError).Code, errs[1].(*FieldError).Code}
+			So(codes, ShouldContain, VALIDATION_UNIQUE)
+			So(codes, ShouldContain, VALIDATION_REF_NOT_FOUND)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// An exact decimal number, stored as BSON decimal128 instead of a lossy double. Arithmetic is exact, except for Div
+// and Round, which round half away from zero. Saving fails if a number has more than 34 significant digits. Doubles,
+// integers and strings that were stored before are read as well. In JSON, decimals are strings (e.g. "19.99"), so
+// clients don't parse them into floats
+type Decimal struct {
+	// The value is coefficient * 10^exponent
+	coefficient *big.Int
+	exponent    int
+}
+
+// coefficient * 10^exponent, e.g. NewDecimal(1999, -2) is 19.99
+func NewDecimal(coefficient int64, exponent int) Decimal {
+	return Decimal{coefficient: big.NewInt(coefficient), exponent: exponent}
+}
+
+// Parses a decimal like "19.99", "-0.5" or "1E+3"
+func ParseDecimal(s string) (Decimal, error) {
+	d, err := primitive.ParseDecimal128(strings.TrimSpace(s))
+	if err != nil {
+		return Decimal{}, err
+	}
+	return DecimalFromDecimal128(d)
+}
+
+func DecimalFromDecimal128(d primitive.Decimal128) (Decimal, error) {
+	coefficient, exponent, err := d.BigInt()
+	if err != nil {
+		return Decimal{}, err
+	}
+	return Decimal{coefficient: coefficient, exponent: exponent}, nil
+}
+
+func (d Decimal) Decimal128() (primitive.Decimal128, error) {
+	value, ok := primitive.ParseDecimal128FromBigInt(d.coef(), d.exponent)
+	if !ok {
+		return primitive.Decimal128{}, errors.New("decimal " + d.String() + " doesn't fit into a decimal128")
+	}
+	return value, nil
+}
+
+// The zero value has no coefficient
+func (d Decimal) coef() *big.Int {
+	if d.coefficient == nil {
+		return new(big.Int)
+	}
+	return d.coefficient
+}
+
+// The coefficients of two decimals scaled to the same exponent
+func alignDecimals(a Decimal, b Decimal) (*big.Int, *big.Int, int) {
+	exponent := a.exponent
+	if b.exponent < exponent {
+		exponent = b.exponent
+	}
+	return scaleCoefficient(a.coef(), a.exponent-exponent), scaleCoefficient(b.coef(), b.exponent-exponent), exponent
+}
+
+// coefficient * 10^shift, for shift >= 0
+func scaleCoefficient(coefficient *big.Int, shift int) *big.Int {
+	return new(big.Int).Mul(coefficient, pow10(shift))
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// n / d rounded half away from zero
+func divRound(n *big.Int, d *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(new(big.Int).Abs(d)) >= 0 {
+		q.Add(q, big.NewInt(int64(n.Sign()*d.Sign())))
+	}
+	return q
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	a, b, exponent := alignDecimals(d, o)
+	return Decimal{coefficient: a.Add(a, b), exponent: exponent}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	a, b, exponent := alignDecimals(d, o)
+	return Decimal{coefficient: a.Sub(a, b), exponent: exponent}
+}
+
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{coefficient: new(big.Int).Mul(d.coef(), o.coef()), exponent: d.exponent + o.exponent}
+}
+
+// Divides by o, rounded to the given number of decimal places
+func (d Decimal) Div(o Decimal, places int) (Decimal, error) {
+	if o.IsZero() {
+		return Decimal{}, errors.New("division of " + d.String() + " by zero")
+	}
+	// d / o = d.coef / o.coef * 10^(d.exponent - o.exponent), wanted with an exponent of -places
+	n, m := d.coef(), o.coef()
+	if shift := d.exponent - o.exponent + places; shift >= 0 {
+		n = scaleCoefficient(n, shift)
+	} else {
+		m = scaleCoefficient(m, -shift)
+	}
+	return Decimal{coefficient: divRound(n, m), exponent: -places}, nil
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{coefficient: new(big.Int).Neg(d.coef()), exponent: d.exponent}
+}
+
+// Rounds to the given number of decimal places
+func (d Decimal) Round(places int) Decimal {
+	if d.exponent >= -places {
+		return d
+	}
+	return Decimal{coefficient: divRound(d.coef(), pow10(-places-d.exponent)), exponent: -places}
+}
+
+// -1, 0 or 1 if d is less than, equal to or greater than o
+func (d Decimal) Cmp(o Decimal) int {
+	a, b, _ := alignDecimals(d, o)
+	return a.Cmp(b)
+}
+
+func (d Decimal) Sign() int {
+	return d.coef().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// The number of decimal places, e.g. 2 for 19.99
+func (d Decimal) Places() int {
+	if d.exponent >= 0 {
+		return 0
+	}
+	return -d.exponent
+}
+
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.coef()).String()
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+	if d.exponent >= 0 {
+		if d.IsZero() {
+			return "0"
+		}
+		return sign + digits + strings.Repeat("0", d.exponent)
+	}
+	places := -d.exponent
+	if len(digits) <= places {
+		digits = strings.Repeat("0", places-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+}
+
+// Checks the decimal for a Validate hook. Returns a *FieldError with the code "min_value" or "max_value" if it is
+// less than min or greater than max. Nil bounds aren't checked
+func (d Decimal) ValidateRange(field string, min *Decimal, max *Decimal) error {
+	if min != nil && d.Cmp(*min) < 0 {
+		return &FieldError{Field: field, Code: "min_value", Params: map[string]interface{}{"min": min.String()},
+			Message: field + " must be at least " + min.String()}
+	}
+	if max != nil && d.Cmp(*max) > 0 {
+		return &FieldError{Field: field, Code: "max_value", Params: map[string]interface{}{"max": max.String()},
+			Message: field + " must be at most " + max.String()}
+	}
+	return nil
+}
+
+// Checks for a Validate hook that the decimal has at most the given number of decimal places, e.g. 2 for cents.
+// Returns a *FieldError with the code "max_places"
+func (d Decimal) ValidatePlaces(field string, places int) error {
+	if d.Round(places).Cmp(d) == 0 {
+		return nil
+	}
+	return &FieldError{Field: field, Code: "max_places", Params: map[string]interface{}{"places": places},
+		Message: field + " must have at most " + strconv.Itoa(places) + " decimal places"}
+}
+
+// A filter for path between min (inclusive) and max (exclusive). Nil bounds are left out, so without any the filter
+// matches everything
+func DecimalRange(path string, min *Decimal, max *Decimal) bson.M {
+	if min == nil && max == nil {
+		return bson.M{}
+	}
+	condition := bson.M{}
+	if min != nil {
+		condition["$gte"] = *min
+	}
+	if max != nil {
+		condition["$lt"] = *max
+	}
+	return bson.M{path: condition}
+}
+
+func (d Decimal) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	value, err := d.Decimal128()
+	if err != nil {
+		return 0, nil, err
+	}
+	return bson.MarshalValue(value)
+}
+
+func (d *Decimal) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	var err error
+	switch t {
+	case bsontype.Decimal128:
+		*d, err = DecimalFromDecimal128(raw.Decimal128())
+	case bsontype.Double:
+		*d, err = ParseDecimal(strconv.FormatFloat(raw.Double(), 'f', -1, 64))
+	case bsontype.Int32:
+		*d = NewDecimal(int64(raw.Int32()), 0)
+	case bsontype.Int64:
+		*d = NewDecimal(raw.Int64(), 0)
+	case bsontype.String:
+		*d, err = ParseDecimal(raw.StringValue())
+	case bsontype.Null, bsontype.Undefined:
+		*d = Decimal{}
+	default:
+		err = errors.New("can't decode " + t.String() + " into a decimal")
+	}
+	return err
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Reads strings and numbers
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	var err error
+	switch v := value.(type) {
+	case string:
+		*d, err = ParseDecimal(v)
+	case json.Number:
+		*d, err = ParseDecimal(v.String())
+	case nil:
+		*d = Decimal{}
+	default:
+		err = errors.New("decimals must be strings or numbers in JSON")
+	}
+	return err
+}
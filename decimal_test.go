@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type decimalDocument struct {
+	DocumentBase `bson:",inline"`
+	Price        Decimal `bson:"price" json:"price"`
+}
+
+func mustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestDecimal(t *testing.T) {
+	Convey("Decimal", t, func() {
+		Convey("should parse and print decimals", func() {
+			So(mustParseDecimal("19.99").String(), ShouldEqual, "19.99")
+			So(mustParseDecimal("-0.05").String(), ShouldEqual, "-0.05")
+			So(mustParseDecimal("1E+3").String(), ShouldEqual, "1000")
+			So(NewDecimal(1999, -2).String(), ShouldEqual, "19.99")
+			So(Decimal{}.String(), ShouldEqual, "0")
+
+			_, err := ParseDecimal("abc")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should do exact arithmetic", func() {
+			So(mustParseDecimal("0.1").Add(mustParseDecimal("0.2")).String(), ShouldEqual, "0.3")
+			So(mustParseDecimal("10").Sub(mustParseDecimal("0.01")).String(), ShouldEqual, "9.99")
+			So(mustParseDecimal("19.99").Mul(NewDecimal(3, 0)).String(), ShouldEqual, "59.97")
+			So(mustParseDecimal("1.5").Neg().String(), ShouldEqual, "-1.5")
+			So(mustParseDecimal("0.30").Cmp(mustParseDecimal("0.3")), ShouldEqual, 0)
+			So(mustParseDecimal("-1").Cmp(Decimal{}), ShouldEqual, -1)
+		})
+
+		Convey("should round half away from zero", func() {
+			So(mustParseDecimal("2.345").Round(2).String(), ShouldEqual, "2.35")
+			So(mustParseDecimal("-2.345").Round(2).String(), ShouldEqual, "-2.35")
+			So(mustParseDecimal("2.344").Round(2).String(), ShouldEqual, "2.34")
+			So(mustParseDecimal("2.3").Round(2).String(), ShouldEqual, "2.3")
+
+			quotient, err := mustParseDecimal("10").Div(NewDecimal(3, 0), 2)
+			So(err, ShouldBeNil)
+			So(quotient.String(), ShouldEqual, "3.33")
+			quotient, err = mustParseDecimal("2").Div(mustParseDecimal("0.003"), 0)
+			So(err, ShouldBeNil)
+			So(quotient.String(), ShouldEqual, "667")
+
+			_, err = NewDecimal(1, 0).Div(Decimal{}, 2)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should be stored as decimal128", func() {
+			raw, err := bson.Marshal(&decimalDocument{Price: mustParseDecimal("19.99")})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("price").Type, ShouldEqual, bson.TypeDecimal128)
+
+			found := &decimalDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Price.String(), ShouldEqual, "19.99")
+		})
+
+		Convey("should read doubles, integers and strings", func() {
+			for value, expected := range map[interface{}]string{19.99: "19.99", int32(5): "5", int64(7): "7", "1.50": "1.50"} {
+				raw, err := bson.Marshal(bson.M{"price": value})
+				So(err, ShouldBeNil)
+				found := &decimalDocument{}
+				So(bson.Unmarshal(raw, found), ShouldBeNil)
+				So(found.Price.String(), ShouldEqual, expected)
+			}
+		})
+
+		Convey("should be a string in JSON", func() {
+			data, err := json.Marshal(mustParseDecimal("19.99"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `"19.99"`)
+
+			d := Decimal{}
+			So(json.Unmarshal([]byte(`"0.10"`), &d), ShouldBeNil)
+			So(d.String(), ShouldEqual, "0.10")
+			So(json.Unmarshal([]byte(`12.5`), &d), ShouldBeNil)
+			So(d.String(), ShouldEqual, "12.5")
+		})
+
+		Convey("should validate", func() {
+			min, max := NewDecimal(0, 0), NewDecimal(100, 0)
+			So(mustParseDecimal("5").ValidateRange("price", &min, &max), ShouldBeNil)
+			So(mustParseDecimal("-1").ValidateRange("price", &min, nil).(*FieldError).Code, ShouldEqual, "min_value")
+			So(mustParseDecimal("101").ValidateRange("price", nil, &max).(*FieldError).Code, ShouldEqual, "max_value")
+			So(mustParseDecimal("1.50").ValidatePlaces("price", 2), ShouldBeNil)
+			So(mustParseDecimal("1.505").ValidatePlaces("price", 2).(*FieldError).Code, ShouldEqual, "max_places")
+		})
+
+		Convey("should build range filters", func() {
+			min, max := NewDecimal(10, 0), NewDecimal(20, 0)
+			So(DecimalRange("price", &min, &max), ShouldResemble, bson.M{"price": bson.M{"$gte": min, "$lt": max}})
+			So(DecimalRange("price", nil, &max), ShouldResemble, bson.M{"price": bson.M{"$lt": max}})
+			So(DecimalRange("price", nil, nil), ShouldResemble, bson.M{})
+		})
+	})
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Returned when a stored document can't be decoded into the document type, e.g. because a field was stored as a
+// string but is declared as an int
+type DecodeError struct {
+	// The collection and _id of the document
+	Collection string
+	ID         interface{}
+
+	// The dotted path of the field that failed to decode, if known
+	Field string
+
+	// The stored type of the field, and the Go type it should have been decoded into (if known)
+	BSONType bsontype.Type
+	GoType   reflect.Type
+
+	// The driver's error
+	Err error
+
+	// The message of Err without the field path, which Field already has
+	message string
+}
+
+func (e *DecodeError) Error() string {
+	msg := "cannot decode document"
+	if len(e.Collection) > 0 {
+		msg += " of " + e.Collection
+	}
+	if e.ID != nil {
+		if oid, ok := e.ID.(primitive.ObjectID); ok {
+			msg += " with _id " + oid.Hex()
+		} else {
+			msg += " with _id " + fmt.Sprint(e.ID)
+		}
+	}
+	if len(e.Field) > 0 {
+		msg += ": field " + e.Field
+		if e.BSONType != 0 && e.GoType != nil {
+			msg += " is stored as " + e.BSONType.String() + " but declared as " + e.GoType.String()
+		}
+	}
+	if len(e.message) > 0 {
+		return msg + " (" + e.message + ")"
+	}
+	return msg + " (" + e.Err.Error() + ")"
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Wraps an error from decoding a raw document into doc with the document's _id and the failing field
+func newDecodeError(c *Collection, raw bson.Raw, doc interface{}, err error) error {
+	decodeErr := &DecodeError{Err: err}
+	if c != nil {
+		decodeErr.Collection = c.Name
+	}
+
+	if id, lookupErr := raw.LookupErr("_id"); lookupErr == nil {
+		id.Unmarshal(&decodeErr.ID)
+	}
+
+	var driverErr *bsoncodec.DecodeError
+	if errors.As(err, &driverErr) {
+		keys := driverErr.Keys()
+		decodeErr.Field = strings.Join(keys, ".")
+		if inner := driverErr.Unwrap(); inner != nil {
+			decodeErr.message = inner.Error()
+		}
+
+		if value, lookupErr := raw.LookupErr(keys...); lookupErr == nil {
+			decodeErr.BSONType = value.Type
+		}
+		decodeErr.GoType = bsonFieldType(reflect.TypeOf(doc), keys)
+	}
+
+	return decodeErr
+}
+
+// Resolves the Go type of the field at a bson key path. Numeric keys index into slices and arrays
+func bsonFieldType(t reflect.Type, keys []string) reflect.Type {
+	for _, key := range keys {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil {
+			return nil
+		}
+
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array:
+			if _, err := strconv.Atoi(key); err != nil {
+				return nil
+			}
+			t = t.Elem()
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			var found reflect.Type
+			walkBsonFields(t, "", func(field reflect.StructField, path string) bool {
+				if path == key {
+					found = field.Type
+				}
+				return false
+			})
+			t = found
+		default:
+			return nil
+		}
+	}
+	return t
+}
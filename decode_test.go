@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+)
+
+type decodeItem struct {
+	Quantity int `bson:"quantity"`
+}
+
+type decodeDocument struct {
+	DocumentBase `bson:",inline"`
+	Count        int           `bson:"count"`
+	Items        []*decodeItem `bson:"items"`
+}
+
+func TestDecodeError(t *testing.T) {
+	Convey("Decode errors", t, func() {
+		id := primitive.NewObjectID()
+
+		Convey("should carry the document id and field of type mismatches", func() {
+			results := resultSetFromDocuments(bson.D{{Key: "_id", Value: id}, {Key: "count", Value: "three"}})
+			results.Collection = &Collection{Name: "orders"}
+
+			So(results.Next(&decodeDocument{}), ShouldBeFalse)
+			err, ok := results.Error.(*DecodeError)
+			So(ok, ShouldBeTrue)
+			So(err.Collection, ShouldEqual, "orders")
+			So(err.ID, ShouldEqual, id)
+			So(err.Field, ShouldEqual, "count")
+			So(err.BSONType, ShouldEqual, bsontype.String)
+			So(err.GoType, ShouldEqual, reflect.TypeOf(0))
+			So(err.Error(), ShouldContainSubstring, "orders with _id "+id.Hex()+": field count is stored as string but declared as int")
+			So(err.Error(), ShouldNotContainSubstring, "error decoding key")
+
+			var driverErr *bsoncodec.DecodeError
+			So(errors.As(err, &driverErr), ShouldBeTrue)
+			So(driverErr.Keys(), ShouldResemble, []string{"count"})
+		})
+
+		Convey("should resolve nested paths through arrays", func() {
+			results := resultSetFromDocuments(bson.D{
+				{Key: "_id", Value: id},
+				{Key: "items", Value: bson.A{bson.D{{Key: "quantity", Value: 1}}, bson.D{{Key: "quantity", Value: "lots"}}}},
+			})
+
+			So(results.Next(&decodeDocument{}), ShouldBeFalse)
+			err := results.Error.(*DecodeError)
+			So(err.Field, ShouldEqual, "items.1.quantity")
+			So(err.BSONType, ShouldEqual, bsontype.String)
+			So(err.GoType, ShouldEqual, reflect.TypeOf(0))
+		})
+	})
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"time"
+)
+
+// Defaults for the operations on a collection, set per collection name in Config.CollectionDefaults or on a copy of
+// a collection with WithDefaults. Zero fields keep the defaults of the client
+type CollectionDefaults struct {
+	// Used by every operation on the collection
+	ReadPreference *readpref.ReadPref
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+
+	// Server-side time limit for finds. A MaxTime find option or ResultSet.Query.SetMaxTime overrides it
+	MaxTime time.Duration
+
+	// Sort of finds without a SortBy option, e.g. bson.D{{"created_at", -1}}
+	Sort interface{}
+
+	// Page size of Paginate calls with a perPage below 1, and of repositories and REST handlers
+	PerPage int
+}
+
+// Get a copy of the collection with some defaults changed, e.g. for one expensive report:
+//
+//	reports := conn.Collection("orders").WithDefaults(&bongo.CollectionDefaults{ReadPreference: readpref.Secondary()})
+func (c *Collection) WithDefaults(defaults *CollectionDefaults) *Collection {
+	merged := *c.defaults()
+	if defaults.ReadPreference != nil {
+		merged.ReadPreference = defaults.ReadPreference
+	}
+	if defaults.ReadConcern != nil {
+		merged.ReadConcern = defaults.ReadConcern
+	}
+	if defaults.WriteConcern != nil {
+		merged.WriteConcern = defaults.WriteConcern
+	}
+	if defaults.MaxTime > 0 {
+		merged.MaxTime = defaults.MaxTime
+	}
+	if defaults.Sort != nil {
+		merged.Sort = defaults.Sort
+	}
+	if defaults.PerPage > 0 {
+		merged.PerPage = defaults.PerPage
+	}
+
+	clone := *c
+	clone.Defaults = &merged
+	return &clone
+}
+
+// The defaults of the collection, never nil
+func (c *Collection) defaults() *CollectionDefaults {
+	if c == nil {
+		return &CollectionDefaults{}
+	}
+	if c.Defaults != nil {
+		return c.Defaults
+	}
+	if c.Connection != nil && c.Connection.config() != nil && c.Connection.config().CollectionDefaults[c.Name] != nil {
+		return c.Connection.config().CollectionDefaults[c.Name]
+	}
+	return &CollectionDefaults{}
+}
+
+// The driver options for the read preference and concerns of the collection, or nil if there are none
+func (c *Collection) driverOptions() *options.CollectionOptions {
+	defaults := c.defaults()
+	if defaults.ReadPreference == nil && defaults.ReadConcern == nil && defaults.WriteConcern == nil {
+		return nil
+	}
+
+	opts := options.Collection()
+	if defaults.ReadPreference != nil {
+		opts.SetReadPreference(defaults.ReadPreference)
+	}
+	if defaults.ReadConcern != nil {
+		opts.SetReadConcern(defaults.ReadConcern)
+	}
+	if defaults.WriteConcern != nil {
+		opts.SetWriteConcern(defaults.WriteConcern)
+	}
+	return opts
+}
+
+// The page size for a requested one, falling back to the collection's default
+func (c *Collection) perPage(perPage int) int {
+	if perPage > 0 {
+		return perPage
+	}
+	return c.defaults().PerPage
+}
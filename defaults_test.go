@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"testing"
+	"time"
+)
+
+func TestCollectionDefaults(t *testing.T) {
+	Convey("Collection defaults", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest", CollectionDefaults: map[string]*CollectionDefaults{
+			"orders": {MaxTime: time.Second, Sort: bson.D{{"created_at", -1}}, PerPage: 50, WriteConcern: writeconcern.Majority()},
+		}}}
+		orders := conn.Collection("orders")
+
+		Convey("should be looked up by collection name", func() {
+			So(orders.defaults().PerPage, ShouldEqual, 50)
+			So(conn.Collection("tests").defaults(), ShouldResemble, &CollectionDefaults{})
+			So(conn.Collection("tests").driverOptions(), ShouldBeNil)
+			So(orders.driverOptions().WriteConcern, ShouldEqual, writeconcern.Majority())
+		})
+
+		Convey("should apply to finds unless overridden", func() {
+			results, err := orders.Find(nil)
+			So(err, ShouldEqual, nil)
+			So(results.Query.Sort, ShouldResemble, bson.D{{"created_at", -1}})
+			So(*results.Query.MaxTime, ShouldEqual, time.Second)
+
+			results, err = orders.Find(nil, SortBy("name"), MaxTime(time.Minute))
+			So(err, ShouldEqual, nil)
+			So(results.Query.Sort, ShouldResemble, bson.D{{"name", 1}})
+			So(*results.Query.MaxTime, ShouldEqual, time.Minute)
+
+			results, _ = conn.Collection("tests").Find(nil)
+			So(results.Query.Sort, ShouldBeNil)
+			So(results.Query.MaxTime, ShouldBeNil)
+		})
+
+		Convey("should provide page sizes", func() {
+			So(orders.perPage(0), ShouldEqual, 50)
+			So(orders.perPage(10), ShouldEqual, 10)
+			So(NewRepository[*noHookDocument](conn, "orders").PerPage, ShouldEqual, 50)
+			So(NewRepository[*noHookDocument](conn, "tests").PerPage, ShouldEqual, 20)
+		})
+
+		Convey("should be changed on copies", func() {
+			secondary := orders.WithDefaults(&CollectionDefaults{ReadPreference: readpref.Secondary(), PerPage: 10})
+			So(secondary.defaults().PerPage, ShouldEqual, 10)
+			So(secondary.defaults().MaxTime, ShouldEqual, time.Second)
+			So(secondary.driverOptions().ReadPreference, ShouldEqual, readpref.Secondary())
+			So(secondary.driverOptions().WriteConcern, ShouldEqual, writeconcern.Majority())
+			So(orders.defaults().PerPage, ShouldEqual, 50)
+		})
+	})
+}
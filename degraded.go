@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sync"
+	"time"
+)
+
+// The number of documents DegradedReadConfig keeps by default
+const DEGRADED_MAX_ENTRIES = 10000
+
+// Keeps the last copy of the documents found with FindByID and FindOne, and serves it when the database is
+// unavailable (the circuit breaker is open or the health monitor finds the connection unhealthy) instead of
+// failing, e.g. for read-mostly endpoints that must stay up during short outages. Copies served this way are marked
+// stale, see StaleTracker
+type DegradedReadConfig struct {
+	// The collections whose reads are kept. Empty means all
+	Collections []string
+
+	// Copies older than this aren't served. Zero means they don't expire
+	MaxAge time.Duration
+
+	// The number of copies to keep, dropping the least recently found first. Defaults to DEGRADED_MAX_ENTRIES
+	MaxEntries int
+}
+
+// Implemented by documents (e.g. through DocumentBase) that can tell whether they were served from the degraded read
+// cache instead of the database
+type StaleTracker interface {
+	SetStale(bool)
+	IsStale() bool
+}
+
+type degradedEntry struct {
+	key    string
+	id     string
+	raw    bson.Raw
+	stored time.Time
+}
+
+// The documents kept for degraded reads, least recently found last
+type degradedCache struct {
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+
+	// The keys of the entries of each document, so saves and deletes can drop them
+	byID map[string]map[string]bool
+}
+
+// The degraded read config of the collection, or nil if its reads aren't kept. Collections with a query guard don't
+// keep reads, since the copies are shared by everyone the guard tells apart (e.g. all tenants)
+func (c *Collection) degradedConfig() *DegradedReadConfig {
+	if c.Connection == nil || c.Connection.config() == nil || c.queryGuard() != nil {
+		return nil
+	}
+	config := c.Connection.config().DegradedReads
+	if config == nil || (len(config.Collections) > 0 && !stringInSlice(c.Name, config.Collections)) {
+		return nil
+	}
+	return config
+}
+
+// The cache key of a read: the collection, the _id or query and the find options that shape the result
+func (c *Collection) degradedKey(query interface{}, opts []FindOption) string {
+	o := newFindOptions(opts)
+	return fmt.Sprint(c.Database, ".", c.Name, " ", query, " ", o.projection, " ", o.sort, " ", o.view)
+}
+
+// Keeps a copy of a document found with a query (or _id) and find options
+func (c *Collection) keepDegraded(query interface{}, opts []FindOption, raw bson.Raw) {
+	config := c.degradedConfig()
+	if config == nil || raw == nil {
+		return
+	}
+	key := c.degradedKey(query, opts)
+	entry := &degradedEntry{key: key, raw: append(bson.Raw{}, raw...), stored: time.Now()}
+	if id, ok := raw.Lookup("_id").ObjectIDOK(); ok {
+		entry.id = c.Database + "." + c.Name + " " + id.Hex()
+	}
+
+	maxEntries := degradedMaxEntries(config)
+
+	cache := &c.Connection.degraded
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.entries == nil {
+		cache.entries = make(map[string]*list.Element)
+		cache.order = list.New()
+		cache.byID = make(map[string]map[string]bool)
+	}
+	if element, ok := cache.entries[key]; ok {
+		cache.remove(element)
+	}
+	cache.entries[key] = cache.order.PushFront(entry)
+	if len(entry.id) > 0 {
+		if cache.byID[entry.id] == nil {
+			cache.byID[entry.id] = make(map[string]bool)
+		}
+		cache.byID[entry.id][key] = true
+	}
+	for cache.order.Len() > maxEntries {
+		cache.remove(cache.order.Back())
+	}
+}
+
+func degradedMaxEntries(config *DegradedReadConfig) int {
+	if config == nil || config.MaxEntries <= 0 {
+		return DEGRADED_MAX_ENTRIES
+	}
+	return config.MaxEntries
+}
+
+// Drops the least recently kept entries over maxEntries
+func (cache *degradedCache) resize(maxEntries int) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.order == nil {
+		return
+	}
+	for cache.order.Len() > maxEntries {
+		cache.remove(cache.order.Back())
+	}
+}
+
+func (cache *degradedCache) remove(element *list.Element) {
+	entry := cache.order.Remove(element).(*degradedEntry)
+	delete(cache.entries, entry.key)
+	if keys := cache.byID[entry.id]; keys != nil {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(cache.byID, entry.id)
+		}
+	}
+}
+
+// Drops the copies of a document that was saved or deleted, so they aren't served instead of its current version
+func (c *Collection) forgetDegraded(id primitive.ObjectID) {
+	if c.Connection == nil {
+		return
+	}
+	cache := &c.Connection.degraded
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for key := range cache.byID[c.Database+"."+c.Name+" "+id.Hex()] {
+		cache.remove(cache.entries[key])
+	}
+}
+
+// Whether a read failed because the database is unavailable
+func (c *Collection) degradedError(err error) bool {
+	if _, notFound := err.(*DocumentNotFoundError); err == nil || notFound || err == mongo.ErrNoDocuments {
+		return false
+	}
+	var open *CircuitOpenError
+	return errors.As(err, &open) || !c.Connection.Healthy()
+}
+
+// Decodes the kept copy of a failed read into doc and marks it stale. Returns false if degraded reads are off for
+// the collection, the read didn't fail because the database is unavailable, or there is no fresh enough copy.
+// Overflow and stream fields aren't loaded and the after find hook doesn't run, but fields the principal may not
+// read are cleared
+func (c *Collection) serveDegraded(query interface{}, opts []FindOption, doc interface{}, err error) bool {
+	config := c.degradedConfig()
+	if config == nil || !c.degradedError(err) {
+		return false
+	}
+
+	cache := &c.Connection.degraded
+	cache.mutex.Lock()
+	element, ok := cache.entries[c.degradedKey(query, opts)]
+	var entry *degradedEntry
+	if ok {
+		entry = element.Value.(*degradedEntry)
+	}
+	cache.mutex.Unlock()
+
+	if entry == nil || (config.MaxAge > 0 && time.Since(entry.stored) > config.MaxAge) {
+		return false
+	}
+	if bson.UnmarshalWithRegistry(Registry, entry.raw, doc) != nil {
+		return false
+	}
+	applyZones(doc)
+	c.stripUnreadable(doc)
+
+	if stale, ok := doc.(StaleTracker); ok {
+		stale.SetStale(true)
+	}
+	if newt, ok := doc.(NewTracker); ok {
+		newt.SetIsNew(false)
+	}
+	return true
+}
+
+// Marks a document as found in the database
+func markFresh(doc interface{}) {
+	if stale, ok := doc.(StaleTracker); ok {
+		stale.SetStale(false)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+type degradedDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string `bson:"name"`
+}
+
+func TestDegradedReads(t *testing.T) {
+	Convey("Degraded reads", t, func() {
+		config := &Config{
+			Database:       "bongotest",
+			DegradedReads:  &DegradedReadConfig{MaxEntries: 2},
+			CircuitBreaker: &CircuitBreakerConfig{OpenTimeout: time.Hour},
+		}
+		conn := &Connection{Config: config, Context: &Context{}}
+		col := conn.Collection("degraded")
+		id := primitive.NewObjectID()
+		raw, err := bson.Marshal(bson.M{"_id": id, "name": "Foo"})
+		So(err, ShouldBeNil)
+		col.keepDegraded(id, nil, raw)
+
+		Convey("should serve the kept copy while the circuit is open", func() {
+			conn.CircuitBreaker(col).open(time.Now())
+
+			doc := &degradedDocument{}
+			So(col.FindByID(id, doc), ShouldBeNil)
+			So(doc.Name, ShouldEqual, "Foo")
+			So(doc.IsStale(), ShouldBeTrue)
+			So(doc.IsNew(), ShouldBeFalse)
+
+			So(col.FindByID(primitive.NewObjectID(), &degradedDocument{}), ShouldHaveSameTypeAs, &CircuitOpenError{})
+			So(col.FindByID(id, &degradedDocument{}, Select("name")), ShouldHaveSameTypeAs, &CircuitOpenError{})
+		})
+
+		Convey("should serve the kept copy while the connection is unhealthy", func() {
+			conn.health.unhealthy.Store(true)
+			doc := &degradedDocument{}
+			So(col.serveDegraded(id, nil, doc, errors.New("server selection error")), ShouldBeTrue)
+			So(col.serveDegraded(id, nil, doc, &DocumentNotFoundError{}), ShouldBeFalse)
+
+			conn.health.unhealthy.Store(false)
+			So(col.serveDegraded(id, nil, doc, errors.New("server selection error")), ShouldBeFalse)
+		})
+
+		Convey("should not serve expired copies", func() {
+			config.DegradedReads.MaxAge = time.Nanosecond
+			time.Sleep(time.Millisecond)
+			So(col.serveDegraded(id, nil, &degradedDocument{}, &CircuitOpenError{}), ShouldBeFalse)
+		})
+
+		Convey("should forget saved and deleted documents", func() {
+			query := bson.M{"name": "Foo"}
+			col.keepDegraded(query, nil, raw)
+			col.forgetDegraded(id)
+			So(col.serveDegraded(id, nil, &degradedDocument{}, &CircuitOpenError{}), ShouldBeFalse)
+			So(col.serveDegraded(query, nil, &degradedDocument{}, &CircuitOpenError{}), ShouldBeFalse)
+		})
+
+		Convey("should keep the most recently found copies", func() {
+			for i := 0; i < 2; i++ {
+				other, _ := bson.Marshal(bson.M{"_id": primitive.NewObjectID()})
+				col.keepDegraded(i, nil, other)
+			}
+			So(conn.degraded.order.Len(), ShouldEqual, 2)
+			So(col.serveDegraded(id, nil, &degradedDocument{}, &CircuitOpenError{}), ShouldBeFalse)
+			So(col.serveDegraded(1, nil, &degradedDocument{}, &CircuitOpenError{}), ShouldBeTrue)
+		})
+
+		Convey("should not keep reads of guarded collections", func() {
+			conn.Config.QueryGuards = map[string]QueryGuard{"degraded": func(ctx context.Context, filter interface{}) (interface{}, error) {
+				return filter, nil
+			}}
+			So(col.serveDegraded(id, nil, &degradedDocument{}, &CircuitOpenError{}), ShouldBeFalse)
+
+			col.keepDegraded(primitive.NewObjectID(), nil, raw)
+			So(conn.degraded.order.Len(), ShouldEqual, 1)
+		})
+
+		Convey("should only keep the configured collections", func() {
+			config.DegradedReads.Collections = []string{"others"}
+			So(col.serveDegraded(id, nil, &degradedDocument{}, &CircuitOpenError{}), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"strings"
+)
+
+// The largest document MongoDB stores, in bytes
+const MAX_DOCUMENT_SIZE = 16 * 1024 * 1024
+
+type DocumentSizeConfig struct {
+	// Log a warning when a saved document is larger than this many bytes. Zero disables the warning
+	WarnSize int
+
+	// Fail saves of documents larger than this many bytes with a *DocumentTooLargeError. Defaults to
+	// MAX_DOCUMENT_SIZE
+	MaxSize int
+
+	// How many of the largest fields warnings and errors name. Defaults to 3
+	Fields int
+}
+
+// The marshaled size of a top level field of a document, in bytes
+type FieldSize struct {
+	Field string
+	Size  int
+}
+
+// Returned by saves of documents that are larger than DocumentSizeConfig.MaxSize, before they are sent to the server
+type DocumentTooLargeError struct {
+	Collection string
+	ID         primitive.ObjectID
+	Size       int
+	MaxSize    int
+
+	// The largest top level fields, largest first
+	LargestFields []FieldSize
+}
+
+func (e *DocumentTooLargeError) Error() string {
+	return fmt.Sprintf("document %s of %s is %d bytes, more than the maximum of %d (largest fields: %s)",
+		e.ID.Hex(), e.Collection, e.Size, e.MaxSize, formatFieldSizes(e.LargestFields))
+}
+
+func formatFieldSizes(fields []FieldSize) string {
+	formatted := make([]string, len(fields))
+	for i, field := range fields {
+		formatted[i] = fmt.Sprintf("%s %d bytes", field.Field, field.Size)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// Marshals the document like the client would and checks its size against Config.DocumentSize
+func (c *Collection) checkDocumentSize(doc Document) error {
+	if c.Connection == nil || c.Connection.config() == nil {
+		return nil
+	}
+	config := c.Connection.config().DocumentSize
+	if config == nil {
+		return nil
+	}
+	maxSize := config.MaxSize
+	if maxSize <= 0 {
+		maxSize = MAX_DOCUMENT_SIZE
+	}
+
+	raw, err := bson.MarshalWithRegistry(Registry, doc)
+	if err != nil {
+		return err
+	}
+	size := len(raw)
+	warn := config.WarnSize > 0 && size > config.WarnSize
+	if size <= maxSize && !warn {
+		return nil
+	}
+
+	fields := config.Fields
+	if fields <= 0 {
+		fields = 3
+	}
+	largest := largestFields(raw, fields)
+	if size > maxSize {
+		return &DocumentTooLargeError{
+			Collection:    c.Name,
+			ID:            doc.GetID(),
+			Size:          size,
+			MaxSize:       maxSize,
+			LargestFields: largest,
+		}
+	}
+	c.Connection.logf(LOG_WARN, "document %s of %s is %d bytes, more than %d (largest fields: %s)",
+		doc.GetID().Hex(), c.Name, size, config.WarnSize, formatFieldSizes(largest))
+	return nil
+}
+
+// The n largest top level fields of a document, largest first
+func largestFields(raw bson.Raw, n int) []FieldSize {
+	elements, _ := raw.Elements()
+	fields := make([]FieldSize, len(elements))
+	for i, element := range elements {
+		fields[i] = FieldSize{Field: element.Key(), Size: len(element)}
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Size > fields[j].Size
+	})
+	if len(fields) > n {
+		fields = fields[:n]
+	}
+	return fields
+}
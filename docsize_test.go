@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+type sizedDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string `bson:"name"`
+	Body         string `bson:"body"`
+}
+
+func TestDocumentSize(t *testing.T) {
+	Convey("Document size", t, func() {
+		logger := &testLogger{}
+		conn := &Connection{Config: &Config{Database: "bongotest", Logger: logger}, Context: &Context{}}
+		col := conn.Collection("tests")
+
+		Convey("should not be checked without a config", func() {
+			_, err := col.prepareSave(&sizedDocument{Body: strings.Repeat("x", 2000)}, newWriteOptions(nil))
+			So(err, ShouldBeNil)
+		})
+
+		Convey("should fail saves of documents over the maximum, naming the largest fields", func() {
+			conn.Config.DocumentSize = &DocumentSizeConfig{MaxSize: 1000, Fields: 2}
+			_, err := col.prepareSave(&sizedDocument{Name: "foo", Body: strings.Repeat("x", 2000)}, newWriteOptions(nil))
+			So(err, ShouldHaveSameTypeAs, &DocumentTooLargeError{})
+
+			tooLarge := err.(*DocumentTooLargeError)
+			So(tooLarge.Size, ShouldBeGreaterThan, 2000)
+			So(tooLarge.MaxSize, ShouldEqual, 1000)
+			So(len(tooLarge.LargestFields), ShouldEqual, 2)
+			So(tooLarge.LargestFields[0].Field, ShouldEqual, "body")
+			So(err.Error(), ShouldContainSubstring, "body 2011 bytes")
+		})
+
+		Convey("should warn about documents over the warning size", func() {
+			conn.Config.DocumentSize = &DocumentSizeConfig{WarnSize: 1000}
+			_, err := col.prepareSave(&sizedDocument{Body: strings.Repeat("x", 2000)}, newWriteOptions(nil))
+			So(err, ShouldBeNil)
+			So(len(logger.lines), ShouldEqual, 1)
+			So(logger.lines[0], ShouldContainSubstring, "largest fields: body")
+
+			_, err = col.prepareSave(&sizedDocument{Body: "small"}, newWriteOptions(nil))
+			So(err, ShouldBeNil)
+			So(len(logger.lines), ShouldEqual, 1)
+		})
+	})
+}
@@ -16,6 +16,10 @@ type DocumentBase struct {
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
 	// We want this to default to false without any work. So this will be the opposite of isNew. We want it to be new unless set to existing
 	exists bool
+	// Served from the degraded read cache instead of the database
+	stale bool
+	// Found with only some of its fields, so saving it would delete the others
+	projected bool
 }
 
 // Satisfy the new tracker interface
@@ -67,3 +71,22 @@ func (d *DocumentBase) SetDeletedAt(t time.Time) {
 func (d *DocumentBase) GetDeletedAt() time.Time {
 	return d.DeletedAt
 }
+
+// Satisfy the stale tracker interface
+func (d *DocumentBase) SetStale(stale bool) {
+	d.stale = stale
+}
+
+// Was the document served from the degraded read cache, see DegradedReadConfig
+func (d *DocumentBase) IsStale() bool {
+	return d.stale
+}
+
+// Satisfy the projection tracker interface
+func (d *DocumentBase) setProjected(projected bool) {
+	d.projected = projected
+}
+
+func (d *DocumentBase) isProjected() bool {
+	return d.projected
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"strconv"
+)
+
+// Dump formats
+const (
+	// One canonical extended JSON document per line, so every BSON type survives the round trip
+	DUMP_EXTJSON = iota
+
+	// Concatenated BSON documents, like the .bson files written by mongodump
+	DUMP_BSON
+)
+
+type DumpOptions struct {
+	// Anonymizes every document before it is written
+	Anonymizer *Anonymizer
+}
+
+// Documents are restored in bulk writes of this size
+const restoreBatchSize = 1000
+
+// Writes every document of the collection to w. Hooks are not run. Returns the number of documents written
+func (c *Collection) Dump(w io.Writer, format int, opts *DumpOptions) (int64, error) {
+	if opts == nil {
+		opts = &DumpOptions{}
+	}
+	if format != DUMP_EXTJSON && format != DUMP_BSON {
+		return 0, errors.New("unknown dump format " + strconv.Itoa(format))
+	}
+
+	var cursor *mongo.Cursor
+	err := c.runOperation("dump", func(ctx context.Context) error {
+		filter, err := c.guardFilter(ctx, bson.M{})
+		if err != nil {
+			return err
+		}
+		cursor, err = c.Collection().Find(ctx, filter)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer c.closeCursor(cursor)
+
+	buffered := bufio.NewWriter(w)
+
+	var count int64
+	for c.nextDocument(cursor) {
+		doc := cursor.Current
+		if opts.Anonymizer != nil {
+			if doc, err = opts.Anonymizer.Anonymize(doc); err != nil {
+				return count, err
+			}
+		}
+
+		data := []byte(doc)
+		if format == DUMP_EXTJSON {
+			data, err = bson.MarshalExtJSON(doc, true, false)
+			if err != nil {
+				return count, err
+			}
+			data = append(data, '\n')
+		}
+
+		if _, err := buffered.Write(data); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+
+	return count, buffered.Flush()
+}
+
+// Reads documents written by Dump and writes them to the collection, replacing documents with the same _id.
+// Hooks are not run. Returns the number of documents restored
+func (c *Collection) Restore(r io.Reader, format int) (int64, error) {
+	var next func() (bson.Raw, error)
+
+	switch format {
+	case DUMP_EXTJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 17*1024*1024)
+		next = func() (bson.Raw, error) {
+			for scanner.Scan() {
+				line := bytes.TrimSpace(scanner.Bytes())
+				if len(line) == 0 {
+					continue
+				}
+				var doc bson.Raw
+				err := bson.UnmarshalExtJSON(line, true, &doc)
+				return doc, err
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+	case DUMP_BSON:
+		buffered := bufio.NewReader(r)
+		next = func() (bson.Raw, error) {
+			return readBSONDocument(buffered)
+		}
+	default:
+		return 0, errors.New("unknown dump format " + strconv.Itoa(format))
+	}
+
+	var count int64
+	models := make([]mongo.WriteModel, 0, restoreBatchSize)
+
+	flush := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		err := c.runOperation("restore", func(ctx context.Context) error {
+			guarded, err := c.guardModels(ctx, models)
+			if err != nil {
+				return err
+			}
+			_, err = c.Collection().BulkWrite(ctx, guarded, options.BulkWrite().SetOrdered(false))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		count += int64(len(models))
+		models = models[:0]
+		return nil
+	}
+
+	for {
+		doc, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+
+		if id, err := doc.LookupErr("_id"); err == nil {
+			models = append(models, mongo.NewReplaceOneModel().SetFilter(bson.D{{Key: "_id", Value: id}}).SetReplacement(doc).SetUpsert(true))
+		} else {
+			models = append(models, mongo.NewInsertOneModel().SetDocument(doc))
+		}
+
+		if len(models) >= restoreBatchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+
+	return count, flush()
+}
+
+// Reads one length-prefixed BSON document. Returns io.EOF at the end of the input
+func readBSONDocument(r io.Reader) (bson.Raw, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated BSON document")
+		}
+		return nil, err
+	}
+
+	length := int32(binary.LittleEndian.Uint32(size[:]))
+	if length < 5 {
+		return nil, errors.New("invalid BSON document length " + strconv.Itoa(int(length)))
+	}
+
+	doc := make([]byte, length)
+	copy(doc, size[:])
+	if _, err := io.ReadFull(r, doc[4:]); err != nil {
+		return nil, errors.New("truncated BSON document")
+	}
+
+	return doc, bson.Raw(doc).Validate()
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReadBSONDocument(t *testing.T) {
+	Convey("Reading BSON documents", t, func() {
+		first, _ := bson.Marshal(bson.M{"name": "foo"})
+		second, _ := bson.Marshal(bson.M{"name": "bar"})
+
+		Convey("should read concatenated documents", func() {
+			r := bytes.NewReader(append(append([]byte{}, first...), second...))
+
+			doc, err := readBSONDocument(r)
+			So(err, ShouldEqual, nil)
+			So(doc.Lookup("name").StringValue(), ShouldEqual, "foo")
+
+			doc, err = readBSONDocument(r)
+			So(err, ShouldEqual, nil)
+			So(doc.Lookup("name").StringValue(), ShouldEqual, "bar")
+
+			_, err = readBSONDocument(r)
+			So(err, ShouldEqual, io.EOF)
+		})
+
+		Convey("should fail on truncated documents", func() {
+			_, err := readBSONDocument(bytes.NewReader(first[:len(first)-2]))
+			So(err, ShouldNotBeNil)
+			So(err, ShouldNotEqual, io.EOF)
+
+			_, err = readBSONDocument(bytes.NewReader(first[:2]))
+			So(err, ShouldNotBeNil)
+			So(err, ShouldNotEqual, io.EOF)
+		})
+	})
+}
+
+func TestDumpRestore(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Dump and restore", t, func() {
+		col := conn.Collection("tests")
+		born := time.Date(2019, 6, 23, 12, 0, 0, 0, time.UTC)
+		doc := &importDocument{Name: "foo", Count: 3, Born: born}
+		So(col.Save(doc), ShouldEqual, nil)
+		col.Save(&importDocument{Name: "bar"})
+
+		for _, format := range []int{DUMP_EXTJSON, DUMP_BSON} {
+			out := &bytes.Buffer{}
+			count, err := col.Dump(out, format, nil)
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 2)
+
+			target := conn.Collection("restored")
+			count, err = target.Restore(bytes.NewReader(out.Bytes()), format)
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 2)
+
+			// Restoring again replaces the documents
+			_, err = target.Restore(bytes.NewReader(out.Bytes()), format)
+			So(err, ShouldEqual, nil)
+			total, _ := target.Collection().CountDocuments(context.Background(), bson.M{})
+			So(total, ShouldEqual, 2)
+
+			restored := &importDocument{}
+			So(target.FindByID(doc.GetID(), restored), ShouldEqual, nil)
+			So(restored.Born.Equal(born), ShouldEqual, true)
+			So(restored.Count, ShouldEqual, 3)
+
+			target.Collection().Drop(context.Background())
+		}
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strconv"
+)
+
+// Documents that share the values of the keys FindDuplicates looked at
+type DuplicateCluster struct {
+	// The shared values by key. Documents missing a key share a nil value
+	Key   bson.M
+	Count int
+	IDs   []primitive.ObjectID
+}
+
+type duplicateGroup struct {
+	Key   bson.M               `bson:"_id"`
+	Count int                  `bson:"count"`
+	IDs   []primitive.ObjectID `bson:"ids"`
+}
+
+// Finds the documents matching filter that share the values of all keys, e.g. users signed up twice with the same
+// email address, largest clusters first:
+//
+//	clusters, err := users.FindDuplicates([]string{"email"}, bson.M{"deleted_at": nil})
+func (c *Collection) FindDuplicates(keys []string, filter interface{}) ([]*DuplicateCluster, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("FindDuplicates needs at least one key")
+	}
+
+	clusters := []*DuplicateCluster{}
+	err := c.runOperation("findDuplicates", func(ctx context.Context) error {
+		clusters = clusters[:0]
+		pipeline, err := c.duplicatesPipeline(ctx, keys, filter)
+		if err != nil {
+			return err
+		}
+		opts := options.Aggregate().SetAllowDiskUse(true)
+		commentOn(ctx, c, opts.SetComment)
+		cursor, err := c.Collection().Aggregate(ctx, pipeline, opts)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			group := &duplicateGroup{}
+			if err := cursor.Decode(group); err != nil {
+				return err
+			}
+			cluster := &DuplicateCluster{Key: bson.M{}, Count: group.Count, IDs: group.IDs}
+			for i, key := range keys {
+				cluster.Key[key] = group.Key[strconv.Itoa(i)]
+			}
+			clusters = append(clusters, cluster)
+		}
+		return cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+// Groups by the keys under their index, since the field names of groups can't have dots
+func (c *Collection) duplicatesPipeline(ctx context.Context, keys []string, filter interface{}) (bson.A, error) {
+	match, err := c.guardFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	group := bson.D{}
+	for i, key := range keys {
+		group = append(group, bson.E{Key: strconv.Itoa(i), Value: "$" + key})
+	}
+
+	return bson.A{
+		bson.M{"$match": match},
+		bson.M{"$group": bson.M{"_id": group, "count": bson.M{"$sum": 1}, "ids": bson.M{"$push": "$_id"}}},
+		bson.M{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+		bson.M{"$sort": bson.D{{"count", -1}, {"_id", 1}}},
+	}, nil
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestDuplicatesPipeline(t *testing.T) {
+	Convey("Duplicates pipeline", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+		users := conn.Collection("users")
+
+		Convey("should group by the keys under their index", func() {
+			pipeline, err := users.duplicatesPipeline(context.Background(), []string{"email", "address.zip"}, nil)
+			So(err, ShouldBeNil)
+			So(pipeline[0], ShouldResemble, bson.M{"$match": bson.M{}})
+			group := pipeline[1].(bson.M)["$group"].(bson.M)
+			So(group["_id"], ShouldResemble, bson.D{{"0", "$email"}, {"1", "$address.zip"}})
+			So(pipeline[2], ShouldResemble, bson.M{"$match": bson.M{"count": bson.M{"$gt": 1}}})
+		})
+
+		Convey("should apply the query guard", func() {
+			conn.Config.QueryGuards = map[string]QueryGuard{
+				"users": func(ctx context.Context, filter interface{}) (interface{}, error) {
+					return AndFilter(filter, bson.M{"tenant": "acme"}), nil
+				},
+			}
+			pipeline, _ := users.duplicatesPipeline(context.Background(), []string{"email"}, nil)
+			So(pipeline[0], ShouldResemble, bson.M{"$match": bson.M{"tenant": "acme"}})
+		})
+
+		Convey("should need a key", func() {
+			_, err := users.FindDuplicates(nil, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestFindDuplicates(t *testing.T) {
+	conn := getConnection()
+
+	Convey("FindDuplicates", t, func() {
+		tests := conn.Collection("tests")
+		first := &noHookDocument{Name: "ann"}
+		second := &noHookDocument{Name: "ann"}
+		for _, doc := range []*noHookDocument{first, second, {Name: "bob"}} {
+			So(tests.Save(doc), ShouldBeNil)
+		}
+
+		Convey("should return the clusters sharing the keys", func() {
+			clusters, err := tests.FindDuplicates([]string{"name"}, nil)
+			So(err, ShouldBeNil)
+			So(len(clusters), ShouldEqual, 1)
+			So(clusters[0].Key, ShouldResemble, bson.M{"name": "ann"})
+			So(clusters[0].Count, ShouldEqual, 2)
+			So(clusters[0].IDs, ShouldContain, first.ID)
+			So(clusters[0].IDs, ShouldContain, second.ID)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
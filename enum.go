@@ -0,0 +1,233 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A field that holds one of the values declared with DeclareEnum for T:
+//
+//	type OrderStatus string
+//
+//	const (
+//		StatusOpen    OrderStatus = "open"
+//		StatusShipped OrderStatus = "shipped"
+//	)
+//
+//	func init() {
+//		bongo.DeclareEnum([]OrderStatus{StatusOpen, StatusShipped}, nil)
+//	}
+//
+//	type Order struct {
+//		bongo.DocumentBase `bson:",inline"`
+//		Status bongo.Enum[OrderStatus] `bson:"status"`
+//	}
+//
+// Saves fail with a *ValidationError holding a *FieldError with the code VALIDATION_INCLUSION if the value isn't
+// declared. String values are stored as strings and int values as the smallest integer that holds them. Unknown
+// values that are found or read from JSON fail with an *UnknownEnumValueError, or decode into the fallback of the
+// declaration. Enums whose type wasn't declared accept any value
+type Enum[T ~string | ~int] struct {
+	Value T
+}
+
+// The code of the *FieldError for Enum fields holding undeclared values
+const VALIDATION_INCLUSION = "inclusion"
+
+type EnumOptions[T ~string | ~int] struct {
+	// Decode unknown stored values into this instead of failing with an *UnknownEnumValueError, e.g. to read
+	// documents written by a newer version of a service
+	Fallback *T
+}
+
+// Returned when a stored or JSON value isn't one of the declared values of its enum
+type UnknownEnumValueError struct {
+	// The enum's type, e.g. "models.OrderStatus"
+	Type  string
+	Value interface{}
+}
+
+func (e *UnknownEnumValueError) Error() string {
+	return fmt.Sprintf("unknown %s value %v", e.Type, e.Value)
+}
+
+type enumDeclaration[T ~string | ~int] struct {
+	values   []T
+	fallback *T
+}
+
+var enumDeclarations sync.Map
+
+// Declares the values of an enum type. Declaring a type again replaces its values
+func DeclareEnum[T ~string | ~int](values []T, opts *EnumOptions[T]) {
+	if opts == nil {
+		opts = &EnumOptions[T]{}
+	}
+	enumDeclarations.Store(reflect.TypeOf(*new(T)), &enumDeclaration[T]{values: values, fallback: opts.Fallback})
+}
+
+func enumDeclarationOf[T ~string | ~int]() *enumDeclaration[T] {
+	declaration, ok := enumDeclarations.Load(reflect.TypeOf(*new(T)))
+	if !ok {
+		return nil
+	}
+	return declaration.(*enumDeclaration[T])
+}
+
+// The declared values of an enum type, or nil if it wasn't declared
+func EnumValues[T ~string | ~int]() []T {
+	if declaration := enumDeclarationOf[T](); declaration != nil {
+		return declaration.values
+	}
+	return nil
+}
+
+// Whether the value is one of the declared values, or the type wasn't declared
+func (e Enum[T]) Valid() bool {
+	declaration := enumDeclarationOf[T]()
+	return declaration == nil || ValidateInclusion(e.Value, declaration.values)
+}
+
+func (e Enum[T]) String() string {
+	return fmt.Sprint(e.Value)
+}
+
+// Implemented by Enum, so validateDocument finds enum fields of any type
+type enumField interface {
+	validateEnum(field string) error
+}
+
+func (e Enum[T]) validateEnum(field string) error {
+	if e.Valid() {
+		return nil
+	}
+	values := make([]string, len(EnumValues[T]()))
+	for i, value := range EnumValues[T]() {
+		values[i] = fmt.Sprint(value)
+	}
+	return &FieldError{Field: field, Code: VALIDATION_INCLUSION, Params: map[string]interface{}{"values": strings.Join(values, ", ")},
+		Message: field + " must be one of " + strings.Join(values, ", ")}
+}
+
+// Replaces an unknown value with the fallback, or fails
+func (e *Enum[T]) checkDecoded() error {
+	if e.Valid() {
+		return nil
+	}
+	if fallback := enumDeclarationOf[T]().fallback; fallback != nil {
+		e.Value = *fallback
+		return nil
+	}
+	return &UnknownEnumValueError{Type: reflect.TypeOf(e.Value).String(), Value: e.Value}
+}
+
+func (e Enum[T]) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	value := reflect.ValueOf(e.Value)
+	if value.Kind() == reflect.String {
+		return bson.MarshalValue(value.String())
+	}
+	if n := value.Int(); n >= math.MinInt32 && n <= math.MaxInt32 {
+		return bson.MarshalValue(int32(n))
+	}
+	return bson.MarshalValue(value.Int())
+}
+
+func (e *Enum[T]) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	value := reflect.ValueOf(&e.Value).Elem()
+	if t == bsontype.Null || t == bsontype.Undefined {
+		value.SetZero()
+		return nil
+	}
+
+	if value.Kind() == reflect.String {
+		s, ok := raw.StringValueOK()
+		if !ok {
+			return errors.New("can't decode " + t.String() + " into a string enum")
+		}
+		value.SetString(s)
+	} else {
+		n, ok := raw.AsInt64OK()
+		if !ok {
+			return errors.New("can't decode " + t.String() + " into an int enum")
+		}
+		value.SetInt(n)
+	}
+	return e.checkDecoded()
+}
+
+func (e Enum[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Value)
+}
+
+func (e *Enum[T]) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &e.Value); err != nil {
+		return err
+	}
+	return e.checkDecoded()
+}
+
+type enumFieldPath struct {
+	goPath string
+	path   string
+}
+
+var enumFieldsCache sync.Map
+
+var enumFieldType = reflect.TypeOf((*enumField)(nil)).Elem()
+
+// The Enum fields of a type, cached per type
+func enumFields(t reflect.Type) []enumFieldPath {
+	if fields, ok := enumFieldsCache.Load(t); ok {
+		return fields.([]enumFieldPath)
+	}
+
+	fields := make([]enumFieldPath, 0)
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		if indirectType(field.Type).Implements(enumFieldType) {
+			fields = append(fields, enumFieldPath{goPath: goPath, path: path})
+			return false
+		}
+		return true
+	})
+
+	enumFieldsCache.Store(t, fields)
+	return fields
+}
+
+// Validates the Enum fields of a document
+func validateEnums(doc interface{}) []error {
+	errs := make([]error, 0)
+	v := reflect.ValueOf(doc)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return errs
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return errs
+	}
+
+	for _, field := range enumFields(v.Type()) {
+		value := fieldByGoPath(v, field.goPath)
+		if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+			continue
+		}
+		if err := reflect.Indirect(value).Interface().(enumField).validateEnum(field.path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type enumStatus string
+
+type enumPriority int
+
+type enumDocument struct {
+	DocumentBase `bson:",inline"`
+	Status       Enum[enumStatus]    `bson:"status" json:"status"`
+	Priority     *Enum[enumPriority] `bson:"priority,omitempty" json:"priority,omitempty"`
+}
+
+func TestEnum(t *testing.T) {
+	Convey("Enum", t, func() {
+		DeclareEnum([]enumStatus{"open", "closed"}, nil)
+		DeclareEnum([]enumPriority{1, 2, 3}, nil)
+
+		Convey("should tell declared values", func() {
+			So(Enum[enumStatus]{Value: "open"}.Valid(), ShouldBeTrue)
+			So(Enum[enumStatus]{Value: "lost"}.Valid(), ShouldBeFalse)
+			So(EnumValues[enumPriority](), ShouldResemble, []enumPriority{1, 2, 3})
+			So(ValidateInclusion(enumStatus("open"), EnumValues[enumStatus]()), ShouldBeTrue)
+		})
+
+		Convey("should fail saves of undeclared values", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+			doc := &enumDocument{Status: Enum[enumStatus]{Value: "lost"}, Priority: &Enum[enumPriority]{Value: 9}}
+			err := conn.Collection("tests").validateDocument(doc)
+			So(err, ShouldHaveSameTypeAs, &ValidationError{})
+
+			errs := err.(*ValidationError).Errors
+			So(len(errs), ShouldEqual, 2)
+			So(errs[0].(*FieldError).Field, ShouldEqual, "status")
+			So(errs[0].(*FieldError).Code, ShouldEqual, "inclusion")
+			So(errs[0].Error(), ShouldEqual, "status must be one of open, closed")
+
+			doc.Status.Value, doc.Priority = "open", nil
+			So(conn.Collection("tests").validateDocument(doc), ShouldBeNil)
+		})
+
+		Convey("should encode strings and small ints", func() {
+			raw, err := bson.Marshal(&enumDocument{Status: Enum[enumStatus]{Value: "open"}, Priority: &Enum[enumPriority]{Value: 2}})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("status").StringValue(), ShouldEqual, "open")
+			So(bson.Raw(raw).Lookup("priority").Type, ShouldEqual, bson.TypeInt32)
+
+			found := &enumDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Status.Value, ShouldEqual, enumStatus("open"))
+			So(found.Priority.Value, ShouldEqual, enumPriority(2))
+		})
+
+		Convey("should fail to decode unknown values", func() {
+			raw, _ := bson.Marshal(bson.M{"status": "lost"})
+			err := bson.Unmarshal(raw, &enumDocument{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unknown bongo.enumStatus value lost")
+
+			So(json.Unmarshal([]byte(`{"status": "lost"}`), &enumDocument{}), ShouldHaveSameTypeAs, &UnknownEnumValueError{})
+		})
+
+		Convey("should decode unknown values into the fallback", func() {
+			fallback := enumStatus("closed")
+			DeclareEnum([]enumStatus{"open", "closed"}, &EnumOptions[enumStatus]{Fallback: &fallback})
+
+			raw, _ := bson.Marshal(bson.M{"status": "lost"})
+			found := &enumDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Status.Value, ShouldEqual, fallback)
+		})
+	})
+}
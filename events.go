@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
+)
+
+// Callbacks registered on a connection for the driver's monitoring events. They are invoked on driver goroutines,
+// so they should return quickly
+type eventCallbacks struct {
+	mutex              sync.RWMutex
+	serverChanged      []func(*event.ServerDescriptionChangedEvent)
+	topologyChanged    []func(*event.TopologyDescriptionChangedEvent)
+	heartbeatSucceeded []func(*event.ServerHeartbeatSucceededEvent)
+	heartbeatFailed    []func(*event.ServerHeartbeatFailedEvent)
+	poolEvent          []func(*event.PoolEvent)
+	poolCleared        []func(*event.PoolEvent)
+}
+
+// Called whenever the driver's description of a server changes (e.g. it became primary or unreachable)
+func (m *Connection) OnServerChanged(fn func(*event.ServerDescriptionChangedEvent)) {
+	m.callbacks.mutex.Lock()
+	defer m.callbacks.mutex.Unlock()
+	m.callbacks.serverChanged = append(m.callbacks.serverChanged, fn)
+}
+
+// Called whenever the driver's description of the topology changes (e.g. after a failover)
+func (m *Connection) OnTopologyChanged(fn func(*event.TopologyDescriptionChangedEvent)) {
+	m.callbacks.mutex.Lock()
+	defer m.callbacks.mutex.Unlock()
+	m.callbacks.topologyChanged = append(m.callbacks.topologyChanged, fn)
+}
+
+// Called after every successful server heartbeat
+func (m *Connection) OnHeartbeatSucceeded(fn func(*event.ServerHeartbeatSucceededEvent)) {
+	m.callbacks.mutex.Lock()
+	defer m.callbacks.mutex.Unlock()
+	m.callbacks.heartbeatSucceeded = append(m.callbacks.heartbeatSucceeded, fn)
+}
+
+// Called after every failed server heartbeat
+func (m *Connection) OnHeartbeatFailed(fn func(*event.ServerHeartbeatFailedEvent)) {
+	m.callbacks.mutex.Lock()
+	defer m.callbacks.mutex.Unlock()
+	m.callbacks.heartbeatFailed = append(m.callbacks.heartbeatFailed, fn)
+}
+
+// Called for every connection pool event (connection created, checked out, checked in, ...)
+func (m *Connection) OnPoolEvent(fn func(*event.PoolEvent)) {
+	m.callbacks.mutex.Lock()
+	defer m.callbacks.mutex.Unlock()
+	m.callbacks.poolEvent = append(m.callbacks.poolEvent, fn)
+}
+
+// Called when a connection pool is cleared, which usually means the server went away
+func (m *Connection) OnPoolCleared(fn func(*event.PoolEvent)) {
+	m.callbacks.mutex.Lock()
+	defer m.callbacks.mutex.Unlock()
+	m.callbacks.poolCleared = append(m.callbacks.poolCleared, fn)
+}
+
+// Builds the client options that route the driver's monitoring events of a client to the registered callbacks.
+// Events of a client the health monitor replaced (generation isn't the current one any more) are dropped, so a
+// client draining its operations can't flip the availability of the connection. Monitors that were set on
+// Config.ClientOptions are still called with all events, and pool stats count the connections of all clients
+func (m *Connection) monitorOptions(generation int64) *options.ClientOptions {
+	var userServer *event.ServerMonitor
+	var userPool *event.PoolMonitor
+	if m.Config.ClientOptions != nil {
+		userServer = m.Config.ClientOptions.ServerMonitor
+		userPool = m.Config.ClientOptions.PoolMonitor
+	}
+
+	cb := &m.callbacks
+	replaced := func() bool {
+		return m.clientGeneration.Load() != generation
+	}
+
+	server := &event.ServerMonitor{
+		ServerDescriptionChanged: func(e *event.ServerDescriptionChangedEvent) {
+			if userServer != nil && userServer.ServerDescriptionChanged != nil {
+				userServer.ServerDescriptionChanged(e)
+			}
+			if replaced() {
+				return
+			}
+			cb.mutex.RLock()
+			defer cb.mutex.RUnlock()
+			for _, fn := range cb.serverChanged {
+				fn(e)
+			}
+		},
+		TopologyDescriptionChanged: func(e *event.TopologyDescriptionChangedEvent) {
+			if userServer != nil && userServer.TopologyDescriptionChanged != nil {
+				userServer.TopologyDescriptionChanged(e)
+			}
+			if replaced() {
+				return
+			}
+			cb.mutex.RLock()
+			defer cb.mutex.RUnlock()
+			for _, fn := range cb.topologyChanged {
+				fn(e)
+			}
+		},
+		ServerHeartbeatSucceeded: func(e *event.ServerHeartbeatSucceededEvent) {
+			if userServer != nil && userServer.ServerHeartbeatSucceeded != nil {
+				userServer.ServerHeartbeatSucceeded(e)
+			}
+			if replaced() {
+				return
+			}
+			cb.mutex.RLock()
+			defer cb.mutex.RUnlock()
+			for _, fn := range cb.heartbeatSucceeded {
+				fn(e)
+			}
+		},
+		ServerHeartbeatFailed: func(e *event.ServerHeartbeatFailedEvent) {
+			if userServer != nil && userServer.ServerHeartbeatFailed != nil {
+				userServer.ServerHeartbeatFailed(e)
+			}
+			if replaced() {
+				return
+			}
+			cb.mutex.RLock()
+			defer cb.mutex.RUnlock()
+			for _, fn := range cb.heartbeatFailed {
+				fn(e)
+			}
+		},
+	}
+
+	if userServer != nil {
+		server.ServerOpening = userServer.ServerOpening
+		server.ServerClosed = userServer.ServerClosed
+		server.TopologyOpening = userServer.TopologyOpening
+		server.TopologyClosed = userServer.TopologyClosed
+		server.ServerHeartbeatStarted = userServer.ServerHeartbeatStarted
+	}
+
+	pool := &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			m.pool.record(e)
+			if userPool != nil && userPool.Event != nil {
+				userPool.Event(e)
+			}
+			if replaced() {
+				return
+			}
+			cb.mutex.RLock()
+			defer cb.mutex.RUnlock()
+			for _, fn := range cb.poolEvent {
+				fn(e)
+			}
+			if e.Type == event.PoolCleared {
+				for _, fn := range cb.poolCleared {
+					fn(e)
+				}
+			}
+		},
+	}
+
+	return options.Client().SetServerMonitor(server).SetPoolMonitor(pool)
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"testing"
+)
+
+func TestEventCallbacks(t *testing.T) {
+	Convey("Event callbacks", t, func() {
+		userPoolEvents := 0
+		conn := &Connection{Config: &Config{
+			ClientOptions: options.Client().SetPoolMonitor(&event.PoolMonitor{
+				Event: func(e *event.PoolEvent) {
+					userPoolEvents++
+				},
+			}),
+		}}
+
+		Convey("should dispatch pool events", func() {
+			poolEvents := 0
+			cleared := 0
+			conn.OnPoolEvent(func(e *event.PoolEvent) {
+				poolEvents++
+			})
+			conn.OnPoolCleared(func(e *event.PoolEvent) {
+				cleared++
+			})
+
+			opts := conn.monitorOptions(0)
+			opts.PoolMonitor.Event(&event.PoolEvent{Type: event.GetSucceeded})
+			opts.PoolMonitor.Event(&event.PoolEvent{Type: event.PoolCleared})
+
+			So(poolEvents, ShouldEqual, 2)
+			So(cleared, ShouldEqual, 1)
+			So(userPoolEvents, ShouldEqual, 2)
+		})
+
+		Convey("should dispatch server events", func() {
+			changed := 0
+			failed := 0
+			conn.OnServerChanged(func(e *event.ServerDescriptionChangedEvent) {
+				changed++
+			})
+			conn.OnHeartbeatFailed(func(e *event.ServerHeartbeatFailedEvent) {
+				failed++
+			})
+
+			opts := conn.monitorOptions(0)
+			opts.ServerMonitor.ServerDescriptionChanged(&event.ServerDescriptionChangedEvent{})
+			opts.ServerMonitor.ServerHeartbeatFailed(&event.ServerHeartbeatFailedEvent{})
+
+			So(changed, ShouldEqual, 1)
+			So(failed, ShouldEqual, 1)
+		})
+	})
+}
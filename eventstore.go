@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"time"
+)
+
+// An append-only store of the events of aggregates, for event-sourced services:
+//
+//	store := conn.EventStore("order_events")
+//	_, err := store.Append(ctx, orderID, 0, &OrderPlaced{Total: 42})
+//
+//	events, err := store.Load(ctx, orderID, 1)
+//	for _, event := range events {
+//		switch event.Type {
+//		case "OrderPlaced":
+//			placed := &OrderPlaced{}
+//			err = event.Decode(placed)
+//		}
+//	}
+//
+// The events appended together are stored as one document (a commit), so they are written all or not at all
+type EventStore struct {
+	Collection *Collection
+}
+
+// An event of an aggregate. The first event of an aggregate has version 1
+type Event struct {
+	AggregateID string    `bson:"aggregate_id"`
+	Version     int64     `bson:"version"`
+	Type        string    `bson:"type"`
+	Data        bson.Raw  `bson:"data"`
+	Created     time.Time `bson:"created"`
+}
+
+// Events that implement EventType are stored with the type it returns, others with the name of their struct
+type EventTyper interface {
+	EventType() string
+}
+
+// Returned by Append when the aggregate isn't at the expected version, because someone else appended to it
+type VersionConflictError struct {
+	AggregateID string
+	Expected    int64
+	Actual      int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("aggregate %s is at version %d, not %d", e.AggregateID, e.Actual, e.Expected)
+}
+
+type eventCommit struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	AggregateID string             `bson:"aggregate_id"`
+	First       int64              `bson:"first"`
+	Last        int64              `bson:"last"`
+	Events      []*Event           `bson:"events"`
+}
+
+// The event store on the named collection
+func (m *Connection) EventStore(name string) *EventStore {
+	return &EventStore{Collection: m.Collection(name)}
+}
+
+// Creates the unique index that lets only one of concurrent appends at the same version succeed. Appends aren't
+// safe from each other without it
+func (s *EventStore) EnsureIndexes(ctx context.Context) error {
+	return s.Collection.runOperationContext(ctx, "createIndex", func(ctx context.Context) error {
+		_, err := s.Collection.Collection().Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{"aggregate_id", 1}, {"first", 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		return err
+	})
+}
+
+// Appends events to the stream of the aggregate, which must be at expectedVersion (0 for a new aggregate), or
+// returns a *VersionConflictError. Returns the stored events
+func (s *EventStore) Append(ctx context.Context, aggregateID string, expectedVersion int64, events ...interface{}) ([]*Event, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	version, err := s.Version(ctx, aggregateID)
+	if err != nil {
+		return nil, err
+	}
+	if version != expectedVersion {
+		return nil, &VersionConflictError{AggregateID: aggregateID, Expected: expectedVersion, Actual: version}
+	}
+
+	commit := &eventCommit{
+		ID:          primitive.NewObjectID(),
+		AggregateID: aggregateID,
+		First:       expectedVersion + 1,
+		Last:        expectedVersion + int64(len(events)),
+	}
+	now := time.Now()
+	for i, payload := range events {
+		data, err := bson.MarshalWithRegistry(Registry, payload)
+		if err != nil {
+			return nil, err
+		}
+		commit.Events = append(commit.Events, &Event{
+			AggregateID: aggregateID,
+			Version:     commit.First + int64(i),
+			Type:        eventType(payload),
+			Data:        data,
+			Created:     now,
+		})
+	}
+
+	// Not retried: a retry of an insert whose reply was lost would fail with a duplicate key, and be reported as a
+	// conflict with itself
+	err = s.Collection.runOperationOnce(ctx, "append", func(ctx context.Context) error {
+		_, err := s.Collection.Collection().InsertOne(ctx, commit)
+		return err
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		// Someone appended at the same version between our check and our insert
+		actual, versionErr := s.Version(ctx, aggregateID)
+		if versionErr != nil {
+			return nil, versionErr
+		}
+		return nil, &VersionConflictError{AggregateID: aggregateID, Expected: expectedVersion, Actual: actual}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return commit.Events, nil
+}
+
+// The version of the last event of the aggregate, 0 if it has none
+func (s *EventStore) Version(ctx context.Context, aggregateID string) (int64, error) {
+	commit := &eventCommit{}
+	err := s.Collection.runOperationContext(ctx, "version", func(ctx context.Context) error {
+		opts := options.FindOne().SetSort(bson.D{{"first", -1}}).SetProjection(bson.M{"last": 1})
+		return s.Collection.Collection().FindOne(ctx, bson.M{"aggregate_id": aggregateID}, opts).Decode(commit)
+	})
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return commit.Last, err
+}
+
+// The events of the aggregate from fromVersion on, in order
+func (s *EventStore) Load(ctx context.Context, aggregateID string, fromVersion int64) ([]*Event, error) {
+	events := []*Event{}
+	err := s.Collection.runOperationContext(ctx, "load", func(ctx context.Context) error {
+		filter := bson.M{"aggregate_id": aggregateID, "last": bson.M{"$gte": fromVersion}}
+		cursor, err := s.Collection.Collection().Find(ctx, filter, options.Find().SetSort(bson.D{{"first", 1}}))
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			commit := &eventCommit{}
+			if err := cursor.Decode(commit); err != nil {
+				return err
+			}
+			for _, event := range commit.Events {
+				if event.Version >= fromVersion {
+					events = append(events, event)
+				}
+			}
+		}
+		return cursor.Err()
+	})
+	return events, err
+}
+
+// Calls fn with the events appended from now on, in the order of their commits, until ctx is canceled or fn
+// returns an error. It watches the collection with a change stream, which needs a replica set
+func (s *EventStore) Subscribe(ctx context.Context, fn func(ctx context.Context, event *Event) error) error {
+	pipeline := mongo.Pipeline{{{"$match", bson.M{"operationType": "insert"}}}}
+	stream, err := s.Collection.Collection().Watch(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+
+	for stream.Next(ctx) {
+		change := struct {
+			FullDocument *eventCommit `bson:"fullDocument"`
+		}{}
+		if err := stream.Decode(&change); err != nil {
+			return err
+		}
+		if change.FullDocument == nil {
+			continue
+		}
+		for _, event := range change.FullDocument.Events {
+			if err := fn(ctx, event); err != nil {
+				return err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return stream.Err()
+}
+
+// Decodes the data of the event into v
+func (e *Event) Decode(v interface{}) error {
+	return bson.UnmarshalWithRegistry(Registry, e.Data, v)
+}
+
+func eventType(payload interface{}) string {
+	if typer, ok := payload.(EventTyper); ok {
+		return typer.EventType()
+	}
+	return reflect.Indirect(reflect.ValueOf(payload)).Type().Name()
+}
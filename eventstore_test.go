@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type orderPlaced struct {
+	Total int `bson:"total"`
+}
+
+type orderShipped struct {
+	Carrier string `bson:"carrier"`
+}
+
+func (e *orderShipped) EventType() string {
+	return "shipped"
+}
+
+func TestEventTypes(t *testing.T) {
+	Convey("Event types", t, func() {
+		Convey("should default to the struct name", func() {
+			So(eventType(&orderPlaced{}), ShouldEqual, "orderPlaced")
+			So(eventType(orderPlaced{}), ShouldEqual, "orderPlaced")
+		})
+
+		Convey("should use EventType when implemented", func() {
+			So(eventType(&orderShipped{}), ShouldEqual, "shipped")
+		})
+
+		Convey("should decode event data", func() {
+			data, err := bson.Marshal(&orderPlaced{Total: 42})
+			So(err, ShouldBeNil)
+			placed := &orderPlaced{}
+			So((&Event{Data: data}).Decode(placed), ShouldBeNil)
+			So(placed.Total, ShouldEqual, 42)
+		})
+
+		Convey("should describe version conflicts", func() {
+			err := &VersionConflictError{AggregateID: "order-1", Expected: 2, Actual: 3}
+			So(err.Error(), ShouldEqual, "aggregate order-1 is at version 3, not 2")
+		})
+	})
+}
+
+func TestEventStore(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Event store", t, func() {
+		ctx := context.Background()
+		store := conn.EventStore("order_events")
+		So(store.EnsureIndexes(ctx), ShouldBeNil)
+
+		Convey("should append and load events in order", func() {
+			events, err := store.Append(ctx, "order-1", 0, &orderPlaced{Total: 42}, &orderShipped{Carrier: "ups"})
+			So(err, ShouldBeNil)
+			So(events[1].Version, ShouldEqual, 2)
+
+			_, err = store.Append(ctx, "order-1", 2, &orderShipped{Carrier: "dhl"})
+			So(err, ShouldBeNil)
+
+			version, err := store.Version(ctx, "order-1")
+			So(err, ShouldBeNil)
+			So(version, ShouldEqual, 3)
+
+			loaded, err := store.Load(ctx, "order-1", 2)
+			So(err, ShouldBeNil)
+			So(len(loaded), ShouldEqual, 2)
+			So(loaded[0].Type, ShouldEqual, "shipped")
+			So(loaded[1].Version, ShouldEqual, 3)
+		})
+
+		Convey("should reject appends at a stale version", func() {
+			_, err := store.Append(ctx, "order-2", 0, &orderPlaced{Total: 1})
+			So(err, ShouldBeNil)
+
+			_, err = store.Append(ctx, "order-2", 0, &orderPlaced{Total: 2})
+			So(err, ShouldHaveSameTypeAs, &VersionConflictError{})
+			So(err.(*VersionConflictError).Actual, ShouldEqual, 1)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
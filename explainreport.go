@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+	"time"
+)
+
+// A summary of an explained query, for logs and developer tooling
+type ExplainReport struct {
+	// The database and collection the query ran on
+	Namespace string
+
+	// The stages of the winning plan, outermost first, e.g. FETCH, IXSCAN
+	Stages []string
+
+	// The indexes the plan uses. Empty for collection scans
+	Indexes []string
+
+	CollectionScan bool
+	InMemorySort   bool
+
+	// Only known when the query was explained with execution stats, see Collection.ExplainReport
+	Executed      bool
+	Returned      int64
+	KeysExamined  int64
+	DocsExamined  int64
+	ExecutionTime time.Duration
+}
+
+// Explains a query with execution stats, which runs it, and summarizes the plan
+func (c *Collection) ExplainReport(query interface{}, sortSpec interface{}) (*ExplainReport, error) {
+	explain, err := c.explain(query, sortSpec, "executionStats")
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeExplain(explain), nil
+}
+
+// Summarizes the output of an explain command, e.g. of Collection.Explain
+func SummarizeExplain(explain bson.M) *ExplainReport {
+	report := &ExplainReport{Stages: []string{}, Indexes: []string{}}
+
+	planner, _ := explain["queryPlanner"].(bson.M)
+	report.Namespace, _ = planner["namespace"].(string)
+	walkPlan(planner["winningPlan"], func(stage bson.M) {
+		name, ok := stage["stage"].(string)
+		if !ok {
+			return
+		}
+		report.Stages = append(report.Stages, name)
+		switch name {
+		case "COLLSCAN":
+			report.CollectionScan = true
+		case "SORT":
+			report.InMemorySort = true
+		}
+		if index, ok := stage["indexName"].(string); ok && !stringInSlice(index, report.Indexes) {
+			report.Indexes = append(report.Indexes, index)
+		}
+	})
+
+	if stats, ok := explain["executionStats"].(bson.M); ok {
+		report.Executed = true
+		report.Returned = explainNumber(stats["nReturned"])
+		report.KeysExamined = explainNumber(stats["totalKeysExamined"])
+		report.DocsExamined = explainNumber(stats["totalDocsExamined"])
+		report.ExecutionTime = time.Duration(explainNumber(stats["executionTimeMillis"])) * time.Millisecond
+	}
+	return report
+}
+
+func explainNumber(value interface{}) int64 {
+	switch n := value.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+// The documents examined per document returned. 1 is ideal, large ratios mean the index doesn't fit the query. 0 if
+// nothing was returned
+func (r *ExplainReport) Ratio() float64 {
+	if r.Returned == 0 {
+		return 0
+	}
+	return float64(r.DocsExamined) / float64(r.Returned)
+}
+
+// A one line summary, e.g. "app.users: FETCH > IXSCAN (email_1), 1 returned, 1 keys and 1 docs examined (ratio
+// 1.0) in 2ms"
+func (r *ExplainReport) String() string {
+	summary := r.Namespace + ": " + strings.Join(r.Stages, " > ")
+	if len(r.Indexes) > 0 {
+		summary += " (" + strings.Join(r.Indexes, ", ") + ")"
+	}
+	if r.InMemorySort {
+		summary += ", in-memory sort"
+	}
+	if r.Executed {
+		summary += fmt.Sprintf(", %d returned, %d keys and %d docs examined (ratio %.1f) in %s",
+			r.Returned, r.KeysExamined, r.DocsExamined, r.Ratio(), r.ExecutionTime)
+	}
+	return summary
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+func TestSummarizeExplain(t *testing.T) {
+	Convey("SummarizeExplain", t, func() {
+		explain := bson.M{
+			"queryPlanner": bson.M{
+				"namespace": "app.users",
+				"winningPlan": bson.M{
+					"stage": "SORT",
+					"inputStage": bson.M{
+						"stage":      "FETCH",
+						"inputStage": bson.M{"stage": "IXSCAN", "indexName": "email_1"},
+					},
+				},
+			},
+		}
+
+		Convey("should summarize the winning plan", func() {
+			report := SummarizeExplain(explain)
+			So(report.Namespace, ShouldEqual, "app.users")
+			So(report.Stages, ShouldResemble, []string{"SORT", "FETCH", "IXSCAN"})
+			So(report.Indexes, ShouldResemble, []string{"email_1"})
+			So(report.InMemorySort, ShouldBeTrue)
+			So(report.CollectionScan, ShouldBeFalse)
+			So(report.Executed, ShouldBeFalse)
+			So(report.String(), ShouldEqual, "app.users: SORT > FETCH > IXSCAN (email_1), in-memory sort")
+		})
+
+		Convey("should include the execution stats", func() {
+			explain["executionStats"] = bson.M{
+				"nReturned": int32(2), "totalKeysExamined": int32(10), "totalDocsExamined": int64(10),
+				"executionTimeMillis": int32(3),
+			}
+			report := SummarizeExplain(explain)
+			So(report.Executed, ShouldBeTrue)
+			So(report.Ratio(), ShouldEqual, 5)
+			So(report.String(), ShouldEndWith, "2 returned, 10 keys and 10 docs examined (ratio 5.0) in 3ms")
+		})
+
+		Convey("should handle empty explains", func() {
+			report := SummarizeExplain(bson.M{})
+			So(report.Stages, ShouldBeEmpty)
+			So(report.Ratio(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestExplainReport(t *testing.T) {
+	conn := getConnection()
+
+	Convey("ExplainReport", t, func() {
+		col := conn.Collection("tests")
+		So(col.Save(&noHookDocument{Name: "foo"}), ShouldBeNil)
+		_, err := col.Collection().Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bson.D{{Key: "name", Value: 1}}})
+		So(err, ShouldBeNil)
+
+		Convey("should report the index and the execution stats", func() {
+			report, err := col.ExplainReport(bson.M{"name": "foo"}, nil)
+			So(err, ShouldBeNil)
+			So(report.Indexes, ShouldResemble, []string{"name_1"})
+			So(report.Returned, ShouldEqual, 1)
+			So(report.DocsExamined, ShouldEqual, 1)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Export formats
+const (
+	EXPORT_NDJSON = iota
+	EXPORT_CSV
+)
+
+type ExportOptions struct {
+	// Dotted paths of the fields to export. For CSV these are the columns, in order. If empty, NDJSON exports
+	// whole documents and CSV uses the (flattened) fields of the first document
+	Fields []string
+
+	// Write canonical instead of relaxed extended JSON (NDJSON only)
+	Canonical bool
+
+	// Anonymizes every document before it is written. Defaults to a redactor of the model registered for the
+	// collection, see NewRedactor
+	Anonymizer *Anonymizer
+
+	// Export the fields tagged for redaction as they are, if there is no Anonymizer
+	Unredacted bool
+}
+
+// Streams the documents of the result set to w, one at a time, as NDJSON (extended JSON) or CSV with nested
+// fields flattened to dotted columns. Hooks are not run. Returns the number of documents written
+func (r *ResultSet) Export(w io.Writer, format int, opts *ExportOptions) (int64, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	if format != EXPORT_NDJSON && format != EXPORT_CSV {
+		return 0, errors.New("unknown export format " + strconv.Itoa(format))
+	}
+	if opts.Anonymizer == nil && !opts.Unredacted {
+		redactor, err := r.Collection.redactor()
+		if err != nil {
+			return 0, err
+		}
+		redacted := *opts
+		redacted.Anonymizer = redactor
+		opts = &redacted
+	}
+
+	if !r.loadedIter {
+		r.loadedIter = true
+		if len(opts.Fields) > 0 && r.Query.Projection == nil {
+			projection := bson.M{}
+			for _, f := range opts.Fields {
+				projection[f] = 1
+			}
+			r.Query.SetProjection(projection)
+		}
+		if err := r.open(); err != nil {
+			r.Error = err
+			return 0, err
+		}
+	}
+
+	buffered := bufio.NewWriter(w)
+
+	var count int64
+	var err error
+	if format == EXPORT_NDJSON {
+		count, err = r.exportNDJSON(buffered, opts)
+	} else {
+		count, err = r.exportCSV(buffered, opts)
+	}
+
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	return count, err
+}
+
+func (r *ResultSet) exportNDJSON(w *bufio.Writer, opts *ExportOptions) (int64, error) {
+	var count int64
+	for r.Collection.nextDocument(r.Cursor) {
+		doc, err := r.exportDocument(opts)
+		if err != nil {
+			return count, err
+		}
+		line, err := bson.MarshalExtJSON(doc, opts.Canonical, false)
+		if err != nil {
+			return count, err
+		}
+		if _, err := w.Write(line); err != nil {
+			return count, err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, r.Cursor.Err()
+}
+
+func (r *ResultSet) exportCSV(w *bufio.Writer, opts *ExportOptions) (int64, error) {
+	writer := csv.NewWriter(w)
+	columns := opts.Fields
+
+	// With Fields the header is known up front, so even an empty result gets one
+	if len(columns) > 0 {
+		if err := writer.Write(columns); err != nil {
+			return 0, err
+		}
+	}
+
+	var count int64
+	for r.Collection.nextDocument(r.Cursor) {
+		doc, err := r.exportDocument(opts)
+		if err != nil {
+			return count, err
+		}
+
+		flat := make(map[string]string)
+		if err := flattenRaw(doc, "", flat, nil); err != nil {
+			return count, err
+		}
+
+		if len(columns) == 0 {
+			columns = make([]string, 0)
+			flattenRaw(doc, "", nil, &columns)
+			if err := writer.Write(columns); err != nil {
+				return count, err
+			}
+		}
+
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = flat[column]
+		}
+		if err := writer.Write(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return count, err
+	}
+	return count, r.Cursor.Err()
+}
+
+// The current document, anonymized if requested
+func (r *ResultSet) exportDocument(opts *ExportOptions) (bson.Raw, error) {
+	if opts.Anonymizer != nil {
+		return opts.Anonymizer.Anonymize(r.Cursor.Current)
+	}
+	return r.Cursor.Current, nil
+}
+
+// The redactor of the model registered for the collection, or nil if there is none
+func (c *Collection) redactor() (*Anonymizer, error) {
+	if c == nil {
+		return nil, nil
+	}
+	model := GetModel(c.Name)
+	if model == nil {
+		return nil, nil
+	}
+	return NewRedactor(model.New())
+}
+
+// Flattens a document into dotted keys, filling values and/or collecting the keys in document order
+func flattenRaw(doc bson.Raw, prefix string, values map[string]string, keys *[]string) error {
+	elements, err := doc.Elements()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range elements {
+		key := e.Key()
+		if len(prefix) > 0 {
+			key = prefix + "." + key
+		}
+
+		value := e.Value()
+		if value.Type == bsontype.EmbeddedDocument {
+			if err := flattenRaw(value.Document(), key, values, keys); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if keys != nil {
+			*keys = append(*keys, key)
+		}
+		if values != nil {
+			values[key] = csvValue(value)
+		}
+	}
+	return nil
+}
+
+// Formats a single value for a CSV cell. Arrays are written as extended JSON
+func csvValue(value bson.RawValue) string {
+	switch value.Type {
+	case bsontype.Null, bsontype.Undefined:
+		return ""
+	case bsontype.String:
+		return value.StringValue()
+	case bsontype.ObjectID:
+		return value.ObjectID().Hex()
+	case bsontype.DateTime:
+		return value.Time().UTC().Format(time.RFC3339Nano)
+	case bsontype.Boolean:
+		return strconv.FormatBool(value.Boolean())
+	case bsontype.Int32:
+		return strconv.FormatInt(int64(value.Int32()), 10)
+	case bsontype.Int64:
+		return strconv.FormatInt(value.Int64(), 10)
+	case bsontype.Double:
+		return strconv.FormatFloat(value.Double(), 'f', -1, 64)
+	case bsontype.Decimal128:
+		return value.Decimal128().String()
+	case bsontype.Array:
+		data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: value}}, false, false)
+		if err != nil {
+			return ""
+		}
+		// Strip the {"v": ...} wrapper
+		return string(data[5 : len(data)-1])
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+// Builds a result set over in-memory documents
+func resultSetFromDocuments(docs ...interface{}) *ResultSet {
+	cursor, err := mongo.NewCursorFromDocuments(docs, nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return &ResultSet{Cursor: cursor, loadedIter: true}
+}
+
+func TestExport(t *testing.T) {
+	Convey("Export", t, func() {
+		id := primitive.NewObjectID()
+		created := time.Date(2019, 6, 23, 12, 0, 0, 0, time.UTC)
+		docs := []interface{}{
+			bson.D{{Key: "_id", Value: id}, {Key: "name", Value: "foo"}, {Key: "address", Value: bson.D{{Key: "city", Value: "Berlin"}}}, {Key: "created_at", Value: created}, {Key: "tags", Value: bson.A{"a", "b"}}},
+			bson.D{{Key: "_id", Value: id}, {Key: "name", Value: "bar, baz"}, {Key: "count", Value: 3}},
+		}
+
+		Convey("should export NDJSON", func() {
+			out := &bytes.Buffer{}
+			count, err := resultSetFromDocuments(docs...).Export(out, EXPORT_NDJSON, nil)
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 2)
+
+			lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+			So(len(lines), ShouldEqual, 2)
+			So(string(lines[0]), ShouldContainSubstring, `{"$oid":"`+id.Hex()+`"}`)
+			So(string(lines[0]), ShouldContainSubstring, `"address":{"city":"Berlin"}`)
+		})
+
+		Convey("should export CSV with flattened columns from the first document", func() {
+			out := &bytes.Buffer{}
+			count, err := resultSetFromDocuments(docs...).Export(out, EXPORT_CSV, nil)
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 2)
+			So(out.String(), ShouldEqual, "_id,name,address.city,created_at,tags\n"+
+				id.Hex()+",foo,Berlin,2019-06-23T12:00:00Z,\"[\"\"a\"\",\"\"b\"\"]\"\n"+
+				id.Hex()+",\"bar, baz\",,,\n")
+		})
+
+		Convey("should export CSV with selected columns", func() {
+			out := &bytes.Buffer{}
+			_, err := resultSetFromDocuments(docs...).Export(out, EXPORT_CSV, &ExportOptions{Fields: []string{"name", "count"}})
+			So(err, ShouldEqual, nil)
+			So(out.String(), ShouldEqual, "name,count\nfoo,\n\"bar, baz\",3\n")
+		})
+
+		Convey("should write the header of selected columns without results", func() {
+			out := &bytes.Buffer{}
+			count, err := resultSetFromDocuments().Export(out, EXPORT_CSV, &ExportOptions{Fields: []string{"name", "count"}})
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 0)
+			So(out.String(), ShouldEqual, "name,count\n")
+
+			out.Reset()
+			_, err = resultSetFromDocuments().Export(out, EXPORT_CSV, nil)
+			So(err, ShouldEqual, nil)
+			So(out.String(), ShouldEqual, "")
+		})
+
+		Convey("should reject unknown formats", func() {
+			_, err := resultSetFromDocuments(docs...).Export(&bytes.Buffer{}, 42, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestExportFromCollection(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Export from a collection", t, func() {
+		col := conn.Collection("tests")
+		col.Save(&noHookDocument{Name: "foo"})
+		col.Save(&noHookDocument{Name: "bar"})
+
+		Convey("should only fetch the selected fields", func() {
+			results, _ := col.Find(bson.M{"name": "foo"})
+			defer results.Free()
+
+			out := &bytes.Buffer{}
+			count, err := results.Export(out, EXPORT_CSV, &ExportOptions{Fields: []string{"name"}})
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 1)
+			So(out.String(), ShouldEqual, "name\nfoo\n")
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
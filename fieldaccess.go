@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"strings"
+)
+
+// Which fields of a collection may be sorted and projected on (with SortBy, Select and Exclude), so sort and field
+// parameters of API clients can be passed through as they are. A nil list allows every field. Allowing a field
+// allows its subfields, and _id is always allowed
+type FieldAccessConfig struct {
+	Sortable    []string
+	Projectable []string
+
+	// Keep the stored values of fields the principal may read but not write (see the access tag), instead of
+	// failing the save
+	DropUnwritable bool
+}
+
+// Returned by finds that sort or project on a field that isn't allowed by the collection's FieldAccessConfig, and by
+// saves that change a field the principal may not write
+type FieldNotAllowedError struct {
+	Collection string
+	Field      string
+
+	// "sort", "projection" or "write"
+	Use string
+}
+
+func (e *FieldNotAllowedError) Error() string {
+	return "field " + e.Field + " of " + e.Collection + " is not allowed for " + e.Use
+}
+
+func (c *Collection) fieldAccess() *FieldAccessConfig {
+	if c.Connection == nil || c.Connection.Config == nil || c.Connection.Config.FieldAccess[c.Name] == nil {
+		return &FieldAccessConfig{}
+	}
+	return c.Connection.Config.FieldAccess[c.Name]
+}
+
+// Builds the find options with the collection's defaults and checks their fields against its FieldAccessConfig
+func (c *Collection) findOptions(opts []FindOption) (*findOptions, error) {
+	o := newFindOptions(opts)
+	if o.maxTime == 0 {
+		o.maxTime = c.defaults().MaxTime
+	}
+	access := c.fieldAccess()
+
+	for _, field := range o.sort {
+		if !fieldAllowed(access.Sortable, field.Key) {
+			return nil, &FieldNotAllowedError{Collection: c.Name, Field: field.Key, Use: "sort"}
+		}
+	}
+	for field := range o.projection {
+		if !fieldAllowed(access.Projectable, field) {
+			return nil, &FieldNotAllowedError{Collection: c.Name, Field: field, Use: "projection"}
+		}
+	}
+	return o, nil
+}
+
+func fieldAllowed(allowed []string, field string) bool {
+	if allowed == nil || field == "_id" {
+		return true
+	}
+	for _, path := range allowed {
+		if field == path || strings.HasPrefix(field, path+".") {
+			return true
+		}
+	}
+	return false
+}
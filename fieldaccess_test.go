@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestFieldAccess(t *testing.T) {
+	Convey("Field access", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest", FieldAccess: map[string]*FieldAccessConfig{
+			"orders": {Sortable: []string{"created_at", "customer"}, Projectable: []string{"status"}},
+		}}}
+		orders := conn.Collection("orders")
+
+		Convey("should allow every field without a config", func() {
+			_, err := conn.Collection("tests").Find(nil, SortBy("anything"), Select("secret"))
+			So(err, ShouldEqual, nil)
+		})
+
+		Convey("should allow the configured fields, their subfields and _id", func() {
+			_, err := orders.Find(nil, SortBy("-created_at", "customer.name", "_id"), Select("status", "_id"))
+			So(err, ShouldEqual, nil)
+		})
+
+		Convey("should reject other fields", func() {
+			_, err := orders.Find(nil, SortBy("total"))
+			So(err, ShouldResemble, &FieldNotAllowedError{Collection: "orders", Field: "total", Use: "sort"})
+
+			_, err = orders.Find(nil, Exclude("customer_name"))
+			So(err, ShouldResemble, &FieldNotAllowedError{Collection: "orders", Field: "customer_name", Use: "projection"})
+
+			So(orders.FindOne(nil, &noHookDocument{}, SortBy("status")), ShouldHaveSameTypeAs, &FieldNotAllowedError{})
+			So(orders.FindByID(primitive.NewObjectID(), &noHookDocument{}, Select("total")), ShouldHaveSameTypeAs, &FieldNotAllowedError{})
+		})
+	})
+}
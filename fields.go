@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Maps the Go field paths of a model (e.g. "Address.City") to the bson paths they are stored under
+// (e.g. "address.city"). Inline structs are flattened, just like their promoted fields in Go
+type FieldNames map[string]string
+
+var fieldNamesCache sync.Map
+
+// Get the field names of a document type. The result is cached per type and must not be modified
+func Fields(doc interface{}) FieldNames {
+	t := reflect.TypeOf(doc)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if names, ok := fieldNamesCache.Load(t); ok {
+		return names.(FieldNames)
+	}
+
+	names := FieldNames{}
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		names[goPath] = path
+		return true
+	})
+
+	fieldNamesCache.Store(t, names)
+	return names
+}
+
+// Get the bson path for a Go field path, or false if the model has no such field
+func (f FieldNames) Lookup(goPath string) (string, bool) {
+	path, ok := f[goPath]
+	return path, ok
+}
+
+// Get the bson path for a Go field path. Panics if the model has no such field, so typos and renames surface
+// immediately instead of silently matching nothing
+func (f FieldNames) Get(goPath string) string {
+	path, ok := f[goPath]
+	if !ok {
+		panic("bongo: unknown field " + goPath)
+	}
+	return path
+}
+
+// Fills the string fields of target (a pointer to a struct) with the bson paths of the model's fields with the
+// same names. Struct fields of target are filled from the nested fields of the model. For example:
+//
+//	var UserFields struct {
+//		Email   string
+//		Address struct {
+//			City string
+//		}
+//	}
+//
+//	bongo.MustBindFields(&UserFields, &User{})
+//	query := bson.M{UserFields.Address.City: "Berlin"}
+func BindFields(target interface{}, doc interface{}) error {
+	val := reflect.ValueOf(target)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return errors.New("BindFields target must be a pointer to a struct")
+	}
+
+	return bindFields(val.Elem(), "", Fields(doc))
+}
+
+// Same as BindFields, but panics on error. Meant for package level field name structs
+func MustBindFields(target interface{}, doc interface{}) {
+	if err := BindFields(target, doc); err != nil {
+		panic(err)
+	}
+}
+
+func bindFields(val reflect.Value, prefix string, names FieldNames) error {
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		goPath := field.Name
+		if len(prefix) > 0 {
+			goPath = prefix + "." + field.Name
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String:
+			path, ok := names.Lookup(goPath)
+			if !ok {
+				return errors.New("model has no field " + goPath)
+			}
+			val.Field(i).SetString(path)
+		case reflect.Struct:
+			if err := bindFields(val.Field(i), goPath, names); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
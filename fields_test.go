@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type fieldsAddress struct {
+	City    string `bson:"city"`
+	ZipCode string
+}
+
+type fieldsDocument struct {
+	DocumentBase `bson:",inline"`
+	Email        string        `bson:"email"`
+	FirstName    string        `bson:"first_name,omitempty"`
+	Address      fieldsAddress `bson:"addr"`
+	Parent       *fieldsDocument
+	Secret       string `bson:"-"`
+}
+
+func TestFields(t *testing.T) {
+	Convey("Field names", t, func() {
+		Convey("should map Go field paths to bson paths", func() {
+			fields := Fields(&fieldsDocument{})
+			So(fields.Get("ID"), ShouldEqual, "_id")
+			So(fields.Get("CreatedAt"), ShouldEqual, "created_at")
+			So(fields.Get("Email"), ShouldEqual, "email")
+			So(fields.Get("FirstName"), ShouldEqual, "first_name")
+			So(fields.Get("Address.City"), ShouldEqual, "addr.city")
+			So(fields.Get("Address.ZipCode"), ShouldEqual, "addr.zipcode")
+			So(fields.Get("Parent"), ShouldEqual, "parent")
+
+			_, ok := fields.Lookup("Secret")
+			So(ok, ShouldBeFalse)
+			So(func() { fields.Get("Nope") }, ShouldPanic)
+		})
+
+		Convey("should bind field names to a struct", func() {
+			var names struct {
+				ID      string
+				Email   string
+				Address struct {
+					City string
+				}
+			}
+			err := BindFields(&names, fieldsDocument{})
+			So(err, ShouldEqual, nil)
+			So(names.ID, ShouldEqual, "_id")
+			So(names.Email, ShouldEqual, "email")
+			So(names.Address.City, ShouldEqual, "addr.city")
+		})
+
+		Convey("should fail to bind unknown fields", func() {
+			var names struct {
+				Nope string
+			}
+			So(BindFields(&names, &fieldsDocument{}), ShouldNotBeNil)
+			So(BindFields(names, &fieldsDocument{}), ShouldNotBeNil)
+			So(func() { MustBindFields(&names, &fieldsDocument{}) }, ShouldPanic)
+		})
+	})
+}
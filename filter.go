@@ -0,0 +1,187 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// The operators of filter tags, and what they translate to. contains and prefix match strings literally, ignoring
+// case, like RegexContains and RegexPrefixFold
+var filterOperators = map[string]string{
+	"eq":       "$eq",
+	"ne":       "$ne",
+	"gt":       "$gt",
+	"gte":      "$gte",
+	"lt":       "$lt",
+	"lte":      "$lte",
+	"in":       "$in",
+	"nin":      "$nin",
+	"exists":   "$exists",
+	"contains": "$regex",
+	"prefix":   "$regex",
+}
+
+// Returned by FilterFromStruct for tags it can't translate. These are programming errors, not invalid input
+type FilterTagError struct {
+	// The Go field and its tag
+	Field string
+	Tag   string
+
+	Reason string
+}
+
+func (e *FilterTagError) Error() string {
+	return "invalid filter tag `" + e.Tag + "` on " + e.Field + ": " + e.Reason
+}
+
+// Builds a query from a struct whose fields are tagged with the bson path and operator (eq if omitted) to filter
+// by, e.g. a decoded HTTP or gRPC request:
+//
+//	type ListOrders struct {
+//		Status   string    `filter:"status"`
+//		Since    time.Time `filter:"created_at,gte"`
+//		Until    time.Time `filter:"created_at,lt"`
+//		Tags     []string  `filter:"tags,in"`
+//		Customer string    `filter:"customer.name,contains"`
+//		Paid     *bool     `filter:"paid"`
+//	}
+//
+//	filter, err := bongo.FilterFromStruct(&ListOrders{Status: "open", Since: lastWeek})
+//	// bson.M{"status": "open", "created_at": bson.M{"$gte": lastWeek}}
+//
+// The operators are eq, ne, gt, gte, lt, lte, in, nin (for slices), exists (for bools), contains and prefix (for
+// strings). Fields with zero values are left out, so use pointers to filter by false, 0 or "". Embedded structs are
+// walked. Only the tags decide on paths and operators, and values must be of plain types (not maps or interfaces),
+// so input decoded into the struct can't inject operators
+func FilterFromStruct(v interface{}) (bson.M, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return bson.M{}, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, errors.New("FilterFromStruct needs a struct, not " + value.Kind().String())
+	}
+
+	conditions := make(map[string]bson.M)
+	paths := make([]string, 0)
+	if err := addFilterFields(value, conditions, &paths); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{}
+	for _, path := range paths {
+		condition := conditions[path]
+		if eq, ok := condition["$eq"]; ok && len(condition) == 1 {
+			filter[path] = eq
+		} else {
+			filter[path] = condition
+		}
+	}
+	return filter, nil
+}
+
+func addFilterFields(value reflect.Value, conditions map[string]bson.M, paths *[]string) error {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, tagged := field.Tag.Lookup("filter")
+
+		if !tagged {
+			if field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct {
+				embedded := reflect.Indirect(value.Field(i))
+				if embedded.IsValid() {
+					if err := addFilterFields(embedded, conditions, paths); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		if tag == "-" {
+			continue
+		}
+
+		path, op, _ := strings.Cut(tag, ",")
+		if len(op) == 0 {
+			op = "eq"
+		}
+		fail := func(reason string) error {
+			return &FilterTagError{Field: t.Name() + "." + field.Name, Tag: tag, Reason: reason}
+		}
+		operator, ok := filterOperators[op]
+		if len(path) == 0 {
+			return fail("no path")
+		}
+		if !ok {
+			return fail("unknown operator " + op)
+		}
+
+		fieldValue := value.Field(i)
+		if !field.IsExported() || fieldValue.IsZero() {
+			continue
+		}
+		fieldValue = reflect.Indirect(fieldValue)
+
+		condition, err := filterCondition(op, fieldValue)
+		if err != nil {
+			return fail(err.Error())
+		}
+		if condition == nil {
+			continue
+		}
+
+		if _, ok := conditions[path]; !ok {
+			conditions[path] = bson.M{}
+			*paths = append(*paths, path)
+		}
+		if _, ok := conditions[path][operator]; ok {
+			return fail("more than one " + op + " condition for " + path)
+		}
+		conditions[path][operator] = condition
+	}
+	return nil
+}
+
+// The operand of a condition, or nil to leave it out
+func filterCondition(op string, value reflect.Value) (interface{}, error) {
+	switch kind := value.Kind(); {
+	case kind == reflect.Map || kind == reflect.Interface || kind == reflect.Func || kind == reflect.Chan:
+		return nil, errors.New("can't filter by " + kind.String() + " values")
+	case (op == "in" || op == "nin") && kind != reflect.Slice && kind != reflect.Array:
+		return nil, errors.New(op + " needs a slice")
+	case op == "exists" && kind != reflect.Bool:
+		return nil, errors.New("exists needs a bool")
+	case (op == "contains" || op == "prefix") && kind != reflect.String:
+		return nil, errors.New(op + " needs a string")
+	}
+
+	switch op {
+	case "in", "nin":
+		if value.Len() == 0 {
+			return nil, nil
+		}
+	case "contains":
+		return primitive.Regex{Pattern: regexp.QuoteMeta(value.String()), Options: "i"}, nil
+	case "prefix":
+		return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(value.String()), Options: "i"}, nil
+	}
+	return value.Interface(), nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+type filterPaging struct {
+	Page int    `filter:"-"`
+	Kind string `filter:"kind"`
+}
+
+type filterRequest struct {
+	filterPaging
+	Status   string    `filter:"status"`
+	Since    time.Time `filter:"created_at,gte"`
+	Until    time.Time `filter:"created_at,lt"`
+	Tags     []string  `filter:"tags,in"`
+	Customer string    `filter:"customer.name,contains"`
+	Paid     *bool     `filter:"paid"`
+	Archived bool      `filter:"archived_at,exists"`
+	Search   string
+}
+
+func TestFilterFromStruct(t *testing.T) {
+	Convey("Filters from structs", t, func() {
+		Convey("should leave out zero values", func() {
+			filter, err := FilterFromStruct(&filterRequest{})
+			So(err, ShouldEqual, nil)
+			So(filter, ShouldResemble, bson.M{})
+
+			filter, err = FilterFromStruct((*filterRequest)(nil))
+			So(err, ShouldEqual, nil)
+			So(filter, ShouldResemble, bson.M{})
+		})
+
+		Convey("should translate the tagged fields", func() {
+			since, until := time.Unix(100, 0), time.Unix(200, 0)
+			paid := false
+			filter, err := FilterFromStruct(&filterRequest{
+				filterPaging: filterPaging{Page: 2, Kind: "retail"},
+				Status:       "open",
+				Since:        since,
+				Until:        until,
+				Tags:         []string{"a", "b"},
+				Customer:     "a.b",
+				Paid:         &paid,
+				Archived:     true,
+				Search:       "ignored",
+			})
+			So(err, ShouldEqual, nil)
+			So(filter, ShouldResemble, bson.M{
+				"kind":          "retail",
+				"status":        "open",
+				"created_at":    bson.M{"$gte": since, "$lt": until},
+				"tags":          bson.M{"$in": []string{"a", "b"}},
+				"customer.name": bson.M{"$regex": primitive.Regex{Pattern: `a\.b`, Options: "i"}},
+				"paid":          false,
+				"archived_at":   bson.M{"$exists": true},
+			})
+		})
+
+		Convey("should reject tags it can't translate", func() {
+			_, err := FilterFromStruct(&struct {
+				Status string `filter:"status,like"`
+			}{Status: "open"})
+			So(err, ShouldHaveSameTypeAs, &FilterTagError{})
+
+			_, err = FilterFromStruct(&struct {
+				Query map[string]interface{} `filter:"status"`
+			}{Query: map[string]interface{}{"$ne": nil}})
+			So(err, ShouldHaveSameTypeAs, &FilterTagError{})
+
+			_, err = FilterFromStruct(&struct {
+				Tags string `filter:"tags,in"`
+			}{Tags: "a"})
+			So(err, ShouldHaveSameTypeAs, &FilterTagError{})
+
+			_, err = FilterFromStruct("status")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"time"
+)
+
+// Options for FindByID, FindOne and Find
+type FindOption func(*findOptions)
+
+type findOptions struct {
+	projection bson.M
+	sort       bson.D
+	maxTime    time.Duration
+
+	// The view of Project, resolved for the type of the found documents
+	view string
+}
+
+// Only fetch the given fields (and the _id). The other fields of the document are left as they are
+func Select(fields ...string) FindOption {
+	return func(o *findOptions) {
+		o.project(fields, 1)
+	}
+}
+
+// Fetch everything but the given fields, e.g. large embedded arrays
+func Exclude(fields ...string) FindOption {
+	return func(o *findOptions) {
+		o.project(fields, 0)
+	}
+}
+
+// Sort by the given fields, descending for fields prefixed with "-", e.g. SortBy("-created_at", "name"). Empty
+// fields are skipped. Ignored by FindByID
+func SortBy(fields ...string) FindOption {
+	return func(o *findOptions) {
+		for _, field := range fields {
+			if len(strings.TrimPrefix(field, "-")) == 0 {
+				continue
+			}
+			if strings.HasPrefix(field, "-") {
+				o.sort = append(o.sort, bson.E{Key: field[1:], Value: -1})
+			} else {
+				o.sort = append(o.sort, bson.E{Key: field, Value: 1})
+			}
+		}
+	}
+}
+
+// Limit the time the server may spend on the query, overriding CollectionDefaults.MaxTime
+func MaxTime(d time.Duration) FindOption {
+	return func(o *findOptions) {
+		o.maxTime = d
+	}
+}
+
+// Returned by saves of documents found with Select, Exclude or Project, which would delete the fields that weren't
+// fetched. Find the whole document to save it, or use Patch for single fields
+type ProjectedDocumentError struct {
+	Collection string
+	ID         primitive.ObjectID
+}
+
+func (e *ProjectedDocumentError) Error() string {
+	return "document " + e.ID.Hex() + " of " + e.Collection + " was found with only some of its fields and can't be saved"
+}
+
+// Implemented by DocumentBase, which remembers whether a document was found with a projection
+type projectionTracker interface {
+	setProjected(bool)
+	isProjected() bool
+}
+
+func markProjected(doc interface{}, projected bool) {
+	if tracker, ok := doc.(projectionTracker); ok {
+		tracker.setProjected(projected)
+	}
+}
+
+func (c *Collection) checkProjected(doc Document) error {
+	if tracker, ok := doc.(projectionTracker); ok && tracker.isProjected() {
+		return &ProjectedDocumentError{Collection: c.Name, ID: doc.GetID()}
+	}
+	return nil
+}
+
+func (o *findOptions) project(fields []string, value int) {
+	if o.projection == nil {
+		o.projection = bson.M{}
+	}
+	for _, field := range fields {
+		o.projection[field] = value
+	}
+}
+
+func newFindOptions(opts []FindOption) *findOptions {
+	o := &findOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
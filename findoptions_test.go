@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestFindOptions(t *testing.T) {
+	Convey("Find options", t, func() {
+		Convey("should build projections", func() {
+			results, _ := (&Collection{}).Find(nil, Select("name", "status"), Exclude("items"))
+			So(results.Query.Projection, ShouldResemble, bson.M{"name": 1, "status": 1, "items": 0})
+
+			results, _ = (&Collection{}).Find(nil)
+			So(results.Query.Projection, ShouldBeNil)
+		})
+
+		Convey("should build sorts", func() {
+			results, _ := (&Collection{}).Find(nil, SortBy("-created_at", "name"))
+			So(results.Query.Sort, ShouldResemble, bson.D{{"created_at", -1}, {"name", 1}})
+
+			results, _ = (&Collection{}).Find(nil, SortBy(""))
+			So(results.Query.Sort, ShouldBeNil)
+		})
+
+		Convey("should refuse to save documents found with a projection", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+			doc := &importDocument{Name: "foo"}
+			doc.SetID(primitive.NewObjectID())
+			markProjected(doc, true)
+
+			err := conn.Collection("tests").Save(doc)
+			So(err, ShouldHaveSameTypeAs, &ProjectedDocumentError{})
+			So(err.Error(), ShouldContainSubstring, doc.GetID().Hex())
+
+			_, err = conn.Collection("tests").Upsert(bson.M{"name": "foo"}, doc, nil)
+			So(err, ShouldHaveSameTypeAs, &ProjectedDocumentError{})
+		})
+	})
+}
+
+func TestProjections(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Projections", t, func() {
+		col := conn.Collection("tests")
+		doc := &importDocument{Name: "foo", Count: 3, Tags: []string{"a", "b"}}
+		So(col.Save(doc), ShouldEqual, nil)
+
+		Convey("should only fetch the selected fields by id", func() {
+			found := &importDocument{}
+			So(col.FindByID(doc.GetID(), found, Select("name")), ShouldEqual, nil)
+			So(found.GetID(), ShouldEqual, doc.GetID())
+			So(found.Name, ShouldEqual, "foo")
+			So(found.Count, ShouldEqual, 0)
+			So(found.Tags, ShouldBeNil)
+			So(col.Save(found), ShouldHaveSameTypeAs, &ProjectedDocumentError{})
+
+			So(col.FindByID(doc.GetID(), found), ShouldEqual, nil)
+			So(col.Save(found), ShouldEqual, nil)
+		})
+
+		Convey("should leave out excluded fields in FindOne", func() {
+			found := &importDocument{}
+			So(col.FindOne(bson.M{"name": "foo"}, found, Exclude("tags")), ShouldEqual, nil)
+			So(found.Count, ShouldEqual, 3)
+			So(found.Tags, ShouldBeNil)
+			So(col.Save(found), ShouldHaveSameTypeAs, &ProjectedDocumentError{})
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+)
+
+// Decodes every document of the result set into a new *T and calls fn with it, running the AfterFind hooks like
+// Next. Stops at the first error (from the query, decoding, a hook, fn or ctx) and returns it. The result set is
+// freed when done:
+//
+//	results, _ := conn.Collection("users").Find(bson.M{"active": true})
+//	err := bongo.ForEach(ctx, results, func(user *User) error {
+//		return notify(user)
+//	})
+func ForEach[T any](ctx context.Context, r *ResultSet, fn func(doc *T) error) error {
+	defer r.Free()
+
+	for {
+		doc := new(T)
+		if !r.next(ctx, doc) {
+			return r.Error
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// Like ForEach, but collects the values returned by fn
+func Map[T any, R any](ctx context.Context, r *ResultSet, fn func(doc *T) (R, error)) ([]R, error) {
+	values := make([]R, 0)
+	err := ForEach(ctx, r, func(doc *T) error {
+		value, err := fn(doc)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type foreachDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string
+	ranAfterFind bool
+}
+
+func (f *foreachDocument) AfterFind(c *Collection) error {
+	if f.Name == "broken" {
+		return errors.New("broken document")
+	}
+	f.ranAfterFind = true
+	return nil
+}
+
+func TestForEach(t *testing.T) {
+	Convey("ForEach and Map", t, func() {
+		docs := []interface{}{bson.M{"name": "foo"}, bson.M{"name": "bar"}, bson.M{"name": "baz"}}
+
+		Convey("should decode every document and run the hooks", func() {
+			names := make([]string, 0)
+			err := ForEach(context.Background(), resultSetFromDocuments(docs...), func(doc *foreachDocument) error {
+				So(doc.ranAfterFind, ShouldBeTrue)
+				So(doc.IsNew(), ShouldBeFalse)
+				names = append(names, doc.Name)
+				return nil
+			})
+			So(err, ShouldEqual, nil)
+			So(names, ShouldResemble, []string{"foo", "bar", "baz"})
+		})
+
+		Convey("should stop at the first error", func() {
+			calls := 0
+			err := ForEach(context.Background(), resultSetFromDocuments(docs...), func(doc *foreachDocument) error {
+				calls++
+				return errors.New("stop")
+			})
+			So(err.Error(), ShouldEqual, "stop")
+			So(calls, ShouldEqual, 1)
+
+			calls = 0
+			broken := []interface{}{bson.M{"name": "foo"}, bson.M{"name": "broken"}, bson.M{"name": "baz"}}
+			err = ForEach(context.Background(), resultSetFromDocuments(broken...), func(doc *foreachDocument) error {
+				calls++
+				return nil
+			})
+			So(err.Error(), ShouldEqual, "broken document")
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("should stop when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			calls := 0
+			err := ForEach(ctx, resultSetFromDocuments(docs...), func(doc *foreachDocument) error {
+				calls++
+				cancel()
+				return nil
+			})
+			So(err, ShouldEqual, context.Canceled)
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("should map documents", func() {
+			names, err := Map(context.Background(), resultSetFromDocuments(docs...), func(doc *foreachDocument) (string, error) {
+				return doc.Name, nil
+			})
+			So(err, ShouldEqual, nil)
+			So(names, ShouldResemble, []string{"foo", "bar", "baz"})
+		})
+	})
+}
@@ -0,0 +1,330 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A secret (like a password) that is only ever stored hashed. Assign the plaintext to it, and it is hashed when
+// the document is saved. Documents loaded from the database only hold the hash, and it is never written to JSON.
+//
+// Values only count as hashed if they were hashed by Hash or decoded from the database, which mark them with a
+// random suffix of the process. Assigned values are always plaintexts, even if they look like hashes, so users
+// can't pick a "password" that is a hash they made up
+type Hashed string
+
+// Limits on the parameters of argon2id hashes, so checking a hash can't take unbounded memory or time
+const (
+	// In KiB (256MB)
+	ARGON2_MAX_MEMORY  = 256 * 1024
+	ARGON2_MAX_TIME    = 16
+	ARGON2_MAX_KEY_LEN = 1024
+)
+
+// Hashes secrets and checks plaintexts against the hashes it produced
+type Hasher interface {
+	Hash(plaintext string) (string, error)
+	Compare(hash string, plaintext string) bool
+
+	// Whether a value is a hash produced by this hasher
+	IsHash(value string) bool
+}
+
+// The hasher used for new hashes. Existing hashes are checked with the hasher that produced them, so this can be
+// changed without invalidating stored secrets
+var DefaultHasher Hasher = &BcryptHasher{Cost: bcrypt.DefaultCost}
+
+var hashers = []Hasher{&BcryptHasher{}, &Argon2Hasher{}}
+
+// Marks hashed values in memory. It is never written anywhere, so it can't be guessed
+var hashedMarker = newHashedMarker()
+
+func newHashedMarker() string {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		panic(err)
+	}
+	return "\x00hashed:" + base64.RawStdEncoding.EncodeToString(random)
+}
+
+// The hash without its marker, if the value is hashed
+func (h Hashed) hash() (string, bool) {
+	if !strings.HasSuffix(string(h), hashedMarker) {
+		return "", false
+	}
+	return strings.TrimSuffix(string(h), hashedMarker), true
+}
+
+// Checks a plaintext against the hash
+func (h Hashed) Compare(plaintext string) bool {
+	hash, ok := h.hash()
+	if !ok {
+		return false
+	}
+	if hasher := hasherFor(hash); hasher != nil {
+		return hasher.Compare(hash, plaintext)
+	}
+	return false
+}
+
+// Whether the value is already hashed (as opposed to a plaintext waiting to be saved)
+func (h Hashed) IsHashed() bool {
+	_, ok := h.hash()
+	return ok
+}
+
+// Hashes the value with the default hasher, unless it is empty or already hashed
+func (h *Hashed) Hash() error {
+	if len(*h) == 0 || h.IsHashed() {
+		return nil
+	}
+	hash, err := DefaultHasher.Hash(string(*h))
+	if err != nil {
+		return err
+	}
+	*h = Hashed(hash + hashedMarker)
+	return nil
+}
+
+// Never prints the value
+func (h Hashed) String() string {
+	if len(h) == 0 {
+		return ""
+	}
+	return "[hashed]"
+}
+
+// Makes sure plaintexts never reach the database, even if the document is written without Collection.Save
+func (h Hashed) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if err := h.Hash(); err != nil {
+		return 0, nil, err
+	}
+	hash, _ := h.hash()
+	return bson.MarshalValue(hash)
+}
+
+// Values loaded from the database are hashes
+func (h *Hashed) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	var value string
+	if err := bson.UnmarshalValue(t, data, &value); err != nil {
+		return err
+	}
+	if len(value) == 0 {
+		*h = ""
+		return nil
+	}
+	*h = Hashed(value + hashedMarker)
+	return nil
+}
+
+// Secrets are write-only in JSON: they can be set (e.g. from a signup form), but are always written as null
+func (h Hashed) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+func hasherFor(value string) Hasher {
+	if DefaultHasher.IsHash(value) {
+		return DefaultHasher
+	}
+	for _, hasher := range hashers {
+		if hasher.IsHash(value) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+var hashedType = reflect.TypeOf(Hashed(""))
+
+var hashedFieldsCache sync.Map
+
+// Hashes the plaintexts of all Hashed fields of a document in place
+func hashFields(doc interface{}) error {
+	v := reflect.ValueOf(doc)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for _, goPath := range hashedFields(v.Type()) {
+		field := fieldByGoPath(v, goPath)
+		if !field.IsValid() {
+			continue
+		}
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+
+		hashed := Hashed(field.String())
+		if err := hashed.Hash(); err != nil {
+			return err
+		}
+		field.SetString(string(hashed))
+	}
+	return nil
+}
+
+// The Go paths of the Hashed fields of a type, cached per type
+func hashedFields(t reflect.Type) []string {
+	if paths, ok := hashedFieldsCache.Load(t); ok {
+		return paths.([]string)
+	}
+
+	paths := make([]string, 0)
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft == hashedType {
+			paths = append(paths, goPath)
+			return false
+		}
+		return true
+	})
+
+	hashedFieldsCache.Store(t, paths)
+	return paths
+}
+
+// Hashes secrets with bcrypt
+type BcryptHasher struct {
+	// Defaults to bcrypt.DefaultCost
+	Cost int
+}
+
+func (b *BcryptHasher) Hash(plaintext string) (string, error) {
+	cost := b.Cost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintext), cost)
+	return string(hash), err
+}
+
+func (b *BcryptHasher) Compare(hash string, plaintext string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plaintext)) == nil
+}
+
+func (b *BcryptHasher) IsHash(value string) bool {
+	if len(value) != 60 {
+		return false
+	}
+	_, err := bcrypt.Cost([]byte(value))
+	return err == nil
+}
+
+// Hashes secrets with argon2id. Hashes are encoded in the PHC string format
+// ($argon2id$v=19$m=65536,t=1,p=4$salt$key), so they can be checked even if the parameters change
+type Argon2Hasher struct {
+	// Defaults to 1 pass over 64MB with 4 threads, a 16 byte salt and a 32 byte key
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+const argon2Prefix = "$argon2id$"
+
+func (a *Argon2Hasher) Hash(plaintext string) (string, error) {
+	time, memory, threads, saltLen, keyLen := a.Time, a.Memory, a.Threads, a.SaltLen, a.KeyLen
+	if time == 0 {
+		time = 1
+	}
+	if memory == 0 {
+		memory = 64 * 1024
+	}
+	if threads == 0 {
+		threads = 4
+	}
+	if saltLen == 0 {
+		saltLen = 16
+	}
+	if keyLen == 0 {
+		keyLen = 32
+	}
+	if time > ARGON2_MAX_TIME || memory > ARGON2_MAX_MEMORY || keyLen > ARGON2_MAX_KEY_LEN {
+		return "", errors.New("argon2id parameters above the limits couldn't be checked")
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plaintext), salt, time, memory, threads, keyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, memory, time, threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *Argon2Hasher) Compare(hash string, plaintext string) bool {
+	time, memory, threads, salt, key, err := parseArgon2Hash(hash)
+	if err != nil {
+		return false
+	}
+	other := argon2.IDKey([]byte(plaintext), salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+func (a *Argon2Hasher) IsHash(value string) bool {
+	_, _, _, _, _, err := parseArgon2Hash(value)
+	return err == nil
+}
+
+func parseArgon2Hash(hash string) (time uint32, memory uint32, threads uint8, salt []byte, key []byte, err error) {
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		return 0, 0, 0, nil, nil, errors.New("not an argon2id hash")
+	}
+
+	parts := strings.Split(hash[len(argon2Prefix):], "$")
+	if len(parts) != 4 {
+		return 0, 0, 0, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[0], "v=%d", &version); err != nil {
+		return
+	}
+	if version != argon2.Version {
+		return 0, 0, 0, nil, nil, errors.New("unsupported argon2 version")
+	}
+	if _, err = fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return
+	}
+	if time == 0 || time > ARGON2_MAX_TIME || threads == 0 || memory < 8*uint32(threads) || memory > ARGON2_MAX_MEMORY {
+		return 0, 0, 0, nil, nil, errors.New("argon2id parameters out of bounds")
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return
+	}
+	if len(key) == 0 || len(key) > ARGON2_MAX_KEY_LEN {
+		err = errors.New("invalid argon2id hash")
+	}
+	return
+}
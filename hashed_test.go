@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+type hashedCredentials struct {
+	Pin *Hashed
+}
+
+type hashedDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string
+	Password     Hashed
+	Credentials  *hashedCredentials
+}
+
+func TestHashed(t *testing.T) {
+	defaultHasher := DefaultHasher
+	DefaultHasher = &BcryptHasher{Cost: bcrypt.MinCost}
+
+	Convey("Hashed", t, func() {
+		Convey("should hash plaintexts and compare against the hash", func() {
+			h := Hashed("secret")
+			So(h.IsHashed(), ShouldEqual, false)
+			So(h.Compare("secret"), ShouldEqual, false)
+
+			So(h.Hash(), ShouldEqual, nil)
+			So(h.IsHashed(), ShouldEqual, true)
+			So(string(h), ShouldNotEqual, "secret")
+			So(h.Compare("secret"), ShouldEqual, true)
+			So(h.Compare("wrong"), ShouldEqual, false)
+
+			// Hashing again doesn't change it
+			hash := h
+			So(h.Hash(), ShouldEqual, nil)
+			So(h, ShouldEqual, hash)
+		})
+
+		Convey("should check hashes of other hashers", func() {
+			h := Hashed("secret")
+			DefaultHasher = &Argon2Hasher{Memory: 1024}
+			So(h.Hash(), ShouldEqual, nil)
+			So(string(h), ShouldStartWith, "$argon2id$v=19$m=1024,t=1,p=4$")
+
+			DefaultHasher = &BcryptHasher{Cost: bcrypt.MinCost}
+			So(h.IsHashed(), ShouldEqual, true)
+			So(h.Compare("secret"), ShouldEqual, true)
+			So(h.Compare("secrets"), ShouldEqual, false)
+		})
+
+		Convey("should never leak the value", func() {
+			doc := &hashedDocument{Name: "foo", Password: "secret"}
+			data, err := json.Marshal(doc)
+			So(err, ShouldEqual, nil)
+			So(string(data), ShouldNotContainSubstring, "secret")
+			So(fmt.Sprint(doc.Password), ShouldEqual, "[hashed]")
+
+			raw, err := bson.Marshal(doc)
+			So(err, ShouldEqual, nil)
+			So(string(raw), ShouldNotContainSubstring, "secret")
+			So(string(raw), ShouldNotContainSubstring, hashedMarker)
+			loaded := &hashedDocument{}
+			So(bson.Unmarshal(raw, loaded), ShouldEqual, nil)
+			So(loaded.Password.IsHashed(), ShouldEqual, true)
+			So(loaded.Password.Compare("secret"), ShouldEqual, true)
+		})
+
+		Convey("should treat assigned hashes as plaintexts", func() {
+			crafted := Hashed("secret")
+			So(crafted.Hash(), ShouldEqual, nil)
+			hash, _ := crafted.hash()
+
+			h := Hashed(hash)
+			So(h.IsHashed(), ShouldEqual, false)
+			So(h.Compare("secret"), ShouldEqual, false)
+			So(h.Hash(), ShouldEqual, nil)
+			So(h.Compare(hash), ShouldEqual, true)
+		})
+
+		Convey("should reject argon2id hashes with parameters above the limits", func() {
+			key := "c29tZWtleXNvbWVrZXk"
+			_, _, _, _, _, err := parseArgon2Hash("$argon2id$v=19$m=4294967295,t=1,p=4$c2FsdHNhbHQ$" + key)
+			So(err, ShouldNotBeNil)
+			_, _, _, _, _, err = parseArgon2Hash("$argon2id$v=19$m=1024,t=100000,p=4$c2FsdHNhbHQ$" + key)
+			So(err, ShouldNotBeNil)
+			_, _, _, _, _, err = parseArgon2Hash("$argon2id$v=19$m=1024,t=1,p=0$c2FsdHNhbHQ$" + key)
+			So(err, ShouldNotBeNil)
+			_, _, _, _, _, err = parseArgon2Hash("$argon2id$v=19$m=1024,t=1,p=4$c2FsdHNhbHQ$" + key)
+			So(err, ShouldEqual, nil)
+
+			_, err = (&Argon2Hasher{Memory: ARGON2_MAX_MEMORY + 1}).Hash("secret")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should hash the fields of a document in place", func() {
+			pin := Hashed("1234")
+			doc := &hashedDocument{Password: "secret", Credentials: &hashedCredentials{Pin: &pin}}
+			So(hashFields(doc), ShouldEqual, nil)
+			So(doc.Password.Compare("secret"), ShouldEqual, true)
+			So(doc.Credentials.Pin.Compare("1234"), ShouldEqual, true)
+
+			// Nil pointers are skipped
+			So(hashFields(&hashedDocument{Password: "secret"}), ShouldEqual, nil)
+		})
+	})
+
+	DefaultHasher = defaultHasher
+}
+
+func TestHashedSave(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Saving hashed fields", t, func() {
+		doc := &hashedDocument{Name: "foo", Password: "secret"}
+		So(conn.Collection("tests").Save(doc), ShouldEqual, nil)
+		So(doc.Password.IsHashed(), ShouldEqual, true)
+
+		loaded := &hashedDocument{}
+		So(conn.Collection("tests").FindByID(doc.GetID(), loaded), ShouldEqual, nil)
+		So(loaded.Password, ShouldEqual, doc.Password)
+		So(loaded.Password.Compare("secret"), ShouldEqual, true)
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
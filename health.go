@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of HealthCheckConfig
+const (
+	defaultHealthCheckInterval  = 10 * time.Second
+	defaultHealthCheckTimeout   = 5 * time.Second
+	defaultHealthCheckThreshold = 3
+	defaultHealthCheckBackoff   = time.Minute
+)
+
+// How the health monitor pings the database, see Config.HealthCheck
+type HealthCheckConfig struct {
+	// Time between pings. Defaults to 10 seconds
+	Interval time.Duration
+
+	// Time a ping, or an attempt to reconnect, may take. Defaults to 5 seconds
+	Timeout time.Duration
+
+	// Consecutive failed pings after which the connection is unhealthy, OnDisconnect runs and a new client is
+	// created. Defaults to 3
+	FailureThreshold int
+
+	// Once unhealthy, the time between attempts doubles up to this. Defaults to a minute
+	MaxBackoff time.Duration
+}
+
+func (h *HealthCheckConfig) withDefaults() *HealthCheckConfig {
+	conf := *h
+	if conf.Interval <= 0 {
+		conf.Interval = defaultHealthCheckInterval
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultHealthCheckTimeout
+	}
+	if conf.FailureThreshold <= 0 {
+		conf.FailureThreshold = defaultHealthCheckThreshold
+	}
+	if conf.MaxBackoff <= 0 {
+		conf.MaxBackoff = defaultHealthCheckBackoff
+	}
+	return &conf
+}
+
+type healthMonitor struct {
+	mutex     sync.Mutex
+	cancel    context.CancelFunc
+	unhealthy atomic.Bool
+	failures  atomic.Int32
+
+	// Replaced by tests
+	ping      func(ctx context.Context) error
+	reconnect func(ctx context.Context) error
+}
+
+// Whether the database answered the last pings of the health monitor. Always true without Config.HealthCheck
+func (m *Connection) Healthy() bool {
+	return !m.health.unhealthy.Load()
+}
+
+// The number of pings of the health monitor that failed in a row
+func (m *Connection) HealthCheckFailures() int {
+	return int(m.health.failures.Load())
+}
+
+// Starts pinging in the background if the config has a HealthCheck. Disconnect stops it
+func (m *Connection) startHealthMonitor() {
+	if m.Config.HealthCheck == nil {
+		return
+	}
+	conf := m.Config.HealthCheck.withDefaults()
+
+	m.health.mutex.Lock()
+	defer m.health.mutex.Unlock()
+	if m.health.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.health.cancel = cancel
+
+	go func() {
+		delay := conf.Interval
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = m.checkHealth(ctx, conf)
+		}
+	}()
+}
+
+// Stops the health monitor, if it runs
+func (m *Connection) stopHealthMonitor() {
+	m.health.mutex.Lock()
+	defer m.health.mutex.Unlock()
+	if m.health.cancel != nil {
+		m.health.cancel()
+		m.health.cancel = nil
+	}
+}
+
+// Pings once, and reconnects if the ping failed too often. Returns the time until the next check
+func (m *Connection) checkHealth(ctx context.Context, conf *HealthCheckConfig) time.Duration {
+	pingCtx, cancel := context.WithTimeout(ctx, conf.Timeout)
+	err := m.pingHealth(pingCtx)
+	cancel()
+
+	if err == nil {
+		m.health.failures.Store(0)
+		m.health.unhealthy.Store(false)
+		m.setAvailable(true)
+		return conf.Interval
+	}
+
+	failures := int(m.health.failures.Add(1))
+	if failures < conf.FailureThreshold {
+		return conf.Interval
+	}
+
+	if !m.health.unhealthy.Swap(true) {
+		m.logf(LOG_ERROR, "database unreachable after %d pings: %v", failures, err)
+	}
+	m.setAvailable(false)
+
+	reconnectCtx, cancel := context.WithTimeout(ctx, conf.Timeout)
+	defer cancel()
+	if err := m.reconnectHealth(reconnectCtx); err != nil {
+		m.logf(LOG_ERROR, "reconnecting failed: %v", err)
+	}
+
+	// Back off exponentially while the database stays unreachable
+	backoff := conf.Interval
+	for i := conf.FailureThreshold; i < failures && backoff < conf.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > conf.MaxBackoff {
+		backoff = conf.MaxBackoff
+	}
+	return backoff
+}
+
+// A client replaced by a reconnect is closed after this, so the operations still running on it can finish
+const reconnectDrainTimeout = time.Minute
+
+// Pings the database through the current client
+func (m *Connection) pingHealth(ctx context.Context) error {
+	if m.health.ping != nil {
+		return m.health.ping(ctx)
+	}
+	return m.Client().Ping(ctx, nil)
+}
+
+// Replaces the client with a new one, if it can reach the database. The old client is closed in the background
+// once the operations running on it had reconnectDrainTimeout to finish
+func (m *Connection) reconnectHealth(ctx context.Context) error {
+	if m.health.reconnect != nil {
+		return m.health.reconnect(ctx)
+	}
+
+	generation := m.clientGenerations.Add(1)
+	client, err := m.newClient(ctx, generation)
+	if err != nil {
+		return err
+	}
+	if err = client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return err
+	}
+
+	m.replaceClient(client, generation)
+	return nil
+}
+
+// Makes client the current one and drains the one it replaces. From now on only the events of client count for
+// the availability of the connection, and as it answered a ping, the connection is healthy again
+func (m *Connection) replaceClient(client *mongo.Client, generation int64) {
+	old := m.Client()
+	m.client.Store(client)
+	m.clientGeneration.Store(generation)
+	m.health.failures.Store(0)
+	m.health.unhealthy.Store(false)
+	m.setAvailable(true)
+
+	if old == nil {
+		return
+	}
+	time.AfterFunc(reconnectDrainTimeout, func() {
+		// Disconnect waits for the connections in use, up to the deadline
+		ctx, cancel := context.WithTimeout(context.Background(), reconnectDrainTimeout)
+		defer cancel()
+		old.Disconnect(ctx)
+	})
+}
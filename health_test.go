@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/address"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"testing"
+	"time"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	Convey("Health monitor", t, func() {
+		calls := make(chan string, 10)
+		conn := &Connection{Config: &Config{
+			OnDisconnect: func(conn *Connection) {
+				calls <- "disconnect"
+			},
+			OnReconnect: func(conn *Connection) {
+				calls <- "reconnect"
+			},
+		}}
+		conf := (&HealthCheckConfig{Interval: time.Second, FailureThreshold: 2, MaxBackoff: 5 * time.Second}).withDefaults()
+
+		var pingErr error
+		reconnects := 0
+		conn.health.ping = func(ctx context.Context) error {
+			return pingErr
+		}
+		var reconnectDeadline bool
+		conn.health.reconnect = func(ctx context.Context) error {
+			reconnects++
+			_, reconnectDeadline = ctx.Deadline()
+			return pingErr
+		}
+
+		next := func() string {
+			select {
+			case call := <-calls:
+				return call
+			case <-time.After(time.Second):
+				return "nothing"
+			}
+		}
+
+		So(conn.Healthy(), ShouldBeTrue)
+		So(conn.checkHealth(context.Background(), conf), ShouldEqual, time.Second)
+
+		Convey("should reconnect with backoff after the failure threshold", func() {
+			pingErr = errors.New("unreachable")
+
+			So(conn.checkHealth(context.Background(), conf), ShouldEqual, time.Second)
+			So(conn.Healthy(), ShouldBeTrue)
+			So(conn.HealthCheckFailures(), ShouldEqual, 1)
+			So(reconnects, ShouldEqual, 0)
+
+			So(conn.checkHealth(context.Background(), conf), ShouldEqual, time.Second)
+			So(conn.Healthy(), ShouldBeFalse)
+			So(reconnects, ShouldEqual, 1)
+			So(reconnectDeadline, ShouldBeTrue)
+			So(next(), ShouldEqual, "disconnect")
+
+			So(conn.checkHealth(context.Background(), conf), ShouldEqual, 2*time.Second)
+			So(conn.checkHealth(context.Background(), conf), ShouldEqual, 4*time.Second)
+			So(conn.checkHealth(context.Background(), conf), ShouldEqual, 5*time.Second)
+			So(conn.checkHealth(context.Background(), conf), ShouldEqual, 5*time.Second)
+			So(reconnects, ShouldEqual, 5)
+			So(next(), ShouldEqual, "nothing")
+
+			Convey("and recover once the pings succeed again", func() {
+				pingErr = nil
+				So(conn.checkHealth(context.Background(), conf), ShouldEqual, time.Second)
+				So(conn.Healthy(), ShouldBeTrue)
+				So(conn.HealthCheckFailures(), ShouldEqual, 0)
+				So(next(), ShouldEqual, "reconnect")
+			})
+		})
+
+		Convey("should use the client that replaced the session", func() {
+			session, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+			So(err, ShouldBeNil)
+			replacement, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+			So(err, ShouldBeNil)
+
+			conn.Session = session
+			So(conn.Client(), ShouldEqual, session)
+			conn.client.Store(replacement)
+			So(conn.Client(), ShouldEqual, replacement)
+			So(conn.Collection("tests").Collection().Database().Client(), ShouldEqual, replacement)
+			So(conn.Session, ShouldEqual, session)
+		})
+
+		Convey("should only follow the events of the current client", func() {
+			oldMonitor := conn.monitorOptions(0).ServerMonitor
+			up := topologyChange(description.Server{Addr: address.Address("a:27017"), Kind: description.Standalone})
+			down := topologyChange(description.Server{Addr: address.Address("a:27017"), Kind: description.Unknown})
+			So(conn.startLifecycle(), ShouldEqual, nil)
+			oldMonitor.TopologyDescriptionChanged(up)
+			oldMonitor.TopologyDescriptionChanged(down)
+			So(next(), ShouldEqual, "disconnect")
+
+			replacement, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+			So(err, ShouldBeNil)
+			generation := conn.clientGenerations.Add(1)
+			newMonitor := conn.monitorOptions(generation).ServerMonitor
+			conn.replaceClient(replacement, generation)
+			So(conn.Client(), ShouldEqual, replacement)
+			So(next(), ShouldEqual, "reconnect")
+
+			// The replaced client flapping while it drains doesn't count
+			oldMonitor.TopologyDescriptionChanged(up)
+			oldMonitor.TopologyDescriptionChanged(down)
+			oldMonitor.TopologyDescriptionChanged(up)
+			newMonitor.TopologyDescriptionChanged(up)
+			So(next(), ShouldEqual, "nothing")
+			So(conn.Healthy(), ShouldBeTrue)
+		})
+
+		Convey("should apply the defaults", func() {
+			conf := (&HealthCheckConfig{}).withDefaults()
+			So(conf.Interval, ShouldEqual, 10*time.Second)
+			So(conf.Timeout, ShouldEqual, 5*time.Second)
+			So(conf.FailureThreshold, ShouldEqual, 3)
+			So(conf.MaxBackoff, ShouldEqual, time.Minute)
+		})
+
+		Convey("should stop when disconnecting", func() {
+			conn.Config.HealthCheck = &HealthCheckConfig{Interval: time.Millisecond}
+			conn.startHealthMonitor()
+			So(conn.health.cancel, ShouldNotBeNil)
+			So(conn.Disconnect(context.Background()), ShouldBeNil)
+			So(conn.health.cancel, ShouldBeNil)
+		})
+	})
+}
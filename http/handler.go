@@ -0,0 +1,557 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+// Package http mounts JSON CRUD endpoints for registered bongo models:
+//
+//	GET    /users        list (paginated with ?page=&per_page= or ?cursor=, filtered by Handler.Filterable fields)
+//	POST   /users        create
+//	GET    /users/{id}   get
+//	PUT    /users/{id}   update (PATCH works the same way, only the top level fields in the body are changed)
+//	DELETE /users/{id}   delete
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-bongo/bongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Actions passed to the authorization callbacks
+const (
+	ActionList   = "list"
+	ActionGet    = "get"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Default limit of request bodies
+const MAX_BODY_BYTES = 1 << 20
+
+// Fields clients can't write unless they are listed in Handler.Writable, since bongo maintains them
+var maintainedFields = []string{"_id", "created_at", "updated_at", "deleted_at", "created_by", "updated_by"}
+
+// Documents can implement this hook to authorize requests on themselves. It is called with the loaded document
+// for get, update and delete, and with the decoded document for create
+type AuthorizeHook interface {
+	Authorize(r *http.Request, action string) error
+}
+
+// Returned by authorization callbacks to reject a request with a 403
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+type Handler struct {
+	Collection *bongo.Collection
+	Model      *bongo.Model
+
+	// Called for every request before the document hook. doc is nil for list requests. Returning an error rejects
+	// the request with a 403. Updates are authorized twice: with the loaded document, and with the document the
+	// body would save, so clients can't hand documents over to someone else
+	Authorize func(r *http.Request, action string, doc bongo.Document) error
+
+	// Bson field names that may be used as equality filters in list query parameters
+	Filterable []string
+
+	// Bson names of the top level fields clients may set on create and update. Other fields of the body are
+	// ignored. Empty means every field except _id, the timestamps and the Blamable fields
+	Writable []string
+
+	// Limit of request bodies, larger ones are rejected with a 413. Defaults to MAX_BODY_BYTES
+	MaxBodyBytes int64
+
+	// Where the errors behind 500 responses are logged, which clients only see as "Internal Server Error".
+	// Defaults to the logger of the connection's config, or the standard logger
+	Logger bongo.Logger
+
+	// Default and maximum page sizes for list requests
+	PerPage    int
+	MaxPerPage int
+}
+
+type ListResponse struct {
+	Data       []bongo.Document      `json:"data"`
+	Pagination *bongo.PaginationInfo `json:"pagination"`
+}
+
+type ErrorResponse struct {
+	Error  string   `json:"error"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Creates a handler for a registered model
+func NewHandler(conn *bongo.Connection, model *bongo.Model) *Handler {
+	return &Handler{
+		Collection: conn.ModelCollection(model),
+		Model:      model,
+		PerPage:    20,
+		MaxPerPage: 100,
+	}
+}
+
+// Mounts a handler on a mux under a prefix like "/users"
+func Mount(mux *http.ServeMux, prefix string, h *Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	handler := http.StripPrefix(prefix, h)
+	mux.Handle(prefix, handler)
+	mux.Handle(prefix+"/", handler)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	collection := h.requestCollection(r)
+	id := strings.Trim(r.URL.Path, "/")
+
+	if len(id) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			h.list(w, r, collection)
+		case http.MethodPost:
+			h.create(w, r, collection)
+		default:
+			h.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		}
+		return
+	}
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, errors.New("invalid id"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, r, collection, oid)
+	case http.MethodPut, http.MethodPatch:
+		h.update(w, r, collection, oid)
+	case http.MethodDelete:
+		h.delete(w, r, collection, oid)
+	default:
+		h.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// The collection bound to the request's context, so operations stop when the client goes away and query guards and
+// hooks see the values middleware put there, like the principal
+func (h *Handler) requestCollection(r *http.Request) *bongo.Collection {
+	if h.Collection == nil {
+		return nil
+	}
+	return h.Collection.WithContext(r.Context())
+}
+
+func (h *Handler) authorize(r *http.Request, action string, doc bongo.Document) error {
+	if h.Authorize != nil {
+		if err := h.Authorize(r, action, doc); err != nil {
+			return err
+		}
+	}
+	if hook, ok := doc.(AuthorizeHook); ok {
+		return hook.Authorize(r, action)
+	}
+	return nil
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, collection *bongo.Collection) {
+	if err := h.authorize(r, ActionList, nil); err != nil {
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	filter, err := h.filter(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := collection.Find(filter)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer results.Free()
+
+	info, err := bongo.PaginationFromRequest(r, h.PerPage, h.MaxPerPage).Paginate(results)
+	if _, ok := err.(*bongo.InvalidPageTokenError); ok {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	bongo.WritePaginationHeaders(w, r, info)
+
+	docs := make([]bongo.Document, 0)
+	for {
+		doc := h.Model.New()
+		if !results.Next(doc) {
+			break
+		}
+		docs = append(docs, doc)
+	}
+	if results.Error != nil {
+		h.writeError(w, http.StatusInternalServerError, results.Error)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, &ListResponse{Data: docs, Pagination: info})
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, collection *bongo.Collection, id primitive.ObjectID) {
+	doc, ok := h.load(w, r, collection, id, ActionGet)
+	if !ok {
+		return
+	}
+	h.writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request, collection *bongo.Collection) {
+	// _id isn't writable, so clients can't pick (and possibly overwrite) an existing document
+	doc := h.Model.New()
+	if !h.decode(w, r, doc) {
+		return
+	}
+
+	if err := h.authorize(r, ActionCreate, doc); err != nil {
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	if err := collection.Save(doc); err != nil {
+		h.writeSaveError(w, r, collection, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, doc)
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request, collection *bongo.Collection, id primitive.ObjectID) {
+	doc, ok := h.load(w, r, collection, id, ActionUpdate)
+	if !ok {
+		return
+	}
+	if !h.decode(w, r, doc) {
+		return
+	}
+
+	if err := h.authorize(r, ActionUpdate, doc); err != nil {
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	if err := collection.Save(doc); err != nil {
+		h.writeSaveError(w, r, collection, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, collection *bongo.Collection, id primitive.ObjectID) {
+	doc, ok := h.load(w, r, collection, id, ActionDelete)
+	if !ok {
+		return
+	}
+
+	if _, err := collection.DeleteDocument(doc); err != nil {
+		h.writeDeleteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Decodes the request body into a fresh document and copies the writable top level fields it contains to doc,
+// writing the error response if that fails
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, doc bongo.Document) bool {
+	limit := h.MaxBodyBytes
+	if limit <= 0 {
+		limit = MAX_BODY_BYTES
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeError(w, http.StatusRequestEntityTooLarge, errors.New("request body too large"))
+		} else {
+			h.writeError(w, http.StatusBadRequest, err)
+		}
+		return false
+	}
+
+	body := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	decoded := h.Model.New()
+	if err := json.Unmarshal(data, decoded); err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	target, source := reflect.ValueOf(doc).Elem(), reflect.ValueOf(decoded).Elem()
+	for goPath, path := range bongo.Fields(doc) {
+		if strings.Contains(goPath, ".") || !h.writable(path) {
+			continue
+		}
+		field, ok := target.Type().FieldByName(goPath)
+		if !ok || !hasJSONKey(body, jsonName(field)) {
+			continue
+		}
+		target.FieldByIndex(field.Index).Set(source.FieldByIndex(field.Index))
+	}
+	return true
+}
+
+func (h *Handler) writable(path string) bool {
+	if len(h.Writable) > 0 {
+		return contains(h.Writable, path)
+	}
+	return !contains(maintainedFields, path)
+}
+
+// The JSON name of a field, or "" if it isn't encoded
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if len(name) == 0 {
+		return field.Name
+	}
+	return name
+}
+
+// Whether a JSON object has a key, matched case insensitively like encoding/json does
+func hasJSONKey(body map[string]json.RawMessage, name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for key := range body {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Loads and authorizes a document, writing the error response if that fails
+func (h *Handler) load(w http.ResponseWriter, r *http.Request, collection *bongo.Collection, id primitive.ObjectID, action string) (bongo.Document, bool) {
+	doc := h.Model.New()
+	if err := collection.FindByID(id, doc); err != nil {
+		if _, ok := err.(*bongo.DocumentNotFoundError); ok {
+			h.writeError(w, http.StatusNotFound, err)
+		} else {
+			h.writeError(w, http.StatusInternalServerError, err)
+		}
+		return nil, false
+	}
+
+	if err := h.authorize(r, action, doc); err != nil {
+		h.writeError(w, http.StatusForbidden, err)
+		return nil, false
+	}
+
+	return doc, true
+}
+
+// Builds an equality filter from the query parameters that name filterable fields
+func (h *Handler) filter(r *http.Request) (bson.M, error) {
+	filter := bson.M{}
+	fields := bongo.Fields(h.Model.New())
+
+	for goPath, path := range fields {
+		if !contains(h.Filterable, path) {
+			continue
+		}
+		raw, ok := r.URL.Query()[path]
+		if !ok || len(raw) == 0 {
+			continue
+		}
+
+		value, err := convert(raw[0], fieldType(h.Model.Type, goPath))
+		if err != nil {
+			return nil, errors.New("invalid value for " + path)
+		}
+		filter[path] = value
+	}
+
+	return filter, nil
+}
+
+// Resolves the type of a (dotted) Go field path
+func fieldType(t reflect.Type, goPath string) reflect.Type {
+	for _, name := range strings.Split(goPath, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return nil
+		}
+		t = field.Type
+	}
+	return t
+}
+
+// Converts a query parameter to the type of the field it filters on
+func convert(raw string, t reflect.Type) (interface{}, error) {
+	if t == nil {
+		return raw, nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(primitive.ObjectID{}) {
+		return primitive.ObjectIDFromHex(raw)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseInt(raw, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, 64)
+	}
+	return raw, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes validation errors in the language of the request (Accept-Language, or the locale of the collection's
+// Context), if the connection has a Translator. Missing references are client errors too, and fields the principal
+// may not write or a ForbiddenError of a hook reject the request with a 403
+func (h *Handler) writeSaveError(w http.ResponseWriter, r *http.Request, collection *bongo.Collection, err error) {
+	var refErr *bongo.ReferenceError
+	if errors.As(err, &refErr) {
+		h.writeError(w, http.StatusUnprocessableEntity, refErr)
+		return
+	}
+
+	var notAllowed *bongo.FieldNotAllowedError
+	var forbidden *ForbiddenError
+	if errors.As(err, &notAllowed) || errors.As(err, &forbidden) {
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	var v *bongo.ValidationError
+	if errors.As(err, &v) {
+		var translator bongo.Translator
+		if collection.Connection != nil && collection.Connection.Config != nil {
+			translator = collection.Connection.Config.Translator
+		}
+
+		locale := requestLocale(r)
+		if len(locale) == 0 {
+			locale = collection.Locale()
+		}
+
+		errs := v.Translate(translator, locale)
+		h.writeJSON(w, http.StatusUnprocessableEntity, &ErrorResponse{Error: "validation failed", Errors: errs})
+		return
+	}
+	h.writeError(w, http.StatusInternalServerError, err)
+}
+
+// Rejects deletes a hook forbids with a 403, and deletes of documents that are still referenced with a 409
+func (h *Handler) writeDeleteError(w http.ResponseWriter, err error) {
+	var forbidden *ForbiddenError
+	if errors.As(err, &forbidden) {
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	var restricted *bongo.RestrictedDeleteError
+	if errors.As(err, &restricted) {
+		h.writeError(w, http.StatusConflict, err)
+		return
+	}
+	h.writeError(w, http.StatusInternalServerError, err)
+}
+
+// The preferred locale of the Accept-Language header, e.g. "de-CH" for "de-CH,de;q=0.9,en;q=0.8"
+func requestLocale(r *http.Request) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := strings.TrimSpace(fields[0])
+		if len(locale) == 0 || locale == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value := strings.TrimSpace(param); strings.HasPrefix(value, "q=") {
+				if parsed, err := strconv.ParseFloat(strings.TrimPrefix(value, "q="), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > bestQ {
+			best, bestQ = locale, q
+		}
+	}
+	return best
+}
+
+// Writes an error response. Server errors are logged, and clients only get a generic message, since the error may
+// contain queries, documents or details of the deployment
+func (h *Handler) writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		h.logger().Printf("%s: %s", h.Model.Collection, err)
+		err = errors.New(http.StatusText(http.StatusInternalServerError))
+	}
+	h.writeJSON(w, status, &ErrorResponse{Error: err.Error()})
+}
+
+// Writes a JSON response. Fields tagged for redaction never leave the server
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	data, err := bongo.MarshalRedactedJSON(body)
+	if err != nil {
+		h.logger().Printf("%s: %s", h.Model.Collection, err)
+		status = http.StatusInternalServerError
+		data, _ = json.Marshal(&ErrorResponse{Error: http.StatusText(http.StatusInternalServerError)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+func (h *Handler) logger() bongo.Logger {
+	if h.Logger != nil {
+		return h.Logger
+	}
+	if h.Collection != nil && h.Collection.Connection != nil {
+		if config := h.Collection.Connection.CurrentConfig(); config != nil && config.Logger != nil {
+			return config.Logger
+		}
+	}
+	return log.New(log.Writer(), "bongo: ", log.LstdFlags)
+}
@@ -0,0 +1,379 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bongo/bongo"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type widget struct {
+	bongo.DocumentBase `bson:",inline"`
+	Name               string `bson:"name" json:"name"`
+	Size               int    `bson:"size" json:"size"`
+	Token              string `bson:"token" json:"token" redact:"true"`
+}
+
+func (w *widget) Validate(c *bongo.Collection) []error {
+	if len(w.Name) == 0 {
+		return []error{errors.New("name is required")}
+	}
+	return nil
+}
+
+func (w *widget) Authorize(r *http.Request, action string) error {
+	if action == ActionDelete && w.Name == "locked" {
+		return &ForbiddenError{"locked"}
+	}
+	return nil
+}
+
+func getConnection() *bongo.Connection {
+	conn, err := bongo.Connect(&bongo.Config{
+		ConnectionString: "mongodb://localhost:27017",
+		Database:         "bongotest",
+	})
+	if err != nil {
+		panic(err)
+	}
+	return conn
+}
+
+func request(h http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestHandlerRouting(t *testing.T) {
+	Convey("Handler routing", t, func() {
+		h := &Handler{Model: bongo.RegisterModel("widgets", &widget{})}
+		mux := http.NewServeMux()
+		Mount(mux, "/widgets", h)
+
+		Convey("should reject invalid ids and methods", func() {
+			So(request(mux, "GET", "/widgets/nope", "").Code, ShouldEqual, http.StatusBadRequest)
+			So(request(mux, "POST", "/widgets/5d0f1d2e9c1f2a0001a1b2c3", "").Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(request(mux, "DELETE", "/widgets", "").Code, ShouldEqual, http.StatusMethodNotAllowed)
+		})
+
+		Convey("should reject unauthorized list requests", func() {
+			h.Authorize = func(r *http.Request, action string, doc bongo.Document) error {
+				return &ForbiddenError{"nope"}
+			}
+			w := request(mux, "GET", "/widgets", "")
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+			So(w.Body.String(), ShouldContainSubstring, "nope")
+		})
+	})
+}
+
+type principalKey struct{}
+
+func TestHandlerRequestContext(t *testing.T) {
+	Convey("Handler request context", t, func() {
+		var principal interface{}
+		conn := &bongo.Connection{Config: &bongo.Config{
+			QueryGuards: map[string]bongo.QueryGuard{
+				"widgets": func(ctx context.Context, filter interface{}) (interface{}, error) {
+					principal = ctx.Value(principalKey{})
+					return nil, errors.New("stop")
+				},
+			},
+		}}
+		h := NewHandler(conn, bongo.RegisterModel("widgets", &widget{}))
+
+		Convey("should run operations in the request's context", func() {
+			r := httptest.NewRequest("GET", "/5d0f1d2e9c1f2a0001a1b2c3", nil)
+			r = r.WithContext(context.WithValue(r.Context(), principalKey{}, "alice"))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(principal, ShouldEqual, "alice")
+			So(h.Collection.Context.Value(principalKey{}), ShouldBeNil)
+		})
+	})
+}
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestHandlerDecoding(t *testing.T) {
+	Convey("Handler decoding", t, func() {
+		h := &Handler{Model: bongo.RegisterModel("widgets", &widget{})}
+		decode := func(doc *widget, body string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			if !h.decode(w, httptest.NewRequest("PUT", "/widgets", strings.NewReader(body)), doc) {
+				return w
+			}
+			return nil
+		}
+
+		Convey("should only change the fields in the body", func() {
+			doc := &widget{Name: "foo", Size: 3}
+			So(decode(doc, `{"size": 5}`), ShouldBeNil)
+			So(doc.Name, ShouldEqual, "foo")
+			So(doc.Size, ShouldEqual, 5)
+
+			So(decode(doc, `{"Name": "bar", "size": 0}`), ShouldBeNil)
+			So(doc.Name, ShouldEqual, "bar")
+			So(doc.Size, ShouldEqual, 0)
+		})
+
+		Convey("should ignore the fields bongo maintains", func() {
+			id := primitive.NewObjectID()
+			doc := &widget{Name: "foo"}
+			doc.ID = id
+			So(decode(doc, `{"id": "5d0f1d2e9c1f2a0001a1b2c3", "created_at": "2019-06-23T12:00:00Z", "name": "bar"}`), ShouldBeNil)
+			So(doc.ID, ShouldEqual, id)
+			So(doc.CreatedAt.IsZero(), ShouldBeTrue)
+			So(doc.Name, ShouldEqual, "bar")
+		})
+
+		Convey("should only write the writable fields", func() {
+			h.Writable = []string{"size"}
+			doc := &widget{Name: "foo"}
+			So(decode(doc, `{"name": "bar", "size": 5, "token": "t0k3n"}`), ShouldBeNil)
+			So(doc.Name, ShouldEqual, "foo")
+			So(doc.Size, ShouldEqual, 5)
+			So(doc.Token, ShouldEqual, "")
+		})
+
+		Convey("should reject invalid and large bodies", func() {
+			So(decode(&widget{}, `{"size": "five"}`).Code, ShouldEqual, http.StatusBadRequest)
+			So(decode(&widget{}, `[]`).Code, ShouldEqual, http.StatusBadRequest)
+
+			h.MaxBodyBytes = 16
+			So(decode(&widget{}, `{"name": "a very long name"}`).Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		})
+
+		Convey("should hide the details of server errors", func() {
+			logger := &testLogger{}
+			h.Logger = logger
+			w := httptest.NewRecorder()
+			h.writeError(w, http.StatusInternalServerError, errors.New("connection to db-7.internal refused"))
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Body.String(), ShouldNotContainSubstring, "db-7")
+			So(w.Body.String(), ShouldContainSubstring, "Internal Server Error")
+			So(logger.lines, ShouldHaveLength, 1)
+			So(logger.lines[0], ShouldContainSubstring, "db-7.internal")
+
+			w = httptest.NewRecorder()
+			h.writeError(w, http.StatusNotFound, errors.New("not found"))
+			So(w.Body.String(), ShouldContainSubstring, "not found")
+			So(logger.lines, ShouldHaveLength, 1)
+		})
+	})
+}
+
+func TestHandlerSaveErrors(t *testing.T) {
+	Convey("Handler save errors", t, func() {
+		logger := &testLogger{}
+		h := &Handler{Model: bongo.RegisterModel("widgets", &widget{}), Logger: logger}
+		collection := &bongo.Collection{Name: "widgets"}
+		write := func(err error) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			h.writeSaveError(w, httptest.NewRequest("POST", "/widgets", nil), collection, err)
+			return w
+		}
+
+		Convey("should return validation errors", func() {
+			w := write(fmt.Errorf("saving: %w", &bongo.ValidationError{Errors: []error{errors.New("name is required")}}))
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+			So(w.Body.String(), ShouldContainSubstring, "name is required")
+		})
+
+		Convey("should return missing references", func() {
+			w := write(&bongo.ReferenceError{Missing: []*bongo.MissingReference{{Field: "owner", Collection: "users", ID: "u1"}}})
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+			So(w.Body.String(), ShouldContainSubstring, "owner (u1 in users)")
+		})
+
+		Convey("should reject fields the principal may not write", func() {
+			w := write(&bongo.FieldNotAllowedError{Collection: "widgets", Field: "token", Use: "write"})
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+			So(w.Body.String(), ShouldContainSubstring, "field token of widgets is not allowed for write")
+		})
+
+		Convey("should reject saves a hook forbids", func() {
+			w := write(fmt.Errorf("before save: %w", &ForbiddenError{"frozen"}))
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+			So(w.Body.String(), ShouldContainSubstring, "frozen")
+		})
+
+		Convey("should hide other errors", func() {
+			w := write(errors.New("connection refused"))
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Body.String(), ShouldNotContainSubstring, "refused")
+		})
+
+		Reset(func() {
+			logger.lines = nil
+		})
+	})
+}
+
+func TestHandlerDeleteErrors(t *testing.T) {
+	Convey("Handler delete errors", t, func() {
+		h := &Handler{Model: bongo.RegisterModel("widgets", &widget{}), Logger: &testLogger{}}
+		write := func(err error) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			h.writeDeleteError(w, err)
+			return w
+		}
+
+		Convey("should reject deletes a hook forbids", func() {
+			w := write(&ForbiddenError{"archived"})
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+			So(w.Body.String(), ShouldContainSubstring, "archived")
+		})
+
+		Convey("should reject deletes of referenced documents", func() {
+			w := write(&bongo.RestrictedDeleteError{Collection: "orders", Field: "widget"})
+			So(w.Code, ShouldEqual, http.StatusConflict)
+			So(w.Body.String(), ShouldContainSubstring, "document is still referenced by orders.widget")
+		})
+
+		Convey("should hide other errors", func() {
+			So(write(errors.New("connection refused")).Code, ShouldEqual, http.StatusInternalServerError)
+		})
+	})
+}
+
+func TestHandlerLogger(t *testing.T) {
+	Convey("Handler logger", t, func() {
+		conn := &bongo.Connection{Config: &bongo.Config{Database: "bongotest"}}
+		h := &Handler{Collection: conn.Collection("widgets")}
+
+		Convey("should use the logger the connection was reconfigured with", func() {
+			logger := &testLogger{}
+			conn.Reconfigure(&bongo.RuntimeOptions{Logger: logger})
+			So(h.logger(), ShouldEqual, logger)
+		})
+	})
+}
+
+func TestRequestLocale(t *testing.T) {
+	Convey("Request locale", t, func() {
+		Convey("should pick the preferred language", func() {
+			r := httptest.NewRequest("POST", "/widgets", nil)
+			So(requestLocale(r), ShouldEqual, "")
+
+			r.Header.Set("Accept-Language", "en;q=0.8, de-CH, de;q=0.9")
+			So(requestLocale(r), ShouldEqual, "de-CH")
+
+			r.Header.Set("Accept-Language", "*, fr;q=0.5")
+			So(requestLocale(r), ShouldEqual, "fr")
+		})
+	})
+}
+
+func TestHandler(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Handler", t, func() {
+		h := NewHandler(conn, bongo.RegisterModel("widgets", &widget{}))
+		h.Filterable = []string{"size"}
+		mux := http.NewServeMux()
+		Mount(mux, "/widgets", h)
+
+		Convey("should create, get, update and delete documents", func() {
+			w := request(mux, "POST", "/widgets", `{"name": "foo", "size": 3, "token": "t0k3n"}`)
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(w.Body.String(), ShouldNotContainSubstring, "t0k3n")
+			created := &widget{}
+			json.Unmarshal(w.Body.Bytes(), created)
+			So(created.ID.IsZero(), ShouldBeFalse)
+
+			path := "/widgets/" + created.ID.Hex()
+			w = request(mux, "GET", path, "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldContainSubstring, `"name":"foo"`)
+			So(w.Body.String(), ShouldNotContainSubstring, "token")
+
+			w = request(mux, "PATCH", path, `{"size": 5}`)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldContainSubstring, `"name":"foo"`)
+			So(w.Body.String(), ShouldContainSubstring, `"size":5`)
+
+			So(request(mux, "DELETE", path, "").Code, ShouldEqual, http.StatusNoContent)
+			So(request(mux, "GET", path, "").Code, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("should authorize the document an update would save", func() {
+			h.Authorize = func(r *http.Request, action string, doc bongo.Document) error {
+				if action == ActionUpdate && doc.(*widget).Name == "taken" {
+					return &ForbiddenError{"taken"}
+				}
+				return nil
+			}
+			w := request(mux, "POST", "/widgets", `{"name": "foo", "created_at": "2019-06-23T12:00:00Z"}`)
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			created := &widget{}
+			json.Unmarshal(w.Body.Bytes(), created)
+			So(created.CreatedAt.Year(), ShouldNotEqual, 2019)
+
+			path := "/widgets/" + created.ID.Hex()
+			So(request(mux, "PATCH", path, `{"name": "taken"}`).Code, ShouldEqual, http.StatusForbidden)
+			So(request(mux, "GET", path, "").Body.String(), ShouldContainSubstring, `"name":"foo"`)
+		})
+
+		Convey("should return validation errors", func() {
+			w := request(mux, "POST", "/widgets", `{"size": 3}`)
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+			So(w.Body.String(), ShouldContainSubstring, "name is required")
+		})
+
+		Convey("should run the document authorization hook", func() {
+			w := request(mux, "POST", "/widgets", `{"name": "locked"}`)
+			created := &widget{}
+			json.Unmarshal(w.Body.Bytes(), created)
+			So(request(mux, "DELETE", "/widgets/"+created.ID.Hex(), "").Code, ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("should list with pagination and filters", func() {
+			for i := 0; i < 5; i++ {
+				request(mux, "POST", "/widgets", `{"name": "foo", "size": `+map[bool]string{true: "1", false: "2"}[i < 3]+`}`)
+			}
+
+			w := request(mux, "GET", "/widgets?per_page=2&page=2", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			list := &struct {
+				Data       []*widget
+				Pagination *bongo.PaginationInfo
+			}{}
+			json.Unmarshal(w.Body.Bytes(), list)
+			So(len(list.Data), ShouldEqual, 2)
+			So(list.Pagination.TotalRecords, ShouldEqual, 5)
+			So(list.Pagination.Current, ShouldEqual, 2)
+			So(w.Header().Get("X-Total-Count"), ShouldEqual, "5")
+			So(w.Header().Get("Link"), ShouldContainSubstring, `rel="next"`)
+
+			w = request(mux, "GET", "/widgets?size=1", "")
+			json.Unmarshal(w.Body.Bytes(), list)
+			So(len(list.Data), ShouldEqual, 3)
+
+			So(request(mux, "GET", "/widgets?size=abc", "").Code, ShouldEqual, http.StatusBadRequest)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
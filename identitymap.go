@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+)
+
+// Keeps the documents loaded during e.g. one request by collection and id, so loading a document again returns the
+// same instance without querying:
+//
+//	users := bongo.NewIdentityMap()
+//	user, err := users.FindByID(ctx, conn.Collection("users"), id, &User{})
+//	same, err := users.FindByID(ctx, conn.Collection("users"), id, &User{}) // user, without a query
+//
+// Saving or deleting a document with the EvictFrom write option (or through a UnitOfWork with an identity map)
+// evicts it, so the next load reads it from the database again. Safe for concurrent use
+type IdentityMap struct {
+	mutex sync.Mutex
+	docs  map[identityKey]Document
+}
+
+type identityKey struct {
+	database   string
+	collection string
+	id         primitive.ObjectID
+}
+
+type identityMapKey struct{}
+
+// Creates an empty identity map
+func NewIdentityMap() *IdentityMap {
+	return &IdentityMap{docs: make(map[identityKey]Document)}
+}
+
+// Attaches an identity map to a context, for IdentityMapFrom, Ref.Get and the writes that take a context
+func WithIdentityMap(ctx context.Context, m *IdentityMap) context.Context {
+	return context.WithValue(ctx, identityMapKey{}, m)
+}
+
+// The identity map attached to a context, or nil
+func IdentityMapFrom(ctx context.Context) *IdentityMap {
+	m, _ := ctx.Value(identityMapKey{}).(*IdentityMap)
+	return m
+}
+
+// Evict saved and deleted documents from an identity map
+func EvictFrom(m *IdentityMap) WriteOption {
+	return func(o *writeOptions) {
+		o.identityMap = m
+	}
+}
+
+func identityOf(collection *Collection, id primitive.ObjectID) identityKey {
+	return identityKey{collection.Database, collection.Name, id}
+}
+
+// Returns the document of the collection with the id, from the map or else loaded into doc (which is then kept).
+// Returns a DocumentNotFoundError when there is no such document
+func (m *IdentityMap) FindByID(ctx context.Context, collection *Collection, id primitive.ObjectID, doc Document) (Document, error) {
+	if cached := m.Get(collection, id); cached != nil {
+		return cached, nil
+	}
+
+	if err := collection.findByID(ctx, id, doc); err != nil {
+		return nil, err
+	}
+
+	// Another goroutine may have loaded it meanwhile, and the first one wins
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := identityOf(collection, id)
+	if cached, ok := m.docs[key]; ok {
+		return cached, nil
+	}
+	m.docs[key] = doc
+	return doc, nil
+}
+
+// The document of the collection with the id, or nil if it isn't in the map
+func (m *IdentityMap) Get(collection *Collection, id primitive.ObjectID) Document {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.docs[identityOf(collection, id)]
+}
+
+// Adds a document, replacing the one with the same id
+func (m *IdentityMap) Put(collection *Collection, doc Document) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.docs[identityOf(collection, doc.GetID())] = doc
+}
+
+// Removes the document of the collection with the id
+func (m *IdentityMap) Evict(collection *Collection, id primitive.ObjectID) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.docs, identityOf(collection, id))
+}
+
+// Removes all documents
+func (m *IdentityMap) Clear() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.docs = make(map[identityKey]Document)
+}
+
+// The number of documents in the map
+func (m *IdentityMap) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.docs)
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestIdentityMapEntries(t *testing.T) {
+	Convey("Identity map", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}}
+		tests, others := conn.Collection("tests"), conn.Collection("others")
+		identityMap := NewIdentityMap()
+
+		doc := &noHookDocument{Name: "a"}
+		doc.ID = primitive.NewObjectID()
+		identityMap.Put(tests, doc)
+
+		Convey("should keep documents per collection", func() {
+			So(identityMap.Get(tests, doc.ID), ShouldEqual, doc)
+			So(identityMap.Get(others, doc.ID), ShouldBeNil)
+			So(identityMap.Get(tests, primitive.NewObjectID()), ShouldBeNil)
+		})
+
+		Convey("should return the kept instance without querying", func() {
+			found, err := identityMap.FindByID(context.Background(), tests, doc.ID, &noHookDocument{})
+			So(err, ShouldEqual, nil)
+			So(found, ShouldEqual, doc)
+		})
+
+		Convey("should evict saved and deleted documents", func() {
+			So(tests.finishSave(doc, newWriteOptions(nil)), ShouldEqual, nil)
+			So(identityMap.Len(), ShouldEqual, 1)
+
+			So(tests.finishSave(doc, newWriteOptions([]WriteOption{EvictFrom(identityMap)})), ShouldEqual, nil)
+			So(identityMap.Len(), ShouldEqual, 0)
+
+			identityMap.Put(tests, doc)
+			So(tests.finishDelete(doc, newWriteOptions([]WriteOption{SkipCascade(), EvictFrom(identityMap)})), ShouldEqual, nil)
+			So(identityMap.Len(), ShouldEqual, 0)
+		})
+
+		Convey("should be attached to contexts", func() {
+			So(IdentityMapFrom(context.Background()), ShouldBeNil)
+			ctx := WithIdentityMap(context.Background(), identityMap)
+			So(IdentityMapFrom(ctx), ShouldEqual, identityMap)
+			So(newWriteOptions(nil).withContext(ctx).identityMap, ShouldEqual, identityMap)
+
+			other := NewIdentityMap()
+			So(newWriteOptions([]WriteOption{EvictFrom(other)}).withContext(ctx).identityMap, ShouldEqual, other)
+		})
+
+		Convey("should belong to units of work", func() {
+			So(conn.NewUnitOfWork(nil).IdentityMap(), ShouldNotBeNil)
+			So(conn.NewUnitOfWork(&UnitOfWorkOptions{IdentityMap: identityMap}).IdentityMap(), ShouldEqual, identityMap)
+		})
+
+		Convey("should clear", func() {
+			identityMap.Clear()
+			So(identityMap.Len(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestIdentityMapFindByID(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Loading through an identity map", t, func() {
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+
+		tests := conn.Collection("tests")
+		doc := &noHookDocument{Name: "a"}
+		So(tests.Save(doc), ShouldEqual, nil)
+
+		identityMap := NewIdentityMap()
+		first, err := identityMap.FindByID(context.Background(), tests, doc.ID, &noHookDocument{})
+		So(err, ShouldEqual, nil)
+		second, err := identityMap.FindByID(context.Background(), tests, doc.ID, &noHookDocument{})
+		So(err, ShouldEqual, nil)
+		So(second, ShouldEqual, first)
+
+		_, err = identityMap.FindByID(context.Background(), tests, primitive.NewObjectID(), &noHookDocument{})
+		So(err, ShouldHaveSameTypeAs, &DocumentNotFoundError{})
+
+		So(tests.Save(first, EvictFrom(identityMap)), ShouldEqual, nil)
+		third, err := identityMap.FindByID(context.Background(), tests, doc.ID, &noHookDocument{})
+		So(err, ShouldEqual, nil)
+		So(third, ShouldNotEqual, first)
+	})
+}
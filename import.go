@@ -0,0 +1,299 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ImportOptions struct {
+	// The document type to decode into. Defaults to the model registered for the collection
+	Model *Model
+
+	// Number of documents per bulk write. Defaults to 500
+	BatchSize int
+
+	// Options for writing each document, e.g. SkipCascade()
+	WriteOptions []WriteOption
+}
+
+// An error for a single line of the input. The line is not imported
+type ImportError struct {
+	Line int
+	Err  error
+}
+
+func (e *ImportError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+type ImportReport struct {
+	// Number of documents written
+	Imported int64
+
+	// Errors for the lines that could not be decoded, validated or written
+	Errors []*ImportError
+}
+
+type importBatch struct {
+	docs  []Document
+	lines []int
+}
+
+// Reads documents from r as NDJSON (extended JSON) or CSV with dotted column names (format is EXPORT_NDJSON or
+// EXPORT_CSV, like the output of ResultSet.Export), decodes them into the model type, runs the validation and save
+// hooks and writes them in batches of upserts. Lines that fail are reported in the import report and don't stop the
+// import. The returned error is only set if the input can't be read
+func (c *Collection) Import(r io.Reader, format int, opts *ImportOptions) (*ImportReport, error) {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+
+	model := opts.Model
+	if model == nil {
+		model = GetModel(c.Name)
+	}
+	if model == nil {
+		return nil, errors.New("no model registered for collection " + c.Name)
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize < 1 {
+		batchSize = 500
+	}
+
+	writeOpts := newWriteOptions(opts.WriteOptions)
+	report := &ImportReport{}
+	batch := &importBatch{}
+
+	add := func(line int, doc Document, err error) {
+		if err == nil {
+			_, err = c.prepareSave(doc, writeOpts)
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, &ImportError{line, err})
+			return
+		}
+
+		batch.docs = append(batch.docs, doc)
+		batch.lines = append(batch.lines, line)
+		if len(batch.docs) >= batchSize {
+			c.writeImportBatch(batch, report, writeOpts)
+			batch = &importBatch{}
+		}
+	}
+
+	var err error
+	switch format {
+	case EXPORT_NDJSON:
+		err = importNDJSON(r, model, add)
+	case EXPORT_CSV:
+		err = importCSV(r, model, add)
+	default:
+		return nil, errors.New("unknown import format " + strconv.Itoa(format))
+	}
+
+	if len(batch.docs) > 0 {
+		c.writeImportBatch(batch, report, writeOpts)
+	}
+
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Line < report.Errors[j].Line
+	})
+
+	return report, err
+}
+
+func importNDJSON(r io.Reader, model *Model, add func(int, Document, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		doc := model.New()
+		add(line, doc, bson.UnmarshalExtJSONWithRegistry(Registry, data, false, doc))
+	}
+
+	return scanner.Err()
+}
+
+func importCSV(r io.Reader, model *Model, add func(int, Document, error)) error {
+	reader := csv.NewReader(r)
+
+	columns, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	types := make(map[string]reflect.Type)
+	walkBsonFields(model.Type, "", func(field reflect.StructField, path string) bool {
+		types[path] = field.Type
+		return true
+	})
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				add(parseErr.StartLine, nil, parseErr.Err)
+				continue
+			}
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		doc := model.New()
+		add(line, doc, decodeCSVRecord(columns, record, types, doc))
+	}
+}
+
+// Decodes a CSV record into a document, converting each cell to the type of the field its column maps to
+func decodeCSVRecord(columns []string, record []string, types map[string]reflect.Type, doc Document) error {
+	values := bson.M{}
+
+	for i, column := range columns {
+		if i >= len(record) || len(record[i]) == 0 {
+			continue
+		}
+
+		value, err := parseCSVValue(record[i], types[column])
+		if err != nil {
+			return errors.New("invalid value for " + column + ": " + err.Error())
+		}
+		setPath(values, column, value)
+	}
+
+	data, err := bson.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return bson.UnmarshalWithRegistry(Registry, data, doc)
+}
+
+// Converts a CSV cell to a value of (or decodable into) the given type. Cells of unknown columns stay strings
+func parseCSVValue(raw string, t reflect.Type) (interface{}, error) {
+	if t == nil {
+		return raw, nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case objectIDType:
+		return primitive.ObjectIDFromHex(raw)
+	case timeType:
+		return time.Parse(time.RFC3339Nano, raw)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseInt(raw, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, 64)
+	}
+
+	// Arrays, maps etc. are written as extended JSON
+	wrapped := bson.M{}
+	if err := bson.UnmarshalExtJSON([]byte(`{"v":`+raw+`}`), false, &wrapped); err != nil {
+		return nil, err
+	}
+	return wrapped["v"], nil
+}
+
+// Sets a value at a dotted path, creating the intermediate documents
+func setPath(doc bson.M, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := doc[key].(bson.M)
+		if !ok {
+			next = bson.M{}
+			doc[key] = next
+		}
+		doc = next
+	}
+	doc[keys[len(keys)-1]] = value
+}
+
+// Writes a batch as unordered upserts and runs the after save hooks of the documents that were written
+func (c *Collection) writeImportBatch(batch *importBatch, report *ImportReport, opts *writeOptions) {
+	models := make([]mongo.WriteModel, len(batch.docs))
+	for i, doc := range batch.docs {
+		models[i] = mongo.NewReplaceOneModel().SetFilter(documentFilter(doc.GetID(), doc)).SetReplacement(doc).SetUpsert(true)
+	}
+
+	err := c.runOperation("import", func(ctx context.Context) error {
+		guarded, err := c.guardModels(ctx, models)
+		if err != nil {
+			return err
+		}
+		_, err = c.Collection().BulkWrite(ctx, guarded, options.BulkWrite().SetOrdered(false))
+		return err
+	})
+
+	failed := make(map[int]error)
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+			for _, writeErr := range bulkErr.WriteErrors {
+				failed[writeErr.Index] = writeErr
+			}
+		} else {
+			for i := range batch.docs {
+				failed[i] = err
+			}
+		}
+	}
+
+	saved := make([]Document, 0, len(batch.docs))
+	for i, doc := range batch.docs {
+		if err, ok := failed[i]; ok {
+			report.Errors = append(report.Errors, &ImportError{batch.lines[i], err})
+			continue
+		}
+		saved = append(saved, doc)
+		if err := c.finishSave(doc, opts); err != nil {
+			report.Errors = append(report.Errors, &ImportError{batch.lines[i], err})
+			continue
+		}
+		report.Imported++
+	}
+
+	if !opts.skipCascade && len(saved) > 0 {
+		toCascade := savedCascadeConfigs(c, saved)
+		c.Connection.goCascade(func() { runCascadeSave(toCascade) })
+	}
+}
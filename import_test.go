@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type importAddress struct {
+	City string
+}
+
+type importDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string
+	Count        int
+	Active       bool
+	Address      importAddress
+	Tags         []string
+	Born         time.Time
+}
+
+func (d *importDocument) Validate(c *Collection) []error {
+	if len(d.Name) == 0 {
+		return []error{errors.New("name is required")}
+	}
+	return nil
+}
+
+func TestImportDecoding(t *testing.T) {
+	Convey("Import decoding", t, func() {
+		types := make(map[string]reflect.Type)
+		walkBsonFields(reflect.TypeOf(importDocument{}), "", func(field reflect.StructField, path string) bool {
+			types[path] = field.Type
+			return true
+		})
+
+		Convey("should convert CSV cells to the field types", func() {
+			doc := &importDocument{}
+			columns := []string{"_id", "name", "count", "active", "address.city", "tags", "born", "unknown"}
+			record := []string{"5d0f4f5fa6d8ba0001a1b2c3", "foo", "3", "true", "Berlin", `["a","b"]`, "2019-06-23T12:00:00Z", "x"}
+
+			So(decodeCSVRecord(columns, record, types, doc), ShouldEqual, nil)
+			So(doc.GetID().Hex(), ShouldEqual, "5d0f4f5fa6d8ba0001a1b2c3")
+			So(doc.Name, ShouldEqual, "foo")
+			So(doc.Count, ShouldEqual, 3)
+			So(doc.Active, ShouldEqual, true)
+			So(doc.Address.City, ShouldEqual, "Berlin")
+			So(doc.Tags, ShouldResemble, []string{"a", "b"})
+			So(doc.Born.Equal(time.Date(2019, 6, 23, 12, 0, 0, 0, time.UTC)), ShouldEqual, true)
+		})
+
+		Convey("should reject cells that don't match the field type", func() {
+			err := decodeCSVRecord([]string{"count"}, []string{"three"}, types, &importDocument{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "count")
+		})
+
+		Convey("should report failing lines without writing anything", func() {
+			col := &Collection{Name: "imports"}
+			model := &Model{Collection: "imports", Type: reflect.TypeOf(importDocument{})}
+
+			input := "{\"name\": \"\"}\n\n{not json}\n"
+			report, err := col.Import(strings.NewReader(input), EXPORT_NDJSON, &ImportOptions{Model: model})
+			So(err, ShouldEqual, nil)
+			So(report.Imported, ShouldEqual, 0)
+			So(len(report.Errors), ShouldEqual, 2)
+			So(report.Errors[0].Line, ShouldEqual, 1)
+			So(report.Errors[0].Error(), ShouldContainSubstring, "name is required")
+			So(report.Errors[1].Line, ShouldEqual, 3)
+
+			input = "name,count\n,1\nfoo,bar\n"
+			report, err = col.Import(strings.NewReader(input), EXPORT_CSV, &ImportOptions{Model: model})
+			So(err, ShouldEqual, nil)
+			So(len(report.Errors), ShouldEqual, 2)
+			So(report.Errors[0].Line, ShouldEqual, 2)
+			So(report.Errors[1].Line, ShouldEqual, 3)
+		})
+
+		Convey("should require a model", func() {
+			_, err := (&Collection{Name: "unregistered"}).Import(strings.NewReader(""), EXPORT_NDJSON, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestImport(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Import", t, func() {
+		col := conn.Collection("imports")
+		model := &Model{Collection: "imports", Type: reflect.TypeOf(importDocument{})}
+
+		Convey("should import NDJSON in batches", func() {
+			input := `{"name": "foo", "count": 1}
+{"name": "bar", "address": {"city": "Berlin"}}
+{"name": ""}
+{"name": "baz"}
+`
+			report, err := col.Import(strings.NewReader(input), EXPORT_NDJSON, &ImportOptions{Model: model, BatchSize: 2})
+			So(err, ShouldEqual, nil)
+			So(report.Imported, ShouldEqual, 3)
+			So(len(report.Errors), ShouldEqual, 1)
+			So(report.Errors[0].Line, ShouldEqual, 3)
+
+			doc := &importDocument{}
+			So(col.FindOne(bson.M{"name": "bar"}, doc), ShouldEqual, nil)
+			So(doc.Address.City, ShouldEqual, "Berlin")
+			So(doc.CreatedAt.IsZero(), ShouldEqual, false)
+		})
+
+		Convey("should import what it exported", func() {
+			col.Save(&importDocument{Name: "foo", Count: 2, Tags: []string{"a"}})
+
+			for _, format := range []int{EXPORT_NDJSON, EXPORT_CSV} {
+				results, _ := col.Find(nil)
+				out := &strings.Builder{}
+				_, err := results.Export(out, format, nil)
+				results.Free()
+				So(err, ShouldEqual, nil)
+
+				report, err := col.Import(strings.NewReader(out.String()), format, &ImportOptions{Model: model})
+				So(err, ShouldEqual, nil)
+				So(report.Imported, ShouldEqual, 1)
+				So(len(report.Errors), ShouldEqual, 0)
+
+				count, _ := col.Collection().CountDocuments(context.Background(), bson.M{})
+				So(count, ShouldEqual, 1)
+			}
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,228 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// An index declared on a model through `index` struct tags.
+//
+// The tag format is `index:"[name][,unique][,sparse][,desc][,text][,ttl=seconds][,language=lang]"`. Fields that
+// share an index name make up a compound index, in field order. Without a name, the index is named after the field.
+//
+// Text indexes stem with the given default language (english if not set). A field tagged with the name of a text
+// index and language_override holds the language of each document, e.g.
+//
+//	Title    string `index:"search,text,language=german"`
+//	Language string `bson:"language" index:"search,language_override"`
+type IndexSpec struct {
+	Name               string
+	Keys               bson.D
+	Unique             bool
+	Sparse             bool
+	ExpireAfterSeconds *int32
+	DefaultLanguage    string
+	LanguageOverride   string
+}
+
+// Parses the index tags of the model's fields
+func (m *Model) Indexes() ([]*IndexSpec, error) {
+	specs := make([]*IndexSpec, 0)
+	byName := make(map[string]*IndexSpec)
+	var err error
+
+	walkBsonFields(m.Type, "", func(field reflect.StructField, path string) bool {
+		tag, ok := field.Tag.Lookup("index")
+		if !ok || err != nil {
+			return err == nil
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if len(name) == 0 {
+			name = path + "_1"
+		}
+
+		spec, exists := byName[name]
+		if !exists {
+			spec = &IndexSpec{Name: name, Keys: bson.D{}}
+			byName[name] = spec
+			specs = append(specs, spec)
+		}
+
+		if stringInSlice("language_override", parts[1:]) {
+			if len(parts[0]) == 0 || len(parts) != 2 {
+				err = errors.New("language_override in index tag of " + m.Type.Name() + "." + field.Name + " needs an index name and no other options")
+				return false
+			}
+			spec.LanguageOverride = path
+			return true
+		}
+
+		var value interface{} = 1
+		for _, opt := range parts[1:] {
+			switch {
+			case opt == "unique":
+				spec.Unique = true
+			case opt == "sparse":
+				spec.Sparse = true
+			case opt == "desc":
+				value = -1
+			case opt == "text":
+				value = "text"
+			case strings.HasPrefix(opt, "ttl="):
+				seconds, e := strconv.Atoi(strings.TrimPrefix(opt, "ttl="))
+				if e != nil {
+					err = errors.New("invalid ttl in index tag of " + m.Type.Name() + "." + field.Name)
+					return false
+				}
+				ttl := int32(seconds)
+				spec.ExpireAfterSeconds = &ttl
+			case strings.HasPrefix(opt, "language="):
+				spec.DefaultLanguage = strings.TrimPrefix(opt, "language=")
+			case len(opt) > 0:
+				err = errors.New("unknown option " + opt + " in index tag of " + m.Type.Name() + "." + field.Name)
+				return false
+			}
+		}
+
+		spec.Keys = append(spec.Keys, bson.E{Key: path, Value: value})
+		return true
+	})
+
+	if err == nil {
+		for _, spec := range specs {
+			if len(spec.Keys) == 0 {
+				return nil, errors.New("index " + spec.Name + " of " + m.Type.Name() + " has no keys")
+			}
+		}
+	}
+
+	return specs, err
+}
+
+// Converts the spec to the driver's index model
+func (s *IndexSpec) IndexModel() mongo.IndexModel {
+	opts := options.Index().SetName(s.Name)
+	if s.Unique {
+		opts.SetUnique(true)
+	}
+	if s.Sparse {
+		opts.SetSparse(true)
+	}
+	if s.ExpireAfterSeconds != nil {
+		opts.SetExpireAfterSeconds(*s.ExpireAfterSeconds)
+	}
+	if len(s.DefaultLanguage) > 0 {
+		opts.SetDefaultLanguage(s.DefaultLanguage)
+	}
+	if len(s.LanguageOverride) > 0 {
+		opts.SetLanguageOverride(s.LanguageOverride)
+	}
+
+	return mongo.IndexModel{Keys: s.Keys, Options: opts}
+}
+
+// Creates the indexes declared on a model, returning the names of the indexes. Indexes that already exist with
+// the same definition are left alone
+func (m *Connection) SyncIndexes(model *Model) ([]string, error) {
+	specs, err := model.Indexes()
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		return []string{}, nil
+	}
+
+	models := make([]mongo.IndexModel, len(specs))
+	for i, spec := range specs {
+		models[i] = spec.IndexModel()
+	}
+
+	collection := m.ModelCollection(model)
+	var names []string
+	err = collection.runOperation("createIndexes", func(ctx context.Context) error {
+		var err error
+		names, err = collection.Collection().Indexes().CreateMany(ctx, models)
+		return err
+	})
+	return names, err
+}
+
+// How the indexes of a collection differ from the indexes declared on its model
+type IndexDrift struct {
+	// Declared indexes that don't exist
+	Missing []*IndexSpec
+
+	// Indexes that exist but aren't declared, other than the _id index
+	Extra []*mongo.IndexSpecification
+
+	// Indexes that exist under a declared name, with other keys or options
+	Mismatched []*IndexMismatch
+}
+
+type IndexMismatch struct {
+	Declared *IndexSpec
+	Existing *mongo.IndexSpecification
+}
+
+// Whether the indexes match the declared ones
+func (d *IndexDrift) InSync() bool {
+	return len(d.Missing) == 0 && len(d.Extra) == 0 && len(d.Mismatched) == 0
+}
+
+// Compares the indexes declared on the model with the indexes of the collection on the server, e.g. at startup or
+// in CI against staging. Collection.Reindex fixes missing and mismatched indexes
+func (c *Collection) IndexDiff(model *Model) (*IndexDrift, error) {
+	specs, err := model.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []*mongo.IndexSpecification
+	err = c.runOperation("listIndexes", func(ctx context.Context) error {
+		var err error
+		existing, err = c.Collection().Indexes().ListSpecifications(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diffIndexes(specs, existing), nil
+}
+
+func diffIndexes(specs []*IndexSpec, existing []*mongo.IndexSpecification) *IndexDrift {
+	drift := &IndexDrift{Missing: []*IndexSpec{}, Extra: []*mongo.IndexSpecification{}, Mismatched: []*IndexMismatch{}}
+	byName := map[string]*mongo.IndexSpecification{}
+	for _, index := range existing {
+		byName[index.Name] = index
+	}
+
+	declared := map[string]bool{"_id_": true}
+	for _, spec := range specs {
+		declared[spec.Name] = true
+		index, ok := byName[spec.Name]
+		switch {
+		case !ok:
+			drift.Missing = append(drift.Missing, spec)
+		case !indexMatches(spec, index):
+			drift.Mismatched = append(drift.Mismatched, &IndexMismatch{Declared: spec, Existing: index})
+		}
+	}
+	for _, index := range existing {
+		if !declared[index.Name] {
+			drift.Extra = append(drift.Extra, index)
+		}
+	}
+	return drift
+}
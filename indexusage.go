@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+	"time"
+)
+
+// Usage statistics for one index, as reported by $indexStats
+type IndexUsage struct {
+	Name     string `bson:"name" json:"name"`
+	Key      bson.D `bson:"key" json:"key"`
+	Host     string `bson:"host" json:"host"`
+	Accesses struct {
+		Ops   int64     `bson:"ops" json:"ops"`
+		Since time.Time `bson:"since" json:"since"`
+	} `bson:"accesses" json:"accesses"`
+
+	Collection *Collection `bson:"-" json:"-"`
+}
+
+// Returns the usage statistics of every index on the collection. Counters are per host and reset when the server
+// restarts, so look at Accesses.Since before pruning anything
+func (c *Collection) IndexUsage() ([]*IndexUsage, error) {
+	pipeline := []bson.M{{"$indexStats": bson.M{}}}
+
+	usage := make([]*IndexUsage, 0)
+	err := c.runOperation("indexStats", func(ctx context.Context) error {
+		usage = usage[:0]
+		cursor, err := c.Collection().Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			u := &IndexUsage{Collection: c}
+			if err := cursor.Decode(u); err != nil {
+				return err
+			}
+			usage = append(usage, u)
+		}
+		return cursor.Err()
+	})
+	return usage, err
+}
+
+// Returns the indexes of the registered models' collections in the configured database that have never been used
+// since the server started tracking them. Views, and the collections of other applications sharing the database,
+// are skipped. The _id index is never reported
+func (m *Connection) UnusedIndexes() ([]*IndexUsage, error) {
+	ctx, cancel := m.operationContext()
+	names, err := m.Client().Database(m.Config.Database).ListCollectionNames(ctx, bson.M{"type": "collection"})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	unused := make([]*IndexUsage, 0)
+	for _, name := range names {
+		if strings.HasPrefix(name, "system.") || GetModel(name) == nil {
+			continue
+		}
+
+		usage, err := m.Collection(name).IndexUsage()
+		if err != nil {
+			return unused, err
+		}
+
+		for _, u := range usage {
+			if u.Name != "_id_" && u.Accesses.Ops == 0 {
+				unused = append(unused, u)
+			}
+		}
+	}
+
+	return unused, nil
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+func TestIndexUsage(t *testing.T) {
+	conn := getConnection()
+	RegisterModel("usage_tests", &noHookDocument{})
+
+	Convey("Index usage", t, func() {
+		col := conn.Collection("usage_tests")
+		So(col.Save(&noHookDocument{Name: "foo"}), ShouldEqual, nil)
+		_, err := col.Collection().Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "name", Value: 1}}},
+			{Keys: bson.D{{Key: "created_at", Value: 1}}},
+		})
+		So(err, ShouldEqual, nil)
+		So(col.FindOne(bson.M{"name": "foo"}, &noHookDocument{}), ShouldEqual, nil)
+
+		Convey("should report usage per index", func() {
+			usage, err := col.IndexUsage()
+			So(err, ShouldEqual, nil)
+			So(len(usage), ShouldEqual, 3)
+
+			for _, u := range usage {
+				So(u.Collection, ShouldEqual, col)
+				if u.Name == "name_1" {
+					So(u.Accesses.Ops, ShouldBeGreaterThan, 0)
+				}
+			}
+		})
+
+		Convey("should report unused indexes across the database", func() {
+			unused, err := conn.UnusedIndexes()
+			So(err, ShouldEqual, nil)
+			So(len(unused), ShouldEqual, 1)
+			So(unused[0].Name, ShouldEqual, "created_at_1")
+			So(unused[0].Collection.Name, ShouldEqual, "usage_tests")
+		})
+
+		Convey("should skip views and the collections of unregistered models", func() {
+			unregistered := conn.Collection("tests")
+			So(unregistered.Save(&noHookDocument{Name: "foo"}), ShouldEqual, nil)
+			_, err := unregistered.Collection().Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bson.D{{Key: "name", Value: 1}}})
+			So(err, ShouldEqual, nil)
+			So(conn.Session.Database("bongotest").CreateView(context.Background(), "usage_view", "usage_tests", mongo.Pipeline{}), ShouldEqual, nil)
+			RegisterModel("usage_view", &noHookDocument{})
+
+			unused, err := conn.UnusedIndexes()
+			So(err, ShouldEqual, nil)
+			So(len(unused), ShouldEqual, 1)
+			So(unused[0].Collection.Name, ShouldEqual, "usage_tests")
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+)
+
+// A JSON:API top level document (https://jsonapi.org/format/)
+type JSONAPIDocument struct {
+	Data  interface{}            `json:"data"`
+	Meta  map[string]interface{} `json:"meta,omitempty"`
+	Links map[string]string      `json:"links,omitempty"`
+}
+
+type JSONAPIResource struct {
+	Type          string                          `json:"type"`
+	ID            string                          `json:"id"`
+	Attributes    map[string]interface{}          `json:"attributes"`
+	Relationships map[string]*JSONAPIRelationship `json:"relationships,omitempty"`
+}
+
+type JSONAPIResourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Data is a *JSONAPIResourceIdentifier for to-one relationships, a []*JSONAPIResourceIdentifier for to-many
+// relationships, or nil for empty to-one relationships
+type JSONAPIRelationship struct {
+	Data interface{} `json:"data"`
+}
+
+// Documents can implement this to declare their JSON:API relationships. Without it, relationships are derived
+// from the document's cascade configs that query related documents by _id
+type JSONAPIRelationshipsHook interface {
+	JSONAPIRelationships(*Collection) map[string]*JSONAPIRelationship
+}
+
+// Builds the JSON:API resource object for a document. The type is the collection name, and the attributes are
+// the document's JSON fields, minus the id and the redacted fields
+func NewJSONAPIResource(c *Collection, doc Document) (*JSONAPIResource, error) {
+	data, err := MarshalRedactedJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]interface{})
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return nil, err
+	}
+	delete(attributes, "id")
+	delete(attributes, "_id")
+
+	resource := &JSONAPIResource{
+		Type:       c.Name,
+		ID:         doc.GetID().Hex(),
+		Attributes: attributes,
+	}
+
+	if hook, ok := doc.(JSONAPIRelationshipsHook); ok {
+		resource.Relationships = hook.JSONAPIRelationships(c)
+	} else if cascading, ok := doc.(CascadingDocument); ok {
+		resource.Relationships = cascadeRelationships(cascading.GetCascade(c))
+	}
+
+	return resource, nil
+}
+
+// Relationships for cascade configs whose query selects related documents by _id
+func cascadeRelationships(configs []*CascadeConfig) map[string]*JSONAPIRelationship {
+	relationships := make(map[string]*JSONAPIRelationship)
+
+	for _, conf := range configs {
+		if conf.Collection == nil {
+			continue
+		}
+		name := conf.Collection.Name
+
+		switch id := conf.Query["_id"].(type) {
+		case primitive.ObjectID:
+			if id.IsZero() {
+				relationships[name] = &JSONAPIRelationship{}
+			} else {
+				relationships[name] = &JSONAPIRelationship{Data: &JSONAPIResourceIdentifier{name, id.Hex()}}
+			}
+		case bson.M:
+			ids, ok := id["$in"].([]primitive.ObjectID)
+			if !ok {
+				continue
+			}
+			identifiers := make([]*JSONAPIResourceIdentifier, len(ids))
+			for i, oid := range ids {
+				identifiers[i] = &JSONAPIResourceIdentifier{name, oid.Hex()}
+			}
+			relationships[name] = &JSONAPIRelationship{Data: identifiers}
+		}
+	}
+
+	if len(relationships) == 0 {
+		return nil
+	}
+	return relationships
+}
+
+// Renders a single document as a JSON:API document
+func MarshalJSONAPI(c *Collection, doc Document) ([]byte, error) {
+	resource, err := NewJSONAPIResource(c, doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&JSONAPIDocument{Data: resource})
+}
+
+// Renders a page of documents as a JSON:API document. If info is given, it is included as meta together with
+// self/first/last/prev/next links built from baseURL using page[number] and page[size] parameters
+func MarshalJSONAPIList(c *Collection, docs []Document, info *PaginationInfo, baseURL string) ([]byte, error) {
+	resources := make([]*JSONAPIResource, len(docs))
+	for i, doc := range docs {
+		resource, err := NewJSONAPIResource(c, doc)
+		if err != nil {
+			return nil, err
+		}
+		resources[i] = resource
+	}
+
+	document := &JSONAPIDocument{Data: resources}
+
+	if info != nil {
+		document.Meta = map[string]interface{}{"pagination": info}
+
+		links, err := paginationLinks(info, baseURL)
+		if err != nil {
+			return nil, err
+		}
+		document.Links = links
+	}
+
+	return json.Marshal(document)
+}
+
+func paginationLinks(info *PaginationInfo, baseURL string) (map[string]string, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return offsetPageLinks(info, base, "page[number]", "page[size]"), nil
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+type jsonapiDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string             `json:"name"`
+	OwnerID      primitive.ObjectID `json:"owner_id"`
+}
+
+func (d *jsonapiDocument) GetCascade(c *Collection) []*CascadeConfig {
+	return []*CascadeConfig{{
+		Collection: c.Connection.Collection("owners"),
+		RelType:    REL_MANY,
+		Query:      bson.M{"_id": d.OwnerID},
+	}}
+}
+
+func TestJSONAPI(t *testing.T) {
+	Convey("JSON:API", t, func() {
+		conn := &Connection{Config: &Config{Database: "test"}}
+		col := conn.Collection("things")
+
+		doc := &jsonapiDocument{Name: "foo", OwnerID: primitive.NewObjectID()}
+		doc.SetID(primitive.NewObjectID())
+
+		Convey("should render a resource with relationships from cascades", func() {
+			data, err := MarshalJSONAPI(col, doc)
+			So(err, ShouldEqual, nil)
+
+			out := map[string]map[string]interface{}{}
+			json.Unmarshal(data, &out)
+			So(out["data"]["type"], ShouldEqual, "things")
+			So(out["data"]["id"], ShouldEqual, doc.ID.Hex())
+
+			attributes := out["data"]["attributes"].(map[string]interface{})
+			So(attributes["name"], ShouldEqual, "foo")
+			So(attributes, ShouldNotContainKey, "id")
+
+			owner := out["data"]["relationships"].(map[string]interface{})["owners"].(map[string]interface{})["data"].(map[string]interface{})
+			So(owner["type"], ShouldEqual, "owners")
+			So(owner["id"], ShouldEqual, doc.OwnerID.Hex())
+		})
+
+		Convey("should render a list with pagination links", func() {
+			info := newPaginationInfo(25, 10, 2)
+			data, err := MarshalJSONAPIList(col, []Document{doc}, info, "https://example.com/things?sort=name")
+			So(err, ShouldEqual, nil)
+
+			out := &struct {
+				Data  []*JSONAPIResource
+				Meta  map[string]interface{}
+				Links map[string]string
+			}{}
+			json.Unmarshal(data, out)
+			So(len(out.Data), ShouldEqual, 1)
+			So(out.Meta, ShouldContainKey, "pagination")
+			So(out.Links["next"], ShouldEqual, "https://example.com/things?page%5Bnumber%5D=3&page%5Bsize%5D=10&sort=name")
+			So(out.Links["prev"], ShouldContainSubstring, "page%5Bnumber%5D=1")
+			So(out.Links["last"], ShouldContainSubstring, "page%5Bnumber%5D=3")
+
+			// Without a count, there is no last page
+			data, _ = MarshalJSONAPIList(col, []Document{doc}, newUncountedPaginationInfo(11, 10, 2), "https://example.com/things")
+			uncounted := &JSONAPIDocument{}
+			json.Unmarshal(data, uncounted)
+			So(uncounted.Links, ShouldNotContainKey, "last")
+			So(uncounted.Links["next"], ShouldContainSubstring, "page%5Bnumber%5D=3")
+		})
+	})
+}
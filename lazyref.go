@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+)
+
+// A reference to a document of a registered model that is stored as its _id, like a plain ObjectID field, and
+// loads the document on first access:
+//
+//	type Child struct {
+//		bongo.DocumentBase `bson:",inline"`
+//		Parent             bongo.Ref[Parent] `bson:"parentId" ref:"parents"`
+//	}
+//
+//	parent, err := child.Parent.Get(ctx, connection)
+//
+// The loaded document is cached until the id changes. Refs are not safe for concurrent use
+type Ref[T any] struct {
+	ID  primitive.ObjectID
+	doc *T
+}
+
+// Creates a reference to the document with the id
+func RefTo[T any](id primitive.ObjectID) Ref[T] {
+	return Ref[T]{ID: id}
+}
+
+// Points the reference to another document, dropping the cached one
+func (r *Ref[T]) SetID(id primitive.ObjectID) {
+	if r.ID != id {
+		r.doc = nil
+	}
+	r.ID = id
+}
+
+// Points the reference to a document (which needs an id) and caches it
+func (r *Ref[T]) Set(doc *T) {
+	if document, ok := interface{}(doc).(Document); ok && doc != nil {
+		r.ID = document.GetID()
+		r.doc = doc
+		return
+	}
+	r.ID = primitive.NilObjectID
+	r.doc = nil
+}
+
+// Whether the referenced document has been loaded
+func (r *Ref[T]) Loaded() bool {
+	return r.doc != nil
+}
+
+// Gets the referenced document from the collection of its registered model, loading it on first access. Returns nil
+// for empty references, and a *DocumentNotFoundError if the document doesn't exist. Goes through the identity map of
+// the context, if any
+func (r *Ref[T]) Get(ctx context.Context, conn *Connection) (*T, error) {
+	if r.doc != nil || r.ID.IsZero() {
+		return r.doc, nil
+	}
+
+	model := modelForType(reflect.TypeOf((*T)(nil)).Elem())
+	if model == nil {
+		return nil, errors.New("no model registered for " + reflect.TypeOf((*T)(nil)).Elem().String())
+	}
+
+	doc := new(T)
+	collection := conn.ModelCollection(model)
+	// Share the instance with the rest of the request
+	if document, ok := any(doc).(Document); ok && IdentityMapFrom(ctx) != nil {
+		found, err := IdentityMapFrom(ctx).FindByID(ctx, collection, r.ID, document)
+		if err != nil {
+			return nil, err
+		}
+		r.doc = any(found).(*T)
+		return r.doc, nil
+	}
+
+	if err := collection.findByID(ctx, r.ID, doc); err != nil {
+		return nil, err
+	}
+	r.doc = doc
+	return doc, nil
+}
+
+// Whether the reference is empty, for omitempty
+func (r Ref[T]) IsZero() bool {
+	return r.ID.IsZero()
+}
+
+// Stores the reference as the id
+func (r Ref[T]) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(r.ID)
+}
+
+func (r *Ref[T]) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	if t == bson.TypeNull || t == bson.TypeUndefined {
+		r.SetID(primitive.NilObjectID)
+		return nil
+	}
+	id, ok := bson.RawValue{Type: t, Value: data}.ObjectIDOK()
+	if !ok {
+		return errors.New("cannot decode " + t.String() + " into a reference")
+	}
+	r.SetID(id)
+	return nil
+}
+
+func (r Ref[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.ID)
+}
+
+func (r *Ref[T]) UnmarshalJSON(data []byte) error {
+	var id primitive.ObjectID
+	if err := json.Unmarshal(data, &id); err != nil {
+		return err
+	}
+	r.SetID(id)
+	return nil
+}
+
+// The model registered for a document type, or nil
+func modelForType(t reflect.Type) *Model {
+	for _, model := range Models() {
+		if model.Type == t {
+			return model
+		}
+	}
+	return nil
+}
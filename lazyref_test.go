@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+type refOwner struct {
+	DocumentBase `bson:",inline"`
+	Name         string `bson:"name"`
+}
+
+type refPet struct {
+	DocumentBase `bson:",inline"`
+	Owner        Ref[refOwner] `bson:"ownerId" json:"ownerId"`
+}
+
+type unregisteredOwner struct {
+	DocumentBase `bson:",inline"`
+}
+
+func TestRef(t *testing.T) {
+	Convey("Lazy references", t, func() {
+		id := primitive.NewObjectID()
+
+		Convey("should be stored as the id", func() {
+			data, err := bson.Marshal(&refPet{Owner: RefTo[refOwner](id)})
+			So(err, ShouldEqual, nil)
+			So(bson.Raw(data).Lookup("ownerId").ObjectID(), ShouldEqual, id)
+
+			pet := &refPet{}
+			So(bson.Unmarshal(data, pet), ShouldEqual, nil)
+			So(pet.Owner.ID, ShouldEqual, id)
+			So(pet.Owner.Loaded(), ShouldBeFalse)
+
+			data, err = json.Marshal(&refPet{Owner: RefTo[refOwner](id)})
+			So(err, ShouldEqual, nil)
+			So(string(data), ShouldContainSubstring, `"ownerId":"`+id.Hex()+`"`)
+
+			pet = &refPet{}
+			So(json.Unmarshal(data, pet), ShouldEqual, nil)
+			So(pet.Owner.ID, ShouldEqual, id)
+		})
+
+		Convey("should cache the document until the id changes", func() {
+			owner := &refOwner{Name: "Ann"}
+			owner.SetID(id)
+
+			ref := Ref[refOwner]{}
+			ref.Set(owner)
+			So(ref.ID, ShouldEqual, id)
+			So(ref.Loaded(), ShouldBeTrue)
+
+			loaded, err := ref.Get(context.Background(), nil)
+			So(err, ShouldEqual, nil)
+			So(loaded, ShouldEqual, owner)
+
+			ref.SetID(id)
+			So(ref.Loaded(), ShouldBeTrue)
+			ref.SetID(primitive.NewObjectID())
+			So(ref.Loaded(), ShouldBeFalse)
+		})
+
+		Convey("should not load empty references", func() {
+			ref := Ref[refOwner]{}
+			loaded, err := ref.Get(context.Background(), nil)
+			So(err, ShouldEqual, nil)
+			So(loaded, ShouldBeNil)
+		})
+
+		Convey("should need a registered model", func() {
+			ref := RefTo[unregisteredOwner](id)
+			_, err := ref.Get(context.Background(), nil)
+			So(err.Error(), ShouldEqual, "no model registered for bongo.unregisteredOwner")
+		})
+	})
+}
+
+func TestRefGet(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Loading lazy references", t, func() {
+		RegisterModel("ref_owners", &refOwner{})
+		owner := &refOwner{Name: "Ann"}
+		So(conn.Collection("ref_owners").Save(owner), ShouldEqual, nil)
+
+		pet := &refPet{Owner: RefTo[refOwner](owner.ID)}
+		loaded, err := pet.Owner.Get(context.Background(), conn)
+		So(err, ShouldEqual, nil)
+		So(loaded.Name, ShouldEqual, "Ann")
+		So(pet.Owner.Loaded(), ShouldBeTrue)
+
+		pet.Owner.SetID(primitive.NewObjectID())
+		_, err = pet.Owner.Get(context.Background(), conn)
+		So(err, ShouldHaveSameTypeAs, &DocumentNotFoundError{})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/event"
+	"sync"
+)
+
+// Availability of the connection for the lifecycle callbacks of the config
+type connectionLifecycle struct {
+	once      sync.Once
+	mutex     sync.Mutex
+	connected bool
+	lost      bool
+	closed    bool
+
+	// Callbacks waiting to run, in order, and whether a goroutine is running them
+	pending []func(*Connection)
+	running bool
+}
+
+// Starts following the availability of the connection for the lifecycle callbacks, and runs OnConnect
+func (m *Connection) startLifecycle() error {
+	m.lifecycle.once.Do(func() {
+		m.OnTopologyChanged(func(e *event.TopologyDescriptionChangedEvent) {
+			connected, _ := topologyState(e)
+			m.setAvailable(connected)
+		})
+	})
+
+	m.lifecycle.mutex.Lock()
+	m.lifecycle.closed = false
+	m.lifecycle.mutex.Unlock()
+
+	if m.Config.OnConnect != nil {
+		return m.Config.OnConnect(m)
+	}
+	return nil
+}
+
+// Records whether any server is available, running OnDisconnect when the last one is lost and OnReconnect when one
+// is found again
+func (m *Connection) setAvailable(available bool) {
+	l := &m.lifecycle
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed || available == l.connected {
+		return
+	}
+	l.connected = available
+
+	if !available {
+		l.lost = true
+		if m.Config.OnDisconnect != nil {
+			m.dispatchLifecycle(m.Config.OnDisconnect)
+		}
+	} else if l.lost {
+		l.lost = false
+		if m.Config.OnReconnect != nil {
+			m.dispatchLifecycle(m.Config.OnReconnect)
+		}
+	}
+}
+
+// Queues a callback. They run one after the other on a goroutine of their own, since availability changes are
+// noticed on the driver's monitoring goroutines, which callbacks querying the database would block. Must be called
+// with the lifecycle mutex held
+func (m *Connection) dispatchLifecycle(fn func(*Connection)) {
+	l := &m.lifecycle
+	l.pending = append(l.pending, fn)
+	if l.running {
+		return
+	}
+	l.running = true
+
+	go func() {
+		for {
+			l.mutex.Lock()
+			if len(l.pending) == 0 {
+				l.running = false
+				l.mutex.Unlock()
+				return
+			}
+			fn := l.pending[0]
+			l.pending = l.pending[1:]
+			l.mutex.Unlock()
+
+			fn(m)
+		}
+	}()
+}
+
+// Flushes the queued writes of write behind caches, closes the connection's client and stops its health monitor,
+// and runs OnDisconnect if it was connected. Returns the error of flushing the writes if closing the client succeeds
+func (m *Connection) Disconnect(ctx context.Context) error {
+	m.stopHealthMonitor()
+	flushErr := m.FlushWrites()
+	m.stopFlushing()
+
+	l := &m.lifecycle
+	l.mutex.Lock()
+	if !l.closed {
+		l.closed = true
+		if l.connected && m.Config.OnDisconnect != nil {
+			m.dispatchLifecycle(m.Config.OnDisconnect)
+		}
+		l.connected = false
+		l.lost = false
+	}
+	l.mutex.Unlock()
+
+	client := m.Client()
+	if client == nil {
+		return flushErr
+	}
+	if err := client.Disconnect(ctx); err != nil {
+		return err
+	}
+	return flushErr
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/mongo/address"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	"testing"
+	"time"
+)
+
+func TestConnectionLifecycle(t *testing.T) {
+	Convey("Connection lifecycle callbacks", t, func() {
+		calls := make(chan string, 10)
+		conn := &Connection{Config: &Config{
+			OnConnect: func(conn *Connection) error {
+				calls <- "connect"
+				return nil
+			},
+			OnDisconnect: func(conn *Connection) {
+				calls <- "disconnect"
+			},
+			OnReconnect: func(conn *Connection) {
+				calls <- "reconnect"
+			},
+		}}
+		monitor := conn.monitorOptions(0).ServerMonitor
+		up := topologyChange(description.Server{Addr: address.Address("a:27017"), Kind: description.Standalone})
+		down := topologyChange(description.Server{Addr: address.Address("a:27017"), Kind: description.Unknown})
+
+		next := func() string {
+			select {
+			case call := <-calls:
+				return call
+			case <-time.After(time.Second):
+				return "nothing"
+			}
+		}
+
+		So(conn.startLifecycle(), ShouldEqual, nil)
+		So(next(), ShouldEqual, "connect")
+
+		Convey("should call OnDisconnect and OnReconnect when the servers come and go", func() {
+			monitor.TopologyDescriptionChanged(up)
+			monitor.TopologyDescriptionChanged(up)
+			monitor.TopologyDescriptionChanged(down)
+			monitor.TopologyDescriptionChanged(down)
+			monitor.TopologyDescriptionChanged(up)
+
+			So(next(), ShouldEqual, "disconnect")
+			So(next(), ShouldEqual, "reconnect")
+			So(len(calls), ShouldEqual, 0)
+		})
+
+		Convey("should call OnDisconnect when disconnecting", func() {
+			monitor.TopologyDescriptionChanged(up)
+			So(conn.Disconnect(context.Background()), ShouldEqual, nil)
+			So(next(), ShouldEqual, "disconnect")
+
+			// The client's own topology changes after closing it don't count
+			monitor.TopologyDescriptionChanged(down)
+			So(conn.Disconnect(context.Background()), ShouldEqual, nil)
+			So(next(), ShouldEqual, "nothing")
+		})
+
+		Convey("should fail connecting when OnConnect fails", func() {
+			conn.Config.OnConnect = func(conn *Connection) error {
+				return errors.New("no indexes")
+			}
+			So(conn.startLifecycle(), ShouldNotBeNil)
+		})
+	})
+}
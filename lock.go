@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// The collection that holds the locks of documents. They are kept apart from the documents, since saves replace
+// whole documents and would drop a lock field
+const LocksCollection = "bongo_locks"
+
+// How long the locks of WithLock last without being renewed
+var LockTTL = 30 * time.Second
+
+// Returned by Lock when another owner holds an unexpired lock on the document
+type LockedError struct {
+	Collection string
+	ID         primitive.ObjectID
+	Owner      string
+	ExpiresAt  time.Time
+}
+
+func (e *LockedError) Error() string {
+	return "document " + e.ID.Hex() + " of " + e.Collection + " is locked by " + e.Owner + " until " + e.ExpiresAt.Format(time.RFC3339)
+}
+
+// Returned by Unlock when the owner doesn't hold the lock (any more)
+type LockNotHeldError struct {
+	Collection string
+	ID         primitive.ObjectID
+	Owner      string
+}
+
+func (e *LockNotHeldError) Error() string {
+	return "the lock on document " + e.ID.Hex() + " of " + e.Collection + " is not held by " + e.Owner
+}
+
+type lockRecord struct {
+	Key        string             `bson:"_id"`
+	Collection string             `bson:"collection"`
+	Document   primitive.ObjectID `bson:"document"`
+	Owner      string             `bson:"owner"`
+	ExpiresAt  time.Time          `bson:"expires_at"`
+}
+
+func (c *Collection) lockKey(id primitive.ObjectID) string {
+	return c.Name + ":" + id.Hex()
+}
+
+func (c *Collection) locks() *Collection {
+	return c.Connection.CollectionFromDatabase(LocksCollection, c.Database)
+}
+
+// Locks the document with the id for owner until ttl has passed, or returns a *LockedError if someone else holds
+// the lock. Locking a document again as the same owner extends the lock. Locks are advisory: only the code that
+// locks documents respects them
+func (c *Collection) Lock(ctx context.Context, id primitive.ObjectID, owner string, ttl time.Duration) error {
+	key := c.lockKey(id)
+	now := time.Now()
+	filter := bson.M{"_id": key, "$or": bson.A{
+		bson.M{"owner": owner},
+		bson.M{"expires_at": bson.M{"$lte": now}},
+	}}
+	update := bson.M{"$set": bson.M{
+		"collection": c.Name,
+		"document":   id,
+		"owner":      owner,
+		"expires_at": now.Add(ttl),
+	}}
+
+	locks := c.locks()
+	err := locks.runOperationContext(ctx, "lock", func(ctx context.Context) error {
+		opts := options.FindOneAndUpdate().SetUpsert(true)
+		return locks.Collection().FindOneAndUpdate(ctx, filter, update, opts).Err()
+	})
+	// The upsert inserts a new lock, which leaves nothing to find
+	if err == nil || err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	// The lock exists, but isn't ours and hasn't expired
+	held := &lockRecord{}
+	err = locks.runOperationContext(ctx, "lock", func(ctx context.Context) error {
+		return locks.Collection().FindOne(ctx, bson.M{"_id": key}).Decode(held)
+	})
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	return &LockedError{Collection: c.Name, ID: id, Owner: held.Owner, ExpiresAt: held.ExpiresAt}
+}
+
+// Releases the lock of owner on the document with the id, or returns a *LockNotHeldError if it doesn't hold it
+func (c *Collection) Unlock(ctx context.Context, id primitive.ObjectID, owner string) error {
+	locks := c.locks()
+	var res *mongo.DeleteResult
+	err := locks.runOperationContext(ctx, "unlock", func(ctx context.Context) error {
+		var err error
+		res, err = locks.Collection().DeleteOne(ctx, bson.M{"_id": c.lockKey(id), "owner": owner})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return &LockNotHeldError{Collection: c.Name, ID: id, Owner: owner}
+	}
+	return nil
+}
+
+// Locks the document with the id, runs fn and unlocks it again, e.g. to serialize the edits of a workflow:
+//
+//	err := orders.WithLock(ctx, id, func(ctx context.Context) error {
+//		order := &Order{}
+//		if err := orders.FindByID(id, order); err != nil {
+//			return err
+//		}
+//		order.Status = "shipped"
+//		return orders.Save(order)
+//	})
+//
+// Returns a *LockedError without running fn if the document is locked. The lock lasts LockTTL and is renewed while
+// fn runs. If renewing fails, the context of fn is canceled
+func (c *Collection) WithLock(ctx context.Context, id primitive.ObjectID, fn func(ctx context.Context) error) error {
+	owner := primitive.NewObjectID().Hex()
+	ttl := LockTTL
+	if err := c.Lock(ctx, id, owner, ttl); err != nil {
+		return err
+	}
+
+	err := withRenewal(ctx, ttl, func(ctx context.Context) error {
+		return c.Lock(ctx, id, owner, ttl)
+	}, fn)
+
+	// Unlock even if ctx is canceled, so the lock doesn't linger until it expires
+	unlockErr := c.Unlock(context.WithoutCancel(ctx), id, owner)
+	if err != nil {
+		return err
+	}
+	return unlockErr
+}
+
+// Runs fn while calling renew every third of the ttl, until fn returns. If renewing fails, the context of fn is
+// canceled
+func withRenewal(ctx context.Context, ttl time.Duration, renew func(ctx context.Context) error, fn func(ctx context.Context) error) error {
+	renewCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := renew(renewCtx); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(renewCtx)
+	close(done)
+	<-renewed
+	return err
+}
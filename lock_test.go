@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestLockErrors(t *testing.T) {
+	Convey("Lock errors", t, func() {
+		id, _ := primitive.ObjectIDFromHex("5d0e6b9b1c9d440000a1b2c3")
+		expires := time.Date(2019, 6, 23, 12, 0, 0, 0, time.UTC)
+
+		err := &LockedError{Collection: "orders", ID: id, Owner: "worker-1", ExpiresAt: expires}
+		So(err.Error(), ShouldEqual, "document 5d0e6b9b1c9d440000a1b2c3 of orders is locked by worker-1 until 2019-06-23T12:00:00Z")
+
+		notHeld := &LockNotHeldError{Collection: "orders", ID: id, Owner: "worker-2"}
+		So(notHeld.Error(), ShouldEqual, "the lock on document 5d0e6b9b1c9d440000a1b2c3 of orders is not held by worker-2")
+
+		col := &Collection{Name: "orders"}
+		So(col.lockKey(id), ShouldEqual, "orders:5d0e6b9b1c9d440000a1b2c3")
+	})
+}
+
+func TestLockRenewal(t *testing.T) {
+	Convey("Lock renewal", t, func() {
+		Convey("should renew until fn returns", func() {
+			renewals := 0
+			err := withRenewal(context.Background(), 30*time.Millisecond, func(ctx context.Context) error {
+				renewals++
+				return nil
+			}, func(ctx context.Context) error {
+				time.Sleep(55 * time.Millisecond)
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(renewals, ShouldBeGreaterThanOrEqualTo, 3)
+			count := renewals
+			time.Sleep(30 * time.Millisecond)
+			So(renewals, ShouldEqual, count)
+		})
+
+		Convey("should cancel fn when renewing fails", func() {
+			err := withRenewal(context.Background(), 30*time.Millisecond, func(ctx context.Context) error {
+				return errors.New("lost")
+			}, func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+			So(err, ShouldEqual, context.Canceled)
+		})
+	})
+}
+
+func TestLock(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Document locks", t, func() {
+		col := conn.Collection("locked_orders")
+		id := primitive.NewObjectID()
+		ctx := context.Background()
+
+		Convey("should only be held by one owner at a time", func() {
+			So(col.Lock(ctx, id, "a", time.Minute), ShouldBeNil)
+			So(col.Lock(ctx, id, "a", time.Minute), ShouldBeNil)
+
+			err := col.Lock(ctx, id, "b", time.Minute)
+			So(err, ShouldHaveSameTypeAs, &LockedError{})
+			So(err.(*LockedError).Owner, ShouldEqual, "a")
+
+			So(col.Unlock(ctx, id, "b"), ShouldHaveSameTypeAs, &LockNotHeldError{})
+			So(col.Unlock(ctx, id, "a"), ShouldBeNil)
+			So(col.Lock(ctx, id, "b", time.Minute), ShouldBeNil)
+		})
+
+		Convey("should be taken over once expired", func() {
+			So(col.Lock(ctx, id, "a", time.Millisecond), ShouldBeNil)
+			time.Sleep(10 * time.Millisecond)
+			So(col.Lock(ctx, id, "b", time.Minute), ShouldBeNil)
+			So(col.Unlock(ctx, id, "a"), ShouldHaveSameTypeAs, &LockNotHeldError{})
+		})
+
+		Convey("should be held while WithLock runs", func() {
+			err := col.WithLock(ctx, id, func(ctx context.Context) error {
+				return col.Lock(ctx, id, "b", time.Minute)
+			})
+			So(err, ShouldHaveSameTypeAs, &LockedError{})
+			So(col.Lock(ctx, id, "b", time.Minute), ShouldBeNil)
+
+			err = col.WithLock(ctx, id, func(ctx context.Context) error {
+				return errors.New("not run")
+			})
+			So(err, ShouldHaveSameTypeAs, &LockedError{})
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// The collection of the named locks of Lockers
+const LockerCollection = "bongo_locker"
+
+type LockerOptions struct {
+	// Who holds the locks, e.g. the host name. Defaults to a random id, unique to the Locker
+	Owner string
+
+	// How long a lock lasts unless it is renewed. Defaults to LockTTL
+	TTL time.Duration
+}
+
+// Named locks shared by all processes using the database, e.g. to run a job on one instance of a service only:
+//
+//	locker := conn.NewLocker(&bongo.LockerOptions{Owner: hostname})
+//	err := locker.Do(ctx, "nightly-report", func(ctx context.Context, lease *bongo.Lease) error {
+//		return writeReport(ctx, lease.Token)
+//	})
+type Locker struct {
+	collection *Collection
+	owner      string
+	ttl        time.Duration
+}
+
+// A held lock. Its Token increases with every acquisition of the name, so the resources a holder writes to can
+// reject the writes of holders whose lock has expired in the meantime (fencing)
+type Lease struct {
+	Name      string
+	Owner     string
+	Token     int64
+	ExpiresAt time.Time
+
+	locker *Locker
+}
+
+// Returned by Acquire when someone else holds the lock
+type LeaseHeldError struct {
+	Name      string
+	Owner     string
+	ExpiresAt time.Time
+}
+
+func (e *LeaseHeldError) Error() string {
+	return "lock " + e.Name + " is held by " + e.Owner + " until " + e.ExpiresAt.Format(time.RFC3339)
+}
+
+// Returned by Renew and Release when the lock expired and may have been acquired by someone else
+type LeaseLostError struct {
+	Name  string
+	Token int64
+}
+
+func (e *LeaseLostError) Error() string {
+	return "lock " + e.Name + " was lost"
+}
+
+type leaseRecord struct {
+	Name      string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	Token     int64     `bson:"token"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// Creates a locker on the LockerCollection of the connection's database
+func (m *Connection) NewLocker(opts *LockerOptions) *Locker {
+	if opts == nil {
+		opts = &LockerOptions{}
+	}
+	locker := &Locker{
+		collection: m.Collection(LockerCollection),
+		owner:      opts.Owner,
+		ttl:        opts.TTL,
+	}
+	if len(locker.owner) == 0 {
+		locker.owner = primitive.NewObjectID().Hex()
+	}
+	if locker.ttl <= 0 {
+		locker.ttl = LockTTL
+	}
+	return locker
+}
+
+// Creates the TTL index that removes expired locks. Fencing tokens are kept apart and survive their locks
+func (l *Locker) EnsureIndexes(ctx context.Context) error {
+	return l.collection.runOperationContext(ctx, "createIndex", func(ctx context.Context) error {
+		_, err := l.collection.Collection().Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{"expires_at", 1}},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		})
+		return err
+	})
+}
+
+// Acquires the named lock, or returns a *LeaseHeldError if someone else (or this locker) holds it
+func (l *Locker) Acquire(ctx context.Context, name string) (*Lease, error) {
+	token, err := l.nextToken(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	lease := &Lease{Name: name, Owner: l.owner, Token: token, ExpiresAt: now.Add(l.ttl), locker: l}
+	filter := bson.M{"_id": name, "expires_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"owner": lease.Owner, "token": lease.Token, "expires_at": lease.ExpiresAt}}
+
+	// Not retried: a retry of an upsert that was applied would find the lock held, by this locker
+	err = l.collection.runOperationOnce(ctx, "acquire", func(ctx context.Context) error {
+		_, err := l.collection.Collection().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		return err
+	})
+	if err == nil {
+		return lease, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	held := &leaseRecord{}
+	err = l.collection.runOperationContext(ctx, "acquire", func(ctx context.Context) error {
+		return l.collection.Collection().FindOne(ctx, bson.M{"_id": name}).Decode(held)
+	})
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	return nil, &LeaseHeldError{Name: name, Owner: held.Owner, ExpiresAt: held.ExpiresAt}
+}
+
+// Counts the acquisitions of a name. The counters have no expires_at, so the TTL index leaves them alone. Like
+// the increments of counters, it is not retried, so it never skips a token
+func (l *Locker) nextToken(ctx context.Context, name string) (int64, error) {
+	counter := struct {
+		Token int64 `bson:"token"`
+	}{}
+	err := l.collection.runOperationOnce(ctx, "acquire", func(ctx context.Context) error {
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+		return l.collection.Collection().FindOneAndUpdate(ctx, bson.M{"_id": bson.M{"counter": name}}, bson.M{"$inc": bson.M{"token": 1}}, opts).Decode(&counter)
+	})
+	return counter.Token, err
+}
+
+// Runs fn while holding the named lock, renewing it until fn returns. If renewing fails, the context of fn is
+// canceled. Returns a *LeaseHeldError without running fn if the lock is held
+func (l *Locker) Do(ctx context.Context, name string, fn func(ctx context.Context, lease *Lease) error) error {
+	lease, err := l.Acquire(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	err = withRenewal(ctx, l.ttl, lease.Renew, func(ctx context.Context) error {
+		return fn(ctx, lease)
+	})
+
+	releaseErr := lease.Release(context.WithoutCancel(ctx))
+	if err != nil {
+		return err
+	}
+	return releaseErr
+}
+
+// Extends the lock by the locker's TTL, or returns a *LeaseLostError if it expired and was taken over
+func (l *Lease) Renew(ctx context.Context) error {
+	expires := time.Now().Add(l.locker.ttl)
+	var res *mongo.UpdateResult
+	err := l.locker.collection.runOperationContext(ctx, "renew", func(ctx context.Context) error {
+		var err error
+		res, err = l.locker.collection.Collection().UpdateOne(ctx, l.filter(), bson.M{"$set": bson.M{"expires_at": expires}})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return &LeaseLostError{Name: l.Name, Token: l.Token}
+	}
+	l.ExpiresAt = expires
+	return nil
+}
+
+// Releases the lock, or returns a *LeaseLostError if it expired and was taken over
+func (l *Lease) Release(ctx context.Context) error {
+	var res *mongo.DeleteResult
+	err := l.locker.collection.runOperationContext(ctx, "release", func(ctx context.Context) error {
+		var err error
+		res, err = l.locker.collection.Collection().DeleteOne(ctx, l.filter())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return &LeaseLostError{Name: l.Name, Token: l.Token}
+	}
+	return nil
+}
+
+func (l *Lease) filter() bson.M {
+	return bson.M{"_id": l.Name, "owner": l.Owner, "token": l.Token}
+}
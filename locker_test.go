@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+func TestNewLocker(t *testing.T) {
+	Convey("NewLocker", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}}
+
+		Convey("should apply the defaults", func() {
+			locker := conn.NewLocker(nil)
+			So(len(locker.owner), ShouldEqual, 24)
+			So(locker.ttl, ShouldEqual, LockTTL)
+			So(locker.collection.Name, ShouldEqual, LockerCollection)
+			So(conn.NewLocker(nil).owner, ShouldNotEqual, locker.owner)
+		})
+
+		Convey("should use the options", func() {
+			locker := conn.NewLocker(&LockerOptions{Owner: "web-1", TTL: time.Minute})
+			So(locker.owner, ShouldEqual, "web-1")
+			So(locker.ttl, ShouldEqual, time.Minute)
+		})
+
+		Convey("should describe its errors", func() {
+			expires := time.Date(2019, 6, 23, 12, 0, 0, 0, time.UTC)
+			So((&LeaseHeldError{Name: "report", Owner: "web-1", ExpiresAt: expires}).Error(), ShouldEqual, "lock report is held by web-1 until 2019-06-23T12:00:00Z")
+			So((&LeaseLostError{Name: "report", Token: 3}).Error(), ShouldEqual, "lock report was lost")
+		})
+	})
+}
+
+func TestLocker(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Locker", t, func() {
+		ctx := context.Background()
+		first := conn.NewLocker(&LockerOptions{Owner: "first", TTL: time.Minute})
+		second := conn.NewLocker(&LockerOptions{Owner: "second", TTL: time.Minute})
+		So(first.EnsureIndexes(ctx), ShouldBeNil)
+
+		Convey("should hand out a lock once at a time, with increasing tokens", func() {
+			lease, err := first.Acquire(ctx, "job")
+			So(err, ShouldBeNil)
+			So(lease.Token, ShouldEqual, 1)
+
+			_, err = second.Acquire(ctx, "job")
+			So(err, ShouldHaveSameTypeAs, &LeaseHeldError{})
+			So(err.(*LeaseHeldError).Owner, ShouldEqual, "first")
+
+			So(lease.Renew(ctx), ShouldBeNil)
+			So(lease.Release(ctx), ShouldBeNil)
+			So(lease.Release(ctx), ShouldHaveSameTypeAs, &LeaseLostError{})
+
+			next, err := second.Acquire(ctx, "job")
+			So(err, ShouldBeNil)
+			So(next.Token, ShouldBeGreaterThan, lease.Token)
+		})
+
+		Convey("should let expired locks be taken over", func() {
+			short := conn.NewLocker(&LockerOptions{Owner: "short", TTL: time.Millisecond})
+			lease, err := short.Acquire(ctx, "job")
+			So(err, ShouldBeNil)
+			time.Sleep(10 * time.Millisecond)
+
+			_, err = second.Acquire(ctx, "job")
+			So(err, ShouldBeNil)
+			So(lease.Renew(ctx), ShouldHaveSameTypeAs, &LeaseLostError{})
+		})
+
+		Convey("should hold the lock while Do runs", func() {
+			err := first.Do(ctx, "job", func(ctx context.Context, lease *Lease) error {
+				_, err := second.Acquire(ctx, "job")
+				return err
+			})
+			So(err, ShouldHaveSameTypeAs, &LeaseHeldError{})
+
+			_, err = second.Acquire(ctx, "job")
+			So(err, ShouldBeNil)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
+
+// Makes the next runs of a command apply their write and then fail with a retryable write concern error, like a
+// reply lost to a stepdown. Twice, so the driver's own retry fails too. Needs a server with test commands enabled
+func failAfterWrite(conn *Connection, command string) error {
+	return conn.Session.Database("admin").RunCommand(context.Background(), bson.D{
+		{"configureFailPoint", "failCommand"},
+		{"mode", bson.M{"times": 2}},
+		{"data", bson.M{
+			"failCommands":      bson.A{command},
+			"writeConcernError": bson.M{"code": 91, "errmsg": "shutdown in progress"},
+			"errorLabels":       bson.A{"RetryableWriteError"},
+		}},
+	}).Err()
+}
+
+func TestLockerRetries(t *testing.T) {
+	conn, err := Connect(&Config{
+		ConnectionString: "mongodb://localhost:27017",
+		Database:         "bongotest",
+		RetryPolicy:      &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	Convey("Locker with a RetryPolicy", t, func() {
+		ctx := context.Background()
+		locker := conn.NewLocker(&LockerOptions{Owner: "first", TTL: time.Minute})
+
+		Convey("should not take its own lock for someone else's when an applied acquisition fails", func() {
+			So(failAfterWrite(conn, "update"), ShouldBeNil)
+
+			_, err := locker.Acquire(ctx, "job")
+			So(err, ShouldNotBeNil)
+			So(err, ShouldNotHaveSameTypeAs, &LeaseHeldError{})
+		})
+
+		Convey("should not skip fencing tokens when an applied increment fails", func() {
+			So(failAfterWrite(conn, "findAndModify"), ShouldBeNil)
+
+			_, err := locker.nextToken(ctx, "job")
+			So(err, ShouldNotBeNil)
+
+			token, err := locker.nextToken(ctx, "job")
+			So(err, ShouldBeNil)
+			So(token, ShouldEqual, 2)
+		})
+
+		Reset(func() {
+			conn.Session.Database("admin").RunCommand(context.Background(), bson.D{{"configureFailPoint", "failCommand"}, {"mode", "off"}})
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"log"
+)
+
+// Anything that can print log lines (*log.Logger, logrus etc.)
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Returns the configured logger, falling back to the standard logger
+func (m *Connection) logger() Logger {
+	if config := m.config(); config != nil && config.Logger != nil {
+		return config.Logger
+	}
+	return log.New(log.Writer(), "bongo: ", log.LstdFlags)
+}
+
+// How important a log line of bongo is, see Config.LogLevel
+type LogLevel int
+
+// The log levels, from the most to the least verbose
+const (
+	// Auto migrations and queries that couldn't be explained
+	LOG_DEBUG LogLevel = iota
+	// Query and model lint findings
+	LOG_INFO
+	// Slow operations and oversized documents
+	LOG_WARN
+	// Failed background work, e.g. flushes of queued writes or reconnecting
+	LOG_ERROR
+	// Nothing
+	LOG_OFF
+)
+
+// Prints a log line with the configured logger, unless its level is below Config.LogLevel
+func (m *Connection) logf(level LogLevel, format string, v ...interface{}) {
+	if config := m.config(); config != nil && level < config.LogLevel {
+		return
+	}
+	m.logger().Printf(format, v...)
+}
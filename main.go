@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,16 +19,111 @@ type Config struct {
 	ConnectionString string
 	Database         string
 	ClientOptions    *options.ClientOptions
+	// Retry operations that fail with transient errors (elections, network blips). Nil disables retries
+	RetryPolicy *RetryPolicy
+	// Fail fast with a *CircuitOpenError while the cluster is struggling. Nil disables the circuit breaker
+	CircuitBreaker *CircuitBreakerConfig
+	// Throttle operations on each collection. Nil disables rate limiting
+	RateLimit *RateLimitConfig
+	// Per-collection overrides for RateLimit, keyed by collection name
+	CollectionRateLimits map[string]*RateLimitConfig
+	// Where bongo logs warnings. Defaults to the standard logger
+	Logger Logger
+	// The least important lines that are logged. Defaults to LOG_DEBUG, all of them
+	LogLevel LogLevel
+	// Explain every new query shape and log full collection scans, in-memory sorts and missing indexes.
+	// Meant for development, as it costs an extra round trip per new query shape
+	QueryLinting bool
+	// Fail operations that take longer than this (including retries) instead of blocking forever on a hung
+	// server. Zero means no timeout. Can be overridden per collection with Collection.WithTimeout
+	OperationTimeout time.Duration
+	// Signs the tokens of ResultSet.PaginateWithToken, so clients can't forge positions
+	PageTokenSecret []byte
+	// Translates the FieldErrors of validation errors, see ValidationError.Translate
+	Translator Translator
+	// Referential integrity of the references declared with `ref` tags, keyed by collection name
+	References map[string]*ReferenceConfig
+	// Fields clients may sort and project on, keyed by collection name
+	FieldAccess map[string]*FieldAccessConfig
+	// Read preference, concerns, max time, sort and page size of the operations on each collection, keyed by
+	// collection name
+	CollectionDefaults map[string]*CollectionDefaults
+	// Called by Connect once the client is connected, e.g. to warm caches or create indexes. An error fails Connect
+	OnConnect func(conn *Connection) error
+	// Called when no server is reachable any more, and by Disconnect. Together with OnReconnect, this can flip the
+	// readiness of a service. Both run on a goroutine of their own, one after the other
+	OnDisconnect func(conn *Connection)
+	// Called when a server is reachable again after OnDisconnect
+	OnReconnect func(conn *Connection)
+	// Ping the database in the background and reconnect when it stays unreachable. Nil disables the health monitor
+	HealthCheck *HealthCheckConfig
+	// Rewrite the filters of the finds, updates and deletes on each collection, keyed by collection name
+	QueryGuards map[string]QueryGuard
+	// Which documents Connection.Archive moves to archives, keyed by collection name
+	ArchivalPolicies map[string]*ArchivalPolicy
+	// Which documents Connection.RunRetention deletes, keyed by collection name
+	RetentionRules map[string][]*RetentionRule
+	// Check the size of documents before they are saved, instead of failing on the server. Nil disables the check
+	DocumentSize *DocumentSizeConfig
+	// Log operations that take longer than this, including retries and waiting for the rate limiter. Zero disables
+	// the log
+	SlowOperationThreshold time.Duration
+	// Serve FindByID and FindOne from the last copy found while the database is unavailable. Nil disables
+	// degraded reads
+	DegradedReads *DegradedReadConfig
+	// Cache the documents of each collection by _id, keyed by collection name
+	Caches map[string]*CacheConfig
+	// The size of the MemoryCache shared by the cached collections without a Cache of their own. Defaults to
+	// CACHE_MAX_ENTRIES
+	CacheMaxEntries int
+	// How many cascades run at once in the background, after saves and deletes. Zero means no limit
+	CascadeConcurrency int
 }
 
 // var EncryptionKey [32]byte
 // var EnableEncryption bool
 
 type Connection struct {
-	Config  *Config
+	Config *Config
+	// The client made by Connect. The health monitor replaces it when it reconnects, see Client
 	Session *mongo.Client
 	// collection []Collection
 	Context *Context
+
+	breakerMutex sync.Mutex
+	breakers     map[string]*CircuitBreaker
+	limiterMutex sync.Mutex
+	limiters     map[string]*RateLimiter
+	callbacks    eventCallbacks
+	events       connectionEvents
+	lintMutex    sync.Mutex
+	lintedShapes map[string]bool
+	lifecycle    connectionLifecycle
+	health       healthMonitor
+	metrics      operationMetrics
+	pool         poolCounters
+	degraded     degradedCache
+	cascades     cascadeLimiter
+	caches       connectionCaches
+	// The config from the last Reconfigure, if any
+	settings      atomic.Pointer[Config]
+	settingsMutex sync.Mutex
+	// Identifies the connection in query comments, see clientToken
+	tokenOnce sync.Once
+	token     string
+	// The current client, if the health monitor replaced Session
+	client atomic.Pointer[mongo.Client]
+	// The generation of the current client, and the last one handed out to a new client, see monitorOptions
+	clientGeneration  atomic.Int64
+	clientGenerations atomic.Int64
+}
+
+// The current client: Session, or the client the health monitor replaced it with
+func (m *Connection) Client() *mongo.Client {
+	if client := m.client.Load(); client != nil {
+		return client
+	}
+	return m.Session
 }
 
 // Create a new connection and run Connect()
@@ -58,28 +155,49 @@ func (m *Connection) Connect() (err error) {
 		}
 	}()
 
-	client, err := mongo.NewClient(options.Client().ApplyURI(m.Config.ConnectionString))
-	if err != nil {
-		return err
-	}
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
-	err = client.Connect(ctx)
+	// The client is current before it connects, so the topology changes of connecting count
+	generation := m.clientGenerations.Add(1)
+	m.clientGeneration.Store(generation)
+	client, err := m.newClient(ctx, generation)
 	if err != nil {
 		return err
 	}
 
 	m.Session = client
 
+	if err = m.startLifecycle(); err != nil {
+		return err
+	}
+	m.startHealthMonitor()
 	return nil
 }
 
+// Creates and connects a client with the options of the config
+func (m *Connection) newClient(ctx context.Context, generation int64) (*mongo.Client, error) {
+	opts := []*options.ClientOptions{options.Client().ApplyURI(m.Config.ConnectionString).SetRegistry(Registry)}
+	if m.Config.ClientOptions != nil {
+		opts = append(opts, m.Config.ClientOptions)
+	}
+	opts = append(opts, m.monitorOptions(generation))
+
+	client, err := mongo.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err = client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 // CollectionFromDatabase ...
 func (m *Connection) CollectionFromDatabase(name string, database string) *Collection {
 	// Just create a new instance - it's cheap and only has name and a database name
 	return &Collection{
 		Connection: m,
-		Context:    m.Context,
+		Context:    m.Context.Derive(),
 		Database:   database,
 		Name:       name,
 	}
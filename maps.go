@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"reflect"
+	"strings"
+)
+
+// Converts a document to a map keyed by its bson field names, as it would be stored: inline structs are flattened,
+// omitempty and transient fields are left out, and types with their own BSON encoding (Decimal, Enum, ...) are
+// encoded. Nested documents are maps too, arrays are []interface{}, dates are time.Time in UTC, and other values
+// are decoded as by bson.Unmarshal into interface{}, e.g. int32 and primitive.ObjectID
+func ToMap(doc interface{}) (map[string]interface{}, error) {
+	data, err := bson.MarshalWithRegistry(Registry, doc)
+	if err != nil {
+		return nil, err
+	}
+	return rawToMap(data)
+}
+
+// Sets the fields of a document from a map keyed by bson field names, e.g. one made with ToMap or read from another
+// system. Values are converted to the field types through BSON, and fields missing from the map are left as they are
+func FromMap(m map[string]interface{}, doc interface{}) error {
+	if doc == nil || reflect.ValueOf(doc).Kind() != reflect.Ptr {
+		return errors.New("FromMap needs a pointer to a document")
+	}
+	data, err := bson.MarshalWithRegistry(Registry, m)
+	if err != nil {
+		return err
+	}
+	return bson.UnmarshalWithRegistry(Registry, data, doc)
+}
+
+func rawToMap(doc bson.Raw) (map[string]interface{}, error) {
+	elements, err := doc.Elements()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{}, len(elements))
+	for _, element := range elements {
+		if m[element.Key()], err = rawToValue(element.Value()); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func rawToValue(raw bson.RawValue) (interface{}, error) {
+	switch raw.Type {
+	case bsontype.EmbeddedDocument:
+		return rawToMap(raw.Document())
+	case bsontype.Array:
+		values, err := raw.Array().Values()
+		if err != nil {
+			return nil, err
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			if items[i], err = rawToValue(value); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case bsontype.DateTime:
+		return raw.Time().UTC(), nil
+	case bsontype.Null, bsontype.Undefined:
+		return nil, nil
+	}
+	var value interface{}
+	err := raw.Unmarshal(&value)
+	return value, err
+}
+
+// The value at a dotted path of a map made by ToMap
+func mapValue(m map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = m
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// The bson path of a field of a type, given either as its bson path or its Go path (ignoring case)
+func bsonPathOf(t reflect.Type, field string) (string, bool) {
+	goPaths := fieldGoPaths(indirectType(t))
+	if _, ok := goPaths[field]; ok {
+		return field, true
+	}
+	for path, goPath := range goPaths {
+		if strings.EqualFold(goPath, field) {
+			return path, true
+		}
+	}
+	return "", false
+}
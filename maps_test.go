@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+type mapAddress struct {
+	City string `bson:"city"`
+	Zip  string `bson:"zip,omitempty"`
+}
+
+type mapDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string        `bson:"name"`
+	Age          int           `bson:"age"`
+	Tags         []string      `bson:"tags"`
+	Address      *mapAddress   `bson:"address"`
+	Previous     []*mapAddress `bson:"previous"`
+	Price        Decimal       `bson:"price"`
+	Summary      string        `bson:"summary" bongo:"transient"`
+}
+
+func TestMaps(t *testing.T) {
+	Convey("Maps", t, func() {
+		id := primitive.NewObjectID()
+		created := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+		doc := &mapDocument{Name: "Foo", Age: 42, Tags: []string{"a"}, Address: &mapAddress{City: "Zurich"},
+			Previous: []*mapAddress{{City: "Bern", Zip: "3000"}}, Price: NewDecimal(1999, -2), Summary: "computed"}
+		doc.ID = id
+		doc.CreatedAt = created
+
+		Convey("should convert documents to maps by bson field names", func() {
+			m, err := ToMap(doc)
+			So(err, ShouldBeNil)
+			So(m["_id"], ShouldEqual, id)
+			So(m["created_at"], ShouldEqual, created)
+			So(m["name"], ShouldEqual, "Foo")
+			So(m["age"], ShouldEqual, 42)
+			So(m["tags"], ShouldResemble, []interface{}{"a"})
+			So(m["address"], ShouldResemble, map[string]interface{}{"city": "Zurich"})
+			So(m["previous"], ShouldResemble, []interface{}{map[string]interface{}{"city": "Bern", "zip": "3000"}})
+			So(m["price"], ShouldHaveSameTypeAs, primitive.Decimal128{})
+			So(m, ShouldNotContainKey, "summary")
+			So(m, ShouldNotContainKey, "DocumentBase")
+		})
+
+		Convey("should set documents from maps", func() {
+			m, err := ToMap(doc)
+			So(err, ShouldBeNil)
+			found := &mapDocument{}
+			So(FromMap(m, found), ShouldBeNil)
+			So(found.ID, ShouldEqual, id)
+			So(found.Address.City, ShouldEqual, "Zurich")
+			So(found.Previous[0].Zip, ShouldEqual, "3000")
+			So(found.Price.String(), ShouldEqual, "19.99")
+
+			So(FromMap(map[string]interface{}{"age": int64(7), "address": map[string]interface{}{"city": "Basel"}}, found), ShouldBeNil)
+			So(found.Age, ShouldEqual, 7)
+			So(found.Address.City, ShouldEqual, "Basel")
+			So(found.Name, ShouldEqual, "Foo")
+
+			So(FromMap(map[string]interface{}{"age": "old"}, found), ShouldNotBeNil)
+			So(FromMap(m, *found), ShouldNotBeNil)
+		})
+
+		Convey("should build cascade maps from bson and Go paths", func() {
+			m := MapFromCascadeProperties([]string{"name", "Age", "address.city", "summary"}, doc)
+			So(m["name"], ShouldEqual, "Foo")
+			So(m["Age"], ShouldEqual, 42)
+			So(m["address"], ShouldResemble, map[string]interface{}{"city": "Zurich"})
+			So(m, ShouldNotContainKey, "summary")
+		})
+	})
+}
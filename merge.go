@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// How Merge combines a field of the documents
+const (
+	// Keep the winner's value unless it is empty, then take the first non-empty value of the losers
+	MERGE_FILL_EMPTY = iota
+
+	// Keep the winner's value
+	MERGE_KEEP_WINNER
+
+	// Take the value of the most recently updated document that has the field
+	MERGE_NEWEST
+
+	// Combine the values of arrays, without duplicates, the winner's first. Other values are merged like
+	// MERGE_FILL_EMPTY
+	MERGE_UNION
+)
+
+// How Merge combines the fields of the documents, by bson field name
+type MergeStrategy struct {
+	// Applies to the fields not in Fields. Defaults to MERGE_FILL_EMPTY
+	Default int
+	Fields  map[string]int
+}
+
+func (s *MergeStrategy) forField(field string) int {
+	if s == nil {
+		return MERGE_FILL_EMPTY
+	}
+	if strategy, ok := s.Fields[field]; ok {
+		return strategy
+	}
+	return s.Default
+}
+
+// A reference to the documents of a collection, found in the registered models
+type mergeReference struct {
+	collection string
+	path       string
+	many       bool
+}
+
+// Merges the losers into the winner, e.g. to deduplicate customers:
+//
+//	winner, err := customers.Merge(ctx, keep.ID, []primitive.ObjectID{dupe.ID}, &bongo.MergeStrategy{
+//		Fields: map[string]int{"tags": bongo.MERGE_UNION, "phone": bongo.MERGE_NEWEST},
+//	})
+//
+// The collection needs a registered model. The fields of the documents are combined by the strategy (nil fills the
+// winner's empty fields) and the winner is saved. Then the references to the losers in the documents of registered
+// models (`ref` tags and relations) are pointed to the winner, and the losers are deleted with DeleteDocument, which
+// runs their hooks and cascades. Each step leaves the losers in place until the last, so a failed merge can be run
+// again
+func (c *Collection) Merge(ctx context.Context, winnerID primitive.ObjectID, loserIDs []primitive.ObjectID, strategy *MergeStrategy) (Document, error) {
+	model := GetModel(c.Name)
+	if model == nil {
+		return nil, errors.New("no model registered for collection " + c.Name)
+	}
+
+	winner := model.New()
+	if err := c.findByID(ctx, winnerID, winner); err != nil {
+		return nil, err
+	}
+	losers := make([]Document, 0, len(loserIDs))
+	for _, id := range loserIDs {
+		if id == winnerID {
+			continue
+		}
+		loser := model.New()
+		if err := c.findByID(ctx, id, loser); err != nil {
+			return nil, err
+		}
+		losers = append(losers, loser)
+	}
+	if len(losers) == 0 {
+		return winner, nil
+	}
+
+	merged, err := mergeDocuments(winner, losers, strategy)
+	if err != nil {
+		return nil, err
+	}
+	data, err := bson.MarshalWithRegistry(Registry, merged)
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.UnmarshalWithRegistry(Registry, data, winner); err != nil {
+		return nil, err
+	}
+
+	o := newWriteOptions(nil)
+	if err := c.save(ctx, winner, o); err != nil {
+		return nil, err
+	}
+
+	ids := make(bson.A, len(losers))
+	for i, loser := range losers {
+		ids[i] = loser.GetID()
+	}
+	for _, ref := range c.mergeReferences() {
+		if err := c.repoint(ctx, ref, ids, winnerID); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, loser := range losers {
+		if _, err := c.deleteDocument(ctx, loser, o); err != nil {
+			return nil, err
+		}
+	}
+	return winner, nil
+}
+
+// Combines the bson fields of the documents into the fields of the winner. The winner keeps all of its fields
+func mergeDocuments(winner Document, losers []Document, strategy *MergeStrategy) (bson.M, error) {
+	merged, err := documentMap(winner)
+	if err != nil {
+		return nil, err
+	}
+	loserMaps := make([]bson.M, len(losers))
+	for i, loser := range losers {
+		if loserMaps[i], err = documentMap(loser); err != nil {
+			return nil, err
+		}
+	}
+
+	// The documents from the most recently updated on, for MERGE_NEWEST
+	newest := append([]bson.M{merged}, loserMaps...)
+	updated := map[int]time.Time{0: updatedAt(winner)}
+	for i, loser := range losers {
+		updated[i+1] = updatedAt(loser)
+	}
+	order := make([]int, len(newest))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return updated[order[i]].After(updated[order[j]])
+	})
+	newestFirst := make([]bson.M, len(order))
+	for i, doc := range order {
+		newestFirst[i] = newest[doc]
+	}
+
+	fields := map[string]bool{}
+	for _, doc := range newest {
+		for field := range doc {
+			fields[field] = true
+		}
+	}
+	delete(fields, "_id")
+
+	for field := range fields {
+		switch strategy.forField(field) {
+		case MERGE_KEEP_WINNER:
+		case MERGE_NEWEST:
+			for _, doc := range newestFirst {
+				if value, ok := doc[field]; ok {
+					merged[field] = value
+					break
+				}
+			}
+		case MERGE_UNION:
+			if union, ok := unionValues(merged[field], loserMaps, field); ok {
+				merged[field] = union
+			} else {
+				merged[field] = fillEmpty(merged[field], loserMaps, field)
+			}
+		default:
+			merged[field] = fillEmpty(merged[field], loserMaps, field)
+		}
+	}
+	return merged, nil
+}
+
+func fillEmpty(value interface{}, losers []bson.M, field string) interface{} {
+	if !isEmptyMergeValue(value) {
+		return value
+	}
+	for _, loser := range losers {
+		if loserValue := loser[field]; !isEmptyMergeValue(loserValue) {
+			return loserValue
+		}
+	}
+	return value
+}
+
+// Combines the arrays of the winner and the losers, or returns false if none of them has an array
+func unionValues(value interface{}, losers []bson.M, field string) (bson.A, bool) {
+	union, ok := value.(bson.A)
+	if !ok && value != nil {
+		return nil, false
+	}
+	union = append(bson.A{}, union...)
+	for _, loser := range losers {
+		values, isArray := loser[field].(bson.A)
+		ok = ok || isArray
+		for _, loserValue := range values {
+			if !containsValue(union, loserValue) {
+				union = append(union, loserValue)
+			}
+		}
+	}
+	return union, ok
+}
+
+func documentMap(doc Document) (bson.M, error) {
+	data, err := bson.MarshalWithRegistry(Registry, doc)
+	if err != nil {
+		return nil, err
+	}
+	m := bson.M{}
+	return m, bson.UnmarshalWithRegistry(Registry, data, &m)
+}
+
+func updatedAt(doc Document) time.Time {
+	if tracker, ok := doc.(TimeModifiedTracker); ok {
+		return tracker.GetUpdatedAt()
+	}
+	return time.Time{}
+}
+
+func isEmptyMergeValue(value interface{}) bool {
+	switch v := value.(type) {
+	case primitive.DateTime:
+		return v.Time().IsZero()
+	case bson.A:
+		return len(v) == 0
+	case bson.M:
+		return len(v) == 0
+	case bson.D:
+		return len(v) == 0
+	}
+	return isZeroValue(value)
+}
+
+func containsValue(values bson.A, value interface{}) bool {
+	for _, existing := range values {
+		if reflect.DeepEqual(existing, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// The fields of registered models that reference the documents of the collection: `ref` tags and BelongsTo
+// relations to it, and the foreign keys of its own HasOne and HasMany relations
+func (c *Collection) mergeReferences() []*mergeReference {
+	refs := []*mergeReference{}
+	seen := map[string]bool{}
+	add := func(collection string, path string, many bool) {
+		if key := collection + "." + path; !seen[key] {
+			seen[key] = true
+			refs = append(refs, &mergeReference{collection: collection, path: path, many: many})
+		}
+	}
+
+	for _, model := range Models() {
+		for _, ref := range references(model.Collection, model.Type) {
+			if ref.collection != c.Name || ref.foreignKey != "_id" {
+				continue
+			}
+			add(model.Collection, ref.path, isArrayField(model.Type, ref.path))
+		}
+	}
+
+	if model := GetModel(c.Name); model != nil {
+		for _, relation := range model.relations {
+			if relation.Kind != BELONGS_TO && relation.LocalKey == "_id" {
+				related := GetModel(relation.Collection)
+				add(relation.Collection, relation.ForeignKey, related != nil && isArrayField(related.Type, relation.ForeignKey))
+			}
+		}
+	}
+	return refs
+}
+
+// Whether the field at the bson path of a type holds an array
+func isArrayField(t reflect.Type, path string) bool {
+	array := false
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, fieldPath string) bool {
+		if fieldPath != path {
+			return true
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		array = fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() != reflect.Uint8
+		return false
+	})
+	return array
+}
+
+// Points the references to the losers at the winner. Arrays of references get the winner once
+func (c *Collection) repoint(ctx context.Context, ref *mergeReference, losers bson.A, winner primitive.ObjectID) error {
+	collection := c.Connection.CollectionFromDatabase(ref.collection, c.Database)
+	return collection.runOperationContext(ctx, "merge", func(ctx context.Context) error {
+		filter, err := collection.guardFilter(ctx, bson.M{ref.path: bson.M{"$in": losers}})
+		if err != nil {
+			return err
+		}
+		if !ref.many {
+			_, err := collection.Collection().UpdateMany(ctx, filter, bson.M{"$set": bson.M{ref.path: winner}})
+			return err
+		}
+		if _, err := collection.Collection().UpdateMany(ctx, filter, bson.M{"$addToSet": bson.M{ref.path: winner}}); err != nil {
+			return err
+		}
+		_, err = collection.Collection().UpdateMany(ctx, filter, bson.M{"$pull": bson.M{ref.path: bson.M{"$in": losers}}})
+		return err
+	})
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type mergeCustomer struct {
+	DocumentBase `bson:",inline"`
+	Name         string   `bson:"name"`
+	Phone        string   `bson:"phone,omitempty"`
+	Tags         []string `bson:"tags"`
+}
+
+type mergeOrder struct {
+	DocumentBase `bson:",inline"`
+	CustomerID   primitive.ObjectID `bson:"customerId" ref:"merge_customers"`
+}
+
+type mergeGroup struct {
+	DocumentBase `bson:",inline"`
+	MemberIDs    []primitive.ObjectID `bson:"memberIds" ref:"merge_customers"`
+}
+
+func registerMergeModels() {
+	RegisterModel("merge_customers", &mergeCustomer{})
+	RegisterModel("merge_orders", &mergeOrder{})
+	RegisterModel("merge_groups", &mergeGroup{})
+}
+
+func TestMergeDocuments(t *testing.T) {
+	Convey("Merging documents", t, func() {
+		now := time.Now()
+		winner := &mergeCustomer{Name: "Ann", Tags: []string{"vip"}}
+		winner.UpdatedAt = now.Add(-time.Hour)
+		loser := &mergeCustomer{Name: "Ann B.", Phone: "555", Tags: []string{"b2b", "vip"}}
+		loser.UpdatedAt = now
+
+		Convey("should fill the winner's empty fields by default", func() {
+			merged, err := mergeDocuments(winner, []Document{loser}, nil)
+			So(err, ShouldBeNil)
+			So(merged["name"], ShouldEqual, "Ann")
+			So(merged["phone"], ShouldEqual, "555")
+			So(merged["tags"], ShouldResemble, bson.A{"vip"})
+		})
+
+		Convey("should apply the strategies of fields", func() {
+			merged, err := mergeDocuments(winner, []Document{loser}, &MergeStrategy{
+				Default: MERGE_KEEP_WINNER,
+				Fields:  map[string]int{"name": MERGE_NEWEST, "tags": MERGE_UNION},
+			})
+			So(err, ShouldBeNil)
+			So(merged["name"], ShouldEqual, "Ann B.")
+			So(merged["tags"], ShouldResemble, bson.A{"vip", "b2b"})
+			So(merged, ShouldNotContainKey, "phone")
+		})
+	})
+}
+
+func TestMergeReferences(t *testing.T) {
+	Convey("Merge references", t, func() {
+		registerMergeModels()
+		conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+
+		Convey("should find the fields referencing the collection", func() {
+			refs := conn.Collection("merge_customers").mergeReferences()
+			So(refs, ShouldContain, &mergeReference{collection: "merge_groups", path: "memberIds", many: true})
+			So(refs, ShouldContain, &mergeReference{collection: "merge_orders", path: "customerId", many: false})
+		})
+
+		Convey("should tell array fields", func() {
+			So(isArrayField(reflect.TypeOf(mergeGroup{}), "memberIds"), ShouldBeTrue)
+			So(isArrayField(reflect.TypeOf(mergeGroup{}), "_id"), ShouldBeFalse)
+		})
+	})
+}
+
+func TestMerge(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Merge", t, func() {
+		registerMergeModels()
+		ctx := context.Background()
+		customers := conn.Collection("merge_customers")
+
+		winner := &mergeCustomer{Name: "Ann", Tags: []string{"vip"}}
+		So(customers.Save(winner), ShouldBeNil)
+		loser := &mergeCustomer{Name: "Ann B.", Phone: "555"}
+		So(customers.Save(loser), ShouldBeNil)
+
+		order := &mergeOrder{CustomerID: loser.ID}
+		So(conn.Collection("merge_orders").Save(order), ShouldBeNil)
+		group := &mergeGroup{MemberIDs: []primitive.ObjectID{winner.ID, loser.ID}}
+		So(conn.Collection("merge_groups").Save(group), ShouldBeNil)
+
+		Convey("should merge the losers into the winner and repoint their references", func() {
+			merged, err := customers.Merge(ctx, winner.ID, []primitive.ObjectID{loser.ID}, nil)
+			So(err, ShouldBeNil)
+			So(merged.(*mergeCustomer).Phone, ShouldEqual, "555")
+
+			So(customers.FindByID(loser.ID, &mergeCustomer{}), ShouldHaveSameTypeAs, &DocumentNotFoundError{})
+
+			foundOrder := &mergeOrder{}
+			So(conn.Collection("merge_orders").FindByID(order.ID, foundOrder), ShouldBeNil)
+			So(foundOrder.CustomerID, ShouldEqual, winner.ID)
+
+			foundGroup := &mergeGroup{}
+			So(conn.Collection("merge_groups").FindByID(group.ID, foundGroup), ShouldBeNil)
+			So(foundGroup.MemberIDs, ShouldResemble, []primitive.ObjectID{winner.ID})
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The upper bounds of the latency buckets: 100µs, doubling up to about 52s. Slower operations land in one more
+// bucket
+var latencyBuckets = func() []time.Duration {
+	bounds := make([]time.Duration, 20)
+	for i := range bounds {
+		bounds[i] = 100 * time.Microsecond << uint(i)
+	}
+	return bounds
+}()
+
+// Latencies of one operation on one collection. Recording only takes atomic adds
+type latencyHistogram struct {
+	buckets [21]atomic.Uint64
+	errors  atomic.Uint64
+	sum     atomic.Int64
+	max     atomic.Int64
+}
+
+type metricsKey struct {
+	collection string
+	operation  string
+}
+
+type operationMetrics struct {
+	histograms sync.Map
+}
+
+func (h *latencyHistogram) record(d time.Duration, failed bool) {
+	bucket := sort.Search(len(latencyBuckets), func(i int) bool {
+		return d <= latencyBuckets[i]
+	})
+	h.buckets[bucket].Add(1)
+	if failed {
+		h.errors.Add(1)
+	}
+	h.sum.Add(int64(d))
+	for {
+		max := h.max.Load()
+		if int64(d) <= max || h.max.CompareAndSwap(max, int64(d)) {
+			return
+		}
+	}
+}
+
+// Records the latency of an operation on a collection. Finding nothing isn't an error
+func (m *Connection) recordLatency(c *Collection, op string, d time.Duration, err error) {
+	key := metricsKey{collection: c.Database + "." + c.Name, operation: op}
+	histogram, ok := m.metrics.histograms.Load(key)
+	if !ok {
+		histogram, _ = m.metrics.histograms.LoadOrStore(key, &latencyHistogram{})
+	}
+	histogram.(*latencyHistogram).record(d, err != nil && err != mongo.ErrNoDocuments)
+}
+
+// Logs an operation that took longer than Config.SlowOperationThreshold
+func (m *Connection) logSlowOperation(c *Collection, op string, d time.Duration) {
+	if threshold := m.config().SlowOperationThreshold; threshold > 0 && d >= threshold {
+		m.logf(LOG_WARN, "slow %s on %s.%s took %s", op, c.Database, c.Name, d)
+	}
+}
+
+// The latencies of one operation (e.g. find, save, delete) on one collection since the connection was created.
+// Percentiles are estimated from buckets that double in size, so they are up to twice the actual value
+type OperationMetrics struct {
+	// The database and name of the collection
+	Collection string
+	Operation  string
+
+	Count  uint64
+	Errors uint64
+	Mean   time.Duration
+	Max    time.Duration
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+}
+
+// Returns the latencies of the operations run so far, per collection and operation, sorted by collection and
+// operation. Latencies include retries and waiting for the rate limiter
+func (m *Connection) Metrics() []*OperationMetrics {
+	metrics := []*OperationMetrics{}
+	m.metrics.histograms.Range(func(key, value interface{}) bool {
+		k := key.(metricsKey)
+		metrics = append(metrics, value.(*latencyHistogram).snapshot(k.collection, k.operation))
+		return true
+	})
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Collection != metrics[j].Collection {
+			return metrics[i].Collection < metrics[j].Collection
+		}
+		return metrics[i].Operation < metrics[j].Operation
+	})
+	return metrics
+}
+
+// Forgets the latencies recorded so far, e.g. to report them per interval
+func (m *Connection) ResetMetrics() {
+	m.metrics.histograms.Range(func(key, value interface{}) bool {
+		m.metrics.histograms.Delete(key)
+		return true
+	})
+}
+
+func (h *latencyHistogram) snapshot(collection string, operation string) *OperationMetrics {
+	var buckets [21]uint64
+	var count uint64
+	for i := range buckets {
+		buckets[i] = h.buckets[i].Load()
+		count += buckets[i]
+	}
+
+	metrics := &OperationMetrics{
+		Collection: collection,
+		Operation:  operation,
+		Count:      count,
+		Errors:     h.errors.Load(),
+		Max:        time.Duration(h.max.Load()),
+	}
+	if count == 0 {
+		return metrics
+	}
+	metrics.Mean = time.Duration(h.sum.Load() / int64(count))
+	metrics.P50 = percentile(buckets[:], count, 0.5, metrics.Max)
+	metrics.P95 = percentile(buckets[:], count, 0.95, metrics.Max)
+	metrics.P99 = percentile(buckets[:], count, 0.99, metrics.Max)
+	return metrics
+}
+
+// The upper bound of the bucket holding the qth of count latencies, but no more than the largest latency
+func percentile(buckets []uint64, count uint64, q float64, max time.Duration) time.Duration {
+	rank := uint64(math.Ceil(q * float64(count)))
+	var seen uint64
+	for i, n := range buckets {
+		seen += n
+		if seen >= rank {
+			if i < len(latencyBuckets) && latencyBuckets[i] < max {
+				return latencyBuckets[i]
+			}
+			return max
+		}
+	}
+	return max
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	Convey("Metrics", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+
+		Convey("should estimate percentiles from the buckets", func() {
+			h := &latencyHistogram{}
+			for i := 0; i < 98; i++ {
+				h.record(150*time.Microsecond, false)
+			}
+			h.record(3*time.Millisecond, true)
+			h.record(time.Second, false)
+
+			metrics := h.snapshot("bongotest.tests", "find")
+			So(metrics.Count, ShouldEqual, 100)
+			So(metrics.Errors, ShouldEqual, 1)
+			So(metrics.Max, ShouldEqual, time.Second)
+			So(metrics.P50, ShouldEqual, 200*time.Microsecond)
+			So(metrics.P99, ShouldEqual, 3200*time.Microsecond)
+		})
+
+		Convey("should cap percentiles at the largest latency", func() {
+			h := &latencyHistogram{}
+			h.record(time.Minute, false)
+			So(h.snapshot("", "").P99, ShouldEqual, time.Minute)
+			So(h.snapshot("", "").Mean, ShouldEqual, time.Minute)
+		})
+
+		Convey("should record operations per collection and operation", func() {
+			tests := conn.Collection("tests")
+			tests.runOperation("find", func(ctx context.Context) error { return nil })
+			tests.runOperation("find", func(ctx context.Context) error { return mongo.ErrNoDocuments })
+			tests.runOperation("save", func(ctx context.Context) error { return errors.New("boom") })
+
+			metrics := conn.Metrics()
+			So(len(metrics), ShouldEqual, 2)
+			So(metrics[0].Collection, ShouldEqual, "bongotest.tests")
+			So(metrics[0].Operation, ShouldEqual, "find")
+			So(metrics[0].Count, ShouldEqual, 2)
+			So(metrics[0].Errors, ShouldEqual, 0)
+			So(metrics[1].Operation, ShouldEqual, "save")
+			So(metrics[1].Errors, ShouldEqual, 1)
+
+			conn.ResetMetrics()
+			So(conn.Metrics(), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The collection that keeps track of applied migrations
+const MigrationsCollection = "bongo_migrations"
+
+// The name of the Locker lock held while migrating
+const MigrationsLock = "bongo_migrations"
+
+// A schema or data migration. Migrations run in order of their IDs, so use sortable IDs like
+// "20190623_rename_email"
+type Migration struct {
+	ID   string
+	Up   func(*Connection) error
+	Down func(*Connection) error
+
+	// Used instead of Up and Down when set. ctx is canceled when the MigrationsLock is lost, so run the operations of
+	// the migration within it, e.g. with m.Collection("people").WithContext(ctx)
+	UpContext   func(ctx context.Context, m *Connection) error
+	DownContext func(ctx context.Context, m *Connection) error
+}
+
+type migrationRecord struct {
+	ID        string    `bson:"_id"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+var migrations = struct {
+	sync.RWMutex
+	list []*Migration
+}{}
+
+// Registers a migration, typically from an init() func
+func RegisterMigration(migration *Migration) {
+	migrations.Lock()
+	defer migrations.Unlock()
+	migrations.list = append(migrations.list, migration)
+}
+
+// Get all registered migrations, sorted by ID
+func Migrations() []*Migration {
+	migrations.RLock()
+	defer migrations.RUnlock()
+
+	list := append([]*Migration{}, migrations.list...)
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Get the IDs of the migrations that have been applied to the database, in order
+func (m *Connection) AppliedMigrations() ([]string, error) {
+	return m.appliedMigrations(m.Collection(MigrationsCollection))
+}
+
+func (m *Connection) appliedMigrations(col *Collection) ([]string, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	var cursor *mongo.Cursor
+	err := col.runOperation("appliedMigrations", func(ctx context.Context) error {
+		var err error
+		cursor, err = col.Collection().Find(ctx, bson.M{}, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer col.closeCursor(cursor)
+
+	ids := make([]string, 0)
+	for col.nextDocument(cursor) {
+		record := &migrationRecord{}
+		if err := cursor.Decode(record); err != nil {
+			return ids, err
+		}
+		ids = append(ids, record.ID)
+	}
+
+	return ids, cursor.Err()
+}
+
+// Runs all pending migrations in order, stopping at the first error. Returns the IDs of the migrations that ran.
+// Holds the MigrationsLock meanwhile, so instances starting at the same time don't run the same migration twice,
+// and returns a *LeaseHeldError if another process is migrating
+func (m *Connection) MigrateUp() ([]string, error) {
+	var ran []string
+	err := m.withMigrationsLock(func(ctx context.Context) error {
+		var err error
+		ran, err = m.migrateUp(ctx)
+		return err
+	})
+	return ran, err
+}
+
+func (m *Connection) migrateUp(ctx context.Context) ([]string, error) {
+	col := m.Collection(MigrationsCollection).WithContext(ctx)
+	applied, err := m.appliedMigrations(col)
+	if err != nil {
+		return nil, err
+	}
+
+	ran := make([]string, 0)
+	for _, migration := range Migrations() {
+		if stringInSlice(migration.ID, applied) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return ran, err
+		}
+
+		if migration.UpContext != nil {
+			err = migration.UpContext(ctx, m)
+		} else if migration.Up != nil {
+			err = migration.Up(m)
+		}
+		if err != nil {
+			return ran, err
+		}
+
+		// Not retried, a retry of an insert that went through would fail with a duplicate key
+		record := &migrationRecord{ID: migration.ID, AppliedAt: time.Now()}
+		insertCtx, cancel := col.operationContext()
+		_, err := col.Collection().InsertOne(insertCtx, record)
+		cancel()
+		if err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration.ID)
+	}
+
+	return ran, nil
+}
+
+// Reverts the last n applied migrations, newest first. Returns the IDs of the migrations that were reverted. Holds
+// the MigrationsLock like MigrateUp
+func (m *Connection) MigrateDown(n int) ([]string, error) {
+	var reverted []string
+	err := m.withMigrationsLock(func(ctx context.Context) error {
+		var err error
+		reverted, err = m.migrateDown(ctx, n)
+		return err
+	})
+	return reverted, err
+}
+
+func (m *Connection) migrateDown(ctx context.Context, n int) ([]string, error) {
+	col := m.Collection(MigrationsCollection).WithContext(ctx)
+	applied, err := m.appliedMigrations(col)
+	if err != nil {
+		return nil, err
+	}
+
+	registered := make(map[string]*Migration)
+	for _, migration := range Migrations() {
+		registered[migration.ID] = migration
+	}
+
+	reverted := make([]string, 0)
+	for i := len(applied) - 1; i >= 0 && len(reverted) < n; i-- {
+		migration, ok := registered[applied[i]]
+		if !ok {
+			return reverted, &UnknownMigrationError{applied[i]}
+		}
+		if err := ctx.Err(); err != nil {
+			return reverted, err
+		}
+
+		if migration.DownContext != nil {
+			err = migration.DownContext(ctx, m)
+		} else if migration.Down != nil {
+			err = migration.Down(m)
+		}
+		if err != nil {
+			return reverted, err
+		}
+
+		err := col.runOperation("migrateDown", func(ctx context.Context) error {
+			_, err := col.Collection().DeleteOne(ctx, bson.M{"_id": migration.ID})
+			return err
+		})
+		if err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, migration.ID)
+	}
+
+	return reverted, nil
+}
+
+// Runs fn holding the MigrationsLock, which is renewed until fn returns. ctx is canceled when the lock is lost
+func (m *Connection) withMigrationsLock(fn func(ctx context.Context) error) error {
+	return m.NewLocker(nil).Do(context.Background(), MigrationsLock, func(ctx context.Context, lease *Lease) error {
+		return fn(ctx)
+	})
+}
+
+// Returned when an applied migration has to be reverted but isn't registered
+type UnknownMigrationError struct {
+	ID string
+}
+
+func (e *UnknownMigrationError) Error() string {
+	return "Migration " + e.ID + " is not registered"
+}
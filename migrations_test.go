@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Migrations", t, func() {
+		ups := []string{}
+		downs := []string{}
+
+		for _, id := range []string{"002_second", "001_first"} {
+			id := id
+			RegisterMigration(&Migration{
+				ID: id,
+				Up: func(c *Connection) error {
+					ups = append(ups, id)
+					return nil
+				},
+				Down: func(c *Connection) error {
+					downs = append(downs, id)
+					return nil
+				},
+			})
+		}
+
+		Convey("should run pending migrations in order, once", func() {
+			ran, err := conn.MigrateUp()
+			So(err, ShouldEqual, nil)
+			So(ran, ShouldResemble, []string{"001_first", "002_second"})
+			So(ups, ShouldResemble, []string{"001_first", "002_second"})
+
+			ran, err = conn.MigrateUp()
+			So(err, ShouldEqual, nil)
+			So(len(ran), ShouldEqual, 0)
+
+			applied, err := conn.AppliedMigrations()
+			So(err, ShouldEqual, nil)
+			So(applied, ShouldResemble, []string{"001_first", "002_second"})
+		})
+
+		Convey("should revert migrations newest first", func() {
+			conn.MigrateUp()
+			reverted, err := conn.MigrateDown(1)
+			So(err, ShouldEqual, nil)
+			So(reverted, ShouldResemble, []string{"002_second"})
+			So(downs, ShouldResemble, []string{"002_second"})
+
+			applied, _ := conn.AppliedMigrations()
+			So(applied, ShouldResemble, []string{"001_first"})
+		})
+
+		Convey("should pass the context of the lock to the migrations", func() {
+			var migrationCtx context.Context
+			var errWhileRunning error
+			RegisterMigration(&Migration{
+				ID: "003_context",
+				UpContext: func(ctx context.Context, c *Connection) error {
+					migrationCtx, errWhileRunning = ctx, ctx.Err()
+					return nil
+				},
+			})
+			ran, err := conn.MigrateUp()
+			So(err, ShouldEqual, nil)
+			So(ran, ShouldResemble, []string{"001_first", "002_second", "003_context"})
+			So(errWhileRunning, ShouldEqual, nil)
+			So(migrationCtx.Err(), ShouldEqual, context.Canceled)
+		})
+
+		Convey("should stop once the lock is lost", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			ran, err := conn.migrateUp(ctx)
+			So(err, ShouldEqual, context.Canceled)
+			So(len(ran), ShouldEqual, 0)
+			So(len(ups), ShouldEqual, 0)
+		})
+
+		Convey("should not migrate while another process holds the lock", func() {
+			lease, err := conn.NewLocker(nil).Acquire(context.Background(), MigrationsLock)
+			So(err, ShouldEqual, nil)
+
+			ran, err := conn.MigrateUp()
+			So(err, ShouldHaveSameTypeAs, &LeaseHeldError{})
+			So(len(ran), ShouldEqual, 0)
+			So(len(ups), ShouldEqual, 0)
+
+			So(lease.Release(context.Background()), ShouldEqual, nil)
+			ran, err = conn.MigrateUp()
+			So(err, ShouldEqual, nil)
+			So(len(ran), ShouldEqual, 2)
+		})
+
+		Reset(func() {
+			migrations.list = nil
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
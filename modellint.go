@@ -0,0 +1,220 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A problem with the struct type of a registered model, found by Model.Lint
+type ModelIssue struct {
+	Collection string
+
+	// The Go path of the field, e.g. Address.City
+	Field   string
+	Problem string
+
+	// Warnings are only logged by ValidateModels, everything else fails it
+	Warning bool
+}
+
+func (i *ModelIssue) String() string {
+	return i.Collection + " " + i.Field + ": " + i.Problem
+}
+
+// Returned by ValidateModels with the issues of the registered models that aren't warnings
+type ModelSchemaError struct {
+	Issues []*ModelIssue
+}
+
+func (e *ModelSchemaError) Error() string {
+	problems := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		problems[i] = issue.String()
+	}
+	return "invalid models: " + strings.Join(problems, "; ")
+}
+
+// Lints the registered models, so mistakes in their struct tags fail at startup instead of corrupting documents at
+// runtime. Warnings (fields without a bson tag, embedded structs that aren't inline) are logged, other issues are
+// returned as a *ModelSchemaError:
+//
+//	if err := connection.ValidateModels(); err != nil {
+//		log.Fatal(err)
+//	}
+func (m *Connection) ValidateModels() error {
+	errs := make([]*ModelIssue, 0)
+	for _, model := range Models() {
+		for _, issue := range model.Lint() {
+			if issue.Warning {
+				m.logf(LOG_INFO, "model lint: %s", issue)
+			} else {
+				errs = append(errs, issue)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &ModelSchemaError{Issues: errs}
+	}
+	return nil
+}
+
+var (
+	valueMarshalerType = reflect.TypeOf((*bsoncodec.ValueMarshaler)(nil)).Elem()
+	marshalerType      = reflect.TypeOf((*bson.Marshaler)(nil)).Elem()
+	keyMarshalerType   = reflect.TypeOf((*bsoncodec.KeyMarshaler)(nil)).Elem()
+)
+
+// Checks the model's struct type for duplicate bson names, fields without bson tags, inline mistakes, types the
+// driver can't encode and invalid bongo tags (index, vector, anonymize, overflow, zone and ref)
+func (m *Model) Lint() []*ModelIssue {
+	issues := make([]*ModelIssue, 0)
+	addIssue := func(field string, problem string, warning bool) {
+		issues = append(issues, &ModelIssue{Collection: m.Collection, Field: field, Problem: problem, Warning: warning})
+	}
+
+	lintStruct(m.Type, "", "", map[string]string{}, map[reflect.Type]bool{}, addIssue)
+
+	if _, err := m.Indexes(); err != nil {
+		addIssue(m.Type.Name(), err.Error(), false)
+	}
+	if _, err := m.VectorIndexes(); err != nil {
+		addIssue(m.Type.Name(), err.Error(), false)
+	}
+	if _, err := NewAnonymizer(m.New()); err != nil {
+		addIssue(m.Type.Name(), err.Error(), false)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Field < issues[j].Field
+	})
+	return issues
+}
+
+// Lints the fields of a struct type. Inline structs share the bson names of the struct they are inlined into
+func lintStruct(t reflect.Type, goPrefix string, prefix string, names map[string]string, visiting map[reflect.Type]bool, addIssue func(field string, problem string, warning bool)) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		goPath := field.Name
+		if len(goPrefix) > 0 {
+			goPath = goPrefix + "." + field.Name
+		}
+		tag, tagged := field.Tag.Lookup("bson")
+		key := bsonKey(field)
+
+		if len(field.PkgPath) > 0 {
+			if tagged && key != "-" {
+				addIssue(goPath, "unexported field has a bson tag but is never stored", true)
+			}
+			continue
+		}
+		if key == "-" || isTransient(field) {
+			continue
+		}
+
+		ft := indirectType(field.Type)
+		if hasBsonOption(field, "inline") {
+			switch {
+			case ft.Kind() == reflect.Struct:
+				lintStruct(ft, goPath, prefix, names, visiting, addIssue)
+			case ft.Kind() == reflect.Map && ft.Key().Kind() == reflect.String:
+				lintType(ft.Elem(), goPath, prefix, visiting, addIssue)
+			default:
+				addIssue(goPath, "inline field must be a struct, a struct pointer or a map with string keys", false)
+			}
+			continue
+		}
+
+		path := key
+		if len(prefix) > 0 {
+			path = prefix + "." + key
+		}
+		if other, ok := names[key]; ok {
+			addIssue(goPath, "bson name "+path+" is also used by "+other, false)
+		} else {
+			names[key] = goPath
+		}
+
+		if len(strings.Split(tag, ",")[0]) == 0 {
+			if field.Anonymous && ft.Kind() == reflect.Struct {
+				addIssue(goPath, "embedded struct is stored as the subdocument "+path+", tag it with bson:\",inline\" to flatten it", true)
+			} else {
+				addIssue(goPath, "field has no bson name and is stored as "+path, true)
+			}
+		}
+
+		lintTags(field, goPath, t, addIssue)
+		lintType(field.Type, goPath, path, visiting, addIssue)
+	}
+}
+
+// Lints a field type: types the driver can't encode, and the fields of (nested) structs
+func lintType(t reflect.Type, goPath string, path string, visiting map[reflect.Type]bool, addIssue func(field string, problem string, warning bool)) {
+	if encodesItself(t) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		addIssue(goPath, "type "+t.String()+" can't be stored", false)
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		lintType(t.Elem(), goPath, path, visiting, addIssue)
+	case reflect.Map:
+		key := t.Key()
+		if key.Kind() != reflect.String && !isIntegerKind(key.Kind()) && !key.Implements(keyMarshalerType) && !key.Implements(textMarshalerType) {
+			addIssue(goPath, "map keys of type "+key.String()+" can't be stored", false)
+		}
+		lintType(t.Elem(), goPath, path, visiting, addIssue)
+	case reflect.Struct:
+		lintStruct(t, goPath, path, map[string]string{}, visiting, addIssue)
+	}
+}
+
+// Whether a type has its own BSON encoding (Decimal, Date, Optional, ...), so its kind doesn't tell how it is stored
+func encodesItself(t reflect.Type) bool {
+	return t.Implements(valueMarshalerType) || t.Implements(marshalerType) ||
+		reflect.PtrTo(t).Implements(valueMarshalerType) || reflect.PtrTo(t).Implements(marshalerType)
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// Lints the bongo tags of a field that are silently ignored when invalid
+func lintTags(field reflect.StructField, goPath string, parent reflect.Type, addIssue func(field string, problem string, warning bool)) {
+	if tag, ok := field.Tag.Lookup("overflow"); ok && len(tag) > 0 {
+		if size, err := strconv.Atoi(tag); err != nil || size <= 0 {
+			addIssue(goPath, "overflow tag must be a positive size in bytes, not "+tag, false)
+		}
+	}
+	if companion, ok := field.Tag.Lookup("zone"); ok {
+		other, found := parent.FieldByName(companion)
+		switch {
+		case indirectType(field.Type) != timeType:
+			addIssue(goPath, "zone tag is only supported on time.Time fields", false)
+		case !found || other.Type.Kind() != reflect.String:
+			addIssue(goPath, "zone tag must name a string field next to it, not "+companion, false)
+		}
+	}
+	if collection, ok := field.Tag.Lookup("ref"); ok && len(collection) == 0 {
+		addIssue(goPath, "ref tag needs a collection", false)
+	}
+}
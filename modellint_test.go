@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type lintAddress struct {
+	City string `bson:"city"`
+	Town string `bson:"city"`
+}
+
+type LintTimestamps struct {
+	Created time.Time `bson:"name"`
+}
+
+type lintDocument struct {
+	DocumentBase   `bson:",inline"`
+	LintTimestamps `bson:",inline"`
+	Name           string `bson:"name"`
+	Untagged       string
+	Address        *lintAddress      `bson:"address"`
+	Flags          int               `bson:",inline"`
+	Callback       func()            `bson:"callback"`
+	Scores         map[float64]int   `bson:"scores"`
+	Attachment     Overflow          `bson:"attachment" overflow:"big"`
+	Start          time.Time         `bson:"start" zone:"Zone"`
+	Owner          string            `bson:"owner" ref:""`
+	Price          Decimal           `bson:"price"`
+	Status         Enum[string]      `bson:"status"`
+	Cache          chan int          `bongo:"transient"`
+	Labels         map[string]string `bson:"labels"`
+	secret         string            `bson:"secret"`
+}
+
+type lintValidDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string    `bson:"name" index:",unique"`
+	Start        time.Time `bson:"start" zone:"Zone"`
+	Zone         string    `bson:"zone"`
+}
+
+func TestModelLint(t *testing.T) {
+	Convey("Model linting", t, func() {
+		problems := func(model *Model) map[string]bool {
+			found := map[string]bool{}
+			for _, issue := range model.Lint() {
+				found[issue.Field] = issue.Warning
+			}
+			return found
+		}
+
+		Convey("should find the issues of a model", func() {
+			found := problems(&Model{Collection: "lint", Type: reflect.TypeOf(lintDocument{})})
+			So(found, ShouldResemble, map[string]bool{
+				"Address.Town": false,
+				"Attachment":   false,
+				"Callback":     false,
+				"Flags":        false,
+				"Name":         false,
+				"Owner":        false,
+				"Scores":       false,
+				"Start":        false,
+				"Untagged":     true,
+				"secret":       true,
+			})
+		})
+
+		Convey("should accept valid models", func() {
+			So(problems(&Model{Collection: "lint", Type: reflect.TypeOf(lintValidDocument{})}), ShouldBeEmpty)
+		})
+
+		Convey("should find invalid index tags", func() {
+			type badIndex struct {
+				DocumentBase `bson:",inline"`
+				Name         string `bson:"name" index:",unknown"`
+			}
+			So(problems(&Model{Collection: "lint", Type: reflect.TypeOf(badIndex{})}), ShouldContainKey, "badIndex")
+		})
+
+		Convey("should fail ValidateModels with the errors and log the warnings", func() {
+			registry.Lock()
+			previous := registry.models
+			registry.models = map[string]*Model{"lint": {Collection: "lint", Type: reflect.TypeOf(lintDocument{})}}
+			registry.Unlock()
+			defer func() {
+				registry.Lock()
+				registry.models = previous
+				registry.Unlock()
+			}()
+
+			logger := &testLogger{}
+			conn := &Connection{Config: &Config{Database: "bongotest", Logger: logger}, Context: &Context{}}
+			err := conn.ValidateModels()
+			So(err, ShouldHaveSameTypeAs, &ModelSchemaError{})
+			So(len(err.(*ModelSchemaError).Issues), ShouldEqual, 8)
+			So(err.Error(), ShouldContainSubstring, "lint Name: bson name name is also used by LintTimestamps.Created")
+			So(len(logger.lines), ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// An amount in a currency, stored as {amount: decimal128, currency: "EUR"}. Adding, subtracting and comparing
+// amounts in different currencies fails with a *CurrencyMismatchError
+type Money struct {
+	Amount Decimal `bson:"amount" json:"amount"`
+	// An ISO 4217 code like "EUR"
+	Currency string `bson:"currency" json:"currency"`
+}
+
+// Returned by operations on amounts in different currencies
+type CurrencyMismatchError struct {
+	Currencies []string
+}
+
+func (e *CurrencyMismatchError) Error() string {
+	return "can't combine amounts in " + e.Currencies[0] + " and " + e.Currencies[1]
+}
+
+// An amount like "19.99", in a currency
+func NewMoney(amount string, currency string) (Money, error) {
+	d, err := ParseDecimal(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: d, Currency: currency}, nil
+}
+
+func (m Money) sameCurrency(o Money) error {
+	if m.Currency != o.Currency {
+		return &CurrencyMismatchError{Currencies: []string{m.Currency, o.Currency}}
+	}
+	return nil
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount.Add(o.Amount), Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount.Sub(o.Amount), Currency: m.Currency}, nil
+}
+
+// Multiplies the amount, e.g. by a quantity or a tax rate. Round the result to the places of the currency
+func (m Money) Mul(factor Decimal) Money {
+	return Money{Amount: m.Amount.Mul(factor), Currency: m.Currency}
+}
+
+// Rounds the amount to the given number of decimal places, half away from zero
+func (m Money) Round(places int) Money {
+	return Money{Amount: m.Amount.Round(places), Currency: m.Currency}
+}
+
+// -1, 0 or 1 if m is less than, equal to or greater than o
+func (m Money) Cmp(o Money) (int, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return 0, err
+	}
+	return m.Amount.Cmp(o.Amount), nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount.IsZero()
+}
+
+// e.g. "19.99 EUR"
+func (m Money) String() string {
+	return m.Amount.String() + " " + m.Currency
+}
+
+// Checks for a Validate hook that the currency is an ISO 4217 code (three upper case letters) and the amount has at
+// most the given number of decimal places. Returns a *FieldError with the code "currency" or "max_places"
+func (m Money) Validate(field string, places int) error {
+	valid := len(m.Currency) == 3
+	for _, c := range m.Currency {
+		valid = valid && c >= 'A' && c <= 'Z'
+	}
+	if !valid {
+		return &FieldError{Field: field + ".currency", Code: "currency",
+			Message: field + " must have a three letter currency code"}
+	}
+	return m.Amount.ValidatePlaces(field+".amount", places)
+}
+
+// A filter for the Money at path in the currency of min or max, with an amount between min (inclusive) and max
+// (exclusive). Nil bounds are left out, and they must be in the same currency
+func MoneyRange(path string, min *Money, max *Money) (bson.M, error) {
+	filter := bson.M{}
+	var minAmount, maxAmount *Decimal
+	if min != nil {
+		minAmount = &min.Amount
+		filter[path+".currency"] = min.Currency
+	}
+	if max != nil {
+		if min != nil {
+			if err := min.sameCurrency(*max); err != nil {
+				return nil, err
+			}
+		}
+		maxAmount = &max.Amount
+		filter[path+".currency"] = max.Currency
+	}
+	for key, value := range DecimalRange(path+".amount", minAmount, maxAmount) {
+		filter[key] = value
+	}
+	return filter, nil
+}
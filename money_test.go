@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestMoney(t *testing.T) {
+	Convey("Money", t, func() {
+		price, err := NewMoney("19.99", "EUR")
+		So(err, ShouldBeNil)
+
+		Convey("should add and compare amounts in the same currency", func() {
+			total, err := price.Add(price)
+			So(err, ShouldBeNil)
+			So(total.String(), ShouldEqual, "39.98 EUR")
+
+			cmp, err := total.Cmp(price)
+			So(err, ShouldBeNil)
+			So(cmp, ShouldEqual, 1)
+
+			So(price.Mul(mustParseDecimal("0.077")).Round(2).String(), ShouldEqual, "1.54 EUR")
+		})
+
+		Convey("should not mix currencies", func() {
+			dollars, _ := NewMoney("1", "USD")
+			_, err := price.Sub(dollars)
+			So(err, ShouldHaveSameTypeAs, &CurrencyMismatchError{})
+			So(err.Error(), ShouldEqual, "can't combine amounts in EUR and USD")
+		})
+
+		Convey("should validate", func() {
+			So(price.Validate("price", 2), ShouldBeNil)
+			So(Money{Amount: price.Amount, Currency: "eur"}.Validate("price", 2).(*FieldError).Code, ShouldEqual, "currency")
+			So(price.Validate("price", 0).(*FieldError).Field, ShouldEqual, "price.amount")
+		})
+
+		Convey("should build range filters", func() {
+			max, _ := NewMoney("100", "EUR")
+			filter, err := MoneyRange("price", &price, &max)
+			So(err, ShouldBeNil)
+			So(filter, ShouldResemble, bson.M{
+				"price.currency": "EUR",
+				"price.amount":   bson.M{"$gte": price.Amount, "$lt": max.Amount},
+			})
+
+			dollars, _ := NewMoney("1", "USD")
+			_, err = MoneyRange("price", &price, &dollars)
+			So(err, ShouldHaveSameTypeAs, &CurrencyMismatchError{})
+		})
+	})
+}
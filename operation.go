@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Get a copy of the collection whose operations time out after d, e.g.
+//
+//	err := conn.Collection("reports").WithTimeout(time.Minute).Save(report)
+func (c *Collection) WithTimeout(d time.Duration) *Collection {
+	clone := *c
+	clone.Timeout = d
+	return &clone
+}
+
+// Get a copy of the collection for one request. Its operations run within ctx (and stop when it is canceled), and
+// its hooks read the values of ctx through Collection.Context, e.g.
+//
+//	err := conn.Collection("orders").WithContext(r.Context()).Save(order)
+func (c *Collection) WithContext(ctx context.Context) *Collection {
+	clone := *c
+	clone.Context = c.Context.WithContext(ctx)
+	return &clone
+}
+
+// Get a copy of the collection with a value in its Context, e.g. for one request. The value isn't visible to the
+// collection it was derived from:
+//
+//	orders := conn.Collection("orders").WithContextValue(bongo.LocaleContextKey, "de")
+func (c *Collection) WithContextValue(key interface{}, value interface{}) *Collection {
+	clone := *c
+	clone.Context = c.Context.Derive()
+	clone.Context.setValue(key, value)
+	return &clone
+}
+
+// Get a copy of the collection whose operations all run on sess, e.g. for snapshot reads, causally consistent reads
+// of one's own writes or a manual transaction:
+//
+//	sess, err := conn.Client().StartSession(options.Session().SetCausalConsistency(true))
+//	defer sess.EndSession(ctx)
+//	orders := conn.Collection("orders").WithSession(sess)
+//
+// Like the session itself, the copy must not be used by several goroutines at once
+func (c *Collection) WithSession(sess mongo.Session) *Collection {
+	clone := *c
+	clone.Session = sess
+	return &clone
+}
+
+// The context operations run in when the caller doesn't pass one
+func (c *Collection) baseContext() context.Context {
+	if c == nil {
+		return context.Background()
+	}
+	return c.Context.Parent()
+}
+
+// The timeout for operations on the collection, or zero for none
+func (c *Collection) timeout() time.Duration {
+	if c == nil {
+		return 0
+	}
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	if c.Connection != nil && c.Connection.config() != nil {
+		return c.Connection.config().OperationTimeout
+	}
+	return 0
+}
+
+// Creates the context for a single operation on the collection, bounded by its timeout
+func (c *Collection) operationContext() (context.Context, context.CancelFunc) {
+	return c.withTimeout(c.baseContext())
+}
+
+// Advances a cursor within the operation timeout, like ResultSet.Next, so a stalled getMore can't block forever
+func (c *Collection) nextDocument(cursor *mongo.Cursor) bool {
+	ctx, cancel := c.operationContext()
+	defer cancel()
+	return cursor.Next(ctx)
+}
+
+// Closes a cursor within the operation timeout
+func (c *Collection) closeCursor(cursor *mongo.Cursor) error {
+	ctx, cancel := c.operationContext()
+	defer cancel()
+	return cursor.Close(ctx)
+}
+
+// Derives the context for a single operation from a caller's context, on the collection's session if it has one
+func (c *Collection) withTimeout(parent context.Context) (context.Context, context.CancelFunc) {
+	if c != nil && c.Session != nil {
+		parent = mongo.NewSessionContext(parent, c.Session)
+	}
+	if timeout := c.timeout(); timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// Runs a single database operation on the collection, applying the connection-wide policies (timeout, rate
+// limiting, circuit breaker, retries etc.)
+func (c *Collection) runOperation(op string, fn func(ctx context.Context) error) error {
+	return c.runOperationContext(c.baseContext(), op, fn)
+}
+
+// Runs a single database operation on the collection like runOperation, within a caller's context
+func (c *Collection) runOperationContext(parent context.Context, op string, fn func(ctx context.Context) error) error {
+	return c.runOperationPolicy(parent, op, true, fn)
+}
+
+// Runs a write that must not be repeated, like an $inc, like runOperation but without the retries of the
+// RetryPolicy, since a write that timed out or lost its connection may still have been applied. The driver's own
+// retryable writes are safe, the server applies them once
+func (c *Collection) runOperationOnce(parent context.Context, op string, fn func(ctx context.Context) error) error {
+	return c.runOperationPolicy(parent, op, false, fn)
+}
+
+func (c *Collection) runOperationPolicy(parent context.Context, op string, retry bool, fn func(ctx context.Context) error) error {
+	ctx, cancel := c.withTimeout(parent)
+	defer cancel()
+
+	if c.Connection == nil || c.Connection.config() == nil {
+		return fn(ctx)
+	}
+
+	start := time.Now()
+	var err error
+	defer func() {
+		elapsed := time.Since(start)
+		c.Connection.recordLatency(c, op, elapsed, err)
+		c.Connection.logSlowOperation(c, op, elapsed)
+	}()
+
+	if limiter := c.Connection.RateLimiter(c); limiter != nil {
+		var release func()
+		release, err = limiter.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	breaker := c.Connection.CircuitBreaker(c)
+	if breaker != nil {
+		if err = breaker.Allow(); err != nil {
+			return err
+		}
+	}
+
+	if retry {
+		err = c.Connection.config().RetryPolicy.Do(ctx, fn)
+	} else {
+		err = fn(ctx)
+	}
+
+	if breaker != nil {
+		// A deadline of the caller isn't the cluster's fault, unlike the operation timeout
+		if err != nil && parent.Err() != nil {
+			breaker.Release()
+		} else {
+			breaker.Record(err)
+		}
+	}
+
+	return err
+}
+
+// Runs an UpdateMany as an operation on the collection
+func (c *Collection) updateMany(query interface{}, update interface{}) (*mongo.UpdateResult, error) {
+	var res *mongo.UpdateResult
+	err := c.runOperation("updateMany", func(ctx context.Context) error {
+		filter, err := c.guardFilter(ctx, query)
+		if err != nil {
+			return err
+		}
+		opts := options.Update()
+		commentOn(ctx, c, opts.SetComment)
+		res, err = c.Collection().UpdateMany(ctx, filter, update, opts)
+		return err
+	})
+	return res, err
+}
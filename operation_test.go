@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+func TestOperationTimeout(t *testing.T) {
+	Convey("Operation timeouts", t, func() {
+		conn := &Connection{Config: &Config{OperationTimeout: time.Second}}
+		col := &Collection{Name: "tests", Connection: conn}
+
+		deadline := func(c *Collection) (time.Duration, bool) {
+			var remaining time.Duration
+			var ok bool
+			c.runOperation("test", func(ctx context.Context) error {
+				var d time.Time
+				d, ok = ctx.Deadline()
+				remaining = time.Until(d)
+				return nil
+			})
+			return remaining, ok
+		}
+
+		Convey("should apply the configured timeout", func() {
+			remaining, ok := deadline(col)
+			So(ok, ShouldBeTrue)
+			So(remaining, ShouldBeBetween, 900*time.Millisecond, time.Second)
+		})
+
+		Convey("should be overridable per collection", func() {
+			remaining, ok := deadline(col.WithTimeout(time.Minute))
+			So(ok, ShouldBeTrue)
+			So(remaining, ShouldBeGreaterThan, time.Second)
+			So(col.Timeout, ShouldEqual, 0)
+		})
+
+		Convey("should not set a deadline without a timeout", func() {
+			conn.Config.OperationTimeout = 0
+			_, ok := deadline(col)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("should fail operations that take too long", func() {
+			err := col.WithTimeout(10*time.Millisecond).runOperation("test", func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+			So(err, ShouldEqual, context.DeadlineExceeded)
+		})
+	})
+}
+
+// A session that is only compared, never used
+type fakeSession struct {
+	mongo.Session
+}
+
+func TestCollectionWithSession(t *testing.T) {
+	Convey("Session-pinned collections", t, func() {
+		conn := &Connection{Config: &Config{}}
+		col := &Collection{Name: "tests", Connection: conn}
+		sess := &fakeSession{}
+
+		session := func(c *Collection) mongo.Session {
+			var found mongo.Session
+			c.runOperation("test", func(ctx context.Context) error {
+				found = mongo.SessionFromContext(ctx)
+				return nil
+			})
+			return found
+		}
+
+		Convey("should run the operations on the session", func() {
+			pinned := col.WithSession(sess)
+			So(session(pinned), ShouldEqual, sess)
+			So(col.Session, ShouldBeNil)
+			So(session(col), ShouldBeNil)
+		})
+
+		Convey("should keep the session when deriving the collection", func() {
+			derived := col.WithSession(sess).WithTimeout(time.Minute).WithContext(context.Background())
+			So(session(derived), ShouldEqual, sess)
+		})
+	})
+}
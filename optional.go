@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"reflect"
+)
+
+// A field that is either absent, null or holds a value, e.g. for partial updates where null clears a field and an
+// absent field is left alone. Tag the field with omitempty so absent values are left out of documents:
+//
+//	type PersonPatch struct {
+//		Nickname bongo.Optional[string] `bson:"nickname,omitempty" json:"nickname"`
+//	}
+//
+// Fields missing from found documents or JSON stay absent. Absent values are written to JSON as null, so use
+// `json:",omitzero"` (Go 1.24) to leave them out there as well
+type Optional[T any] struct {
+	value T
+	set   bool
+	null  bool
+}
+
+// An optional holding a value
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{value: value, set: true}
+}
+
+// An optional that is explicitly null
+func Null[T any]() Optional[T] {
+	return Optional[T]{set: true, null: true}
+}
+
+// Whether the optional is absent, which omitempty leaves out
+func (o Optional[T]) IsZero() bool {
+	return !o.set
+}
+
+func (o Optional[T]) IsNull() bool {
+	return o.set && o.null
+}
+
+// Whether the optional holds a value
+func (o Optional[T]) IsPresent() bool {
+	return o.set && !o.null
+}
+
+// The value, and whether there is one
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.IsPresent()
+}
+
+// The value, or fallback if there is none
+func (o Optional[T]) OrElse(fallback T) T {
+	if o.IsPresent() {
+		return o.value
+	}
+	return fallback
+}
+
+func (o Optional[T]) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if !o.IsPresent() {
+		return bsontype.Null, nil, nil
+	}
+	return bson.MarshalValueWithRegistry(Registry, o.value)
+}
+
+func (o *Optional[T]) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	if t == bsontype.Null || t == bsontype.Undefined {
+		*o = Null[T]()
+		return nil
+	}
+	var value T
+	if err := (bson.RawValue{Type: t, Value: data}).UnmarshalWithRegistry(Registry, &value); err != nil {
+		return err
+	}
+	*o = Some(value)
+	return nil
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.IsPresent() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// Only called for fields that are in the JSON, so missing fields stay absent
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*o = Null[T]()
+		return nil
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*o = Some(value)
+	return nil
+}
+
+// Implemented by Optional, so PatchFromStruct finds optional fields of any type
+type optionalField interface {
+	optional() (set bool, null bool, value interface{})
+}
+
+func (o Optional[T]) optional() (bool, bool, interface{}) {
+	return o.set, o.null, o.value
+}
+
+var optionalFieldType = reflect.TypeOf((*optionalField)(nil)).Elem()
+
+// Builds an update from the Optional and pointer fields of a struct, e.g. a decoded PATCH request: fields with a
+// value are $set, null Optionals are $unset, and absent Optionals and nil pointers are left alone. Other fields are
+// ignored, except for structs, whose fields are walked. Collection.Patch applies the update with validation and hooks
+//
+//	update, err := bongo.PatchFromStruct(&PersonPatch{Nickname: bongo.Null[string]()})
+//	// bson.M{"$unset": bson.M{"nickname": ""}}
+func PatchFromStruct(v interface{}) (bson.M, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return bson.M{}, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, errors.New("PatchFromStruct needs a struct, not " + value.Kind().String())
+	}
+
+	set, unset := bson.M{}, bson.M{}
+	walkFields(value.Type(), "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		if field.Type.Kind() == reflect.Ptr && !field.Type.Implements(optionalFieldType) {
+			if fieldValue := fieldByGoPath(value, goPath); fieldValue.IsValid() && !fieldValue.IsNil() {
+				set[path] = fieldValue.Elem().Interface()
+			}
+			return false
+		}
+		if !field.Type.Implements(optionalFieldType) {
+			return true
+		}
+		fieldValue := fieldByGoPath(value, goPath)
+		if !fieldValue.IsValid() {
+			return false
+		}
+		isSet, isNull, optionalValue := fieldValue.Interface().(optionalField).optional()
+		switch {
+		case isSet && isNull:
+			unset[path] = ""
+		case isSet:
+			set[path] = optionalValue
+		}
+		return false
+	})
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
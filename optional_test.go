@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type optionalDocument struct {
+	DocumentBase `bson:",inline"`
+	Nickname     Optional[string] `bson:"nickname,omitempty" json:"nickname"`
+	Age          Optional[int]    `bson:"age,omitempty" json:"age"`
+	Name         string           `bson:"name" json:"name"`
+}
+
+func TestOptional(t *testing.T) {
+	Convey("Optional", t, func() {
+		Convey("should tell absent, null and present values", func() {
+			absent, null, some := Optional[string]{}, Null[string](), Some("foo")
+			So(absent.IsZero(), ShouldBeTrue)
+			So(null.IsNull(), ShouldBeTrue)
+			So(null.IsZero(), ShouldBeFalse)
+			value, ok := some.Get()
+			So(value, ShouldEqual, "foo")
+			So(ok, ShouldBeTrue)
+			So(null.OrElse("bar"), ShouldEqual, "bar")
+		})
+
+		Convey("should leave out absent values and store null", func() {
+			raw, err := bson.Marshal(&optionalDocument{Nickname: Null[string](), Age: Optional[int]{}})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("nickname").Type, ShouldEqual, bson.TypeNull)
+			_, err = bson.Raw(raw).LookupErr("age")
+			So(err, ShouldNotBeNil)
+
+			found := &optionalDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Nickname.IsNull(), ShouldBeTrue)
+			So(found.Age.IsZero(), ShouldBeTrue)
+		})
+
+		Convey("should store values", func() {
+			raw, err := bson.Marshal(&optionalDocument{Age: Some(42)})
+			So(err, ShouldBeNil)
+
+			found := &optionalDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Age.OrElse(0), ShouldEqual, 42)
+		})
+
+		Convey("should tell null and missing JSON fields", func() {
+			patch := &optionalDocument{}
+			So(json.Unmarshal([]byte(`{"nickname": null, "name": "foo"}`), patch), ShouldBeNil)
+			So(patch.Nickname.IsNull(), ShouldBeTrue)
+			So(patch.Age.IsZero(), ShouldBeTrue)
+
+			data, err := json.Marshal(&optionalDocument{Age: Some(3)})
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, `"age":3`)
+		})
+
+		Convey("should build updates", func() {
+			update, err := PatchFromStruct(&optionalDocument{Nickname: Null[string](), Age: Some(42), Name: "ignored"})
+			So(err, ShouldBeNil)
+			So(update, ShouldResemble, bson.M{"$set": bson.M{"age": 42}, "$unset": bson.M{"nickname": ""}})
+
+			update, err = PatchFromStruct(&optionalDocument{})
+			So(err, ShouldBeNil)
+			So(update, ShouldResemble, bson.M{})
+
+			_, err = PatchFromStruct("foo")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,259 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// The GridFS bucket that Overflow fields are stored in, in the database of their collection
+const OverflowBucket = "bongo_overflow"
+
+// The size in bytes above which Overflow fields are moved to GridFS, unless their `overflow` tag says otherwise
+const OVERFLOW_SIZE = 256 * 1024
+
+// Bytes that are stored in the document while they are small, and in GridFS once they are larger than the size in
+// the field's `overflow` tag (OVERFLOW_SIZE if the tag is missing or empty), keeping the document small. Saves
+// upload changed data and finds download it again, so Data is always there to read and write:
+//
+//	type Message struct {
+//		bongo.DocumentBase `bson:",inline"`
+//		Payload bongo.Overflow `bson:"payload" overflow:"1048576"`
+//	}
+//
+// Uploads happen before the document is written and outside of any transaction, so a failed save can leave a file
+// behind. Files that are replaced are deleted once the save succeeds, and files of deleted documents once the
+// delete succeeds
+type Overflow struct {
+	Data []byte
+
+	// The GridFS file holding Data, if it overflowed
+	file primitive.ObjectID
+	// The checksum of Data when it was uploaded or downloaded, to tell whether it changed since
+	sum [sha256.Size]byte
+	// Files replaced by a save that are deleted once the save succeeds
+	replaced []primitive.ObjectID
+}
+
+// The reference to an overflowed field that is stored in the document
+type overflowRef struct {
+	File primitive.ObjectID `bson:"gridfs"`
+	Size int                `bson:"size"`
+}
+
+// Whether Data is stored in GridFS
+func (o Overflow) Overflowed() bool {
+	return !o.file.IsZero()
+}
+
+func (o Overflow) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if o.file.IsZero() {
+		return bson.MarshalValue(primitive.Binary{Data: o.Data})
+	}
+	return bson.MarshalValue(overflowRef{File: o.file, Size: len(o.Data)})
+}
+
+func (o *Overflow) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	*o = Overflow{}
+	raw := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		return nil
+	case bsontype.EmbeddedDocument:
+		ref := overflowRef{}
+		if err := raw.Unmarshal(&ref); err != nil {
+			return err
+		}
+		o.file = ref.File
+		return nil
+	}
+	binary := primitive.Binary{}
+	if err := raw.Unmarshal(&binary); err != nil {
+		return err
+	}
+	o.Data = binary.Data
+	return nil
+}
+
+var overflowType = reflect.TypeOf(Overflow{})
+
+type overflowField struct {
+	goPath string
+	size   int
+}
+
+var overflowFieldsCache sync.Map
+
+// The Overflow fields of a type with their size thresholds, cached per type
+func overflowFields(t reflect.Type) []overflowField {
+	if fields, ok := overflowFieldsCache.Load(t); ok {
+		return fields.([]overflowField)
+	}
+
+	fields := make([]overflowField, 0)
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType != overflowType {
+			return true
+		}
+		size, err := strconv.Atoi(field.Tag.Get("overflow"))
+		if err != nil || size <= 0 {
+			size = OVERFLOW_SIZE
+		}
+		fields = append(fields, overflowField{goPath: goPath, size: size})
+		return false
+	})
+
+	overflowFieldsCache.Store(t, fields)
+	return fields
+}
+
+// Calls fn with each Overflow field of a document that is set, and its size threshold
+func eachOverflow(doc interface{}, fn func(field *Overflow, size int) error) error {
+	v := reflect.ValueOf(doc)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for _, overflow := range overflowFields(v.Type()) {
+		field := fieldByGoPath(v, overflow.goPath)
+		if !field.IsValid() {
+			continue
+		}
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		if err := fn(field.Addr().Interface().(*Overflow), overflow.size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collection) overflowBucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(c.Connection.Client().Database(c.Database), options.GridFSBucket().SetName(OverflowBucket))
+}
+
+// Uploads the Overflow fields of a document that grew past their threshold or changed since they were uploaded, and
+// takes fields that shrank back into the document
+func (c *Collection) storeOverflow(parent context.Context, doc interface{}) error {
+	return eachOverflow(doc, func(field *Overflow, size int) error {
+		if len(field.Data) <= size {
+			if !field.file.IsZero() {
+				field.replaced = append(field.replaced, field.file)
+				field.file = primitive.NilObjectID
+			}
+			return nil
+		}
+
+		sum := sha256.Sum256(field.Data)
+		if !field.file.IsZero() && sum == field.sum {
+			return nil
+		}
+
+		var file primitive.ObjectID
+		err := c.runOperationContext(parent, "overflowUpload", func(ctx context.Context) error {
+			bucket, err := c.overflowBucket()
+			if err != nil {
+				return err
+			}
+			if deadline, ok := ctx.Deadline(); ok {
+				bucket.SetWriteDeadline(deadline)
+			}
+			file, err = bucket.UploadFromStream(c.Name, bytes.NewReader(field.Data))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if !field.file.IsZero() {
+			field.replaced = append(field.replaced, field.file)
+		}
+		field.file, field.sum = file, sum
+		return nil
+	})
+}
+
+// Downloads the Overflow fields of a found document
+func (c *Collection) loadOverflow(parent context.Context, doc interface{}) error {
+	return eachOverflow(doc, func(field *Overflow, size int) error {
+		if field.file.IsZero() || field.Data != nil {
+			return nil
+		}
+
+		data := &bytes.Buffer{}
+		err := c.runOperationContext(parent, "overflowDownload", func(ctx context.Context) error {
+			data.Reset()
+			bucket, err := c.overflowBucket()
+			if err != nil {
+				return err
+			}
+			if deadline, ok := ctx.Deadline(); ok {
+				bucket.SetReadDeadline(deadline)
+			}
+			_, err = bucket.DownloadToStream(field.file, data)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		field.Data = data.Bytes()
+		field.sum = sha256.Sum256(field.Data)
+		return nil
+	})
+}
+
+// Deletes the files that a saved document's Overflow fields no longer use, or all its files if it was deleted
+func (c *Collection) dropOverflow(doc interface{}, deleted bool) {
+	eachOverflow(doc, func(field *Overflow, size int) error {
+		files := field.replaced
+		field.replaced = nil
+		if deleted && !field.file.IsZero() {
+			files = append(files, field.file)
+		}
+		c.deleteFiles(files)
+		return nil
+	})
+}
+
+// Deletes files from the OverflowBucket. The document is already written, so failures are only logged
+func (c *Collection) deleteFiles(files []primitive.ObjectID) {
+	for _, file := range files {
+		err := c.runOperation("deleteFile", func(ctx context.Context) error {
+			bucket, err := c.overflowBucket()
+			if err != nil {
+				return err
+			}
+			return bucket.DeleteContext(ctx, file)
+		})
+		if err != nil && err != gridfs.ErrFileNotFound {
+			c.Connection.logf(LOG_ERROR, "failed to delete file %s of %s: %s", file.Hex(), c.Name, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+)
+
+type overflowDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string    `bson:"name"`
+	Payload      Overflow  `bson:"payload" overflow:"16"`
+	Raw          *Overflow `bson:"raw,omitempty"`
+}
+
+func TestOverflowFields(t *testing.T) {
+	Convey("Overflow fields", t, func() {
+		Convey("should be found with their thresholds", func() {
+			fields := overflowFields(reflect.TypeOf(overflowDocument{}))
+			So(fields, ShouldResemble, []overflowField{{goPath: "Payload", size: 16}, {goPath: "Raw", size: OVERFLOW_SIZE}})
+		})
+
+		Convey("should be stored inline until they overflow", func() {
+			raw, err := bson.Marshal(&overflowDocument{Payload: Overflow{Data: []byte("small")}})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("payload").Type, ShouldEqual, bson.TypeBinary)
+
+			found := &overflowDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(string(found.Payload.Data), ShouldEqual, "small")
+			So(found.Payload.Overflowed(), ShouldBeFalse)
+		})
+
+		Convey("should store a reference to their file once they overflow", func() {
+			file := primitive.NewObjectID()
+			raw, err := bson.Marshal(&overflowDocument{Payload: Overflow{Data: []byte("large"), file: file}})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("payload", "gridfs").ObjectID(), ShouldEqual, file)
+
+			found := &overflowDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Payload.Data, ShouldBeNil)
+			So(found.Payload.file, ShouldEqual, file)
+		})
+
+		Convey("should be taken back into the document when they shrink", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+			file := primitive.NewObjectID()
+			doc := &overflowDocument{Payload: Overflow{Data: []byte("small"), file: file}}
+
+			So(conn.Collection("tests").storeOverflow(context.Background(), doc), ShouldBeNil)
+			So(doc.Payload.Overflowed(), ShouldBeFalse)
+			So(doc.Payload.replaced, ShouldResemble, []primitive.ObjectID{file})
+		})
+	})
+}
+
+func TestOverflow(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Overflow", t, func() {
+		col := conn.Collection("tests")
+		large := bytes.Repeat([]byte("x"), 1024)
+
+		Convey("should move large fields to GridFS and load them on find", func() {
+			doc := &overflowDocument{Name: "foo", Payload: Overflow{Data: large}}
+			So(col.Save(doc), ShouldBeNil)
+			So(doc.Payload.Overflowed(), ShouldBeTrue)
+
+			raw, err := col.Collection().FindOne(context.Background(), bson.M{"_id": doc.ID}).Raw()
+			So(err, ShouldBeNil)
+			So(raw.Lookup("payload").Type, ShouldEqual, bson.TypeEmbeddedDocument)
+
+			found := &overflowDocument{}
+			So(col.FindByID(doc.ID, found), ShouldBeNil)
+			So(found.Payload.Data, ShouldResemble, large)
+
+			found.Payload.Data = []byte("small")
+			So(col.Save(found), ShouldBeNil)
+			files, err := conn.Session.Database("bongotest").Collection(OverflowBucket+".files").CountDocuments(context.Background(), bson.M{})
+			So(err, ShouldBeNil)
+			So(files, ShouldEqual, 0)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
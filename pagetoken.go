@@ -0,0 +1,429 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"hash"
+	"sort"
+	"strings"
+)
+
+// A position in a sorted result set, packed into page tokens. Pages after the position start with the record
+// following it, pages before it end with the record preceding it
+type PageCursor struct {
+	// The sort field (or _id if the result set isn't sorted) and its direction, 1 or -1
+	Field     string `bson:"f"`
+	Direction int    `bson:"d"`
+
+	// The sort value and _id of the record at the position
+	Value bson.RawValue `bson:"v"`
+	ID    bson.RawValue `bson:"i"`
+
+	// Whether the token is for the page before the position
+	Before bool `bson:"b,omitempty"`
+}
+
+// Returned for page tokens that are malformed, tampered with or don't match the result set
+type InvalidPageTokenError struct {
+	Reason string
+}
+
+func (e *InvalidPageTokenError) Error() string {
+	return "invalid page token: " + e.Reason
+}
+
+// Packs a cursor into an encrypted, URL-safe token (AES-GCM with a key derived from the secret), so the values
+// of the record at the position can't be read or changed. The token only decodes with the same scope, e.g. a
+// digest of the result set it is for
+func EncodePageToken(secret []byte, scope []byte, cursor *PageCursor) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("a secret is required to encrypt page tokens")
+	}
+
+	data, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := pageTokenCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, data, scope)), nil
+}
+
+// Decrypts and unpacks a token created by EncodePageToken with the same scope
+func DecodePageToken(secret []byte, scope []byte, token string) (*PageCursor, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("a secret is required to decrypt page tokens")
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, &InvalidPageTokenError{"malformed"}
+	}
+	aead, err := pageTokenCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, &InvalidPageTokenError{"malformed"}
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, ciphertext, scope)
+	if err != nil {
+		return nil, &InvalidPageTokenError{"it was tampered with or belongs to another result set"}
+	}
+
+	cursor := &PageCursor{}
+	if err := bson.Unmarshal(data, cursor); err != nil {
+		return nil, &InvalidPageTokenError{"malformed"}
+	}
+	return cursor, nil
+}
+
+func pageTokenCipher(secret []byte) (cipher.AEAD, error) {
+	// Derive the key, so secrets of any length work and the secret isn't used as a key directly
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("bongo page tokens"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// A digest of the collection and filter of a result set, so its tokens can't be used on another one. Documents
+// in the filter are digested with their keys sorted, since bson.M has no stable order
+func pageTokenScope(c *Collection, filter interface{}) ([]byte, error) {
+	raw, err := bson.MarshalWithRegistry(Registry, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.New()
+	writeScopePart(digest, []byte(c.Database+"."+c.Name))
+	if err := digestDocument(digest, raw); err != nil {
+		return nil, err
+	}
+	return digest.Sum(nil), nil
+}
+
+func digestDocument(digest hash.Hash, doc bson.Raw) error {
+	elements, err := doc.Elements()
+	if err != nil {
+		return err
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		return elements[i].Key() < elements[j].Key()
+	})
+
+	digest.Write([]byte{'{'})
+	for _, e := range elements {
+		writeScopePart(digest, []byte(e.Key()))
+		if err := digestValue(digest, e.Value()); err != nil {
+			return err
+		}
+	}
+	digest.Write([]byte{'}'})
+	return nil
+}
+
+func digestValue(digest hash.Hash, value bson.RawValue) error {
+	digest.Write([]byte{byte(value.Type)})
+	switch value.Type {
+	case bson.TypeEmbeddedDocument:
+		return digestDocument(digest, value.Document())
+	case bson.TypeArray:
+		values, err := value.Array().Values()
+		if err != nil {
+			return err
+		}
+		digest.Write([]byte{'['})
+		for _, v := range values {
+			if err := digestValue(digest, v); err != nil {
+				return err
+			}
+		}
+		digest.Write([]byte{']'})
+		return nil
+	}
+	writeScopePart(digest, value.Value)
+	return nil
+}
+
+// Writes a length prefixed part, so different parts can't run into each other
+func writeScopePart(digest hash.Hash, part []byte) {
+	length := make([]byte, 8)
+	binary.BigEndian.PutUint64(length, uint64(len(part)))
+	digest.Write(length)
+	digest.Write(part)
+}
+
+// Paginates by position instead of page number (keyset pagination), so pages stay stable while documents are
+// inserted or removed, and deep pages are as fast as the first one. Pass an empty token for the first page, then
+// the NextCursor or PrevCursor of the returned info. Tokens are encrypted with Config.PageTokenSecret and only
+// work on result sets of the same collection and filter.
+//
+// The result set may be sorted by one field (Query.SetSort(bson.D{{"created_at", -1}})), which should be
+// present in every document; _id is used to break ties. Without a sort, it is sorted by _id. The records aren't
+// counted, so TotalRecords and TotalPages are -1. A perPage below 1 uses the collection's CollectionDefaults.PerPage
+func (r *ResultSet) PaginateWithToken(token string, perPage int) (*PaginationInfo, error) {
+	if r.Collection == nil || r.Collection.Connection == nil || r.Collection.Connection.Config == nil {
+		return nil, errors.New("the result set has no connection")
+	}
+	secret := r.Collection.Connection.Config.PageTokenSecret
+	perPage = r.Collection.perPage(perPage)
+	if perPage < 1 {
+		return nil, errors.New("perPage must be positive")
+	}
+
+	field, direction, err := singleSortField(r.Query.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the keys of the records on the page, plus one to know whether there are more
+	filter := queryFilter(r.Params)
+	scope, err := pageTokenScope(r.Collection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var position *PageCursor
+	if len(token) > 0 {
+		position, err = DecodePageToken(secret, scope, token)
+		if err != nil {
+			return nil, err
+		}
+		if position.Field != field || position.Direction != direction {
+			return nil, &InvalidPageTokenError{"it belongs to a result set with a different sort"}
+		}
+	}
+
+	keysFilter := filter
+	keysDirection := direction
+	if position != nil {
+		if position.Before {
+			keysDirection = -direction
+		}
+		keysFilter = bson.M{"$and": bson.A{filter, keysetCondition(field, keysDirection, position.Value, position.ID, false)}}
+	}
+
+	keys, err := r.pageKeys(keysFilter, field, keysDirection, int64(perPage)+1)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(keys) > perPage
+	if more {
+		keys = keys[:perPage]
+	}
+	if position != nil && position.Before {
+		// Fetched backwards
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	info := &PaginationInfo{
+		Current:       -1,
+		TotalPages:    -1,
+		PerPage:       perPage,
+		TotalRecords:  -1,
+		RecordsOnPage: len(keys),
+	}
+
+	if position == nil {
+		info.Current = 1
+		info.HasNext = more
+	} else if position.Before {
+		info.HasPrev = more
+		info.HasNext = true
+	} else {
+		info.HasPrev = true
+		info.HasNext = more
+	}
+
+	sortSpec := bson.D{{Key: field, Value: direction}}
+	if field != "_id" {
+		sortSpec = append(sortSpec, bson.E{Key: "_id", Value: direction})
+	}
+	r.Query.SetSort(sortSpec).SetSkip(0).SetLimit(int64(perPage))
+
+	if len(keys) == 0 {
+		// Match nothing rather than everything
+		r.Params = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$exists": false}}}}
+		return info, nil
+	}
+
+	first, last := keys[0], keys[len(keys)-1]
+	r.Params = bson.M{"$and": bson.A{
+		filter,
+		keysetCondition(field, direction, first.value, first.id, true),
+		keysetCondition(field, -direction, last.value, last.id, true),
+	}}
+
+	if info.HasNext {
+		info.NextCursor, err = EncodePageToken(secret, scope, &PageCursor{Field: field, Direction: direction, Value: last.value, ID: last.id})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if info.HasPrev {
+		info.PrevCursor, err = EncodePageToken(secret, scope, &PageCursor{Field: field, Direction: direction, Value: first.value, ID: first.id, Before: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+type pageKey struct {
+	value bson.RawValue
+	id    bson.RawValue
+}
+
+// Fetches only the sort values and ids of the records in a key range
+func (r *ResultSet) pageKeys(filter interface{}, field string, direction int, limit int64) ([]*pageKey, error) {
+	sortSpec := bson.D{{Key: field, Value: direction}}
+	if field != "_id" {
+		sortSpec = append(sortSpec, bson.E{Key: "_id", Value: direction})
+	}
+	opts := options.Find().SetSort(sortSpec).SetLimit(limit).SetProjection(bson.M{field: 1, "_id": 1})
+
+	keys := make([]*pageKey, 0, limit)
+	err := r.Collection.runOperation("pageKeys", func(ctx context.Context) error {
+		keys = keys[:0]
+		guarded, err := r.Collection.guardFilter(ctx, filter)
+		if err != nil {
+			return err
+		}
+		commentOn(ctx, r.Collection, opts.SetComment)
+		cursor, err := r.Collection.Collection().Find(ctx, guarded, opts)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			key := &pageKey{id: cursor.Current.Lookup("_id")}
+			key.value = cursor.Current.Lookup(strings.Split(field, ".")...)
+			keys = append(keys, key)
+		}
+		return cursor.Err()
+	})
+	return keys, err
+}
+
+// Matches the records after a position in the given direction, including the record at it if inclusive
+func keysetCondition(field string, direction int, value bson.RawValue, id bson.RawValue, inclusive bool) bson.M {
+	op, idOp := "$gt", "$gt"
+	if direction < 0 {
+		op, idOp = "$lt", "$lt"
+	}
+	if inclusive {
+		idOp += "e"
+	}
+
+	if field == "_id" {
+		return bson.M{"_id": bson.M{idOp: id}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{idOp: id}},
+	}}
+}
+
+// Gets the field and direction of a sort on (at most) one field, defaulting to _id ascending
+func singleSortField(sortSpec interface{}) (string, int, error) {
+	var key string
+	var value interface{}
+
+	switch s := sortSpec.(type) {
+	case nil:
+		return "_id", 1, nil
+	case bson.D:
+		if len(s) == 0 {
+			return "_id", 1, nil
+		}
+		if len(s) > 2 || (len(s) == 2 && s[1].Key != "_id") {
+			return "", 0, errors.New("token pagination only supports sorting by one field")
+		}
+		key, value = s[0].Key, s[0].Value
+		if len(s) == 2 {
+			// Ties are broken by _id in the direction of the field, a sort breaking them the other way would skip
+			// or repeat them
+			direction, err := sortDirection(key, value)
+			if err != nil {
+				return "", 0, err
+			}
+			tieBreak, err := sortDirection("_id", s[1].Value)
+			if err != nil {
+				return "", 0, err
+			}
+			if tieBreak != direction {
+				return "", 0, errors.New("token pagination needs _id to be sorted in the direction of " + key)
+			}
+		}
+	case bson.M:
+		if len(s) == 0 {
+			return "_id", 1, nil
+		}
+		if len(s) > 1 {
+			return "", 0, errors.New("token pagination only supports sorting by one field")
+		}
+		for k, v := range s {
+			key, value = k, v
+		}
+	default:
+		return "", 0, errors.New("token pagination needs the sort to be a bson.D or bson.M")
+	}
+
+	direction, err := sortDirection(key, value)
+	if err != nil {
+		return "", 0, err
+	}
+	return key, direction, nil
+}
+
+func sortDirection(key string, value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return sign(int64(v)), nil
+	case int32:
+		return sign(int64(v)), nil
+	case int64:
+		return sign(v), nil
+	case float64:
+		return sign(int64(v)), nil
+	}
+	return 0, errors.New("unsupported sort direction for " + key)
+}
+
+func sign(v int64) int {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
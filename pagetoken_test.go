@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestPageTokens(t *testing.T) {
+	Convey("Page tokens", t, func() {
+		secret := []byte("secret")
+		scope := []byte("bongotest.tests")
+		_, value, _ := bson.MarshalValue(time.Date(2019, 6, 23, 12, 0, 0, 0, time.UTC))
+		id := primitive.NewObjectID()
+		_, idValue, _ := bson.MarshalValue(id)
+
+		cursor := &PageCursor{
+			Field:     "created_at",
+			Direction: -1,
+			Value:     bson.RawValue{Type: bson.TypeDateTime, Value: value},
+			ID:        bson.RawValue{Type: bson.TypeObjectID, Value: idValue},
+		}
+
+		Convey("should round trip cursors", func() {
+			token, err := EncodePageToken(secret, scope, cursor)
+			So(err, ShouldEqual, nil)
+			So(token, ShouldNotContainSubstring, "/")
+			So(token, ShouldNotContainSubstring, "+")
+
+			decoded, err := DecodePageToken(secret, scope, token)
+			So(err, ShouldEqual, nil)
+			So(decoded.Field, ShouldEqual, "created_at")
+			So(decoded.Direction, ShouldEqual, -1)
+			So(decoded.Value.Time().Equal(time.Date(2019, 6, 23, 12, 0, 0, 0, time.UTC)), ShouldBeTrue)
+			So(decoded.ID.ObjectID(), ShouldEqual, id)
+			So(decoded.Before, ShouldBeFalse)
+		})
+
+		Convey("should reject forged and malformed tokens", func() {
+			token, _ := EncodePageToken(secret, scope, cursor)
+
+			_, err := DecodePageToken([]byte("other"), scope, token)
+			So(err, ShouldHaveSameTypeAs, &InvalidPageTokenError{})
+
+			_, err = DecodePageToken(secret, scope, "x"+token)
+			So(err, ShouldHaveSameTypeAs, &InvalidPageTokenError{})
+
+			_, err = DecodePageToken(secret, scope, "nope")
+			So(err, ShouldHaveSameTypeAs, &InvalidPageTokenError{})
+
+			_, err = EncodePageToken(nil, scope, cursor)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should not reveal the position", func() {
+			token, err := EncodePageToken(secret, scope, cursor)
+			So(err, ShouldEqual, nil)
+			data, err := base64.RawURLEncoding.DecodeString(token)
+			So(err, ShouldEqual, nil)
+			So(bytes.Contains(data, idValue), ShouldBeFalse)
+			So(bytes.Contains(data, []byte("created_at")), ShouldBeFalse)
+
+			again, _ := EncodePageToken(secret, scope, cursor)
+			So(again, ShouldNotEqual, token)
+		})
+
+		Convey("should only decode tokens in their scope", func() {
+			token, _ := EncodePageToken(secret, scope, cursor)
+			_, err := DecodePageToken(secret, []byte("bongotest.others"), token)
+			So(err, ShouldHaveSameTypeAs, &InvalidPageTokenError{})
+
+			tests := &Collection{Name: "tests", Database: "bongotest"}
+			a, err := pageTokenScope(tests, bson.M{"tenant": "acme", "status": "open"})
+			So(err, ShouldEqual, nil)
+			b, _ := pageTokenScope(tests, bson.D{{Key: "status", Value: "open"}, {Key: "tenant", Value: "acme"}})
+			So(a, ShouldResemble, b)
+
+			other, _ := pageTokenScope(tests, bson.M{"tenant": "globex", "status": "open"})
+			So(other, ShouldNotResemble, a)
+			other, _ = pageTokenScope(&Collection{Name: "others", Database: "bongotest"}, bson.M{"tenant": "acme", "status": "open"})
+			So(other, ShouldNotResemble, a)
+		})
+
+		Convey("should only support sorts on one field", func() {
+			field, direction, err := singleSortField(nil)
+			So(err, ShouldEqual, nil)
+			So(field, ShouldEqual, "_id")
+			So(direction, ShouldEqual, 1)
+
+			field, direction, err = singleSortField(bson.D{{Key: "name", Value: -1}})
+			So(err, ShouldEqual, nil)
+			So(field, ShouldEqual, "name")
+			So(direction, ShouldEqual, -1)
+
+			_, _, err = singleSortField(bson.D{{Key: "name", Value: 1}, {Key: "age", Value: 1}})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should only break ties by _id in the direction of the field", func() {
+			field, direction, err := singleSortField(bson.D{{Key: "created", Value: -1}, {Key: "_id", Value: -1}})
+			So(err, ShouldEqual, nil)
+			So(field, ShouldEqual, "created")
+			So(direction, ShouldEqual, -1)
+
+			_, _, err = singleSortField(bson.D{{Key: "created", Value: 1}, {Key: "_id", Value: -1}})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestPaginateWithToken(t *testing.T) {
+	conn := getConnection()
+	conn.Config.PageTokenSecret = []byte("secret")
+
+	Convey("Token pagination", t, func() {
+		col := conn.Collection("tests")
+		for i := 0; i < 7; i++ {
+			col.Save(&importDocument{Name: "foo", Count: i % 3})
+		}
+
+		page := func(token string) ([]int, *PaginationInfo) {
+			results, _ := col.Find(bson.M{"name": "foo"})
+			defer results.Free()
+			results.Query.SetSort(bson.D{{Key: "count", Value: -1}})
+
+			info, err := results.PaginateWithToken(token, 3)
+			So(err, ShouldEqual, nil)
+
+			counts := make([]int, 0)
+			doc := &importDocument{}
+			for results.Next(doc) {
+				counts = append(counts, doc.Count)
+			}
+			So(len(counts), ShouldEqual, info.RecordsOnPage)
+			return counts, info
+		}
+
+		Convey("should page forwards and backwards", func() {
+			first, info := page("")
+			So(first, ShouldResemble, []int{2, 2, 1})
+			So(info.HasNext, ShouldBeTrue)
+			So(info.HasPrev, ShouldBeFalse)
+
+			second, info := page(info.NextCursor)
+			So(second, ShouldResemble, []int{1, 0, 0})
+			So(info.HasPrev, ShouldBeTrue)
+
+			last, info := page(info.NextCursor)
+			So(last, ShouldResemble, []int{0})
+			So(info.HasNext, ShouldBeFalse)
+
+			back, info := page(info.PrevCursor)
+			So(back, ShouldResemble, []int{1, 0, 0})
+
+			back, info = page(info.PrevCursor)
+			So(back, ShouldResemble, []int{2, 2, 1})
+			So(info.HasPrev, ShouldBeFalse)
+		})
+
+		Convey("should page through duplicate sort keys with an _id tie break", func() {
+			seen := make(map[primitive.ObjectID]int)
+			token := ""
+			for {
+				results, _ := col.Find(bson.M{"name": "foo"})
+				results.Query.SetSort(bson.D{{Key: "count", Value: 1}, {Key: "_id", Value: 1}})
+				info, err := results.PaginateWithToken(token, 2)
+				So(err, ShouldEqual, nil)
+
+				doc := &importDocument{}
+				for results.Next(doc) {
+					seen[doc.ID]++
+				}
+				results.Free()
+				if !info.HasNext {
+					break
+				}
+				token = info.NextCursor
+			}
+
+			So(len(seen), ShouldEqual, 7)
+			for _, times := range seen {
+				So(times, ShouldEqual, 1)
+			}
+		})
+
+		Convey("should reject tokens of a differently sorted result set", func() {
+			_, info := page("")
+			results, _ := col.Find(bson.M{"name": "foo"})
+			_, err := results.PaginateWithToken(info.NextCursor, 3)
+			So(err, ShouldHaveSameTypeAs, &InvalidPageTokenError{})
+		})
+
+		Convey("should reject tokens of a result set with another filter", func() {
+			_, info := page("")
+			results, _ := col.Find(bson.M{"name": "bar"})
+			results.Query.SetSort(bson.D{{Key: "count", Value: -1}})
+			_, err := results.PaginateWithToken(info.NextCursor, 3)
+			So(err, ShouldHaveSameTypeAs, &InvalidPageTokenError{})
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
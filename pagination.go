@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// The pagination parameters of a list request, see PaginationFromRequest
+type PageRequest struct {
+	// 1-based page number, for offset pagination
+	Page    int
+	PerPage int
+
+	// The page token, for token pagination. Empty for the first page
+	Cursor string
+
+	// Whether the request asked for token pagination, by passing a cursor parameter (empty for the first page)
+	UseCursor bool
+}
+
+// Reads the page, per_page and cursor query parameters of a list request. Missing or invalid page sizes fall back
+// to defaultPerPage and are capped at maxPerPage (unless it is 0), pages below 1 are treated as the first page:
+//
+//	params := bongo.PaginationFromRequest(r, 20, 100)
+//	info, err := params.Paginate(results)
+//	bongo.WritePaginationHeaders(w, r, info)
+func PaginationFromRequest(r *http.Request, defaultPerPage int, maxPerPage int) *PageRequest {
+	query := r.URL.Query()
+	params := &PageRequest{Page: 1, PerPage: defaultPerPage}
+
+	if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 1 {
+		params.Page = page
+	}
+	if perPage, err := strconv.Atoi(query.Get("per_page")); err == nil && perPage > 0 {
+		params.PerPage = perPage
+	}
+	if maxPerPage > 0 && params.PerPage > maxPerPage {
+		params.PerPage = maxPerPage
+	}
+
+	_, params.UseCursor = query["cursor"]
+	params.Cursor = query.Get("cursor")
+	return params
+}
+
+// Paginates the result set by token (see ResultSet.PaginateWithToken) if the request passed a cursor, and by page
+// number otherwise
+func (p *PageRequest) Paginate(r *ResultSet) (*PaginationInfo, error) {
+	if p.UseCursor {
+		return r.PaginateWithToken(p.Cursor, p.PerPage)
+	}
+	return r.Paginate(p.PerPage, p.Page)
+}
+
+// Sets a Link header with the first, prev, next and last pages (as far as they are known) of a list request, and
+// X-Total-Count if the records were counted. The links keep the other query parameters of the request
+func WritePaginationHeaders(w http.ResponseWriter, r *http.Request, info *PaginationInfo) {
+	var links map[string]string
+	if len(info.NextCursor) > 0 || len(info.PrevCursor) > 0 || r.URL.Query().Has("cursor") {
+		links = cursorPageLinks(info, r.URL)
+	} else {
+		links = offsetPageLinks(info, r.URL, "page", "per_page")
+	}
+
+	values := make([]string, 0, 4)
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		if link, ok := links[rel]; ok {
+			values = append(values, "<"+link+`>; rel="`+rel+`"`)
+		}
+	}
+	if len(values) > 0 {
+		w.Header().Set("Link", strings.Join(values, ", "))
+	}
+	if info.TotalRecords >= 0 {
+		w.Header().Set("X-Total-Count", strconv.FormatInt(info.TotalRecords, 10))
+	}
+}
+
+// Links to the self, first, last, prev and next pages of offset pagination
+func offsetPageLinks(info *PaginationInfo, base *url.URL, pageParam string, sizeParam string) map[string]string {
+	link := func(page int) string {
+		u := *base
+		q := u.Query()
+		q.Set(pageParam, strconv.Itoa(page))
+		q.Set(sizeParam, strconv.Itoa(info.PerPage))
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	links := map[string]string{
+		"self":  link(info.Current),
+		"first": link(1),
+	}
+
+	// The last page is unknown if the records weren't counted
+	if info.TotalPages >= 0 {
+		last := info.TotalPages
+		if last < 1 {
+			last = 1
+		}
+		links["last"] = link(last)
+	}
+	if info.HasPrev {
+		links["prev"] = link(info.Current - 1)
+	}
+	if info.HasNext {
+		links["next"] = link(info.Current + 1)
+	}
+
+	return links
+}
+
+// Links to the first, prev and next pages of token pagination
+func cursorPageLinks(info *PaginationInfo, base *url.URL) map[string]string {
+	link := func(cursor string) string {
+		u := *base
+		q := u.Query()
+		q.Del("page")
+		q.Set("cursor", cursor)
+		q.Set("per_page", strconv.Itoa(info.PerPage))
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	links := map[string]string{"first": link("")}
+	if len(info.PrevCursor) > 0 {
+		links["prev"] = link(info.PrevCursor)
+	}
+	if len(info.NextCursor) > 0 {
+		links["next"] = link(info.NextCursor)
+	}
+	return links
+}
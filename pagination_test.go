@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPaginationFromRequest(t *testing.T) {
+	Convey("Pagination parameters", t, func() {
+		params := func(query string) *PageRequest {
+			return PaginationFromRequest(httptest.NewRequest("GET", "/users"+query, nil), 20, 100)
+		}
+
+		Convey("should read page and per_page", func() {
+			So(params("?page=3&per_page=50"), ShouldResemble, &PageRequest{Page: 3, PerPage: 50})
+		})
+
+		Convey("should clamp invalid values", func() {
+			So(params(""), ShouldResemble, &PageRequest{Page: 1, PerPage: 20})
+			So(params("?page=-1&per_page=0"), ShouldResemble, &PageRequest{Page: 1, PerPage: 20})
+			So(params("?page=abc&per_page=abc"), ShouldResemble, &PageRequest{Page: 1, PerPage: 20})
+			So(params("?per_page=1000"), ShouldResemble, &PageRequest{Page: 1, PerPage: 100})
+		})
+
+		Convey("should read cursors", func() {
+			So(params("?cursor=abc"), ShouldResemble, &PageRequest{Page: 1, PerPage: 20, Cursor: "abc", UseCursor: true})
+			So(params("?cursor="), ShouldResemble, &PageRequest{Page: 1, PerPage: 20, UseCursor: true})
+		})
+	})
+}
+
+func TestWritePaginationHeaders(t *testing.T) {
+	Convey("Pagination headers", t, func() {
+		Convey("should link the pages of offset pagination", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/users?page=2&per_page=10&status=active", nil)
+			WritePaginationHeaders(w, r, newPaginationInfo(35, 10, 2))
+
+			So(w.Header().Get("X-Total-Count"), ShouldEqual, "35")
+			So(w.Header().Get("Link"), ShouldEqual, `</users?page=1&per_page=10&status=active>; rel="first", `+
+				`</users?page=1&per_page=10&status=active>; rel="prev", `+
+				`</users?page=3&per_page=10&status=active>; rel="next", `+
+				`</users?page=4&per_page=10&status=active>; rel="last"`)
+		})
+
+		Convey("should link the pages of token pagination", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/users?cursor=abc&per_page=10", nil)
+			WritePaginationHeaders(w, r, &PaginationInfo{PerPage: 10, TotalRecords: -1, TotalPages: -1, NextCursor: "def"})
+
+			So(w.Header().Get("X-Total-Count"), ShouldEqual, "")
+			So(w.Header().Get("Link"), ShouldEqual, `</users?cursor=&per_page=10>; rel="first", </users?cursor=def&per_page=10>; rel="next"`)
+		})
+	})
+}
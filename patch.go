@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Returned by Patch for fields of a patch that aren't fields of the document, and for _id
+type PatchFieldError struct {
+	Collection string
+	Field      string
+}
+
+func (e *PatchFieldError) Error() string {
+	return "field " + e.Field + " can't be patched in " + e.Collection
+}
+
+// Finds the document with the id into doc, applies a patch struct of Optional and pointer fields to it (see
+// PatchFromStruct) and saves it, e.g. for a PATCH request:
+//
+//	patch := &PersonPatch{}
+//	err := json.NewDecoder(r.Body).Decode(patch)
+//	person := &Person{}
+//	err = people.Patch(ctx, id, patch, person)
+//
+// The fields of the patch must be fields of doc with the same bson paths, or the patch fails with a
+// *PatchFieldError before anything is found. Values are converted to the type of the document's field through
+// BSON, and null fields are set to their zero value. Validation, field access and the save hooks run as for Save.
+// The whole document is saved, so concurrent changes to other fields are overwritten; use WithLock if that matters
+func (c *Collection) Patch(ctx context.Context, id primitive.ObjectID, patch interface{}, doc Document, opts ...WriteOption) error {
+	update, err := PatchFromStruct(patch)
+	if err != nil {
+		return err
+	}
+	if err := c.checkPatch(update, doc); err != nil {
+		return err
+	}
+
+	if err := c.findByID(ctx, id, doc); err != nil {
+		return err
+	}
+	if err := applyPatch(update, doc); err != nil {
+		return err
+	}
+	return c.save(ctx, doc, newWriteOptions(opts))
+}
+
+var fieldGoPathsCache sync.Map
+
+// The Go paths of the fields of a type by bson path, cached per type
+func fieldGoPaths(t reflect.Type) map[string]string {
+	if paths, ok := fieldGoPathsCache.Load(t); ok {
+		return paths.(map[string]string)
+	}
+
+	paths := map[string]string{}
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		paths[path] = goPath
+		return true
+	})
+
+	fieldGoPathsCache.Store(t, paths)
+	return paths
+}
+
+// Checks that the paths of an update are fields of the document
+func (c *Collection) checkPatch(update bson.M, doc interface{}) error {
+	paths := fieldGoPaths(indirectType(reflect.TypeOf(doc)))
+	for _, operator := range []string{"$set", "$unset"} {
+		fields, _ := update[operator].(bson.M)
+		for path := range fields {
+			if _, ok := paths[path]; !ok || path == "_id" {
+				return &PatchFieldError{Collection: c.Name, Field: path}
+			}
+		}
+	}
+	return nil
+}
+
+// Sets the $set fields of an update on a document, converting their values through BSON, and zeroes its $unset
+// fields. Nil pointers on the way to a $set field are allocated
+func applyPatch(update bson.M, doc interface{}) error {
+	v := reflect.ValueOf(doc)
+	paths := fieldGoPaths(indirectType(v.Type()))
+
+	set, _ := update["$set"].(bson.M)
+	for path, value := range set {
+		field := allocFieldByGoPath(v, paths[path])
+		t, data, err := bson.MarshalValueWithRegistry(Registry, value)
+		if err != nil {
+			return err
+		}
+		converted := reflect.New(field.Type())
+		if err := (bson.RawValue{Type: t, Value: data}).UnmarshalWithRegistry(Registry, converted.Interface()); err != nil {
+			return err
+		}
+		field.Set(converted.Elem())
+	}
+
+	unset, _ := update["$unset"].(bson.M)
+	for path := range unset {
+		if field := fieldByGoPath(v, paths[path]); field.IsValid() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+	return nil
+}
+
+// Like fieldByGoPath, but allocates nil pointers on the way
+func allocFieldByGoPath(v reflect.Value, goPath string) reflect.Value {
+	field := v
+	for _, name := range strings.Split(goPath, ".") {
+		for field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		field = field.FieldByName(name)
+	}
+	return field
+}
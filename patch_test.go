@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type patchAddress struct {
+	City string `bson:"city"`
+}
+
+type patchDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string        `bson:"name"`
+	Age          int64         `bson:"age"`
+	Nickname     string        `bson:"nickname"`
+	Address      *patchAddress `bson:"address"`
+}
+
+type patchRequest struct {
+	Name     *string          `bson:"name"`
+	Age      Optional[int]    `bson:"age,omitempty"`
+	Nickname Optional[string] `bson:"nickname,omitempty"`
+	Address  struct {
+		City *string `bson:"city"`
+	} `bson:"address"`
+}
+
+func TestPatchUpdate(t *testing.T) {
+	Convey("Patches", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+		col := conn.Collection("patches")
+		name, city := "Bar", "Zurich"
+
+		Convey("should $set pointer fields and leave out nil ones", func() {
+			update, err := PatchFromStruct(&patchRequest{Name: &name})
+			So(err, ShouldBeNil)
+			So(update, ShouldResemble, bson.M{"$set": bson.M{"name": "Bar"}})
+		})
+
+		Convey("should apply values, nulls and nested fields to a document", func() {
+			patch := &patchRequest{Name: &name, Age: Some(42), Nickname: Null[string]()}
+			patch.Address.City = &city
+			update, err := PatchFromStruct(patch)
+			So(err, ShouldBeNil)
+			So(col.checkPatch(update, &patchDocument{}), ShouldBeNil)
+
+			doc := &patchDocument{Name: "Foo", Age: 3, Nickname: "foo"}
+			So(applyPatch(update, doc), ShouldBeNil)
+			So(doc.Name, ShouldEqual, "Bar")
+			So(doc.Age, ShouldEqual, 42)
+			So(doc.Nickname, ShouldEqual, "")
+			So(doc.Address.City, ShouldEqual, "Zurich")
+		})
+
+		Convey("should refuse fields the document doesn't have", func() {
+			err := col.checkPatch(bson.M{"$set": bson.M{"email": "a@example.com"}}, &patchDocument{})
+			So(err, ShouldHaveSameTypeAs, &PatchFieldError{})
+			So(err.(*PatchFieldError).Field, ShouldEqual, "email")
+
+			err = col.checkPatch(bson.M{"$unset": bson.M{"_id": ""}}, &patchDocument{})
+			So(err, ShouldHaveSameTypeAs, &PatchFieldError{})
+		})
+
+		Convey("should fail values that don't convert to the field's type", func() {
+			So(applyPatch(bson.M{"$set": bson.M{"age": "old"}}, &patchDocument{}), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestPatch(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Patch", t, func() {
+		col := conn.Collection("patches")
+		doc := &patchDocument{Name: "Foo", Age: 3, Nickname: "foo"}
+		So(col.Save(doc), ShouldBeNil)
+
+		Convey("should save the patched document", func() {
+			name := "Bar"
+			found := &patchDocument{}
+			So(col.Patch(context.Background(), doc.ID, &patchRequest{Name: &name, Nickname: Null[string]()}, found), ShouldBeNil)
+
+			reloaded := &patchDocument{}
+			So(col.FindByID(doc.ID, reloaded), ShouldBeNil)
+			So(reloaded.Name, ShouldEqual, "Bar")
+			So(reloaded.Age, ShouldEqual, 3)
+			So(reloaded.Nickname, ShouldEqual, "")
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
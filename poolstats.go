@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/event"
+	"sync/atomic"
+	"time"
+)
+
+// Counters of the driver's connection pool events, across the pools of all servers
+type poolCounters struct {
+	created         atomic.Int64
+	closed          atomic.Int64
+	checkOutStarted atomic.Int64
+	checkedOut      atomic.Int64
+	checkOutFailed  atomic.Int64
+	checkedIn       atomic.Int64
+	cleared         atomic.Int64
+	waitTime        atomic.Int64
+	maxWaitTime     atomic.Int64
+	maxPoolSize     atomic.Uint64
+}
+
+// Counts a pool event. Only takes atomic adds, since the driver calls it on every check out
+func (p *poolCounters) record(e *event.PoolEvent) {
+	switch e.Type {
+	case event.PoolCreated:
+		if e.PoolOptions != nil {
+			p.maxPoolSize.Store(e.PoolOptions.MaxPoolSize)
+		}
+	case event.ConnectionCreated:
+		p.created.Add(1)
+	case event.ConnectionClosed:
+		p.closed.Add(1)
+	case event.GetStarted:
+		p.checkOutStarted.Add(1)
+	case event.GetSucceeded:
+		p.checkedOut.Add(1)
+		p.wait(e.Duration)
+	case event.GetFailed:
+		p.checkOutFailed.Add(1)
+		p.wait(e.Duration)
+	case event.ConnectionReturned:
+		p.checkedIn.Add(1)
+	case event.PoolCleared:
+		p.cleared.Add(1)
+	}
+}
+
+func (p *poolCounters) wait(d time.Duration) {
+	p.waitTime.Add(int64(d))
+	for {
+		max := p.maxWaitTime.Load()
+		if int64(d) <= max || p.maxWaitTime.CompareAndSwap(max, int64(d)) {
+			return
+		}
+	}
+}
+
+// The state of the driver's connection pools since the connection was created, added up over all servers. Counters
+// only grow; Open, InUse and Waiting are gauges
+type PoolStats struct {
+	// The most connections a pool of one server may open, 0 if unlimited
+	MaxPoolSize uint64 `json:"max_pool_size"`
+
+	// Connections currently open, checked out by an operation, and operations waiting for a connection
+	Open    int64 `json:"open"`
+	InUse   int64 `json:"in_use"`
+	Waiting int64 `json:"waiting"`
+
+	Created        int64 `json:"created"`
+	Closed         int64 `json:"closed"`
+	CheckedOut     int64 `json:"checked_out"`
+	CheckedIn      int64 `json:"checked_in"`
+	CheckOutFailed int64 `json:"check_out_failed"`
+
+	// How often a pool was cleared, which closes all its connections, usually because the server went away
+	Cleared int64 `json:"cleared"`
+
+	// The time operations spent waiting for a connection, in total, on average and at most
+	WaitTime    time.Duration `json:"wait_time"`
+	MeanWait    time.Duration `json:"mean_wait"`
+	MaxWaitTime time.Duration `json:"max_wait_time"`
+}
+
+// Returns the connection pool statistics, e.g. to alert when InUse nears MaxPoolSize or operations start waiting
+func (m *Connection) Stats() *PoolStats {
+	p := &m.pool
+	stats := &PoolStats{
+		MaxPoolSize:    p.maxPoolSize.Load(),
+		Created:        p.created.Load(),
+		Closed:         p.closed.Load(),
+		CheckedOut:     p.checkedOut.Load(),
+		CheckedIn:      p.checkedIn.Load(),
+		CheckOutFailed: p.checkOutFailed.Load(),
+		Cleared:        p.cleared.Load(),
+		WaitTime:       time.Duration(p.waitTime.Load()),
+		MaxWaitTime:    time.Duration(p.maxWaitTime.Load()),
+	}
+	stats.Open = nonNegative(stats.Created - stats.Closed)
+	stats.InUse = nonNegative(stats.CheckedOut - stats.CheckedIn)
+	stats.Waiting = nonNegative(p.checkOutStarted.Load() - stats.CheckedOut - stats.CheckOutFailed)
+	if waits := stats.CheckedOut + stats.CheckOutFailed; waits > 0 {
+		stats.MeanWait = stats.WaitTime / time.Duration(waits)
+	}
+	return stats
+}
+
+// The counters are read one after the other, so a gauge can briefly come out negative
+func nonNegative(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
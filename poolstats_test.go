@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/event"
+	"testing"
+	"time"
+)
+
+func TestPoolStats(t *testing.T) {
+	Convey("Pool stats", t, func() {
+		conn := &Connection{Config: &Config{}}
+		monitor := conn.monitorOptions(0).PoolMonitor
+
+		Convey("should be empty before any pool event", func() {
+			So(conn.Stats(), ShouldResemble, &PoolStats{})
+		})
+
+		Convey("should count pool events", func() {
+			monitor.Event(&event.PoolEvent{Type: event.PoolCreated, PoolOptions: &event.MonitorPoolOptions{MaxPoolSize: 10}})
+			monitor.Event(&event.PoolEvent{Type: event.ConnectionCreated})
+			monitor.Event(&event.PoolEvent{Type: event.ConnectionCreated})
+			for i := 0; i < 4; i++ {
+				monitor.Event(&event.PoolEvent{Type: event.GetStarted})
+			}
+			monitor.Event(&event.PoolEvent{Type: event.GetSucceeded, Duration: time.Millisecond})
+			monitor.Event(&event.PoolEvent{Type: event.GetSucceeded, Duration: 3 * time.Millisecond})
+			monitor.Event(&event.PoolEvent{Type: event.GetFailed, Duration: 5 * time.Millisecond})
+			monitor.Event(&event.PoolEvent{Type: event.ConnectionReturned})
+			monitor.Event(&event.PoolEvent{Type: event.PoolCleared})
+			monitor.Event(&event.PoolEvent{Type: event.ConnectionClosed})
+
+			stats := conn.Stats()
+			So(stats.MaxPoolSize, ShouldEqual, 10)
+			So(stats.Open, ShouldEqual, 1)
+			So(stats.InUse, ShouldEqual, 1)
+			So(stats.Waiting, ShouldEqual, 1)
+			So(stats.Created, ShouldEqual, 2)
+			So(stats.Closed, ShouldEqual, 1)
+			So(stats.CheckedOut, ShouldEqual, 2)
+			So(stats.CheckedIn, ShouldEqual, 1)
+			So(stats.CheckOutFailed, ShouldEqual, 1)
+			So(stats.Cleared, ShouldEqual, 1)
+			So(stats.WaitTime, ShouldEqual, 9*time.Millisecond)
+			So(stats.MeanWait, ShouldEqual, 3*time.Millisecond)
+			So(stats.MaxWaitTime, ShouldEqual, 5*time.Millisecond)
+		})
+	})
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+	"time"
+)
+
+// Profiling levels
+const (
+	PROFILING_OFF  = 0
+	PROFILING_SLOW = 1
+	PROFILING_ALL  = 2
+)
+
+// An entry from the system.profile collection
+type ProfileEntry struct {
+	Op           string    `bson:"op" json:"op"`
+	Namespace    string    `bson:"ns" json:"ns"`
+	Command      bson.M    `bson:"command" json:"command"`
+	Millis       int64     `bson:"millis" json:"millis"`
+	Timestamp    time.Time `bson:"ts" json:"ts"`
+	KeysExamined int64     `bson:"keysExamined" json:"keysExamined"`
+	DocsExamined int64     `bson:"docsExamined" json:"docsExamined"`
+	NReturned    int64     `bson:"nreturned" json:"nreturned"`
+	PlanSummary  string    `bson:"planSummary" json:"planSummary"`
+	Client       string    `bson:"client" json:"client"`
+	AppName      string    `bson:"appName" json:"appName"`
+	User         string    `bson:"user" json:"user"`
+
+	// The bongo collection the operation ran against
+	Collection *Collection `bson:"-" json:"-"`
+}
+
+// Sets the profiling level (PROFILING_OFF, PROFILING_SLOW or PROFILING_ALL) and slow operation threshold on the
+// configured database
+func (m *Connection) EnableProfiling(level int, slowMS int) error {
+	cmd := bson.D{{Key: "profile", Value: level}, {Key: "slowms", Value: slowMS}}
+	ctx, cancel := m.operationContext()
+	defer cancel()
+	return m.Client().Database(m.Config.Database).RunCommand(ctx, cmd).Err()
+}
+
+// Reads the operations recorded by the profiler since the given time, oldest first
+func (m *Connection) SlowOps(since time.Time) ([]*ProfileEntry, error) {
+	profile := m.CollectionFromDatabase("system.profile", m.Config.Database)
+
+	opts := options.Find().SetSort(bson.D{{Key: "ts", Value: 1}})
+	var cursor *mongo.Cursor
+	err := profile.runOperation("slowOps", func(ctx context.Context) error {
+		var err error
+		cursor, err = profile.Collection().Find(ctx, bson.M{"ts": bson.M{"$gte": since}}, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer profile.closeCursor(cursor)
+
+	entries := make([]*ProfileEntry, 0)
+	for profile.nextDocument(cursor) {
+		entry := &ProfileEntry{}
+		if err := cursor.Decode(entry); err != nil {
+			return entries, err
+		}
+
+		split := strings.SplitN(entry.Namespace, ".", 2)
+		if len(split) == 2 {
+			entry.Collection = m.CollectionFromDatabase(split[1], split[0])
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, cursor.Err()
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+func TestProfiler(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Profiler", t, func() {
+		Convey("should record slow operations and attribute them to collections", func() {
+			since := time.Now().Add(-time.Second)
+			err := conn.EnableProfiling(PROFILING_ALL, 0)
+			So(err, ShouldEqual, nil)
+
+			doc := &noHookDocument{Name: "foo"}
+			So(conn.Collection("tests").Save(doc), ShouldEqual, nil)
+			So(conn.Collection("tests").FindOne(bson.M{"name": "foo"}, &noHookDocument{}), ShouldEqual, nil)
+
+			ops, err := conn.SlowOps(since)
+			So(err, ShouldEqual, nil)
+
+			found := false
+			for _, op := range ops {
+				if op.Op == "query" && op.Collection != nil && op.Collection.Name == "tests" {
+					found = true
+					So(op.Collection.Database, ShouldEqual, "bongotest")
+				}
+			}
+			So(found, ShouldBeTrue)
+		})
+
+		Reset(func() {
+			conn.EnableProfiling(PROFILING_OFF, 100)
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
+)
+
+// Rewrites the filter of every find, count, update and delete on a collection (including the _id filters of saves
+// and deletes of single documents), so constraints like tenancy or ownership are added in one place instead of at
+// every call site:
+//
+//	config.QueryGuards = map[string]bongo.QueryGuard{
+//		"orders": func(ctx context.Context, filter interface{}) (interface{}, error) {
+//			tenant, ok := TenantKey.From(ctx)
+//			if !ok {
+//				return nil, errors.New("no tenant")
+//			}
+//			return bongo.AndFilter(filter, bson.M{"tenant": tenant}), nil
+//		},
+//	}
+//
+// ctx is the context of the operation, which also holds the values of the collection's Context. An error fails the
+// operation
+type QueryGuard func(ctx context.Context, filter interface{}) (interface{}, error)
+
+// Combines a filter with a condition the documents must match as well
+func AndFilter(filter interface{}, condition interface{}) interface{} {
+	if isEmptyFilter(filter) {
+		return condition
+	}
+	return bson.M{"$and": bson.A{filter, condition}}
+}
+
+func isEmptyFilter(filter interface{}) bool {
+	if filter == nil {
+		return true
+	}
+	v := reflect.ValueOf(filter)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	case reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// Reads values from the operation's context first, then from the collection's Context
+type guardContext struct {
+	context.Context
+	values *Context
+}
+
+func (g *guardContext) Value(key interface{}) interface{} {
+	if value := g.Context.Value(key); value != nil {
+		return value
+	}
+	return g.values.Value(key)
+}
+
+func (c *Collection) queryGuard() QueryGuard {
+	if c.Connection == nil || c.Connection.Config == nil {
+		return nil
+	}
+	return c.Connection.Config.QueryGuards[c.Name]
+}
+
+// Runs the collection's query guard, if it has one, on the filter of an operation
+func (c *Collection) guardFilter(ctx context.Context, filter interface{}) (interface{}, error) {
+	guard := c.queryGuard()
+	if guard == nil {
+		return queryFilter(filter), nil
+	}
+	guarded, err := guard(&guardContext{Context: ctx, values: c.Context}, filter)
+	if err != nil {
+		return nil, err
+	}
+	return queryFilter(guarded), nil
+}
+
+// Copies the models of a bulk write with guarded filters. Inserts have no filter and are kept as they are, other
+// model types fail, since they could get past the guard
+func (c *Collection) guardModels(ctx context.Context, models []mongo.WriteModel) ([]mongo.WriteModel, error) {
+	if c.queryGuard() == nil {
+		return models, nil
+	}
+
+	guarded := make([]mongo.WriteModel, len(models))
+	for i, model := range models {
+		switch m := model.(type) {
+		case *mongo.ReplaceOneModel:
+			filter, err := c.guardFilter(ctx, m.Filter)
+			if err != nil {
+				return nil, err
+			}
+			replace := *m
+			replace.Filter = filter
+			guarded[i] = &replace
+		case *mongo.DeleteOneModel:
+			filter, err := c.guardFilter(ctx, m.Filter)
+			if err != nil {
+				return nil, err
+			}
+			del := *m
+			del.Filter = filter
+			guarded[i] = &del
+		case *mongo.DeleteManyModel:
+			filter, err := c.guardFilter(ctx, m.Filter)
+			if err != nil {
+				return nil, err
+			}
+			del := *m
+			del.Filter = filter
+			guarded[i] = &del
+		case *mongo.UpdateOneModel:
+			filter, err := c.guardFilter(ctx, m.Filter)
+			if err != nil {
+				return nil, err
+			}
+			update := *m
+			update.Filter = filter
+			guarded[i] = &update
+		case *mongo.UpdateManyModel:
+			filter, err := c.guardFilter(ctx, m.Filter)
+			if err != nil {
+				return nil, err
+			}
+			update := *m
+			update.Filter = filter
+			guarded[i] = &update
+		case *mongo.InsertOneModel:
+			guarded[i] = model
+		default:
+			return nil, fmt.Errorf("can't guard the filter of a %T on %s", model, c.Name)
+		}
+	}
+	return guarded, nil
+}
+
+// Appends a stage matching the collection's query guard to an aggregation pipeline, for stages that can't take the
+// guarded filter themselves (like $vectorSearch)
+func (c *Collection) guardPipeline(ctx context.Context, pipeline mongo.Pipeline) (mongo.Pipeline, error) {
+	if c.queryGuard() == nil {
+		return pipeline, nil
+	}
+	filter, err := c.guardFilter(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if isEmptyFilter(filter) {
+		return pipeline, nil
+	}
+	guarded := append(mongo.Pipeline{}, pipeline...)
+	return append(guarded, bson.D{{Key: "$match", Value: filter}}), nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testTenantKey = NewContextKey[string]("tenant")
+
+func TestQueryGuard(t *testing.T) {
+	Convey("Query guards", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest", QueryGuards: map[string]QueryGuard{
+			"orders": func(ctx context.Context, filter interface{}) (interface{}, error) {
+				tenant, ok := testTenantKey.From(ctx)
+				if !ok {
+					return nil, errors.New("no tenant")
+				}
+				return AndFilter(filter, bson.M{"tenant": tenant}), nil
+			},
+		}}, Context: &Context{}}
+		orders := conn.Collection("orders")
+
+		Convey("should combine filters", func() {
+			So(AndFilter(nil, bson.M{"a": 1}), ShouldResemble, bson.M{"a": 1})
+			So(AndFilter(bson.D{}, bson.M{"a": 1}), ShouldResemble, bson.M{"a": 1})
+			So(AndFilter(bson.M{"b": 2}, bson.M{"a": 1}), ShouldResemble, bson.M{"$and": bson.A{bson.M{"b": 2}, bson.M{"a": 1}}})
+		})
+
+		Convey("should rewrite the filters of guarded collections", func() {
+			ctx := testTenantKey.WithValue(context.Background(), "acme")
+			filter, err := orders.guardFilter(ctx, nil)
+			So(err, ShouldBeNil)
+			So(filter, ShouldResemble, bson.M{"tenant": "acme"})
+		})
+
+		Convey("should read the values of the collection's Context", func() {
+			filter, err := orders.WithContextValue(testTenantKey, "acme").guardFilter(context.Background(), bson.M{"paid": true})
+			So(err, ShouldBeNil)
+			So(filter, ShouldResemble, bson.M{"$and": bson.A{bson.M{"paid": true}, bson.M{"tenant": "acme"}}})
+		})
+
+		Convey("should fail operations the guard rejects", func() {
+			_, err := orders.guardFilter(context.Background(), nil)
+			So(err, ShouldNotBeNil)
+
+			_, err = orders.guardModels(context.Background(), []mongo.WriteModel{mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": 1})})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should guard the models of bulk writes without changing them", func() {
+			ctx := testTenantKey.WithValue(context.Background(), "acme")
+			model := mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": 1}).SetReplacement(bson.M{})
+			guarded, err := orders.guardModels(ctx, []mongo.WriteModel{model})
+			So(err, ShouldBeNil)
+			So(guarded[0].(*mongo.ReplaceOneModel).Filter, ShouldResemble, bson.M{"$and": bson.A{bson.M{"_id": 1}, bson.M{"tenant": "acme"}}})
+			So(model.Filter, ShouldResemble, bson.M{"_id": 1})
+		})
+
+		Convey("should guard every kind of write model", func() {
+			ctx := testTenantKey.WithValue(context.Background(), "acme")
+			guarded, err := orders.guardModels(ctx, []mongo.WriteModel{
+				mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": 1}).SetUpdate(bson.M{"$set": bson.M{"paid": true}}),
+				mongo.NewUpdateManyModel().SetFilter(bson.M{"paid": false}).SetUpdate(bson.M{"$set": bson.M{"paid": true}}),
+				mongo.NewDeleteManyModel().SetFilter(bson.M{"paid": false}),
+				mongo.NewInsertOneModel().SetDocument(bson.M{"tenant": "acme"}),
+			})
+			So(err, ShouldBeNil)
+			So(guarded[0].(*mongo.UpdateOneModel).Filter, ShouldResemble, bson.M{"$and": bson.A{bson.M{"_id": 1}, bson.M{"tenant": "acme"}}})
+			So(guarded[1].(*mongo.UpdateManyModel).Filter, ShouldResemble, bson.M{"$and": bson.A{bson.M{"paid": false}, bson.M{"tenant": "acme"}}})
+			So(guarded[2].(*mongo.DeleteManyModel).Filter, ShouldResemble, bson.M{"$and": bson.A{bson.M{"paid": false}, bson.M{"tenant": "acme"}}})
+			So(guarded[3].(*mongo.InsertOneModel).Document, ShouldResemble, bson.M{"tenant": "acme"})
+
+			_, err = orders.guardModels(ctx, []mongo.WriteModel{nil})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should append the guard to aggregation pipelines", func() {
+			ctx := testTenantKey.WithValue(context.Background(), "acme")
+			pipeline := vectorSearchPipeline("embeddings", "embedding", []float32{1, 0}, 5, nil)
+			guarded, err := orders.guardPipeline(ctx, pipeline)
+			So(err, ShouldBeNil)
+			So(guarded, ShouldHaveLength, len(pipeline)+1)
+			So(guarded[len(pipeline)], ShouldResemble, bson.D{{Key: "$match", Value: bson.M{"tenant": "acme"}}})
+
+			unguarded, err := conn.Collection("products").guardPipeline(ctx, pipeline)
+			So(err, ShouldBeNil)
+			So(unguarded, ShouldResemble, pipeline)
+		})
+
+		Convey("should guard imports, dumps, restores, vector searches and queued writes", func() {
+			model := &Model{Collection: "orders", Type: reflect.TypeOf(importDocument{})}
+			report, err := orders.Import(strings.NewReader(`{"name":"foo"}`+"\n"), EXPORT_NDJSON, &ImportOptions{Model: model})
+			So(err, ShouldBeNil)
+			So(report.Imported, ShouldEqual, 0)
+			So(report.Errors, ShouldHaveLength, 1)
+			So(report.Errors[0].Err.Error(), ShouldEqual, "no tenant")
+
+			_, err = orders.Dump(&bytes.Buffer{}, DUMP_EXTJSON, nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "no tenant")
+
+			restored, err := orders.Restore(strings.NewReader(`{"_id":{"$oid":"5d0f4f5fa6d8ba0001a1b2c3"}}`+"\n"), DUMP_EXTJSON)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "no tenant")
+			So(restored, ShouldEqual, 0)
+
+			_, err = orders.VectorSearch("embeddings", "embedding", []float32{1, 0}, 5, nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "no tenant")
+
+			queue := newWriteBehindQueue(orders)
+			queue.pending[primitive.NewObjectID()] = mongo.NewReplaceOneModel().SetFilter(bson.M{}).SetReplacement(bson.M{}).SetUpsert(true)
+			err = queue.flush()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "no tenant")
+			So(queue.pending, ShouldBeEmpty)
+		})
+
+		Convey("should leave other collections alone", func() {
+			filter, err := conn.Collection("products").guardFilter(context.Background(), nil)
+			So(err, ShouldBeNil)
+			So(filter, ShouldResemble, bson.M{})
+		})
+	})
+}
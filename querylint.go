@@ -0,0 +1,216 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Runs the explain command for a find with the given filter and sort (both may be nil), returning the raw
+// explain output
+func (c *Collection) Explain(query interface{}, sortSpec interface{}) (bson.M, error) {
+	return c.explain(query, sortSpec, "queryPlanner")
+}
+
+func (c *Collection) explain(query interface{}, sortSpec interface{}, verbosity string) (bson.M, error) {
+	if query == nil {
+		query = bson.M{}
+	}
+
+	find := bson.D{{Key: "find", Value: c.Name}, {Key: "filter", Value: query}}
+	if sortSpec != nil {
+		find = append(find, bson.E{Key: "sort", Value: sortSpec})
+	}
+
+	cmd := bson.D{{Key: "explain", Value: find}, {Key: "verbosity", Value: verbosity}}
+
+	ctx, cancel := c.operationContext()
+	defer cancel()
+
+	result := bson.M{}
+	err := c.Connection.Client().Database(c.Database).RunCommand(ctx, cmd).Decode(&result)
+	return result, err
+}
+
+// Explains a query and returns warnings for full collection scans, in-memory sorts and filters that can't use
+// an index
+func (c *Collection) LintQuery(query interface{}, sortSpec interface{}) ([]string, error) {
+	explain, err := c.Explain(query, sortSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	planner, _ := explain["queryPlanner"].(bson.M)
+	stages := planStages(planner["winningPlan"])
+
+	warnings := make([]string, 0)
+	shape := QueryShape(query)
+
+	if stringInSlice("COLLSCAN", stages) && shape != "{}" {
+		warnings = append(warnings, "full collection scan on "+c.Name+" for query "+shape)
+	}
+
+	if stringInSlice("SORT", stages) {
+		warnings = append(warnings, "in-memory sort on "+c.Name+" for query "+shape+" sorted by "+sortShape(sortSpec))
+	}
+
+	if shape != "{}" && !stringInSlice("IXSCAN", stages) && !stringInSlice("IDHACK", stages) &&
+		!stringInSlice("EXPRESS_IXSCAN", stages) && !stringInSlice("EXPRESS_CLUSTERED_IXSCAN", stages) {
+		warnings = append(warnings, "no index used on "+c.Name+" for query "+shape)
+	}
+
+	return warnings, nil
+}
+
+// Lints a query the first time its shape is seen on this collection, logging any warnings
+func (c *Collection) lintOnce(query interface{}, sortSpec interface{}) {
+	if c.Connection == nil || c.Connection.config() == nil || !c.Connection.config().QueryLinting {
+		return
+	}
+
+	key := c.Database + "." + c.Name + " " + QueryShape(query) + " " + sortShape(sortSpec)
+
+	m := c.Connection
+	m.lintMutex.Lock()
+	if m.lintedShapes == nil {
+		m.lintedShapes = make(map[string]bool)
+	}
+	seen := m.lintedShapes[key]
+	m.lintedShapes[key] = true
+	m.lintMutex.Unlock()
+
+	if seen {
+		return
+	}
+
+	warnings, err := c.LintQuery(query, sortSpec)
+	if err != nil {
+		m.logf(LOG_DEBUG, "could not explain query on %s: %s", c.Name, err.Error())
+		return
+	}
+
+	for _, w := range warnings {
+		m.logf(LOG_INFO, "query lint: %s", w)
+	}
+}
+
+// Collects the stage names of an explain plan, descending into inputStage(s)
+func planStages(plan interface{}) []string {
+	stages := make([]string, 0)
+	walkPlan(plan, func(stage bson.M) {
+		if name, ok := stage["stage"].(string); ok {
+			stages = append(stages, name)
+		}
+	})
+	return stages
+}
+
+// Calls fn with every stage of an explain plan, outermost first
+func walkPlan(plan interface{}, fn func(stage bson.M)) {
+	stage, ok := plan.(bson.M)
+	if !ok {
+		return
+	}
+
+	fn(stage)
+
+	if input, ok := stage["inputStage"]; ok {
+		walkPlan(input, fn)
+	}
+
+	if inputs, ok := stage["inputStages"].(bson.A); ok {
+		for _, input := range inputs {
+			walkPlan(input, fn)
+		}
+	}
+
+	// Plans that went through the slot based execution engine keep the classic plan in queryPlan
+	if query, ok := stage["queryPlan"]; ok {
+		walkPlan(query, fn)
+	}
+}
+
+// Describes the shape of a query: its (sorted) field names and operators, with all values replaced by "?"
+func QueryShape(query interface{}) string {
+	if query == nil {
+		return "{}"
+	}
+
+	raw, err := bson.Marshal(query)
+	if err != nil {
+		return "?"
+	}
+
+	return rawShape(bson.Raw(raw))
+}
+
+// Describes a sort: its fields and directions in order, since both decide which index can serve it
+func sortShape(sortSpec interface{}) string {
+	if sortSpec == nil {
+		return "{}"
+	}
+
+	raw, err := bson.Marshal(sortSpec)
+	if err != nil {
+		return "?"
+	}
+	elements, err := bson.Raw(raw).Elements()
+	if err != nil {
+		return "?"
+	}
+
+	parts := make([]string, len(elements))
+	for i, e := range elements {
+		if direction, ok := e.Value().AsInt64OK(); ok {
+			parts[i] = e.Key() + ":" + strconv.FormatInt(direction, 10)
+		} else {
+			parts[i] = e.Key() + ":" + valueShape(e.Value())
+		}
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func rawShape(doc bson.Raw) string {
+	elements, err := doc.Elements()
+	if err != nil {
+		return "?"
+	}
+
+	parts := make([]string, len(elements))
+	for i, e := range elements {
+		parts[i] = e.Key() + ":" + valueShape(e.Value())
+	}
+	// Maps don't have a stable key order
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func valueShape(value bson.RawValue) string {
+	switch value.Type {
+	case bsontype.EmbeddedDocument:
+		return rawShape(value.Document())
+	case bsontype.Array:
+		values, err := value.Array().Values()
+		if err != nil {
+			return "?"
+		}
+		parts := make([]string, 0)
+		for _, v := range values {
+			// Arrays of documents ($or, $and) are part of the shape, arrays of values ($in) are not
+			if v.Type == bsontype.EmbeddedDocument {
+				parts = append(parts, rawShape(v.Document()))
+			}
+		}
+		if len(parts) == 0 {
+			return "?"
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+	return "?"
+}
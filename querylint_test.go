@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestQueryShape(t *testing.T) {
+	Convey("QueryShape", t, func() {
+		So(QueryShape(nil), ShouldEqual, "{}")
+		So(QueryShape(bson.M{"name": "foo"}), ShouldEqual, "{name:?}")
+		So(QueryShape(bson.D{{Key: "age", Value: bson.M{"$gt": 3}}, {Key: "name", Value: "bar"}}), ShouldEqual, "{age:{$gt:?},name:?}")
+		So(QueryShape(bson.M{"name": bson.M{"$in": []string{"a", "b"}}}), ShouldEqual, "{name:{$in:?}}")
+		So(QueryShape(bson.M{"$or": []bson.M{{"a": 1}, {"b": 2}}}), ShouldEqual, "{$or:[{a:?},{b:?}]}")
+	})
+
+	Convey("sortShape", t, func() {
+		So(sortShape(nil), ShouldEqual, "{}")
+		So(sortShape(bson.D{{Key: "name", Value: 1}, {Key: "age", Value: -1}}), ShouldEqual, "{name:1,age:-1}")
+		So(sortShape(bson.D{{Key: "age", Value: -1}, {Key: "name", Value: 1}}), ShouldEqual, "{age:-1,name:1}")
+		So(sortShape(bson.M{"score": bson.M{"$meta": "textScore"}}), ShouldEqual, "{score:{$meta:?}}")
+	})
+}
+
+func TestQueryLinting(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Query linting", t, func() {
+		col := conn.Collection("tests")
+		So(col.Save(&noHookDocument{Name: "foo"}), ShouldEqual, nil)
+
+		Convey("should warn about collection scans and in-memory sorts", func() {
+			warnings, err := col.LintQuery(bson.M{"name": "foo"}, bson.M{"created_at": -1})
+			So(err, ShouldEqual, nil)
+			So(len(warnings), ShouldEqual, 3)
+		})
+
+		Convey("should not warn when an index is used", func() {
+			_, err := col.Collection().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+				Keys: bson.D{{Key: "name", Value: 1}},
+			})
+			So(err, ShouldEqual, nil)
+
+			warnings, err := col.LintQuery(bson.M{"name": "foo"}, nil)
+			So(err, ShouldEqual, nil)
+			So(len(warnings), ShouldEqual, 0)
+		})
+
+		Convey("should lint each query shape once when enabled", func() {
+			logger := &testLogger{}
+			conn.Config.QueryLinting = true
+			conn.Config.Logger = logger
+
+			col.FindOne(bson.M{"name": "foo"}, &noHookDocument{})
+			col.FindOne(bson.M{"name": "bar"}, &noHookDocument{})
+
+			So(len(logger.lines), ShouldEqual, 2)
+		})
+
+		Reset(func() {
+			conn.Config.QueryLinting = false
+			conn.Config.Logger = nil
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
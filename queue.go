@@ -0,0 +1,296 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type QueueOptions struct {
+	// How long a claimed job stays invisible to other workers. A job whose worker neither completes nor fails it in
+	// time is claimed again. Defaults to 5 minutes
+	VisibilityTimeout time.Duration
+
+	// Claims of a job, after which a failing job is moved to the dead letter collection. Defaults to 5
+	MaxAttempts int
+
+	// The delay before retrying a job that failed for the nth time. Defaults to 2^n seconds, up to an hour
+	Backoff func(attempt int) time.Duration
+
+	// Where jobs that failed too often are moved. Defaults to the queue's name plus "_dead"
+	DeadLetterCollection string
+
+	// How long Work waits before looking for jobs again when there are none. Defaults to a second
+	PollInterval time.Duration
+}
+
+// A queue of delayed jobs in a collection of its own. Workers claim jobs atomically, so every job runs on one
+// worker at a time:
+//
+//	emails := conn.Queue("email_jobs", nil)
+//	_, err := emails.Enqueue(ctx, &Email{To: "ann@example.com"}, time.Now().Add(time.Hour))
+//
+//	err = emails.Work(ctx, hostname, func(ctx context.Context, job *bongo.Job) error {
+//		email := &Email{}
+//		if err := job.Decode(email); err != nil {
+//			return err
+//		}
+//		return send(ctx, email)
+//	})
+type Queue struct {
+	Name        string
+	Collection  *Collection
+	DeadLetters *Collection
+
+	options QueueOptions
+}
+
+// A job of a queue
+type Job struct {
+	DocumentBase `bson:",inline"`
+	Payload      bson.Raw  `bson:"payload"`
+	RunAt        time.Time `bson:"run_at"`
+	Attempts     int       `bson:"attempts"`
+	ClaimedBy    string    `bson:"claimed_by,omitempty"`
+	LastError    string    `bson:"last_error,omitempty"`
+
+	// When the job was moved to the dead letter collection
+	FailedAt time.Time `bson:"failed_at,omitempty"`
+}
+
+// Returned by Complete and Fail when the job's claim timed out and another worker may have claimed it
+type JobClaimLostError struct {
+	Queue string
+	Job   *Job
+	// Why the copy a failed job left in the dead letter collection couldn't be removed, if it couldn't
+	Err error
+}
+
+func (e *JobClaimLostError) Error() string {
+	msg := "the claim on job " + e.Job.ID.Hex() + " of queue " + e.Queue + " was lost"
+	if e.Err != nil {
+		msg += ", and its dead letter wasn't removed: " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *JobClaimLostError) Unwrap() error {
+	return e.Err
+}
+
+// Creates a queue on the named collection
+func (m *Connection) Queue(name string, opts *QueueOptions) *Queue {
+	q := &Queue{Name: name, Collection: m.Collection(name)}
+	if opts != nil {
+		q.options = *opts
+	}
+	if q.options.VisibilityTimeout <= 0 {
+		q.options.VisibilityTimeout = 5 * time.Minute
+	}
+	if q.options.MaxAttempts <= 0 {
+		q.options.MaxAttempts = 5
+	}
+	if q.options.Backoff == nil {
+		q.options.Backoff = defaultQueueBackoff
+	}
+	if len(q.options.DeadLetterCollection) == 0 {
+		q.options.DeadLetterCollection = name + "_dead"
+	}
+	if q.options.PollInterval <= 0 {
+		q.options.PollInterval = time.Second
+	}
+	q.DeadLetters = m.Collection(q.options.DeadLetterCollection)
+	return q
+}
+
+func defaultQueueBackoff(attempt int) time.Duration {
+	if attempt > 12 {
+		return time.Hour
+	}
+	delay := time.Duration(1<<uint(attempt)) * time.Second
+	if delay > time.Hour {
+		return time.Hour
+	}
+	return delay
+}
+
+// Creates the index the workers claim jobs with
+func (q *Queue) EnsureIndexes(ctx context.Context) error {
+	return q.Collection.runOperationContext(ctx, "createIndex", func(ctx context.Context) error {
+		_, err := q.Collection.Collection().Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"run_at", 1}}})
+		return err
+	})
+}
+
+// Adds a job that runs once runAt has come. The payload is stored as a bson document
+func (q *Queue) Enqueue(ctx context.Context, payload interface{}, runAt time.Time) (*Job, error) {
+	data, err := bson.MarshalWithRegistry(Registry, payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{Payload: data, RunAt: runAt}
+	if err := q.Collection.save(ctx, job, newWriteOptions(nil)); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Claims the job that has been due the longest for the worker, or returns nil if no job is due. The job is
+// invisible to other workers until the visibility timeout passes
+func (q *Queue) Claim(ctx context.Context, worker string) (*Job, error) {
+	now := time.Now()
+	filter := bson.M{"run_at": bson.M{"$lte": now}}
+	update := bson.M{
+		"$set": bson.M{"run_at": now.Add(q.options.VisibilityTimeout), "claimed_by": worker},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{"run_at", 1}}).SetReturnDocument(options.After)
+
+	job := &Job{}
+	// Not retried, every retry would count as an attempt
+	err := q.Collection.runOperationOnce(ctx, "claim", func(ctx context.Context) error {
+		return q.Collection.Collection().FindOneAndUpdate(ctx, filter, update, opts).Decode(job)
+	})
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.SetIsNew(false)
+	return job, nil
+}
+
+// Removes a job its worker is done with
+func (q *Queue) Complete(ctx context.Context, job *Job) error {
+	var res *mongo.DeleteResult
+	// Not retried, a retry of a delete that was applied would report the claim as lost
+	err := q.Collection.runOperationOnce(ctx, "complete", func(ctx context.Context) error {
+		var err error
+		res, err = q.Collection.Collection().DeleteOne(ctx, claimFilter(job))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return &JobClaimLostError{Queue: q.Name, Job: job}
+	}
+	return nil
+}
+
+// Schedules a retry of a job that failed with jobErr after the backoff, or moves it to the dead letter collection
+// once it used up its attempts
+func (q *Queue) Fail(ctx context.Context, job *Job, jobErr error) error {
+	job.LastError = jobErr.Error()
+
+	if job.Attempts >= q.options.MaxAttempts {
+		// Copied before it is removed, so a failure in between leaves the job in the queue rather than losing it.
+		// Replacing by _id makes the copy idempotent when the job fails there again
+		job.FailedAt = time.Now().Truncate(time.Millisecond)
+		err := q.DeadLetters.runOperationContext(ctx, "deadLetter", func(ctx context.Context) error {
+			_, err := q.DeadLetters.Collection().ReplaceOne(ctx, bson.M{"_id": job.ID}, job, options.Replace().SetUpsert(true))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		var res *mongo.DeleteResult
+		err = q.Collection.runOperationOnce(ctx, "fail", func(ctx context.Context) error {
+			var err error
+			res, err = q.Collection.Collection().DeleteOne(ctx, claimFilter(job))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if res.DeletedCount == 0 {
+			// Another worker has the job now, so it isn't dead yet
+			err = q.DeadLetters.runOperationContext(ctx, "deadLetter", func(ctx context.Context) error {
+				_, err := q.DeadLetters.Collection().DeleteOne(ctx, bson.M{"_id": job.ID, "failed_at": job.FailedAt})
+				return err
+			})
+			return &JobClaimLostError{Queue: q.Name, Job: job, Err: err}
+		}
+		return nil
+	}
+
+	runAt := time.Now().Add(q.options.Backoff(job.Attempts))
+	update := bson.M{
+		"$set":   bson.M{"run_at": runAt, "last_error": job.LastError},
+		"$unset": bson.M{"claimed_by": ""},
+	}
+	var res *mongo.UpdateResult
+	err := q.Collection.runOperationOnce(ctx, "fail", func(ctx context.Context) error {
+		var err error
+		res, err = q.Collection.Collection().UpdateOne(ctx, claimFilter(job), update)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return &JobClaimLostError{Queue: q.Name, Job: job}
+	}
+	job.RunAt = runAt
+	job.ClaimedBy = ""
+	return nil
+}
+
+// Matches a job as long as the claim it was returned with is the latest
+func claimFilter(job *Job) bson.M {
+	return bson.M{"_id": job.ID, "claimed_by": job.ClaimedBy, "attempts": job.Attempts}
+}
+
+// Claims and runs jobs with fn until ctx is canceled, completing them or failing them with the error of fn. fn's
+// context times out with the visibility timeout. Errors completing or failing jobs are logged
+func (q *Queue) Work(ctx context.Context, worker string, fn func(ctx context.Context, job *Job) error) error {
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		job, err := q.Claim(ctx, worker)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(q.options.PollInterval):
+			}
+			continue
+		}
+
+		jobCtx, cancel := context.WithTimeout(ctx, q.options.VisibilityTimeout)
+		err = fn(jobCtx, job)
+		cancel()
+
+		// Finish the job even if ctx was canceled while it ran
+		finishCtx := context.WithoutCancel(ctx)
+		if err != nil {
+			err = q.Fail(finishCtx, job, err)
+		} else {
+			err = q.Complete(finishCtx, job)
+		}
+		if err != nil {
+			q.Collection.Connection.logf(LOG_ERROR, "queue %s: %v", q.Name, err)
+		}
+	}
+}
+
+// Decodes the payload of the job into v
+func (j *Job) Decode(v interface{}) error {
+	return bson.UnmarshalWithRegistry(Registry, j.Payload, v)
+}
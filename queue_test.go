@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+type queuedEmail struct {
+	To string `bson:"to"`
+}
+
+func TestQueueOptions(t *testing.T) {
+	Convey("Queue options", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}}
+
+		Convey("should apply the defaults", func() {
+			q := conn.Queue("email_jobs", nil)
+			So(q.Collection.Name, ShouldEqual, "email_jobs")
+			So(q.DeadLetters.Name, ShouldEqual, "email_jobs_dead")
+			So(q.options.VisibilityTimeout, ShouldEqual, 5*time.Minute)
+			So(q.options.MaxAttempts, ShouldEqual, 5)
+			So(q.options.PollInterval, ShouldEqual, time.Second)
+		})
+
+		Convey("should back off exponentially up to an hour", func() {
+			So(defaultQueueBackoff(1), ShouldEqual, 2*time.Second)
+			So(defaultQueueBackoff(3), ShouldEqual, 8*time.Second)
+			So(defaultQueueBackoff(12), ShouldEqual, time.Hour)
+			So(defaultQueueBackoff(100), ShouldEqual, time.Hour)
+		})
+
+		Convey("should decode payloads", func() {
+			data, err := bson.Marshal(&queuedEmail{To: "ann@example.com"})
+			So(err, ShouldBeNil)
+			email := &queuedEmail{}
+			So((&Job{Payload: data}).Decode(email), ShouldBeNil)
+			So(email.To, ShouldEqual, "ann@example.com")
+		})
+
+		Convey("should tell when a lost job's dead letter is left behind", func() {
+			job := &Job{}
+			lost := &JobClaimLostError{Queue: "email_jobs", Job: job}
+			So(lost.Error(), ShouldEqual, "the claim on job "+job.ID.Hex()+" of queue email_jobs was lost")
+
+			lost.Err = errors.New("not primary")
+			So(lost.Error(), ShouldEndWith, "was lost, and its dead letter wasn't removed: not primary")
+			So(errors.Unwrap(lost), ShouldEqual, lost.Err)
+		})
+	})
+}
+
+func TestQueue(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Queue", t, func() {
+		ctx := context.Background()
+		q := conn.Queue("email_jobs", &QueueOptions{MaxAttempts: 2, Backoff: func(int) time.Duration { return 0 }})
+
+		Convey("should only hand out due jobs, once", func() {
+			_, err := q.Enqueue(ctx, &queuedEmail{To: "later@example.com"}, time.Now().Add(time.Hour))
+			So(err, ShouldBeNil)
+			due, err := q.Enqueue(ctx, &queuedEmail{To: "now@example.com"}, time.Now())
+			So(err, ShouldBeNil)
+
+			job, err := q.Claim(ctx, "a")
+			So(err, ShouldBeNil)
+			So(job.ID, ShouldEqual, due.ID)
+			So(job.Attempts, ShouldEqual, 1)
+
+			none, err := q.Claim(ctx, "b")
+			So(err, ShouldBeNil)
+			So(none, ShouldBeNil)
+
+			So(q.Complete(ctx, job), ShouldBeNil)
+			So(q.Complete(ctx, job), ShouldHaveSameTypeAs, &JobClaimLostError{})
+		})
+
+		Convey("should retry failed jobs and move them to the dead letters", func() {
+			_, err := q.Enqueue(ctx, &queuedEmail{To: "bounce@example.com"}, time.Now())
+			So(err, ShouldBeNil)
+
+			job, _ := q.Claim(ctx, "a")
+			So(q.Fail(ctx, job, errors.New("bounced")), ShouldBeNil)
+
+			job, _ = q.Claim(ctx, "a")
+			So(job.Attempts, ShouldEqual, 2)
+			So(job.LastError, ShouldEqual, "bounced")
+			So(q.Fail(ctx, job, errors.New("bounced again")), ShouldBeNil)
+
+			job, _ = q.Claim(ctx, "a")
+			So(job, ShouldBeNil)
+
+			dead := &Job{}
+			So(q.DeadLetters.FindOne(nil, dead), ShouldBeNil)
+			So(dead.LastError, ShouldEqual, "bounced again")
+		})
+
+		Convey("should only move jobs to the dead letters while they are claimed", func() {
+			_, err := q.Enqueue(ctx, &queuedEmail{To: "bounce@example.com"}, time.Now())
+			So(err, ShouldBeNil)
+
+			job, _ := q.Claim(ctx, "a")
+			job.Attempts = 2
+			So(q.Fail(ctx, job, errors.New("bounced")), ShouldHaveSameTypeAs, &JobClaimLostError{})
+
+			So(q.DeadLetters.FindOne(nil, &Job{}), ShouldHaveSameTypeAs, &DocumentNotFoundError{})
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
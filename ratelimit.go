@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Configuration for client-side throttling of the operations on a collection
+type RateLimitConfig struct {
+	// Maximum sustained operations per second. Zero means unlimited
+	OpsPerSecond float64
+
+	// Number of operations that may run in a burst above OpsPerSecond. Defaults to 1
+	Burst int
+
+	// Maximum number of operations in flight at the same time. Zero means unlimited
+	MaxConcurrent int
+
+	// Return a *RateLimitError immediately instead of waiting for capacity
+	FailFast bool
+}
+
+// Returned by fail-fast limiters when there is no capacity left
+type RateLimitError struct {
+	Name string
+}
+
+func (e *RateLimitError) Error() string {
+	return "Rate limit exceeded for " + e.Name
+}
+
+type RateLimiter struct {
+	Name   string
+	config *RateLimitConfig
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+	slots  chan struct{}
+}
+
+func NewRateLimiter(name string, config *RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{
+		Name:   name,
+		config: config,
+		tokens: float64(burst(config)),
+		last:   time.Now(),
+	}
+
+	if config.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+
+	return l
+}
+
+func burst(config *RateLimitConfig) int {
+	if config.Burst < 1 {
+		return 1
+	}
+	return config.Burst
+}
+
+// Takes a token from the bucket, or returns how long to wait until one is available
+func (l *RateLimiter) reserve() time.Duration {
+	if l.config.OpsPerSecond <= 0 {
+		return 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.config.OpsPerSecond
+	if max := float64(burst(l.config)); l.tokens > max {
+		l.tokens = max
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.config.OpsPerSecond * float64(time.Second))
+}
+
+// Puts back the token of an operation that didn't run after all
+func (l *RateLimiter) refund() {
+	if l.config.OpsPerSecond <= 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.tokens++
+	if max := float64(burst(l.config)); l.tokens > max {
+		l.tokens = max
+	}
+}
+
+// Waits for capacity to run one operation. The returned func must be called once the operation is done
+func (l *RateLimiter) Acquire(ctx context.Context) (func(), error) {
+	for {
+		wait := l.reserve()
+		if wait == 0 {
+			break
+		}
+		if l.config.FailFast {
+			return nil, &RateLimitError{l.Name}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+
+	// Without a slot the operation doesn't run, so it doesn't use up its token
+	if l.config.FailFast {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			l.refund()
+			return nil, &RateLimitError{l.Name}
+		}
+	} else {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			l.refund()
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() { <-l.slots }, nil
+}
+
+// Get the rate limiter for a collection, or nil if it isn't rate limited
+func (m *Connection) RateLimiter(collection *Collection) *RateLimiter {
+	settings := m.config()
+	if settings == nil {
+		return nil
+	}
+
+	config := settings.RateLimit
+	if conf, ok := settings.CollectionRateLimits[collection.Name]; ok {
+		config = conf
+	}
+	if config == nil {
+		return nil
+	}
+
+	name := collection.Database + "." + collection.Name
+
+	m.limiterMutex.Lock()
+	defer m.limiterMutex.Unlock()
+
+	if m.limiters == nil {
+		m.limiters = make(map[string]*RateLimiter)
+	}
+
+	limiter, ok := m.limiters[name]
+	if !ok {
+		limiter = NewRateLimiter(name, config)
+		m.limiters[name] = limiter
+	}
+	return limiter
+}
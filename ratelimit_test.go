@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	Convey("RateLimiter", t, func() {
+		ctx := context.Background()
+
+		Convey("should fail fast when out of tokens", func() {
+			l := NewRateLimiter("test", &RateLimitConfig{OpsPerSecond: 1, Burst: 2, FailFast: true})
+			_, err := l.Acquire(ctx)
+			So(err, ShouldBeNil)
+			_, err = l.Acquire(ctx)
+			So(err, ShouldBeNil)
+			_, err = l.Acquire(ctx)
+			_, ok := err.(*RateLimitError)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("should block until a token is available", func() {
+			l := NewRateLimiter("test", &RateLimitConfig{OpsPerSecond: 50})
+			start := time.Now()
+			for i := 0; i < 3; i++ {
+				release, err := l.Acquire(ctx)
+				So(err, ShouldBeNil)
+				release()
+			}
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 30*time.Millisecond)
+		})
+
+		Convey("should limit concurrent operations", func() {
+			l := NewRateLimiter("test", &RateLimitConfig{MaxConcurrent: 1, FailFast: true})
+			release, err := l.Acquire(ctx)
+			So(err, ShouldBeNil)
+			_, err = l.Acquire(ctx)
+			So(err, ShouldNotBeNil)
+			release()
+			_, err = l.Acquire(ctx)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("should give up waiting when the context is done", func() {
+			l := NewRateLimiter("test", &RateLimitConfig{MaxConcurrent: 1})
+			l.Acquire(ctx)
+			timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			_, err := l.Acquire(timeout)
+			So(err, ShouldEqual, context.DeadlineExceeded)
+		})
+
+		Convey("should give back the token when no slot is free", func() {
+			l := NewRateLimiter("test", &RateLimitConfig{OpsPerSecond: 0.001, Burst: 2, MaxConcurrent: 1, FailFast: true})
+			release, err := l.Acquire(ctx)
+			So(err, ShouldBeNil)
+			_, err = l.Acquire(ctx)
+			So(err, ShouldHaveSameTypeAs, &RateLimitError{})
+
+			release()
+			_, err = l.Acquire(ctx)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("should give back the token when the context ends waiting for a slot", func() {
+			l := NewRateLimiter("test", &RateLimitConfig{OpsPerSecond: 0.001, Burst: 2, MaxConcurrent: 1})
+			release, err := l.Acquire(ctx)
+			So(err, ShouldBeNil)
+			timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			_, err = l.Acquire(timeout)
+			So(err, ShouldEqual, context.DeadlineExceeded)
+
+			release()
+			timeout, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			_, err = l.Acquire(timeout)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("should use per-collection overrides", func() {
+			conn := &Connection{Config: &Config{
+				CollectionRateLimits: map[string]*RateLimitConfig{
+					"limited": {MaxConcurrent: 1},
+				},
+			}}
+			So(conn.RateLimiter(conn.Collection("other")), ShouldBeNil)
+			So(conn.RateLimiter(conn.Collection("limited")), ShouldNotBeNil)
+			So(conn.RateLimiter(conn.Collection("limited")), ShouldEqual, conn.RateLimiter(conn.Collection("limited")))
+		})
+	})
+}
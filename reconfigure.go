@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"time"
+)
+
+// The settings Reconfigure can change while connected. Nil fields are left as they are. To turn a feature off, pass
+// its zero value, e.g. a zero OperationTimeout or &RateLimitConfig{} for no limits
+type RuntimeOptions struct {
+	Logger                 Logger
+	LogLevel               *LogLevel
+	QueryLinting           *bool
+	OperationTimeout       *time.Duration
+	SlowOperationThreshold *time.Duration
+	RetryPolicy            *RetryPolicy
+	RateLimit              *RateLimitConfig
+
+	// Replaces the circuit breakers, so their circuits start closed. &CircuitBreakerConfig{} turns them off
+	CircuitBreaker *CircuitBreakerConfig
+
+	// Replace the per-collection overrides of RateLimit
+	CollectionRateLimits map[string]*RateLimitConfig
+
+	// Replace the defaults of the operations on each collection
+	CollectionDefaults map[string]*CollectionDefaults
+	DocumentSize       *DocumentSizeConfig
+
+	// Resizes the shared memory cache, dropping the least recently used documents over the new size
+	CacheMaxEntries *int
+
+	// Replaces the degraded read config, dropping the least recently found copies over its MaxEntries. Degraded
+	// reads can't be turned off at runtime
+	DegradedReads *DegradedReadConfig
+
+	// Cascades waiting for a slot start as soon as the new limit allows
+	CascadeConcurrency *int
+}
+
+// The config the operations of the connection use: the one it was created with, or the latest from Reconfigure
+func (m *Connection) CurrentConfig() *Config {
+	return m.config()
+}
+
+func (m *Connection) config() *Config {
+	if config := m.settings.Load(); config != nil {
+		return config
+	}
+	return m.Config
+}
+
+// Changes settings that are safe to change at runtime, e.g. from a config file watcher or an admin endpoint,
+// without reconnecting:
+//
+//	timeout := 2 * time.Second
+//	connection.Reconfigure(&bongo.RuntimeOptions{OperationTimeout: &timeout})
+//
+// Operations that start afterwards use the new settings, operations in flight finish with the old ones. Changing
+// the rate limits replaces the rate limiters, so operations waiting for the old ones still count against them.
+// Shrinking the caches drops their least recently used entries right away.
+// Connection.Config keeps the settings the connection was created with; CurrentConfig returns the ones in use
+func (m *Connection) Reconfigure(opts *RuntimeOptions) {
+	if opts == nil {
+		return
+	}
+
+	m.settingsMutex.Lock()
+	defer m.settingsMutex.Unlock()
+
+	config := &Config{}
+	if current := m.config(); current != nil {
+		*config = *current
+	}
+
+	if opts.Logger != nil {
+		config.Logger = opts.Logger
+	}
+	if opts.LogLevel != nil {
+		config.LogLevel = *opts.LogLevel
+	}
+	if opts.QueryLinting != nil {
+		config.QueryLinting = *opts.QueryLinting
+	}
+	if opts.OperationTimeout != nil {
+		config.OperationTimeout = *opts.OperationTimeout
+	}
+	if opts.SlowOperationThreshold != nil {
+		config.SlowOperationThreshold = *opts.SlowOperationThreshold
+	}
+	if opts.RetryPolicy != nil {
+		config.RetryPolicy = opts.RetryPolicy
+	}
+	if opts.RateLimit != nil {
+		config.RateLimit = opts.RateLimit
+	}
+	if opts.CircuitBreaker != nil {
+		config.CircuitBreaker = opts.CircuitBreaker
+	}
+	if opts.CollectionRateLimits != nil {
+		config.CollectionRateLimits = opts.CollectionRateLimits
+	}
+	if opts.CollectionDefaults != nil {
+		config.CollectionDefaults = opts.CollectionDefaults
+	}
+	if opts.DocumentSize != nil {
+		config.DocumentSize = opts.DocumentSize
+	}
+	if opts.CacheMaxEntries != nil {
+		config.CacheMaxEntries = *opts.CacheMaxEntries
+	}
+	if opts.DegradedReads != nil {
+		config.DegradedReads = opts.DegradedReads
+	}
+	if opts.CascadeConcurrency != nil {
+		config.CascadeConcurrency = *opts.CascadeConcurrency
+	}
+
+	m.settings.Store(config)
+
+	if opts.RateLimit != nil || opts.CollectionRateLimits != nil {
+		m.limiterMutex.Lock()
+		m.limiters = nil
+		m.limiterMutex.Unlock()
+	}
+	if opts.CircuitBreaker != nil {
+		m.breakerMutex.Lock()
+		m.breakers = nil
+		m.breakerMutex.Unlock()
+	}
+	if opts.CacheMaxEntries != nil {
+		m.caches.resize(config.CacheMaxEntries)
+	}
+	if opts.DegradedReads != nil {
+		m.degraded.resize(degradedMaxEntries(config.DegradedReads))
+	}
+	if opts.CascadeConcurrency != nil {
+		m.cascades.wake()
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestReconfigure(t *testing.T) {
+	Convey("Reconfigure", t, func() {
+		logger := &testLogger{}
+		config := &Config{Database: "bongotest", OperationTimeout: time.Second, RateLimit: &RateLimitConfig{MaxConcurrent: 1}}
+		conn := &Connection{Config: config, Context: &Context{}}
+		col := conn.Collection("reconfigured")
+
+		Convey("should change the settings in use and keep the others", func() {
+			timeout, linting := 5*time.Second, true
+			conn.Reconfigure(&RuntimeOptions{OperationTimeout: &timeout, QueryLinting: &linting, Logger: logger})
+			So(col.timeout(), ShouldEqual, 5*time.Second)
+			So(conn.CurrentConfig().QueryLinting, ShouldBeTrue)
+			So(conn.CurrentConfig().Database, ShouldEqual, "bongotest")
+			So(conn.logger(), ShouldEqual, logger)
+			So(config.OperationTimeout, ShouldEqual, time.Second)
+
+			conn.Reconfigure(&RuntimeOptions{})
+			So(col.timeout(), ShouldEqual, 5*time.Second)
+		})
+
+		Convey("should replace the rate limiters", func() {
+			So(conn.RateLimiter(col).config.MaxConcurrent, ShouldEqual, 1)
+			conn.Reconfigure(&RuntimeOptions{RateLimit: &RateLimitConfig{MaxConcurrent: 10}})
+			So(conn.RateLimiter(col).config.MaxConcurrent, ShouldEqual, 10)
+		})
+
+		Convey("should replace the circuit breakers", func() {
+			So(conn.CircuitBreaker(col), ShouldBeNil)
+			conn.Reconfigure(&RuntimeOptions{CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 0.5, MinRequests: 10}})
+			So(conn.CircuitBreaker(col).config.MinRequests, ShouldEqual, 10)
+
+			conn.Reconfigure(&RuntimeOptions{CircuitBreaker: &CircuitBreakerConfig{}})
+			So(conn.CircuitBreaker(col), ShouldBeNil)
+		})
+
+		Convey("should only log lines at or above the log level", func() {
+			level := LOG_ERROR
+			conn.Reconfigure(&RuntimeOptions{LogLevel: &level, Logger: logger})
+			conn.logf(LOG_WARN, "slow")
+			conn.logf(LOG_ERROR, "failed")
+			So(logger.lines, ShouldResemble, []string{"failed"})
+		})
+
+		Convey("should resize the caches", func() {
+			conn.Config.Caches = map[string]*CacheConfig{"reconfigured": {}}
+			conn.Config.DegradedReads = &DegradedReadConfig{}
+			memory := col.cache(col.cacheConfig()).(*MemoryCache)
+			for i := 0; i < 3; i++ {
+				memory.Set(string(rune('a'+i)), []byte("1"), 0)
+				raw, _ := bson.Marshal(bson.M{"_id": primitive.NewObjectID()})
+				col.keepDegraded(bson.M{"i": i}, nil, raw)
+			}
+
+			size := 1
+			conn.Reconfigure(&RuntimeOptions{CacheMaxEntries: &size, DegradedReads: &DegradedReadConfig{MaxEntries: 2}})
+			So(memory.Len(), ShouldEqual, 1)
+			So(conn.degraded.order.Len(), ShouldEqual, 2)
+		})
+
+		Convey("should limit the cascades running at once", func() {
+			limit := 1
+			conn.Reconfigure(&RuntimeOptions{CascadeConcurrency: &limit})
+			release := make(chan bool)
+			started := make(chan int, 2)
+			for i := 0; i < 2; i++ {
+				i := i
+				conn.goCascade(func() {
+					started <- i
+					<-release
+				})
+			}
+			<-started
+			select {
+			case <-started:
+				So("second cascade started", ShouldBeEmpty)
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			limit = 2
+			conn.Reconfigure(&RuntimeOptions{CascadeConcurrency: &limit})
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				So("second cascade didn't start", ShouldBeEmpty)
+			}
+			close(release)
+		})
+
+		Convey("should log slow operations", func() {
+			threshold := 10 * time.Millisecond
+			conn.Reconfigure(&RuntimeOptions{SlowOperationThreshold: &threshold, Logger: logger})
+			So(col.runOperation("find", func(ctx context.Context) error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			}), ShouldBeNil)
+			So(col.runOperation("find", func(ctx context.Context) error { return nil }), ShouldBeNil)
+			So(len(logger.lines), ShouldEqual, 1)
+			So(logger.lines[0], ShouldStartWith, "slow find on bongotest.reconfigured took")
+		})
+	})
+}
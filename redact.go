@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Values of the redact tag. redact:"true" is the same as redact:"omit"
+const (
+	// Removes the field
+	REDACT_OMIT = "omit"
+
+	// Replaces the value with RedactedValue
+	REDACT_MASK = "mask"
+)
+
+// What masked fields are replaced with
+var RedactedValue = "[REDACTED]"
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Marshals a value to JSON like json.Marshal, but leaves out or masks the struct fields tagged for redaction,
+// e.g. tokens or SSNs that must never leave the persistence layer:
+//
+//	Token string `json:"token" redact:"true"`
+//	SSN   string `json:"ssn" redact:"mask"`
+//
+// Fields of nested structs, and of structs in slices, maps and interfaces, are redacted too. Unknown values of the
+// redact tag are an error
+func MarshalRedactedJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || !hasRedactions(reflect.TypeOf(v), map[reflect.Type]bool{}) {
+		return data, err
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	redacted, err := redactJSON(reflect.ValueOf(v), decoded)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(redacted)
+}
+
+// Whether a type (statically) has redacted fields, or interfaces that might hold some
+func hasRedactions(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if t == nil || visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return hasRedactions(t.Elem(), visiting)
+	case reflect.Struct:
+		if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+			return false
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if _, ok := field.Tag.Lookup("redact"); ok || hasRedactions(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Walks a value together with its decoded JSON, removing or masking the redacted fields
+func redactJSON(v reflect.Value, data interface{}) (interface{}, error) {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return data, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return data, nil
+	}
+
+	// Types that marshal themselves are left alone
+	if v.Type().Implements(jsonMarshalerType) || (v.CanAddr() && v.Addr().Type().Implements(jsonMarshalerType)) {
+		return data, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if object, ok := data.(map[string]interface{}); ok {
+			if err := redactStruct(v, object); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if items, ok := data.([]interface{}); ok {
+			for i := 0; i < v.Len() && i < len(items); i++ {
+				item, err := redactJSON(v.Index(i), items[i])
+				if err != nil {
+					return nil, err
+				}
+				items[i] = item
+			}
+		}
+	case reflect.Map:
+		object, ok := data.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String || v.Type().Key().Implements(textMarshalerType) {
+			return data, nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			if value, ok := object[key]; ok {
+				redacted, err := redactJSON(iter.Value(), value)
+				if err != nil {
+					return nil, err
+				}
+				object[key] = redacted
+			}
+		}
+	}
+	return data, nil
+}
+
+func redactStruct(v reflect.Value, object map[string]interface{}) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name := field.Name
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" && len(tag) == 1 {
+			continue
+		}
+		if len(tag[0]) > 0 {
+			name = tag[0]
+		}
+
+		// Untagged embedded structs are promoted into the same object
+		if field.Anonymous && len(tag[0]) == 0 {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if _, err := redactJSON(v.Field(i), object); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		value, ok := object[name]
+		if !ok {
+			continue
+		}
+
+		strategy, _, err := redactStrategy(field)
+		if err != nil {
+			return err
+		}
+		switch strategy {
+		case ANONYMIZE_DROP:
+			delete(object, name)
+		case ANONYMIZE_MASK:
+			if value != nil {
+				object[name] = RedactedValue
+			}
+		default:
+			if object[name], err = redactJSON(v.Field(i), value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type redactedAccount struct {
+	Number string `json:"number" bson:"number" redact:"mask"`
+	Bank   string `json:"bank" bson:"bank"`
+}
+
+type redactedDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string                      `json:"name" bson:"name"`
+	Token        string                      `json:"token" bson:"token" redact:"true"`
+	SSN          string                      `json:"ssn,omitempty" bson:"ssn" redact:"mask"`
+	Account      *redactedAccount            `json:"account" bson:"account"`
+	Accounts     []redactedAccount           `json:"accounts" bson:"accounts"`
+	ByName       map[string]*redactedAccount `json:"by_name" bson:"-"`
+	Extra        interface{}                 `json:"extra" bson:"-"`
+}
+
+func TestMarshalRedactedJSON(t *testing.T) {
+	Convey("MarshalRedactedJSON", t, func() {
+		doc := &redactedDocument{
+			Name:     "foo",
+			Token:    "t0k3n",
+			SSN:      "123-45-6789",
+			Account:  &redactedAccount{"DE001", "Bank"},
+			Accounts: []redactedAccount{{"DE002", "Bank"}},
+			ByName:   map[string]*redactedAccount{"main": {"DE003", "Bank"}},
+			Extra:    &redactedAccount{"DE004", "Bank"},
+		}
+
+		Convey("should omit and mask redacted fields", func() {
+			data, err := MarshalRedactedJSON(doc)
+			So(err, ShouldEqual, nil)
+
+			json := string(data)
+			So(json, ShouldContainSubstring, `"name":"foo"`)
+			So(json, ShouldNotContainSubstring, "token")
+			So(json, ShouldContainSubstring, `"ssn":"[REDACTED]"`)
+			So(json, ShouldContainSubstring, `"bank":"Bank"`)
+			So(json, ShouldNotContainSubstring, "DE00")
+			So(json, ShouldContainSubstring, `"id":`)
+		})
+
+		Convey("should handle lists and empty values", func() {
+			doc.SSN = ""
+			doc.Account = nil
+			data, err := MarshalRedactedJSON([]*redactedDocument{doc})
+			So(err, ShouldEqual, nil)
+			So(string(data), ShouldStartWith, "[{")
+			So(string(data), ShouldNotContainSubstring, "ssn")
+			So(string(data), ShouldContainSubstring, `"account":null`)
+		})
+
+		Convey("should marshal types without redactions as is", func() {
+			data, err := MarshalRedactedJSON(&noHookDocument{Name: "foo"})
+			So(err, ShouldEqual, nil)
+			So(string(data), ShouldContainSubstring, `"Name":"foo"`)
+		})
+
+		Convey("should redact exports", func() {
+			redactor, err := NewRedactor(&redactedDocument{})
+			So(err, ShouldEqual, nil)
+
+			raw, _ := bson.Marshal(doc)
+			out := &bytes.Buffer{}
+			_, err = resultSetFromDocuments(raw).Export(out, EXPORT_NDJSON, &ExportOptions{Anonymizer: redactor})
+			So(err, ShouldEqual, nil)
+			So(out.String(), ShouldNotContainSubstring, "t0k3n")
+			So(out.String(), ShouldNotContainSubstring, "DE00")
+			So(out.String(), ShouldContainSubstring, `"name":"foo"`)
+		})
+
+		Convey("should redact exports of registered models by default", func() {
+			RegisterModel("redacted", &redactedDocument{})
+			raw, _ := bson.Marshal(doc)
+			conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+
+			out := &bytes.Buffer{}
+			results := resultSetFromDocuments(raw)
+			results.Collection = conn.Collection("redacted")
+			_, err := results.Export(out, EXPORT_NDJSON, nil)
+			So(err, ShouldEqual, nil)
+			So(out.String(), ShouldNotContainSubstring, "t0k3n")
+			So(out.String(), ShouldContainSubstring, `"name":"foo"`)
+
+			out.Reset()
+			results = resultSetFromDocuments(raw)
+			results.Collection = conn.Collection("redacted")
+			_, err = results.Export(out, EXPORT_NDJSON, &ExportOptions{Unredacted: true})
+			So(err, ShouldEqual, nil)
+			So(out.String(), ShouldContainSubstring, "t0k3n")
+		})
+
+		Convey("should reject unknown redact values", func() {
+			type typoDocument struct {
+				DocumentBase `bson:",inline"`
+				Token        string `json:"token" bson:"token" redact:"yes"`
+			}
+			_, err := MarshalRedactedJSON(&typoDocument{Token: "t0k3n"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unknown redact value yes on field Token")
+
+			_, err = NewRedactor(&typoDocument{})
+			So(err, ShouldNotBeNil)
+			_, err = NewAnonymizer(&typoDocument{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Checks whether a document with the id exists in the collection
+func ValidateRefExists(ctx context.Context, id interface{}, collection *Collection) (bool, error) {
+	err := collection.runOperationContext(ctx, "validateRef", func(ctx context.Context) error {
+		filter, err := collection.guardFilter(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+		commentOn(ctx, collection, opts.SetComment)
+		return collection.Collection().FindOne(ctx, filter, opts).Err()
+	})
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// A field declared as a reference to the documents of another collection, with a `ref:"collection"` tag or a
+// BelongsTo relation
+type refField struct {
+	goPath     string
+	path       string
+	collection string
+	foreignKey string
+}
+
+var refFieldsCache sync.Map
+
+// The reference fields of a type, cached per type
+func refFields(t reflect.Type) []*refField {
+	if fields, ok := refFieldsCache.Load(t); ok {
+		return fields.([]*refField)
+	}
+
+	fields := make([]*refField, 0)
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		if collection, ok := field.Tag.Lookup("ref"); ok && len(collection) > 0 {
+			fields = append(fields, &refField{goPath: goPath, path: path, collection: collection, foreignKey: "_id"})
+			return false
+		}
+		return true
+	})
+
+	refFieldsCache.Store(t, fields)
+	return fields
+}
+
+// The reference fields of the documents of a collection: the ref tags of the type and the local keys of the
+// BelongsTo relations of the model registered for the collection
+func references(collection string, t reflect.Type) []*refField {
+	fields := refFields(t)
+	model := GetModel(collection)
+	if model == nil {
+		return fields
+	}
+
+	fields = append([]*refField{}, fields...)
+	for _, relation := range model.relations {
+		if relation.Kind != BELONGS_TO {
+			continue
+		}
+		if goPath := goPathOf(t, relation.LocalKey); len(goPath) > 0 {
+			fields = append(fields, &refField{goPath: goPath, path: relation.LocalKey, collection: relation.Collection, foreignKey: relation.ForeignKey})
+		}
+	}
+	return fields
+}
+
+// Adds a check for every reference of a document of the collection, i.e. every value of a field tagged with
+// `ref:"collection"` or holding the key of a BelongsTo relation. The values of slice fields are checked one by one
+func addRefChecks(doc interface{}, collection string, checks *DBChecks) {
+	v := reflect.ValueOf(doc)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, ref := range references(collection, v.Type()) {
+		field := fieldByGoPath(v, ref.goPath)
+		if field.IsValid() && field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		if !field.IsValid() {
+			continue
+		}
+
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < field.Len(); i++ {
+				checks.Exists(ref.path+"."+strconv.Itoa(i), ref.collection, ref.foreignKey, field.Index(i).Interface())
+			}
+			continue
+		}
+		checks.Exists(ref.path, ref.collection, ref.foreignKey, field.Interface())
+	}
+}
+
+// How a collection treats the references declared with `ref` tags
+type ReferenceConfig struct {
+	// Fail saves of documents whose references point to missing documents with a *ReferenceError
+	Enforce bool
+
+	// Refuse to delete documents (with DeleteDocument) that are still referenced by documents of registered
+	// models, with a *RestrictedDeleteError
+	RestrictDelete bool
+}
+
+// A reference to a document that doesn't exist
+type MissingReference struct {
+	// The path of the reference in the saved document, e.g. memberIds.2
+	Field      string
+	Collection string
+	ID         interface{}
+}
+
+// Returned by saves of documents with missing references, in collections that enforce references
+type ReferenceError struct {
+	Missing []*MissingReference
+}
+
+func (e *ReferenceError) Error() string {
+	refs := make([]string, len(e.Missing))
+	for i, ref := range e.Missing {
+		refs[i] = fmt.Sprintf("%s (%v in %s)", ref.Field, ref.ID, ref.Collection)
+	}
+	return "missing references: " + strings.Join(refs, ", ")
+}
+
+// Returned when deleting a document that is still referenced, in collections that restrict deletes
+type RestrictedDeleteError struct {
+	// The first collection and field found to reference the document
+	Collection string
+	Field      string
+}
+
+func (e *RestrictedDeleteError) Error() string {
+	return "document is still referenced by " + e.Collection + "." + e.Field
+}
+
+func (c *Collection) referenceConfig() *ReferenceConfig {
+	if c.Connection == nil || c.Connection.Config == nil || c.Connection.Config.References[c.Name] == nil {
+		return &ReferenceConfig{}
+	}
+	return c.Connection.Config.References[c.Name]
+}
+
+// Checks the references of a document if the collection enforces them
+func (c *Collection) enforceReferences(doc Document) error {
+	if !c.referenceConfig().Enforce {
+		return nil
+	}
+
+	checks := newDBChecks(doc.GetID())
+	addRefChecks(doc, c.Name, checks)
+	errs, err := checks.Run(c)
+	if err != nil || len(errs) == 0 {
+		return err
+	}
+
+	refErr := &ReferenceError{Missing: make([]*MissingReference, len(errs))}
+	for i, err := range errs {
+		fieldErr := err.(*FieldError)
+		refErr.Missing[i] = &MissingReference{
+			Field:      fieldErr.Field,
+			Collection: fieldErr.Params["collection"].(string),
+			ID:         fieldErr.Params["value"],
+		}
+	}
+	return refErr
+}
+
+// Fails if the collection restricts deletes and a document of a registered model references the id
+func (c *Collection) restrictDelete(id primitive.ObjectID) error {
+	if !c.referenceConfig().RestrictDelete {
+		return nil
+	}
+
+	for _, model := range Models() {
+		for _, ref := range references(model.Collection, model.Type) {
+			if ref.collection != c.Name || ref.foreignKey != "_id" {
+				continue
+			}
+
+			// Not guarded: references the guard hides from the caller still keep the document
+			referencing := c.Connection.CollectionFromDatabase(model.Collection, c.Database)
+			var count int64
+			err := referencing.runOperation("restrictDelete", func(ctx context.Context) error {
+				var err error
+				opts := options.Count().SetLimit(1)
+				commentOn(ctx, referencing, opts.SetComment)
+				count, err = referencing.Collection().CountDocuments(ctx, bson.M{ref.path: id}, opts)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return &RestrictedDeleteError{Collection: model.Collection, Field: ref.path}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+)
+
+type refAddress struct {
+	CountryID primitive.ObjectID `bson:"countryId" ref:"countries"`
+}
+
+type refDocument struct {
+	DocumentBase `bson:",inline"`
+	OwnerID      primitive.ObjectID   `bson:"ownerId" ref:"users"`
+	ReviewerID   *primitive.ObjectID  `bson:"reviewerId,omitempty" ref:"users"`
+	MemberIDs    []primitive.ObjectID `bson:"memberIds" ref:"users"`
+	Address      *refAddress          `bson:"address"`
+}
+
+func TestRefFields(t *testing.T) {
+	Convey("Reference fields", t, func() {
+		Convey("should find the ref tags", func() {
+			fields := refFields(reflect.TypeOf(refDocument{}))
+			So(len(fields), ShouldEqual, 4)
+			So(*fields[0], ShouldResemble, refField{goPath: "OwnerID", path: "ownerId", collection: "users", foreignKey: "_id"})
+			So(*fields[3], ShouldResemble, refField{goPath: "Address.CountryID", path: "address.countryId", collection: "countries", foreignKey: "_id"})
+		})
+
+		Convey("should check every set reference", func() {
+			owner, member1, member2, country := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+			doc := &refDocument{OwnerID: owner, MemberIDs: []primitive.ObjectID{member1, member2}, Address: &refAddress{CountryID: country}}
+
+			checks := newDBChecks(primitive.NilObjectID)
+			addRefChecks(doc, "refs", checks)
+			So(len(checks.checks), ShouldEqual, 4)
+			So(*checks.checks[0], ShouldResemble, dbCheck{kind: dbCheckExists, collection: "users", field: "_id", name: "ownerId", value: owner})
+			So(checks.checks[2].name, ShouldEqual, "memberIds.1")
+			So(checks.checks[3].name, ShouldEqual, "address.countryId")
+			So(checks.checks[3].collection, ShouldEqual, "countries")
+
+			checks = newDBChecks(primitive.NilObjectID)
+			addRefChecks(&refDocument{}, "refs", checks)
+			So(len(checks.checks), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestReferenceConfig(t *testing.T) {
+	Convey("Reference integrity", t, func() {
+		Convey("should be off by default", func() {
+			col := &Collection{Name: "refs"}
+			So(col.enforceReferences(&refDocument{OwnerID: primitive.NewObjectID()}), ShouldEqual, nil)
+			So(col.restrictDelete(primitive.NewObjectID()), ShouldEqual, nil)
+		})
+
+		Convey("should name the missing references", func() {
+			id := primitive.NewObjectID()
+			err := &ReferenceError{Missing: []*MissingReference{{Field: "ownerId", Collection: "users", ID: id}}}
+			So(err.Error(), ShouldEqual, "missing references: ownerId ("+id.String()+" in users)")
+		})
+	})
+}
+
+func TestRefValidation(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Reference validation", t, func() {
+		user := &noHookDocument{Name: "user"}
+		So(conn.Collection("users").Save(user), ShouldEqual, nil)
+
+		Convey("ValidateRefExists should tell whether the document exists", func() {
+			exists, err := ValidateRefExists(context.Background(), user.ID, conn.Collection("users"))
+			So(err, ShouldEqual, nil)
+			So(exists, ShouldBeTrue)
+
+			exists, err = ValidateRefExists(context.Background(), primitive.NewObjectID(), conn.Collection("users"))
+			So(err, ShouldEqual, nil)
+			So(exists, ShouldBeFalse)
+		})
+
+		Convey("should only check references when they are enforced", func() {
+			missing := primitive.NewObjectID()
+			So(conn.Collection("refs").Save(&refDocument{OwnerID: missing}), ShouldEqual, nil)
+
+			conn.Config.References = map[string]*ReferenceConfig{"refs": {Enforce: true}}
+			err := conn.Collection("refs").Save(&refDocument{OwnerID: user.ID, MemberIDs: []primitive.ObjectID{user.ID, missing}})
+			So(err, ShouldHaveSameTypeAs, &ReferenceError{})
+			So(err.(*ReferenceError).Missing, ShouldResemble, []*MissingReference{{Field: "memberIds.1", Collection: "users", ID: missing}})
+
+			So(conn.Collection("refs").Save(&refDocument{OwnerID: user.ID}), ShouldEqual, nil)
+		})
+
+		Convey("should restrict deleting referenced documents", func() {
+			RegisterModel("refs", &refDocument{})
+			conn.Config.References = map[string]*ReferenceConfig{"users": {RestrictDelete: true}}
+
+			project := &refDocument{MemberIDs: []primitive.ObjectID{user.ID}}
+			So(conn.Collection("refs").Save(project), ShouldEqual, nil)
+
+			_, err := conn.Collection("users").DeleteDocument(user)
+			So(err, ShouldResemble, &RestrictedDeleteError{Collection: "refs", Field: "memberIds"})
+
+			conn.Collection("refs").DeleteDocument(project)
+			_, err = conn.Collection("users").DeleteDocument(user)
+			So(err, ShouldEqual, nil)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"regexp"
+)
+
+// Helpers to match user input with regular expressions. The input is always escaped, so it is matched literally
+// and can't inject patterns (like catastrophic backtracking) into the query
+
+// Matches documents whose field contains the input, ignoring case
+func RegexContains(field string, input string) bson.M {
+	return bson.M{field: primitive.Regex{Pattern: regexp.QuoteMeta(input), Options: "i"}}
+}
+
+// Matches documents whose field starts with the input. The match is case sensitive, so MongoDB can use an index
+// on the field
+func RegexPrefix(field string, input string) bson.M {
+	return bson.M{field: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(input)}}
+}
+
+// Matches documents whose field starts with the input, ignoring case. Unlike RegexPrefix, this can't use an index
+// efficiently
+func RegexPrefixFold(field string, input string) bson.M {
+	return bson.M{field: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(input), Options: "i"}}
+}
+
+// Matches documents whose field equals the input, ignoring case. For frequent queries, prefer an index with a
+// case insensitive collation
+func RegexEqualFold(field string, input string) bson.M {
+	return bson.M{field: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(input) + "$", Options: "i"}}
+}
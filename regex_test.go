@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestRegexHelpers(t *testing.T) {
+	Convey("Regex helpers", t, func() {
+		Convey("should escape the input", func() {
+			So(RegexContains("name", "a.b*(c"), ShouldResemble, bson.M{"name": primitive.Regex{Pattern: `a\.b\*\(c`, Options: "i"}})
+			So(RegexPrefix("name", "a+"), ShouldResemble, bson.M{"name": primitive.Regex{Pattern: `^a\+`}})
+			So(RegexPrefixFold("name", "a+"), ShouldResemble, bson.M{"name": primitive.Regex{Pattern: `^a\+`, Options: "i"}})
+			So(RegexEqualFold("name", "a$"), ShouldResemble, bson.M{"name": primitive.Regex{Pattern: `^a\$$`, Options: "i"}})
+		})
+	})
+}
+
+func TestRegexQueries(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Regex queries", t, func() {
+		col := conn.Collection("tests")
+		col.Save(&noHookDocument{Name: "Foo (Bar)"})
+		col.Save(&noHookDocument{Name: "foobar"})
+
+		count := func(query bson.M) int64 {
+			count, _ := col.Collection().CountDocuments(context.Background(), query)
+			return count
+		}
+
+		So(count(RegexContains("name", "(bar)")), ShouldEqual, 1)
+		So(count(RegexContains("name", "FOO")), ShouldEqual, 2)
+		So(count(RegexPrefix("name", "foo")), ShouldEqual, 1)
+		So(count(RegexPrefixFold("name", "foo")), ShouldEqual, 2)
+		So(count(RegexEqualFold("name", "FOOBAR")), ShouldEqual, 1)
+		So(count(RegexContains("name", ".*")), ShouldEqual, 0)
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
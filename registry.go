@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// A document type registered against a collection, so tooling (index sync, migrations, the CLI) can work with
+// every model of an application
+type Model struct {
+	// The collection the documents are stored in
+	Collection string
+
+	// The struct type of the document (never a pointer)
+	Type reflect.Type
+
+	relations []*Relation
+}
+
+var registry = struct {
+	sync.RWMutex
+	models map[string]*Model
+}{models: make(map[string]*Model)}
+
+// Registers a document type for a collection, typically from an init() func. Registering the same collection
+// again replaces the previous model
+func RegisterModel(collection string, doc Document) *Model {
+	t := reflect.TypeOf(doc)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	model := &Model{
+		Collection: collection,
+		Type:       t,
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	registry.models[collection] = model
+
+	return model
+}
+
+// Get the model registered for a collection, or nil
+func GetModel(collection string) *Model {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.models[collection]
+}
+
+// Get all registered models, sorted by collection name
+func Models() []*Model {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	models := make([]*Model, 0, len(registry.models))
+	for _, model := range registry.models {
+		models = append(models, model)
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Collection < models[j].Collection
+	})
+
+	return models
+}
+
+// Creates a new, empty instance of the model's document type
+func (m *Model) New() Document {
+	return reflect.New(m.Type).Interface().(Document)
+}
+
+// Get the collection for a model on this connection
+func (m *Connection) ModelCollection(model *Model) *Collection {
+	return m.Collection(model.Collection)
+}
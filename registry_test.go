@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+type indexedAddress struct {
+	City string `bson:"city" index:""`
+}
+
+type indexedDocument struct {
+	DocumentBase `bson:",inline"`
+	Email        string         `bson:"email" index:",unique"`
+	FirstName    string         `bson:"first_name" index:"name"`
+	LastName     string         `bson:"last_name" index:"name,desc"`
+	Address      indexedAddress `bson:"address"`
+	Nickname     *string        `bson:"nickname"`
+	Tags         []string       `bson:"tags,omitempty"`
+	Ignored      string         `bson:"-" index:""`
+}
+
+type badIndexDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string `index:",foo"`
+}
+
+func TestRegistry(t *testing.T) {
+	Convey("Model registry", t, func() {
+		model := RegisterModel("indexed", &indexedDocument{})
+
+		Convey("should register and retrieve models", func() {
+			So(GetModel("indexed"), ShouldEqual, model)
+			So(GetModel("nope"), ShouldBeNil)
+			So(model.Type.Name(), ShouldEqual, "indexedDocument")
+
+			_, ok := model.New().(*indexedDocument)
+			So(ok, ShouldBeTrue)
+
+			found := false
+			for _, m := range Models() {
+				if m == model {
+					found = true
+				}
+			}
+			So(found, ShouldBeTrue)
+		})
+
+		Convey("should parse index tags", func() {
+			specs, err := model.Indexes()
+			So(err, ShouldEqual, nil)
+			So(len(specs), ShouldEqual, 3)
+
+			So(specs[0].Name, ShouldEqual, "email_1")
+			So(specs[0].Unique, ShouldBeTrue)
+			So(specs[0].Keys, ShouldResemble, bson.D{{Key: "email", Value: 1}})
+
+			So(specs[1].Name, ShouldEqual, "name")
+			So(specs[1].Keys, ShouldResemble, bson.D{{Key: "first_name", Value: 1}, {Key: "last_name", Value: -1}})
+
+			So(specs[2].Name, ShouldEqual, "address.city_1")
+			So(specs[2].Keys, ShouldResemble, bson.D{{Key: "address.city", Value: 1}})
+		})
+
+		Convey("should reject invalid index tags", func() {
+			_, err := RegisterModel("bad", &badIndexDocument{}).Indexes()
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should generate a json schema", func() {
+			schema := model.JSONSchema()
+			properties := schema["properties"].(bson.M)
+
+			So(properties["email"], ShouldResemble, bson.M{"bsonType": "string"})
+			So(properties["_id"], ShouldResemble, bson.M{"bsonType": "objectId"})
+			So(properties["created_at"], ShouldResemble, bson.M{"bsonType": "date"})
+			So(properties["nickname"], ShouldResemble, bson.M{"bsonType": bson.A{"string", "null"}})
+			So(properties["address"].(bson.M)["properties"], ShouldResemble, bson.M{"city": bson.M{"bsonType": "string"}})
+
+			required := schema["required"].([]string)
+			So(required, ShouldContain, "email")
+			So(required, ShouldNotContain, "_id")
+			So(required, ShouldNotContain, "tags")
+			So(required, ShouldNotContain, "nickname")
+		})
+	})
+}
+
+func TestIndexDrift(t *testing.T) {
+	Convey("Index drift", t, func() {
+		specs, err := RegisterModel("indexed", &indexedDocument{}).Indexes()
+		So(err, ShouldBeNil)
+		index := func(name string, keys bson.D) *mongo.IndexSpecification {
+			raw, _ := bson.Marshal(keys)
+			return &mongo.IndexSpecification{Name: name, KeysDocument: raw}
+		}
+		unique := true
+		email := index("email_1", bson.D{{"email", int32(1)}})
+		email.Unique = &unique
+
+		Convey("should be in sync when the declared indexes exist", func() {
+			drift := diffIndexes(specs, []*mongo.IndexSpecification{
+				index("_id_", bson.D{{"_id", int32(1)}}),
+				email,
+				index("name", bson.D{{"first_name", int32(1)}, {"last_name", int32(-1)}}),
+				index("address.city_1", bson.D{{"address.city", int32(1)}}),
+			})
+			So(drift.InSync(), ShouldBeTrue)
+		})
+
+		Convey("should report missing, extra and mismatched indexes", func() {
+			drift := diffIndexes(specs, []*mongo.IndexSpecification{
+				index("email_1", bson.D{{"email", int32(1)}}),
+				index("name", bson.D{{"first_name", int32(1)}, {"last_name", int32(-1)}}),
+				index("legacy", bson.D{{"old", int32(1)}}),
+			})
+			So(drift.InSync(), ShouldBeFalse)
+			So(len(drift.Missing), ShouldEqual, 1)
+			So(drift.Missing[0].Name, ShouldEqual, "address.city_1")
+			So(len(drift.Extra), ShouldEqual, 1)
+			So(drift.Extra[0].Name, ShouldEqual, "legacy")
+			So(len(drift.Mismatched), ShouldEqual, 1)
+			So(drift.Mismatched[0].Declared.Name, ShouldEqual, "email_1")
+		})
+	})
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+type ReindexOptions struct {
+	// Build changed indexes under a temporary name first and drop it once the new index is built, so queries keep
+	// an index on the keys while the old one is replaced. Costs a second build. Indexes that only change their
+	// options (e.g. unique) can't exist twice and are dropped before they are rebuilt regardless
+	Swap bool
+
+	// Drop the indexes of the collection that aren't in the specs, except the _id index
+	DropUnlisted bool
+
+	// Called while an index builds, if the server reports its progress, and after each index
+	Progress func(*ReindexProgress)
+
+	// How often the progress of a build is checked. Defaults to 5 seconds
+	ProgressInterval time.Duration
+}
+
+// How far Reindex has got
+type ReindexProgress struct {
+	// The index being built, and the documents it has scanned out of the total if the server reports it
+	Index string
+	Done  int64
+	Total int64
+
+	// The names of the indexes built, left alone because they were up to date, and dropped
+	Created   []string
+	Unchanged []string
+	Dropped   []string
+}
+
+// The suffix of the temporary indexes of Reindex with Swap
+const REINDEX_SUFFIX = "_reindex"
+
+// Makes the indexes of the collection match specs, e.g. the Indexes of its model: missing indexes are built, indexes
+// whose keys or options changed are rebuilt, and indexes that are up to date are left alone. Canceling ctx stops the
+// index being built and drops it again; the indexes done so far stay
+func (c *Collection) Reindex(ctx context.Context, specs []*IndexSpec, opts *ReindexOptions) (*ReindexProgress, error) {
+	if opts == nil {
+		opts = &ReindexOptions{}
+	}
+	progress := &ReindexProgress{Created: []string{}, Unchanged: []string{}, Dropped: []string{}}
+
+	current, err := c.Collection().Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return progress, err
+	}
+	existing := map[string]*mongo.IndexSpecification{}
+	for _, index := range current {
+		existing[index.Name] = index
+	}
+
+	for _, spec := range specs {
+		index, exists := existing[spec.Name]
+		switch {
+		case exists && indexMatches(spec, index):
+			progress.Unchanged = append(progress.Unchanged, spec.Name)
+			continue
+		case exists && opts.Swap && !indexKeysMatch(spec, index.KeysDocument):
+			temp := *spec
+			temp.Name = spec.Name + REINDEX_SUFFIX
+			if err := c.buildIndex(ctx, &temp, opts, progress); err != nil {
+				return progress, err
+			}
+			if err := c.dropIndex(ctx, spec.Name); err != nil {
+				return progress, err
+			}
+			if err := c.buildIndex(ctx, spec, opts, progress); err != nil {
+				return progress, err
+			}
+			if err := c.dropIndex(ctx, temp.Name); err != nil {
+				return progress, err
+			}
+		case exists:
+			if err := c.dropIndex(ctx, spec.Name); err != nil {
+				return progress, err
+			}
+			fallthrough
+		default:
+			if err := c.buildIndex(ctx, spec, opts, progress); err != nil {
+				return progress, err
+			}
+		}
+
+		progress.Created = append(progress.Created, spec.Name)
+		progress.Index, progress.Done, progress.Total = "", 0, 0
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	if opts.DropUnlisted {
+		listed := map[string]bool{"_id_": true}
+		for _, spec := range specs {
+			listed[spec.Name] = true
+		}
+		for _, index := range current {
+			if listed[index.Name] {
+				continue
+			}
+			if err := c.dropIndex(ctx, index.Name); err != nil {
+				return progress, err
+			}
+			progress.Dropped = append(progress.Dropped, index.Name)
+		}
+	}
+	return progress, nil
+}
+
+// Builds an index, reporting its progress. Index builds take as long as they take, so they don't get the
+// operation timeout
+func (c *Collection) buildIndex(ctx context.Context, spec *IndexSpec, opts *ReindexOptions, progress *ReindexProgress) error {
+	progress.Index, progress.Done, progress.Total = spec.Name, 0, 0
+
+	done := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		if opts.Progress == nil {
+			return
+		}
+		interval := opts.ProgressInterval
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if doneDocs, total, ok := c.indexBuildProgress(ctx); ok {
+					progress.Done, progress.Total = doneDocs, total
+					opts.Progress(progress)
+				}
+			}
+		}
+	}()
+
+	_, err := c.Collection().Indexes().CreateOne(ctx, spec.IndexModel())
+	close(done)
+	<-watched
+
+	if err != nil && ctx.Err() != nil {
+		// The server keeps building after the client gives up, until the index is dropped
+		c.dropIndex(context.WithoutCancel(ctx), spec.Name)
+	}
+	return err
+}
+
+// The progress of an index build on the collection as reported by $currentOp, which needs the inprog privilege
+func (c *Collection) indexBuildProgress(ctx context.Context) (int64, int64, bool) {
+	pipeline := bson.A{
+		bson.M{"$currentOp": bson.M{}},
+		bson.M{"$match": bson.M{"command.createIndexes": c.Name, "command.$db": c.Database, "progress": bson.M{"$exists": true}}},
+	}
+	cursor, err := c.Connection.Client().Database("admin").Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer cursor.Close(ctx)
+
+	op := struct {
+		Progress struct {
+			Done  int64 `bson:"done"`
+			Total int64 `bson:"total"`
+		} `bson:"progress"`
+	}{}
+	if !cursor.Next(ctx) || cursor.Decode(&op) != nil {
+		return 0, 0, false
+	}
+	return op.Progress.Done, op.Progress.Total, true
+}
+
+func (c *Collection) dropIndex(ctx context.Context, name string) error {
+	_, err := c.Collection().Indexes().DropOne(ctx, name)
+	return err
+}
+
+// Whether an existing index is what the spec describes
+func indexMatches(spec *IndexSpec, index *mongo.IndexSpecification) bool {
+	if !indexKeysMatch(spec, index.KeysDocument) {
+		return false
+	}
+	if spec.Unique != (index.Unique != nil && *index.Unique) || spec.Sparse != (index.Sparse != nil && *index.Sparse) {
+		return false
+	}
+	if (spec.ExpireAfterSeconds == nil) != (index.ExpireAfterSeconds == nil) {
+		return false
+	}
+	return spec.ExpireAfterSeconds == nil || *spec.ExpireAfterSeconds == *index.ExpireAfterSeconds
+}
+
+// Compares the keys of a spec with the keys of an existing index. Text indexes are stored with internal keys, so
+// any text index matches a spec with text keys
+func indexKeysMatch(spec *IndexSpec, keys bson.Raw) bool {
+	elements, err := keys.Elements()
+	if err != nil {
+		return false
+	}
+
+	for _, key := range spec.Keys {
+		if key.Value == "text" {
+			_, err := keys.LookupErr("_fts")
+			return err == nil
+		}
+	}
+
+	if len(elements) != len(spec.Keys) {
+		return false
+	}
+	for i, key := range spec.Keys {
+		if elements[i].Key() != key.Key {
+			return false
+		}
+		value := elements[i].Value()
+		if direction, ok := value.AsInt64OK(); ok {
+			if fmt.Sprint(direction) != fmt.Sprint(key.Value) {
+				return false
+			}
+		} else if name, ok := value.StringValueOK(); !ok || name != fmt.Sprint(key.Value) {
+			return false
+		}
+	}
+	return true
+}
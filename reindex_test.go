@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+func TestIndexMatches(t *testing.T) {
+	Convey("Index matching", t, func() {
+		keys, _ := bson.Marshal(bson.D{{"name", int32(1)}, {"age", int32(-1)}})
+		unique := true
+		index := &mongo.IndexSpecification{Name: "name_age", KeysDocument: keys, Unique: &unique}
+
+		Convey("should match the same keys and options", func() {
+			So(indexMatches(&IndexSpec{Name: "name_age", Keys: bson.D{{"name", 1}, {"age", -1}}, Unique: true}, index), ShouldBeTrue)
+		})
+
+		Convey("should tell changed keys", func() {
+			So(indexKeysMatch(&IndexSpec{Keys: bson.D{{"name", 1}, {"age", 1}}}, keys), ShouldBeFalse)
+			So(indexKeysMatch(&IndexSpec{Keys: bson.D{{"name", 1}}}, keys), ShouldBeFalse)
+		})
+
+		Convey("should tell changed options", func() {
+			So(indexMatches(&IndexSpec{Keys: bson.D{{"name", 1}, {"age", -1}}}, index), ShouldBeFalse)
+			ttl := int32(60)
+			So(indexMatches(&IndexSpec{Keys: bson.D{{"name", 1}, {"age", -1}}, Unique: true, ExpireAfterSeconds: &ttl}, index), ShouldBeFalse)
+		})
+
+		Convey("should match text indexes by their internal keys", func() {
+			text, _ := bson.Marshal(bson.D{{"_fts", "text"}, {"_ftsx", int32(1)}})
+			So(indexKeysMatch(&IndexSpec{Keys: bson.D{{"title", "text"}}}, text), ShouldBeTrue)
+			So(indexKeysMatch(&IndexSpec{Keys: bson.D{{"title", "text"}}}, keys), ShouldBeFalse)
+		})
+	})
+}
+
+func TestReindex(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Reindex", t, func() {
+		ctx := context.Background()
+		tests := conn.Collection("tests")
+		So(tests.Save(&noHookDocument{Name: "ann"}), ShouldBeNil)
+		_, err := tests.Collection().Indexes().CreateOne(ctx, (&IndexSpec{Name: "name", Keys: bson.D{{"name", 1}}}).IndexModel())
+		So(err, ShouldBeNil)
+
+		Convey("should build missing and changed indexes only", func() {
+			specs := []*IndexSpec{
+				{Name: "name", Keys: bson.D{{"name", -1}}},
+				{Name: "created", Keys: bson.D{{"created_at", 1}}},
+			}
+			progress, err := tests.Reindex(ctx, specs, &ReindexOptions{Swap: true})
+			So(err, ShouldBeNil)
+			So(progress.Created, ShouldResemble, []string{"name", "created"})
+
+			progress, err = tests.Reindex(ctx, specs, &ReindexOptions{DropUnlisted: true})
+			So(err, ShouldBeNil)
+			So(progress.Unchanged, ShouldResemble, []string{"name", "created"})
+			So(progress.Dropped, ShouldBeEmpty)
+
+			current, err := tests.Collection().Indexes().ListSpecifications(ctx)
+			So(err, ShouldBeNil)
+			So(len(current), ShouldEqual, 3)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
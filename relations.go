@@ -0,0 +1,481 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Kinds of relations between models
+const (
+	// One related document holds the key of this one in its ForeignKey
+	HAS_ONE = iota
+
+	// Any number of related documents hold the key of this one in their ForeignKey
+	HAS_MANY
+
+	// This document holds the key of the related one (or an array of keys) in its LocalKey
+	BELONGS_TO
+)
+
+// A relation of a registered model to the documents of another collection, declared with Model.HasOne, HasMany or
+// BelongsTo. Relations power Populate, generate the cascade configs of documents that don't implement GetCascade,
+// and BelongsTo relations are checked like `ref` tags
+type Relation struct {
+	// The Go field of the document that Populate fills, e.g. Parent (a *Parent) or Children (a []*Child). It is
+	// usually tagged `bson:"-"`
+	Name string
+	Kind int
+
+	// The related collection
+	Collection string
+
+	// The bson fields that link the documents, LocalKey on this document and ForeignKey on the related ones. The
+	// foreign key of BelongsTo relations and the local key of HasOne/HasMany relations default to _id
+	LocalKey   string
+	ForeignKey string
+
+	cascade *relationCascade
+}
+
+type relationCascade struct {
+	throughProp string
+	relType     int
+	properties  []string
+	notify      bool
+}
+
+// Declares that one document of a collection holds the key of this model's documents in foreignKey
+func (m *Model) HasOne(name string, collection string, foreignKey string) *Relation {
+	return m.addRelation(&Relation{Name: name, Kind: HAS_ONE, Collection: collection, LocalKey: "_id", ForeignKey: foreignKey})
+}
+
+// Declares that documents of a collection hold the key of this model's documents in foreignKey
+func (m *Model) HasMany(name string, collection string, foreignKey string) *Relation {
+	return m.addRelation(&Relation{Name: name, Kind: HAS_MANY, Collection: collection, LocalKey: "_id", ForeignKey: foreignKey})
+}
+
+// Declares that this model's documents hold the _id of a document of a collection in localKey, e.g.
+//
+//	bongo.RegisterModel("children", &Child{}).BelongsTo("Parent", "parents", "parentId")
+func (m *Model) BelongsTo(name string, collection string, localKey string) *Relation {
+	return m.addRelation(&Relation{Name: name, Kind: BELONGS_TO, Collection: collection, LocalKey: localKey, ForeignKey: "_id"})
+}
+
+// Adds a relation, replacing any relation with the same name
+func (m *Model) addRelation(relation *Relation) *Relation {
+	for i, existing := range m.relations {
+		if existing.Name == relation.Name {
+			m.relations[i] = relation
+			return relation
+		}
+	}
+	m.relations = append(m.relations, relation)
+	return relation
+}
+
+// Get the relations declared on the model
+func (m *Model) Relations() []*Relation {
+	return append([]*Relation{}, m.relations...)
+}
+
+// Get a relation of the model by name, or nil
+func (m *Model) Relation(name string) *Relation {
+	for _, relation := range m.relations {
+		if relation.Name == name {
+			return relation
+		}
+	}
+	return nil
+}
+
+// Copies properties (bson paths) of the documents into the document they belong to whenever they are saved, and
+// removes them again on delete, like a CascadeConfig with the same ThroughProp, RelType and Properties. Without a
+// through prop, the properties are set on the root of the related document. Only for BelongsTo relations:
+//
+//	bongo.GetModel("children").BelongsTo("Parent", "parents", "parentId").CascadeTo("children", bongo.REL_MANY, "name")
+func (r *Relation) CascadeTo(throughProp string, relType int, properties ...string) *Relation {
+	r.cascade = &relationCascade{throughProp: throughProp, relType: relType, properties: properties}
+	return r
+}
+
+// Calls the AfterCascadedInto hook of the related documents after cascading to them (see CascadeConfig.Notify).
+// Only after CascadeTo
+func (r *Relation) NotifyTargets() *Relation {
+	if r.cascade != nil {
+		r.cascade.notify = true
+	}
+	return r
+}
+
+// Loads the related documents of the named relations into the relation fields of docs (a document, or a slice of
+// documents, of the model registered for the collection), with one $in query per relation:
+//
+//	err := conn.Collection("parents").Populate(parents, "Children")
+//
+// Dotted paths load the relations of the related documents too, one level at a time, e.g. "Children.Toys" or
+// "Orders.Items.Product". Relation fields can be a *T, a T, or a []*T or []T for relations that can match more
+// than one document
+func (c *Collection) Populate(docs interface{}, relations ...string) error {
+	values, err := documentValues(docs)
+	if err != nil || len(values) == 0 {
+		return err
+	}
+	return c.populatePaths(values, relations)
+}
+
+// Populates the relation paths of documents of the collection
+func (c *Collection) populatePaths(docs []reflect.Value, paths []string) error {
+	model := GetModel(c.Name)
+	if model == nil {
+		return errors.New("no model registered for collection " + c.Name)
+	}
+
+	// Group the paths by their first relation, in order
+	names := make([]string, 0)
+	nested := make(map[string][]string)
+	for _, path := range paths {
+		name, rest, _ := strings.Cut(path, ".")
+		if _, ok := nested[name]; !ok {
+			names = append(names, name)
+			nested[name] = make([]string, 0)
+		}
+		if len(rest) > 0 {
+			nested[name] = append(nested[name], rest)
+		}
+	}
+
+	for _, name := range names {
+		relation := model.Relation(name)
+		if relation == nil {
+			return errors.New("unknown relation " + name + " of " + c.Name)
+		}
+		if err := c.populate(relation, docs); err != nil {
+			return err
+		}
+
+		if len(nested[name]) == 0 {
+			continue
+		}
+		if related := relatedValues(docs, name); len(related) > 0 {
+			err := c.Connection.CollectionFromDatabase(relation.Collection, c.Database).populatePaths(related, nested[name])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Gets the struct values of the documents loaded into a relation field, each document once
+func relatedValues(docs []reflect.Value, name string) []reflect.Value {
+	values := make([]reflect.Value, 0)
+	seen := make(map[uintptr]bool)
+	add := func(v reflect.Value) {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return
+			}
+			v = v.Elem()
+		}
+		if !seen[v.Addr().Pointer()] {
+			seen[v.Addr().Pointer()] = true
+			values = append(values, v)
+		}
+	}
+
+	for _, doc := range docs {
+		field := doc.FieldByName(name)
+		if field.Kind() != reflect.Slice {
+			if field.Kind() != reflect.Ptr && field.IsZero() {
+				continue
+			}
+			add(field)
+			continue
+		}
+		for i := 0; i < field.Len(); i++ {
+			add(field.Index(i))
+		}
+	}
+	return values
+}
+
+// Gets the struct values of a document or slice of documents
+func documentValues(docs interface{}) ([]reflect.Value, error) {
+	v := reflect.ValueOf(docs)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		if v.Elem().Kind() == reflect.Struct {
+			return []reflect.Value{v.Elem()}, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		return nil, errors.New("populate needs a pointer to a document or a slice of documents")
+	}
+
+	values := make([]reflect.Value, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return nil, errors.New("populate needs a pointer to a document or a slice of documents")
+		}
+		values = append(values, elem)
+	}
+	return values, nil
+}
+
+// Loads the related documents of one relation with a single query
+func (c *Collection) populate(relation *Relation, docs []reflect.Value) error {
+	field, ok := docs[0].Type().FieldByName(relation.Name)
+	if !ok {
+		return errors.New("relation " + relation.Name + " has no field on " + docs[0].Type().Name())
+	}
+	relatedType := field.Type
+	if relatedType.Kind() == reflect.Slice {
+		relatedType = relatedType.Elem()
+	}
+	if relatedType.Kind() == reflect.Ptr {
+		relatedType = relatedType.Elem()
+	}
+	if relatedType.Kind() != reflect.Struct {
+		return errors.New("relation field " + relation.Name + " must be a struct, a pointer or a slice of either")
+	}
+
+	// The local keys of each document
+	keys := make([][]bson.RawValue, len(docs))
+	all := bson.A{}
+	for i, doc := range docs {
+		raw, err := bson.MarshalWithRegistry(Registry, doc.Addr().Interface())
+		if err != nil {
+			return err
+		}
+		if value, err := bson.Raw(raw).LookupErr(strings.Split(relation.LocalKey, ".")...); err == nil {
+			keys[i] = rawValues(value)
+			for _, key := range keys[i] {
+				all = append(all, key)
+			}
+		}
+	}
+
+	related := c.Connection.CollectionFromDatabase(relation.Collection, c.Database)
+	index := make(map[string][]reflect.Value)
+	found := make([]reflect.Value, 0)
+
+	if len(all) > 0 {
+		filter := bson.M{relation.ForeignKey: bson.M{"$in": all}}
+		opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+		path := strings.Split(relation.ForeignKey, ".")
+
+		err := related.runOperation("populate", func(ctx context.Context) error {
+			index = make(map[string][]reflect.Value)
+			found = found[:0]
+
+			guarded, err := related.guardFilter(ctx, filter)
+			if err != nil {
+				return err
+			}
+			commentOn(ctx, related, opts.SetComment)
+			cursor, err := related.Collection().Find(ctx, guarded, opts)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+
+			for cursor.Next(ctx) {
+				doc := reflect.New(relatedType)
+				if err := cursor.Decode(doc.Interface()); err != nil {
+					return newDecodeError(related, cursor.Current, doc.Interface(), err)
+				}
+				found = append(found, doc)
+
+				value, err := cursor.Current.LookupErr(path...)
+				if err != nil {
+					continue
+				}
+				for _, key := range rawValues(value) {
+					index[rawKey(key)] = append(index[rawKey(key)], doc)
+				}
+			}
+			return cursor.Err()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, doc := range found {
+		if hook, ok := doc.Interface().(AfterFindHook); ok {
+			if err := hook.AfterFind(related); err != nil {
+				return err
+			}
+		}
+		if newt, ok := doc.Interface().(NewTracker); ok {
+			newt.SetIsNew(false)
+		}
+	}
+
+	for i, doc := range docs {
+		matches := make([]reflect.Value, 0)
+		seen := make(map[uintptr]bool)
+		for _, key := range keys[i] {
+			for _, match := range index[rawKey(key)] {
+				if !seen[match.Pointer()] {
+					seen[match.Pointer()] = true
+					matches = append(matches, match)
+				}
+			}
+		}
+		setRelated(doc.FieldByName(relation.Name), matches)
+	}
+	return nil
+}
+
+// Sets a relation field to the matching documents (pointers)
+func setRelated(field reflect.Value, matches []reflect.Value) {
+	switch field.Kind() {
+	case reflect.Slice:
+		values := reflect.MakeSlice(field.Type(), 0, len(matches))
+		for _, match := range matches {
+			if field.Type().Elem().Kind() == reflect.Ptr {
+				values = reflect.Append(values, match)
+			} else {
+				values = reflect.Append(values, match.Elem())
+			}
+		}
+		field.Set(values)
+	case reflect.Ptr:
+		if len(matches) > 0 {
+			field.Set(matches[0])
+		} else {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	default:
+		if len(matches) > 0 {
+			field.Set(matches[0].Elem())
+		} else {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+}
+
+// The values of an array, or the value itself
+func rawValues(value bson.RawValue) []bson.RawValue {
+	if array, ok := value.ArrayOK(); ok {
+		values, _ := array.Values()
+		return values
+	}
+	return []bson.RawValue{value}
+}
+
+// A map key for a stored value, the same for numbers of different types with the same value
+func rawKey(value bson.RawValue) string {
+	if number, ok := rawNumber(value); ok {
+		return "n" + strconv.FormatFloat(number, 'g', -1, 64)
+	}
+	return string([]byte{byte(value.Type)}) + string(value.Value)
+}
+
+// Generates the cascade configs of the BelongsTo relations with CascadeTo of the model registered for the collection
+func relationCascades(collection *Collection, doc Document) []*CascadeConfig {
+	model := GetModel(collection.Name)
+	if model == nil || collection.Connection == nil {
+		return nil
+	}
+
+	configs := make([]*CascadeConfig, 0)
+	var raw bson.Raw
+	for _, relation := range model.relations {
+		if relation.Kind != BELONGS_TO || relation.cascade == nil {
+			continue
+		}
+
+		if raw == nil {
+			data, err := bson.MarshalWithRegistry(Registry, doc)
+			if err != nil {
+				return configs
+			}
+			raw = data
+		}
+
+		key, err := raw.LookupErr(strings.Split(relation.LocalKey, ".")...)
+		if err != nil {
+			continue
+		}
+
+		conf := &CascadeConfig{
+			Collection:     collection.Connection.CollectionFromDatabase(relation.Collection, collection.Database),
+			RelType:        relation.cascade.relType,
+			ThroughProp:    relation.cascade.throughProp,
+			Properties:     relation.cascade.properties,
+			Query:          bson.M{relation.ForeignKey: key},
+			Data:           cascadeData(raw, relation.cascade),
+			ReferenceQuery: []*ReferenceField{{"_id", doc.GetID()}},
+			Notify:         relation.cascade.notify,
+		}
+		if original := originalValue(doc, relation.LocalKey); original != nil {
+			conf.OldQuery = bson.M{relation.ForeignKey: original}
+		}
+		configs = append(configs, conf)
+	}
+	return configs
+}
+
+// The data a relation cascades. Embedded copies also get the _id, so they can be found again
+func cascadeData(raw bson.Raw, cascade *relationCascade) bson.M {
+	data := bson.M{}
+	if len(cascade.throughProp) > 0 {
+		data["_id"] = raw.Lookup("_id")
+	}
+	for _, property := range cascade.properties {
+		if value, err := raw.LookupErr(strings.Split(property, ".")...); err == nil {
+			setPath(data, property, value)
+		}
+	}
+	return data
+}
+
+// The original value of a changed field, if the document tracks its changes
+func originalValue(doc Document, path string) interface{} {
+	tracked, ok := doc.(interface {
+		GetDiffTracker() *DiffTracker
+	})
+	if !ok {
+		return nil
+	}
+
+	goPath := goPathOf(reflect.TypeOf(doc), path)
+	if len(goPath) == 0 || !tracked.GetDiffTracker().Modified(goPath) {
+		return nil
+	}
+	original, err := tracked.GetDiffTracker().GetOriginalValue(goPath)
+	if err != nil {
+		return nil
+	}
+	return original
+}
+
+// The Go path of the field stored under a bson path, or ""
+func goPathOf(t reflect.Type, path string) string {
+	goPath := ""
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, fieldGoPath string, fieldPath string) bool {
+		if fieldPath == path {
+			goPath = fieldGoPath
+		}
+		return len(goPath) == 0
+	})
+	return goPath
+}
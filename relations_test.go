@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type relAuthor struct {
+	DocumentBase `bson:",inline"`
+	Name         string     `bson:"name"`
+	Books        []*relBook `bson:"-"`
+}
+
+type relBook struct {
+	DocumentBase `bson:",inline"`
+	Title        string             `bson:"title"`
+	AuthorID     primitive.ObjectID `bson:"authorId"`
+	Pages        int                `bson:"pages"`
+	Author       *relAuthor         `bson:"-"`
+	diffTracker  *DiffTracker
+}
+
+func (b *relBook) GetDiffTracker() *DiffTracker {
+	if b.diffTracker == nil {
+		b.diffTracker = NewDiffTracker(b)
+	}
+	return b.diffTracker
+}
+
+func registerRelModels() {
+	RegisterModel("rel_authors", &relAuthor{}).HasMany("Books", "rel_books", "authorId")
+	RegisterModel("rel_books", &relBook{}).BelongsTo("Author", "rel_authors", "authorId").CascadeTo("books", REL_MANY, "title")
+}
+
+func TestRelations(t *testing.T) {
+	Convey("Relations", t, func() {
+		registerRelModels()
+
+		Convey("should default the keys", func() {
+			books := GetModel("rel_authors").Relation("Books")
+			So(books.Kind, ShouldEqual, HAS_MANY)
+			So(books.LocalKey, ShouldEqual, "_id")
+			So(books.ForeignKey, ShouldEqual, "authorId")
+
+			author := GetModel("rel_books").Relation("Author")
+			So(author.Kind, ShouldEqual, BELONGS_TO)
+			So(author.LocalKey, ShouldEqual, "authorId")
+			So(author.ForeignKey, ShouldEqual, "_id")
+
+			So(GetModel("rel_books").Relation("Missing"), ShouldBeNil)
+		})
+
+		Convey("should replace relations with the same name", func() {
+			model := RegisterModel("rel_authors", &relAuthor{})
+			model.HasMany("Books", "rel_books", "authorId")
+			model.HasOne("Books", "rel_books", "writerId")
+			So(len(model.Relations()), ShouldEqual, 1)
+			So(model.Relation("Books").Kind, ShouldEqual, HAS_ONE)
+		})
+
+		Convey("should generate cascade configs", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest"}}
+			author := primitive.NewObjectID()
+			book := &relBook{Title: "Dune", AuthorID: author}
+			book.SetID(primitive.NewObjectID())
+			book.GetDiffTracker().Reset()
+
+			configs := relationCascades(&Collection{Name: "rel_books", Connection: conn}, book)
+			So(len(configs), ShouldEqual, 1)
+			So(configs[0].Collection.Name, ShouldEqual, "rel_authors")
+			So(configs[0].ThroughProp, ShouldEqual, "books")
+			So(configs[0].RelType, ShouldEqual, REL_MANY)
+			So(configs[0].Query, ShouldResemble, bson.M{"_id": bson.RawValue{Type: bson.TypeObjectID, Value: author[:]}})
+			So(configs[0].OldQuery, ShouldBeNil)
+			So(configs[0].ReferenceQuery[0].Value, ShouldEqual, book.GetID())
+
+			data := configs[0].Data.(bson.M)
+			So(data["_id"].(bson.RawValue).ObjectID(), ShouldEqual, book.GetID())
+			So(data["title"].(bson.RawValue).StringValue(), ShouldEqual, "Dune")
+		})
+
+		Convey("should check BelongsTo keys like ref tags", func() {
+			fields := references("rel_books", reflect.TypeOf(relBook{}))
+			So(len(fields), ShouldEqual, 1)
+			So(*fields[0], ShouldResemble, refField{goPath: "AuthorID", path: "authorId", collection: "rel_authors", foreignKey: "_id"})
+		})
+
+		Convey("should assign related documents to the relation fields", func() {
+			first, second := &relBook{Title: "a"}, &relBook{Title: "b"}
+			matches := []reflect.Value{reflect.ValueOf(first), reflect.ValueOf(second)}
+
+			author := &relAuthor{}
+			setRelated(reflect.ValueOf(author).Elem().FieldByName("Books"), matches)
+			So(author.Books, ShouldResemble, []*relBook{first, second})
+
+			book := &relBook{}
+			setRelated(reflect.ValueOf(book).Elem().FieldByName("Author"), []reflect.Value{reflect.ValueOf(&relAuthor{Name: "x"})})
+			So(book.Author.Name, ShouldEqual, "x")
+			setRelated(reflect.ValueOf(book).Elem().FieldByName("Author"), nil)
+			So(book.Author, ShouldBeNil)
+		})
+
+		Convey("should collect the loaded documents once for nested relations", func() {
+			author := &relAuthor{Name: "x"}
+			books := []reflect.Value{reflect.ValueOf(&relBook{Author: author}).Elem(), reflect.ValueOf(&relBook{Author: author}).Elem(), reflect.ValueOf(&relBook{}).Elem()}
+			related := relatedValues(books, "Author")
+			So(len(related), ShouldEqual, 1)
+			So(related[0].Addr().Interface(), ShouldEqual, author)
+
+			authors := []reflect.Value{reflect.ValueOf(&relAuthor{Books: []*relBook{{Title: "a"}, nil, {Title: "b"}}}).Elem()}
+			So(len(relatedValues(authors, "Books")), ShouldEqual, 2)
+		})
+
+		Convey("should reject unknown relations", func() {
+			col := &Collection{Name: "rel_books"}
+			So(col.Populate(&relBook{}, "Publisher").Error(), ShouldEqual, "unknown relation Publisher of rel_books")
+			So(col.Populate(&relBook{}, "Publisher.Address").Error(), ShouldEqual, "unknown relation Publisher of rel_books")
+			So((&Collection{Name: "rel_unknown"}).Populate(&relBook{}, "Author"), ShouldNotEqual, nil)
+		})
+	})
+}
+
+func TestPopulate(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Populate", t, func() {
+		registerRelModels()
+		authors, books := conn.Collection("rel_authors"), conn.Collection("rel_books")
+
+		author := &relAuthor{Name: "Frank"}
+		So(authors.Save(author), ShouldEqual, nil)
+		dune := &relBook{Title: "Dune", AuthorID: author.ID}
+		messiah := &relBook{Title: "Messiah", AuthorID: author.ID}
+		So(books.Save(dune), ShouldEqual, nil)
+		So(books.Save(messiah), ShouldEqual, nil)
+
+		Convey("should load BelongsTo relations", func() {
+			list := []*relBook{dune, messiah}
+			So(books.Populate(list, "Author"), ShouldEqual, nil)
+			So(list[0].Author.Name, ShouldEqual, "Frank")
+			So(list[1].Author.ID, ShouldEqual, author.ID)
+		})
+
+		Convey("should load HasMany relations", func() {
+			So(authors.Populate(author, "Books"), ShouldEqual, nil)
+			So(len(author.Books), ShouldEqual, 2)
+			So(author.Books[0].Title, ShouldEqual, "Dune")
+		})
+
+		Convey("should load nested relations", func() {
+			list := []*relBook{dune}
+			So(books.Populate(list, "Author.Books"), ShouldEqual, nil)
+			So(len(list[0].Author.Books), ShouldEqual, 2)
+			So(list[0].Author.Books[1].Title, ShouldEqual, "Messiah")
+		})
+
+		Convey("should cascade to the related document", func() {
+			time.Sleep(100 * time.Millisecond)
+			stored := bson.M{}
+			err := authors.Collection().FindOne(context.Background(), bson.M{"_id": author.ID}).Decode(&stored)
+			So(err, ShouldEqual, nil)
+			So(len(stored["books"].(bson.A)), ShouldEqual, 2)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+)
+
+type RenameOptions struct {
+	// How many documents are renamed per update. Defaults to 1000
+	BatchSize int
+
+	// Called after each batch
+	Progress func(*RenameProgress)
+}
+
+// How far RenameFields has got
+type RenameProgress struct {
+	// Documents renamed so far, and the number of batches
+	Renamed int64
+	Batches int
+
+	// The _id of the last renamed document
+	LastID interface{}
+}
+
+const defaultRenameBatchSize = 1000
+
+// Renames fields (old name => new name, dotted paths allowed) in the documents matching the filter, in batches
+// of $rename updates ordered by _id, so huge collections don't need one long running update. Only documents that
+// still have one of the old fields are touched, so it can simply be run again after an interruption to resume
+// where it stopped, e.g. from a migration after a bson tag changed:
+//
+//	_, err := conn.Collection("users").RenameFields(map[string]string{"mail": "email"}, nil, nil)
+func (c *Collection) RenameFields(renames map[string]string, filter interface{}, opts *RenameOptions) (*RenameProgress, error) {
+	if opts == nil {
+		opts = &RenameOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRenameBatchSize
+	}
+
+	if len(renames) == 0 {
+		return nil, errors.New("no fields to rename")
+	}
+	for from, to := range renames {
+		if len(from) == 0 || len(to) == 0 || from == to {
+			return nil, errors.New("invalid rename of " + from + " to " + to)
+		}
+	}
+
+	progress := &RenameProgress{}
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(batchSize)).
+		SetProjection(bson.M{"_id": 1})
+
+	for {
+		ids := make(bson.A, 0, batchSize)
+		err := c.runOperation("renameFields", func(ctx context.Context) error {
+			ids = ids[:0]
+			guarded, err := c.guardFilter(ctx, renameFilter(renames, filter, progress.LastID))
+			if err != nil {
+				return err
+			}
+			cursor, err := c.Collection().Find(ctx, guarded, findOpts)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+
+			for cursor.Next(ctx) {
+				ids = append(ids, cursor.Current.Lookup("_id"))
+			}
+			return cursor.Err()
+		})
+		if err != nil {
+			return progress, err
+		}
+		if len(ids) == 0 {
+			return progress, nil
+		}
+
+		res, err := c.updateMany(bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$rename": renames})
+		if err != nil {
+			return progress, err
+		}
+
+		progress.Renamed += res.ModifiedCount
+		progress.Batches++
+		var lastID interface{}
+		if err := ids[len(ids)-1].(bson.RawValue).Unmarshal(&lastID); err != nil {
+			return progress, err
+		}
+		progress.LastID = lastID
+
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+}
+
+// Matches the documents after the last renamed one that still have one of the old fields
+func renameFilter(renames map[string]string, filter interface{}, after interface{}) bson.M {
+	fields := make([]string, 0, len(renames))
+	for from := range renames {
+		fields = append(fields, from)
+	}
+	sort.Strings(fields)
+
+	exists := make(bson.A, len(fields))
+	for i, field := range fields {
+		exists[i] = bson.M{field: bson.M{"$exists": true}}
+	}
+
+	conditions := bson.A{queryFilter(filter), bson.M{"$or": exists}}
+	if after != nil {
+		conditions = append(conditions, bson.M{"_id": bson.M{"$gt": after}})
+	}
+	return bson.M{"$and": conditions}
+}
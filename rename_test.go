@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestRenameFilter(t *testing.T) {
+	Convey("Rename filter", t, func() {
+		Convey("should match documents that still have an old field", func() {
+			filter := renameFilter(map[string]string{"mail": "email", "fname": "first_name"}, bson.M{"active": true}, nil)
+			So(filter, ShouldResemble, bson.M{"$and": bson.A{
+				bson.M{"active": true},
+				bson.M{"$or": bson.A{
+					bson.M{"fname": bson.M{"$exists": true}},
+					bson.M{"mail": bson.M{"$exists": true}},
+				}},
+			}})
+		})
+
+		Convey("should continue after the last renamed document", func() {
+			filter := renameFilter(map[string]string{"mail": "email"}, nil, 5)
+			So(filter["$and"].(bson.A)[2], ShouldResemble, bson.M{"_id": bson.M{"$gt": 5}})
+		})
+
+		Convey("should reject invalid renames", func() {
+			_, err := (&Collection{}).RenameFields(map[string]string{}, nil, nil)
+			So(err, ShouldNotBeNil)
+			_, err = (&Collection{}).RenameFields(map[string]string{"mail": "mail"}, nil, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestRenameFields(t *testing.T) {
+	conn := getConnection()
+
+	Convey("RenameFields", t, func() {
+		col := conn.Collection("renames")
+		for i := 0; i < 5; i++ {
+			col.Collection().InsertOne(context.Background(), bson.M{"_id": i, "mail": "user@example.com"})
+		}
+
+		Convey("should rename in batches and report progress", func() {
+			calls := 0
+			progress, err := col.RenameFields(map[string]string{"mail": "email"}, nil, &RenameOptions{
+				BatchSize: 2,
+				Progress: func(p *RenameProgress) {
+					calls++
+				},
+			})
+			So(err, ShouldEqual, nil)
+			So(progress.Renamed, ShouldEqual, 5)
+			So(progress.Batches, ShouldEqual, 3)
+			So(calls, ShouldEqual, 3)
+
+			count, _ := col.Collection().CountDocuments(context.Background(), bson.M{"email": "user@example.com"})
+			So(count, ShouldEqual, 5)
+		})
+
+		Convey("should have nothing to do when run again", func() {
+			col.RenameFields(map[string]string{"mail": "email"}, nil, nil)
+			progress, err := col.RenameFields(map[string]string{"mail": "email"}, nil, nil)
+			So(err, ShouldEqual, nil)
+			So(progress.Renamed, ShouldEqual, 0)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
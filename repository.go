@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+)
+
+// Typed CRUD on one collection, for services that don't need more than that:
+//
+//	users := bongo.NewRepository[*User](conn, "users")
+//	err := users.Create(ctx, &User{Name: "Ann"})
+//	page, err := users.List(ctx, &bongo.ListOptions{Filter: bson.M{"active": true}, Page: 2})
+//
+// The writes run the hooks and cascades like Save and DeleteDocument do
+type Repository[T Document] struct {
+	Collection *Collection
+
+	// Default and maximum page sizes for List. PerPage defaults to CollectionDefaults.PerPage, or else 20
+	PerPage    int
+	MaxPerPage int
+}
+
+type ListOptions struct {
+	// Query to match, all documents if nil
+	Filter interface{}
+
+	// Sort order, e.g. bson.D{{"created_at", -1}}. The collection's default sort or _id if nil
+	Sort interface{}
+
+	// 1-based page, and the page size (the repository's PerPage if 0)
+	Page    int
+	PerPage int
+}
+
+// A page of documents listed by a Repository
+type Page[T Document] struct {
+	Data       []T             `json:"data"`
+	Pagination *PaginationInfo `json:"pagination"`
+}
+
+// Creates a repository on the named collection. T must be a pointer to a document struct, like *User
+func NewRepository[T Document](conn *Connection, name string) *Repository[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic("bongo: NewRepository needs a pointer to a document struct, not " + t.String())
+	}
+
+	repository := &Repository[T]{
+		Collection: conn.Collection(name),
+		PerPage:    20,
+		MaxPerPage: 100,
+	}
+	if perPage := repository.Collection.defaults().PerPage; perPage > 0 {
+		repository.PerPage = perPage
+	}
+	return repository
+}
+
+// A new, empty document
+func (r *Repository[T]) New() T {
+	return reflect.New(reflect.TypeOf((*T)(nil)).Elem().Elem()).Interface().(T)
+}
+
+// Gets the document with the id, or a *DocumentNotFoundError
+func (r *Repository[T]) Get(ctx context.Context, id primitive.ObjectID) (T, error) {
+	doc := r.New()
+	if err := r.Collection.findByID(ctx, id, doc); err != nil {
+		var zero T
+		return zero, err
+	}
+	return doc, nil
+}
+
+// Lists a page of the documents matching the filter
+func (r *Repository[T]) List(ctx context.Context, opts *ListOptions) (*Page[T], error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	perPage := opts.PerPage
+	if perPage < 1 {
+		perPage = r.PerPage
+	}
+	if r.MaxPerPage > 0 && perPage > r.MaxPerPage {
+		perPage = r.MaxPerPage
+	}
+
+	results, err := r.Collection.Find(opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Sort != nil {
+		results.Query.SetSort(opts.Sort)
+	} else if results.Query.Sort == nil {
+		results.Query.SetSort(bson.D{{"_id", 1}})
+	}
+
+	info, err := results.Paginate(perPage, opts.Page)
+	if err != nil {
+		results.Free()
+		return nil, err
+	}
+
+	page := &Page[T]{Data: make([]T, 0, info.RecordsOnPage), Pagination: info}
+	for {
+		doc := r.New()
+		if !results.next(ctx, doc) {
+			break
+		}
+		page.Data = append(page.Data, doc)
+	}
+	results.Free()
+	if results.Error != nil {
+		return nil, results.Error
+	}
+	return page, nil
+}
+
+// Saves a new document. Documents that have been saved or loaded before are rejected
+func (r *Repository[T]) Create(ctx context.Context, doc T, opts ...WriteOption) error {
+	if newt, ok := Document(doc).(NewTracker); ok && !newt.IsNew() {
+		return errors.New("the document already exists, use Update")
+	}
+	return r.Collection.save(ctx, doc, newWriteOptions(opts))
+}
+
+// Saves an existing document, or returns a *DocumentNotFoundError if there is no document with its id
+func (r *Repository[T]) Update(ctx context.Context, doc T, opts ...WriteOption) error {
+	exists, err := r.exists(ctx, doc.GetID())
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &DocumentNotFoundError{}
+	}
+	return r.Collection.save(ctx, doc, newWriteOptions(opts))
+}
+
+// Deletes the document with the id, or returns a *DocumentNotFoundError
+func (r *Repository[T]) Delete(ctx context.Context, id primitive.ObjectID, opts ...WriteOption) error {
+	doc, err := r.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = r.Collection.deleteDocument(ctx, doc, newWriteOptions(opts))
+	return err
+}
+
+// Counts the documents matching the filter, all documents if nil
+func (r *Repository[T]) Count(ctx context.Context, filter interface{}) (int64, error) {
+	var count int64
+	err := r.Collection.runOperationContext(ctx, "count", func(ctx context.Context) error {
+		guarded, err := r.Collection.guardFilter(ctx, filter)
+		if err != nil {
+			return err
+		}
+		opts := options.Count()
+		commentOn(ctx, r.Collection, opts.SetComment)
+		count, err = r.Collection.Collection().CountDocuments(ctx, guarded, opts)
+		return err
+	})
+	return count, err
+}
+
+func (r *Repository[T]) exists(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	if id.IsZero() {
+		return false, nil
+	}
+
+	var count int64
+	err := r.Collection.runOperationContext(ctx, "count", func(ctx context.Context) error {
+		filter, err := r.Collection.guardFilter(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		opts := options.Count().SetLimit(1)
+		commentOn(ctx, r.Collection, opts.SetComment)
+		count, err = r.Collection.Collection().CountDocuments(ctx, filter, opts)
+		return err
+	})
+	return count > 0, err
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestRepositoryChecks(t *testing.T) {
+	Convey("Repository", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}}
+		repository := NewRepository[*noHookDocument](conn, "tests")
+
+		Convey("should use the named collection", func() {
+			So(repository.Collection.Name, ShouldEqual, "tests")
+			So(repository.Collection.Database, ShouldEqual, "bongotest")
+		})
+
+		Convey("should create new documents", func() {
+			doc := repository.New()
+			So(doc, ShouldNotBeNil)
+			So(doc.IsNew(), ShouldBeTrue)
+			So(repository.New(), ShouldNotPointTo, doc)
+		})
+
+		Convey("should only create new documents", func() {
+			doc := repository.New()
+			doc.SetIsNew(false)
+			So(repository.Create(context.Background(), doc), ShouldNotBeNil)
+		})
+
+		Convey("should only update saved documents", func() {
+			err := repository.Update(context.Background(), repository.New())
+			So(err, ShouldHaveSameTypeAs, &DocumentNotFoundError{})
+		})
+	})
+}
+
+func TestRepository(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Repository CRUD", t, func() {
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+
+		ctx := context.Background()
+		repository := NewRepository[*noHookDocument](conn, "tests")
+		for _, name := range []string{"c", "a", "b"} {
+			So(repository.Create(ctx, &noHookDocument{Name: name}), ShouldEqual, nil)
+		}
+
+		count, err := repository.Count(ctx, bson.M{"name": bson.M{"$ne": "a"}})
+		So(err, ShouldEqual, nil)
+		So(count, ShouldEqual, 2)
+
+		page, err := repository.List(ctx, &ListOptions{Sort: bson.D{{"name", 1}}, Page: 2, PerPage: 2})
+		So(err, ShouldEqual, nil)
+		So(page.Pagination.TotalRecords, ShouldEqual, 3)
+		So(len(page.Data), ShouldEqual, 1)
+		So(page.Data[0].Name, ShouldEqual, "c")
+
+		doc, err := repository.Get(ctx, page.Data[0].ID)
+		So(err, ShouldEqual, nil)
+		doc.Name = "d"
+		So(repository.Update(ctx, doc), ShouldEqual, nil)
+
+		So(repository.Delete(ctx, doc.ID), ShouldEqual, nil)
+		_, err = repository.Get(ctx, doc.ID)
+		So(err, ShouldHaveSameTypeAs, &DocumentNotFoundError{})
+		So(repository.Delete(ctx, doc.ID), ShouldHaveSameTypeAs, &DocumentNotFoundError{})
+	})
+}
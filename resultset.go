@@ -6,9 +6,13 @@ package bongo
 
 import (
 	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"math"
+	"reflect"
+	"strconv"
 )
 
 type ResultSet struct {
@@ -18,6 +22,9 @@ type ResultSet struct {
 	Collection *Collection
 	Error      error
 	Params     interface{}
+
+	// Find options with a view to project, which needs the type of the documents passed to Next
+	view *findOptions
 }
 
 type PaginationInfo struct {
@@ -26,19 +33,59 @@ type PaginationInfo struct {
 	PerPage       int   `json:"perPage"`
 	TotalRecords  int64 `json:"totalRecords"`
 	RecordsOnPage int   `json:"recordsOnPage"`
+
+	HasNext bool `json:"hasNext"`
+	HasPrev bool `json:"hasPrev"`
+
+	// 1-based positions of the first and last records on the page within all records, or 0 if the page is empty
+	FirstRecord int64 `json:"firstRecord"`
+	LastRecord  int64 `json:"lastRecord"`
+
+	// Opaque tokens for fetching the next and previous pages with cursor based pagination. Empty for offset
+	// based pagination and on the first/last page
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
 }
 
 func (r *ResultSet) Next(doc interface{}) bool {
+	return r.next(r.Collection.baseContext(), doc)
+}
+
+func (r *ResultSet) next(ctx context.Context, doc interface{}) bool {
+	if err := ctx.Err(); err != nil {
+		r.Error = err
+		return false
+	}
 
 	// Check if the iter has been instantiated yet
 	if !r.loadedIter {
 		r.loadedIter = true
+		if r.view != nil && len(r.view.view) > 0 {
+			if projection := r.view.viewProjection(reflect.TypeOf(doc)); projection != nil {
+				r.Query.SetProjection(projection)
+			}
+		}
+		if err := r.open(); err != nil {
+			r.Error = err
+			return false
+		}
 	}
 
-	gotResult := r.Cursor.Next(context.Background())
+	ctx, cancel := r.Collection.withTimeout(ctx)
+	defer cancel()
+	gotResult := r.Cursor.Next(ctx)
 
 	if gotResult {
 
+		if err := r.Cursor.Decode(doc); err != nil {
+			r.Error = newDecodeError(r.Collection, r.Cursor.Current, doc, err)
+			return false
+		}
+		if err := r.Collection.completeFound(ctx, doc); err != nil {
+			r.Error = err
+			return false
+		}
+
 		if hook, ok := doc.(AfterFindHook); ok {
 			err := hook.AfterFind(r.Collection)
 			if err != nil {
@@ -46,10 +93,12 @@ func (r *ResultSet) Next(doc interface{}) bool {
 				return false
 			}
 		}
+		r.Collection.stripUnreadable(doc)
 
 		if newt, ok := doc.(NewTracker); ok {
 			newt.SetIsNew(false)
 		}
+		markProjected(doc, r.Query != nil && r.Query.Projection != nil)
 		return true
 	}
 
@@ -61,9 +110,28 @@ func (r *ResultSet) Next(doc interface{}) bool {
 	return false
 }
 
+// Runs the query with the options that have been set on it
+func (r *ResultSet) open() error {
+	c := r.Collection
+	filter := queryFilter(r.Params)
+
+	c.lintOnce(filter, r.Query.Sort)
+
+	return c.runOperation("find", func(ctx context.Context) error {
+		guarded, err := c.guardFilter(ctx, filter)
+		if err != nil {
+			return err
+		}
+		commentOn(ctx, c, r.Query.SetComment)
+		cursor, err := c.Collection().Find(ctx, guarded, r.Query)
+		r.Cursor = cursor
+		return err
+	})
+}
+
 func (r *ResultSet) Free() error {
-	if r.loadedIter {
-		if err := r.Cursor.Close(context.Background()); err != nil {
+	if r.loadedIter && r.Cursor != nil {
+		if err := r.Collection.closeCursor(r.Cursor); err != nil {
 			return err
 		}
 	}
@@ -71,30 +139,153 @@ func (r *ResultSet) Free() error {
 	return nil
 }
 
-// Set skip + limit on the current query and generates a PaginationInfo struct with info for your front end
+// Counts all documents matching the query of the result set, regardless of skip and limit
+func (r *ResultSet) Count() (int64, error) {
+	var count int64
+	err := r.Collection.runOperation("count", func(ctx context.Context) error {
+		filter, err := r.Collection.guardFilter(ctx, r.Params)
+		if err != nil {
+			return err
+		}
+		opts := options.Count()
+		commentOn(ctx, r.Collection, opts.SetComment)
+		count, err = r.Collection.Collection().CountDocuments(ctx, filter, opts)
+		return err
+	})
+	return count, err
+}
+
+// How Paginate counts the records
+const (
+	// Count the matching documents exactly. Slow on very large collections
+	COUNT_EXACT = iota
+
+	// Use the collection's estimated document count from its metadata. Ignores the query, so only use it for
+	// unfiltered result sets
+	COUNT_ESTIMATED
+
+	// Don't count at all, only check whether there is a next page. TotalRecords and TotalPages are -1, and pages
+	// beyond the last one are not clamped
+	COUNT_NONE
+)
+
+// Set skip + limit on the current query and generates a PaginationInfo struct with info for your front end. A
+// perPage below 1 uses the collection's CollectionDefaults.PerPage
 func (r *ResultSet) Paginate(perPage, page int) (*PaginationInfo, error) {
-	info := new(PaginationInfo)
-	// Get count on a different session to avoid blocking
-	sess := r.Collection.Connection.Session
-	count, err := sess.Database(r.Collection.Database).Collection(r.Collection.Name).CountDocuments(context.Background(), nil)
+	return r.PaginateWith(perPage, page, COUNT_EXACT)
+}
 
-	if err != nil {
-		return info, err
+// Same as Paginate, with one of the COUNT_* modes to avoid exact counts on huge collections
+func (r *ResultSet) PaginateWith(perPage, page int, mode int) (*PaginationInfo, error) {
+	var info *PaginationInfo
+
+	perPage = r.Collection.perPage(perPage)
+	if perPage < 1 {
+		return new(PaginationInfo), errors.New("perPage must be positive")
+	}
+
+	switch mode {
+	case COUNT_EXACT, COUNT_ESTIMATED:
+		var count int64
+		var err error
+		if mode == COUNT_EXACT {
+			count, err = r.Count()
+		} else {
+			count, err = r.estimatedCount()
+		}
+		if err != nil {
+			return new(PaginationInfo), err
+		}
+		info = newPaginationInfo(count, perPage, page)
+	case COUNT_NONE:
+		if page < 1 {
+			page = 1
+		}
+		// Counting at most one record past the page is as cheap as fetching the page
+		skip := int64((page - 1) * perPage)
+		count, err := r.countRange(skip, int64(perPage)+1)
+		if err != nil {
+			return new(PaginationInfo), err
+		}
+		info = newUncountedPaginationInfo(count, perPage, page)
+	default:
+		return new(PaginationInfo), errors.New("unknown count mode " + strconv.Itoa(mode))
+	}
+
+	r.Query.SetSkip(int64((info.Current - 1) * perPage)).SetLimit(int64(perPage))
+
+	return info, nil
+}
+
+func (r *ResultSet) estimatedCount() (int64, error) {
+	// The estimate is of the whole collection, which the query guard may not allow
+	if r.Collection.queryGuard() != nil {
+		return r.Count()
+	}
+
+	var count int64
+	err := r.Collection.runOperation("estimatedCount", func(ctx context.Context) error {
+		var err error
+		count, err = r.Collection.Collection().EstimatedDocumentCount(ctx)
+		return err
+	})
+	return count, err
+}
+
+// Counts the matching documents within a skip/limit window
+func (r *ResultSet) countRange(skip int64, limit int64) (int64, error) {
+	var count int64
+	err := r.Collection.runOperation("count", func(ctx context.Context) error {
+		filter, err := r.Collection.guardFilter(ctx, r.Params)
+		if err != nil {
+			return err
+		}
+		opts := options.Count().SetSkip(skip).SetLimit(limit)
+		commentOn(ctx, r.Collection, opts.SetComment)
+		count, err = r.Collection.Collection().CountDocuments(ctx, filter, opts)
+		return err
+	})
+	return count, err
+}
+
+// Pagination info for a page whose records were counted up to one past the page
+func newUncountedPaginationInfo(count int64, perPage int, page int) *PaginationInfo {
+	info := &PaginationInfo{
+		Current:      page,
+		TotalPages:   -1,
+		PerPage:      perPage,
+		TotalRecords: -1,
+		HasNext:      count > int64(perPage),
+		HasPrev:      page > 1,
+	}
+
+	info.RecordsOnPage = int(count)
+	if info.HasNext {
+		info.RecordsOnPage = perPage
 	}
 
+	if info.RecordsOnPage > 0 {
+		info.FirstRecord = int64((page-1)*perPage) + 1
+		info.LastRecord = info.FirstRecord + int64(info.RecordsOnPage) - 1
+	}
+
+	return info
+}
+
+// Calculates the pagination info for a page of all records. Pages out of range are clamped to the first/last page
+func newPaginationInfo(count int64, perPage int, page int) *PaginationInfo {
+	info := new(PaginationInfo)
+
 	// Calculate how many pages
 	totalPages := int(math.Ceil(float64(count) / float64(perPage)))
 
+	if page > totalPages {
+		page = totalPages
+	}
 	if page < 1 {
 		page = 1
-	} else if page > totalPages {
-		page = totalPages
 	}
 
-	skip := (page - 1) * perPage
-
-	r.Query.SetSkip(int64(skip)).SetLimit(int64(perPage))
-
 	info.TotalPages = totalPages
 	info.PerPage = perPage
 	info.Current = page
@@ -112,5 +303,21 @@ func (r *ResultSet) Paginate(perPage, page int) (*PaginationInfo, error) {
 
 	}
 
-	return info, nil
+	info.HasNext = info.Current < info.TotalPages
+	info.HasPrev = info.Current > 1
+
+	if info.RecordsOnPage > 0 {
+		info.FirstRecord = int64((info.Current-1)*perPage) + 1
+		info.LastRecord = info.FirstRecord + int64(info.RecordsOnPage) - 1
+	}
+
+	return info
+}
+
+// The driver doesn't accept nil filters, so match everything instead
+func queryFilter(query interface{}) interface{} {
+	if query == nil {
+		return bson.M{}
+	}
+	return query
 }
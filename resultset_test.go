@@ -6,6 +6,7 @@ package bongo
 
 import (
 	"context"
+	"encoding/json"
 	. "github.com/smartystreets/goconvey/convey"
 	"go.mongodb.org/mongo-driver/bson"
 	"testing"
@@ -36,6 +37,39 @@ func TestResultSet(t *testing.T) {
 			So(count, ShouldEqual, 10)
 		})
 
+		Convey("should paginate without exact counts", func() {
+			rset, _ := collection.Find(nil)
+			defer rset.Free()
+			info, err := rset.PaginateWith(3, 2, COUNT_ESTIMATED)
+			So(err, ShouldEqual, nil)
+			So(info.TotalRecords, ShouldEqual, 10)
+			So(info.RecordsOnPage, ShouldEqual, 3)
+
+			rset2, _ := collection.Find(nil)
+			defer rset2.Free()
+			info, err = rset2.PaginateWith(3, 4, COUNT_NONE)
+			So(err, ShouldEqual, nil)
+			So(info.HasNext, ShouldBeFalse)
+			So(info.RecordsOnPage, ShouldEqual, 1)
+
+			count := 0
+			doc := &noHookDocument{}
+			for rset2.Next(doc) {
+				count++
+			}
+			So(count, ShouldEqual, 1)
+		})
+
+		Convey("should count all results regardless of pagination", func() {
+			rset, _ := collection.Find(nil)
+			defer rset.Free()
+			rset.Paginate(3, 2)
+
+			count, err := rset.Count()
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 10)
+		})
+
 		Convey("should let you paginate and get pagination info", func() {
 			rset, _ := collection.Find(nil)
 			defer rset.Free()
@@ -151,3 +185,64 @@ func TestResultSet(t *testing.T) {
 		})
 	})
 }
+
+func TestPaginationInfo(t *testing.T) {
+	Convey("Pagination info", t, func() {
+		Convey("should describe a page in the middle", func() {
+			info := newPaginationInfo(10, 3, 2)
+			So(info.Current, ShouldEqual, 2)
+			So(info.TotalPages, ShouldEqual, 4)
+			So(info.RecordsOnPage, ShouldEqual, 3)
+			So(info.HasNext, ShouldBeTrue)
+			So(info.HasPrev, ShouldBeTrue)
+			So(info.FirstRecord, ShouldEqual, 4)
+			So(info.LastRecord, ShouldEqual, 6)
+		})
+
+		Convey("should describe the last page", func() {
+			info := newPaginationInfo(10, 3, 9)
+			So(info.Current, ShouldEqual, 4)
+			So(info.RecordsOnPage, ShouldEqual, 1)
+			So(info.HasNext, ShouldBeFalse)
+			So(info.FirstRecord, ShouldEqual, 10)
+			So(info.LastRecord, ShouldEqual, 10)
+		})
+
+		Convey("should describe an empty result", func() {
+			info := newPaginationInfo(0, 3, 2)
+			So(info.Current, ShouldEqual, 1)
+			So(info.HasNext, ShouldBeFalse)
+			So(info.HasPrev, ShouldBeFalse)
+			So(info.FirstRecord, ShouldEqual, 0)
+			So(info.LastRecord, ShouldEqual, 0)
+		})
+
+		Convey("should have stable JSON field names", func() {
+			data, _ := json.Marshal(newPaginationInfo(10, 3, 1))
+			So(string(data), ShouldEqual, `{"current":1,"totalPages":4,"perPage":3,"totalRecords":10,"recordsOnPage":3,`+
+				`"hasNext":true,"hasPrev":false,"firstRecord":1,"lastRecord":3}`)
+		})
+	})
+}
+
+func TestUncountedPaginationInfo(t *testing.T) {
+	Convey("Uncounted pagination info", t, func() {
+		Convey("should report a next page if there is one more record", func() {
+			info := newUncountedPaginationInfo(4, 3, 2)
+			So(info.HasNext, ShouldBeTrue)
+			So(info.HasPrev, ShouldBeTrue)
+			So(info.RecordsOnPage, ShouldEqual, 3)
+			So(info.FirstRecord, ShouldEqual, 4)
+			So(info.LastRecord, ShouldEqual, 6)
+			So(info.TotalRecords, ShouldEqual, -1)
+			So(info.TotalPages, ShouldEqual, -1)
+		})
+
+		Convey("should report the last page", func() {
+			info := newUncountedPaginationInfo(2, 3, 4)
+			So(info.HasNext, ShouldBeFalse)
+			So(info.RecordsOnPage, ShouldEqual, 2)
+			So(info.LastRecord, ShouldEqual, 11)
+		})
+	})
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ResyncOptions struct {
+	// How many source documents are read per query. Defaults to 500
+	BatchSize int
+
+	// Resume after the document with this _id, e.g. the LastID of an interrupted run
+	After interface{}
+
+	// Called after each batch
+	Progress func(*ResyncProgress)
+}
+
+// How far ResyncCascades has got
+type ResyncProgress struct {
+	// Source documents resynced so far, and the number of batches
+	Resynced int64
+	Batches  int
+
+	// The _id of the last resynced document
+	LastID interface{}
+}
+
+const defaultResyncBatchSize = 500
+
+// Re-reads the documents of the collection matching the filter and applies their cascade configs again, to repair
+// denormalized data after bugs, skipped cascades or manual edits. Documents are read in batches ordered by _id, and
+// the returned progress can be passed back through ResyncOptions.After to resume an interrupted run:
+//
+//	progress, err := bongo.ResyncCascades(ctx, conn.Collection("children"), nil, nil)
+//
+// The collection needs a registered model. Old queries are ignored (the current relations are rewritten), and
+// nested configs aren't followed; resync their collections separately
+func ResyncCascades(ctx context.Context, collection *Collection, filter interface{}, opts *ResyncOptions) (*ResyncProgress, error) {
+	if opts == nil {
+		opts = &ResyncOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultResyncBatchSize
+	}
+
+	model := GetModel(collection.Name)
+	if model == nil {
+		return nil, errors.New("no model registered for collection " + collection.Name)
+	}
+
+	progress := &ResyncProgress{LastID: opts.After}
+	err := readBatches(ctx, collection, model, filter, opts.After, batchSize, func(docs []Document) error {
+		toCascade := make([]*CascadeConfig, 0)
+		for _, doc := range docs {
+			for _, conf := range cascadeConfigs(collection, doc) {
+				conf.OldQuery = nil
+				toCascade = append(toCascade, conf)
+			}
+		}
+		if err := runCascadeWrites(toCascade, cascadeSaveModels); err != nil {
+			return err
+		}
+
+		progress.Resynced += int64(len(docs))
+		progress.LastID = docs[len(docs)-1].GetID()
+
+		progress.Batches++
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+		return nil
+	})
+	return progress, err
+}
+
+// Reads the documents of the collection matching the filter into instances of the model, in batches ordered by
+// _id starting after the id (if any), and calls fn with each batch
+func readBatches(ctx context.Context, collection *Collection, model *Model, filter interface{}, after interface{}, batchSize int, fn func(docs []Document) error) error {
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(batchSize))
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		docs := make([]Document, 0, batchSize)
+		err := collection.runOperationContext(ctx, "readBatches", func(ctx context.Context) error {
+			docs = docs[:0]
+			guarded, err := collection.guardFilter(ctx, afterFilter(filter, after))
+			if err != nil {
+				return err
+			}
+			cursor, err := collection.Collection().Find(ctx, guarded, findOpts)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+
+			for cursor.Next(ctx) {
+				doc := model.New()
+				if err := cursor.Decode(doc); err != nil {
+					return newDecodeError(collection, cursor.Current, doc, err)
+				}
+				docs = append(docs, doc)
+			}
+			return cursor.Err()
+		})
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		if err := fn(docs); err != nil {
+			return err
+		}
+		after = docs[len(docs)-1].GetID()
+	}
+}
+
+// Matches the documents of the filter after the _id, if any
+func afterFilter(filter interface{}, after interface{}) interface{} {
+	if after == nil {
+		return queryFilter(filter)
+	}
+	return bson.M{"$and": bson.A{queryFilter(filter), bson.M{"_id": bson.M{"$gt": after}}}}
+}
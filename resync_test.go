@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestResyncOptions(t *testing.T) {
+	Convey("Cascade resync", t, func() {
+		Convey("should continue after the last resynced document", func() {
+			So(afterFilter(nil, nil), ShouldResemble, bson.M{})
+			So(afterFilter(bson.M{"active": true}, 5), ShouldResemble, bson.M{"$and": bson.A{
+				bson.M{"active": true},
+				bson.M{"_id": bson.M{"$gt": 5}},
+			}})
+		})
+
+		Convey("should need a registered model", func() {
+			_, err := ResyncCascades(context.Background(), &Collection{Name: "unregistered"}, nil, nil)
+			So(err.Error(), ShouldEqual, "no model registered for collection unregistered")
+		})
+	})
+}
+
+func TestResyncCascades(t *testing.T) {
+	conn := getConnection()
+
+	Convey("ResyncCascades", t, func() {
+		registerRelModels()
+		authors, books := conn.Collection("rel_authors"), conn.Collection("rel_books")
+
+		author := &relAuthor{Name: "Frank"}
+		So(authors.Save(author), ShouldEqual, nil)
+		for _, title := range []string{"Dune", "Messiah", "Children"} {
+			So(books.Save(&relBook{Title: title, AuthorID: author.ID}, SkipCascade()), ShouldEqual, nil)
+		}
+
+		Convey("should re-apply the cascades in batches", func() {
+			calls := 0
+			progress, err := ResyncCascades(context.Background(), books, nil, &ResyncOptions{
+				BatchSize: 2,
+				Progress: func(p *ResyncProgress) {
+					calls++
+				},
+			})
+			So(err, ShouldEqual, nil)
+			So(progress.Resynced, ShouldEqual, 3)
+			So(progress.Batches, ShouldEqual, 2)
+			So(calls, ShouldEqual, 2)
+
+			stored := bson.M{}
+			So(authors.Collection().FindOne(context.Background(), bson.M{"_id": author.ID}).Decode(&stored), ShouldEqual, nil)
+			So(len(stored["books"].(bson.A)), ShouldEqual, 3)
+
+			Convey("and not duplicate the cascaded copies when run again", func() {
+				_, err := ResyncCascades(context.Background(), books, nil, nil)
+				So(err, ShouldEqual, nil)
+
+				stored := bson.M{}
+				So(authors.Collection().FindOne(context.Background(), bson.M{"_id": author.ID}).Decode(&stored), ShouldEqual, nil)
+				So(len(stored["books"].(bson.A)), ShouldEqual, 3)
+			})
+		})
+
+		Convey("should resume after a document", func() {
+			progress, err := ResyncCascades(context.Background(), books, nil, nil)
+			So(err, ShouldEqual, nil)
+
+			progress, err = ResyncCascades(context.Background(), books, nil, &ResyncOptions{After: progress.LastID})
+			So(err, ShouldEqual, nil)
+			So(progress.Resynced, ShouldEqual, 0)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"time"
+)
+
+// Deletes the documents of a collection once they are older than Age, e.g. soft-deleted documents 30 days after
+// they were deleted:
+//
+//	&bongo.RetentionRule{Name: "purge deleted", AgeField: "deleted_at", Age: 30 * 24 * time.Hour}
+type RetentionRule struct {
+	// Shows up in the reports of RunRetention
+	Name string
+
+	// Documents the rule applies to once they are old enough. Nil applies it to every document
+	Filter interface{}
+
+	// How old documents may get
+	Age time.Duration
+
+	// The time field the age is measured by. Defaults to "created_at". Documents without it are kept
+	AgeField string
+
+	// Documents deleted per batch. Defaults to 1000
+	BatchSize int
+
+	// Throttles the batches, so purges don't crowd out the application. Nil runs them back to back
+	RateLimit *RateLimitConfig
+}
+
+func (r *RetentionRule) withDefaults() *RetentionRule {
+	rule := *r
+	if len(rule.AgeField) == 0 {
+		rule.AgeField = "created_at"
+	}
+	if rule.BatchSize <= 0 {
+		rule.BatchSize = 1000
+	}
+	return &rule
+}
+
+// What a retention rule did
+type RetentionReport struct {
+	Collection string
+	Rule       string
+	Deleted    int64
+	Batches    int
+	Duration   time.Duration
+}
+
+// Runs the RetentionRules of the config, collection by collection, and reports the documents each rule deleted.
+// Documents are deleted directly, without hooks or cascades. Stops at the first error, returning the reports so far
+// including the one of the failed rule
+func (m *Connection) RunRetention(ctx context.Context) ([]*RetentionReport, error) {
+	names := make([]string, 0, len(m.Config.RetentionRules))
+	for name := range m.Config.RetentionRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reports := []*RetentionReport{}
+	for _, name := range names {
+		collection := m.Collection(name)
+		for _, rule := range m.Config.RetentionRules[name] {
+			report, err := collection.applyRetention(ctx, rule)
+			reports = append(reports, report)
+			if err != nil {
+				return reports, err
+			}
+		}
+	}
+	return reports, nil
+}
+
+func (c *Collection) applyRetention(ctx context.Context, rule *RetentionRule) (*RetentionReport, error) {
+	rule = rule.withDefaults()
+	report := &RetentionReport{Collection: c.Name, Rule: rule.Name}
+	start := time.Now()
+	defer func() {
+		report.Duration = time.Since(start)
+	}()
+
+	var limiter *RateLimiter
+	if rule.RateLimit != nil {
+		limiter = NewRateLimiter(c.Database+"."+c.Name+" retention", rule.RateLimit)
+	}
+	filter := AndFilter(rule.Filter, bson.M{rule.AgeField: bson.M{"$lt": time.Now().Add(-rule.Age)}})
+
+	for {
+		if limiter != nil {
+			release, err := limiter.Acquire(ctx)
+			if err != nil {
+				return report, err
+			}
+			release()
+		}
+
+		var ids bson.A
+		err := c.runOperationContext(ctx, "retention", func(ctx context.Context) error {
+			ids = nil
+			guarded, err := c.guardFilter(ctx, filter)
+			if err != nil {
+				return err
+			}
+			opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(int64(rule.BatchSize))
+			cursor, err := c.Collection().Find(ctx, guarded, opts)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+			for cursor.Next(ctx) {
+				ids = append(ids, cursor.Current.Lookup("_id"))
+			}
+			return cursor.Err()
+		})
+		if err != nil || len(ids) == 0 {
+			return report, err
+		}
+
+		err = c.runOperationContext(ctx, "retention", func(ctx context.Context) error {
+			// Documents that changed since they were picked may not match any more
+			batch, err := c.guardFilter(ctx, AndFilter(filter, bson.M{"_id": bson.M{"$in": ids}}))
+			if err != nil {
+				return err
+			}
+			res, err := c.Collection().DeleteMany(ctx, batch)
+			if err != nil {
+				return err
+			}
+			report.Deleted += res.DeletedCount
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+		report.Batches++
+		if len(ids) < rule.BatchSize {
+			return report, nil
+		}
+	}
+}
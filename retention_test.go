@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+func TestRetentionRule(t *testing.T) {
+	Convey("Retention rules", t, func() {
+		Convey("should apply the defaults", func() {
+			rule := (&RetentionRule{Age: time.Hour}).withDefaults()
+			So(rule.AgeField, ShouldEqual, "created_at")
+			So(rule.BatchSize, ShouldEqual, 1000)
+		})
+
+		Convey("should report nothing without rules", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest"}}
+			reports, err := conn.RunRetention(context.Background())
+			So(err, ShouldBeNil)
+			So(reports, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestRunRetention(t *testing.T) {
+	conn := getConnection()
+
+	Convey("RunRetention", t, func() {
+		ctx := context.Background()
+		tests := conn.Collection("tests")
+		for _, name := range []string{"deleted", "deleted", "deleted", "alive"} {
+			So(tests.Save(&noHookDocument{Name: name}), ShouldBeNil)
+		}
+		_, err := tests.Collection().UpdateMany(ctx, bson.M{"name": "deleted"}, bson.M{"$set": bson.M{"deleted_at": time.Now().Add(-48 * time.Hour)}})
+		So(err, ShouldBeNil)
+
+		Convey("should delete old documents in batches", func() {
+			conn.Config.RetentionRules = map[string][]*RetentionRule{
+				"tests": {{Name: "purge deleted", AgeField: "deleted_at", Age: 24 * time.Hour, BatchSize: 2}},
+			}
+
+			reports, err := conn.RunRetention(ctx)
+			So(err, ShouldBeNil)
+			So(len(reports), ShouldEqual, 1)
+			So(reports[0].Rule, ShouldEqual, "purge deleted")
+			So(reports[0].Deleted, ShouldEqual, 3)
+			So(reports[0].Batches, ShouldEqual, 2)
+
+			count, err := tests.Collection().CountDocuments(ctx, bson.M{})
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+		})
+
+		Reset(func() {
+			conn.Config.RetentionRules = nil
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// Server error codes that indicate a replica set election or a node that is (temporarily) unable to serve
+var transientErrorCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	262,   // ExceededTimeLimit
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// Configuration for automatically retrying operations that failed with a transient error
+type RetryPolicy struct {
+	// Total number of attempts, including the first one. Values below 1 are treated as 1
+	MaxAttempts int
+
+	// Delay before the first retry. It is doubled on each subsequent retry
+	Backoff time.Duration
+
+	// Upper bound for the delay between two attempts. Zero means no upper bound
+	MaxBackoff time.Duration
+
+	// Decides whether an error should be retried. Defaults to IsTransientError
+	Retryable func(error) bool
+}
+
+// Returns a retry policy suitable for riding out replica set elections
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     100 * time.Millisecond,
+		MaxBackoff:  2 * time.Second,
+	}
+}
+
+// Reports whether an error is a network error or a "not primary"-type server error that is likely to succeed
+// when retried
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+
+	var se mongo.ServerError
+	if errors.As(err, &se) {
+		if se.HasErrorLabel("RetryableWriteError") || se.HasErrorLabel("TransientTransactionError") {
+			return true
+		}
+		for _, code := range transientErrorCodes {
+			if se.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsTransientError(err)
+}
+
+// Returns how long to wait before the given (1-based) retry
+func (p *RetryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// Runs fn until it succeeds, returns a non-retryable error, the attempts are exhausted or the context is done
+func (p *RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn(ctx)
+		if err == nil || attempt == attempts || !p.retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
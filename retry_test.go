@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	Convey("RetryPolicy", t, func() {
+		notPrimary := mongo.CommandError{Code: 10107, Message: "not primary"}
+
+		Convey("should detect transient errors", func() {
+			So(IsTransientError(nil), ShouldBeFalse)
+			So(IsTransientError(errors.New("foo")), ShouldBeFalse)
+			So(IsTransientError(notPrimary), ShouldBeTrue)
+			So(IsTransientError(mongo.CommandError{Code: 11000}), ShouldBeFalse)
+			So(IsTransientError(mongo.CommandError{Labels: []string{"RetryableWriteError"}}), ShouldBeTrue)
+		})
+
+		Convey("should retry transient errors until it succeeds", func() {
+			policy := &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+			calls := 0
+			err := policy.Do(context.Background(), func(ctx context.Context) error {
+				calls++
+				if calls < 3 {
+					return notPrimary
+				}
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(calls, ShouldEqual, 3)
+		})
+
+		Convey("should give up after max attempts", func() {
+			policy := &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}
+			calls := 0
+			err := policy.Do(context.Background(), func(ctx context.Context) error {
+				calls++
+				return notPrimary
+			})
+			So(err, ShouldResemble, notPrimary)
+			So(calls, ShouldEqual, 2)
+		})
+
+		Convey("should not retry other errors", func() {
+			policy := &RetryPolicy{MaxAttempts: 5, Backoff: time.Millisecond}
+			calls := 0
+			err := policy.Do(context.Background(), func(ctx context.Context) error {
+				calls++
+				return errors.New("foo")
+			})
+			So(err.Error(), ShouldEqual, "foo")
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("should use a custom retryable func", func() {
+			policy := &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, Retryable: func(err error) bool {
+				return true
+			}}
+			calls := 0
+			policy.Do(context.Background(), func(ctx context.Context) error {
+				calls++
+				return errors.New("foo")
+			})
+			So(calls, ShouldEqual, 2)
+		})
+
+		Convey("should not retry writes that must run once", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest", RetryPolicy: &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}}, Context: &Context{}}
+			col := conn.Collection("counters")
+			calls := 0
+			fail := func(ctx context.Context) error {
+				calls++
+				return notPrimary
+			}
+
+			So(col.runOperationContext(context.Background(), "incr", fail), ShouldResemble, notPrimary)
+			So(calls, ShouldEqual, 3)
+
+			calls = 0
+			So(col.runOperationOnce(context.Background(), "incr", fail), ShouldResemble, notPrimary)
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("should cap the backoff", func() {
+			policy := &RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+			So(policy.delay(1), ShouldEqual, 100*time.Millisecond)
+			So(policy.delay(2), ShouldEqual, 200*time.Millisecond)
+			So(policy.delay(3), ShouldEqual, 300*time.Millisecond)
+			So(policy.delay(10), ShouldEqual, 300*time.Millisecond)
+		})
+
+		Convey("should run once with a nil policy", func() {
+			var policy *RetryPolicy
+			calls := 0
+			policy.Do(context.Background(), func(ctx context.Context) error {
+				calls++
+				return notPrimary
+			})
+			So(calls, ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strings"
+)
+
+// A document of a graph, prepared for saving
+type graphWrite struct {
+	collection *Collection
+	doc        Document
+}
+
+// Saves a document together with the documents in its relation fields (see Model.BelongsTo, HasOne and HasMany),
+// recursively, in one transaction. Documents a document belongs to are saved before it and its local key is set to
+// their id; the documents it has are saved after it, with their foreign key set to its id:
+//
+//	order := &Order{Customer: &Customer{Name: "Ann"}, Items: []*Item{{Sku: "a"}, {Sku: "b"}}}
+//	err := conn.Collection("orders").SaveGraph(ctx, order)
+//
+// Every document reached through relation fields is saved, each once. Hooks run like for Save, the after save hooks
+// and cascades once the transaction is committed. Transactions need a replica set. References between the
+// documents of the graph aren't checked against the database, even in collections that enforce references, since
+// they only exist once the transaction commits
+func (c *Collection) SaveGraph(ctx context.Context, doc Document, opts ...WriteOption) error {
+	o := newWriteOptions(opts).withContext(ctx)
+	graph := &graphWriter{options: o, visited: make(map[uintptr]bool)}
+	if err := graph.add(c, doc); err != nil {
+		return err
+	}
+
+	err := c.Connection.inTransaction(ctx, func(ctx context.Context) error {
+		for _, write := range graph.writes {
+			filter, err := write.collection.guardFilter(ctx, documentFilter(write.doc.GetID(), write.doc))
+			if err != nil {
+				return err
+			}
+			opts := options.Replace().SetUpsert(true)
+			commentOn(ctx, write.collection, opts.SetComment)
+			_, err = write.collection.Collection().ReplaceOne(ctx, filter, write.doc, opts)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, write := range graph.writes {
+		if !o.skipCascade {
+			toCascade := savedCascadeConfigs(write.collection, []Document{write.doc})
+			write.collection.Connection.goCascade(func() { runCascadeSave(toCascade) })
+		}
+		if err := write.collection.finishSave(write.doc, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Walks a document graph and collects the writes in order
+type graphWriter struct {
+	options *writeOptions
+	visited map[uintptr]bool
+	writes  []*graphWrite
+}
+
+// Adds a document and the documents of its relations
+func (g *graphWriter) add(c *Collection, doc Document) error {
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("SaveGraph needs a pointer to a document")
+	}
+	if g.visited[v.Pointer()] {
+		return nil
+	}
+	g.visited[v.Pointer()] = true
+
+	var relations []*Relation
+	if model := GetModel(c.Name); model != nil {
+		relations = model.relations
+	}
+
+	// The documents this one belongs to come first, so their ids can be referenced
+	for _, relation := range relations {
+		if relation.Kind != BELONGS_TO {
+			continue
+		}
+		related := relatedDocuments(v.Elem(), relation.Name)
+		if len(related) == 0 {
+			continue
+		}
+
+		if err := g.add(c.Connection.CollectionFromDatabase(relation.Collection, c.Database), related[0]); err != nil {
+			return err
+		}
+		if err := copyKey(related[0], relation.ForeignKey, doc, relation.LocalKey); err != nil {
+			return err
+		}
+	}
+
+	o := *g.options
+	o.skipReferences = true
+	if _, err := c.prepareSave(doc, &o); err != nil {
+		return err
+	}
+	g.writes = append(g.writes, &graphWrite{collection: c, doc: doc})
+
+	// The documents it has come after, referencing it
+	for _, relation := range relations {
+		if relation.Kind == BELONGS_TO {
+			continue
+		}
+		related := c.Connection.CollectionFromDatabase(relation.Collection, c.Database)
+		for _, child := range relatedDocuments(v.Elem(), relation.Name) {
+			if err := copyKey(doc, relation.LocalKey, child, relation.ForeignKey); err != nil {
+				return err
+			}
+			if err := g.add(related, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// The documents in a relation field
+func relatedDocuments(v reflect.Value, name string) []Document {
+	field := v.FieldByName(name)
+	if !field.IsValid() {
+		return nil
+	}
+
+	docs := make([]Document, 0)
+	add := func(value reflect.Value) {
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return
+			}
+		} else if value.CanAddr() && !value.IsZero() {
+			value = value.Addr()
+		} else {
+			return
+		}
+		if doc, ok := value.Interface().(Document); ok {
+			docs = append(docs, doc)
+		}
+	}
+
+	if field.Kind() == reflect.Slice {
+		for i := 0; i < field.Len(); i++ {
+			add(field.Index(i))
+		}
+	} else {
+		add(field)
+	}
+	return docs
+}
+
+// Copies the value stored under one document's key into the field of another document stored under a key
+func copyKey(from Document, fromKey string, to Document, toKey string) error {
+	var value bson.RawValue
+	if fromKey == "_id" {
+		t, data, err := bson.MarshalValue(from.GetID())
+		if err != nil {
+			return err
+		}
+		value = bson.RawValue{Type: t, Value: data}
+	} else {
+		raw, err := bson.MarshalWithRegistry(Registry, from)
+		if err != nil {
+			return err
+		}
+		if value, err = bson.Raw(raw).LookupErr(strings.Split(fromKey, ".")...); err != nil {
+			return nil
+		}
+	}
+
+	goPath := goPathOf(reflect.TypeOf(to), toKey)
+	field := fieldByGoPath(reflect.ValueOf(to).Elem(), goPath)
+	if len(goPath) == 0 || !field.IsValid() || !field.CanAddr() {
+		return errors.New("no field for " + toKey + " on " + reflect.TypeOf(to).Elem().Name())
+	}
+	return value.Unmarshal(field.Addr().Interface())
+}
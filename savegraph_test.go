@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestGraphWriter(t *testing.T) {
+	Convey("Document graphs", t, func() {
+		registerRelModels()
+		conn := &Connection{Config: &Config{Database: "bongotest"}}
+		graph := &graphWriter{options: &writeOptions{}, visited: make(map[uintptr]bool)}
+
+		order := func() []string {
+			names := make([]string, len(graph.writes))
+			for i, write := range graph.writes {
+				names[i] = write.collection.Name
+			}
+			return names
+		}
+
+		Convey("should save the documents a document belongs to first", func() {
+			book := &relBook{Title: "Dune", Author: &relAuthor{Name: "Frank"}}
+			So(graph.add(conn.Collection("rel_books"), book), ShouldEqual, nil)
+			So(order(), ShouldResemble, []string{"rel_authors", "rel_books"})
+			So(book.Author.ID.IsZero(), ShouldBeFalse)
+			So(book.AuthorID, ShouldEqual, book.Author.ID)
+		})
+
+		Convey("should save the documents a document has after it", func() {
+			author := &relAuthor{Name: "Frank", Books: []*relBook{{Title: "Dune"}, {Title: "Messiah"}}}
+			So(graph.add(conn.Collection("rel_authors"), author), ShouldEqual, nil)
+			So(order(), ShouldResemble, []string{"rel_authors", "rel_books", "rel_books"})
+			So(author.Books[0].AuthorID, ShouldEqual, author.ID)
+			So(author.Books[1].AuthorID, ShouldEqual, author.ID)
+		})
+
+		Convey("should save every document once", func() {
+			author := &relAuthor{Name: "Frank"}
+			book := &relBook{Title: "Dune", Author: author}
+			author.Books = []*relBook{book}
+			So(graph.add(conn.Collection("rel_books"), book), ShouldEqual, nil)
+			So(order(), ShouldResemble, []string{"rel_authors", "rel_books"})
+		})
+
+		Convey("should stop at invalid documents", func() {
+			So(graph.add(conn.Collection("rel_books"), (*relBook)(nil)), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestSaveGraph(t *testing.T) {
+	conn := getConnection()
+
+	Convey("SaveGraph", t, func() {
+		registerRelModels()
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+
+		author := &relAuthor{Name: "Frank", Books: []*relBook{{Title: "Dune"}, {Title: "Messiah"}}}
+		err := conn.Collection("rel_authors").SaveGraph(context.Background(), author)
+
+		// Transactions need a replica set
+		if err != nil {
+			So(err.Error(), ShouldContainSubstring, "replica set")
+			return
+		}
+
+		So(author.IsNew(), ShouldBeFalse)
+		loaded := &relAuthor{}
+		So(conn.Collection("rel_authors").FindByID(author.ID, loaded), ShouldEqual, nil)
+		So(conn.Collection("rel_authors").Populate(loaded, "Books"), ShouldEqual, nil)
+		So(len(loaded.Books), ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// Generates a $jsonSchema document describing the model's fields. Fields without omitempty are required. Fields of
+// types with their own BSON encoding (Decimal, Date, Optional, Enum, ...) are left out, since how they are stored
+// depends on their value
+func (m *Model) JSONSchema() bson.M {
+	return structSchema(m.Type)
+}
+
+func structSchema(t reflect.Type) bson.M {
+	properties := bson.M{}
+	required := make([]string, 0)
+
+	walkBsonFields(t, "", func(field reflect.StructField, path string) bool {
+		if encodesItself(indirectType(field.Type)) {
+			return false
+		}
+		schema := typeSchema(field.Type)
+		if schema != nil {
+			properties[path] = schema
+		}
+		if field.Type.Kind() != reflect.Ptr && !hasBsonOption(field, "omitempty") {
+			required = append(required, path)
+		}
+		// Nested structs get their own object schema
+		return false
+	})
+
+	schema := bson.M{
+		"bsonType":   "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// The schema of a single Go type, or nil if anything goes
+func typeSchema(t reflect.Type) bson.M {
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		nullable = true
+		t = t.Elem()
+	}
+	if encodesItself(t) {
+		return nil
+	}
+
+	var schema bson.M
+
+	switch {
+	case t == timeType:
+		schema = bson.M{"bsonType": "date"}
+	case t == objectIDType:
+		schema = bson.M{"bsonType": "objectId"}
+	case t.Kind() == reflect.String:
+		schema = bson.M{"bsonType": "string"}
+	case t.Kind() == reflect.Bool:
+		schema = bson.M{"bsonType": "bool"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = bson.M{"bsonType": bson.A{"int", "long"}}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = bson.M{"bsonType": bson.A{"double", "int", "long"}}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		schema = bson.M{"bsonType": "binData"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		// Nil slices are stored as null
+		nullable = t.Kind() == reflect.Slice
+		schema = bson.M{"bsonType": "array"}
+		if items := typeSchema(t.Elem()); items != nil {
+			schema["items"] = items
+		}
+	case t.Kind() == reflect.Map:
+		nullable = true
+		schema = bson.M{"bsonType": "object"}
+	case t.Kind() == reflect.Struct:
+		schema = structSchema(t)
+	default:
+		return nil
+	}
+
+	if nullable {
+		schema["bsonType"] = append(toArray(schema["bsonType"]), "null")
+	}
+
+	return schema
+}
+
+func toArray(value interface{}) bson.A {
+	if arr, ok := value.(bson.A); ok {
+		return arr
+	}
+	return bson.A{value}
+}
+
+// Counts the documents in the collection that don't match a $jsonSchema
+func (c *Collection) ValidateSchema(schema bson.M) (int64, error) {
+	var count int64
+	err := c.runOperation("validateSchema", func(ctx context.Context) error {
+		filter, err := c.guardFilter(ctx, bson.M{"$nor": bson.A{bson.M{"$jsonSchema": schema}}})
+		if err != nil {
+			return err
+		}
+		count, err = c.Collection().CountDocuments(ctx, filter)
+		return err
+	})
+	return count, err
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type schemaStatus string
+
+type customTypesDocument struct {
+	DocumentBase `bson:",inline"`
+	Price        Decimal               `bson:"price"`
+	Total        Money                 `bson:"total"`
+	Birthday     Date                  `bson:"birthday"`
+	Opens        TimeOfDay             `bson:"opens"`
+	Avatar       Binary                `bson:"avatar"`
+	Nickname     Optional[string]      `bson:"nickname"`
+	Meeting      ZonedTime             `bson:"meeting"`
+	Status       Enum[schemaStatus]    `bson:"status"`
+	Owner        Ref[noHookDocument]   `bson:"owner"`
+	Attachment   Stream                `bson:"attachment"`
+	Notes        Overflow              `bson:"notes"`
+	Password     Hashed                `bson:"password"`
+	Prices       []Decimal             `bson:"prices"`
+	Deadline     *Date                 `bson:"deadline"`
+	Tags         []string              `bson:"tags"`
+	Address      struct{ City string } `bson:"address"`
+}
+
+// The aliases of $jsonSchema's bsonType
+var schemaTypeAliases = map[bsontype.Type]string{
+	bsontype.Double:           "double",
+	bsontype.String:           "string",
+	bsontype.EmbeddedDocument: "object",
+	bsontype.Array:            "array",
+	bsontype.Binary:           "binData",
+	bsontype.ObjectID:         "objectId",
+	bsontype.Boolean:          "bool",
+	bsontype.DateTime:         "date",
+	bsontype.Null:             "null",
+	bsontype.Int32:            "int",
+	bsontype.Int64:            "long",
+	bsontype.Decimal128:       "decimal",
+}
+
+// The paths of a document that don't match a schema made by JSONSchema, i.e. missing required fields and values of
+// the wrong bsonType
+func schemaViolations(schema bson.M, value bson.RawValue, path string) []string {
+	violations := make([]string, 0)
+	if types, ok := schema["bsonType"]; ok && !stringInSlice(schemaTypeAliases[value.Type], schemaTypes(types)) {
+		return append(violations, path+" is "+schemaTypeAliases[value.Type])
+	}
+
+	switch value.Type {
+	case bsontype.EmbeddedDocument:
+		doc := value.Document()
+		if required, ok := schema["required"].([]string); ok {
+			for _, field := range required {
+				if _, err := doc.LookupErr(field); err != nil {
+					violations = append(violations, path+"."+field+" is missing")
+				}
+			}
+		}
+		properties, _ := schema["properties"].(bson.M)
+		for field, property := range properties {
+			if element, err := doc.LookupErr(field); err == nil {
+				violations = append(violations, schemaViolations(property.(bson.M), element, path+"."+field)...)
+			}
+		}
+	case bsontype.Array:
+		if items, ok := schema["items"].(bson.M); ok {
+			values, _ := value.Array().Values()
+			for _, item := range values {
+				violations = append(violations, schemaViolations(items, item, path+".items")...)
+			}
+		}
+	}
+	return violations
+}
+
+func schemaTypes(types interface{}) []string {
+	if list, ok := types.(bson.A); ok {
+		names := make([]string, len(list))
+		for i, name := range list {
+			names[i] = name.(string)
+		}
+		return names
+	}
+	return []string{types.(string)}
+}
+
+func TestJSONSchema(t *testing.T) {
+	Convey("JSON schemas", t, func() {
+		model := &Model{Collection: "custom", Type: reflect.TypeOf(customTypesDocument{})}
+		schema := model.JSONSchema()
+
+		check := func(doc *customTypesDocument) []string {
+			data, err := bson.MarshalWithRegistry(Registry, doc)
+			So(err, ShouldBeNil)
+			return schemaViolations(schema, bson.RawValue{Type: bsontype.EmbeddedDocument, Value: data}, "custom")
+		}
+
+		Convey("should match documents with zero values", func() {
+			So(check(&customTypesDocument{}), ShouldBeEmpty)
+		})
+
+		Convey("should match documents with values", func() {
+			total, err := NewMoney("9.99", "EUR")
+			So(err, ShouldBeNil)
+			deadline := NewDate(2030, time.January, 1)
+			doc := &customTypesDocument{
+				Price:    NewDecimal(999, -2),
+				Total:    total,
+				Birthday: NewDate(1990, time.May, 17),
+				Opens:    NewTimeOfDay(9, 30, 0),
+				Avatar:   Binary{Data: []byte{1, 2, 3}},
+				Nickname: Some("Foo"),
+				Meeting:  ZonedTime{time.Now()},
+				Status:   Enum[schemaStatus]{Value: "active"},
+				Owner:    RefTo[noHookDocument](primitive.NewObjectID()),
+				Notes:    Overflow{Data: []byte("notes")},
+				Password: Hashed("$2a$10$hash"),
+				Prices:   []Decimal{NewDecimal(1, 0)},
+				Deadline: &deadline,
+				Tags:     []string{"a"},
+			}
+			doc.ID = primitive.NewObjectID()
+			doc.CreatedAt = time.Now()
+			So(check(doc), ShouldBeEmpty)
+		})
+
+		Convey("should still describe the fields it knows", func() {
+			properties := schema["properties"].(bson.M)
+			So(properties["tags"], ShouldResemble, bson.M{"bsonType": bson.A{"array", "null"}, "items": bson.M{"bsonType": "string"}})
+			So(properties["total"].(bson.M)["properties"], ShouldResemble, bson.M{"currency": bson.M{"bsonType": "string"}})
+			So(properties, ShouldNotContainKey, "price")
+			So(schema["required"], ShouldNotContain, "price")
+			So(schema["required"], ShouldContain, "tags")
+		})
+	})
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type SearchOptions struct {
+	// The language to stem the search terms with, e.g. "german", or "none" to disable stemming. Defaults to the
+	// default language of the text index
+	Language string
+
+	CaseSensitive      bool
+	DiacriticSensitive bool
+
+	// Additional conditions the documents have to match
+	Filter bson.M
+
+	// Sort the results by relevance. The score is returned in the "score" field
+	SortByScore bool
+}
+
+// Runs a $text search on the collection's text index. Like Find, the query runs on the first call to
+// ResultSet.Next, so the result set can still be paginated
+func (c *Collection) Search(text string, opts *SearchOptions) (*ResultSet, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+
+	search := bson.M{"$search": text}
+	if len(opts.Language) > 0 {
+		search["$language"] = opts.Language
+	}
+	if opts.CaseSensitive {
+		search["$caseSensitive"] = true
+	}
+	if opts.DiacriticSensitive {
+		search["$diacriticSensitive"] = true
+	}
+
+	query := bson.M{}
+	for key, value := range opts.Filter {
+		query[key] = value
+	}
+	query["$text"] = search
+
+	results, err := c.Find(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.SortByScore {
+		score := bson.M{"score": bson.M{"$meta": "textScore"}}
+		results.Query.SetProjection(score)
+		results.Query.SetSort(score)
+	}
+
+	return results, nil
+}
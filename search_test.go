@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+type searchDocument struct {
+	DocumentBase `bson:",inline"`
+	Title        string  `bson:"title" index:"search,text,language=german"`
+	Body         string  `bson:"body" index:"search,text"`
+	Language     string  `bson:"lang,omitempty" index:"search,language_override"`
+	Score        float64 `bson:"score,omitempty"`
+}
+
+type badLanguageOverrideDocument struct {
+	DocumentBase `bson:",inline"`
+	Language     string `index:"search,language_override"`
+}
+
+func TestSearchIndexes(t *testing.T) {
+	Convey("Text index languages", t, func() {
+		Convey("should parse the language options", func() {
+			specs, err := RegisterModel("searchable", &searchDocument{}).Indexes()
+			So(err, ShouldEqual, nil)
+			So(len(specs), ShouldEqual, 1)
+			So(specs[0].Keys, ShouldResemble, bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}})
+			So(specs[0].DefaultLanguage, ShouldEqual, "german")
+			So(specs[0].LanguageOverride, ShouldEqual, "lang")
+
+			opts := specs[0].IndexModel().Options
+			So(*opts.DefaultLanguage, ShouldEqual, "german")
+			So(*opts.LanguageOverride, ShouldEqual, "lang")
+		})
+
+		Convey("should reject language overrides without an index", func() {
+			_, err := (&Model{Type: reflect.TypeOf(badLanguageOverrideDocument{})}).Indexes()
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should build the $text query", func() {
+			results, err := (&Collection{}).Search("Häuser", &SearchOptions{
+				Language:    "german",
+				Filter:      bson.M{"published": true},
+				SortByScore: true,
+			})
+			So(err, ShouldEqual, nil)
+			So(results.Params, ShouldResemble, bson.M{
+				"published": true,
+				"$text":     bson.M{"$search": "Häuser", "$language": "german"},
+			})
+			So(results.Query.Sort, ShouldResemble, bson.M{"score": bson.M{"$meta": "textScore"}})
+		})
+	})
+}
+
+func TestSearch(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Search", t, func() {
+		model := RegisterModel("searchable", &searchDocument{})
+		_, err := conn.SyncIndexes(model)
+		So(err, ShouldEqual, nil)
+
+		col := conn.ModelCollection(model)
+		col.Save(&searchDocument{Title: "Die Häuser", Body: "Viele Häuser am See"})
+		col.Save(&searchDocument{Title: "The houses", Body: "Houses by the lake", Language: "english"})
+
+		Convey("should stem per document language", func() {
+			results, err := col.Search("haus", &SearchOptions{Language: "german"})
+			So(err, ShouldEqual, nil)
+			doc := &searchDocument{}
+			So(results.Next(doc), ShouldBeTrue)
+			So(doc.Title, ShouldEqual, "Die Häuser")
+
+			results, _ = col.Search("house", &SearchOptions{Language: "english", SortByScore: true})
+			So(results.Next(doc), ShouldBeTrue)
+			So(doc.Title, ShouldEqual, "The houses")
+			So(doc.Score, ShouldBeGreaterThan, 0)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
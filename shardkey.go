@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"sync"
+)
+
+// Implemented by documents that declare their shard key in code instead of with `shard` struct tags. Returns the
+// fields of the key (in order) with the document's values, e.g.
+//
+//	func (o *Order) ShardKey() bson.D {
+//		return bson.D{{"tenant", o.Tenant}, {"region", o.Region}}
+//	}
+type ShardKeyDocument interface {
+	ShardKey() bson.D
+}
+
+// Returned by saves of documents whose shard key field is missing (a zero value), or changed since the document was
+// loaded
+type ShardKeyError struct {
+	Collection string
+	Field      string
+
+	// "missing" or "changed"
+	Reason string
+}
+
+func (e *ShardKeyError) Error() string {
+	return "shard key field " + e.Field + " of " + e.Collection + " is " + e.Reason
+}
+
+type shardKeyField struct {
+	goPath string
+	path   string
+}
+
+var shardKeyFieldsCache sync.Map
+
+// The fields of a type tagged with `shard`, cached per type
+func shardKeyFields(t reflect.Type) []shardKeyField {
+	if fields, ok := shardKeyFieldsCache.Load(t); ok {
+		return fields.([]shardKeyField)
+	}
+
+	fields := make([]shardKeyField, 0)
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		if _, ok := field.Tag.Lookup("shard"); ok {
+			fields = append(fields, shardKeyField{goPath: goPath, path: path})
+			return false
+		}
+		return true
+	})
+
+	shardKeyFieldsCache.Store(t, fields)
+	return fields
+}
+
+// The shard key of a document with its values, or nil if it doesn't declare one
+func shardKey(doc interface{}) bson.D {
+	if keyed, ok := doc.(ShardKeyDocument); ok {
+		return keyed.ShardKey()
+	}
+
+	v := reflect.ValueOf(doc)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := shardKeyFields(v.Type())
+	if len(fields) == 0 {
+		return nil
+	}
+	key := make(bson.D, len(fields))
+	for i, field := range fields {
+		key[i] = bson.E{Key: field.path}
+		if value := fieldByGoPath(v, field.goPath); value.IsValid() {
+			key[i].Value = value.Interface()
+		}
+	}
+	return key
+}
+
+// Makes sure a document about to be saved has all of its shard key, and that documents loaded with a DiffTracker
+// still have the shard key they were loaded with
+func (c *Collection) checkShardKey(doc Document, isNew bool) error {
+	key := shardKey(doc)
+	for _, e := range key {
+		if isZeroValue(e.Value) {
+			return &ShardKeyError{Collection: c.Name, Field: e.Key, Reason: "missing"}
+		}
+	}
+	if len(key) == 0 || isNew {
+		return nil
+	}
+
+	trackable, ok := doc.(Trackable)
+	if !ok || trackable.GetDiffTracker() == nil || trackable.GetDiffTracker().original == nil {
+		return nil
+	}
+
+	// The original is a struct value, the key is declared on the pointer
+	original := reflect.ValueOf(trackable.GetDiffTracker().original)
+	ptr := reflect.New(original.Type())
+	ptr.Elem().Set(original)
+	originalKey := shardKey(ptr.Interface())
+
+	for i, e := range key {
+		if i < len(originalKey) && !isZeroValue(originalKey[i].Value) && !reflect.DeepEqual(originalKey[i].Value, e.Value) {
+			return &ShardKeyError{Collection: c.Name, Field: e.Key, Reason: "changed"}
+		}
+	}
+	return nil
+}
+
+// The filter for a document by id, with the parts of its shard key that are set, so the query only goes to the
+// shard holding the document
+func documentFilter(id primitive.ObjectID, doc interface{}) bson.D {
+	filter := bson.D{{"_id", id}}
+	for _, e := range shardKey(doc) {
+		if !isZeroValue(e.Value) {
+			filter = append(filter, e)
+		}
+	}
+	return filter
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+type shardedDocument struct {
+	DocumentBase `bson:",inline"`
+	Tenant       string `bson:"tenant" shard:""`
+	Region       string `bson:"region" shard:""`
+	Name         string
+	diffTracker  *DiffTracker
+}
+
+func (s *shardedDocument) GetDiffTracker() *DiffTracker {
+	if s.diffTracker == nil {
+		s.diffTracker = NewDiffTracker(s)
+	}
+	return s.diffTracker
+}
+
+type declaredShardKeyDocument struct {
+	DocumentBase `bson:",inline"`
+	Tenant       primitive.ObjectID `bson:"tenant"`
+}
+
+func (d *declaredShardKeyDocument) ShardKey() bson.D {
+	return bson.D{{"tenant", d.Tenant}}
+}
+
+func TestShardKey(t *testing.T) {
+	Convey("Shard keys", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}}
+		col := conn.Collection("sharded")
+
+		Convey("should be read from the shard tags", func() {
+			doc := &shardedDocument{Tenant: "acme", Region: "eu"}
+			So(shardKey(doc), ShouldResemble, bson.D{{"tenant", "acme"}, {"region", "eu"}})
+			So(shardKey(&noHookDocument{}), ShouldBeNil)
+		})
+
+		Convey("should be read from ShardKeyDocument", func() {
+			tenant := primitive.NewObjectID()
+			So(shardKey(&declaredShardKeyDocument{Tenant: tenant}), ShouldResemble, bson.D{{"tenant", tenant}})
+		})
+
+		Convey("should be required when saving", func() {
+			_, err := col.prepareSave(&shardedDocument{Tenant: "acme"}, newWriteOptions(nil))
+			So(err, ShouldResemble, &ShardKeyError{Collection: "sharded", Field: "region", Reason: "missing"})
+
+			_, err = col.prepareSave(&declaredShardKeyDocument{}, newWriteOptions(nil))
+			So(err, ShouldHaveSameTypeAs, &ShardKeyError{})
+
+			_, err = col.prepareSave(&shardedDocument{Tenant: "acme", Region: "eu"}, newWriteOptions(nil))
+			So(err, ShouldBeNil)
+		})
+
+		Convey("should not change once loaded", func() {
+			doc := &shardedDocument{Tenant: "acme", Region: "eu"}
+			doc.SetIsNew(false)
+			doc.SetID(primitive.NewObjectID())
+			doc.GetDiffTracker().Reset()
+
+			doc.Name = "renamed"
+			_, err := col.prepareSave(doc, newWriteOptions(nil))
+			So(err, ShouldBeNil)
+
+			doc.Region = "us"
+			_, err = col.prepareSave(doc, newWriteOptions(nil))
+			So(err, ShouldResemble, &ShardKeyError{Collection: "sharded", Field: "region", Reason: "changed"})
+		})
+
+		Convey("should target the filters by id", func() {
+			id := primitive.NewObjectID()
+			So(documentFilter(id, &shardedDocument{Tenant: "acme"}), ShouldResemble, bson.D{{"_id", id}, {"tenant", "acme"}})
+			So(documentFilter(id, &shardedDocument{}), ShouldResemble, bson.D{{"_id", id}})
+			So(documentFilter(id, &noHookDocument{}), ShouldResemble, bson.D{{"_id", id}})
+		})
+	})
+}
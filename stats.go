@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Storage statistics of a collection, as reported by $collStats. Sizes are in bytes. On sharded collections, the
+// statistics of all shards are added up
+type CollectionStats struct {
+	Count          int64            `bson:"count" json:"count"`
+	Size           int64            `bson:"size" json:"size"`
+	AvgObjSize     float64          `bson:"avgObjSize" json:"avg_obj_size"`
+	StorageSize    int64            `bson:"storageSize" json:"storage_size"`
+	FreeStorage    int64            `bson:"freeStorageSize" json:"free_storage_size"`
+	NumIndexes     int              `bson:"nindexes" json:"num_indexes"`
+	TotalIndexSize int64            `bson:"totalIndexSize" json:"total_index_size"`
+	IndexSizes     map[string]int64 `bson:"indexSizes" json:"index_sizes"`
+
+	// Capped collections keep at most Max documents (if set) and MaxSize bytes
+	Capped  bool  `bson:"capped" json:"capped"`
+	Max     int64 `bson:"max" json:"max,omitempty"`
+	MaxSize int64 `bson:"maxSize" json:"max_size,omitempty"`
+
+	// The number of shards the statistics come from, 1 if the collection isn't sharded
+	Shards int `bson:"-" json:"shards"`
+}
+
+// Returns the storage statistics of the collection, e.g. for dashboards and capacity checks
+func (c *Collection) Stats(ctx context.Context) (*CollectionStats, error) {
+	pipeline := bson.A{bson.M{"$collStats": bson.M{"storageStats": bson.M{}}}}
+
+	stats := &CollectionStats{IndexSizes: map[string]int64{}}
+	err := c.runOperationContext(ctx, "collStats", func(ctx context.Context) error {
+		stats = &CollectionStats{IndexSizes: map[string]int64{}}
+		cursor, err := c.Collection().Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			shard := struct {
+				StorageStats *CollectionStats `bson:"storageStats"`
+			}{}
+			if err := cursor.Decode(&shard); err != nil {
+				return err
+			}
+			if shard.StorageStats != nil {
+				stats.add(shard.StorageStats)
+			}
+		}
+		return cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Adds the statistics of a shard
+func (s *CollectionStats) add(shard *CollectionStats) {
+	s.Shards++
+	s.Count += shard.Count
+	s.Size += shard.Size
+	s.StorageSize += shard.StorageSize
+	s.FreeStorage += shard.FreeStorage
+	s.TotalIndexSize += shard.TotalIndexSize
+	for name, size := range shard.IndexSizes {
+		s.IndexSizes[name] += size
+	}
+	if shard.NumIndexes > s.NumIndexes {
+		s.NumIndexes = shard.NumIndexes
+	}
+	s.Capped, s.Max, s.MaxSize = shard.Capped, shard.Max, shard.MaxSize
+
+	s.AvgObjSize = 0
+	if s.Count > 0 {
+		s.AvgObjSize = float64(s.Size) / float64(s.Count)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestCollectionStatsDecoding(t *testing.T) {
+	Convey("Collection stats", t, func() {
+		Convey("should decode the storage stats of $collStats", func() {
+			raw, _ := bson.Marshal(bson.M{
+				"count": int32(10), "size": int32(1000), "avgObjSize": int32(100), "storageSize": int32(4096),
+				"nindexes": int32(2), "totalIndexSize": int32(8192),
+				"indexSizes": bson.M{"_id_": int32(4096), "name_1": int32(4096)},
+				"capped":     true, "maxSize": int64(1 << 20),
+			})
+			shard := &CollectionStats{}
+			So(bson.Unmarshal(raw, shard), ShouldBeNil)
+			So(shard.Count, ShouldEqual, 10)
+			So(shard.IndexSizes["name_1"], ShouldEqual, 4096)
+			So(shard.Capped, ShouldBeTrue)
+			So(shard.MaxSize, ShouldEqual, 1<<20)
+		})
+
+		Convey("should add up the stats of shards", func() {
+			stats := &CollectionStats{IndexSizes: map[string]int64{}}
+			stats.add(&CollectionStats{Count: 10, Size: 1000, StorageSize: 4096, NumIndexes: 1, IndexSizes: map[string]int64{"_id_": 100}})
+			stats.add(&CollectionStats{Count: 30, Size: 1000, StorageSize: 4096, NumIndexes: 1, IndexSizes: map[string]int64{"_id_": 300}})
+			So(stats.Shards, ShouldEqual, 2)
+			So(stats.Count, ShouldEqual, 40)
+			So(stats.AvgObjSize, ShouldEqual, 50)
+			So(stats.IndexSizes["_id_"], ShouldEqual, 400)
+			So(stats.NumIndexes, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestCollectionStats(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Collection.Stats", t, func() {
+		tests := conn.Collection("tests")
+		So(tests.Save(&noHookDocument{Name: "ann"}), ShouldBeNil)
+
+		Convey("should return the stats of the collection", func() {
+			stats, err := tests.Stats(context.Background())
+			So(err, ShouldBeNil)
+			So(stats.Count, ShouldEqual, 1)
+			So(stats.Shards, ShouldEqual, 1)
+			So(stats.IndexSizes, ShouldContainKey, "_id_")
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
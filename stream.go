@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Content that is streamed to and from a GridFS file in the OverflowBucket instead of being held in memory. The
+// document only stores a reference to the file. Set the content with SetReader, it is uploaded by the next save;
+// read it with Open or CopyTo once the document was saved or found:
+//
+//	type Attachment struct {
+//		bongo.DocumentBase `bson:",inline"`
+//		Content bongo.Stream `bson:"content"`
+//	}
+//
+//	attachment.Content.SetReader(file)
+//	err := connection.Collection("attachments").Save(attachment)
+//
+// Like Overflow fields, uploads happen before the document is written and outside of any transaction. Replaced files
+// are deleted once the save succeeds, and the file of a deleted document once the delete succeeds
+type Stream struct {
+	file primitive.ObjectID
+	size int64
+
+	// The content to upload on the next save
+	reader io.Reader
+	// The collection the stream was saved to or found in, whose database holds the file
+	collection *Collection
+	// Files replaced by a save that are deleted once the save succeeds
+	replaced []primitive.ObjectID
+}
+
+// Sets the content of the stream. It is read and uploaded when the document is saved, and can't be retried
+func (s *Stream) SetReader(r io.Reader) {
+	s.reader = r
+}
+
+// Removes the content of the stream. Its file is deleted once the document is saved
+func (s *Stream) Clear() {
+	s.reader = nil
+	if !s.file.IsZero() {
+		s.replaced = append(s.replaced, s.file)
+	}
+	s.file, s.size = primitive.NilObjectID, 0
+}
+
+// Whether the stream has content that was saved
+func (s *Stream) Saved() bool {
+	return !s.file.IsZero()
+}
+
+// The size of the saved content in bytes
+func (s *Stream) Size() int64 {
+	return s.size
+}
+
+// Opens the saved content for reading. The caller has to close it. ctx only bounds the reads by its deadline
+func (s *Stream) Open(ctx context.Context) (io.ReadCloser, error) {
+	if s.file.IsZero() || s.collection == nil {
+		return nil, errors.New("stream has no saved content")
+	}
+	bucket, err := s.collection.overflowBucket()
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		bucket.SetReadDeadline(deadline)
+	}
+	return bucket.OpenDownloadStream(s.file)
+}
+
+// Copies the saved content to w, returning the number of bytes copied
+func (s *Stream) CopyTo(ctx context.Context, w io.Writer) (int64, error) {
+	r, err := s.Open(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return io.Copy(w, r)
+}
+
+func (s Stream) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if s.file.IsZero() {
+		return bsontype.Null, nil, nil
+	}
+	return bson.MarshalValue(overflowRef{File: s.file, Size: int(s.size)})
+}
+
+func (s *Stream) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	*s = Stream{}
+	if t != bsontype.EmbeddedDocument {
+		return nil
+	}
+	ref := overflowRef{}
+	if err := (bson.RawValue{Type: t, Value: data}).Unmarshal(&ref); err != nil {
+		return err
+	}
+	s.file, s.size = ref.File, int64(ref.Size)
+	return nil
+}
+
+var streamType = reflect.TypeOf(Stream{})
+
+var streamFieldsCache sync.Map
+
+// The Go paths of the Stream fields of a type, cached per type
+func streamFields(t reflect.Type) []string {
+	if paths, ok := streamFieldsCache.Load(t); ok {
+		return paths.([]string)
+	}
+
+	paths := make([]string, 0)
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType != streamType {
+			return true
+		}
+		paths = append(paths, goPath)
+		return false
+	})
+
+	streamFieldsCache.Store(t, paths)
+	return paths
+}
+
+// Calls fn with each Stream field of a document that is set
+func eachStream(doc interface{}, fn func(field *Stream) error) error {
+	v := reflect.ValueOf(doc)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for _, goPath := range streamFields(v.Type()) {
+		field := fieldByGoPath(v, goPath)
+		if !field.IsValid() {
+			continue
+		}
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		if err := fn(field.Addr().Interface().(*Stream)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// Uploads the content set on the Stream fields of a document. Readers can't be rewound, so uploads aren't retried
+func (c *Collection) storeStreams(ctx context.Context, doc interface{}) error {
+	return eachStream(doc, func(field *Stream) error {
+		field.collection = c
+		if field.reader == nil {
+			return nil
+		}
+
+		bucket, err := c.overflowBucket()
+		if err != nil {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			bucket.SetWriteDeadline(deadline)
+		}
+		reader := &countingReader{reader: field.reader}
+		file, err := bucket.UploadFromStream(c.Name, reader)
+		if err != nil {
+			return err
+		}
+
+		if !field.file.IsZero() {
+			field.replaced = append(field.replaced, field.file)
+		}
+		field.file, field.size, field.reader = file, reader.count, nil
+		return nil
+	})
+}
+
+// Remembers the collection of the Stream fields of a found document, so they can be opened
+func (c *Collection) attachStreams(doc interface{}) {
+	eachStream(doc, func(field *Stream) error {
+		field.collection = c
+		return nil
+	})
+}
+
+// Deletes the files that a saved document's Stream fields no longer use, or all its files if it was deleted
+func (c *Collection) dropStreams(doc interface{}, deleted bool) {
+	eachStream(doc, func(field *Stream) error {
+		files := field.replaced
+		field.replaced = nil
+		if deleted && !field.file.IsZero() {
+			files = append(files, field.file)
+		}
+		c.deleteFiles(files)
+		return nil
+	})
+}
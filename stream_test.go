@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"bytes"
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type streamDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string `bson:"name"`
+	Content      Stream `bson:"content"`
+}
+
+func TestStreamFields(t *testing.T) {
+	Convey("Stream fields", t, func() {
+		Convey("should be found", func() {
+			So(streamFields(reflect.TypeOf(streamDocument{})), ShouldResemble, []string{"Content"})
+		})
+
+		Convey("should store a reference to their file", func() {
+			file := primitive.NewObjectID()
+			raw, err := bson.Marshal(&streamDocument{Content: Stream{file: file, size: 42}})
+			So(err, ShouldBeNil)
+
+			found := &streamDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Content.file, ShouldEqual, file)
+			So(found.Content.Size(), ShouldEqual, 42)
+			So(found.Content.Saved(), ShouldBeTrue)
+		})
+
+		Convey("should be null without content", func() {
+			raw, err := bson.Marshal(&streamDocument{})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("content").Type, ShouldEqual, bson.TypeNull)
+
+			found := &streamDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Content.Saved(), ShouldBeFalse)
+		})
+
+		Convey("should not open before they are saved", func() {
+			stream := &Stream{}
+			stream.SetReader(strings.NewReader("foo"))
+			_, err := stream.Open(context.Background())
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should replace their file when cleared", func() {
+			file := primitive.NewObjectID()
+			stream := &Stream{file: file, size: 42}
+			stream.Clear()
+			So(stream.Saved(), ShouldBeFalse)
+			So(stream.replaced, ShouldResemble, []primitive.ObjectID{file})
+		})
+	})
+}
+
+func TestStream(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Stream", t, func() {
+		ctx := context.Background()
+		col := conn.Collection("tests")
+
+		Convey("should upload content on save and stream it after find", func() {
+			doc := &streamDocument{Name: "foo"}
+			doc.Content.SetReader(strings.NewReader("hello world"))
+			So(col.Save(doc), ShouldBeNil)
+			So(doc.Content.Size(), ShouldEqual, 11)
+
+			found := &streamDocument{}
+			So(col.FindByID(doc.ID, found), ShouldBeNil)
+			content := &bytes.Buffer{}
+			n, err := found.Content.CopyTo(ctx, content)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 11)
+			So(content.String(), ShouldEqual, "hello world")
+
+			_, err = col.DeleteDocument(found)
+			So(err, ShouldBeNil)
+			files, err := conn.Session.Database("bongotest").Collection(OverflowBucket+".files").CountDocuments(ctx, bson.M{})
+			So(err, ShouldBeNil)
+			So(files, ShouldEqual, 0)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"reflect"
+	"strings"
+)
+
+// Fields tagged `bongo:"transient"` only live at runtime (computed caches, injected services etc.): they are never
+// written to the database, never read from it and never cascaded, whatever their bson tag says, e.g.
+//
+//	Mailer  *Mailer  `bongo:"transient"`
+//	Summary string   `bson:"summary" bongo:"transient"`
+func isTransient(field reflect.StructField) bool {
+	tags := strings.Split(field.Tag.Get("bongo"), ",")
+	return stringInSlice("transient", tags)
+}
+
+// Skips transient fields, and parses the bson tag of all others like the driver does
+var transientStructTagParser bsoncodec.StructTagParserFunc = func(field reflect.StructField) (bsoncodec.StructTags, error) {
+	if isTransient(field) {
+		return bsoncodec.StructTags{Name: field.Name, Skip: true}, nil
+	}
+	return bsoncodec.DefaultStructTagParser(field)
+}
+
+// The registry of the connection's client: the driver's defaults, with transient fields left out of structs
+var Registry = newRegistry()
+
+func newRegistry() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	codec, err := bsoncodec.NewStructCodec(transientStructTagParser)
+	if err != nil {
+		panic(err)
+	}
+	registry.RegisterKindEncoder(reflect.Struct, codec)
+	registry.RegisterKindDecoder(reflect.Struct, codec)
+	return registry
+}
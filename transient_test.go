@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type transientCache struct {
+	Hits int
+}
+
+type transientDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string          `bson:"name"`
+	Summary      string          `bson:"summary" bongo:"transient"`
+	Cache        *transientCache `bongo:"transient"`
+}
+
+func TestTransientFields(t *testing.T) {
+	Convey("Transient fields", t, func() {
+		doc := &transientDocument{Name: "Ann", Summary: "computed", Cache: &transientCache{Hits: 3}}
+
+		Convey("should not be written", func() {
+			data, err := bson.MarshalWithRegistry(Registry, doc)
+			So(err, ShouldBeNil)
+			raw := bson.Raw(data)
+			So(raw.Lookup("name").StringValue(), ShouldEqual, "Ann")
+			_, err = raw.LookupErr("summary")
+			So(err, ShouldNotBeNil)
+			_, err = raw.LookupErr("cache")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should not be read", func() {
+			data, err := bson.Marshal(bson.M{"name": "Bob", "summary": "stored"})
+			So(err, ShouldBeNil)
+			So(bson.UnmarshalWithRegistry(Registry, data, doc), ShouldBeNil)
+			So(doc.Name, ShouldEqual, "Bob")
+			So(doc.Summary, ShouldEqual, "computed")
+		})
+
+		Convey("should not be upserted", func() {
+			update, err := upsertUpdate(doc, primitive.NewObjectID(), time.Now(), []string{"summary"})
+			So(err, ShouldBeNil)
+			So(update["$set"], ShouldNotContainKey, "summary")
+			So(update["$setOnInsert"], ShouldNotContainKey, "summary")
+
+			previous, err := bson.Marshal(bson.M{"summary": "stored"})
+			So(err, ShouldBeNil)
+			So(decodeInsertOnly(previous, bson.M{"summary": "stored"}, doc), ShouldBeNil)
+			So(doc.Summary, ShouldEqual, "computed")
+		})
+
+		Convey("should not be read from vector search results", func() {
+			raw, err := bson.Marshal(bson.D{{Key: "name", Value: "Bob"}, {Key: "summary", Value: "stored"}, {Key: vectorScoreField, Value: 0.9}})
+			So(err, ShouldBeNil)
+			result, err := newVectorSearchResult(&Collection{Name: "transients"}, raw)
+			So(err, ShouldBeNil)
+			So(result.Decode(doc), ShouldBeNil)
+			So(doc.Name, ShouldEqual, "Bob")
+			So(doc.Summary, ShouldEqual, "computed")
+		})
+
+		Convey("should not be cascaded", func() {
+			data := MapFromCascadeProperties([]string{"Name", "Summary", "Cache.Hits"}, doc)
+			So(data, ShouldContainKey, "Name")
+			So(data, ShouldNotContainKey, "Summary")
+			So(data, ShouldNotContainKey, "Cache")
+		})
+
+		Convey("should not be walked as persisted fields", func() {
+			paths := make([]string, 0)
+			walkBsonFields(reflect.TypeOf(transientDocument{}), "", func(field reflect.StructField, path string) bool {
+				paths = append(paths, path)
+				return false
+			})
+			So(paths, ShouldNotContain, "summary")
+			So(paths, ShouldContain, "name")
+		})
+	})
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The Context key holding the locale (e.g. "de-CH") validation messages are translated to
+const LocaleContextKey = "locale"
+
+// A validation failure identified by a code, so it can be translated. Return these from Validate instead of plain
+// errors, e.g.
+//
+//	return []error{&bongo.FieldError{Field: "name", Code: "min_length", Params: map[string]interface{}{"min": 3},
+//		Message: "name must have at least 3 characters"}}
+type FieldError struct {
+	// The bson path of the invalid field, if the failure is about a single field
+	Field string
+
+	Code string
+
+	// Values for the {placeholders} of translated messages, besides {field}
+	Params map[string]interface{}
+
+	// The untranslated message
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if len(e.Message) > 0 {
+		return e.Message
+	}
+	if len(e.Field) > 0 {
+		return e.Field + ": " + e.Code
+	}
+	return e.Code
+}
+
+// Looks up the message for a code in a locale. The message may contain {field} and {param} placeholders
+type Translator interface {
+	Translate(locale string, code string) (string, bool)
+}
+
+// A Translator with the messages of each locale, keyed by locale and code:
+//
+//	bongo.MessageCatalog{
+//		"de": {"required": "{field} ist erforderlich", "min_length": "{field} braucht mindestens {min} Zeichen"},
+//	}
+//
+// Regional locales fall back to their language, so "de-CH" (or "de_CH") uses the "de" messages unless it has its own
+type MessageCatalog map[string]map[string]string
+
+func (m MessageCatalog) Translate(locale string, code string) (string, bool) {
+	locale = strings.ReplaceAll(locale, "_", "-")
+	for len(locale) > 0 {
+		if message, ok := m[locale][code]; ok {
+			return message, true
+		}
+
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return "", false
+}
+
+// Renders the messages of the validation errors in a locale. FieldErrors with a translation are rendered from it,
+// everything else with its Error(). A nil translator leaves all messages untranslated
+func (v *ValidationError) Translate(t Translator, locale string) []string {
+	messages := make([]string, len(v.Errors))
+	for i, err := range v.Errors {
+		messages[i] = err.Error()
+
+		var fieldErr *FieldError
+		if t == nil || !errors.As(err, &fieldErr) {
+			continue
+		}
+		if message, ok := t.Translate(locale, fieldErr.Code); ok {
+			messages[i] = fieldErr.render(message)
+		}
+	}
+	return messages
+}
+
+// Fills the placeholders of a translated message
+func (e *FieldError) render(message string) string {
+	replacements := []string{"{field}", e.Field}
+	for key, value := range e.Params {
+		replacements = append(replacements, "{"+key+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(replacements...).Replace(message)
+}
+
+// The locale set in the collection's Context under LocaleContextKey, or ""
+func (c *Collection) Locale() string {
+	if c.Context == nil {
+		return ""
+	}
+	locale, _ := c.Context.Get(LocaleContextKey).(string)
+	return locale
+}
+
+// Renders the messages of a validation error with the connection's Translator, in the collection's locale
+func (c *Collection) ValidationMessages(err *ValidationError) []string {
+	var t Translator
+	if c.Connection != nil && c.Connection.Config != nil {
+		t = c.Connection.Config.Translator
+	}
+	return err.Translate(t, c.Locale())
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	Convey("Validation message translation", t, func() {
+		catalog := MessageCatalog{
+			"de":    {"required": "{field} ist erforderlich", "min_length": "{field} braucht mindestens {min} Zeichen"},
+			"de-CH": {"required": "{field} muss angegeben werden"},
+		}
+		validationErr := &ValidationError{[]error{
+			&FieldError{Field: "name", Code: "min_length", Params: map[string]interface{}{"min": 3}, Message: "name is too short"},
+			&FieldError{Field: "email", Code: "required", Message: "email is required"},
+			errors.New("something else"),
+		}}
+
+		Convey("should fall back from regional locales to their language", func() {
+			message, ok := catalog.Translate("de-CH", "min_length")
+			So(ok, ShouldBeTrue)
+			So(message, ShouldEqual, "{field} braucht mindestens {min} Zeichen")
+
+			message, ok = catalog.Translate("de_CH", "required")
+			So(message, ShouldEqual, "{field} muss angegeben werden")
+
+			_, ok = catalog.Translate("fr", "required")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("should render translated messages with their params", func() {
+			So(validationErr.Translate(catalog, "de"), ShouldResemble, []string{
+				"name braucht mindestens 3 Zeichen",
+				"email ist erforderlich",
+				"something else",
+			})
+		})
+
+		Convey("should keep the messages without a translation", func() {
+			So(validationErr.Translate(catalog, "fr"), ShouldResemble, []string{"name is too short", "email is required", "something else"})
+			So(validationErr.Translate(nil, "de"), ShouldResemble, []string{"name is too short", "email is required", "something else"})
+		})
+
+		Convey("should use the translator and locale of the collection", func() {
+			col := &Collection{Connection: &Connection{Config: &Config{Translator: catalog}}, Context: &Context{}}
+			col.Context.Set(LocaleContextKey, "de")
+			So(col.Locale(), ShouldEqual, "de")
+			So(col.ValidationMessages(validationErr)[1], ShouldEqual, "email ist erforderlich")
+		})
+
+		Convey("should describe untranslated field errors", func() {
+			So((&FieldError{Field: "email", Code: "required"}).Error(), ShouldEqual, "email: required")
+		})
+	})
+}
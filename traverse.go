@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+)
+
+// The field $graphLookup stores the depth of the documents it reaches in
+const TRAVERSAL_DEPTH_FIELD = "_depth"
+
+// A document reached by Traverse, with its number of hops from the start documents: 0 for the documents the start
+// documents point to directly
+type Traversed[T any] struct {
+	Document T
+	Depth    int
+}
+
+// Implemented by *Traversed, so Traverse can tell the depth to it
+type traversedResult interface {
+	setTraversed(c *Collection, raw bson.Raw, depth int) error
+}
+
+func (t *Traversed[T]) setTraversed(c *Collection, raw bson.Raw, depth int) error {
+	t.Depth = depth
+	v := reflect.ValueOf(&t.Document).Elem()
+	if v.Kind() != reflect.Ptr {
+		return c.decodeFound(raw, v.Addr().Interface())
+	}
+	if v.IsNil() {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+	return c.decodeFound(raw, v.Interface())
+}
+
+// Follows the references between the documents of the collection with $graphLookup, e.g. up an org chart:
+//
+//	var managers []*bongo.Traversed[*Employee]
+//	err := employees.Traverse(bson.M{"_id": id}, "manager_id", "_id", -1, &managers)
+//
+// Starting from the documents matching startFilter, it follows the values of the connectFrom field to the
+// documents whose connectTo field has them, up to maxDepth hops deep (without limit if negative). results must
+// point to a slice of documents or of *Traversed documents, which also hold the depth. Every document reached is
+// returned once, at its smallest depth, ordered by depth
+func (c *Collection) Traverse(startFilter interface{}, connectFrom string, connectTo string, maxDepth int, results interface{}) error {
+	slice := reflect.ValueOf(results)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return errors.New("the results of Traverse must be a pointer to a slice")
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+
+	return c.runOperation("traverse", func(ctx context.Context) error {
+		pipeline, err := c.traversalPipeline(ctx, startFilter, connectFrom, connectTo, maxDepth)
+		if err != nil {
+			return err
+		}
+		opts := options.Aggregate()
+		commentOn(ctx, c, opts.SetComment)
+		cursor, err := c.Collection().Aggregate(ctx, pipeline, opts)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		found := reflect.MakeSlice(slice.Type(), 0, 0)
+		for cursor.Next(ctx) {
+			elem := reflect.New(elemType).Elem()
+			target := elem.Addr()
+			if elemType.Kind() == reflect.Ptr {
+				elem = reflect.New(elemType.Elem())
+				target = elem
+			}
+
+			depth, _ := cursor.Current.Lookup(TRAVERSAL_DEPTH_FIELD).AsInt64OK()
+			if traversed, ok := target.Interface().(traversedResult); ok {
+				err = traversed.setTraversed(c, cursor.Current, int(depth))
+			} else {
+				err = c.decodeFound(cursor.Current, target.Interface())
+			}
+			if err != nil {
+				return err
+			}
+			found = reflect.Append(found, elem)
+		}
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+		slice.Set(found)
+		return nil
+	})
+}
+
+// The aggregation behind Traverse. The query guard of the collection applies to the start documents and to every
+// document reached
+func (c *Collection) traversalPipeline(ctx context.Context, startFilter interface{}, connectFrom string, connectTo string, maxDepth int) (bson.A, error) {
+	match, err := c.guardFilter(ctx, startFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := bson.M{
+		"from":             c.Name,
+		"startWith":        "$" + connectFrom,
+		"connectFromField": connectFrom,
+		"connectToField":   connectTo,
+		"as":               "_traversed",
+		"depthField":       TRAVERSAL_DEPTH_FIELD,
+	}
+	if maxDepth >= 0 {
+		lookup["maxDepth"] = maxDepth
+	}
+	if c.queryGuard() != nil {
+		restriction, err := c.guardFilter(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		lookup["restrictSearchWithMatch"] = restriction
+	}
+
+	return bson.A{
+		bson.M{"$match": match},
+		bson.M{"$graphLookup": lookup},
+		bson.M{"$unwind": "$_traversed"},
+		bson.M{"$replaceRoot": bson.M{"newRoot": "$_traversed"}},
+		// Documents reached from several start documents, or along several paths, count at their smallest depth
+		bson.M{"$sort": bson.D{{TRAVERSAL_DEPTH_FIELD, 1}, {"_id", 1}}},
+		bson.M{"$group": bson.M{"_id": "$_id", "doc": bson.M{"$first": "$$ROOT"}}},
+		bson.M{"$replaceRoot": bson.M{"newRoot": "$doc"}},
+		bson.M{"$sort": bson.D{{TRAVERSAL_DEPTH_FIELD, 1}, {"_id", 1}}},
+	}, nil
+}
+
+// Decodes a document found by an aggregation like ResultSet.Next decodes the documents of a find
+func (c *Collection) decodeFound(raw bson.Raw, doc interface{}) error {
+	if err := bson.UnmarshalWithRegistry(Registry, raw, doc); err != nil {
+		return newDecodeError(c, raw, doc, err)
+	}
+	if err := c.completeFound(c.baseContext(), doc); err != nil {
+		return err
+	}
+
+	if hook, ok := doc.(AfterFindHook); ok {
+		if err := hook.AfterFind(c); err != nil {
+			return err
+		}
+	}
+	c.stripUnreadable(doc)
+
+	if newt, ok := doc.(NewTracker); ok {
+		newt.SetIsNew(false)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+type traversedEmployee struct {
+	DocumentBase `bson:",inline"`
+	Name         string             `bson:"name"`
+	ManagerID    primitive.ObjectID `bson:"manager_id,omitempty"`
+}
+
+func TestTraversalPipeline(t *testing.T) {
+	Convey("Traversal pipelines", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}, Context: &Context{}}
+		employees := conn.Collection("employees")
+
+		Convey("should follow the references with $graphLookup", func() {
+			pipeline, err := employees.traversalPipeline(context.Background(), bson.M{"name": "Ann"}, "manager_id", "_id", 2)
+			So(err, ShouldBeNil)
+			So(pipeline[0], ShouldResemble, bson.M{"$match": bson.M{"name": "Ann"}})
+
+			lookup := pipeline[1].(bson.M)["$graphLookup"].(bson.M)
+			So(lookup["from"], ShouldEqual, "employees")
+			So(lookup["startWith"], ShouldEqual, "$manager_id")
+			So(lookup["connectToField"], ShouldEqual, "_id")
+			So(lookup["maxDepth"], ShouldEqual, 2)
+			So(lookup, ShouldNotContainKey, "restrictSearchWithMatch")
+		})
+
+		Convey("should not limit the depth if it is negative", func() {
+			pipeline, _ := employees.traversalPipeline(context.Background(), nil, "manager_id", "_id", -1)
+			So(pipeline[1].(bson.M)["$graphLookup"], ShouldNotContainKey, "maxDepth")
+		})
+
+		Convey("should apply the query guard to every document", func() {
+			conn.Config.QueryGuards = map[string]QueryGuard{
+				"employees": func(ctx context.Context, filter interface{}) (interface{}, error) {
+					return AndFilter(filter, bson.M{"tenant": "acme"}), nil
+				},
+			}
+			pipeline, _ := employees.traversalPipeline(context.Background(), nil, "manager_id", "_id", -1)
+			So(pipeline[0], ShouldResemble, bson.M{"$match": bson.M{"tenant": "acme"}})
+			So(pipeline[1].(bson.M)["$graphLookup"].(bson.M)["restrictSearchWithMatch"], ShouldResemble, bson.M{"tenant": "acme"})
+		})
+
+		Convey("should decode Traversed results with their depth", func() {
+			raw, _ := bson.Marshal(bson.M{"name": "Bob", TRAVERSAL_DEPTH_FIELD: int64(1)})
+			result := &Traversed[*traversedEmployee]{}
+			So(result.setTraversed(employees, raw, 1), ShouldBeNil)
+			So(result.Document.Name, ShouldEqual, "Bob")
+			So(result.Document.IsNew(), ShouldBeFalse)
+			So(result.Depth, ShouldEqual, 1)
+		})
+
+		Convey("should need a pointer to a slice", func() {
+			So(employees.Traverse(nil, "manager_id", "_id", -1, []*traversedEmployee{}), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestTraverse(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Traverse", t, func() {
+		employees := conn.Collection("employees")
+		ceo := &traversedEmployee{Name: "Ceo"}
+		So(employees.Save(ceo), ShouldBeNil)
+		cto := &traversedEmployee{Name: "Cto", ManagerID: ceo.ID}
+		So(employees.Save(cto), ShouldBeNil)
+		dev := &traversedEmployee{Name: "Dev", ManagerID: cto.ID}
+		So(employees.Save(dev), ShouldBeNil)
+
+		Convey("should find the managers with their depth", func() {
+			var managers []*Traversed[*traversedEmployee]
+			So(employees.Traverse(bson.M{"_id": dev.ID}, "manager_id", "_id", -1, &managers), ShouldBeNil)
+			So(len(managers), ShouldEqual, 2)
+			So(managers[0].Document.Name, ShouldEqual, "Cto")
+			So(managers[0].Depth, ShouldEqual, 0)
+			So(managers[1].Document.Name, ShouldEqual, "Ceo")
+			So(managers[1].Depth, ShouldEqual, 1)
+		})
+
+		Convey("should find the reports down to a depth", func() {
+			var reports []*traversedEmployee
+			So(employees.Traverse(bson.M{"_id": ceo.ID}, "_id", "manager_id", 0, &reports), ShouldBeNil)
+			So(len(reports), ShouldEqual, 1)
+			So(reports[0].Name, ShouldEqual, "Cto")
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strconv"
+)
+
+type UnitOfWorkOptions struct {
+	// Commit in one transaction, which needs a replica set. Otherwise the writes of each collection are applied in
+	// one BulkWrite, collection after collection
+	Transaction bool
+
+	// Options for all the writes, e.g. SkipCascade
+	WriteOptions []WriteOption
+
+	// Identity map for FindByID, which committed documents are evicted from. Defaults to a new one per unit of work
+	IdentityMap *IdentityMap
+}
+
+// Collects documents to save or delete, across collections, and writes them all at once on Commit, e.g. once per
+// request:
+//
+//	uow := conn.NewUnitOfWork(&bongo.UnitOfWorkOptions{Transaction: true})
+//	uow.Save(conn.Collection("orders"), order)
+//	uow.Save(conn.Collection("customers"), customer)
+//	uow.Delete(conn.Collection("carts"), cart)
+//	err := uow.Commit(ctx)
+//
+// If the commit fails, the documents are restored to their state before Commit (ids, timestamps, newness and
+// changes made by hooks). Without Transaction, a commit can fail after some collections were written, see
+// PartialCommitError. Not safe for concurrent use
+type UnitOfWork struct {
+	connection  *Connection
+	options     *UnitOfWorkOptions
+	identityMap *IdentityMap
+	entries     []*unitOfWorkEntry
+}
+
+type unitOfWorkEntry struct {
+	collection *Collection
+	doc        Document
+	delete     bool
+}
+
+// Returned by Commit without Transaction when some of the documents were written before a write failed. The
+// written documents are committed (their after hooks and cascades ran), the others are restored and stay
+// registered, so Commit can be called again for them. The documents of a collection whose write failed without a
+// write error, e.g. with a network error, are counted as not written, though the server may have applied them
+type PartialCommitError struct {
+	Written []Document
+	Err     error
+}
+
+func (e *PartialCommitError) Error() string {
+	return "only " + strconv.Itoa(len(e.Written)) + " documents of the unit of work were written: " + e.Err.Error()
+}
+
+func (e *PartialCommitError) Unwrap() error {
+	return e.Err
+}
+
+// Creates a unit of work on the connection
+func (m *Connection) NewUnitOfWork(opts *UnitOfWorkOptions) *UnitOfWork {
+	if opts == nil {
+		opts = &UnitOfWorkOptions{}
+	}
+	identityMap := opts.IdentityMap
+	if identityMap == nil {
+		identityMap = NewIdentityMap()
+	}
+	return &UnitOfWork{connection: m, options: opts, identityMap: identityMap}
+}
+
+// Loads a document through the identity map of the unit of work, see IdentityMap.FindByID
+func (u *UnitOfWork) FindByID(ctx context.Context, collection *Collection, id primitive.ObjectID, doc Document) (Document, error) {
+	return u.identityMap.FindByID(ctx, collection, id, doc)
+}
+
+// The identity map of the unit of work
+func (u *UnitOfWork) IdentityMap() *IdentityMap {
+	return u.identityMap
+}
+
+// Registers a new or changed document to save. Registering a document again replaces its previous registration
+func (u *UnitOfWork) Save(collection *Collection, doc Document) {
+	u.register(&unitOfWorkEntry{collection: collection, doc: doc})
+}
+
+// Registers a document to delete
+func (u *UnitOfWork) Delete(collection *Collection, doc Document) {
+	u.register(&unitOfWorkEntry{collection: collection, doc: doc, delete: true})
+}
+
+func (u *UnitOfWork) register(entry *unitOfWorkEntry) {
+	for i, existing := range u.entries {
+		if existing.doc == entry.doc {
+			u.entries = append(u.entries[:i], u.entries[i+1:]...)
+			break
+		}
+	}
+	u.entries = append(u.entries, entry)
+}
+
+// The number of registered documents
+func (u *UnitOfWork) Len() int {
+	return len(u.entries)
+}
+
+// Forgets the registered documents
+func (u *UnitOfWork) Discard() {
+	u.entries = nil
+}
+
+// Runs the before hooks of the registered documents and writes them, with one BulkWrite per collection. After
+// hooks and cascades run once everything is written, and the unit of work is empty again
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	o := newWriteOptions(u.options.WriteOptions)
+	o.identityMap = u.identityMap
+	snapshots := make([]reflect.Value, len(u.entries))
+	for i, entry := range u.entries {
+		snapshots[i] = snapshotDocument(entry.doc)
+	}
+	rollback := func(err error) error {
+		for i, entry := range u.entries {
+			restoreDocument(entry.doc, snapshots[i])
+		}
+		return err
+	}
+
+	writes := make([]*cascadeWrite, 0)
+	writeEntries := make(map[*cascadeWrite][]*unitOfWorkEntry)
+	byCollection := make(map[string]*cascadeWrite)
+	for _, entry := range u.entries {
+		var model mongo.WriteModel
+		if entry.delete {
+			if err := entry.collection.prepareDelete(entry.doc, o); err != nil {
+				return rollback(err)
+			}
+			model = mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": entry.doc.GetID()})
+		} else {
+			id, err := entry.collection.prepareSave(entry.doc, o)
+			if err != nil {
+				return rollback(err)
+			}
+			model = mongo.NewReplaceOneModel().SetFilter(documentFilter(id, entry.doc)).SetReplacement(entry.doc).SetUpsert(true)
+		}
+
+		key := entry.collection.Database + "." + entry.collection.Name
+		write, ok := byCollection[key]
+		if !ok {
+			write = &cascadeWrite{collection: entry.collection}
+			byCollection[key] = write
+			writes = append(writes, write)
+		}
+		write.models = append(write.models, model)
+		writeEntries[write] = append(writeEntries[write], entry)
+	}
+
+	bulkWrite := func(ctx context.Context, write *cascadeWrite) error {
+		models, err := write.collection.guardModels(ctx, write.models)
+		if err != nil {
+			return err
+		}
+		_, err = write.collection.Collection().BulkWrite(ctx, models, options.BulkWrite().SetOrdered(true))
+		return err
+	}
+
+	var err error
+	if u.options.Transaction {
+		err = u.connection.inTransaction(ctx, func(ctx context.Context) error {
+			for _, write := range writes {
+				if err := bulkWrite(ctx, write); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	} else {
+		// Not retried, a retry of a batch that was partly applied would fail with duplicate keys
+		written := make([]*unitOfWorkEntry, 0)
+		for _, write := range writes {
+			write := write
+			err = write.collection.runOperationOnce(ctx, "unitOfWork", func(ctx context.Context) error {
+				return bulkWrite(ctx, write)
+			})
+			if err != nil {
+				written = append(written, writeEntries[write][:appliedWrites(err)]...)
+				break
+			}
+			written = append(written, writeEntries[write]...)
+		}
+		if err != nil && len(written) > 0 {
+			return u.commitPartially(written, snapshots, o, err)
+		}
+	}
+	if err != nil {
+		return rollback(err)
+	}
+
+	entries := u.entries
+	u.entries = nil
+	return u.finish(entries, o)
+}
+
+// Finishes the written entries and restores the others, which stay registered
+func (u *UnitOfWork) commitPartially(written []*unitOfWorkEntry, snapshots []reflect.Value, o *writeOptions, err error) error {
+	isWritten := make(map[*unitOfWorkEntry]bool)
+	docs := make([]Document, len(written))
+	for i, entry := range written {
+		isWritten[entry] = true
+		docs[i] = entry.doc
+	}
+
+	unwritten := make([]*unitOfWorkEntry, 0)
+	for i, entry := range u.entries {
+		if !isWritten[entry] {
+			restoreDocument(entry.doc, snapshots[i])
+			unwritten = append(unwritten, entry)
+		}
+	}
+	u.entries = unwritten
+
+	if finishErr := u.finish(written, o); finishErr != nil {
+		err = finishErr
+	}
+	return &PartialCommitError{Written: docs, Err: err}
+}
+
+// The number of models of an ordered BulkWrite that were applied before it failed
+func appliedWrites(err error) int {
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+		return bulkErr.WriteErrors[0].Index
+	}
+	return 0
+}
+
+// Runs the after hooks and cascades of written entries
+func (u *UnitOfWork) finish(entries []*unitOfWorkEntry, o *writeOptions) error {
+	var err error
+	for _, entry := range entries {
+		if entry.delete {
+			err = entry.collection.finishDelete(entry.doc, o)
+		} else {
+			if !o.skipCascade {
+				toCascade := savedCascadeConfigs(entry.collection, []Document{entry.doc})
+				entry.collection.Connection.goCascade(func() { runCascadeSave(toCascade) })
+			}
+			err = entry.collection.finishSave(entry.doc, o)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Runs fn in a transaction, with a context that makes the operations on the connection's client part of it
+func (m *Connection) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := m.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// A shallow copy of a document's struct
+func snapshotDocument(doc Document) reflect.Value {
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return reflect.Value{}
+	}
+	snapshot := reflect.New(v.Elem().Type()).Elem()
+	snapshot.Set(v.Elem())
+	return snapshot
+}
+
+func restoreDocument(doc Document, snapshot reflect.Value) {
+	if snapshot.IsValid() {
+		reflect.ValueOf(doc).Elem().Set(snapshot)
+	}
+}
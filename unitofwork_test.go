@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"testing"
+)
+
+func TestUnitOfWorkRegistration(t *testing.T) {
+	Convey("Unit of work", t, func() {
+		conn := &Connection{Config: &Config{Database: "bongotest"}}
+		uow := conn.NewUnitOfWork(nil)
+		tests := conn.Collection("tests")
+
+		Convey("should register each document once, keeping the last registration", func() {
+			a, b := &noHookDocument{Name: "a"}, &noHookDocument{Name: "b"}
+			uow.Save(tests, a)
+			uow.Save(tests, b)
+			uow.Delete(tests, a)
+			So(uow.Len(), ShouldEqual, 2)
+			So(uow.entries[0].doc, ShouldEqual, b)
+			So(uow.entries[1].doc, ShouldEqual, a)
+			So(uow.entries[1].delete, ShouldBeTrue)
+
+			uow.Discard()
+			So(uow.Len(), ShouldEqual, 0)
+		})
+
+		Convey("should restore the documents when a document can't be saved", func() {
+			saved := &noHookDocument{Name: "a"}
+			invalid := &validatedDocument{Name: "b"}
+			uow.Save(tests, saved)
+			uow.Save(tests, invalid)
+
+			err := uow.Commit(context.Background())
+			So(err, ShouldNotBeNil)
+			So(saved.IsNew(), ShouldBeTrue)
+			So(saved.ID.IsZero(), ShouldBeTrue)
+			So(saved.CreatedAt.IsZero(), ShouldBeTrue)
+			So(uow.Len(), ShouldEqual, 2)
+		})
+
+		Convey("should count the writes applied before a bulk write failed", func() {
+			So(appliedWrites(errors.New("connection reset")), ShouldEqual, 0)
+			bulkErr := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Index: 2, Code: 11000}}}}
+			So(appliedWrites(bulkErr), ShouldEqual, 2)
+			So(appliedWrites(fmt.Errorf("saveMany: %w", bulkErr)), ShouldEqual, 2)
+
+			err := &PartialCommitError{Written: []Document{&noHookDocument{}}, Err: bulkErr}
+			var unwrapped mongo.BulkWriteException
+			So(errors.As(err, &unwrapped), ShouldBeTrue)
+			So(err.Error(), ShouldStartWith, "only 1 documents of the unit of work were written: ")
+		})
+	})
+}
+
+func TestUnitOfWorkCommit(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Committing a unit of work", t, func() {
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+
+		tests, others := conn.Collection("tests"), conn.Collection("others")
+		removed := &noHookDocument{Name: "removed"}
+		So(tests.Save(removed), ShouldEqual, nil)
+
+		uow := conn.NewUnitOfWork(nil)
+		a, b := &noHookDocument{Name: "a"}, &noHookDocument{Name: "b"}
+		uow.Save(tests, a)
+		uow.Save(others, b)
+		uow.Delete(tests, removed)
+		So(uow.Commit(context.Background()), ShouldEqual, nil)
+		So(uow.Len(), ShouldEqual, 0)
+		So(a.IsNew(), ShouldBeFalse)
+
+		found := &noHookDocument{}
+		So(tests.FindByID(a.ID, found), ShouldEqual, nil)
+		So(others.FindByID(b.ID, found), ShouldEqual, nil)
+		_, ok := tests.FindByID(removed.ID, found).(*DocumentNotFoundError)
+		So(ok, ShouldBeTrue)
+
+		Convey("should keep the documents that were written when a later collection fails", func() {
+			_, err := others.Collection().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+				Keys:    bson.D{{Key: "name", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			})
+			So(err, ShouldEqual, nil)
+
+			uow := conn.NewUnitOfWork(nil)
+			c, d := &noHookDocument{Name: "c"}, &noHookDocument{Name: "b"}
+			uow.Save(tests, c)
+			uow.Save(others, d)
+			err = uow.Commit(context.Background())
+			partial, ok := err.(*PartialCommitError)
+			So(ok, ShouldBeTrue)
+			So(partial.Written, ShouldResemble, []Document{c})
+			So(c.IsNew(), ShouldBeFalse)
+			So(d.ID.IsZero(), ShouldBeTrue)
+			So(uow.Len(), ShouldEqual, 1)
+
+			d.Name = "d"
+			So(uow.Commit(context.Background()), ShouldEqual, nil)
+			count, err := tests.Collection().CountDocuments(context.Background(), bson.M{"name": "c"})
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 1)
+		})
+	})
+}
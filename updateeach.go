@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UpdateEachOptions struct {
+	// Number of documents per bulk write. Defaults to 500
+	BatchSize int
+
+	// Record documents that fail (in fn, validation, hooks or the write) in the report and carry on, instead of
+	// stopping at the first failure
+	ContinueOnError bool
+
+	// Options for writing each document, e.g. SkipHooks()
+	WriteOptions []WriteOption
+}
+
+// An error for a single document. The document is not updated
+type UpdateEachError struct {
+	ID  primitive.ObjectID
+	Err error
+}
+
+func (e *UpdateEachError) Error() string {
+	return "document " + e.ID.Hex() + ": " + e.Err.Error()
+}
+
+func (e *UpdateEachError) Unwrap() error {
+	return e.Err
+}
+
+type UpdateEachReport struct {
+	// Number of documents written
+	Updated int64
+
+	// The documents that failed, with ContinueOnError
+	Errors []*UpdateEachError
+}
+
+// Streams the documents matching the query into new *T, calls fn to change each one, then runs the validation and
+// save hooks and writes them back in batches, like Save would one by one. The documents are read in _id order.
+// Without ContinueOnError, the first failure stops the update and is returned as an *UpdateEachError (the
+// documents already changed in the current batch are still written):
+//
+//	report, err := bongo.UpdateEach(conn.Collection("users"), bson.M{"country": "UK"}, func(user *User) error {
+//		user.Country = "GB"
+//		return nil
+//	}, nil)
+func UpdateEach[T any](c *Collection, query interface{}, fn func(doc *T) error, opts *UpdateEachOptions) (*UpdateEachReport, error) {
+	if opts == nil {
+		opts = &UpdateEachOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize < 1 {
+		batchSize = 500
+	}
+
+	if _, ok := interface{}(new(T)).(Document); !ok {
+		return nil, errors.New("UpdateEach needs a document type")
+	}
+
+	results, err := c.Find(query)
+	if err != nil {
+		return nil, err
+	}
+	results.Query.SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	writeOpts := newWriteOptions(opts.WriteOptions)
+	report := &UpdateEachReport{Errors: []*UpdateEachError{}}
+	batch := make([]Document, 0, batchSize)
+
+	// Records a failure, returning it if the update has to stop
+	fail := func(doc Document, err error) error {
+		updateErr := &UpdateEachError{ID: doc.GetID(), Err: err}
+		if !opts.ContinueOnError {
+			return updateErr
+		}
+		report.Errors = append(report.Errors, updateErr)
+		return nil
+	}
+
+	err = ForEach(c.baseContext(), results, func(value *T) error {
+		doc := interface{}(value).(Document)
+
+		err := fn(value)
+		if err == nil {
+			_, err = c.prepareSave(doc, writeOpts)
+		}
+		if err != nil {
+			return fail(doc, err)
+		}
+
+		batch = append(batch, doc)
+		if len(batch) < batchSize {
+			return nil
+		}
+		err = c.writeUpdateBatch(batch, report, writeOpts, fail)
+		batch = batch[:0]
+		return err
+	})
+
+	if len(batch) > 0 {
+		if writeErr := c.writeUpdateBatch(batch, report, writeOpts, fail); err == nil {
+			err = writeErr
+		}
+	}
+
+	return report, err
+}
+
+// Replaces the documents of a batch by _id and runs their after save hooks. Documents deleted in the meantime are
+// not recreated
+func (c *Collection) writeUpdateBatch(batch []Document, report *UpdateEachReport, opts *writeOptions, fail func(Document, error) error) error {
+	models := make([]mongo.WriteModel, len(batch))
+	for i, doc := range batch {
+		models[i] = mongo.NewReplaceOneModel().SetFilter(documentFilter(doc.GetID(), doc)).SetReplacement(doc)
+	}
+
+	err := c.runOperation("updateEach", func(ctx context.Context) error {
+		guarded, err := c.guardModels(ctx, models)
+		if err != nil {
+			return err
+		}
+		_, err = c.Collection().BulkWrite(ctx, guarded, options.BulkWrite().SetOrdered(false))
+		return err
+	})
+
+	failed := make(map[int]error)
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+			for _, writeErr := range bulkErr.WriteErrors {
+				failed[writeErr.Index] = writeErr
+			}
+		} else {
+			for i := range batch {
+				failed[i] = err
+			}
+		}
+	}
+
+	var stop error
+	saved := make([]Document, 0, len(batch))
+	for i, doc := range batch {
+		err, ok := failed[i]
+		if !ok {
+			saved = append(saved, doc)
+			err = c.finishSave(doc, opts)
+		}
+		if err != nil {
+			if stop == nil {
+				stop = fail(doc, err)
+			}
+			continue
+		}
+		report.Updated++
+	}
+
+	if !opts.skipCascade && len(saved) > 0 {
+		toCascade := savedCascadeConfigs(c, saved)
+		c.Connection.goCascade(func() { runCascadeSave(toCascade) })
+	}
+	return stop
+}
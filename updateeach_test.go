@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type updateEachDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string
+	Count        int
+}
+
+func (d *updateEachDocument) Validate(c *Collection) []error {
+	if d.Count < 0 {
+		return []error{errors.New("count can't be negative")}
+	}
+	return nil
+}
+
+func TestUpdateEachDocumentType(t *testing.T) {
+	Convey("UpdateEach", t, func() {
+		Convey("should need a document type", func() {
+			_, err := UpdateEach(&Collection{}, nil, func(doc *bson.M) error { return nil }, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestUpdateEach(t *testing.T) {
+	conn := getConnection()
+
+	Convey("UpdateEach", t, func() {
+		col := conn.Collection("update_each")
+		for i := 0; i < 5; i++ {
+			col.Save(&updateEachDocument{Name: "doc", Count: i})
+		}
+
+		Convey("should change, validate and write back every matching document", func() {
+			report, err := UpdateEach(col, bson.M{"count": bson.M{"$gte": 1}}, func(doc *updateEachDocument) error {
+				doc.Count *= 10
+				return nil
+			}, &UpdateEachOptions{BatchSize: 2})
+			So(err, ShouldEqual, nil)
+			So(report.Updated, ShouldEqual, 4)
+
+			count, _ := col.Collection().CountDocuments(context.Background(), bson.M{"count": bson.M{"$in": bson.A{0, 10, 20, 30, 40}}})
+			So(count, ShouldEqual, 5)
+		})
+
+		Convey("should stop at the first invalid document", func() {
+			report, err := UpdateEach(col, nil, func(doc *updateEachDocument) error {
+				doc.Count -= 2
+				return nil
+			}, nil)
+			So(err, ShouldNotBeNil)
+			So(err.(*UpdateEachError).Err, ShouldHaveSameTypeAs, &ValidationError{})
+			So(report.Updated, ShouldEqual, 0)
+		})
+
+		Convey("should report invalid documents and carry on with ContinueOnError", func() {
+			report, err := UpdateEach(col, nil, func(doc *updateEachDocument) error {
+				doc.Count -= 2
+				return nil
+			}, &UpdateEachOptions{ContinueOnError: true})
+			So(err, ShouldEqual, nil)
+			So(report.Updated, ShouldEqual, 3)
+			So(len(report.Errors), ShouldEqual, 2)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
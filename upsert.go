@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+	"time"
+)
+
+// Where TimeCreatedTracker documents store their creation time, as in DocumentBase
+const createdAtField = "created_at"
+
+type UpsertOptions struct {
+	// Top level bson fields of the document that are only written when it is inserted, e.g. defaults that must
+	// not overwrite existing values. The _id, created_at and created_by are always insert-only
+	OnInsert []string
+}
+
+// Updates the document matching the query with the fields of doc, or inserts doc if nothing matches. Unlike Save,
+// the fields in OnInsert (plus _id and the creation time) are set with $setOnInsert, so they keep their stored
+// values on updates, while UpdatedAt is refreshed either way:
+//
+//	user := &User{Email: email, Name: name, Plan: "free"}
+//	inserted, err := conn.Collection("users").Upsert(bson.M{"email": email}, user, &bongo.UpsertOptions{
+//		OnInsert: []string{"plan"},
+//	})
+//
+// Validation, field access and the save hooks run as for Save of a new document. Afterwards doc has the stored _id
+// and insert-only values. Returns whether it was inserted
+func (c *Collection) Upsert(query interface{}, doc Document, opts *UpsertOptions) (bool, error) {
+	if opts == nil {
+		opts = &UpsertOptions{}
+	}
+
+	// Whether it is inserted isn't known yet, so it is checked like a new document, and the creator is insert-only
+	o := &writeOptions{}
+	if err := c.validateSave(doc, true, o); err != nil {
+		return false, err
+	}
+	c.blame(doc, true)
+
+	id := doc.GetID()
+	if id.IsZero() {
+		id = primitive.NewObjectID()
+	}
+	if err := c.encodeSave(doc, true); err != nil {
+		return false, err
+	}
+
+	update, err := upsertUpdate(doc, id, time.Now(), opts.OnInsert)
+	if err != nil {
+		return false, err
+	}
+
+	// The previous version tells whether the document was inserted, and holds the insert-only values to keep
+	findOpts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var previous bson.Raw
+	err = c.runOperation("upsert", func(ctx context.Context) error {
+		filter, err := c.guardFilter(ctx, query)
+		if err != nil {
+			return err
+		}
+		commentOn(ctx, c, findOpts.SetComment)
+		previous, err = c.Collection().FindOneAndUpdate(ctx, filter, update, findOpts).Raw()
+		return err
+	})
+
+	inserted := err == mongo.ErrNoDocuments
+	if inserted {
+		doc.SetID(id)
+	} else if err != nil {
+		return false, err
+	} else if err := decodeInsertOnly(previous, update["$setOnInsert"].(bson.M), doc); err != nil {
+		return false, newDecodeError(c, previous, doc, err)
+	}
+
+	toCascade := savedCascadeConfigs(c, []Document{doc})
+	c.Connection.goCascade(func() { runCascadeSave(toCascade) })
+
+	return inserted, c.finishSave(doc, o)
+}
+
+// Copies the stored values of the insert-only fields into the document
+func decodeInsertOnly(previous bson.Raw, insertOnly bson.M, doc Document) error {
+	stored := bson.D{}
+	for field := range insertOnly {
+		if value, err := previous.LookupErr(field); err == nil {
+			stored = append(stored, bson.E{Key: field, Value: value})
+		}
+	}
+
+	data, err := bson.MarshalWithRegistry(Registry, stored)
+	if err != nil {
+		return err
+	}
+	return bson.UnmarshalWithRegistry(Registry, data, doc)
+}
+
+// Splits the document into the $set and $setOnInsert parts of an upsert
+func upsertUpdate(doc Document, id primitive.ObjectID, now time.Time, onInsert []string) (bson.M, error) {
+	if tt, ok := doc.(TimeCreatedTracker); ok {
+		tt.SetCreatedAt(now)
+	}
+	if tt, ok := doc.(TimeModifiedTracker); ok {
+		tt.SetUpdatedAt(now)
+	}
+
+	data, err := bson.MarshalWithRegistry(Registry, doc)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.M{}
+	if err := bson.UnmarshalWithRegistry(Registry, data, &set); err != nil {
+		return nil, err
+	}
+
+	delete(set, "_id")
+	insertOnly := bson.M{"_id": id}
+
+	fields := onInsert
+	if _, ok := doc.(TimeCreatedTracker); ok {
+		fields = append([]string{createdAtField}, fields...)
+	}
+	if _, ok := doc.(CreatedByTracker); ok {
+		fields = append([]string{createdByField}, fields...)
+	}
+	for _, field := range fields {
+		if strings.Contains(field, ".") {
+			return nil, errors.New("insert-only field " + field + " must be a top level field")
+		}
+		if value, ok := set[field]; ok {
+			insertOnly[field] = value
+			delete(set, field)
+		}
+	}
+
+	update := bson.M{"$setOnInsert": insertOnly}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	return update, nil
+}
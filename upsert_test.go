@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+type upsertDocument struct {
+	DocumentBase `bson:",inline"`
+	Email        string `bson:"email"`
+	Name         string `bson:"name"`
+	Plan         string `bson:"plan"`
+}
+
+func TestUpsertUpdate(t *testing.T) {
+	Convey("Upsert updates", t, func() {
+		now := time.Now().Truncate(time.Millisecond)
+		id := primitive.NewObjectID()
+
+		Convey("should split the document into $set and $setOnInsert", func() {
+			update, err := upsertUpdate(&upsertDocument{Email: "a@example.com", Name: "A", Plan: "free"}, id, now, []string{"plan"})
+			So(err, ShouldEqual, nil)
+
+			insertOnly := update["$setOnInsert"].(bson.M)
+			So(insertOnly["_id"], ShouldEqual, id)
+			So(insertOnly["plan"], ShouldEqual, "free")
+			So(insertOnly["created_at"], ShouldEqual, primitive.NewDateTimeFromTime(now))
+
+			set := update["$set"].(bson.M)
+			So(set["name"], ShouldEqual, "A")
+			So(set["updated_at"], ShouldEqual, primitive.NewDateTimeFromTime(now))
+			So(set, ShouldNotContainKey, "plan")
+			So(set, ShouldNotContainKey, "created_at")
+			So(set, ShouldNotContainKey, "_id")
+		})
+
+		Convey("should only allow top level insert-only fields", func() {
+			_, err := upsertUpdate(&upsertDocument{}, id, now, []string{"address.city"})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should prepare documents like Save before writing them", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest", DocumentSize: &DocumentSizeConfig{MaxSize: 16}}, Context: &Context{}}
+			_, err := conn.Collection("upserts").Upsert(bson.M{"email": "a@example.com"}, &upsertDocument{Email: "a@example.com"}, nil)
+			_, tooLarge := err.(*DocumentTooLargeError)
+			So(tooLarge, ShouldBeTrue)
+		})
+	})
+}
+
+func TestUpsert(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Upsert", t, func() {
+		col := conn.Collection("upserts")
+
+		Convey("should insert, then update without touching insert-only fields", func() {
+			doc := &upsertDocument{Email: "a@example.com", Name: "A", Plan: "free"}
+			inserted, err := col.Upsert(bson.M{"email": "a@example.com"}, doc, &UpsertOptions{OnInsert: []string{"plan"}})
+			So(err, ShouldEqual, nil)
+			So(inserted, ShouldBeTrue)
+			So(doc.ID.IsZero(), ShouldBeFalse)
+			So(doc.IsNew(), ShouldBeFalse)
+
+			again := &upsertDocument{Email: "a@example.com", Name: "B", Plan: "trial"}
+			inserted, err = col.Upsert(bson.M{"email": "a@example.com"}, again, &UpsertOptions{OnInsert: []string{"plan"}})
+			So(err, ShouldEqual, nil)
+			So(inserted, ShouldBeFalse)
+			So(again.ID, ShouldEqual, doc.ID)
+			So(again.Plan, ShouldEqual, "free")
+			So(again.CreatedAt.Unix(), ShouldEqual, doc.CreatedAt.Unix())
+
+			stored := &upsertDocument{}
+			So(col.FindByID(doc.ID, stored), ShouldEqual, nil)
+			So(stored.Name, ShouldEqual, "B")
+			So(stored.Plan, ShouldEqual, "free")
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
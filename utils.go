@@ -29,3 +29,95 @@ func GetBsonName(field reflect.StructField) string {
 	}
 
 }
+
+// The key the driver stores a field under: the bson tag name, or the lower cased field name
+func bsonKey(field reflect.StructField) string {
+	tags := strings.Split(field.Tag.Get("bson"), ",")
+	if len(tags[0]) > 0 {
+		return tags[0]
+	}
+	return strings.ToLower(field.Name)
+}
+
+// Checks for an option (omitempty, inline, ...) in a field's bson tag
+func hasBsonOption(field reflect.StructField, option string) bool {
+	tags := strings.Split(field.Tag.Get("bson"), ",")
+	return stringInSlice(option, tags[1:])
+}
+
+// Calls fn for every exported field of a struct type that is persisted, with its dotted path. Inline structs are
+// flattened, and nested structs are descended into if fn returns true
+func walkBsonFields(t reflect.Type, prefix string, fn func(field reflect.StructField, path string) bool) {
+	walkFields(t, "", prefix, map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		return fn(field, path)
+	})
+}
+
+// Gets the field at a Go path (e.g. Address.City) of a struct value, or an invalid value if a pointer on the way
+// is nil
+func fieldByGoPath(v reflect.Value, goPath string) reflect.Value {
+	field := v
+	for _, name := range strings.Split(goPath, ".") {
+		for field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				return reflect.Value{}
+			}
+			field = field.Elem()
+		}
+		if field.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+		field = field.FieldByName(name)
+	}
+	return field
+}
+
+// Same as walkBsonFields, but also passes the Go path of the field (e.g. Address.City). Types that are already
+// being walked are not descended into again, so self-referencing types don't recurse forever
+func walkFields(t reflect.Type, goPrefix string, prefix string, visiting map[reflect.Type]bool, fn func(field reflect.StructField, goPath string, path string) bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visiting[t] {
+		return
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		key := bsonKey(field)
+		if key == "-" || isTransient(field) {
+			continue
+		}
+
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		// Inline structs are promoted, both in Go and in the document
+		if hasBsonOption(field, "inline") && ft.Kind() == reflect.Struct {
+			walkFields(ft, goPrefix, prefix, visiting, fn)
+			continue
+		}
+
+		path := key
+		goPath := field.Name
+		if len(prefix) > 0 {
+			path = prefix + "." + key
+		}
+		if len(goPrefix) > 0 {
+			goPath = goPrefix + "." + field.Name
+		}
+
+		if fn(field, goPath, path) && ft.Kind() == reflect.Struct {
+			walkFields(ft, goPath, path, visiting, fn)
+		}
+	}
+}
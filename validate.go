@@ -5,8 +5,6 @@
 package bongo
 
 import (
-	"context"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"reflect"
 )
@@ -16,13 +14,10 @@ func ValidateRequired(val interface{}) bool {
 	return valueOf.Interface() != reflect.Zero(valueOf.Type()).Interface()
 }
 
+// Deprecated: use ValidateRefExists, which reports query errors, or a `ref` tag
 func ValidateMongoIdRef(id primitive.ObjectID, collection *Collection) bool {
-	cur, err := collection.Collection().Find(context.Background(), bson.M{"_id": id})
-	if err != nil || cur.Err() != nil || cur != nil {
-		return false
-	}
-
-	return true
+	exists, err := ValidateRefExists(collection.baseContext(), id, collection)
+	return err == nil && exists
 }
 
 func stringInSlice(a string, list []string) bool {
@@ -35,5 +30,15 @@ func stringInSlice(a string, list []string) bool {
 }
 
 func ValidateInclusionIn(value string, options []string) bool {
-	return stringInSlice(value, options)
+	return ValidateInclusion(value, options)
+}
+
+// Whether the value is one of the options, e.g. the values of an enum type
+func ValidateInclusion[T comparable](value T, options []T) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
 }
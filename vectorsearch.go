@@ -0,0 +1,321 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Similarity functions of Atlas vector indexes
+const (
+	SIMILARITY_COSINE      = "cosine"
+	SIMILARITY_EUCLIDEAN   = "euclidean"
+	SIMILARITY_DOT_PRODUCT = "dotProduct"
+)
+
+// How many candidates $vectorSearch considers per requested result
+const vectorSearchCandidates = 10
+
+// Atlas caps numCandidates at 10000
+const maxVectorSearchCandidates = 10000
+
+// Where the pipeline puts the similarity score before it is taken out of the document
+const vectorScoreField = "__bongo_vector_score"
+
+// An Atlas Vector Search index declared on a model through `vector` struct tags.
+//
+// Embeddings are stored in []float32 fields, tagged with
+// `vector:"name,dims=n[,similarity=cosine|euclidean|dotProduct]"` (similarity defaults to cosine). Fields tagged
+// `vector:"name,filter"` can be used in the pre-filter of VectorSearch, e.g.
+//
+//	Embedding []float32 `bson:"embedding" vector:"embeddings,dims=1536"`
+//	Tenant    string    `bson:"tenant" vector:"embeddings,filter"`
+type VectorIndexSpec struct {
+	Name       string
+	Path       string
+	Dimensions int
+	Similarity string
+	Filters    []string
+}
+
+var float32SliceType = reflect.TypeOf([]float32{})
+
+// Parses the vector tags of the model's fields
+func (m *Model) VectorIndexes() ([]*VectorIndexSpec, error) {
+	specs := make([]*VectorIndexSpec, 0)
+	byName := make(map[string]*VectorIndexSpec)
+	var err error
+
+	walkBsonFields(m.Type, "", func(field reflect.StructField, path string) bool {
+		tag, ok := field.Tag.Lookup("vector")
+		if !ok || err != nil {
+			return err == nil
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if len(name) == 0 {
+			err = errors.New("vector tag of " + m.Type.Name() + "." + field.Name + " needs an index name")
+			return false
+		}
+
+		spec, exists := byName[name]
+		if !exists {
+			spec = &VectorIndexSpec{Name: name, Filters: []string{}}
+			byName[name] = spec
+			specs = append(specs, spec)
+		}
+
+		if stringInSlice("filter", parts[1:]) {
+			if len(parts) != 2 {
+				err = errors.New("filter in vector tag of " + m.Type.Name() + "." + field.Name + " takes no other options")
+				return false
+			}
+			spec.Filters = append(spec.Filters, path)
+			return true
+		}
+
+		if len(spec.Path) > 0 {
+			err = errors.New("vector index " + name + " of " + m.Type.Name() + " has more than one vector field")
+			return false
+		}
+		if field.Type != float32SliceType {
+			err = errors.New("vector field " + m.Type.Name() + "." + field.Name + " must be a []float32")
+			return false
+		}
+
+		spec.Path = path
+		spec.Similarity = SIMILARITY_COSINE
+		for _, opt := range parts[1:] {
+			switch {
+			case strings.HasPrefix(opt, "dims="):
+				dims, e := strconv.Atoi(strings.TrimPrefix(opt, "dims="))
+				if e != nil || dims <= 0 {
+					err = errors.New("invalid dims in vector tag of " + m.Type.Name() + "." + field.Name)
+					return false
+				}
+				spec.Dimensions = dims
+			case strings.HasPrefix(opt, "similarity="):
+				spec.Similarity = strings.TrimPrefix(opt, "similarity=")
+				if !stringInSlice(spec.Similarity, []string{SIMILARITY_COSINE, SIMILARITY_EUCLIDEAN, SIMILARITY_DOT_PRODUCT}) {
+					err = errors.New("unknown similarity " + spec.Similarity + " in vector tag of " + m.Type.Name() + "." + field.Name)
+					return false
+				}
+			case len(opt) > 0:
+				err = errors.New("unknown option " + opt + " in vector tag of " + m.Type.Name() + "." + field.Name)
+				return false
+			}
+		}
+		return true
+	})
+
+	if err == nil {
+		for _, spec := range specs {
+			if len(spec.Path) == 0 {
+				return nil, errors.New("vector index " + spec.Name + " of " + m.Type.Name() + " has no vector field")
+			}
+			if spec.Dimensions == 0 {
+				return nil, errors.New("vector index " + spec.Name + " of " + m.Type.Name() + " needs dims")
+			}
+		}
+	}
+
+	return specs, err
+}
+
+// The definition of the index, as accepted by createSearchIndexes
+func (s *VectorIndexSpec) Definition() bson.M {
+	fields := bson.A{bson.M{
+		"type":          "vector",
+		"path":          s.Path,
+		"numDimensions": s.Dimensions,
+		"similarity":    s.Similarity,
+	}}
+	for _, path := range s.Filters {
+		fields = append(fields, bson.M{"type": "filter", "path": path})
+	}
+	return bson.M{"fields": fields}
+}
+
+// Converts the spec to the driver's search index model
+func (s *VectorIndexSpec) SearchIndexModel() mongo.SearchIndexModel {
+	return mongo.SearchIndexModel{
+		Definition: s.Definition(),
+		Options:    options.SearchIndexes().SetName(s.Name).SetType("vectorSearch"),
+	}
+}
+
+// Creates the vector search indexes declared on a model, returning the names of the created indexes. Indexes that
+// already exist are left alone, even if their definition changed. Atlas builds the indexes in the background, so
+// they may not be queryable right away
+func (m *Connection) SyncVectorIndexes(model *Model) ([]string, error) {
+	specs, err := model.VectorIndexes()
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		return []string{}, nil
+	}
+
+	collection := m.ModelCollection(model)
+	view := collection.Collection().SearchIndexes()
+
+	existing := make([]struct {
+		Name string `bson:"name"`
+	}, 0)
+	err = collection.runOperation("listSearchIndexes", func(ctx context.Context) error {
+		cursor, err := view.List(ctx, nil)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &existing)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]mongo.SearchIndexModel, 0, len(specs))
+	for _, spec := range specs {
+		found := false
+		for _, index := range existing {
+			found = found || index.Name == spec.Name
+		}
+		if !found {
+			models = append(models, spec.SearchIndexModel())
+		}
+	}
+	if len(models) == 0 {
+		return []string{}, nil
+	}
+
+	var names []string
+	err = collection.runOperation("createSearchIndexes", func(ctx context.Context) error {
+		var err error
+		names, err = view.CreateMany(ctx, models)
+		return err
+	})
+	return names, err
+}
+
+// A document found by VectorSearch, with its similarity to the query vector (between 0 and 1, higher is closer)
+type VectorSearchResult struct {
+	Score    float64
+	Document bson.Raw
+
+	collection *Collection
+}
+
+// Decodes the document like FindByID: loading its overflow, attaching its streams, running its AfterFind hook
+// and clearing the fields the caller may not read
+func (r *VectorSearchResult) Decode(doc interface{}) error {
+	if err := bson.UnmarshalWithRegistry(Registry, r.Document, doc); err != nil {
+		return newDecodeError(r.collection, r.Document, doc, err)
+	}
+
+	ctx, cancel := r.collection.operationContext()
+	defer cancel()
+	return r.collection.finishFind(ctx, doc)
+}
+
+// Finds the k documents whose embeddings in field are nearest to queryVector, using the Atlas Vector Search index
+// indexName. The optional filter (on fields declared as filters of the index) narrows the documents before the
+// search. Results are ordered by descending score. On collections with a query guard, the guard filters the k
+// nearest documents, so fewer may be returned
+func (c *Collection) VectorSearch(indexName string, field string, queryVector []float32, k int, filter interface{}) ([]*VectorSearchResult, error) {
+	if len(queryVector) == 0 {
+		return nil, errors.New("the query vector is empty")
+	}
+	if k <= 0 {
+		return nil, errors.New("k must be positive")
+	}
+
+	pipeline := vectorSearchPipeline(indexName, field, queryVector, k, filter)
+
+	results := make([]*VectorSearchResult, 0, k)
+	err := c.runOperation("vectorSearch", func(ctx context.Context) error {
+		results = results[:0]
+		guarded, err := c.guardPipeline(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		opts := options.Aggregate()
+		commentOn(ctx, c, opts.SetComment)
+		cursor, err := c.Collection().Aggregate(ctx, guarded, opts)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			result, err := newVectorSearchResult(c, cursor.Current)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func vectorSearchPipeline(indexName string, field string, queryVector []float32, k int, filter interface{}) mongo.Pipeline {
+	candidates := k * vectorSearchCandidates
+	if candidates > maxVectorSearchCandidates {
+		candidates = maxVectorSearchCandidates
+	}
+	if candidates < k {
+		candidates = k
+	}
+
+	search := bson.D{
+		{Key: "index", Value: indexName},
+		{Key: "path", Value: field},
+		{Key: "queryVector", Value: queryVector},
+		{Key: "numCandidates", Value: candidates},
+		{Key: "limit", Value: k},
+	}
+	if filter != nil {
+		search = append(search, bson.E{Key: "filter", Value: filter})
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$vectorSearch", Value: search}},
+		{{Key: "$addFields", Value: bson.M{vectorScoreField: bson.M{"$meta": "vectorSearchScore"}}}},
+	}
+}
+
+// Takes the score out of a document returned by the pipeline
+func newVectorSearchResult(c *Collection, raw bson.Raw) (*VectorSearchResult, error) {
+	elements, err := raw.Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VectorSearchResult{collection: c}
+	doc := bson.D{}
+	for _, element := range elements {
+		if element.Key() == vectorScoreField {
+			result.Score, _ = element.Value().DoubleOK()
+			continue
+		}
+		doc = append(doc, bson.E{Key: element.Key(), Value: element.Value()})
+	}
+
+	result.Document, err = bson.MarshalWithRegistry(Registry, doc)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type vectorDocument struct {
+	DocumentBase `bson:",inline"`
+	Title        string    `bson:"title"`
+	Tenant       string    `bson:"tenant" vector:"embeddings,filter"`
+	Embedding    []float32 `bson:"embedding" vector:"embeddings,dims=3,similarity=dotProduct"`
+}
+
+type badVectorDocument struct {
+	DocumentBase `bson:",inline"`
+	Embedding    []float64 `bson:"embedding" vector:"embeddings,dims=3"`
+}
+
+type missingDimsVectorDocument struct {
+	DocumentBase `bson:",inline"`
+	Embedding    []float32 `bson:"embedding" vector:"embeddings"`
+}
+
+func TestVectorIndexes(t *testing.T) {
+	Convey("Vector indexes", t, func() {
+		Convey("should parse the vector tags", func() {
+			specs, err := (&Model{Type: reflect.TypeOf(vectorDocument{})}).VectorIndexes()
+			So(err, ShouldEqual, nil)
+			So(len(specs), ShouldEqual, 1)
+			So(specs[0].Name, ShouldEqual, "embeddings")
+			So(specs[0].Path, ShouldEqual, "embedding")
+			So(specs[0].Dimensions, ShouldEqual, 3)
+			So(specs[0].Similarity, ShouldEqual, SIMILARITY_DOT_PRODUCT)
+			So(specs[0].Filters, ShouldResemble, []string{"tenant"})
+
+			So(specs[0].Definition(), ShouldResemble, bson.M{"fields": bson.A{
+				bson.M{"type": "vector", "path": "embedding", "numDimensions": 3, "similarity": "dotProduct"},
+				bson.M{"type": "filter", "path": "tenant"},
+			}})
+			So(*specs[0].SearchIndexModel().Options.Type, ShouldEqual, "vectorSearch")
+		})
+
+		Convey("should require []float32 vector fields", func() {
+			_, err := (&Model{Type: reflect.TypeOf(badVectorDocument{})}).VectorIndexes()
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should require dims", func() {
+			_, err := (&Model{Type: reflect.TypeOf(missingDimsVectorDocument{})}).VectorIndexes()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestVectorSearchPipeline(t *testing.T) {
+	Convey("Vector search", t, func() {
+		Convey("should build the $vectorSearch stage", func() {
+			pipeline := vectorSearchPipeline("embeddings", "embedding", []float32{0.1, 0.2}, 5, bson.M{"tenant": "acme"})
+			So(pipeline, ShouldResemble, mongo.Pipeline{
+				{{Key: "$vectorSearch", Value: bson.D{
+					{Key: "index", Value: "embeddings"},
+					{Key: "path", Value: "embedding"},
+					{Key: "queryVector", Value: []float32{0.1, 0.2}},
+					{Key: "numCandidates", Value: 50},
+					{Key: "limit", Value: 5},
+					{Key: "filter", Value: bson.M{"tenant": "acme"}},
+				}}},
+				{{Key: "$addFields", Value: bson.M{vectorScoreField: bson.M{"$meta": "vectorSearchScore"}}}},
+			})
+		})
+
+		Convey("should cap the number of candidates", func() {
+			pipeline := vectorSearchPipeline("embeddings", "embedding", []float32{0.1}, 5000, nil)
+			search := pipeline[0][0].Value.(bson.D)
+			So(search[3].Value, ShouldEqual, 10000)
+			So(len(search), ShouldEqual, 5)
+		})
+
+		Convey("should reject empty vectors and k", func() {
+			_, err := (&Collection{}).VectorSearch("embeddings", "embedding", nil, 5, nil)
+			So(err, ShouldNotBeNil)
+			_, err = (&Collection{}).VectorSearch("embeddings", "embedding", []float32{0.1}, 0, nil)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should take the score out of the results", func() {
+			raw, err := bson.Marshal(bson.D{{Key: "title", Value: "Lake houses"}, {Key: vectorScoreField, Value: 0.87}})
+			So(err, ShouldEqual, nil)
+
+			result, err := newVectorSearchResult(&Collection{Name: "articles"}, raw)
+			So(err, ShouldEqual, nil)
+			So(result.Score, ShouldEqual, 0.87)
+			_, err = result.Document.LookupErr(vectorScoreField)
+			So(err, ShouldNotBeNil)
+
+			doc := &vectorDocument{}
+			So(result.Decode(doc), ShouldEqual, nil)
+			So(doc.Title, ShouldEqual, "Lake houses")
+			So(doc.IsNew(), ShouldBeFalse)
+		})
+
+		Convey("should decode the results like found documents", func() {
+			conn := &Connection{Config: &Config{Database: "bongotest", FieldAccess: map[string]*FieldAccessConfig{}}}
+			ctx := PrincipalContextKey.WithValue(context.Background(), rolePrincipal{"hr"})
+			raw, err := bson.Marshal(bson.D{{Key: "name", Value: "Ann"}, {Key: "salary", Value: 100}, {Key: "notes", Value: "secret"}, {Key: vectorScoreField, Value: 0.5}})
+			So(err, ShouldBeNil)
+
+			result, err := newVectorSearchResult(conn.Collection("staff").WithContext(ctx), raw)
+			So(err, ShouldBeNil)
+			doc := &accessDocument{}
+			So(result.Decode(doc), ShouldBeNil)
+			So(doc.Salary, ShouldEqual, 100)
+			So(doc.Notes, ShouldEqual, "")
+
+			end := time.Date(2024, 3, 1, 17, 0, 0, 0, time.UTC)
+			raw, err = bson.Marshal(bson.D{{Key: "end", Value: end}, {Key: "endZone", Value: "Asia/Kolkata"}})
+			So(err, ShouldBeNil)
+			result, err = newVectorSearchResult(conn.Collection("events"), raw)
+			So(err, ShouldBeNil)
+			zoned := &zonedDocument{}
+			So(result.Decode(zoned), ShouldBeNil)
+			So(zoned.End.Location().String(), ShouldEqual, "Asia/Kolkata")
+		})
+	})
+}
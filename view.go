@@ -0,0 +1,210 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Fields tagged with view:"summary,detail" are only part of the listed views, so list and detail endpoints can share
+// a model. Untagged fields are part of every view. A tag on a struct field applies to all of its fields
+type viewField struct {
+	path  string
+	views []string
+}
+
+func (f *viewField) in(view string) bool {
+	for _, v := range f.views {
+		if v == view {
+			return true
+		}
+	}
+	return false
+}
+
+func parseViews(tag string) []string {
+	views := make([]string, 0)
+	for _, view := range strings.Split(tag, ",") {
+		if view = strings.TrimSpace(view); len(view) > 0 {
+			views = append(views, view)
+		}
+	}
+	return views
+}
+
+var viewFieldsCache sync.Map
+
+// The fields of a type with a view tag, cached per type
+func viewFields(t reflect.Type) []*viewField {
+	if fields, ok := viewFieldsCache.Load(t); ok {
+		return fields.([]*viewField)
+	}
+
+	fields := make([]*viewField, 0)
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		tag, ok := field.Tag.Lookup("view")
+		if !ok {
+			return true
+		}
+		fields = append(fields, &viewField{path: path, views: parseViews(tag)})
+		return false
+	})
+
+	viewFieldsCache.Store(t, fields)
+	return fields
+}
+
+// Only fetch the fields of a view of the document's type, i.e. leave out the fields tagged for other views:
+//
+//	Bio    string   `bson:"bio" json:"bio" view:"detail"`
+//	Orders []*Order `bson:"orders" json:"orders" view:"detail"`
+//
+//	results, err := people.Find(query, bongo.Project("summary"))
+//
+// The view is an exclusion projection, so it can be combined with Exclude but not with Select
+func Project(view string) FindOption {
+	return func(o *findOptions) {
+		o.view = view
+	}
+}
+
+// The projection leaving out the fields that aren't part of a view, or nil if there are none
+func viewProjection(t reflect.Type, view string) bson.M {
+	var projection bson.M
+	for _, field := range viewFields(indirectType(t)) {
+		if field.in(view) {
+			continue
+		}
+		if projection == nil {
+			projection = bson.M{}
+		}
+		projection[field.path] = 0
+	}
+	return projection
+}
+
+// Adds the projection of the view in the options, if any, for documents of a type
+func (o *findOptions) viewProjection(t reflect.Type) bson.M {
+	if len(o.view) == 0 {
+		return o.projection
+	}
+	projection := viewProjection(t, o.view)
+	if projection == nil {
+		return o.projection
+	}
+	for field, value := range o.projection {
+		projection[field] = value
+	}
+	return projection
+}
+
+// Marshals a value to JSON like MarshalRedactedJSON, but leaves out the struct fields that aren't part of a view,
+// e.g. for the items of a list endpoint:
+//
+//	data, err := bongo.MarshalViewJSON(people, "summary")
+//
+// Fields of nested structs, and of structs in slices, maps and interfaces, are left out too
+func MarshalViewJSON(v interface{}, view string) ([]byte, error) {
+	data, err := MarshalRedactedJSON(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(viewJSON(reflect.ValueOf(v), decoded, view))
+}
+
+// Walks a value together with its decoded JSON, removing the fields that aren't part of the view
+func viewJSON(v reflect.Value, data interface{}, view string) interface{} {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return data
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return data
+	}
+
+	// Types that marshal themselves are left alone
+	if v.Type().Implements(jsonMarshalerType) || (v.CanAddr() && v.Addr().Type().Implements(jsonMarshalerType)) {
+		return data
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if object, ok := data.(map[string]interface{}); ok {
+			viewStruct(v, object, view)
+		}
+	case reflect.Slice, reflect.Array:
+		if items, ok := data.([]interface{}); ok {
+			for i := 0; i < v.Len() && i < len(items); i++ {
+				items[i] = viewJSON(v.Index(i), items[i], view)
+			}
+		}
+	case reflect.Map:
+		object, ok := data.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String || v.Type().Key().Implements(textMarshalerType) {
+			return data
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			if value, ok := object[key]; ok {
+				object[key] = viewJSON(iter.Value(), value, view)
+			}
+		}
+	}
+	return data
+}
+
+func viewStruct(v reflect.Value, object map[string]interface{}, view string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name := field.Name
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" && len(tag) == 1 {
+			continue
+		}
+		if len(tag[0]) > 0 {
+			name = tag[0]
+		}
+
+		// Untagged embedded structs are promoted into the same object
+		if field.Anonymous && len(tag[0]) == 0 {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				viewJSON(v.Field(i), object, view)
+				continue
+			}
+		}
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		value, ok := object[name]
+		if !ok {
+			continue
+		}
+		if views, ok := field.Tag.Lookup("view"); ok && !(&viewField{views: parseViews(views)}).in(view) {
+			delete(object, name)
+			continue
+		}
+		object[name] = viewJSON(v.Field(i), value, view)
+	}
+}
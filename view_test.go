@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+type viewProfile struct {
+	Bio     string `bson:"bio" json:"bio" view:"detail"`
+	Website string `bson:"website" json:"website"`
+}
+
+type viewDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string       `bson:"name" json:"name"`
+	Email        string       `bson:"email" json:"email" view:"summary, detail"`
+	Token        string       `bson:"token" json:"token" redact:"true"`
+	Notes        []string     `bson:"notes" json:"notes" view:"detail"`
+	Profile      *viewProfile `bson:"profile" json:"profile"`
+}
+
+func TestViews(t *testing.T) {
+	Convey("Views", t, func() {
+		docType := reflect.TypeOf(&viewDocument{})
+
+		Convey("should project the fields of a view", func() {
+			So(viewProjection(docType, "summary"), ShouldResemble, bson.M{"notes": 0, "profile.bio": 0})
+			So(viewProjection(docType, "detail"), ShouldBeNil)
+			So(viewProjection(docType, "admin"), ShouldResemble, bson.M{"email": 0, "notes": 0, "profile.bio": 0})
+		})
+
+		Convey("should combine views with Exclude", func() {
+			o := newFindOptions([]FindOption{Project("summary"), Exclude("website")})
+			So(o.viewProjection(docType), ShouldResemble, bson.M{"notes": 0, "profile.bio": 0, "website": 0})
+			So(newFindOptions(nil).viewProjection(docType), ShouldBeNil)
+		})
+
+		Convey("should marshal the fields of a view to JSON", func() {
+			doc := &viewDocument{Name: "Foo", Email: "foo@example.com", Token: "secret", Notes: []string{"a"},
+				Profile: &viewProfile{Bio: "Bar", Website: "example.com"}}
+			data, err := MarshalViewJSON([]*viewDocument{doc}, "summary")
+			So(err, ShouldBeNil)
+
+			decoded := []map[string]interface{}{}
+			So(json.Unmarshal(data, &decoded), ShouldBeNil)
+			So(decoded[0]["name"], ShouldEqual, "Foo")
+			So(decoded[0]["email"], ShouldEqual, "foo@example.com")
+			So(decoded[0], ShouldContainKey, "created_at")
+			So(decoded[0], ShouldNotContainKey, "notes")
+			So(decoded[0], ShouldNotContainKey, "token")
+			So(decoded[0]["profile"], ShouldResemble, map[string]interface{}{"website": "example.com"})
+		})
+	})
+}
+
+func TestProject(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Project", t, func() {
+		col := conn.Collection("views")
+		doc := &viewDocument{Name: "Foo", Email: "foo@example.com", Notes: []string{"a"}, Profile: &viewProfile{Bio: "Bar", Website: "example.com"}}
+		So(col.Save(doc), ShouldBeNil)
+
+		Convey("should leave out the fields of other views", func() {
+			found := &viewDocument{}
+			So(col.FindByID(doc.ID, found, Project("summary")), ShouldBeNil)
+			So(found.Name, ShouldEqual, "Foo")
+			So(found.Notes, ShouldBeNil)
+			So(found.Profile.Bio, ShouldEqual, "")
+			So(found.Profile.Website, ShouldEqual, "example.com")
+
+			results, err := col.Find(bson.M{}, Project("summary"))
+			So(err, ShouldBeNil)
+			found = &viewDocument{}
+			So(results.Next(found), ShouldBeTrue)
+			So(found.Notes, ShouldBeNil)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
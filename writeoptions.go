@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+)
+
+// Options for Save and DeleteDocument (and the bulk writes of Import and UpdateEach)
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	skipHooks   bool
+	skipCascade bool
+
+	// Set by SaveGraph, whose references only exist once it commits
+	skipReferences bool
+
+	// Saved and deleted documents are evicted from it
+	identityMap *IdentityMap
+
+	// Set by Save when the write was queued by a write behind cache
+	queued bool
+}
+
+// Don't run the BeforeSave/AfterSave or BeforeDelete/AfterDelete hooks, for system level writes like migrations,
+// replays and data fixes. Validation still runs
+func SkipHooks() WriteOption {
+	return func(o *writeOptions) {
+		o.skipHooks = true
+	}
+}
+
+// Don't cascade the document to related documents (or remove its references from them on delete). For bulk
+// imports and backfills, which can resync the related documents once afterwards with CascadeSave
+func SkipCascade() WriteOption {
+	return func(o *writeOptions) {
+		o.skipCascade = true
+	}
+}
+
+// Uses the identity map of the context for eviction unless one was given
+func (o *writeOptions) withContext(ctx context.Context) *writeOptions {
+	if o.identityMap != nil {
+		return o
+	}
+	withMap := *o
+	withMap.identityMap = IdentityMapFrom(ctx)
+	return &withMap
+}
+
+func newWriteOptions(opts []WriteOption) *writeOptions {
+	o := &writeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
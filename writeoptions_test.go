@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestSkipHooks(t *testing.T) {
+	Convey("SkipHooks", t, func() {
+		Convey("should skip the save hooks but not validation", func() {
+			col := &Collection{}
+			doc := &hookedDocument{}
+			o := newWriteOptions([]WriteOption{SkipHooks()})
+
+			_, err := col.prepareSave(doc, o)
+			So(err, ShouldEqual, nil)
+			So(col.finishSave(doc, o), ShouldEqual, nil)
+			So(doc.RanBeforeSave, ShouldBeFalse)
+			So(doc.RanAfterSave, ShouldBeFalse)
+			So(doc.IsNew(), ShouldBeFalse)
+
+			_, err = col.prepareSave(&validatedDocument{}, o)
+			So(err, ShouldHaveSameTypeAs, &ValidationError{})
+		})
+	})
+}
+
+func TestSkipCascade(t *testing.T) {
+	Convey("SkipCascade", t, func() {
+		Convey("should combine with other options", func() {
+			o := newWriteOptions([]WriteOption{SkipCascade(), SkipHooks()})
+			So(o.skipCascade, ShouldBeTrue)
+			So(o.skipHooks, ShouldBeTrue)
+
+			o = newWriteOptions(nil)
+			So(o.skipCascade, ShouldBeFalse)
+		})
+	})
+}
+
+func TestSkipHooksWrites(t *testing.T) {
+	conn := getConnection()
+
+	Convey("Writes with SkipHooks", t, func() {
+		col := conn.Collection("tests")
+
+		Convey("should save and delete without running hooks", func() {
+			doc := &hookedDocument{}
+			So(col.Save(doc, SkipHooks()), ShouldEqual, nil)
+			So(doc.RanBeforeSave, ShouldBeFalse)
+			So(doc.RanAfterSave, ShouldBeFalse)
+
+			_, err := col.DeleteDocument(doc, SkipHooks())
+			So(err, ShouldEqual, nil)
+			So(doc.RanBeforeDelete, ShouldBeFalse)
+			So(doc.RanAfterDelete, ShouldBeFalse)
+		})
+
+		Reset(func() {
+			conn.Session.Database("bongotest").Drop(context.Background())
+		})
+	})
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A time that keeps its time zone in the database, for domains like calendars where the wall clock time matters.
+// Stored as {utc: date, offset: seconds east of UTC, zone: "Europe/Zurich"}, and found in its zone again. Zones that
+// can't be loaded fall back to a fixed zone with the stored offset. Query the instant with the "utc" field, e.g.
+// DayInZone("start.utc", ...), and the wall clock time with LocalDateFilter. In JSON, it is an RFC 3339 time, which
+// keeps the offset but not the zone name. The zero value is stored as null
+type ZonedTime struct {
+	time.Time
+}
+
+type zonedTimeDocument struct {
+	UTC    time.Time `bson:"utc"`
+	Offset int       `bson:"offset"`
+	Zone   string    `bson:"zone"`
+}
+
+func (z ZonedTime) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if z.IsZero() {
+		return bsontype.Null, nil, nil
+	}
+	_, offset := z.Zone()
+	return bson.MarshalValue(zonedTimeDocument{UTC: z.UTC(), Offset: offset, Zone: z.Location().String()})
+}
+
+// Reads the zoned time subdocument, and plain dates as UTC
+func (z *ZonedTime) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.EmbeddedDocument:
+		stored := zonedTimeDocument{}
+		if err := raw.Unmarshal(&stored); err != nil {
+			return err
+		}
+		z.Time = stored.UTC.In(loadZone(stored.Zone, stored.Offset))
+	case bsontype.DateTime:
+		z.Time = raw.Time().UTC()
+	case bsontype.Null, bsontype.Undefined:
+		z.Time = time.Time{}
+	default:
+		return errors.New("can't decode " + t.String() + " into a zoned time")
+	}
+	return nil
+}
+
+// The location of a zone name, or a fixed zone with the offset if the name can't be loaded
+func loadZone(name string, offset int) *time.Location {
+	if len(name) > 0 {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.FixedZone(name, offset)
+}
+
+// A filter for the times at path that fall on a date in a location, e.g. the appointments on a day in the time zone
+// of the user looking at the calendar
+func DayInZone(path string, d Date, loc *time.Location) bson.M {
+	return bson.M{path: bson.M{"$gte": d.In(loc), "$lt": d.AddDate(0, 0, 1).In(loc)}}
+}
+
+// A filter for the ZonedTimes at path that fall on a date in their own zone, e.g. everything that starts on
+// 2020-03-10 wherever it happens. The server converts every stored time, so an index on the field isn't used; narrow
+// the filter down with DayInZone for the widest offsets if needed
+func LocalDateFilter(path string, d Date) bson.M {
+	localDate := bson.M{"$dateToString": bson.M{
+		"date":     "$" + path + ".utc",
+		"format":   "%Y-%m-%d",
+		"timezone": "$" + path + ".zone",
+	}}
+	return bson.M{"$expr": bson.M{"$eq": bson.A{localDate, d.String()}}}
+}
+
+// time.Time fields tagged with `zone:"<Go field>"` store the name of their time zone in a string field next to them,
+// and are found in that zone again
+type zoneField struct {
+	goPath          string
+	companionGoPath string
+}
+
+var zoneFieldsCache sync.Map
+
+// The time fields of a type with a companion zone field, cached per type
+func zoneFields(t reflect.Type) []zoneField {
+	if fields, ok := zoneFieldsCache.Load(t); ok {
+		return fields.([]zoneField)
+	}
+
+	fields := make([]zoneField, 0)
+	walkFields(t, "", "", map[reflect.Type]bool{}, func(field reflect.StructField, goPath string, path string) bool {
+		companion, ok := field.Tag.Lookup("zone")
+		if !ok || len(companion) == 0 || indirectType(field.Type) != timeType {
+			return true
+		}
+		if i := strings.LastIndex(goPath, "."); i >= 0 {
+			companion = goPath[:i+1] + companion
+		}
+		fields = append(fields, zoneField{goPath: goPath, companionGoPath: companion})
+		return false
+	})
+
+	zoneFieldsCache.Store(t, fields)
+	return fields
+}
+
+// Calls fn with each time field of a document that has a companion zone field, and the companion
+func eachZoneField(doc interface{}, fn func(t reflect.Value, zone reflect.Value)) {
+	v := reflect.ValueOf(doc)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, field := range zoneFields(v.Type()) {
+		t := reflect.Indirect(fieldByGoPath(v, field.goPath))
+		zone := fieldByGoPath(v, field.companionGoPath)
+		if !t.IsValid() || !zone.IsValid() || zone.Kind() != reflect.String || !zone.CanSet() {
+			continue
+		}
+		fn(t, zone)
+	}
+}
+
+// Writes the zone names of the tagged time fields of a document to their companion fields
+func storeZones(doc interface{}) {
+	eachZoneField(doc, func(t reflect.Value, zone reflect.Value) {
+		value := t.Interface().(time.Time)
+		if value.IsZero() {
+			zone.SetString("")
+			return
+		}
+		zone.SetString(value.Location().String())
+	})
+}
+
+// Moves the tagged time fields of a found document into the zones named by their companion fields. Times whose
+// zone can't be loaded stay in UTC
+func applyZones(doc interface{}) {
+	eachZoneField(doc, func(t reflect.Value, zone reflect.Value) {
+		value := t.Interface().(time.Time)
+		if value.IsZero() || len(zone.String()) == 0 || !t.CanSet() {
+			return
+		}
+		if loc, err := time.LoadLocation(zone.String()); err == nil {
+			t.Set(reflect.ValueOf(value.In(loc)))
+		}
+	})
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2019. Pandranki Global Private Limited
+ */
+
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+type zonedDocument struct {
+	DocumentBase `bson:",inline"`
+	Start        ZonedTime `bson:"start"`
+	End          time.Time `bson:"end" zone:"EndZone"`
+	EndZone      string    `bson:"endZone"`
+}
+
+func TestZonedTime(t *testing.T) {
+	Convey("Zoned times", t, func() {
+		zurich, err := time.LoadLocation("Europe/Zurich")
+		So(err, ShouldBeNil)
+		start := time.Date(2020, 3, 10, 9, 0, 0, 0, zurich)
+
+		Convey("should be stored with their zone and found in it", func() {
+			raw, err := bson.Marshal(&zonedDocument{Start: ZonedTime{start}})
+			So(err, ShouldBeNil)
+			stored := bson.Raw(raw).Lookup("start").Document()
+			So(stored.Lookup("utc").Time().UTC(), ShouldEqual, start.UTC())
+			So(stored.Lookup("offset").Int32(), ShouldEqual, 3600)
+			So(stored.Lookup("zone").StringValue(), ShouldEqual, "Europe/Zurich")
+
+			found := &zonedDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Start.Location().String(), ShouldEqual, "Europe/Zurich")
+			So(found.Start.Hour(), ShouldEqual, 9)
+		})
+
+		Convey("should fall back to the stored offset for unknown zones", func() {
+			raw, _ := bson.Marshal(bson.M{"start": bson.M{"utc": start.UTC(), "offset": 3600, "zone": "Nowhere/Town"}})
+			found := &zonedDocument{}
+			So(bson.Unmarshal(raw, found), ShouldBeNil)
+			So(found.Start.Hour(), ShouldEqual, 9)
+		})
+
+		Convey("should keep the zone of tagged time fields in their companion field", func() {
+			doc := &zonedDocument{End: start}
+			storeZones(doc)
+			So(doc.EndZone, ShouldEqual, "Europe/Zurich")
+
+			doc.End = doc.End.UTC()
+			applyZones(doc)
+			So(doc.End.Location().String(), ShouldEqual, "Europe/Zurich")
+			So(doc.End.Hour(), ShouldEqual, 9)
+		})
+
+		Convey("should build filters for days in a zone", func() {
+			day := NewDate(2020, 3, 10)
+			So(DayInZone("start.utc", day, zurich), ShouldResemble, bson.M{"start.utc": bson.M{
+				"$gte": time.Date(2020, 3, 10, 0, 0, 0, 0, zurich),
+				"$lt":  time.Date(2020, 3, 11, 0, 0, 0, 0, zurich),
+			}})
+			filter := LocalDateFilter("start", day)
+			So(filter["$expr"], ShouldNotBeNil)
+		})
+	})
+}